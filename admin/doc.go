@@ -0,0 +1,4 @@
+// Package admin provides a secondary HTTP server for operators: profiles,
+// expvars, runtime metrics, build info, health, and log levels. Serve it on
+// a separate, private address from a service's main listener.
+package admin
@@ -0,0 +1,226 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime/debug"
+	"runtime/metrics"
+	"time"
+
+	"github.com/barrett370/kit/v2/health"
+	"github.com/barrett370/kit/v2/log/level"
+)
+
+// DefaultShutdownTimeout is how long Run waits for requests in flight to
+// finish when it's stopped.
+const DefaultShutdownTimeout = 5 * time.Second
+
+// Option sets an optional parameter for Servers.
+type Option func(*Server)
+
+// WithHealth serves the liveness and readiness of h at /health/live and
+// /health/ready.
+func WithHealth(h *health.Health) Option {
+	return func(s *Server) { s.health = h }
+}
+
+// WithLevelHandler serves h at /log/level, to change log levels at runtime.
+func WithLevelHandler(h *level.Handler) Option {
+	return func(s *Server) { s.level = h }
+}
+
+// WithBasicAuth requires HTTP basic authentication with the username and
+// password for all endpoints but health, which probes must reach without
+// credentials.
+func WithBasicAuth(username, password string) Option {
+	return func(s *Server) { s.username, s.password = username, password }
+}
+
+// WithHandler serves h at the pattern as well, as with http.ServeMux, e.g. a
+// metrics handler, or an endpoint to change settings at runtime.
+func WithHandler(pattern string, h http.Handler) Option {
+	return func(s *Server) { s.handlers = append(s.handlers, route{pattern, h}) }
+}
+
+// WithShutdownTimeout sets how long Run waits for requests in flight to
+// finish when it's stopped. By default, it's DefaultShutdownTimeout.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(s *Server) { s.shutdownTimeout = d }
+}
+
+type route struct {
+	pattern string
+	handler http.Handler
+}
+
+// Server is an admin HTTP server. It serves
+//
+//	/debug/pprof/     profiles, from net/http/pprof
+//	/debug/vars       expvars, from expvar
+//	/debug/runtime    runtime metrics, from runtime/metrics, as JSON
+//	/debug/buildinfo  build info, from runtime/debug, as JSON
+//	/health/live      liveness, with WithHealth
+//	/health/ready     readiness, with WithHealth
+//	/log/level        log levels, with WithLevelHandler
+//
+// and any handlers added with WithHandler.
+type Server struct {
+	addr            string
+	health          *health.Health
+	level           *level.Handler
+	username        string
+	password        string
+	handlers        []route
+	shutdownTimeout time.Duration
+	handler         http.Handler
+}
+
+// NewServer returns a Server which listens on addr when it's Run.
+func NewServer(addr string, options ...Option) *Server {
+	s := &Server{
+		addr:            addr,
+		shutdownTimeout: DefaultShutdownTimeout,
+	}
+	for _, option := range options {
+		option(s)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/pprof/", s.auth(http.HandlerFunc(pprof.Index)))
+	mux.Handle("/debug/pprof/cmdline", s.auth(http.HandlerFunc(pprof.Cmdline)))
+	mux.Handle("/debug/pprof/profile", s.auth(http.HandlerFunc(pprof.Profile)))
+	mux.Handle("/debug/pprof/symbol", s.auth(http.HandlerFunc(pprof.Symbol)))
+	mux.Handle("/debug/pprof/trace", s.auth(http.HandlerFunc(pprof.Trace)))
+	mux.Handle("/debug/vars", s.auth(expvar.Handler()))
+	mux.Handle("/debug/runtime", s.auth(http.HandlerFunc(runtimeMetrics)))
+	mux.Handle("/debug/buildinfo", s.auth(http.HandlerFunc(buildInfo)))
+	if s.health != nil {
+		mux.Handle("/health/live", s.health.LivenessHandler())
+		mux.Handle("/health/ready", s.health.ReadinessHandler())
+	}
+	if s.level != nil {
+		mux.Handle("/log/level", s.auth(s.level))
+	}
+	for _, r := range s.handlers {
+		mux.Handle(r.pattern, s.auth(r.handler))
+	}
+	s.handler = mux
+	return s
+}
+
+// ServeHTTP implements http.Handler, so the Server's endpoints may be served
+// by another server, or in tests.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// Run listens on the Server's address, and serves until the context is done,
+// when it shuts down, waiting for requests in flight, and returns the
+// context's error. If it can't listen, or serving fails, it returns that
+// error at once.
+func (s *Server) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve is like Run, but serves connections on the listener.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	server := &http.Server{Handler: s.handler}
+	errc := make(chan error, 1)
+	go func() { errc <- server.Serve(ln) }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		server.Close()
+	}
+	if err := <-errc; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return ctx.Err()
+}
+
+func (s *Server) auth(next http.Handler) http.Handler {
+	if s.username == "" && s.password == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(username), []byte(s.username)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(password), []byte(s.password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="admin"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// runtimeMetrics responds with all the supported runtime metrics, by name.
+// Histograms are reported as their bucket boundaries and counts.
+func runtimeMetrics(w http.ResponseWriter, r *http.Request) {
+	descs := metrics.All()
+	samples := make([]metrics.Sample, len(descs))
+	for i, d := range descs {
+		samples[i].Name = d.Name
+	}
+	metrics.Read(samples)
+
+	values := make(map[string]interface{}, len(samples))
+	for _, sample := range samples {
+		switch sample.Value.Kind() {
+		case metrics.KindUint64:
+			values[sample.Name] = sample.Value.Uint64()
+		case metrics.KindFloat64:
+			values[sample.Name] = jsonFloat(sample.Value.Float64())
+		case metrics.KindFloat64Histogram:
+			h := sample.Value.Float64Histogram()
+			buckets := make([]interface{}, len(h.Buckets))
+			for i, b := range h.Buckets {
+				buckets[i] = jsonFloat(b)
+			}
+			values[sample.Name] = map[string]interface{}{"buckets": buckets, "counts": h.Counts}
+		}
+	}
+	writeJSON(w, values)
+}
+
+// jsonFloat returns f, or a string for infinities and NaN, which JSON can't
+// represent as numbers, and which histogram buckets have at their ends.
+func jsonFloat(f float64) interface{} {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return fmt.Sprint(f)
+	}
+	return f
+}
+
+func buildInfo(w http.ResponseWriter, r *http.Request) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		http.Error(w, "no build info", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, info)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(v)
+}
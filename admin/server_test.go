@@ -0,0 +1,106 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/barrett370/kit/v2/health"
+	"github.com/barrett370/kit/v2/log/level"
+)
+
+func TestServer(t *testing.T) {
+	var (
+		h      = health.New()
+		levels = level.NewHandler(&level.LevelVar{})
+		custom = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("custom")) })
+		s      = NewServer("", WithHealth(h), WithLevelHandler(levels), WithHandler("/custom", custom))
+	)
+	for _, path := range []string{
+		"/debug/pprof/",
+		"/debug/pprof/cmdline",
+		"/debug/vars",
+		"/debug/runtime",
+		"/health/live",
+		"/health/ready",
+		"/log/level",
+		"/custom",
+	} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if want, have := http.StatusOK, rec.Code; want != have {
+			t.Errorf("%s: want %d, have %d", path, want, have)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/runtime", nil))
+	var metrics map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&metrics); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := metrics["/sched/goroutines:goroutines"]; !ok {
+		t.Errorf("want the goroutine count, have %v", metrics)
+	}
+}
+
+func TestServerBasicAuth(t *testing.T) {
+	s := NewServer("", WithHealth(health.New()), WithBasicAuth("admin", "secret"))
+	for _, tc := range []struct {
+		path     string
+		username string
+		password string
+		want     int
+	}{
+		{"/debug/vars", "", "", http.StatusUnauthorized},
+		{"/debug/vars", "admin", "wrong", http.StatusUnauthorized},
+		{"/debug/vars", "admin", "secret", http.StatusOK},
+		{"/health/ready", "", "", http.StatusOK}, // for probes
+	} {
+		req := httptest.NewRequest("GET", tc.path, nil)
+		if tc.username != "" {
+			req.SetBasicAuth(tc.username, tc.password)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		if want, have := tc.want, rec.Code; want != have {
+			t.Errorf("%s as %q: want %d, have %d", tc.path, tc.username, want, have)
+		}
+	}
+}
+
+func TestServerServe(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		s           = NewServer("")
+		ctx, cancel = context.WithCancel(context.Background())
+		errc        = make(chan error, 1)
+	)
+	go func() { errc <- s.Serve(ctx, ln) }()
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/debug/vars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want, have := http.StatusOK, resp.StatusCode; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+
+	cancel()
+	select {
+	case err := <-errc:
+		if want, have := context.Canceled, err; want != have {
+			t.Errorf("want %v, have %v", want, have)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for shutdown")
+	}
+}
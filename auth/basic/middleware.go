@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"crypto/sha256"
-	"crypto/subtle"
 	"encoding/base64"
 	"fmt"
 	"net/http"
@@ -65,12 +64,21 @@ func toHashSlice(s []byte) []byte {
 
 // AuthMiddleware returns a Basic Authentication middleware for a particular user and password.
 func AuthMiddleware[I, O any](requiredUser, requiredPassword, realm string) endpoint.Middleware[I, O] {
-	requiredUserBytes := toHashSlice([]byte(requiredUser))
-	requiredPasswordBytes := toHashSlice([]byte(requiredPassword))
+	return NewMiddleware[I, O](realm, StaticCredentials(map[string]string{requiredUser: requiredPassword}))
+}
 
+// NewMiddleware returns a Basic Authentication middleware for the realm,
+// which checks credentials with the verifier. On success, the authenticated
+// *Principal is stored in the context under PrincipalContextKey. Requests
+// with missing or invalid credentials fail with an AuthError for the realm,
+// and errors from the verifier are returned as-is.
+//
+// As with AuthMiddleware, the Authorization header must be passed through the
+// context, e.g. with httptransport.ServerBefore(httptransport.PopulateRequestContext).
+func NewMiddleware[I, O any](realm string, verifier Verifier) endpoint.Middleware[I, O] {
 	return func(next endpoint.Endpoint[I, O]) endpoint.Endpoint[I, O] {
 		return func(ctx context.Context, request I) (O, error) {
-			auth, ok := ctx.Value(httptransport.ContextKeyRequestAuthorization).(string)
+			auth, ok := httptransport.RequestAuthorizationKey.Get(ctx)
 			if !ok {
 				var zero O
 				return zero, AuthError{realm}
@@ -82,15 +90,17 @@ func AuthMiddleware[I, O any](requiredUser, requiredPassword, realm string) endp
 				return zero, AuthError{realm}
 			}
 
-			givenUserBytes := toHashSlice(givenUser)
-			givenPasswordBytes := toHashSlice(givenPassword)
-
-			if subtle.ConstantTimeCompare(givenUserBytes, requiredUserBytes) == 0 ||
-				subtle.ConstantTimeCompare(givenPasswordBytes, requiredPasswordBytes) == 0 {
+			valid, err := verifier.Verify(ctx, realm, string(givenUser), string(givenPassword))
+			if err != nil {
+				var zero O
+				return zero, err
+			}
+			if !valid {
 				var zero O
 				return zero, AuthError{realm}
 			}
 
+			ctx = PrincipalContextKey.Set(ctx, &Principal{Username: string(givenUser), Realm: realm})
 			return next(ctx, request)
 		}
 	}
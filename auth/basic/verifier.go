@@ -0,0 +1,105 @@
+package basic
+
+import (
+	"context"
+	"crypto/subtle"
+
+	"github.com/barrett370/kit/v2/contextkey"
+)
+
+// PrincipalContextKey holds the key used to store the authenticated
+// *Principal in the context, e.g. for logging or authorization downstream.
+var PrincipalContextKey = contextkey.New[*Principal]("BasicAuthPrincipal")
+
+// Principal is a user authenticated by the middleware.
+type Principal struct {
+	Username string
+	Realm    string
+}
+
+// PrincipalFromContext returns the principal stored in the context by the
+// middleware, if any.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := PrincipalContextKey.Get(ctx)
+	return p, ok
+}
+
+// Verifier checks the credentials given for a realm.
+type Verifier interface {
+	// Verify reports whether the password is valid for the user in the
+	// realm. It returns an error only if it couldn't decide, e.g. because
+	// its store is unavailable.
+	Verify(ctx context.Context, realm, user, password string) (bool, error)
+}
+
+// VerifierFunc is an adapter to allow the use of ordinary functions as
+// Verifiers.
+type VerifierFunc func(ctx context.Context, realm, user, password string) (bool, error)
+
+// Verify implements Verifier.
+func (f VerifierFunc) Verify(ctx context.Context, realm, user, password string) (bool, error) {
+	return f(ctx, realm, user, password)
+}
+
+// StaticCredentials returns a Verifier which checks credentials against the
+// map of passwords, by user. Passwords are compared in constant time, and
+// unknown users take as long to reject as wrong passwords.
+func StaticCredentials(passwords map[string]string) Verifier {
+	hashes := make(map[string][]byte, len(passwords))
+	for user, password := range passwords {
+		hashes[user] = toHashSlice([]byte(password))
+	}
+	unknown := toHashSlice(nil)
+	return VerifierFunc(func(_ context.Context, _, user, password string) (bool, error) {
+		required, ok := hashes[user]
+		if !ok {
+			required = unknown
+		}
+		match := subtle.ConstantTimeCompare(toHashSlice([]byte(password)), required) == 1
+		return ok && match, nil
+	})
+}
+
+// CompareFunc compares a hashed password with a plaintext one, returning nil
+// if they match. bcrypt.CompareHashAndPassword, from
+// golang.org/x/crypto/bcrypt, is a CompareFunc.
+type CompareFunc func(hashedPassword, password []byte) error
+
+// HashedCredentials returns a Verifier which checks credentials against the
+// map of hashed passwords, by user, with the compare func, e.g.
+//
+//	basic.HashedCredentials(hashes, bcrypt.CompareHashAndPassword)
+//
+// To keep the time taken to reject unknown users close to that of wrong
+// passwords, a password for an unknown user is compared with the hash of an
+// arbitrary known user.
+func HashedCredentials(hashes map[string][]byte, compare CompareFunc) Verifier {
+	var decoy []byte
+	for _, hash := range hashes {
+		decoy = hash
+		break
+	}
+	return VerifierFunc(func(_ context.Context, _, user, password string) (bool, error) {
+		hash, ok := hashes[user]
+		if !ok {
+			if decoy != nil {
+				compare(decoy, []byte(password))
+			}
+			return false, nil
+		}
+		return compare(hash, []byte(password)) == nil, nil
+	})
+}
+
+// Realms returns a Verifier which delegates to the verifier for the realm, so
+// that one Verifier can serve middlewares for several realms. Credentials for
+// a realm without a verifier are invalid.
+func Realms(verifiers map[string]Verifier) Verifier {
+	return VerifierFunc(func(ctx context.Context, realm, user, password string) (bool, error) {
+		v, ok := verifiers[realm]
+		if !ok {
+			return false, nil
+		}
+		return v.Verify(ctx, realm, user, password)
+	})
+}
@@ -0,0 +1,87 @@
+package basic
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"testing"
+
+	httptransport "github.com/barrett370/kit/v2/transport/http"
+)
+
+// compareSHA256 stands in for bcrypt.CompareHashAndPassword.
+func compareSHA256(hashed, password []byte) error {
+	sum := sha256.Sum256(password)
+	if subtle.ConstantTimeCompare(hashed, sum[:]) != 1 {
+		return errors.New("mismatch")
+	}
+	return nil
+}
+
+func sha256Hash(s string) []byte {
+	sum := sha256.Sum256([]byte(s))
+	return sum[:]
+}
+
+func TestVerifiers(t *testing.T) {
+	var (
+		static = StaticCredentials(map[string]string{"alice": "wonderland"})
+		hashed = HashedCredentials(map[string][]byte{"bob": sha256Hash("builder")}, compareSHA256)
+		realms = Realms(map[string]Verifier{"admin": static, "api": hashed})
+	)
+	for _, tc := range []struct {
+		name     string
+		verifier Verifier
+		realm    string
+		user     string
+		password string
+		want     bool
+	}{
+		{"static", static, "", "alice", "wonderland", true},
+		{"static, wrong password", static, "", "alice", "looking-glass", false},
+		{"static, unknown user", static, "", "mallory", "wonderland", false},
+		{"static, unknown user, empty password", static, "", "mallory", "", false},
+		{"hashed", hashed, "", "bob", "builder", true},
+		{"hashed, wrong password", hashed, "", "bob", "fixer", false},
+		{"hashed, unknown user", hashed, "", "mallory", "builder", false},
+		{"realm", realms, "api", "bob", "builder", true},
+		{"other realm", realms, "admin", "bob", "builder", false},
+		{"unknown realm", realms, "ops", "alice", "wonderland", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			have, err := tc.verifier.Verify(context.Background(), tc.realm, tc.user, tc.password)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := tc.want; want != have {
+				t.Errorf("want %v, have %v", want, have)
+			}
+		})
+	}
+}
+
+func TestNewMiddlewarePrincipal(t *testing.T) {
+	e := func(ctx context.Context, request interface{}) (interface{}, error) {
+		p, _ := PrincipalFromContext(ctx)
+		return p, nil
+	}
+	mw := NewMiddleware[any, any]("api", StaticCredentials(map[string]string{"alice": "wonderland"}))
+
+	ctx := context.WithValue(context.Background(), httptransport.ContextKeyRequestAuthorization, makeAuthString("alice", "wonderland"))
+	result, err := mw(e)(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := (&Principal{Username: "alice", Realm: "api"}), result.(*Principal); *want != *have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	errStore := errors.New("store unavailable")
+	mw = NewMiddleware[any, any]("api", VerifierFunc(func(context.Context, string, string, string) (bool, error) {
+		return false, errStore
+	}))
+	if _, err := mw(e)(ctx, nil); err != errStore {
+		t.Errorf("want %v, have %v", errStore, err)
+	}
+}
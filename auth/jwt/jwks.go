@@ -0,0 +1,247 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	stdhttp "net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrKeyNotFound denotes that no key in the JSON Web Key Set matches the key
+// ID (kid) header of a token, even after refreshing the set.
+var ErrKeyNotFound = errors.New("no key found for the token's key ID")
+
+// Defaults for NewJWKSKeyFunc.
+const (
+	DefaultJWKSRefreshInterval    = time.Hour
+	DefaultJWKSMinRefreshInterval = time.Minute
+	DefaultJWKSTimeout            = 10 * time.Second
+)
+
+// JWKSOption sets an optional parameter for NewJWKSKeyFunc.
+type JWKSOption func(*jwks)
+
+// JWKSHTTPClient sets the client used to fetch the key set. By default, a
+// client with a timeout of DefaultJWKSTimeout is used.
+func JWKSHTTPClient(client *stdhttp.Client) JWKSOption {
+	return func(k *jwks) { k.client = client }
+}
+
+// JWKSRefreshInterval sets the interval after which the key set is fetched
+// again, so that rotated keys are picked up. By default, it's
+// DefaultJWKSRefreshInterval.
+func JWKSRefreshInterval(d time.Duration) JWKSOption {
+	return func(k *jwks) { k.refreshInterval = d }
+}
+
+// JWKSMinRefreshInterval sets the minimum interval between fetches of the key
+// set, which are otherwise made whenever a token has an unknown key ID. It
+// stops tokens with bogus key IDs from flooding the provider with requests.
+// By default, it's DefaultJWKSMinRefreshInterval.
+func JWKSMinRefreshInterval(d time.Duration) JWKSOption {
+	return func(k *jwks) { k.minRefreshInterval = d }
+}
+
+// NewJWKSKeyFunc returns a jwt.Keyfunc, for use with NewParser, which looks up
+// the key for a token by its key ID (kid) header in the JSON Web Key Set
+// (RFC 7517) served at the URL, e.g. the jwks_uri of an OpenID Connect
+// provider. RSA, EC and Ed25519 signing keys are supported.
+//
+// The key set is fetched on first use, and cached. It's fetched again when
+// it's older than the refresh interval, and when a token has a key ID which
+// isn't in the set, such as after the provider has rotated its keys. Only the
+// first use, and tokens with unknown key IDs, wait for a fetch; once the set
+// is merely old, it's fetched in the background while the cached keys are
+// used. Concurrent fetches are collapsed into one. If a fetch fails, the
+// previously fetched keys continue to be used.
+func NewJWKSKeyFunc(url string, options ...JWKSOption) jwt.Keyfunc {
+	k := &jwks{
+		url:                url,
+		client:             &stdhttp.Client{Timeout: DefaultJWKSTimeout},
+		refreshInterval:    DefaultJWKSRefreshInterval,
+		minRefreshInterval: DefaultJWKSMinRefreshInterval,
+	}
+	for _, option := range options {
+		option(k)
+	}
+	return k.keyFunc
+}
+
+type jwks struct {
+	url                string
+	client             *stdhttp.Client
+	refreshInterval    time.Duration
+	minRefreshInterval time.Duration
+	group              singleflight.Group
+
+	mtx     sync.Mutex
+	keys    map[string]interface{} // by kid
+	fetched time.Time
+}
+
+func (k *jwks) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	keys, fetched := k.cached()
+	if keys == nil {
+		if err := k.refresh(); err != nil {
+			return nil, err
+		}
+		keys, fetched = k.cached()
+	} else if time.Since(fetched) > k.refreshInterval {
+		k.group.DoChan("", func() (interface{}, error) { return nil, k.fetch() })
+	}
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(fetched) > k.minRefreshInterval {
+		err := k.refresh()
+		keys, _ = k.cached()
+		if key, ok := keys[kid]; ok {
+			return key, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+// cached returns the cached keys, and when they were last fetched.
+func (k *jwks) cached() (map[string]interface{}, time.Time) {
+	k.mtx.Lock()
+	defer k.mtx.Unlock()
+	return k.keys, k.fetched
+}
+
+// refresh fetches the key set, or waits for the fetch in flight.
+func (k *jwks) refresh() error {
+	_, err, _ := k.group.Do("", func() (interface{}, error) { return nil, k.fetch() })
+	return err
+}
+
+// fetch fetches the key set, and replaces the cached keys with it. It's only
+// called via the group, so fetches don't overlap.
+func (k *jwks) fetch() error {
+	// Even if the fetch fails, don't retry before the min refresh interval.
+	k.mtx.Lock()
+	k.fetched = time.Now()
+	k.mtx.Unlock()
+
+	resp, err := k.client.Get(k.url)
+	if err != nil {
+		return fmt.Errorf("fetching JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != stdhttp.StatusOK {
+		return fmt.Errorf("fetching JWKS: %s", resp.Status)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("decoding JWKS: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue // skip keys we can't use, rather than the whole set
+		}
+		keys[jwk.Kid] = key
+	}
+	k.mtx.Lock()
+	k.keys = keys
+	k.mtx.Unlock()
+	return nil
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+var errUnsupportedKey = errors.New("unsupported JSON Web Key")
+
+func (jwk jsonWebKey) publicKey() (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errUnsupportedKey
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, errUnsupportedKey
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errUnsupportedKey
+		}
+		return ed25519.PublicKey(x), nil
+
+	default:
+		return nil, errUnsupportedKey
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
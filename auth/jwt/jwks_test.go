@@ -0,0 +1,176 @@
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+type jwksServer struct {
+	mtx     sync.Mutex
+	keys    map[string]*rsa.PrivateKey
+	fetches uint64
+}
+
+func (s *jwksServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddUint64(&s.fetches, 1)
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	var set struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	for kid, key := range s.keys {
+		set.Keys = append(set.Keys, map[string]string{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	json.NewEncoder(w).Encode(set)
+}
+
+func (s *jwksServer) rotate(t *testing.T, kid string) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.keys = map[string]*rsa.PrivateKey{kid: key}
+	return key
+}
+
+func signRS256(t *testing.T, kid string, key *rsa.PrivateKey) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"user": "go-kit"})
+	token.Header["kid"] = kid
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestJWKSKeyFunc(t *testing.T) {
+	var (
+		js     = &jwksServer{}
+		server = httptest.NewServer(js)
+		first  = js.rotate(t, "first")
+	)
+	defer server.Close()
+
+	e := func(ctx context.Context, i struct{}) (interface{}, error) { return ctx, nil }
+	parser := NewParser[struct{}, any](NewJWKSKeyFunc(server.URL, JWKSMinRefreshInterval(0)), jwt.SigningMethodRS256, MapClaimsFactory)(e)
+
+	ctx := context.WithValue(context.Background(), JWTContextKey, signRS256(t, "first", first))
+	if _, err := parser(ctx, struct{}{}); err != nil {
+		t.Fatalf("first key: %v", err)
+	}
+	if _, err := parser(ctx, struct{}{}); err != nil {
+		t.Fatalf("first key, cached: %v", err)
+	}
+	if want, have := uint64(1), atomic.LoadUint64(&js.fetches); want != have {
+		t.Errorf("fetches: want %d, have %d", want, have)
+	}
+
+	// After rotation, the unknown kid triggers a refresh.
+	second := js.rotate(t, "second")
+	ctx = context.WithValue(context.Background(), JWTContextKey, signRS256(t, "second", second))
+	if _, err := parser(ctx, struct{}{}); err != nil {
+		t.Fatalf("rotated key: %v", err)
+	}
+	if want, have := uint64(2), atomic.LoadUint64(&js.fetches); want != have {
+		t.Errorf("fetches: want %d, have %d", want, have)
+	}
+
+	// Tokens signed by keys no longer in the set are rejected.
+	ctx = context.WithValue(context.Background(), JWTContextKey, signRS256(t, "first", first))
+	if _, err := parser(ctx, struct{}{}); err != ErrKeyNotFound {
+		t.Errorf("want %v, have %v", ErrKeyNotFound, err)
+	}
+}
+
+func TestJWKSMinRefreshInterval(t *testing.T) {
+	var (
+		js     = &jwksServer{}
+		server = httptest.NewServer(js)
+		key    = js.rotate(t, "known")
+	)
+	defer server.Close()
+
+	keyFunc := NewJWKSKeyFunc(server.URL)
+	for i := 0; i < 3; i++ {
+		token, _ := jwt.Parse(signRS256(t, "unknown", key), keyFunc)
+		if token.Valid {
+			t.Fatal("token with unknown kid was valid")
+		}
+	}
+	if want, have := uint64(1), atomic.LoadUint64(&js.fetches); want != have {
+		t.Errorf("fetches: want %d, have %d", want, have)
+	}
+}
+
+func TestJWKSRefreshDoesNotBlock(t *testing.T) {
+	var (
+		js      = &jwksServer{}
+		release = make(chan struct{})
+		blocked = make(chan struct{}, 1)
+		server  = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.LoadUint64(&js.fetches) > 0 {
+				blocked <- struct{}{}
+				<-release
+			}
+			js.ServeHTTP(w, r)
+		}))
+		key = js.rotate(t, "known")
+	)
+	defer server.Close()
+	defer close(release)
+
+	keyFunc := NewJWKSKeyFunc(server.URL, JWKSRefreshInterval(time.Nanosecond))
+	token := signRS256(t, "known", key)
+	if _, err := jwt.Parse(token, keyFunc); err != nil {
+		t.Fatal(err)
+	}
+
+	// The set is now old, so the next use starts a fetch, which blocks. Uses
+	// meanwhile are served from the cached keys.
+	done := make(chan error)
+	go func() {
+		for i := 0; i < 3; i++ {
+			if _, err := jwt.Parse(token, keyFunc); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("key func blocked on the fetch in flight")
+	}
+	select {
+	case <-blocked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no fetch in the background")
+	}
+	if want, have := uint64(1), atomic.LoadUint64(&js.fetches); want != have {
+		t.Errorf("completed fetches: want %d, have %d", want, have)
+	}
+}
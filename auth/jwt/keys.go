@@ -0,0 +1,73 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrNoSigningKey denotes a KeyProvider has no key to sign tokens with.
+var ErrNoSigningKey = errors.New("no signing key available")
+
+// SigningKey is a key with which tokens are signed, along with its ID, which
+// is set as the token's Key ID header (kid), and its signing method. The type
+// of Key depends on the method, e.g. []byte for HMAC, or *rsa.PrivateKey for
+// RSA.
+type SigningKey struct {
+	ID     string
+	Key    interface{}
+	Method jwt.SigningMethod
+}
+
+// KeyProvider provides the key with which a token is signed.
+type KeyProvider interface {
+	SigningKey(ctx context.Context) (SigningKey, error)
+}
+
+// KeyProviderFunc is an adapter to allow the use of ordinary functions as
+// KeyProviders.
+type KeyProviderFunc func(ctx context.Context) (SigningKey, error)
+
+// SigningKey implements KeyProvider.
+func (f KeyProviderFunc) SigningKey(ctx context.Context) (SigningKey, error) {
+	return f(ctx)
+}
+
+// StaticKey returns a KeyProvider which always provides the same key.
+func StaticKey(key SigningKey) KeyProvider {
+	return KeyProviderFunc(func(context.Context) (SigningKey, error) { return key, nil })
+}
+
+// RotatingKeys is a KeyProvider whose key may be replaced at any time, e.g. by
+// a goroutine which periodically loads the current key from a secret store.
+// The zero value has no key, and yields ErrNoSigningKey until the first
+// Rotate.
+type RotatingKeys struct {
+	mtx sync.RWMutex
+	key *SigningKey
+}
+
+// NewRotatingKeys returns RotatingKeys with the initial key.
+func NewRotatingKeys(initial SigningKey) *RotatingKeys {
+	return &RotatingKeys{key: &initial}
+}
+
+// Rotate makes key the key with which tokens are signed from now on. Parsers
+// must know the new key, by its ID, before tokens signed with it reach them.
+func (r *RotatingKeys) Rotate(key SigningKey) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.key = &key
+}
+
+// SigningKey implements KeyProvider.
+func (r *RotatingKeys) SigningKey(context.Context) (SigningKey, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	if r.key == nil {
+		return SigningKey{}, ErrNoSigningKey
+	}
+	return *r.key, nil
+}
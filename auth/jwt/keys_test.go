@@ -0,0 +1,64 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+type subjectKey struct{}
+
+func TestRotatingSigner(t *testing.T) {
+	var (
+		keys   RotatingKeys
+		tokens []string
+		e      = func(ctx context.Context, i struct{}) (struct{}, error) {
+			tokens = append(tokens, ctx.Value(JWTContextKey).(string))
+			return struct{}{}, nil
+		}
+		newClaims = func(ctx context.Context) (jwt.Claims, error) {
+			sub, ok := ctx.Value(subjectKey{}).(string)
+			if !ok {
+				return nil, errors.New("no subject")
+			}
+			return jwt.MapClaims{"sub": sub}, nil
+		}
+		signer = NewRotatingSigner[struct{}, struct{}](&keys, newClaims)(e)
+		ctx    = context.WithValue(context.Background(), subjectKey{}, "alice")
+	)
+
+	if _, err := signer(ctx, struct{}{}); err != ErrNoSigningKey {
+		t.Errorf("want %v, have %v", ErrNoSigningKey, err)
+	}
+	if _, err := signer(context.Background(), struct{}{}); err == nil {
+		t.Error("want claims error, have nil")
+	}
+
+	first, second := []byte("first_key"), []byte("second_key")
+	keys.Rotate(SigningKey{ID: "1", Key: first, Method: method})
+	if _, err := signer(ctx, struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	keys.Rotate(SigningKey{ID: "2", Key: second, Method: method})
+	if _, err := signer(ctx, struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	byKID := map[string][]byte{"1": first, "2": second}
+	for i, tokenString := range tokens {
+		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+			return byKID[token.Header["kid"].(string)], nil
+		})
+		if err != nil {
+			t.Fatalf("token %d: %v", i, err)
+		}
+		if want, have := "alice", token.Claims.(jwt.MapClaims)["sub"]; want != have {
+			t.Errorf("token %d: sub: want %v, have %v", i, want, have)
+		}
+		if want, have := []string{"1", "2"}[i], token.Header["kid"]; want != have {
+			t.Errorf("token %d: kid: want %v, have %v", i, want, have)
+		}
+	}
+}
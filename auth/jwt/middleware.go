@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 
+	"github.com/barrett370/kit/v2/contextkey"
 	"github.com/barrett370/kit/v2/endpoint"
 	"github.com/golang-jwt/jwt/v4"
 )
@@ -12,18 +13,32 @@ type contextKey string
 
 const (
 	// JWTContextKey holds the key used to store a JWT in the context.
+	//
+	// Deprecated: use JWTTokenKey, which gets and sets the token without type
+	// assertions.
 	JWTContextKey contextKey = "JWTToken"
 
 	// JWTTokenContextKey is an alias for JWTContextKey.
 	//
-	// Deprecated: prefer JWTContextKey.
+	// Deprecated: prefer JWTTokenKey.
 	JWTTokenContextKey = JWTContextKey
 
 	// JWTClaimsContextKey holds the key used to store the JWT Claims in the
 	// context.
+	//
+	// Deprecated: use JWTClaimsKey.
 	JWTClaimsContextKey contextKey = "JWTClaims"
 )
 
+var (
+	// JWTTokenKey gets and sets the JWT in the context, under JWTContextKey.
+	JWTTokenKey = contextkey.For[string](JWTContextKey)
+
+	// JWTClaimsKey gets and sets the JWT Claims in the context, under
+	// JWTClaimsContextKey.
+	JWTClaimsKey = contextkey.For[jwt.Claims](JWTClaimsContextKey)
+)
+
 var (
 	// ErrTokenContextMissing denotes a token was not passed into the parsing
 	// middleware's context.
@@ -52,18 +67,42 @@ var (
 // Tokens are signed with a Key ID header (kid) which is useful for determining
 // the key to use for parsing. Particularly useful for clients.
 func NewSigner[I, O any](kid string, key []byte, method jwt.SigningMethod, claims jwt.Claims) endpoint.Middleware[I, O] {
+	return NewRotatingSigner[I, O](
+		StaticKey(SigningKey{ID: kid, Key: key, Method: method}),
+		func(context.Context) (jwt.Claims, error) { return claims, nil },
+	)
+}
+
+// NewRotatingSigner creates a new JWT generating middleware, which signs each
+// token with the current key of the KeyProvider, and with claims built per
+// request by the ContextClaimsFactory, e.g. from the caller's identity in the
+// context. Tokens are signed with the Key ID header (kid) of the key, so that
+// parsers can select the key to verify them with, even as keys are rotated.
+// Particularly useful for clients.
+func NewRotatingSigner[I, O any](keys KeyProvider, newClaims ContextClaimsFactory) endpoint.Middleware[I, O] {
 	return func(next endpoint.Endpoint[I, O]) endpoint.Endpoint[I, O] {
 		return func(ctx context.Context, request I) (response O, err error) {
-			token := jwt.NewWithClaims(method, claims)
-			token.Header["kid"] = kid
+			key, err := keys.SigningKey(ctx)
+			if err != nil {
+				var zero O
+				return zero, err
+			}
+			claims, err := newClaims(ctx)
+			if err != nil {
+				var zero O
+				return zero, err
+			}
+
+			token := jwt.NewWithClaims(key.Method, claims)
+			token.Header["kid"] = key.ID
 
 			// Sign and get the complete encoded token as a string using the secret
-			tokenString, err := token.SignedString(key)
+			tokenString, err := token.SignedString(key.Key)
 			if err != nil {
 				var zero O
 				return zero, err
 			}
-			ctx = context.WithValue(ctx, JWTContextKey, tokenString)
+			ctx = JWTTokenKey.Set(ctx, tokenString)
 
 			return next(ctx, request)
 		}
@@ -74,6 +113,10 @@ func NewSigner[I, O any](kid string, key []byte, method jwt.SigningMethod, claim
 // Useful in NewParser middleware.
 type ClaimsFactory func() jwt.Claims
 
+// ContextClaimsFactory builds the claims of a token for a request. Useful in
+// NewRotatingSigner middleware.
+type ContextClaimsFactory func(ctx context.Context) (jwt.Claims, error)
+
 // MapClaimsFactory is a ClaimsFactory that returns
 // an empty jwt.MapClaims.
 func MapClaimsFactory() jwt.Claims {
@@ -89,12 +132,18 @@ func StandardClaimsFactory() jwt.Claims {
 // NewParser creates a new JWT parsing middleware, specifying a
 // jwt.Keyfunc interface, the signing method and the claims type to be used. NewParser
 // adds the resulting claims to endpoint context or returns error on invalid token.
-// Particularly useful for servers.
-func NewParser[I, O any](keyFunc jwt.Keyfunc, method jwt.SigningMethod, newClaims ClaimsFactory) endpoint.Middleware[I, O] {
+// Particularly useful for servers. Options may require standard claims, such
+// as the issuer and audience, and scopes or roles.
+func NewParser[I, O any](keyFunc jwt.Keyfunc, method jwt.SigningMethod, newClaims ClaimsFactory, options ...ParserOption) endpoint.Middleware[I, O] {
+	var opts parserOptions
+	for _, option := range options {
+		option(&opts)
+	}
+	parser := &jwt.Parser{SkipClaimsValidation: opts.leeway > 0}
 	return func(next endpoint.Endpoint[I, O]) endpoint.Endpoint[I, O] {
 		return func(ctx context.Context, request I) (response O, err error) {
 			// tokenString is stored in the context from the transport handlers.
-			tokenString, ok := ctx.Value(JWTContextKey).(string)
+			tokenString, ok := JWTTokenKey.Get(ctx)
 			if !ok {
 				var zero O
 				return zero, ErrTokenContextMissing
@@ -106,7 +155,7 @@ func NewParser[I, O any](keyFunc jwt.Keyfunc, method jwt.SigningMethod, newClaim
 			// of the token to identify which key to use, but the parsed token
 			// (head and claims) is provided to the callback, providing
 			// flexibility.
-			token, err := jwt.ParseWithClaims(tokenString, newClaims(), func(token *jwt.Token) (interface{}, error) {
+			token, err := parser.ParseWithClaims(tokenString, newClaims(), func(token *jwt.Token) (interface{}, error) {
 				// Don't forget to validate the alg is what you expect:
 				if token.Method != method {
 					return nil, ErrUnexpectedSigningMethod
@@ -146,7 +195,14 @@ func NewParser[I, O any](keyFunc jwt.Keyfunc, method jwt.SigningMethod, newClaim
 				return zero, ErrTokenInvalid
 			}
 
-			ctx = context.WithValue(ctx, JWTClaimsContextKey, token.Claims)
+			if opts.enabled() {
+				if err := opts.validate(token.Claims); err != nil {
+					var zero O
+					return zero, err
+				}
+			}
+
+			ctx = JWTClaimsKey.Set(ctx, token.Claims)
 
 			return next(ctx, request)
 		}
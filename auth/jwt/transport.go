@@ -12,6 +12,10 @@ import (
 const (
 	bearer       string = "bearer"
 	bearerFormat string = "Bearer %s"
+
+	// authorizationKey is the metadata key which carries the JWT in transports
+	// other than HTTP. gRPC requires metadata keys to be lower-case.
+	authorizationKey = "authorization"
 )
 
 // HTTPToContext moves a JWT from request header to context. Particularly
@@ -23,7 +27,7 @@ func HTTPToContext() http.RequestFunc {
 			return ctx
 		}
 
-		return context.WithValue(ctx, JWTContextKey, token)
+		return JWTTokenKey.Set(ctx, token)
 	}
 }
 
@@ -31,7 +35,7 @@ func HTTPToContext() http.RequestFunc {
 // useful for clients.
 func ContextToHTTP() http.RequestFunc {
 	return func(ctx context.Context, r *stdhttp.Request) context.Context {
-		token, ok := ctx.Value(JWTContextKey).(string)
+		token, ok := JWTTokenKey.Get(ctx)
 		if ok {
 			r.Header.Add("Authorization", generateAuthHeaderFromToken(token))
 		}
@@ -39,6 +43,98 @@ func ContextToHTTP() http.RequestFunc {
 	}
 }
 
+// HeadersToContext moves a JWT from the authorization entry of a set of
+// headers to the context. The headers may be gRPC metadata (metadata.MD),
+// NATS headers (nats.Header), or any other map[string][]string; the key is
+// matched case-insensitively. Call it from the server before funcs, or
+// interceptors, of those transports, e.g.
+//
+//	md, _ := metadata.FromIncomingContext(ctx)
+//	ctx = jwt.HeadersToContext(ctx, md)
+//
+//	func(ctx context.Context, msg *nats.Msg) context.Context {
+//		return jwt.HeadersToContext(ctx, msg.Header)
+//	}
+//
+// Particularly useful for servers.
+func HeadersToContext(ctx context.Context, headers map[string][]string) context.Context {
+	for k, vs := range headers {
+		if !strings.EqualFold(k, authorizationKey) || len(vs) == 0 {
+			continue
+		}
+		if token, ok := extractTokenFromAuthHeader(vs[0]); ok {
+			return JWTTokenKey.Set(ctx, token)
+		}
+	}
+	return ctx
+}
+
+// ContextToHeaders moves a JWT from the context to the authorization entry of
+// a set of headers, such as gRPC metadata or NATS headers; see
+// HeadersToContext. It returns the headers, which are allocated if they're
+// nil and there's a JWT, e.g.
+//
+//	md, _ := metadata.FromOutgoingContext(ctx)
+//	ctx = metadata.NewOutgoingContext(ctx, jwt.ContextToHeaders(ctx, md.Copy()))
+//
+//	func(ctx context.Context, msg *nats.Msg) context.Context {
+//		msg.Header = jwt.ContextToHeaders(ctx, msg.Header)
+//		return ctx
+//	}
+//
+// Particularly useful for clients.
+func ContextToHeaders(ctx context.Context, headers map[string][]string) map[string][]string {
+	if token, ok := JWTTokenKey.Get(ctx); ok {
+		if headers == nil {
+			headers = map[string][]string{}
+		}
+		headers[authorizationKey] = []string{generateAuthHeaderFromToken(token)}
+	}
+	return headers
+}
+
+// TableToContext moves a JWT from the authorization entry of AMQP message
+// headers (amqp.Table) to the context, e.g.
+//
+//	func(ctx context.Context, d *amqp.Delivery) context.Context {
+//		return jwt.TableToContext(ctx, d.Headers)
+//	}
+//
+// Particularly useful for servers.
+func TableToContext(ctx context.Context, table map[string]interface{}) context.Context {
+	for k, v := range table {
+		if !strings.EqualFold(k, authorizationKey) {
+			continue
+		}
+		if s, ok := v.(string); ok {
+			if token, ok := extractTokenFromAuthHeader(s); ok {
+				return JWTTokenKey.Set(ctx, token)
+			}
+		}
+	}
+	return ctx
+}
+
+// ContextToTable moves a JWT from the context to the authorization entry of
+// AMQP message headers (amqp.Table). It returns the headers, which are
+// allocated if they're nil and there's a JWT, e.g.
+//
+//	func(ctx context.Context, pub *amqp.Publishing) context.Context {
+//		pub.Headers = jwt.ContextToTable(ctx, pub.Headers)
+//		return ctx
+//	}
+//
+// Particularly useful for clients.
+func ContextToTable(ctx context.Context, table map[string]interface{}) map[string]interface{} {
+	if token, ok := JWTTokenKey.Get(ctx); ok {
+		if table == nil {
+			table = map[string]interface{}{}
+		}
+		table[authorizationKey] = generateAuthHeaderFromToken(token)
+	}
+	return table
+}
+
 func extractTokenFromAuthHeader(val string) (token string, ok bool) {
 	authHeaderParts := strings.Split(val, " ")
 	if len(authHeaderParts) != 2 || !strings.EqualFold(authHeaderParts[0], bearer) {
@@ -60,3 +60,68 @@ func TestContextToHTTP(t *testing.T) {
 		t.Errorf("Authorization header does not contain the expected JWT; expected %s, got %s", expected, token)
 	}
 }
+
+func TestHeadersToContext(t *testing.T) {
+	// When the header doesn't exist
+	ctx := HeadersToContext(context.Background(), map[string][]string{})
+	if ctx.Value(JWTContextKey) != nil {
+		t.Error("Context shouldn't contain the encoded JWT")
+	}
+
+	// Authorization header value has invalid format
+	ctx = HeadersToContext(context.Background(), map[string][]string{"authorization": {"no expected auth header format value"}})
+	if ctx.Value(JWTContextKey) != nil {
+		t.Error("Context shouldn't contain the encoded JWT")
+	}
+
+	// Authorization header is correct, in gRPC and in NATS style
+	for _, key := range []string{"authorization", "Authorization"} {
+		ctx = HeadersToContext(context.Background(), map[string][]string{key: {generateAuthHeaderFromToken(signedKey)}})
+		if want, have := signedKey, ctx.Value(JWTContextKey); want != have {
+			t.Errorf("%s: want %v, have %v", key, want, have)
+		}
+	}
+}
+
+func TestContextToHeaders(t *testing.T) {
+	headers := map[string][]string{}
+	ContextToHeaders(context.Background(), headers)
+	if len(headers) != 0 {
+		t.Error("authorization key should not exist in metadata")
+	}
+
+	ctx := context.WithValue(context.Background(), JWTContextKey, signedKey)
+	ContextToHeaders(ctx, headers)
+
+	// Round trip
+	if want, have := signedKey, HeadersToContext(context.Background(), headers).Value(JWTContextKey); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestTable(t *testing.T) {
+	table := map[string]interface{}{}
+	ContextToTable(context.Background(), table)
+	if len(table) != 0 {
+		t.Error("authorization key should not exist in table")
+	}
+
+	ctx := context.WithValue(context.Background(), JWTContextKey, signedKey)
+	ContextToTable(ctx, table)
+	if want, have := signedKey, TableToContext(context.Background(), table).Value(JWTContextKey); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestNilHeaders(t *testing.T) {
+	ctx := context.WithValue(context.Background(), JWTContextKey, signedKey)
+	if want, have := signedKey, HeadersToContext(context.Background(), ContextToHeaders(ctx, nil)).Value(JWTContextKey); want != have {
+		t.Errorf("headers: want %v, have %v", want, have)
+	}
+	if want, have := signedKey, TableToContext(context.Background(), ContextToTable(ctx, nil)).Value(JWTContextKey); want != have {
+		t.Errorf("table: want %v, have %v", want, have)
+	}
+	if headers := ContextToHeaders(context.Background(), nil); headers != nil {
+		t.Errorf("want nil headers without a JWT, have %v", headers)
+	}
+}
@@ -0,0 +1,227 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	stdhttp "net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	// ErrTokenInvalidIssuer denotes a token's issuer (iss) is not one of the
+	// issuers required by ParserIssuer.
+	ErrTokenInvalidIssuer = errors.New("JWT issuer is not accepted")
+
+	// ErrTokenInvalidAudience denotes a token's audience (aud) doesn't include
+	// any of the audiences required by ParserAudience.
+	ErrTokenInvalidAudience = errors.New("JWT audience is not accepted")
+)
+
+// InsufficientClaimsError denotes a valid token which lacks values required
+// by ParserRequireClaimValues or ParserRequireScopes, e.g. a scope or role.
+// Unlike the other errors returned by the parser, which mean the client isn't
+// authenticated, it means the client isn't authorized, so its StatusCode is
+// 403 Forbidden.
+type InsufficientClaimsError struct {
+	Path    string   // path of the claim, e.g. "scope"
+	Missing []string // required values which the claim lacks
+}
+
+// Error implements error.
+func (e *InsufficientClaimsError) Error() string {
+	return fmt.Sprintf("JWT claim %s lacks %s", e.Path, strings.Join(e.Missing, ", "))
+}
+
+// StatusCode implements the http transport's StatusCoder, so that the error
+// is encoded as 403 Forbidden.
+func (e *InsufficientClaimsError) StatusCode() int {
+	return stdhttp.StatusForbidden
+}
+
+// ParserOption sets an optional parameter for NewParser.
+type ParserOption func(*parserOptions)
+
+type parserOptions struct {
+	issuers   []string
+	audiences []string
+	leeway    time.Duration
+	required  []requiredClaim
+}
+
+type requiredClaim struct {
+	path   string
+	values []string
+}
+
+// ParserIssuer requires the token's issuer (iss) to be one of the issuers.
+// Otherwise, the parser returns ErrTokenInvalidIssuer.
+func ParserIssuer(issuers ...string) ParserOption {
+	return func(o *parserOptions) { o.issuers = append(o.issuers, issuers...) }
+}
+
+// ParserAudience requires the token's audience (aud) to include at least one
+// of the audiences. Otherwise, the parser returns ErrTokenInvalidAudience.
+func ParserAudience(audiences ...string) ParserOption {
+	return func(o *parserOptions) { o.audiences = append(o.audiences, audiences...) }
+}
+
+// ParserLeeway allows for clock skew between the issuer and the parser when
+// validating the token's expiry (exp) and not before (nbf) times. If it's
+// set, the claims' own Valid method isn't called, as it knows no leeway.
+func ParserLeeway(d time.Duration) ParserOption {
+	return func(o *parserOptions) { o.leeway = d }
+}
+
+// ParserRequireClaimValues requires the claim at the path to contain all of
+// the values. The path is a dot-separated list of keys, e.g.
+// "realm_access.roles", and the claim may be either an array of strings, or a
+// string of space-separated values. Otherwise, the parser returns an
+// *InsufficientClaimsError.
+func ParserRequireClaimValues(path string, values ...string) ParserOption {
+	return func(o *parserOptions) { o.required = append(o.required, requiredClaim{path, values}) }
+}
+
+// ParserRequireScopes requires the token's scope claim (RFC 8693) to contain
+// all of the scopes. It's shorthand for ParserRequireClaimValues("scope",
+// scopes...).
+func ParserRequireScopes(scopes ...string) ParserOption {
+	return ParserRequireClaimValues("scope", scopes...)
+}
+
+func (o *parserOptions) enabled() bool {
+	return len(o.issuers) > 0 || len(o.audiences) > 0 || o.leeway > 0 || len(o.required) > 0
+}
+
+// validate validates the claims of a token, whose signature has been verified,
+// against the options.
+func (o *parserOptions) validate(claims jwt.Claims) error {
+	m, err := toMapClaims(claims)
+	if err != nil {
+		return err
+	}
+
+	if o.leeway > 0 {
+		now := time.Now()
+		if exp, ok := numericDate(m["exp"]); ok && now.After(exp.Add(o.leeway)) {
+			return ErrTokenExpired
+		}
+		if nbf, ok := numericDate(m["nbf"]); ok && now.Before(nbf.Add(-o.leeway)) {
+			return ErrTokenNotActive
+		}
+	}
+
+	if len(o.issuers) > 0 {
+		iss, _ := m["iss"].(string)
+		if !contains(o.issuers, iss) {
+			return ErrTokenInvalidIssuer
+		}
+	}
+
+	if len(o.audiences) > 0 {
+		auds := claimValues(m["aud"])
+		if aud, ok := m["aud"].(string); ok {
+			auds = []string{aud} // a single audience, not space-separated
+		}
+		var ok bool
+		for _, aud := range auds {
+			if contains(o.audiences, aud) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return ErrTokenInvalidAudience
+		}
+	}
+
+	for _, r := range o.required {
+		have := claimValues(lookup(m, r.path))
+		var missing []string
+		for _, v := range r.values {
+			if !contains(have, v) {
+				missing = append(missing, v)
+			}
+		}
+		if len(missing) > 0 {
+			return &InsufficientClaimsError{Path: r.path, Missing: missing}
+		}
+	}
+
+	return nil
+}
+
+// toMapClaims returns the claims as MapClaims, round-tripping them through
+// JSON if they're of another type, e.g. a struct embedding StandardClaims.
+func toMapClaims(claims jwt.Claims) (jwt.MapClaims, error) {
+	if m, ok := claims.(jwt.MapClaims); ok {
+		return m, nil
+	}
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+	var m jwt.MapClaims
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func numericDate(v interface{}) (time.Time, bool) {
+	switch n := v.(type) {
+	case float64:
+		return time.Unix(int64(n), 0), true
+	case json.Number:
+		f, err := n.Float64()
+		return time.Unix(int64(f), 0), err == nil
+	default:
+		return time.Time{}, false
+	}
+}
+
+// lookup returns the value at the dot-separated path in the claims, or nil.
+func lookup(m map[string]interface{}, path string) interface{} {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := m[key].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		m = next
+	}
+	return m[keys[len(keys)-1]]
+}
+
+// claimValues returns the values of a claim which is either a string of
+// space-separated values, or an array of strings.
+func claimValues(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	case []string:
+		return v
+	default:
+		return nil
+	}
+}
+
+func contains(a []string, s string) bool {
+	for _, e := range a {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
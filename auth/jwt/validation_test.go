@@ -0,0 +1,114 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestParserOptions(t *testing.T) {
+	e := func(ctx context.Context, i struct{}) (interface{}, error) { return ctx, nil }
+	keys := func(token *jwt.Token) (interface{}, error) { return key, nil }
+	sign := func(claims jwt.MapClaims) context.Context {
+		s, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return context.WithValue(context.Background(), JWTContextKey, s)
+	}
+	now := time.Now()
+
+	for _, tc := range []struct {
+		name    string
+		options []ParserOption
+		claims  jwt.MapClaims
+		want    error
+	}{
+		{
+			name:    "issuer",
+			options: []ParserOption{ParserIssuer("https://a.example.com", "https://b.example.com")},
+			claims:  jwt.MapClaims{"iss": "https://b.example.com"},
+		},
+		{
+			name:    "wrong issuer",
+			options: []ParserOption{ParserIssuer("https://a.example.com")},
+			claims:  jwt.MapClaims{"iss": "https://evil.example.com"},
+			want:    ErrTokenInvalidIssuer,
+		},
+		{
+			name:    "audience",
+			options: []ParserOption{ParserAudience("api")},
+			claims:  jwt.MapClaims{"aud": []string{"web", "api"}},
+		},
+		{
+			name:    "wrong audience",
+			options: []ParserOption{ParserAudience("api")},
+			claims:  jwt.MapClaims{"aud": "web"},
+			want:    ErrTokenInvalidAudience,
+		},
+		{
+			name:    "expired within leeway",
+			options: []ParserOption{ParserLeeway(time.Minute)},
+			claims:  jwt.MapClaims{"exp": now.Add(-30 * time.Second).Unix()},
+		},
+		{
+			name:    "expired beyond leeway",
+			options: []ParserOption{ParserLeeway(time.Minute)},
+			claims:  jwt.MapClaims{"exp": now.Add(-2 * time.Minute).Unix()},
+			want:    ErrTokenExpired,
+		},
+		{
+			name:    "not yet active within leeway",
+			options: []ParserOption{ParserLeeway(time.Minute)},
+			claims:  jwt.MapClaims{"nbf": now.Add(30 * time.Second).Unix()},
+		},
+		{
+			name:    "scopes",
+			options: []ParserOption{ParserRequireScopes("read", "write")},
+			claims:  jwt.MapClaims{"scope": "openid read write"},
+		},
+		{
+			name:    "roles",
+			options: []ParserOption{ParserRequireClaimValues("realm_access.roles", "admin")},
+			claims:  jwt.MapClaims{"realm_access": map[string]interface{}{"roles": []string{"user", "admin"}}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := NewParser[struct{}, any](keys, method, MapClaimsFactory, tc.options...)(e)
+			if _, have := parser(sign(tc.claims), struct{}{}); tc.want != have {
+				t.Errorf("want %v, have %v", tc.want, have)
+			}
+		})
+	}
+}
+
+func TestInsufficientClaims(t *testing.T) {
+	e := func(ctx context.Context, i struct{}) (interface{}, error) { return ctx, nil }
+	keys := func(token *jwt.Token) (interface{}, error) { return key, nil }
+	s, err := jwt.NewWithClaims(method, jwt.MapClaims{"scope": "read"}).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.WithValue(context.Background(), JWTContextKey, s)
+
+	parser := NewParser[struct{}, any](keys, method, MapClaimsFactory, ParserRequireScopes("read", "write", "admin"))(e)
+	_, err = parser(ctx, struct{}{})
+
+	var ice *InsufficientClaimsError
+	if !errors.As(err, &ice) {
+		t.Fatalf("want *InsufficientClaimsError, have %v", err)
+	}
+	if want, have := "scope", ice.Path; want != have {
+		t.Errorf("path: want %q, have %q", want, have)
+	}
+	if want, have := 2, len(ice.Missing); want != have {
+		t.Errorf("missing: want %d, have %d (%v)", want, have, ice.Missing)
+	}
+	if want, have := http.StatusForbidden, ice.StatusCode(); want != have {
+		t.Errorf("status code: want %d, have %d", want, have)
+	}
+}
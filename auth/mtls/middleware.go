@@ -0,0 +1,130 @@
+package mtls
+
+import (
+	"context"
+	stdhttp "net/http"
+
+	"github.com/barrett370/kit/v2/endpoint"
+)
+
+// authError is returned for requests which aren't authorized. Its StatusCode
+// makes the http transport encode it accordingly.
+type authError struct {
+	msg    string
+	status int
+}
+
+func (e authError) Error() string { return e.msg }
+
+// StatusCode implements the http transport's StatusCoder.
+func (e authError) StatusCode() int { return e.status }
+
+var (
+	// ErrNoIdentity denotes a request without a verified client
+	// certificate. Its StatusCode is 401 Unauthorized.
+	ErrNoIdentity error = authError{"no verified client certificate", stdhttp.StatusUnauthorized}
+
+	// ErrForbidden denotes a client whose identity isn't allowed by the
+	// policy. Its StatusCode is 403 Forbidden.
+	ErrForbidden error = authError{"client identity is not allowed", stdhttp.StatusForbidden}
+)
+
+// Policy decides whether a client identity is allowed to make a request. It
+// returns nil to allow it, and typically ErrForbidden to deny it.
+type Policy func(ctx context.Context, id *Identity) error
+
+// NewMiddleware returns an endpoint middleware which authorizes requests by
+// the client identity stored in the context by HTTPToContext. Requests
+// without an identity fail with ErrNoIdentity.
+func NewMiddleware[I, O any](policy Policy) endpoint.Middleware[I, O] {
+	return func(next endpoint.Endpoint[I, O]) endpoint.Endpoint[I, O] {
+		return func(ctx context.Context, request I) (O, error) {
+			id, ok := IdentityFromContext(ctx)
+			if !ok {
+				var zero O
+				return zero, ErrNoIdentity
+			}
+			if err := policy(ctx, id); err != nil {
+				var zero O
+				return zero, err
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// AllowSPIFFEIDs returns a Policy which allows clients with one of the SPIFFE
+// IDs, e.g. "spiffe://example.org/ns/prod/sa/orders".
+func AllowSPIFFEIDs(ids ...string) Policy {
+	allowed := set(ids)
+	return func(ctx context.Context, id *Identity) error {
+		if id.SPIFFEID == nil || !allowed[id.SPIFFEID.String()] {
+			return ErrForbidden
+		}
+		return nil
+	}
+}
+
+// AllowTrustDomains returns a Policy which allows clients with a SPIFFE ID in
+// one of the trust domains, e.g. "example.org".
+func AllowTrustDomains(domains ...string) Policy {
+	allowed := set(domains)
+	return func(ctx context.Context, id *Identity) error {
+		if domain := id.TrustDomain(); domain == "" || !allowed[domain] {
+			return ErrForbidden
+		}
+		return nil
+	}
+}
+
+// AllowCommonNames returns a Policy which allows clients whose certificate
+// has one of the subject common names.
+func AllowCommonNames(names ...string) Policy {
+	allowed := set(names)
+	return func(ctx context.Context, id *Identity) error {
+		if !allowed[id.CommonName] {
+			return ErrForbidden
+		}
+		return nil
+	}
+}
+
+// AllowDNSNames returns a Policy which allows clients whose certificate has a
+// DNS SAN which is one of the names.
+func AllowDNSNames(names ...string) Policy {
+	allowed := set(names)
+	return func(ctx context.Context, id *Identity) error {
+		for _, name := range id.DNSNames {
+			if allowed[name] {
+				return nil
+			}
+		}
+		return ErrForbidden
+	}
+}
+
+// Any returns a Policy which allows clients allowed by any of the policies.
+// If all of them deny a client, the first policy's error is returned.
+func Any(policies ...Policy) Policy {
+	return func(ctx context.Context, id *Identity) error {
+		first := ErrForbidden
+		for i, policy := range policies {
+			err := policy(ctx, id)
+			if err == nil {
+				return nil
+			}
+			if i == 0 {
+				first = err
+			}
+		}
+		return first
+	}
+}
+
+func set(values []string) map[string]bool {
+	m := make(map[string]bool, len(values))
+	for _, v := range values {
+		m[v] = true
+	}
+	return m
+}
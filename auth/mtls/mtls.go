@@ -0,0 +1,83 @@
+// Package mtls authorizes requests by the identity in the client certificate
+// of a mutually authenticated TLS connection, e.g. the SPIFFE ID of a
+// workload in a zero-trust network.
+//
+// The server must verify client certificates, e.g. by setting ClientAuth to
+// tls.RequireAndVerifyClientCert in its tls.Config; only verified
+// certificates are considered.
+package mtls
+
+import (
+	"context"
+	"crypto/x509"
+	stdhttp "net/http"
+	"net/url"
+
+	"github.com/barrett370/kit/v2/contextkey"
+	"github.com/barrett370/kit/v2/transport/http"
+)
+
+// IdentityContextKey holds the key used to store the client's *Identity in
+// the context.
+var IdentityContextKey = contextkey.New[*Identity]("MTLSIdentity")
+
+// Identity is the identity asserted by a verified client certificate.
+type Identity struct {
+	// SPIFFEID is the certificate's spiffe:// URI SAN, or nil if it has
+	// none.
+	SPIFFEID *url.URL
+
+	CommonName     string
+	DNSNames       []string
+	EmailAddresses []string
+	URIs           []*url.URL
+
+	Certificate *x509.Certificate
+}
+
+// TrustDomain returns the trust domain of the SPIFFE ID, e.g. "example.org"
+// for spiffe://example.org/ns/prod/sa/orders, or "" if there is none.
+func (id *Identity) TrustDomain() string {
+	if id.SPIFFEID == nil {
+		return ""
+	}
+	return id.SPIFFEID.Host
+}
+
+// NewIdentity returns the identity asserted by the certificate.
+func NewIdentity(cert *x509.Certificate) *Identity {
+	id := &Identity{
+		CommonName:     cert.Subject.CommonName,
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		URIs:           cert.URIs,
+		Certificate:    cert,
+	}
+	for _, uri := range cert.URIs {
+		if uri.Scheme == "spiffe" {
+			id.SPIFFEID = uri
+			break
+		}
+	}
+	return id
+}
+
+// HTTPToContext returns a RequestFunc, for use with ServerBefore, which
+// stores the identity of the client's verified certificate in the context
+// under IdentityContextKey. Requests over plain connections, or whose
+// certificate wasn't verified, are left without an identity.
+func HTTPToContext() http.RequestFunc {
+	return func(ctx context.Context, r *stdhttp.Request) context.Context {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			return ctx
+		}
+		return IdentityContextKey.Set(ctx, NewIdentity(r.TLS.VerifiedChains[0][0]))
+	}
+}
+
+// IdentityFromContext returns the identity stored in the context by
+// HTTPToContext, if any.
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := IdentityContextKey.Get(ctx)
+	return id, ok
+}
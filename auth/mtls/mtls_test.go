@@ -0,0 +1,121 @@
+package mtls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func newCertificate(t *testing.T, cn string, uris ...string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{cn + ".internal"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	for _, s := range uris {
+		u, err := url.Parse(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		template.URIs = append(template.URIs, u)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func requestWith(cert *x509.Certificate, verified bool) *http.Request {
+	r := httptest.NewRequest("GET", "https://orders.internal/", nil)
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	if verified {
+		r.TLS.VerifiedChains = [][]*x509.Certificate{{cert}}
+	}
+	return r
+}
+
+func TestHTTPToContext(t *testing.T) {
+	cert := newCertificate(t, "orders", "https://example.org/docs", "spiffe://example.org/ns/prod/sa/orders")
+
+	ctx := HTTPToContext()(context.Background(), requestWith(cert, true))
+	id, ok := IdentityFromContext(ctx)
+	if !ok {
+		t.Fatal("no identity in context")
+	}
+	if want, have := "spiffe://example.org/ns/prod/sa/orders", id.SPIFFEID.String(); want != have {
+		t.Errorf("SPIFFE ID: want %q, have %q", want, have)
+	}
+	if want, have := "example.org", id.TrustDomain(); want != have {
+		t.Errorf("trust domain: want %q, have %q", want, have)
+	}
+	if want, have := "orders", id.CommonName; want != have {
+		t.Errorf("common name: want %q, have %q", want, have)
+	}
+
+	// Unverified certificates are ignored.
+	ctx = HTTPToContext()(context.Background(), requestWith(cert, false))
+	if _, ok := IdentityFromContext(ctx); ok {
+		t.Error("identity from unverified certificate")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var (
+		orders  = newCertificate(t, "orders", "spiffe://example.org/ns/prod/sa/orders")
+		billing = newCertificate(t, "billing", "spiffe://example.org/ns/prod/sa/billing")
+		foreign = newCertificate(t, "orders", "spiffe://evil.com/ns/prod/sa/orders")
+		legacy  = newCertificate(t, "legacy")
+	)
+	e := func(ctx context.Context, i struct{}) (struct{}, error) { return i, nil }
+
+	for _, tc := range []struct {
+		name   string
+		policy Policy
+		cert   *x509.Certificate
+		want   error
+	}{
+		{"SPIFFE ID", AllowSPIFFEIDs("spiffe://example.org/ns/prod/sa/orders"), orders, nil},
+		{"other SPIFFE ID", AllowSPIFFEIDs("spiffe://example.org/ns/prod/sa/orders"), billing, ErrForbidden},
+		{"trust domain", AllowTrustDomains("example.org"), billing, nil},
+		{"other trust domain", AllowTrustDomains("example.org"), foreign, ErrForbidden},
+		{"no SPIFFE ID", AllowTrustDomains("example.org"), legacy, ErrForbidden},
+		{"common name", AllowCommonNames("legacy"), legacy, nil},
+		{"DNS name", AllowDNSNames("legacy.internal"), legacy, nil},
+		{"any", Any(AllowTrustDomains("example.org"), AllowCommonNames("legacy")), legacy, nil},
+		{"none", Any(AllowTrustDomains("example.org"), AllowCommonNames("legacy")), foreign, ErrForbidden},
+		{"no identity", AllowCommonNames("orders"), nil, ErrNoIdentity},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.cert != nil {
+				ctx = HTTPToContext()(ctx, requestWith(tc.cert, true))
+			}
+			_, err := NewMiddleware[struct{}, struct{}](tc.policy)(e)(ctx, struct{}{})
+			if want, have := tc.want, err; want != have {
+				t.Errorf("want %v, have %v", want, have)
+			}
+		})
+	}
+}
@@ -0,0 +1,292 @@
+// Package oauth2 provides OAuth 2.0 client authentication for outgoing
+// requests. Tokens are obtained from an authorization server via the client
+// credentials grant (RFC 6749, section 4.4) or the token exchange grant
+// (RFC 8693), cached until shortly before they expire, and attached to
+// requests as Authorization headers.
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	stdhttp "net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Defaults for token sources.
+const (
+	DefaultExpiryDelta = 10 * time.Second
+	DefaultTimeout     = 10 * time.Second
+)
+
+// Token type URIs for the token exchange grant, from RFC 8693, section 3.
+const (
+	AccessTokenType = "urn:ietf:params:oauth:token-type:access_token"
+	IDTokenType     = "urn:ietf:params:oauth:token-type:id_token"
+	JWTTokenType    = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+const (
+	grantTypeClientCredentials = "client_credentials"
+	grantTypeTokenExchange     = "urn:ietf:params:oauth:grant-type:token-exchange"
+)
+
+// Token is an access token issued by an authorization server.
+type Token struct {
+	AccessToken string
+	TokenType   string    // e.g. "Bearer"
+	Expiry      time.Time // zero if the token doesn't expire
+}
+
+// Header returns the value of the Authorization header which carries the
+// token, e.g. "Bearer mF_9.B5f-4.1JqM".
+func (t *Token) Header() string {
+	typ := t.TokenType
+	if typ == "" || strings.EqualFold(typ, "bearer") {
+		typ = "Bearer"
+	}
+	return typ + " " + t.AccessToken
+}
+
+// valid reports whether the token can still be used for at least delta.
+func (t *Token) valid(delta time.Duration) bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || time.Now().Add(delta).Before(t.Expiry))
+}
+
+// Error is an error response from the token endpoint (RFC 6749, section 5.2).
+type Error struct {
+	Status      int    // HTTP status code of the response
+	Code        string // e.g. "invalid_client"
+	Description string
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("oauth2: token endpoint returned %d %s", e.Status, stdhttp.StatusText(e.Status))
+	}
+	if e.Description == "" {
+		return fmt.Sprintf("oauth2: token endpoint returned %s", e.Code)
+	}
+	return fmt.Sprintf("oauth2: token endpoint returned %s: %s", e.Code, e.Description)
+}
+
+// TokenSource supplies tokens for outgoing requests.
+type TokenSource interface {
+	Token(ctx context.Context) (*Token, error)
+}
+
+// Config identifies the client to the authorization server.
+type Config struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+
+	// EndpointParams are additional parameters sent to the token endpoint,
+	// e.g. an audience or resource.
+	EndpointParams url.Values
+}
+
+// Option sets an optional parameter for token sources.
+type Option func(*source)
+
+// HTTPClient sets the client used to request tokens. By default, a client
+// with a timeout of DefaultTimeout is used.
+func HTTPClient(client *stdhttp.Client) Option {
+	return func(s *source) { s.client = client }
+}
+
+// ExpiryDelta sets how long before its expiry a token is renewed, so that it
+// doesn't expire in flight. By default, it's DefaultExpiryDelta.
+func ExpiryDelta(d time.Duration) Option {
+	return func(s *source) { s.expiryDelta = d }
+}
+
+// ClientSecretInParams sends the client ID and secret as form parameters,
+// for authorization servers which don't support HTTP Basic authentication
+// of clients. By default, Basic authentication is used.
+func ClientSecretInParams() Option {
+	return func(s *source) { s.secretInParams = true }
+}
+
+// SubjectTokenFunc returns the token to be exchanged for the request
+// represented by the context, e.g. the incoming JWT, and its type, e.g.
+// JWTTokenType.
+type SubjectTokenFunc func(ctx context.Context) (token, tokenType string, err error)
+
+// ClientCredentials returns a TokenSource which obtains tokens for the client
+// itself via the client credentials grant. A token is cached, and shared by
+// all requests, until shortly before it expires.
+func ClientCredentials(cfg Config, options ...Option) TokenSource {
+	return newSource(cfg, nil, options)
+}
+
+// TokenExchange returns a TokenSource which exchanges the subject token of
+// each request, returned by subject, for a token via the token exchange
+// grant, e.g. to call a downstream service on behalf of the caller. Exchanged
+// tokens are cached per subject token, until shortly before they expire.
+func TokenExchange(cfg Config, subject SubjectTokenFunc, options ...Option) TokenSource {
+	return newSource(cfg, subject, options)
+}
+
+func newSource(cfg Config, subject SubjectTokenFunc, options []Option) *source {
+	s := &source{
+		cfg:         cfg,
+		subject:     subject,
+		client:      &stdhttp.Client{Timeout: DefaultTimeout},
+		expiryDelta: DefaultExpiryDelta,
+		tokens:      map[string]*Token{},
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+type source struct {
+	cfg            Config
+	subject        SubjectTokenFunc
+	client         *stdhttp.Client
+	expiryDelta    time.Duration
+	secretInParams bool
+
+	group  singleflight.Group
+	mtx    sync.Mutex
+	tokens map[string]*Token // by subject token
+}
+
+// Token implements TokenSource. Concurrent requests for a token which isn't
+// cached share a single request to the token endpoint, made with the context
+// of the first of them.
+func (s *source) Token(ctx context.Context) (*Token, error) {
+	params := url.Values{}
+	var key string
+	if s.subject == nil {
+		params.Set("grant_type", grantTypeClientCredentials)
+	} else {
+		token, tokenType, err := s.subject(ctx)
+		if err != nil {
+			return nil, err
+		}
+		params.Set("grant_type", grantTypeTokenExchange)
+		params.Set("subject_token", token)
+		params.Set("subject_token_type", tokenType)
+		key = tokenType + " " + token
+	}
+
+	if t := s.cached(key); t != nil {
+		return t, nil
+	}
+	v, err, _ := s.group.Do(key, func() (interface{}, error) {
+		if t := s.cached(key); t != nil {
+			return t, nil
+		}
+		t, err := s.fetch(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		s.store(key, t)
+		return t, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*Token), nil
+}
+
+func (s *source) cached(key string) *Token {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if t := s.tokens[key]; t.valid(s.expiryDelta) {
+		return t
+	}
+	return nil
+}
+
+func (s *source) store(key string, t *Token) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	// Drop expired tokens, so that exchanged tokens don't accumulate.
+	for k, old := range s.tokens {
+		if !old.valid(0) {
+			delete(s.tokens, k)
+		}
+	}
+	s.tokens[key] = t
+}
+
+func (s *source) fetch(ctx context.Context, params url.Values) (*Token, error) {
+	if len(s.cfg.Scopes) > 0 {
+		params.Set("scope", strings.Join(s.cfg.Scopes, " "))
+	}
+	for k, vs := range s.cfg.EndpointParams {
+		params[k] = vs
+	}
+	if s.secretInParams {
+		params.Set("client_id", s.cfg.ClientID)
+		if s.cfg.ClientSecret != "" {
+			params.Set("client_secret", s.cfg.ClientSecret)
+		}
+	}
+
+	req, err := stdhttp.NewRequestWithContext(ctx, "POST", s.cfg.TokenURL, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if !s.secretInParams {
+		// RFC 6749, section 2.3.1: the credentials are form-encoded first.
+		req.SetBasicAuth(url.QueryEscape(s.cfg.ClientID), url.QueryEscape(s.cfg.ClientSecret))
+	}
+
+	begin := time.Now()
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: requesting token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: reading token response: %w", err)
+	}
+
+	if resp.StatusCode != stdhttp.StatusOK {
+		e := &Error{Status: resp.StatusCode}
+		var errResp struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		if json.Unmarshal(body, &errResp) == nil {
+			e.Code, e.Description = errResp.Error, errResp.Description
+		}
+		return nil, e
+	}
+
+	var tokenResp struct {
+		AccessToken string      `json:"access_token"`
+		TokenType   string      `json:"token_type"`
+		ExpiresIn   json.Number `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return nil, fmt.Errorf("oauth2: decoding token response: %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return nil, fmt.Errorf("oauth2: token response has no access_token")
+	}
+	t := &Token{AccessToken: tokenResp.AccessToken, TokenType: tokenResp.TokenType}
+	// Some servers send expires_in as a string, hence json.Number.
+	if secs, err := strconv.ParseInt(tokenResp.ExpiresIn.String(), 10, 64); err == nil && secs > 0 {
+		t.Expiry = begin.Add(time.Duration(secs) * time.Second)
+	}
+	return t, nil
+}
@@ -0,0 +1,127 @@
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type tokenServer struct {
+	requests  uint64
+	expiresIn int
+	delay     time.Duration
+}
+
+func (s *tokenServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := atomic.AddUint64(&s.requests, 1)
+	time.Sleep(s.delay)
+	if id, secret, _ := r.BasicAuth(); id != "client" || secret != "s%3Acret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	}
+	token := r.FormValue("grant_type") + "-" + r.FormValue("subject_token") + "-" + string(rune('0'+n))
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": token,
+		"token_type":   "bearer",
+		"expires_in":   s.expiresIn,
+	})
+}
+
+func TestClientCredentials(t *testing.T) {
+	ts := &tokenServer{expiresIn: 3600, delay: 10 * time.Millisecond}
+	server := httptest.NewServer(ts)
+	defer server.Close()
+
+	source := ClientCredentials(Config{TokenURL: server.URL, ClientID: "client", ClientSecret: "s:cret"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := source.Token(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if want, have := "Bearer client_credentials--1", token.Header(); want != have {
+				t.Errorf("want %q, have %q", want, have)
+			}
+		}()
+	}
+	wg.Wait()
+	if want, have := uint64(1), atomic.LoadUint64(&ts.requests); want != have {
+		t.Errorf("requests: want %d, have %d", want, have)
+	}
+}
+
+func TestRenewal(t *testing.T) {
+	ts := &tokenServer{expiresIn: 5}
+	server := httptest.NewServer(ts)
+	defer server.Close()
+
+	// Tokens expiring within the delta are renewed before use.
+	source := ClientCredentials(Config{TokenURL: server.URL, ClientID: "client", ClientSecret: "s:cret"}, ExpiryDelta(time.Minute))
+	for i := 0; i < 2; i++ {
+		if _, err := source.Token(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want, have := uint64(2), atomic.LoadUint64(&ts.requests); want != have {
+		t.Errorf("requests: want %d, have %d", want, have)
+	}
+}
+
+func TestTokenExchange(t *testing.T) {
+	ts := &tokenServer{expiresIn: 3600}
+	server := httptest.NewServer(ts)
+	defer server.Close()
+
+	type subjectKey struct{}
+	subject := func(ctx context.Context) (string, string, error) {
+		return ctx.Value(subjectKey{}).(string), JWTTokenType, nil
+	}
+	source := TokenExchange(Config{TokenURL: server.URL, ClientID: "client", ClientSecret: "s:cret"}, subject)
+
+	for _, tc := range []struct {
+		subject string
+		want    string
+	}{
+		{"alice", grantTypeTokenExchange + "-alice-1"},
+		{"bob", grantTypeTokenExchange + "-bob-2"},
+		{"alice", grantTypeTokenExchange + "-alice-1"},
+	} {
+		token, err := source.Token(context.WithValue(context.Background(), subjectKey{}, tc.subject))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, have := tc.want, token.AccessToken; want != have {
+			t.Errorf("%s: want %q, have %q", tc.subject, want, have)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	server := httptest.NewServer(&tokenServer{})
+	defer server.Close()
+
+	source := ClientCredentials(Config{TokenURL: server.URL, ClientID: "client", ClientSecret: "wrong"})
+	_, err := source.Token(context.Background())
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("want *Error, have %v", err)
+	}
+	if want, have := "invalid_client", e.Code; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := http.StatusUnauthorized, e.Status; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
@@ -0,0 +1,52 @@
+package oauth2
+
+import (
+	"context"
+	stdhttp "net/http"
+
+	"github.com/barrett370/kit/v2/contextkey"
+	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/transport/http"
+)
+
+// TokenContextKey holds the key used to store the *Token obtained by
+// Middleware in the context.
+var TokenContextKey = contextkey.New[*Token]("OAuth2Token")
+
+// Middleware returns an endpoint middleware, for use with client endpoints,
+// which obtains a token from the source before calling the next endpoint, and
+// stores it in the context under TokenContextKey, for TokenToHTTP to attach
+// to the request. Unlike TokenToHTTP alone, it fails the call if no token
+// can be obtained.
+func Middleware[I, O any](ts TokenSource) endpoint.Middleware[I, O] {
+	return func(next endpoint.Endpoint[I, O]) endpoint.Endpoint[I, O] {
+		return func(ctx context.Context, request I) (O, error) {
+			t, err := ts.Token(ctx)
+			if err != nil {
+				var zero O
+				return zero, err
+			}
+			return next(TokenContextKey.Set(ctx, t), request)
+		}
+	}
+}
+
+// TokenToHTTP returns a RequestFunc, for use with ClientBefore, which sets the
+// Authorization header of the request to the token in the context, as stored
+// by Middleware, or otherwise to a token obtained from the source. Since a
+// RequestFunc can't fail, the header is left unset if no token can be
+// obtained, and the server will reject the request; wrap the client endpoint
+// with Middleware to return the error instead.
+func TokenToHTTP(ts TokenSource) http.RequestFunc {
+	return func(ctx context.Context, r *stdhttp.Request) context.Context {
+		t, ok := TokenContextKey.Get(ctx)
+		if !ok {
+			var err error
+			if t, err = ts.Token(ctx); err != nil {
+				return ctx
+			}
+		}
+		r.Header.Set("Authorization", t.Header())
+		return ctx
+	}
+}
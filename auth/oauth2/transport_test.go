@@ -0,0 +1,54 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+type tokenSourceFunc func(ctx context.Context) (*Token, error)
+
+func (f tokenSourceFunc) Token(ctx context.Context) (*Token, error) { return f(ctx) }
+
+func TestTokenToHTTP(t *testing.T) {
+	source := tokenSourceFunc(func(context.Context) (*Token, error) {
+		return &Token{AccessToken: "fetched", TokenType: "bearer"}, nil
+	})
+	reqFunc := TokenToHTTP(source)
+
+	r, _ := http.NewRequest("GET", "http://example.com", nil)
+	reqFunc(context.Background(), r)
+	if want, have := "Bearer fetched", r.Header.Get("Authorization"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	r, _ = http.NewRequest("GET", "http://example.com", nil)
+	ctx := context.WithValue(context.Background(), TokenContextKey, &Token{AccessToken: "stored", TokenType: "MAC"})
+	reqFunc(ctx, r)
+	if want, have := "MAC stored", r.Header.Get("Authorization"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	e := func(ctx context.Context, i struct{}) (*Token, error) {
+		t, _ := ctx.Value(TokenContextKey).(*Token)
+		return t, nil
+	}
+
+	source := tokenSourceFunc(func(context.Context) (*Token, error) { return &Token{AccessToken: "ok"}, nil })
+	token, err := Middleware[struct{}, *Token](source)(e)(context.Background(), struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "ok", token.AccessToken; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	errFetch := errors.New("fetch failed")
+	source = tokenSourceFunc(func(context.Context) (*Token, error) { return nil, errFetch })
+	if _, err := Middleware[struct{}, *Token](source)(e)(context.Background(), struct{}{}); err != errFetch {
+		t.Errorf("want %v, have %v", errFetch, err)
+	}
+}
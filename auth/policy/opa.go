@@ -0,0 +1,82 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	stdhttp "net/http"
+	"time"
+)
+
+// DefaultOPATimeout is the default timeout of requests to OPA.
+const DefaultOPATimeout = 5 * time.Second
+
+// OPAOption sets an optional parameter for OPA.
+type OPAOption func(*opa)
+
+// OPAHTTPClient sets the client used to query OPA. By default, a client with
+// a timeout of DefaultOPATimeout is used.
+func OPAHTTPClient(client *stdhttp.Client) OPAOption {
+	return func(o *opa) { o.client = client }
+}
+
+// OPAInput sets a func which returns the input document sent to OPA, e.g. to
+// add attributes of the request beyond the subject, object and action. By
+// default, the Input itself is sent.
+func OPAInput(f func(ctx context.Context, in Input) interface{}) OPAOption {
+	return func(o *opa) { o.input = f }
+}
+
+// OPA returns an Engine which queries an Open Policy Agent server, via its
+// data API, for a boolean decision, e.g. with the URL
+// http://localhost:8181/v1/data/httpapi/authz/allow. The input document is
+// the Input, as {"subject": ..., "object": ..., "action": ...}, unless set
+// by OPAInput. An undefined decision denies the input.
+func OPA(url string, options ...OPAOption) Engine {
+	o := &opa{
+		url:    url,
+		client: &stdhttp.Client{Timeout: DefaultOPATimeout},
+		input:  func(_ context.Context, in Input) interface{} { return in },
+	}
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
+
+type opa struct {
+	url    string
+	client *stdhttp.Client
+	input  func(ctx context.Context, in Input) interface{}
+}
+
+// Allow implements Engine.
+func (o *opa) Allow(ctx context.Context, in Input) (bool, error) {
+	body, err := json.Marshal(map[string]interface{}{"input": o.input(ctx, in)})
+	if err != nil {
+		return false, err
+	}
+	req, err := stdhttp.NewRequestWithContext(ctx, "POST", o.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("querying OPA: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != stdhttp.StatusOK {
+		return false, fmt.Errorf("querying OPA: %s", resp.Status)
+	}
+
+	var decision struct {
+		Result *bool `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decision); err != nil {
+		return false, fmt.Errorf("decoding OPA decision: %w", err)
+	}
+	return decision.Result != nil && *decision.Result, nil
+}
@@ -0,0 +1,154 @@
+// Package policy provides an authorization middleware which asks a policy
+// engine whether a subject may perform an action on an object. The subject,
+// object and action are derived from the context and the typed request by a
+// user-supplied func, so the same middleware serves any endpoint, and the
+// engine is pluggable: Casbin, Open Policy Agent over HTTP, or a plain func.
+package policy
+
+import (
+	"context"
+	"errors"
+	stdhttp "net/http"
+
+	"github.com/barrett370/kit/v2/endpoint"
+)
+
+// deniedError is returned for requests which the engine denies. Its
+// StatusCode makes the http transport encode it as 403 Forbidden.
+type deniedError struct{}
+
+func (deniedError) Error() string { return "access denied by policy" }
+
+// StatusCode implements the http transport's StatusCoder.
+func (deniedError) StatusCode() int { return stdhttp.StatusForbidden }
+
+// ErrDenied denotes a request which the policy engine denied.
+var ErrDenied error = deniedError{}
+
+// Input is what the policy engine decides on: whether the subject, e.g. a
+// user ID, may perform the action, e.g. "read", on the object, e.g.
+// "/orders/42".
+type Input struct {
+	Subject string `json:"subject"`
+	Object  string `json:"object"`
+	Action  string `json:"action"`
+}
+
+// InputFunc derives the Input for a request, typically the subject from a
+// value stored in the context by an authentication middleware, and the object
+// and action from the request. Its errors are returned by the middleware.
+type InputFunc[I any] func(ctx context.Context, request I) (Input, error)
+
+// Engine evaluates policies.
+type Engine interface {
+	Allow(ctx context.Context, in Input) (bool, error)
+}
+
+// EngineFunc is an adapter to allow the use of ordinary functions as Engines.
+type EngineFunc func(ctx context.Context, in Input) (bool, error)
+
+// Allow implements Engine.
+func (f EngineFunc) Allow(ctx context.Context, in Input) (bool, error) { return f(ctx, in) }
+
+// AuditFunc is called for each request which isn't allowed, with its input,
+// and either ErrDenied or the error which prevented a decision.
+type AuditFunc func(ctx context.Context, in Input, err error)
+
+// Option sets an optional parameter for NewMiddleware.
+type Option func(*options)
+
+type options struct {
+	audit AuditFunc
+}
+
+// Audit sets a func which is called for each request which isn't allowed,
+// e.g. to log it for a security audit trail.
+func Audit(f AuditFunc) Option {
+	return func(o *options) { o.audit = f }
+}
+
+// NewMiddleware returns an endpoint middleware which allows requests only if
+// the engine allows their input, as derived by the InputFunc. Denied requests
+// fail with ErrDenied. Errors from the engine are returned as-is, so that
+// they're distinguishable from denials; either way, the request isn't
+// allowed.
+func NewMiddleware[I, O any](engine Engine, input InputFunc[I], opts ...Option) endpoint.Middleware[I, O] {
+	var o options
+	for _, option := range opts {
+		option(&o)
+	}
+	return func(next endpoint.Endpoint[I, O]) endpoint.Endpoint[I, O] {
+		return func(ctx context.Context, request I) (O, error) {
+			in, err := input(ctx, request)
+			if err != nil {
+				var zero O
+				return zero, err
+			}
+			allowed, err := engine.Allow(ctx, in)
+			if err == nil && !allowed {
+				err = ErrDenied
+			}
+			if err != nil {
+				if o.audit != nil {
+					o.audit(ctx, in, err)
+				}
+				var zero O
+				return zero, err
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// Enforcer is implemented by *casbin.Enforcer, and *casbin.SyncedEnforcer,
+// from github.com/casbin/casbin/v2.
+type Enforcer interface {
+	Enforce(rvals ...interface{}) (bool, error)
+}
+
+// Casbin returns an Engine which enforces the Casbin model and policy of the
+// enforcer, with request values (subject, object, action), i.e. a model with
+//
+//	[request_definition]
+//	r = sub, obj, act
+func Casbin(e Enforcer) Engine {
+	return EngineFunc(func(ctx context.Context, in Input) (bool, error) {
+		return e.Enforce(in.Subject, in.Object, in.Action)
+	})
+}
+
+// All returns an Engine which allows inputs only if all of the engines allow
+// them. It stops at the first engine which doesn't.
+func All(engines ...Engine) Engine {
+	return EngineFunc(func(ctx context.Context, in Input) (bool, error) {
+		for _, e := range engines {
+			if allowed, err := e.Allow(ctx, in); err != nil || !allowed {
+				return false, err
+			}
+		}
+		return true, nil
+	})
+}
+
+var errNoEngines = errors.New("policy: no engines")
+
+// Any returns an Engine which allows inputs if any of the engines allows
+// them. Errors from engines are ignored if another engine allows the input.
+func Any(engines ...Engine) Engine {
+	return EngineFunc(func(ctx context.Context, in Input) (bool, error) {
+		if len(engines) == 0 {
+			return false, errNoEngines
+		}
+		var firstErr error
+		for _, e := range engines {
+			allowed, err := e.Allow(ctx, in)
+			if err == nil && allowed {
+				return true, nil
+			}
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return false, firstErr
+	})
+}
@@ -0,0 +1,140 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type getOrder struct{ ID string }
+
+type subjectKey struct{}
+
+func orderInput(ctx context.Context, r getOrder) (Input, error) {
+	sub, ok := ctx.Value(subjectKey{}).(string)
+	if !ok {
+		return Input{}, errors.New("unauthenticated")
+	}
+	return Input{Subject: sub, Object: "/orders/" + r.ID, Action: "read"}, nil
+}
+
+// enforcer mimics a Casbin enforcer with a fixed set of (sub, obj, act)
+// policies.
+type enforcer map[[3]string]bool
+
+func (e enforcer) Enforce(rvals ...interface{}) (bool, error) {
+	return e[[3]string{rvals[0].(string), rvals[1].(string), rvals[2].(string)}], nil
+}
+
+func TestMiddleware(t *testing.T) {
+	var (
+		engine = Casbin(enforcer{{"alice", "/orders/1", "read"}: true})
+		e      = func(ctx context.Context, r getOrder) (string, error) { return r.ID, nil }
+		audits []Input
+		audit  = Audit(func(ctx context.Context, in Input, err error) { audits = append(audits, in) })
+		ep     = NewMiddleware[getOrder, string](engine, orderInput, audit)(e)
+	)
+
+	for _, tc := range []struct {
+		name    string
+		subject string
+		id      string
+		want    error
+	}{
+		{"allowed", "alice", "1", nil},
+		{"other object", "alice", "2", ErrDenied},
+		{"other subject", "bob", "1", ErrDenied},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), subjectKey{}, tc.subject)
+			if _, have := ep(ctx, getOrder{tc.id}); tc.want != have {
+				t.Errorf("want %v, have %v", tc.want, have)
+			}
+		})
+	}
+
+	if want, have := 2, len(audits); want != have {
+		t.Fatalf("audits: want %d, have %d", want, have)
+	}
+	if want, have := (Input{"bob", "/orders/1", "read"}), audits[1]; want != have {
+		t.Errorf("audit: want %v, have %v", want, have)
+	}
+
+	// Input errors are returned before the engine is consulted.
+	if _, err := ep(context.Background(), getOrder{"1"}); err == nil || err == ErrDenied {
+		t.Errorf("want input error, have %v", err)
+	}
+}
+
+func TestCombinators(t *testing.T) {
+	var (
+		allow   = EngineFunc(func(context.Context, Input) (bool, error) { return true, nil })
+		deny    = EngineFunc(func(context.Context, Input) (bool, error) { return false, nil })
+		errFail = errors.New("engine unavailable")
+		fail    = EngineFunc(func(context.Context, Input) (bool, error) { return false, errFail })
+	)
+	for _, tc := range []struct {
+		name    string
+		engine  Engine
+		allowed bool
+		err     error
+	}{
+		{"all allow", All(allow, allow), true, nil},
+		{"all, one denies", All(allow, deny), false, nil},
+		{"all, one fails", All(fail, allow), false, errFail},
+		{"any, one allows", Any(fail, deny, allow), true, nil},
+		{"any, none allow", Any(deny, fail), false, errFail},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			allowed, err := tc.engine.Allow(context.Background(), Input{})
+			if want, have := tc.allowed, allowed; want != have {
+				t.Errorf("allowed: want %v, have %v", want, have)
+			}
+			if want, have := tc.err, err; want != have {
+				t.Errorf("err: want %v, have %v", want, have)
+			}
+		})
+	}
+}
+
+func TestOPA(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Input Input `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch body.Input.Subject {
+		case "alice":
+			w.Write([]byte(`{"result": true}`))
+		case "bob":
+			w.Write([]byte(`{"result": false}`))
+		default:
+			w.Write([]byte(`{}`)) // undefined
+		}
+	}))
+	defer server.Close()
+
+	engine := OPA(server.URL + "/v1/data/httpapi/authz/allow")
+	for _, tc := range []struct {
+		subject string
+		want    bool
+	}{
+		{"alice", true},
+		{"bob", false},
+		{"carol", false},
+	} {
+		allowed, err := engine.Allow(context.Background(), Input{Subject: tc.subject, Object: "/orders/1", Action: "read"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, have := tc.want, allowed; want != have {
+			t.Errorf("%s: want %v, have %v", tc.subject, want, have)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package sign
+
+import (
+	"context"
+	"crypto/hmac"
+	stdhttp "net/http"
+	"time"
+
+	"github.com/barrett370/kit/v2/endpoint"
+)
+
+// DefaultMaxSkew is the default tolerance for the difference between the
+// timestamp of a request and the server's clock.
+const DefaultMaxSkew = 5 * time.Minute
+
+// authError is returned for requests which fail verification. Its StatusCode
+// makes the http transport encode it as 401 Unauthorized.
+type authError string
+
+func (e authError) Error() string { return string(e) }
+
+// StatusCode implements the http transport's StatusCoder.
+func (authError) StatusCode() int { return stdhttp.StatusUnauthorized }
+
+var (
+	// ErrSignatureMissing denotes a request without a signature, or whose
+	// signature wasn't extracted into the context by HTTPToContext.
+	ErrSignatureMissing error = authError("request is not signed")
+
+	// ErrUnknownKey denotes a request signed with a key ID for which the
+	// SecretFunc has no secret.
+	ErrUnknownKey error = authError("request is signed with an unknown key")
+
+	// ErrSignatureInvalid denotes a request whose signature doesn't match.
+	ErrSignatureInvalid error = authError("request signature is invalid")
+
+	// ErrTimestampSkewed denotes a request whose timestamp is further from
+	// the server's clock than the max skew.
+	ErrTimestampSkewed error = authError("request timestamp is outside the allowed window")
+
+	// ErrNonceMissing denotes a request without a nonce, when a NonceStore
+	// is in use.
+	ErrNonceMissing error = authError("request has no nonce")
+
+	// ErrReplayed denotes a request whose nonce has already been seen.
+	ErrReplayed error = authError("request has already been received")
+)
+
+// SecretFunc returns the secret for a key ID. It returns ErrUnknownKey if
+// there is none.
+type SecretFunc func(ctx context.Context, keyID string) ([]byte, error)
+
+// StaticSecrets returns a SecretFunc which looks up secrets in the map, by key
+// ID.
+func StaticSecrets(secrets map[string][]byte) SecretFunc {
+	return func(ctx context.Context, keyID string) ([]byte, error) {
+		secret, ok := secrets[keyID]
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+		return secret, nil
+	}
+}
+
+// MiddlewareOption sets an optional parameter for NewMiddleware.
+type MiddlewareOption func(*verifier)
+
+// MaxSkew sets the maximum difference, either way, between the timestamp of a
+// request and the server's clock. By default, it's DefaultMaxSkew.
+func MaxSkew(d time.Duration) MiddlewareOption {
+	return func(v *verifier) { v.maxSkew = d }
+}
+
+// Nonces enables replay protection: each nonce is recorded in the store, and
+// requests which lack a nonce, or reuse one, are rejected. Nonces need only be
+// kept for the max skew, after which their requests are rejected anyway.
+func Nonces(store NonceStore) MiddlewareOption {
+	return func(v *verifier) { v.nonces = store }
+}
+
+type verifier struct {
+	secrets SecretFunc
+	maxSkew time.Duration
+	nonces  NonceStore
+}
+
+// NewMiddleware returns an endpoint middleware which verifies the signature of
+// the request, as extracted into the context by HTTPToContext, with the
+// secret for its key ID. The key ID is then available to the next endpoint
+// from the *Request stored under RequestContextKey.
+func NewMiddleware[I, O any](secrets SecretFunc, options ...MiddlewareOption) endpoint.Middleware[I, O] {
+	v := &verifier{secrets: secrets, maxSkew: DefaultMaxSkew}
+	for _, option := range options {
+		option(v)
+	}
+	return func(next endpoint.Endpoint[I, O]) endpoint.Endpoint[I, O] {
+		return func(ctx context.Context, request I) (O, error) {
+			if err := v.verify(ctx); err != nil {
+				var zero O
+				return zero, err
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+func (v *verifier) verify(ctx context.Context) error {
+	r, ok := RequestContextKey.Get(ctx)
+	if !ok {
+		return ErrSignatureMissing
+	}
+
+	secret, err := v.secrets(ctx, r.KeyID)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(Signature(secret, r.Canonical)), []byte(r.Signature)) {
+		return ErrSignatureInvalid
+	}
+
+	// Check the timestamp only once the signature proves it's genuine.
+	if skew := time.Since(r.Timestamp); skew > v.maxSkew || skew < -v.maxSkew {
+		return ErrTimestampSkewed
+	}
+
+	if v.nonces == nil {
+		return nil
+	}
+	if r.Nonce == "" {
+		return ErrNonceMissing
+	}
+	seen, err := v.nonces.Seen(ctx, r.KeyID, r.Nonce, r.Timestamp.Add(v.maxSkew))
+	if err != nil {
+		return err
+	}
+	if seen {
+		return ErrReplayed
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package sign
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// NonceStore records the nonces of verified requests, for replay protection.
+// Implementations shared by several servers, e.g. backed by Redis, protect
+// against requests replayed to another instance.
+type NonceStore interface {
+	// Seen records the nonce for the key ID until the expiry, and reports
+	// whether it was already recorded.
+	Seen(ctx context.Context, keyID, nonce string, expiry time.Time) (bool, error)
+}
+
+// ErrNonceStoreFull is returned by a MemoryNonceStore which holds its maximum
+// number of unexpired nonces. Requests are rejected, rather than risk
+// forgetting a nonce which could then be replayed.
+var ErrNonceStoreFull = errors.New("nonce store is full")
+
+// nonceSweepInterval is how often a MemoryNonceStore forgets expired nonces,
+// if it doesn't fill up first.
+const nonceSweepInterval = time.Minute
+
+// MemoryNonceStore is a NonceStore for a single server, which keeps nonces in
+// memory. Expired nonces are forgotten when the store is full, and at least
+// every minute.
+type MemoryNonceStore struct {
+	mtx    sync.Mutex
+	max    int
+	nonces map[string]time.Time // expiry by key ID and nonce
+	swept  time.Time
+	now    func() time.Time
+}
+
+// NewMemoryNonceStore returns a MemoryNonceStore which holds at most max
+// unexpired nonces. If max is zero, it's unbounded.
+func NewMemoryNonceStore(max int) *MemoryNonceStore {
+	return &MemoryNonceStore{max: max, nonces: map[string]time.Time{}, swept: time.Now(), now: time.Now}
+}
+
+// Seen implements NonceStore.
+func (s *MemoryNonceStore) Seen(_ context.Context, keyID, nonce string, expiry time.Time) (bool, error) {
+	key := keyID + "\n" + nonce
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	now := s.now()
+	if exp, ok := s.nonces[key]; ok && now.Before(exp) {
+		return true, nil
+	}
+	full := s.max > 0 && len(s.nonces) >= s.max
+	if full || now.Sub(s.swept) >= nonceSweepInterval {
+		s.sweep(now)
+	}
+	if s.max > 0 && len(s.nonces) >= s.max {
+		return false, ErrNonceStoreFull
+	}
+	s.nonces[key] = expiry
+	return false, nil
+}
+
+// sweep forgets the nonces expired at now.
+func (s *MemoryNonceStore) sweep(now time.Time) {
+	for k, exp := range s.nonces {
+		if !now.Before(exp) {
+			delete(s.nonces, k)
+		}
+	}
+	s.swept = now
+}
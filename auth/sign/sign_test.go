@@ -0,0 +1,160 @@
+package sign
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var secrets = StaticSecrets(map[string][]byte{"client": []byte("secret")})
+
+func verify(t *testing.T, r *http.Request, options ...MiddlewareOption) error {
+	t.Helper()
+	ctx := HTTPToContext()(context.Background(), r)
+	e := func(ctx context.Context, i struct{}) (struct{}, error) { return i, nil }
+	_, err := NewMiddleware[struct{}, struct{}](secrets, options...)(e)(ctx, struct{}{})
+	return err
+}
+
+// roundTrip signs a request, and returns it as received by a server.
+func roundTrip(t *testing.T, keyID string, secret []byte, method, path, body string) *http.Request {
+	t.Helper()
+	var received *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		HTTPToContext()(context.Background(), r) // reads the body while the server is running
+	}))
+	defer server.Close()
+
+	r, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	SignHTTP(keyID, secret)(context.Background(), r)
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return received
+}
+
+func TestSignVerify(t *testing.T) {
+	r := roundTrip(t, "client", []byte("secret"), "POST", "/orders/a%2Fb?dry=true", `{"qty":1}`)
+	if err := verify(t, r); err != nil {
+		t.Errorf("want %v, have %v", nil, err)
+	}
+}
+
+func TestVerifyErrors(t *testing.T) {
+	newRequest := func(keyID string, secret []byte, timestamp time.Time, tamper func(*http.Request)) *http.Request {
+		r := httptest.NewRequest("POST", "/orders", strings.NewReader("body"))
+		SignHTTP(keyID, secret)(context.Background(), r)
+		if !timestamp.IsZero() {
+			// Re-sign with the given timestamp.
+			ts := timestamp.Unix()
+			nonce := r.Header.Get(NonceHeader)
+			r.Header.Set(TimestampHeader, strconv.FormatInt(ts, 10))
+			r.Header.Set(SignatureHeader, Signature(secret, Canonical("POST", "/orders", []byte("body"), ts, nonce)))
+		}
+		if tamper != nil {
+			tamper(r)
+		}
+		return r
+	}
+
+	for _, tc := range []struct {
+		name string
+		r    *http.Request
+		want error
+	}{
+		{"unsigned", httptest.NewRequest("GET", "/", nil), ErrSignatureMissing},
+		{"unknown key", newRequest("other", []byte("secret"), time.Time{}, nil), ErrUnknownKey},
+		{"wrong secret", newRequest("client", []byte("guess"), time.Time{}, nil), ErrSignatureInvalid},
+		{"tampered path", newRequest("client", []byte("secret"), time.Time{}, func(r *http.Request) { r.URL.Path = "/admin" }), ErrSignatureInvalid},
+		{"within skew", newRequest("client", []byte("secret"), time.Now().Add(-4*time.Minute), nil), nil},
+		{"beyond skew", newRequest("client", []byte("secret"), time.Now().Add(-6*time.Minute), nil), ErrTimestampSkewed},
+		{"future", newRequest("client", []byte("secret"), time.Now().Add(6*time.Minute), nil), ErrTimestampSkewed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if want, have := tc.want, verify(t, tc.r); want != have {
+				t.Errorf("want %v, have %v", want, have)
+			}
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	nonces := Nonces(NewMemoryNonceStore(0))
+	r := httptest.NewRequest("GET", "/", nil)
+	SignHTTP("client", []byte("secret"))(context.Background(), r)
+
+	if err := verify(t, r, nonces); err != nil {
+		t.Fatalf("first: want %v, have %v", nil, err)
+	}
+	if want, have := ErrReplayed, verify(t, r, nonces); want != have {
+		t.Errorf("replay: want %v, have %v", want, have)
+	}
+
+	// Without a nonce, requests are rejected when replay protection is on.
+	r = httptest.NewRequest("GET", "/", nil)
+	ts := time.Now().Unix()
+	r.Header.Set(KeyIDHeader, "client")
+	r.Header.Set(TimestampHeader, strconv.FormatInt(ts, 10))
+	r.Header.Set(SignatureHeader, Signature([]byte("secret"), Canonical("GET", "/", nil, ts, "")))
+	if err := verify(t, r); err != nil {
+		t.Errorf("no nonce, no store: want %v, have %v", nil, err)
+	}
+	if want, have := ErrNonceMissing, verify(t, r, nonces); want != have {
+		t.Errorf("no nonce: want %v, have %v", want, have)
+	}
+}
+
+func TestMemoryNonceStore(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		store = NewMemoryNonceStore(2)
+		later = time.Now().Add(time.Minute)
+	)
+	for _, nonce := range []string{"a", "b"} {
+		if seen, err := store.Seen(ctx, "k", nonce, later); seen || err != nil {
+			t.Fatalf("%s: want false, nil; have %v, %v", nonce, seen, err)
+		}
+	}
+	if want, have := ErrNonceStoreFull, func() error { _, err := store.Seen(ctx, "k", "c", later); return err }(); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// Expired nonces make room.
+	store = NewMemoryNonceStore(1)
+	store.Seen(ctx, "k", "a", time.Now().Add(-time.Second))
+	if seen, err := store.Seen(ctx, "k", "b", later); seen || err != nil {
+		t.Errorf("want false, nil; have %v, %v", seen, err)
+	}
+}
+
+func TestMemoryNonceStoreSweep(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		now   = time.Now()
+		store = NewMemoryNonceStore(0)
+	)
+	store.now, store.swept = func() time.Time { return now }, now
+	for _, nonce := range []string{"a", "b", "c"} {
+		store.Seen(ctx, "k", nonce, now.Add(time.Second))
+	}
+	store.Seen(ctx, "k", "d", now.Add(time.Hour))
+	if want, have := 4, len(store.nonces); want != have {
+		t.Fatalf("before sweep: want %d nonces, have %d", want, have)
+	}
+
+	now = now.Add(nonceSweepInterval)
+	store.Seen(ctx, "k", "e", now.Add(time.Hour))
+	if want, have := 2, len(store.nonces); want != have {
+		t.Errorf("after sweep: want %d nonces, have %d", want, have)
+	}
+	if seen, err := store.Seen(ctx, "k", "d", now.Add(time.Hour)); !seen || err != nil {
+		t.Errorf("unexpired nonce: want true, nil; have %v, %v", seen, err)
+	}
+}
@@ -0,0 +1,218 @@
+package chaos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/barrett370/kit/v2/endpoint"
+)
+
+// ErrInjected is returned by requests which fail due to an injected error,
+// unless the Fault has an error message of its own.
+var ErrInjected = errors.New("chaos: injected error")
+
+// DefaultRoute is the route whose Fault applies to routes without a Fault of
+// their own.
+const DefaultRoute = "*"
+
+// Fault describes the faults injected into the requests of a route. Each kind
+// of fault is injected independently, with its own probability, from 0 to 1.
+type Fault struct {
+	// Delay is added to requests, with DelayProbability, before they're
+	// made, or until their context is done.
+	Delay            time.Duration
+	DelayProbability float64
+
+	// Requests fail with ErrorProbability, without being made, with Error as
+	// the error message, or ErrInjected if it's empty.
+	Error            string
+	ErrorProbability float64
+
+	// Responses are corrupted with CorruptProbability, by the middleware's
+	// Corrupt func, or replaced by the zero value if it has none.
+	CorruptProbability float64
+}
+
+type faultJSON struct {
+	Delay              string  `json:"delay,omitempty"`
+	DelayProbability   float64 `json:"delay_probability,omitempty"`
+	Error              string  `json:"error,omitempty"`
+	ErrorProbability   float64 `json:"error_probability,omitempty"`
+	CorruptProbability float64 `json:"corrupt_probability,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler, with the delay as a duration
+// string, e.g. "200ms".
+func (f Fault) MarshalJSON() ([]byte, error) {
+	v := faultJSON{
+		DelayProbability:   f.DelayProbability,
+		Error:              f.Error,
+		ErrorProbability:   f.ErrorProbability,
+		CorruptProbability: f.CorruptProbability,
+	}
+	if f.Delay > 0 {
+		v.Delay = f.Delay.String()
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (f *Fault) UnmarshalJSON(data []byte) error {
+	var v faultJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	var delay time.Duration
+	if v.Delay != "" {
+		d, err := time.ParseDuration(v.Delay)
+		if err != nil {
+			return err
+		}
+		delay = d
+	}
+	*f = Fault{
+		Delay:              delay,
+		DelayProbability:   v.DelayProbability,
+		Error:              v.Error,
+		ErrorProbability:   v.ErrorProbability,
+		CorruptProbability: v.CorruptProbability,
+	}
+	return nil
+}
+
+// Injector holds the Faults of routes, which may be changed at any time,
+// e.g. through its HTTP handler. It injects no faults until they're set.
+type Injector struct {
+	mtx    sync.Mutex
+	r      *rand.Rand
+	faults map[string]Fault
+}
+
+// NewInjector returns an Injector with no Faults, whose probabilities are
+// decided by a random source with the seed.
+func NewInjector(seed int64) *Injector {
+	return &Injector{
+		r:      rand.New(rand.NewSource(seed)),
+		faults: map[string]Fault{},
+	}
+}
+
+// Set sets the Fault of the route.
+func (inj *Injector) Set(route string, f Fault) {
+	inj.mtx.Lock()
+	defer inj.mtx.Unlock()
+	inj.faults[route] = f
+}
+
+// SetAll replaces the Faults of all routes.
+func (inj *Injector) SetAll(faults map[string]Fault) {
+	copied := make(map[string]Fault, len(faults))
+	for route, f := range faults {
+		copied[route] = f
+	}
+	inj.mtx.Lock()
+	defer inj.mtx.Unlock()
+	inj.faults = copied
+}
+
+// Clear removes the Fault of the route.
+func (inj *Injector) Clear(route string) {
+	inj.mtx.Lock()
+	defer inj.mtx.Unlock()
+	delete(inj.faults, route)
+}
+
+// Faults returns the Faults of all routes.
+func (inj *Injector) Faults() map[string]Fault {
+	inj.mtx.Lock()
+	defer inj.mtx.Unlock()
+	faults := make(map[string]Fault, len(inj.faults))
+	for route, f := range inj.faults {
+		faults[route] = f
+	}
+	return faults
+}
+
+// decision is the faults to inject into a request.
+type decision struct {
+	delay   time.Duration
+	err     error
+	corrupt bool
+}
+
+func (inj *Injector) decide(route string) decision {
+	inj.mtx.Lock()
+	defer inj.mtx.Unlock()
+	f, ok := inj.faults[route]
+	if !ok {
+		if f, ok = inj.faults[DefaultRoute]; !ok {
+			return decision{}
+		}
+	}
+	var d decision
+	if f.DelayProbability > 0 && inj.r.Float64() < f.DelayProbability {
+		d.delay = f.Delay
+	}
+	if f.ErrorProbability > 0 && inj.r.Float64() < f.ErrorProbability {
+		d.err = ErrInjected
+		if f.Error != "" {
+			d.err = errors.New(f.Error)
+		}
+	}
+	if f.CorruptProbability > 0 && inj.r.Float64() < f.CorruptProbability {
+		d.corrupt = true
+	}
+	return d
+}
+
+// Option sets an optional parameter for the middleware.
+type Option[O any] func(*options[O])
+
+type options[O any] struct {
+	corrupt func(O) O
+}
+
+// Corrupt sets the func which corrupts responses, e.g. by dropping fields or
+// truncating lists. By default, corrupt responses are the zero value.
+func Corrupt[O any](f func(O) O) Option[O] {
+	return func(o *options[O]) { o.corrupt = f }
+}
+
+// NewMiddleware returns an endpoint middleware which injects the Faults of
+// the route into requests.
+func NewMiddleware[I, O any](inj *Injector, route string, opts ...Option[O]) endpoint.Middleware[I, O] {
+	var o options[O]
+	for _, option := range opts {
+		option(&o)
+	}
+	return func(next endpoint.Endpoint[I, O]) endpoint.Endpoint[I, O] {
+		return func(ctx context.Context, request I) (O, error) {
+			var zero O
+			d := inj.decide(route)
+			if d.delay > 0 {
+				t := time.NewTimer(d.delay)
+				select {
+				case <-t.C:
+				case <-ctx.Done():
+					t.Stop()
+					return zero, ctx.Err()
+				}
+			}
+			if d.err != nil {
+				return zero, d.err
+			}
+			response, err := next(ctx, request)
+			if err != nil || !d.corrupt {
+				return response, err
+			}
+			if o.corrupt == nil {
+				return zero, nil
+			}
+			return o.corrupt(response), nil
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package chaos
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func echo(_ context.Context, request string) (string, error) { return request, nil }
+
+func TestMiddlewareNoFaults(t *testing.T) {
+	e := NewMiddleware[string, string](NewInjector(0), "echo")(echo)
+	if have, err := e(context.Background(), "ok"); have != "ok" || err != nil {
+		t.Errorf("want ok, have %q, %v", have, err)
+	}
+}
+
+func TestMiddlewareError(t *testing.T) {
+	inj := NewInjector(0)
+	e := NewMiddleware[string, string](inj, "echo")(echo)
+
+	inj.Set("echo", Fault{ErrorProbability: 1})
+	if _, err := e(context.Background(), "ok"); err != ErrInjected {
+		t.Errorf("want %v, have %v", ErrInjected, err)
+	}
+	inj.Set("echo", Fault{ErrorProbability: 1, Error: "unavailable"})
+	if response, err := e(context.Background(), "ok"); response != "" || err == nil || err.Error() != "unavailable" {
+		t.Errorf("want the zero response and unavailable, have %q, %v", response, err)
+	}
+
+	// Other routes are unaffected, unless there's a default Fault.
+	other := NewMiddleware[string, string](inj, "other")(echo)
+	if _, err := other(context.Background(), "ok"); err != nil {
+		t.Errorf("want no error, have %v", err)
+	}
+	inj.Set(DefaultRoute, Fault{ErrorProbability: 1})
+	if _, err := other(context.Background(), "ok"); err != ErrInjected {
+		t.Errorf("want %v, have %v", ErrInjected, err)
+	}
+}
+
+func TestMiddlewareErrorProbability(t *testing.T) {
+	inj := NewInjector(0)
+	inj.Set("echo", Fault{ErrorProbability: 0.25})
+	e := NewMiddleware[string, string](inj, "echo")(echo)
+	var failed int
+	for i := 0; i < 1000; i++ {
+		if _, err := e(context.Background(), "ok"); err != nil {
+			failed++
+		}
+	}
+	if failed < 200 || failed > 300 { // 250 is exact
+		t.Errorf("want about 25%% failed, have %d", failed)
+	}
+}
+
+func TestMiddlewareDelay(t *testing.T) {
+	inj := NewInjector(0)
+	inj.Set("echo", Fault{Delay: 20 * time.Millisecond, DelayProbability: 1})
+	e := NewMiddleware[string, string](inj, "echo")(echo)
+
+	begin := time.Now()
+	if _, err := e(context.Background(), "ok"); err != nil {
+		t.Fatal(err)
+	}
+	if took := time.Since(begin); took < 20*time.Millisecond {
+		t.Errorf("want a delay of at least 20ms, have %v", took)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if _, err := e(ctx, "ok"); err != context.DeadlineExceeded {
+		t.Errorf("want %v, have %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestMiddlewareCorrupt(t *testing.T) {
+	inj := NewInjector(0)
+	inj.Set("echo", Fault{CorruptProbability: 1})
+	e := NewMiddleware[string, string](inj, "echo", Corrupt(strings.ToUpper))(echo)
+	if want, have := "OK", mustEcho(t, e); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	e = NewMiddleware[string, string](inj, "echo")(echo)
+	if want, have := "", mustEcho(t, e); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func mustEcho(t *testing.T, e func(context.Context, string) (string, error)) string {
+	t.Helper()
+	response, err := e(context.Background(), "ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func TestHandler(t *testing.T) {
+	inj := NewInjector(0)
+	do := func(method, target, body string) (int, string) {
+		rec := httptest.NewRecorder()
+		inj.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec.Code, strings.TrimSpace(rec.Body.String())
+	}
+
+	code, body := do("PUT", "/chaos?route=echo", `{"delay": "200ms", "delay_probability": 0.5}`)
+	if want, have := http.StatusOK, code; want != have {
+		t.Fatalf("want %d, have %d: %s", want, have, body)
+	}
+	if want, have := `{"echo":{"delay":"200ms","delay_probability":0.5}}`, body; want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	if want, have := map[string]Fault{"echo": {Delay: 200 * time.Millisecond, DelayProbability: 0.5}}, inj.Faults(); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	if code, _ := do("PUT", "/chaos?route=echo", `{"delay": "soon"}`); code != http.StatusBadRequest {
+		t.Errorf("want %d, have %d", http.StatusBadRequest, code)
+	}
+
+	do("POST", "/chaos", `{"a": {"error_probability": 1}, "b": {"corrupt_probability": 1}}`)
+	do("DELETE", "/chaos?route=a", "")
+	if want, have := `{"b":{"corrupt_probability":1}}`, mustBody(do("GET", "/chaos", "")); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	if want, have := `{}`, mustBody(do("DELETE", "/chaos", "")); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+}
+
+func mustBody(_ int, body string) string { return body }
@@ -0,0 +1,15 @@
+// Package chaos provides an endpoint middleware which injects faults into
+// requests: latency, errors, and corrupted responses, with probabilities set
+// by route, and changed at runtime, e.g. through the admin server. It's meant
+// for staging, to exercise resilience middlewares, such as circuit breakers,
+// retries, and timeouts, and the services which depend on them.
+//
+//	injector := chaos.NewInjector(time.Now().UnixNano())
+//	admin.NewServer(":9090", admin.WithHandler("/chaos", injector))
+//	getUser = chaos.NewMiddleware[GetUserRequest, GetUserResponse](injector, "GetUser")(getUser)
+//
+// Faults are then injected with a request to the admin server:
+//
+//	curl -X PUT 'localhost:9090/chaos?route=GetUser' \
+//	    -d '{"delay": "200ms", "delay_probability": 0.5, "error_probability": 0.1}'
+package chaos
@@ -0,0 +1,56 @@
+package chaos
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ServeHTTP implements http.Handler, to control the Injector at runtime.
+//
+// GET returns the Faults of all routes, as a JSON object by route. PUT or
+// POST with a route query parameter sets the Fault of the route to the JSON
+// object in the body, and without one, replaces the Faults of all routes.
+// DELETE with a route clears its Fault, and without one, clears all of them.
+func (inj *Injector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route, hasRoute := r.URL.Query()["route"]
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+
+	case http.MethodPut, http.MethodPost:
+		if hasRoute {
+			var f Fault
+			if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			inj.Set(route[0], f)
+		} else {
+			var faults map[string]Fault
+			if err := json.NewDecoder(r.Body).Decode(&faults); err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			inj.SetAll(faults)
+		}
+
+	case http.MethodDelete:
+		if hasRoute {
+			inj.Clear(route[0])
+		} else {
+			inj.SetAll(nil)
+		}
+
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(inj.Faults())
+}
+
+func writeError(w http.ResponseWriter, code int, err string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": err})
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"go/format"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+// generatedImports are always imported by the generated file.
+var generatedImports = map[string]bool{
+	`"context"`:       true,
+	`"encoding/json"`: true,
+	`"errors"`:        true,
+	`"io"`:            true,
+	`"net/http"`:      true,
+	`"net/url"`:       true,
+	`"path"`:          true,
+	`"strings"`:       true,
+}
+
+func generate(svc *service) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, svc); err != nil {
+		return nil, err
+	}
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return buf.Bytes(), err
+	}
+	return code, nil
+}
+
+// unexport returns name with its first letter lowercased, for the names of
+// the generated file's unexported helpers, which are prefixed with the name of
+// the service, so files generated for several services in a package don't
+// collide.
+func unexport(name string) string {
+	r, n := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(r)) + name[n:]
+}
+
+var tmpl = template.Must(template.New("kitgen").Funcs(template.FuncMap{
+	"lower":    strings.ToLower,
+	"unexport": unexport,
+}).Parse(`// Code generated by kitgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"{{range .Imports}}
+	{{.}}{{end}}
+
+	"github.com/barrett370/kit/v2/endpoint"
+	httptransport "github.com/barrett370/kit/v2/transport/http"
+)
+{{range .Methods}}
+// {{.Name}}Request collects the request parameters for the {{.Name}} method.
+type {{.Name}}Request struct {
+{{- range .Params}}
+	{{.Field}} {{.Type}} ` + "`" + `json:"{{.JSON}}"` + "`" + `{{end}}
+}
+
+// {{.Name}}Response collects the response values for the {{.Name}} method.
+type {{.Name}}Response struct {
+{{- range .Results}}
+	{{.Field}} {{.Type}} ` + "`" + `json:"{{.JSON}}"` + "`" + `{{end}}
+}
+
+// Make{{.Name}}Endpoint returns an endpoint that invokes {{.Name}} on the service.
+func Make{{.Name}}Endpoint(s {{$.Name}}) endpoint.Endpoint[{{.Name}}Request, {{.Name}}Response] {
+	return func(ctx context.Context, req {{.Name}}Request) ({{.Name}}Response, error) {
+		var (
+			resp {{.Name}}Response
+			err  error
+		)
+		{{range .Results}}resp.{{.Field}}, {{end}}err = s.{{.Name}}(ctx{{range .Params}}, req.{{.Field}}{{end}}{{if .Variadic}}...{{end}})
+		return resp, err
+	}
+}
+{{end}}
+// Endpoints collects all of the endpoints that compose the {{.Name}}. It's
+// meant to be used as a helper struct, to collect all of the endpoints into a
+// single parameter.
+//
+// In a server, it's useful for functions that need to operate on a per-endpoint
+// basis. In a client, it's useful to collect individually constructed endpoints
+// into a single type that implements the {{.Name}} interface.
+type Endpoints struct {
+{{- range .Methods}}
+	{{.Name}}Endpoint endpoint.Endpoint[{{.Name}}Request, {{.Name}}Response]{{end}}
+}
+
+// Middlewares collects the middlewares to apply to each endpoint. The first
+// middleware in each slice is treated as the outermost middleware.
+type Middlewares struct {
+{{- range .Methods}}
+	{{.Name}} []endpoint.Middleware[{{.Name}}Request, {{.Name}}Response]{{end}}
+}
+
+// MakeEndpoints returns an Endpoints that wraps the provided service, with
+// the given middlewares applied.
+func MakeEndpoints(s {{.Name}}, mw Middlewares) Endpoints {
+	return Endpoints{
+{{- range .Methods}}
+		{{.Name}}Endpoint: {{unexport $.Name}}Chain(Make{{.Name}}Endpoint(s), mw.{{.Name}}),{{end}}
+	}
+}
+{{range .Methods}}
+// {{.Name}} implements the {{$.Name}} interface, so Endpoints may be used as a
+// service. This is primarily useful in the context of a client library.
+func (e Endpoints) {{.Name}}(ctx context.Context{{range .Params}}, {{.Name}} {{.ParamType}}{{end}}) ({{range .Results}}{{.Type}}, {{end}}error) {
+	resp, err := e.{{.Name}}Endpoint(ctx, {{.Name}}Request{ {{- range $i, $p := .Params}}{{if $i}}, {{end}}{{.Field}}: {{.Name}}{{end -}} })
+	return {{range .Results}}resp.{{.Field}}, {{end}}err
+}
+{{end}}
+// NewHTTPHandler returns an HTTP handler that serves each endpoint via POST
+// to /<method>, e.g. /{{lower (index .Methods 0).Name}}. Requests and responses are JSON encoded.
+func NewHTTPHandler(e Endpoints) http.Handler {
+	m := http.NewServeMux()
+{{- range .Methods}}
+	m.Handle("/{{lower .Name}}", httptransport.NewServer(
+		e.{{.Name}}Endpoint,
+		{{unexport $.Name}}DecodeHTTPRequest[{{.Name}}Request],
+		{{unexport $.Name}}EncodeHTTPResponse[{{.Name}}Response],
+	)){{end}}
+	return m
+}
+
+// NewHTTPClient returns an Endpoints backed by a remote instance serving the
+// handler returned by NewHTTPHandler. The instance may be a host:port or a
+// base URL, whose path prefixes the paths of the methods.
+func NewHTTPClient(instance string) (Endpoints, error) {
+	if !strings.HasPrefix(instance, "http") {
+		instance = "http://" + instance
+	}
+	u, err := url.Parse(instance)
+	if err != nil {
+		return Endpoints{}, err
+	}
+	return Endpoints{
+{{- range .Methods}}
+		{{.Name}}Endpoint: httptransport.NewClient(
+			"POST",
+			{{unexport $.Name}}MethodURL(u, "{{lower .Name}}"),
+			{{unexport $.Name}}EncodeHTTPRequest[{{.Name}}Request],
+			{{unexport $.Name}}DecodeHTTPResponse[{{.Name}}Response],
+		).Endpoint(),{{end}}
+	}, nil
+}
+
+func {{unexport .Name}}Chain[I, O any](e endpoint.Endpoint[I, O], mw []endpoint.Middleware[I, O]) endpoint.Endpoint[I, O] {
+	for i := len(mw) - 1; i >= 0; i-- {
+		e = mw[i](e)
+	}
+	return e
+}
+
+func {{unexport .Name}}DecodeHTTPRequest[I any](_ context.Context, r *http.Request) (I, error) {
+	var req I
+	err := json.NewDecoder(r.Body).Decode(&req)
+	return req, err
+}
+
+func {{unexport .Name}}EncodeHTTPResponse[O any](ctx context.Context, w http.ResponseWriter, resp O) error {
+	return httptransport.EncodeJSONResponse(ctx, w, resp)
+}
+
+func {{unexport .Name}}EncodeHTTPRequest[I any](ctx context.Context, r *http.Request, req I) error {
+	return httptransport.EncodeJSONRequest(ctx, r, req)
+}
+
+func {{unexport .Name}}MethodURL(base *url.URL, method string) *url.URL {
+	u := *base
+	u.Path, u.RawPath = path.Join("/", u.Path, method), ""
+	return &u
+}
+
+func {{unexport .Name}}DecodeHTTPResponse[O any](_ context.Context, r *http.Response) (O, error) {
+	var resp O
+	if r.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(r.Body)
+		return resp, errors.New(strings.TrimSpace(string(body)))
+	}
+	err := json.NewDecoder(r.Body).Decode(&resp)
+	return resp, err
+}
+`))
@@ -0,0 +1,51 @@
+// Package addsvc is a small service used to exercise the code generated by
+// kitgen.
+package addsvc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+//go:generate go run ../.. service.go
+
+// Service describes a service that adds things together.
+type Service interface {
+	Sum(ctx context.Context, a, b int) (int, error)
+	Concat(ctx context.Context, parts ...string) (result string, err error)
+	Uptime(ctx context.Context) (since time.Time, d time.Duration, err error)
+}
+
+// ErrIntOverflow protects the Sum method.
+var ErrIntOverflow = errors.New("integer overflow")
+
+// NewService returns a basic implementation of Service.
+func NewService(started time.Time) Service {
+	return basicService{started: started}
+}
+
+type basicService struct {
+	started time.Time
+}
+
+const (
+	intMax = 1<<31 - 1
+	intMin = -(intMax + 1)
+)
+
+func (basicService) Sum(_ context.Context, a, b int) (int, error) {
+	if (b > 0 && a > (intMax-b)) || (b < 0 && a < (intMin-b)) {
+		return 0, ErrIntOverflow
+	}
+	return a + b, nil
+}
+
+func (basicService) Concat(_ context.Context, parts ...string) (string, error) {
+	return strings.Join(parts, ""), nil
+}
+
+func (s basicService) Uptime(context.Context) (time.Time, time.Duration, error) {
+	return s.started, time.Since(s.started), nil
+}
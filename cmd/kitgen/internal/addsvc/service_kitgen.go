@@ -0,0 +1,229 @@
+// Code generated by kitgen. DO NOT EDIT.
+
+package addsvc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	httptransport "github.com/barrett370/kit/v2/transport/http"
+)
+
+// SumRequest collects the request parameters for the Sum method.
+type SumRequest struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+// SumResponse collects the response values for the Sum method.
+type SumResponse struct {
+	V int `json:"v"`
+}
+
+// MakeSumEndpoint returns an endpoint that invokes Sum on the service.
+func MakeSumEndpoint(s Service) endpoint.Endpoint[SumRequest, SumResponse] {
+	return func(ctx context.Context, req SumRequest) (SumResponse, error) {
+		var (
+			resp SumResponse
+			err  error
+		)
+		resp.V, err = s.Sum(ctx, req.A, req.B)
+		return resp, err
+	}
+}
+
+// ConcatRequest collects the request parameters for the Concat method.
+type ConcatRequest struct {
+	Parts []string `json:"parts"`
+}
+
+// ConcatResponse collects the response values for the Concat method.
+type ConcatResponse struct {
+	Result string `json:"result"`
+}
+
+// MakeConcatEndpoint returns an endpoint that invokes Concat on the service.
+func MakeConcatEndpoint(s Service) endpoint.Endpoint[ConcatRequest, ConcatResponse] {
+	return func(ctx context.Context, req ConcatRequest) (ConcatResponse, error) {
+		var (
+			resp ConcatResponse
+			err  error
+		)
+		resp.Result, err = s.Concat(ctx, req.Parts...)
+		return resp, err
+	}
+}
+
+// UptimeRequest collects the request parameters for the Uptime method.
+type UptimeRequest struct {
+}
+
+// UptimeResponse collects the response values for the Uptime method.
+type UptimeResponse struct {
+	Since time.Time     `json:"since"`
+	D     time.Duration `json:"d"`
+}
+
+// MakeUptimeEndpoint returns an endpoint that invokes Uptime on the service.
+func MakeUptimeEndpoint(s Service) endpoint.Endpoint[UptimeRequest, UptimeResponse] {
+	return func(ctx context.Context, req UptimeRequest) (UptimeResponse, error) {
+		var (
+			resp UptimeResponse
+			err  error
+		)
+		resp.Since, resp.D, err = s.Uptime(ctx)
+		return resp, err
+	}
+}
+
+// Endpoints collects all of the endpoints that compose the Service. It's
+// meant to be used as a helper struct, to collect all of the endpoints into a
+// single parameter.
+//
+// In a server, it's useful for functions that need to operate on a per-endpoint
+// basis. In a client, it's useful to collect individually constructed endpoints
+// into a single type that implements the Service interface.
+type Endpoints struct {
+	SumEndpoint    endpoint.Endpoint[SumRequest, SumResponse]
+	ConcatEndpoint endpoint.Endpoint[ConcatRequest, ConcatResponse]
+	UptimeEndpoint endpoint.Endpoint[UptimeRequest, UptimeResponse]
+}
+
+// Middlewares collects the middlewares to apply to each endpoint. The first
+// middleware in each slice is treated as the outermost middleware.
+type Middlewares struct {
+	Sum    []endpoint.Middleware[SumRequest, SumResponse]
+	Concat []endpoint.Middleware[ConcatRequest, ConcatResponse]
+	Uptime []endpoint.Middleware[UptimeRequest, UptimeResponse]
+}
+
+// MakeEndpoints returns an Endpoints that wraps the provided service, with
+// the given middlewares applied.
+func MakeEndpoints(s Service, mw Middlewares) Endpoints {
+	return Endpoints{
+		SumEndpoint:    serviceChain(MakeSumEndpoint(s), mw.Sum),
+		ConcatEndpoint: serviceChain(MakeConcatEndpoint(s), mw.Concat),
+		UptimeEndpoint: serviceChain(MakeUptimeEndpoint(s), mw.Uptime),
+	}
+}
+
+// Sum implements the Service interface, so Endpoints may be used as a
+// service. This is primarily useful in the context of a client library.
+func (e Endpoints) Sum(ctx context.Context, a int, b int) (int, error) {
+	resp, err := e.SumEndpoint(ctx, SumRequest{A: a, B: b})
+	return resp.V, err
+}
+
+// Concat implements the Service interface, so Endpoints may be used as a
+// service. This is primarily useful in the context of a client library.
+func (e Endpoints) Concat(ctx context.Context, parts ...string) (string, error) {
+	resp, err := e.ConcatEndpoint(ctx, ConcatRequest{Parts: parts})
+	return resp.Result, err
+}
+
+// Uptime implements the Service interface, so Endpoints may be used as a
+// service. This is primarily useful in the context of a client library.
+func (e Endpoints) Uptime(ctx context.Context) (time.Time, time.Duration, error) {
+	resp, err := e.UptimeEndpoint(ctx, UptimeRequest{})
+	return resp.Since, resp.D, err
+}
+
+// NewHTTPHandler returns an HTTP handler that serves each endpoint via POST
+// to /<method>, e.g. /sum. Requests and responses are JSON encoded.
+func NewHTTPHandler(e Endpoints) http.Handler {
+	m := http.NewServeMux()
+	m.Handle("/sum", httptransport.NewServer(
+		e.SumEndpoint,
+		serviceDecodeHTTPRequest[SumRequest],
+		serviceEncodeHTTPResponse[SumResponse],
+	))
+	m.Handle("/concat", httptransport.NewServer(
+		e.ConcatEndpoint,
+		serviceDecodeHTTPRequest[ConcatRequest],
+		serviceEncodeHTTPResponse[ConcatResponse],
+	))
+	m.Handle("/uptime", httptransport.NewServer(
+		e.UptimeEndpoint,
+		serviceDecodeHTTPRequest[UptimeRequest],
+		serviceEncodeHTTPResponse[UptimeResponse],
+	))
+	return m
+}
+
+// NewHTTPClient returns an Endpoints backed by a remote instance serving the
+// handler returned by NewHTTPHandler. The instance may be a host:port or a
+// base URL, whose path prefixes the paths of the methods.
+func NewHTTPClient(instance string) (Endpoints, error) {
+	if !strings.HasPrefix(instance, "http") {
+		instance = "http://" + instance
+	}
+	u, err := url.Parse(instance)
+	if err != nil {
+		return Endpoints{}, err
+	}
+	return Endpoints{
+		SumEndpoint: httptransport.NewClient(
+			"POST",
+			serviceMethodURL(u, "sum"),
+			serviceEncodeHTTPRequest[SumRequest],
+			serviceDecodeHTTPResponse[SumResponse],
+		).Endpoint(),
+		ConcatEndpoint: httptransport.NewClient(
+			"POST",
+			serviceMethodURL(u, "concat"),
+			serviceEncodeHTTPRequest[ConcatRequest],
+			serviceDecodeHTTPResponse[ConcatResponse],
+		).Endpoint(),
+		UptimeEndpoint: httptransport.NewClient(
+			"POST",
+			serviceMethodURL(u, "uptime"),
+			serviceEncodeHTTPRequest[UptimeRequest],
+			serviceDecodeHTTPResponse[UptimeResponse],
+		).Endpoint(),
+	}, nil
+}
+
+func serviceChain[I, O any](e endpoint.Endpoint[I, O], mw []endpoint.Middleware[I, O]) endpoint.Endpoint[I, O] {
+	for i := len(mw) - 1; i >= 0; i-- {
+		e = mw[i](e)
+	}
+	return e
+}
+
+func serviceDecodeHTTPRequest[I any](_ context.Context, r *http.Request) (I, error) {
+	var req I
+	err := json.NewDecoder(r.Body).Decode(&req)
+	return req, err
+}
+
+func serviceEncodeHTTPResponse[O any](ctx context.Context, w http.ResponseWriter, resp O) error {
+	return httptransport.EncodeJSONResponse(ctx, w, resp)
+}
+
+func serviceEncodeHTTPRequest[I any](ctx context.Context, r *http.Request, req I) error {
+	return httptransport.EncodeJSONRequest(ctx, r, req)
+}
+
+func serviceMethodURL(base *url.URL, method string) *url.URL {
+	u := *base
+	u.Path, u.RawPath = path.Join("/", u.Path, method), ""
+	return &u
+}
+
+func serviceDecodeHTTPResponse[O any](_ context.Context, r *http.Response) (O, error) {
+	var resp O
+	if r.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(r.Body)
+		return resp, errors.New(strings.TrimSpace(string(body)))
+	}
+	err := json.NewDecoder(r.Body).Decode(&resp)
+	return resp, err
+}
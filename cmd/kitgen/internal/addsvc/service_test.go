@@ -0,0 +1,88 @@
+package addsvc_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/barrett370/kit/v2/cmd/kitgen/internal/addsvc"
+	"github.com/barrett370/kit/v2/endpoint"
+)
+
+func TestHTTPRoundTrip(t *testing.T) {
+	var calls int
+	counting := func(next endpoint.Endpoint[addsvc.SumRequest, addsvc.SumResponse]) endpoint.Endpoint[addsvc.SumRequest, addsvc.SumResponse] {
+		return func(ctx context.Context, req addsvc.SumRequest) (addsvc.SumResponse, error) {
+			calls++
+			return next(ctx, req)
+		}
+	}
+
+	started := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	endpoints := addsvc.MakeEndpoints(addsvc.NewService(started), addsvc.Middlewares{
+		Sum: []endpoint.Middleware[addsvc.SumRequest, addsvc.SumResponse]{counting},
+	})
+	server := httptest.NewServer(addsvc.NewHTTPHandler(endpoints))
+	defer server.Close()
+
+	var client addsvc.Service
+	client, err := addsvc.NewHTTPClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum, err := client.Sum(context.Background(), 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 3, sum; want != have {
+		t.Errorf("Sum: want %d, have %d", want, have)
+	}
+	if want, have := 1, calls; want != have {
+		t.Errorf("middleware calls: want %d, have %d", want, have)
+	}
+
+	if _, err := client.Sum(context.Background(), 1<<31-1, 1); err == nil || err.Error() != addsvc.ErrIntOverflow.Error() {
+		t.Errorf("Sum: want %v, have %v", addsvc.ErrIntOverflow, err)
+	}
+
+	concat, err := client.Concat(context.Background(), "a", "b", "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "abc", concat; want != have {
+		t.Errorf("Concat: want %q, have %q", want, have)
+	}
+
+	since, _, err := client.Uptime(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := started, since; !want.Equal(have) {
+		t.Errorf("Uptime: want %v, have %v", want, have)
+	}
+}
+
+func TestHTTPClientBasePath(t *testing.T) {
+	mux := http.NewServeMux()
+	endpoints := addsvc.MakeEndpoints(addsvc.NewService(time.Now()), addsvc.Middlewares{})
+	mux.Handle("/api/v1/", http.StripPrefix("/api/v1", addsvc.NewHTTPHandler(endpoints)))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	for _, base := range []string{server.URL + "/api/v1", server.URL + "/api/v1/"} {
+		client, err := addsvc.NewHTTPClient(base)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum, err := client.Sum(context.Background(), 1, 2)
+		if err != nil {
+			t.Fatalf("%s: %v", base, err)
+		}
+		if want, have := 3, sum; want != have {
+			t.Errorf("%s: Sum: want %d, have %d", base, want, have)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGenerateGolden(t *testing.T) {
+	// The addsvc package is compiled and tested with the generated code, so
+	// keeping it up to date verifies the generated code works.
+	src, err := os.ReadFile("internal/addsvc/service.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc, err := parseService("service.go", src, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	have, err := generate(svc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile("internal/addsvc/service_kitgen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(want, have) {
+		t.Errorf("internal/addsvc/service_kitgen.go is out of date; run go generate ./cmd/kitgen/...")
+	}
+}
+
+func TestParseService(t *testing.T) {
+	src := []byte(`package foo
+
+import (
+	"context"
+	stdurl "net/url"
+	"time"
+)
+
+type Service interface {
+	Fetch(ctx context.Context, u *stdurl.URL, _ string, s int) (time.Time, error)
+}
+`)
+	svc, err := parseService("foo.go", src, "Service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := `"time",stdurl "net/url"`, strings.Join(svc.Imports, ","); want != have {
+		t.Errorf("imports: want %s, have %s", want, have)
+	}
+	m := svc.Methods[0]
+	for i, want := range []field{
+		{Name: "u", Field: "U", JSON: "u", Type: "*stdurl.URL"},
+		{Name: "arg2", Field: "Arg2", JSON: "arg2", Type: "string"},
+		{Name: "s_", Field: "S", JSON: "s", Type: "int"},
+	} {
+		if have := m.Params[i]; want != have {
+			t.Errorf("param %d: want %+v, have %+v", i, want, have)
+		}
+	}
+	if want, have := (field{Name: "v", Field: "V", JSON: "v", Type: "time.Time"}), m.Results[0]; want != have {
+		t.Errorf("result: want %+v, have %+v", want, have)
+	}
+}
+
+func TestParseServiceErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"no context", `package foo; type S interface { F(int) error }`, "doesn't import context"},
+		{"no ctx param", `package foo; import "context"; type S interface { F(int) error }`, "first parameter must be a context.Context"},
+		{"no error", `package foo; import "context"; type S interface { F(context.Context) int }`, "last result must be an error"},
+		{"ambiguous", `package foo; import "context"; type S interface{}; type T interface{}`, "multiple interfaces"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseService("foo.go", []byte(tc.src), "")
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("want error containing %q, have %v", tc.want, err)
+			}
+		})
+	}
+}
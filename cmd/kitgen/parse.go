@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// service describes a parsed service interface.
+type service struct {
+	Package string
+	Name    string
+	Imports []string // import specs used by method signatures, e.g. `"time"`
+	Methods []method
+}
+
+// method describes a single method of a service interface.
+type method struct {
+	Name     string
+	Params   []field // excluding the leading context.Context
+	Results  []field // excluding the trailing error
+	Variadic bool    // the last param is variadic
+}
+
+// field is a method parameter or result, which becomes a field of the
+// generated request or response struct.
+type field struct {
+	Name  string // local variable name; as declared, or generated if unnamed
+	Field string // exported struct field name
+	JSON  string // JSON object key
+	Type  string // as declared, with ... replaced by []
+
+	variadic bool
+}
+
+// ParamType returns the type as it appears in a parameter list.
+func (f field) ParamType() string {
+	if f.variadic {
+		return "..." + strings.TrimPrefix(f.Type, "[]")
+	}
+	return f.Type
+}
+
+func parseService(filename string, src []byte, name string) (*service, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Map each import's local name to its spec, so the imports used by the
+	// method signatures can be carried into the generated file.
+	imports := map[string]string{}
+	for _, spec := range f.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		local := path[strings.LastIndex(path, "/")+1:]
+		if spec.Name != nil {
+			local = spec.Name.Name
+		}
+		imports[local] = importSpec(spec)
+	}
+	contextName := ""
+	for local, spec := range imports {
+		if strings.HasSuffix(spec, `"context"`) {
+			contextName = local
+		}
+	}
+	if contextName == "" {
+		return nil, errors.New("file doesn't import context")
+	}
+
+	ts, err := findInterface(f, name)
+	if err != nil {
+		return nil, err
+	}
+	it := ts.Type.(*ast.InterfaceType)
+
+	svc := &service{Package: f.Name.Name, Name: ts.Name.Name}
+	used := map[string]bool{}
+	for _, m := range it.Methods.List {
+		ft, ok := m.Type.(*ast.FuncType)
+		if !ok || len(m.Names) == 0 {
+			return nil, fmt.Errorf("%s: embedded interfaces aren't supported", fset.Position(m.Pos()))
+		}
+		meth, err := parseMethod(fset, m.Names[0].Name, ft, contextName)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", fset.Position(m.Pos()), err)
+		}
+		ast.Inspect(ft, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if id, ok := sel.X.(*ast.Ident); ok && id.Name != contextName {
+					used[id.Name] = true
+				}
+			}
+			return true
+		})
+		svc.Methods = append(svc.Methods, meth)
+	}
+	if len(svc.Methods) == 0 {
+		return nil, fmt.Errorf("interface %s has no methods", svc.Name)
+	}
+
+	for local := range used {
+		if spec, ok := imports[local]; ok && !generatedImports[spec] {
+			svc.Imports = append(svc.Imports, spec)
+		}
+	}
+	sort.Strings(svc.Imports)
+	return svc, nil
+}
+
+func findInterface(f *ast.File, name string) (*ast.TypeSpec, error) {
+	var found []*ast.TypeSpec
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			if _, ok := ts.Type.(*ast.InterfaceType); !ok {
+				continue
+			}
+			if name == "" || ts.Name.Name == name {
+				found = append(found, ts)
+			}
+		}
+	}
+	switch {
+	case len(found) == 1:
+		return found[0], nil
+	case len(found) == 0 && name != "":
+		return nil, fmt.Errorf("interface %s not found", name)
+	case len(found) == 0:
+		return nil, errors.New("no interface found")
+	default:
+		return nil, errors.New("multiple interfaces found; use -interface to pick one")
+	}
+}
+
+func parseMethod(fset *token.FileSet, name string, ft *ast.FuncType, contextName string) (method, error) {
+	m := method{Name: name}
+
+	params := expand(fset, ft.Params, "arg")
+	if len(params) == 0 || params[0].Type != contextName+".Context" {
+		return m, fmt.Errorf("%s: first parameter must be a context.Context", name)
+	}
+	m.Params = params[1:]
+	m.Variadic = params[len(params)-1].variadic
+
+	results := expand(fset, ft.Results, "v")
+	if len(results) == 0 || results[len(results)-1].Type != "error" {
+		return m, fmt.Errorf("%s: last result must be an error", name)
+	}
+	m.Results = results[:len(results)-1]
+	if len(m.Results) == 1 && ft.Results.List[0].Names == nil {
+		m.Results[0].Name, m.Results[0].Field, m.Results[0].JSON = "v", "V", "v"
+	}
+	return m, nil
+}
+
+// reserved names are used by the generated code, and are suffixed with an
+// underscore when they appear as parameter or result names.
+var reserved = map[string]bool{"ctx": true, "e": true, "s": true, "req": true, "resp": true, "err": true}
+
+// expand flattens a field list into one field per name, inventing names for
+// unnamed fields from the given prefix.
+func expand(fset *token.FileSet, fl *ast.FieldList, prefix string) []field {
+	if fl == nil {
+		return nil
+	}
+	var fields []field
+	for _, f := range fl.List {
+		typ := f.Type
+		e, variadic := typ.(*ast.Ellipsis)
+		if variadic {
+			typ = &ast.ArrayType{Elt: e.Elt}
+		}
+		var buf bytes.Buffer
+		printer.Fprint(&buf, fset, typ)
+
+		names := f.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{ast.NewIdent(prefix + strconv.Itoa(len(fields)))}
+		}
+		for _, n := range names {
+			name := n.Name
+			if name == "_" {
+				name = prefix + strconv.Itoa(len(fields))
+			}
+			local := name
+			if reserved[local] {
+				local += "_"
+			}
+			fields = append(fields, field{
+				Name:  local,
+				Field: exported(name),
+				JSON:  name,
+				Type:  buf.String(),
+
+				variadic: variadic,
+			})
+		}
+	}
+	return fields
+}
+
+func exported(name string) string {
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+func importSpec(spec *ast.ImportSpec) string {
+	if spec.Name != nil {
+		return spec.Name.Name + " " + spec.Path.Value
+	}
+	return spec.Path.Value
+}
@@ -0,0 +1,196 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Option sets an optional parameter for Load.
+type Option func(*loader)
+
+type loader struct {
+	file      string
+	unmarshal func(data []byte, v interface{}) error
+	envPrefix string
+	lookupEnv func(string) (string, bool)
+	flags     *flag.FlagSet
+	args      []string
+}
+
+// WithFile reads settings from the file, as JSON, into the struct, as with
+// json.Unmarshal. A missing file is an error.
+func WithFile(path string) Option {
+	return func(l *loader) { l.file = path }
+}
+
+// WithUnmarshal sets the func which decodes the file, e.g. the Unmarshal
+// func of a YAML package, for files in YAML. By default, it's json.Unmarshal.
+func WithUnmarshal(unmarshal func(data []byte, v interface{}) error) Option {
+	return func(l *loader) { l.unmarshal = unmarshal }
+}
+
+// WithEnvPrefix prefixes the names of all environment variables, e.g. with
+// "ORDERS_".
+func WithEnvPrefix(prefix string) Option {
+	return func(l *loader) { l.envPrefix = prefix }
+}
+
+// WithLookupEnv sets the func which looks up environment variables. By
+// default, it's os.LookupEnv.
+func WithLookupEnv(lookupEnv func(string) (string, bool)) Option {
+	return func(l *loader) { l.lookupEnv = lookupEnv }
+}
+
+// WithFlags defines flags for fields with flag tags on the FlagSet, and
+// parses the args with it, e.g. os.Args[1:]. By default, flags aren't used.
+func WithFlags(fs *flag.FlagSet, args []string) Option {
+	return func(l *loader) { l.flags, l.args = fs, args }
+}
+
+// Load populates the struct v points to from its sources, and validates it.
+// Errors in values, and validation errors, are reported together, as Errors.
+//
+// Fields may be strings, bools, integers, floats, time.Durations, slices of
+// them, as comma-separated values, or implement encoding.TextUnmarshaler or
+// flag.Value, e.g. *level.LevelVar, or be structs of more fields. Their tags
+// are
+//
+//	default   the value if it's not set by any other source
+//	env       the environment variable, or the prefix for a struct
+//	flag      the flag, or the prefix for a struct
+//	usage     the usage of the flag
+//	validate  rules: required, min=x, max=x, oneof=a b c, separated by commas
+func Load(v interface{}, options ...Option) error {
+	l := loader{
+		unmarshal: json.Unmarshal,
+		lookupEnv: os.LookupEnv,
+	}
+	for _, option := range options {
+		option(&l)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("config: Load needs a pointer to a struct")
+	}
+
+	var errs Errors
+	fields := collect(rv.Elem(), l.envPrefix, "", "")
+
+	for _, f := range fields {
+		if d, ok := f.tag.Lookup("default"); ok && f.value.IsZero() {
+			if err := set(f.value, d); err != nil {
+				errs = append(errs, Error{Field: f.path, Err: fmt.Errorf("default: %w", err)})
+			}
+		}
+	}
+
+	if l.file != "" {
+		data, err := os.ReadFile(l.file)
+		if err != nil {
+			return err
+		}
+		if err := l.unmarshal(data, v); err != nil {
+			return fmt.Errorf("config: decoding %s: %w", l.file, err)
+		}
+	}
+
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		if s, ok := l.lookupEnv(f.env); ok {
+			if err := set(f.value, s); err != nil {
+				errs = append(errs, Error{Field: f.path, Err: fmt.Errorf("%s: %w", f.env, err)})
+			}
+		}
+	}
+
+	if l.flags != nil {
+		for _, f := range fields {
+			if f.flag != "" {
+				l.flags.Var(flagValue{f.value}, f.flag, f.tag.Get("usage"))
+			}
+		}
+		if err := l.flags.Parse(l.args); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range fields {
+		if err := validate(f.value, f.tag.Get("validate")); err != nil {
+			errs = append(errs, Error{Field: f.path, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Error is an error in the value of a field.
+type Error struct {
+	Field string // path, e.g. "Consul.Addr"
+	Err   error
+}
+
+func (e Error) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e Error) Unwrap() error {
+	return e.Err
+}
+
+// Errors are the errors in the values of fields.
+type Errors []Error
+
+func (e Errors) Error() string {
+	s := make([]string, len(e))
+	for i, err := range e {
+		s[i] = err.Error()
+	}
+	return "config: " + strings.Join(s, "; ")
+}
+
+type field struct {
+	path  string
+	value reflect.Value
+	tag   reflect.StructTag
+	env   string
+	flag  string
+}
+
+// collect returns the settable fields of the struct, and of the structs in
+// it, with their full environment variable and flag names.
+func collect(v reflect.Value, envPrefix, flagPrefix, pathPrefix string) []field {
+	var fields []field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" { // unexported
+			continue
+		}
+		fv := v.Field(i)
+		path := pathPrefix + sf.Name
+		env, hasEnv := sf.Tag.Lookup("env")
+		fl, hasFlag := sf.Tag.Lookup("flag")
+		if sf.Type.Kind() == reflect.Struct && !isValue(fv) {
+			fields = append(fields, collect(fv, envPrefix+env, flagPrefix+fl, path+".")...)
+			continue
+		}
+		f := field{path: path, value: fv, tag: sf.Tag}
+		if hasEnv && env != "" {
+			f.env = envPrefix + env
+		}
+		if hasFlag && fl != "" {
+			f.flag = flagPrefix + fl
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
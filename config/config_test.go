@@ -0,0 +1,122 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/barrett370/kit/v2/log/level"
+)
+
+type consulConfig struct {
+	Addr string   `json:"addr" env:"ADDR" flag:"addr" default:"localhost:8500"`
+	Tags []string `json:"tags" env:"TAGS"`
+}
+
+type testConfig struct {
+	Addr     string         `json:"addr" env:"ADDR" flag:"addr" default:":8080" validate:"required"`
+	Timeout  time.Duration  `json:"timeout" env:"TIMEOUT" flag:"timeout" default:"5s" validate:"min=1ms"`
+	Rate     float64        `json:"rate" env:"RATE" default:"100" validate:"min=0"`
+	Retries  int            `json:"retries" flag:"retries" validate:"min=0,max=10"`
+	Debug    bool           `json:"debug" flag:"debug"`
+	Mode     string         `json:"mode" env:"MODE" default:"live" validate:"oneof=live shadow"`
+	LogLevel level.LevelVar `json:"-" env:"LOG_LEVEL" flag:"log.level"`
+	Consul   consulConfig   `json:"consul" env:"CONSUL_" flag:"consul."`
+}
+
+func env(vars map[string]string) Option {
+	return WithLookupEnv(func(k string) (string, bool) {
+		v, ok := vars[k]
+		return v, ok
+	})
+}
+
+func TestLoadDefaults(t *testing.T) {
+	var c testConfig
+	if err := Load(&c, env(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := ":8080", c.Addr; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := 5*time.Second, c.Timeout; want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	if want, have := "localhost:8500", c.Consul.Addr; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"addr": ":9000", "rate": 5, "consul": {"addr": "consul:8500"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		c  testConfig
+		fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	)
+	err := Load(&c,
+		WithFile(path),
+		WithEnvPrefix("APP_"),
+		env(map[string]string{"APP_RATE": "7.5", "APP_CONSUL_TAGS": "a, b", "APP_LOG_LEVEL": "debug", "APP_ADDR": ":9001"}),
+		WithFlags(fs, []string{"-addr", ":9002", "-debug", "-consul.addr", "flag:8500", "-retries", "3"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name       string
+		want, have interface{}
+	}{
+		{"addr from flag", ":9002", c.Addr},
+		{"rate from env", 7.5, c.Rate},
+		{"tags from env", []string{"a", "b"}, c.Consul.Tags},
+		{"consul addr from flag", "flag:8500", c.Consul.Addr},
+		{"debug from flag", true, c.Debug},
+		{"retries from flag", 3, c.Retries},
+		{"log level from env", "debug", c.LogLevel.String()},
+		{"timeout from default", 5 * time.Second, c.Timeout},
+	} {
+		if !reflect.DeepEqual(tc.want, tc.have) {
+			t.Errorf("%s: want %v, have %v", tc.name, tc.want, tc.have)
+		}
+	}
+}
+
+func TestLoadValidation(t *testing.T) {
+	var c testConfig
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	err := Load(&c,
+		env(map[string]string{"TIMEOUT": "0s", "RATE": "fast", "MODE": "dark"}),
+		WithFlags(fs, []string{"-retries", "11"}),
+	)
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("want Errors, have %v", err)
+	}
+	var fields []string
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+	if want, have := []string{"Rate", "Timeout", "Retries", "Mode"}, fields; !reflect.DeepEqual(want, have) {
+		t.Errorf("want errors in %v, have %v (%v)", want, have, err)
+	}
+	if !strings.Contains(err.Error(), `"dark" is not one of live shadow`) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestLoadNotStruct(t *testing.T) {
+	var s string
+	if err := Load(&s); err == nil {
+		t.Error("want error, have nil")
+	}
+}
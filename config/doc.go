@@ -0,0 +1,17 @@
+// Package config populates structs of settings, e.g. for servers, clients,
+// rate limiters, circuit breakers, metrics and logging, from defaults, a
+// file, environment variables and flags, as described by struct tags, and
+// validates them, so that wiring components is declarative:
+//
+//	type Config struct {
+//		Addr     string          `env:"ADDR" flag:"addr" default:":8080" usage:"listen address"`
+//		Timeout  time.Duration   `env:"TIMEOUT" flag:"timeout" default:"5s" validate:"min=1ms"`
+//		Rate     float64         `env:"RATE" default:"100" validate:"min=0"`
+//		LogLevel level.LevelVar  `env:"LOG_LEVEL" flag:"log.level"`
+//		Consul   ConsulConfig    `env:"CONSUL_" flag:"consul."`
+//	}
+//
+// Later sources override earlier ones: defaults, then the file, then the
+// environment, then flags. The env and flag tags of struct fields are
+// prefixes for the fields of the struct.
+package config
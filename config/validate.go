@@ -0,0 +1,88 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// validate checks v against the comma-separated rules.
+func validate(v reflect.Value, rules string) error {
+	if rules == "" {
+		return nil
+	}
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "required":
+			if v.IsZero() {
+				return errors.New("required")
+			}
+		case "min", "max":
+			c, err := compare(v, arg)
+			if err != nil {
+				return fmt.Errorf("invalid rule %q: %w", rule, err)
+			}
+			if name == "min" && c < 0 {
+				return fmt.Errorf("%s is less than %s", flagValue{v}, arg)
+			}
+			if name == "max" && c > 0 {
+				return fmt.Errorf("%s is more than %s", flagValue{v}, arg)
+			}
+		case "oneof":
+			s := flagValue{v}.String()
+			if !contains(strings.Fields(arg), s) {
+				return fmt.Errorf("%q is not one of %s", s, arg)
+			}
+		default:
+			return fmt.Errorf("unknown rule %q", rule)
+		}
+	}
+	return nil
+}
+
+// compare compares a number with the limit, parsed as the same type, or the
+// length of a string or slice with the limit, as an int.
+func compare(v reflect.Value, limit string) (int, error) {
+	if k := v.Kind(); k == reflect.String || k == reflect.Slice {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return 0, err
+		}
+		return sign(float64(v.Len() - n)), nil
+	}
+	l := reflect.New(v.Type()).Elem()
+	if err := set(l, limit); err != nil {
+		return 0, err
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return sign(float64(v.Int()) - float64(l.Int())), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return sign(float64(v.Uint()) - float64(l.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return sign(v.Float() - l.Float()), nil
+	}
+	return 0, fmt.Errorf("can't compare %s", v.Type())
+}
+
+func sign(f float64) int {
+	switch {
+	case f < 0:
+		return -1
+	case f > 0:
+		return 1
+	}
+	return 0
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+// Package contextkey provides typed keys for context values, which set and
+// get values of their type without the type assertions of context.Value.
+//
+//	var UserKey = contextkey.New[*User]("User")
+//
+//	ctx = UserKey.Set(ctx, user)
+//	user, ok := UserKey.Get(ctx)
+//
+// Keys returned by New are pointers, so each is distinct from every other key,
+// even those with the same name and type. They're ordinary context keys too,
+// so values set with context.WithValue(ctx, UserKey, user) are got by
+// UserKey.Get, and those set by Set are got by ctx.Value(UserKey).
+//
+// Packages whose context keys predate this package keep them, as constants,
+// and declare typed keys for the same values with For.
+package contextkey
+
+import (
+	"context"
+	"fmt"
+)
+
+// Key is a typed key for context values of type T.
+type Key[T any] struct {
+	key  interface{}
+	name string
+}
+
+// New returns a new key for values of type T. The name is only used to
+// describe the key, e.g. when the context is printed.
+func New[T any](name string) *Key[T] {
+	k := &Key[T]{name: name}
+	k.key = k
+	return k
+}
+
+// For returns a key for values of type T stored in contexts under key, which
+// must be comparable, e.g. an untyped context key constant. Values set with
+// either key are got by both.
+func For[T any](key interface{}) *Key[T] {
+	return &Key[T]{key: key, name: fmt.Sprint(key)}
+}
+
+// Set returns a copy of ctx carrying the value under the key.
+func (k *Key[T]) Set(ctx context.Context, v T) context.Context {
+	return context.WithValue(ctx, k.key, v)
+}
+
+// Get returns the value under the key, and whether ctx carries a value of
+// type T under it.
+func (k *Key[T]) Get(ctx context.Context) (T, bool) {
+	v, ok := ctx.Value(k.key).(T)
+	return v, ok
+}
+
+// Value returns the value under the key, or the zero value of T if ctx
+// doesn't carry one.
+func (k *Key[T]) Value(ctx context.Context) T {
+	v, _ := k.Get(ctx)
+	return v
+}
+
+// String returns the name of the key.
+func (k *Key[T]) String() string {
+	return k.name
+}
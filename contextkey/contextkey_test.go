@@ -0,0 +1,67 @@
+package contextkey_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/barrett370/kit/v2/contextkey"
+)
+
+func TestKey(t *testing.T) {
+	var (
+		key   = contextkey.New[string]("name")
+		other = contextkey.New[string]("name")
+		ctx   = key.Set(context.Background(), "a")
+	)
+	if have, ok := key.Get(ctx); !ok || have != "a" {
+		t.Errorf("want a, have %q (%v)", have, ok)
+	}
+	if have, ok := other.Get(ctx); ok {
+		t.Errorf("want nothing for a key of the same name, have %q", have)
+	}
+	if want, have := "", other.Value(ctx); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "name", fmt.Sprint(key); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestKeyContextValue(t *testing.T) {
+	key := contextkey.New[int]("n")
+
+	// Keys are ordinary context keys too.
+	ctx := context.WithValue(context.Background(), key, 1)
+	if want, have := 1, key.Value(ctx); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	ctx = key.Set(ctx, 2)
+	if want, have := 2, ctx.Value(key); want != have {
+		t.Errorf("want %d, have %v", want, have)
+	}
+
+	// Values of other types aren't got.
+	ctx = context.WithValue(ctx, key, "3")
+	if _, ok := key.Get(ctx); ok {
+		t.Error("want no value of another type")
+	}
+}
+
+func TestFor(t *testing.T) {
+	type legacyKey string
+	const legacy legacyKey = "legacy"
+	key := contextkey.For[string](legacy)
+
+	ctx := context.WithValue(context.Background(), legacy, "a")
+	if want, have := "a", key.Value(ctx); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	ctx = key.Set(ctx, "b")
+	if want, have := "b", ctx.Value(legacy); want != have {
+		t.Errorf("want %q, have %v", want, have)
+	}
+	if want, have := "legacy", fmt.Sprint(key); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
@@ -0,0 +1,90 @@
+package deadline
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/barrett370/kit/v2/endpoint"
+)
+
+// BudgetExceeded is returned by clients when the remaining budget of a
+// request can't cover the minimum of a call.
+type BudgetExceeded struct {
+	Remaining time.Duration
+	Minimum   time.Duration
+}
+
+func (e *BudgetExceeded) Error() string {
+	return fmt.Sprintf("deadline budget exceeded: %v remaining, %v required", e.Remaining, e.Minimum)
+}
+
+// Is returns true for context.DeadlineExceeded, so errors.Is treats budgets
+// which are exceeded like deadlines which are.
+func (e *BudgetExceeded) Is(target error) bool {
+	return target == context.DeadlineExceeded
+}
+
+// StatusCode implements the StatusCoder interface of package transport/http.
+func (e *BudgetExceeded) StatusCode() int {
+	return http.StatusGatewayTimeout
+}
+
+// Remaining returns the remaining budget of the request, and whether it has
+// one.
+func Remaining(ctx context.Context) (time.Duration, bool) {
+	d, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	return time.Until(d), true
+}
+
+type incomingKey struct{}
+
+// contextWithIncoming returns a context with the deadline of the caller, for
+// NewMiddleware to apply.
+func contextWithIncoming(ctx context.Context, d time.Time) context.Context {
+	return context.WithValue(ctx, incomingKey{}, d)
+}
+
+// NewMiddleware returns an endpoint middleware which sets the budget of
+// requests, by setting the deadline of their contexts to budget from now, or
+// to the deadline propagated by the caller, e.g. with HTTPToContext, or of
+// the context, if they're sooner. If budget is 0, only those deadlines apply.
+func NewMiddleware[I, O any](budget time.Duration) endpoint.Middleware[I, O] {
+	return func(next endpoint.Endpoint[I, O]) endpoint.Endpoint[I, O] {
+		return func(ctx context.Context, request I) (O, error) {
+			var d time.Time
+			if budget > 0 {
+				d = time.Now().Add(budget)
+			}
+			if incoming, ok := ctx.Value(incomingKey{}).(time.Time); ok && (d.IsZero() || incoming.Before(d)) {
+				d = incoming
+			}
+			if d.IsZero() {
+				return next(ctx, request)
+			}
+			ctx, cancel := context.WithDeadline(ctx, d)
+			defer cancel()
+			return next(ctx, request)
+		}
+	}
+}
+
+// NewClientMiddleware returns an endpoint middleware for clients which fails
+// calls with a *BudgetExceeded at once if the remaining budget of the request
+// is less than the minimum, e.g. the typical latency of the call. Calls of
+// requests without a budget are made as usual.
+func NewClientMiddleware[I, O any](minimum time.Duration) endpoint.Middleware[I, O] {
+	return func(next endpoint.Endpoint[I, O]) endpoint.Endpoint[I, O] {
+		return func(ctx context.Context, request I) (O, error) {
+			if remaining, ok := Remaining(ctx); ok && remaining < minimum {
+				var zero O
+				return zero, &BudgetExceeded{Remaining: remaining, Minimum: minimum}
+			}
+			return next(ctx, request)
+		}
+	}
+}
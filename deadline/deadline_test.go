@@ -0,0 +1,104 @@
+package deadline_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/barrett370/kit/v2/deadline"
+)
+
+func remaining(ctx context.Context, _ string) (time.Duration, error) {
+	d, ok := deadline.Remaining(ctx)
+	if !ok {
+		return -1, nil
+	}
+	return d, nil
+}
+
+func TestMiddleware(t *testing.T) {
+	e := deadline.NewMiddleware[string, time.Duration](time.Second)(remaining)
+	if d, _ := e(context.Background(), ""); d <= 900*time.Millisecond || d > time.Second {
+		t.Errorf("want a budget of about 1s, have %v", d)
+	}
+
+	// A sooner deadline of the context applies.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if d, _ := e(ctx, ""); d > 100*time.Millisecond {
+		t.Errorf("want a budget of at most 100ms, have %v", d)
+	}
+
+	// No budget leaves requests without a deadline.
+	e = deadline.NewMiddleware[string, time.Duration](0)(remaining)
+	if want, have := time.Duration(-1), must(e(context.Background(), "")); want != have {
+		t.Errorf("want no budget, have %v", have)
+	}
+}
+
+func TestClientMiddleware(t *testing.T) {
+	var calls int
+	e := deadline.NewClientMiddleware[string, time.Duration](50 * time.Millisecond)(func(ctx context.Context, request string) (time.Duration, error) {
+		calls++
+		return remaining(ctx, request)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := e(ctx, "")
+	var exceeded *deadline.BudgetExceeded
+	if !errors.As(err, &exceeded) {
+		t.Fatalf("want BudgetExceeded, have %v", err)
+	}
+	if want, have := 50*time.Millisecond, exceeded.Minimum; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want %v to be context.DeadlineExceeded", err)
+	}
+	if want, have := 504, exceeded.StatusCode(); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+
+	for _, ctx := range []context.Context{context.Background(), mustTimeout(t, time.Second)} {
+		if _, err := e(ctx, ""); err != nil {
+			t.Errorf("want no error, have %v", err)
+		}
+	}
+	if want, have := 2, calls; want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+}
+
+func TestHTTPPropagation(t *testing.T) {
+	// The client propagates what remains of its budget.
+	out := httptest.NewRequest("GET", "/", nil)
+	deadline.ContextToHTTP()(mustTimeout(t, 200*time.Millisecond), out)
+	ms, err := strconv.Atoi(out.Header.Get(deadline.TimeoutHeader))
+	if err != nil || ms <= 100 || ms > 200 {
+		t.Fatalf("want about 200ms, have %q", out.Header.Get(deadline.TimeoutHeader))
+	}
+
+	// The server's budget is capped by it.
+	ctx := deadline.HTTPToContext()(context.Background(), out)
+	e := deadline.NewMiddleware[string, time.Duration](time.Second)(remaining)
+	if d := must(e(ctx, "")); d > 200*time.Millisecond {
+		t.Errorf("want a budget of at most 200ms, have %v", d)
+	}
+}
+
+func mustTimeout(t *testing.T, d time.Duration) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func must(d time.Duration, err error) time.Duration {
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
@@ -0,0 +1,12 @@
+// Package deadline maintains a latency budget for each request, as the
+// deadline of its context, which is spent by the calls it makes downstream.
+// Servers set the budget with NewMiddleware, capped by the budget of their
+// caller, if it's propagated. Clients fail fast with NewClientMiddleware
+// when too little of it remains for a call to succeed, rather than making
+// calls which are bound to time out, and propagate what remains to the
+// services they call.
+//
+// Clients which make requests with the context, such as the HTTP client of
+// package transport/http, stop at the deadline on their own. gRPC propagates
+// deadlines itself, in the grpc-timeout header.
+package deadline
@@ -0,0 +1,41 @@
+package deadline
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	httptransport "github.com/barrett370/kit/v2/transport/http"
+)
+
+// TimeoutHeader is the HTTP header which carries the remaining budget of a
+// request, in milliseconds.
+const TimeoutHeader = "X-Request-Timeout-Ms"
+
+// HTTPToContext returns a RequestFunc which reads the budget propagated by
+// the caller from TimeoutHeader, for NewMiddleware to apply. Particularly
+// useful for servers.
+func HTTPToContext() httptransport.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		ms, err := strconv.ParseInt(r.Header.Get(TimeoutHeader), 10, 64)
+		if err != nil || ms < 0 {
+			return ctx
+		}
+		return contextWithIncoming(ctx, time.Now().Add(time.Duration(ms)*time.Millisecond))
+	}
+}
+
+// ContextToHTTP returns a RequestFunc which propagates the remaining budget
+// of the request in TimeoutHeader. Particularly useful for clients.
+func ContextToHTTP() httptransport.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		if remaining, ok := Remaining(ctx); ok {
+			if remaining < 0 {
+				remaining = 0
+			}
+			r.Header.Set(TimeoutHeader, strconv.FormatInt(remaining.Milliseconds(), 10))
+		}
+		return ctx
+	}
+}
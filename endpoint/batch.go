@@ -0,0 +1,139 @@
+package endpoint
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Batcher accumulates individual requests into batches, which it passes to
+// an endpoint of batches, e.g. a client of a bulk API, and returns each
+// caller the response at the index of its request. A batch is made when it
+// reaches its max size, or when its oldest request has waited its max delay.
+//
+// If the batch endpoint fails, all the requests of the batch fail with its
+// error. Responses which implement Failer fail only their own requests.
+type Batcher[I, O any] struct {
+	e        Endpoint[[]I, []O]
+	maxSize  int
+	maxDelay time.Duration
+	timeout  time.Duration
+
+	mtx     sync.Mutex
+	pending []batchCall[I, O]
+	timer   *time.Timer
+}
+
+type batchCall[I, O any] struct {
+	request I
+	result  chan batchResult[O]
+}
+
+type batchResult[O any] struct {
+	response O
+	err      error
+}
+
+// BatcherOption sets an optional parameter for Batchers.
+type BatcherOption[I, O any] func(*Batcher[I, O])
+
+// BatcherTimeout sets the timeout of calls of the batch endpoint, which
+// aren't bound to the contexts of any of its callers. By default, there's
+// none.
+func BatcherTimeout[I, O any](d time.Duration) BatcherOption[I, O] {
+	return func(b *Batcher[I, O]) { b.timeout = d }
+}
+
+// NewBatcher returns a Batcher of the batch endpoint, with batches of up to
+// maxSize requests, made at most maxDelay after their first request.
+func NewBatcher[I, O any](e Endpoint[[]I, []O], maxSize int, maxDelay time.Duration, options ...BatcherOption[I, O]) *Batcher[I, O] {
+	if maxSize < 1 {
+		maxSize = 1
+	}
+	b := &Batcher[I, O]{e: e, maxSize: maxSize, maxDelay: maxDelay}
+	for _, option := range options {
+		option(b)
+	}
+	return b
+}
+
+// Endpoint returns an endpoint of individual requests, which are batched.
+// If the context of a request is done before its batch returns, it returns
+// the context's error, though the request is still made.
+func (b *Batcher[I, O]) Endpoint() Endpoint[I, O] {
+	return func(ctx context.Context, request I) (O, error) {
+		call := batchCall[I, O]{request: request, result: make(chan batchResult[O], 1)}
+		b.add(call)
+		select {
+		case r := <-call.result:
+			return r.response, r.err
+		case <-ctx.Done():
+			var zero O
+			return zero, ctx.Err()
+		}
+	}
+}
+
+func (b *Batcher[I, O]) add(call batchCall[I, O]) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.pending = append(b.pending, call)
+	switch {
+	case len(b.pending) >= b.maxSize:
+		if b.timer != nil {
+			b.timer.Stop()
+			b.timer = nil
+		}
+		go b.do(b.take())
+	case len(b.pending) == 1:
+		b.timer = time.AfterFunc(b.maxDelay, b.flush)
+	}
+}
+
+// flush makes a batch of the pending requests, when the max delay of the
+// oldest of them is elapsed.
+func (b *Batcher[I, O]) flush() {
+	b.mtx.Lock()
+	calls := b.take()
+	b.timer = nil
+	b.mtx.Unlock()
+	if len(calls) > 0 {
+		b.do(calls)
+	}
+}
+
+// take returns the pending requests, and resets them.
+func (b *Batcher[I, O]) take() []batchCall[I, O] {
+	calls := b.pending
+	b.pending = nil
+	return calls
+}
+
+func (b *Batcher[I, O]) do(calls []batchCall[I, O]) {
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if b.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, b.timeout)
+	}
+	defer cancel()
+
+	requests := make([]I, len(calls))
+	for i, call := range calls {
+		requests[i] = call.request
+	}
+	responses, err := b.e(ctx, requests)
+	if err == nil && len(responses) != len(requests) {
+		err = fmt.Errorf("batch endpoint returned %d responses for %d requests", len(responses), len(requests))
+	}
+	for i, call := range calls {
+		if err != nil {
+			call.result <- batchResult[O]{err: err}
+			continue
+		}
+		r := batchResult[O]{response: responses[i]}
+		if f, ok := interface{}(r.response).(Failer); ok && f.Failed() != nil {
+			r.err = f.Failed()
+		}
+		call.result <- r
+	}
+}
@@ -0,0 +1,119 @@
+package endpoint_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/barrett370/kit/v2/endpoint"
+)
+
+type upper struct {
+	s   string
+	err error
+}
+
+func (u upper) Failed() error { return u.err }
+
+// uppers upper-cases strings, in batches, which it records.
+type uppers struct {
+	mtx     sync.Mutex
+	batches [][]string
+}
+
+func (u *uppers) endpoint(_ context.Context, requests []string) ([]upper, error) {
+	u.mtx.Lock()
+	u.batches = append(u.batches, requests)
+	u.mtx.Unlock()
+	responses := make([]upper, len(requests))
+	for i, s := range requests {
+		if s == "" {
+			responses[i].err = errors.New("empty")
+			continue
+		}
+		responses[i].s = strings.ToUpper(s)
+	}
+	return responses, nil
+}
+
+func do(e endpoint.Endpoint[string, upper], requests ...string) ([]upper, []error) {
+	var (
+		wg        sync.WaitGroup
+		responses = make([]upper, len(requests))
+		errs      = make([]error, len(requests))
+	)
+	for i, request := range requests {
+		wg.Add(1)
+		go func(i int, request string) {
+			defer wg.Done()
+			responses[i], errs[i] = e(context.Background(), request)
+		}(i, request)
+	}
+	wg.Wait()
+	return responses, errs
+}
+
+func TestBatcherMaxSize(t *testing.T) {
+	u := &uppers{}
+	e := endpoint.NewBatcher[string, upper](u.endpoint, 3, time.Hour).Endpoint()
+
+	responses, errs := do(e, "a", "", "c")
+	for i, want := range []string{"A", "", "C"} {
+		if have := responses[i].s; want != have {
+			t.Errorf("%d: want %q, have %q", i, want, have)
+		}
+	}
+	if errs[0] != nil || errs[1] == nil || errs[2] != nil {
+		t.Errorf("want only the empty request to fail, have %v", errs)
+	}
+	if want, have := 1, len(u.batches); want != have {
+		t.Errorf("want %d batches, have %v", want, u.batches)
+	}
+}
+
+func TestBatcherMaxDelay(t *testing.T) {
+	u := &uppers{}
+	e := endpoint.NewBatcher[string, upper](u.endpoint, 100, 10*time.Millisecond).Endpoint()
+
+	begin := time.Now()
+	responses, _ := do(e, "a", "b")
+	if took := time.Since(begin); took < 10*time.Millisecond {
+		t.Errorf("want a delay of at least 10ms, have %v", took)
+	}
+	if responses[0].s != "A" || responses[1].s != "B" {
+		t.Errorf("want A and B, have %v", responses)
+	}
+	if want, have := 1, len(u.batches); want != have {
+		t.Errorf("want %d batches, have %v", want, u.batches)
+	}
+}
+
+func TestBatcherError(t *testing.T) {
+	errBatch := errors.New("unavailable")
+	e := endpoint.NewBatcher[string, upper](func(context.Context, []string) ([]upper, error) {
+		return nil, errBatch
+	}, 2, time.Hour).Endpoint()
+	if _, errs := do(e, "a", "b"); errs[0] != errBatch || errs[1] != errBatch {
+		t.Errorf("want %v for all, have %v", errBatch, errs)
+	}
+
+	// Too few responses fail all the requests.
+	e = endpoint.NewBatcher[string, upper](func(context.Context, []string) ([]upper, error) {
+		return []upper{{s: "A"}}, nil
+	}, 2, time.Hour).Endpoint()
+	if _, errs := do(e, "a", "b"); errs[0] == nil || errs[1] == nil {
+		t.Errorf("want errors for all, have %v", errs)
+	}
+}
+
+func TestBatcherContext(t *testing.T) {
+	e := endpoint.NewBatcher[string, upper]((&uppers{}).endpoint, 100, time.Hour).Endpoint()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if _, err := e(ctx, "a"); err != context.DeadlineExceeded {
+		t.Errorf("want %v, have %v", context.DeadlineExceeded, err)
+	}
+}
@@ -0,0 +1,145 @@
+package endpoint
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Set groups named endpoints of heterogeneous types, so that cross-cutting
+// middlewares such as logging, instrumentation, or tracing can be applied to
+// all of them at once, rather than wired by hand to each endpoint.
+//
+// Endpoints are added with Add and retrieved with Get, both of which are
+// typed. Middlewares registered with Use or UseNamed operate on type-erased
+// endpoints, and are applied when the endpoint is retrieved, regardless of
+// whether it was added before or after the middleware was registered.
+//
+// A Set is intended to be built once, during program initialization, and
+// isn't safe for concurrent modification.
+type Set struct {
+	entries     map[string]setEntry
+	middlewares []scopedMiddleware
+}
+
+// NamedMiddleware constructs a type-erased middleware for the endpoint with
+// the given name. It's useful for middlewares which label their output with
+// the endpoint name, e.g. logging or metrics.
+type NamedMiddleware func(name string) Middleware[interface{}, interface{}]
+
+type setEntry struct {
+	endpoint interface{} // Endpoint[I, O]
+	wrap     func(e interface{}, mw Middleware[interface{}, interface{}]) interface{}
+}
+
+type scopedMiddleware struct {
+	mw    NamedMiddleware
+	names map[string]bool // nil means all endpoints
+}
+
+// NewSet returns an empty endpoint set.
+func NewSet() *Set {
+	return &Set{entries: map[string]setEntry{}}
+}
+
+// Add registers the endpoint under the given name, replacing any endpoint
+// previously registered under that name.
+func Add[I, O any](s *Set, name string, e Endpoint[I, O]) {
+	s.entries[name] = setEntry{
+		endpoint: e,
+		wrap: func(e interface{}, mw Middleware[interface{}, interface{}]) interface{} {
+			return unerase[I, O](mw(erase(e.(Endpoint[I, O]))))
+		},
+	}
+}
+
+// Get returns the endpoint registered under the given name, with all of the
+// set's applicable middlewares applied. It returns false if no endpoint is
+// registered under the name, or if its request or response type don't match.
+func Get[I, O any](s *Set, name string) (Endpoint[I, O], bool) {
+	entry, ok := s.entries[name]
+	if !ok {
+		return nil, false
+	}
+	if _, ok := entry.endpoint.(Endpoint[I, O]); !ok {
+		return nil, false
+	}
+
+	e := entry.endpoint
+	for i := len(s.middlewares) - 1; i >= 0; i-- { // reverse
+		m := s.middlewares[i]
+		if m.names != nil && !m.names[name] {
+			continue
+		}
+		e = entry.wrap(e, m.mw(name))
+	}
+	return e.(Endpoint[I, O]), true
+}
+
+// MustGet is like Get, but panics if the endpoint doesn't exist or has a
+// different type. It's intended for use during program initialization.
+func MustGet[I, O any](s *Set, name string) Endpoint[I, O] {
+	e, ok := Get[I, O](s, name)
+	if !ok {
+		var (
+			i I
+			o O
+		)
+		panic(fmt.Sprintf("endpoint: no Endpoint[%T, %T] named %q in set", i, o, name))
+	}
+	return e
+}
+
+// Use applies the middleware to the endpoints with the given names, or to all
+// endpoints in the set if no names are given. Middlewares are treated like
+// Chain: the first middleware registered is the outermost.
+func (s *Set) Use(mw Middleware[interface{}, interface{}], names ...string) {
+	s.UseNamed(func(string) Middleware[interface{}, interface{}] { return mw }, names...)
+}
+
+// UseNamed is like Use, but constructs the middleware separately for each
+// endpoint, passing the endpoint name.
+func (s *Set) UseNamed(mw NamedMiddleware, names ...string) {
+	m := scopedMiddleware{mw: mw}
+	if len(names) > 0 {
+		m.names = make(map[string]bool, len(names))
+		for _, name := range names {
+			m.names[name] = true
+		}
+	}
+	s.middlewares = append(s.middlewares, m)
+}
+
+// Names returns the names of all endpoints in the set, in lexical order.
+func (s *Set) Names() []string {
+	names := make([]string, 0, len(s.entries))
+	for name := range s.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// erase converts a typed endpoint to one that accepts and returns empty
+// interfaces, so it may be wrapped by a type-erased middleware.
+func erase[I, O any](e Endpoint[I, O]) Endpoint[interface{}, interface{}] {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, ok := request.(I)
+		if !ok && request != nil {
+			return nil, fmt.Errorf("endpoint: middleware passed request of type %T, want %T", request, req)
+		}
+		return e(ctx, req)
+	}
+}
+
+// unerase is the inverse of erase.
+func unerase[I, O any](e Endpoint[interface{}, interface{}]) Endpoint[I, O] {
+	return func(ctx context.Context, request I) (O, error) {
+		response, err := e(ctx, request)
+		resp, ok := response.(O)
+		if !ok && response != nil && err == nil {
+			return resp, fmt.Errorf("endpoint: middleware returned response of type %T, want %T", response, resp)
+		}
+		return resp, err
+	}
+}
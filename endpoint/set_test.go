@@ -0,0 +1,69 @@
+package endpoint_test
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/barrett370/kit/v2/endpoint"
+)
+
+func TestSet(t *testing.T) {
+	var calls []string
+	record := func(name string) endpoint.Middleware[any, any] {
+		return func(next endpoint.Endpoint[any, any]) endpoint.Endpoint[any, any] {
+			return func(ctx context.Context, request interface{}) (interface{}, error) {
+				calls = append(calls, name)
+				return next(ctx, request)
+			}
+		}
+	}
+
+	s := endpoint.NewSet()
+	s.UseNamed(record)
+	endpoint.Add(s, "itoa", func(_ context.Context, i int) (string, error) { return strconv.Itoa(i), nil })
+	endpoint.Add(s, "atoi", func(_ context.Context, s string) (int, error) { return strconv.Atoi(s) })
+	s.Use(record("atoi only"), "atoi")
+
+	if want, have := []string{"atoi", "itoa"}, s.Names(); !reflect.DeepEqual(want, have) {
+		t.Errorf("Names: want %v, have %v", want, have)
+	}
+
+	itoa := endpoint.MustGet[int, string](s, "itoa")
+	if str, err := itoa(context.Background(), 42); err != nil || str != "42" {
+		t.Errorf("itoa: want 42, have %q (%v)", str, err)
+	}
+	atoi := endpoint.MustGet[string, int](s, "atoi")
+	if i, err := atoi(context.Background(), "42"); err != nil || i != 42 {
+		t.Errorf("atoi: want 42, have %d (%v)", i, err)
+	}
+	if _, err := atoi(context.Background(), "x"); err == nil {
+		t.Error("atoi: want error, have none")
+	}
+
+	if want, have := []string{"itoa", "atoi", "atoi only", "atoi", "atoi only"}, calls; !reflect.DeepEqual(want, have) {
+		t.Errorf("calls: want %v, have %v", want, have)
+	}
+
+	if _, ok := endpoint.Get[string, string](s, "itoa"); ok {
+		t.Error("Get with wrong types: want false, have true")
+	}
+	if _, ok := endpoint.Get[int, string](s, "missing"); ok {
+		t.Error("Get of missing endpoint: want false, have true")
+	}
+}
+
+func TestSetMiddlewareTypeMismatch(t *testing.T) {
+	s := endpoint.NewSet()
+	endpoint.Add(s, "len", func(_ context.Context, s string) (int, error) { return len(s), nil })
+	s.Use(func(next endpoint.Endpoint[any, any]) endpoint.Endpoint[any, any] {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			return next(ctx, 123)
+		}
+	})
+
+	if _, err := endpoint.MustGet[string, int](s, "len")(context.Background(), "abc"); err == nil {
+		t.Error("want error, have none")
+	}
+}
@@ -0,0 +1,7 @@
+// Package feature provides endpoint middlewares which evaluate feature flags
+// for each request, against the attributes of the request, e.g. its tenant or
+// user, so that experiments and rollouts are run on the server side without
+// touching business logic. Flags are evaluated by a pluggable Provider: the
+// in-memory and file Providers of this package, or an adapter to a feature
+// flag service, such as an OpenFeature client.
+package feature
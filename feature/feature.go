@@ -0,0 +1,126 @@
+package feature
+
+import (
+	"context"
+
+	"github.com/barrett370/kit/v2/endpoint"
+)
+
+// EvalContext is what a flag is evaluated against: the key, e.g. a user ID,
+// which percentage rollouts are stable for, and attributes, e.g. the tenant.
+type EvalContext struct {
+	TargetingKey string
+	Attributes   map[string]string
+}
+
+// EvalContextFunc derives the EvalContext of a request, typically from values
+// stored in the context by authentication middlewares.
+type EvalContextFunc[I any] func(ctx context.Context, request I) EvalContext
+
+// Provider evaluates flags. Unknown flags are off.
+type Provider interface {
+	Enabled(ctx context.Context, flag string, ec EvalContext) (bool, error)
+}
+
+// ProviderFunc is an adapter to allow the use of ordinary functions as
+// Providers, e.g. to adapt an OpenFeature client:
+//
+//	feature.ProviderFunc(func(ctx context.Context, flag string, ec feature.EvalContext) (bool, error) {
+//		attrs := map[string]interface{}{}
+//		for k, v := range ec.Attributes {
+//			attrs[k] = v
+//		}
+//		return client.BooleanValue(ctx, flag, false, openfeature.NewEvaluationContext(ec.TargetingKey, attrs))
+//	})
+type ProviderFunc func(ctx context.Context, flag string, ec EvalContext) (bool, error)
+
+// Enabled implements Provider.
+func (f ProviderFunc) Enabled(ctx context.Context, flag string, ec EvalContext) (bool, error) {
+	return f(ctx, flag, ec)
+}
+
+// Option sets an optional parameter for middlewares.
+type Option func(*options)
+
+type options struct {
+	errorHandler func(ctx context.Context, flag string, err error)
+}
+
+// ErrorHandler sets a func which is called with errors from the Provider,
+// e.g. to log them. The flag is off for requests whose evaluation fails, so
+// they take the established path. By default, errors are ignored.
+func ErrorHandler(f func(ctx context.Context, flag string, err error)) Option {
+	return func(o *options) { o.errorHandler = f }
+}
+
+// NewRouter returns an endpoint middleware which routes requests for which
+// the flag is on to the alternate endpoint, e.g. a new implementation, and
+// others to the next endpoint.
+func NewRouter[I, O any](p Provider, flag string, ecf EvalContextFunc[I], alternate endpoint.Endpoint[I, O], opts ...Option) endpoint.Middleware[I, O] {
+	eval := evaluator(p, flag, ecf, opts)
+	return func(next endpoint.Endpoint[I, O]) endpoint.Endpoint[I, O] {
+		return func(ctx context.Context, request I) (O, error) {
+			if eval(ctx, request) {
+				return alternate(ctx, request)
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// NewMiddleware returns an endpoint middleware which evaluates the flag for
+// each request, and stores the decision in the context, for the service to
+// read with FromContext.
+func NewMiddleware[I, O any](p Provider, flag string, ecf EvalContextFunc[I], opts ...Option) endpoint.Middleware[I, O] {
+	eval := evaluator(p, flag, ecf, opts)
+	return func(next endpoint.Endpoint[I, O]) endpoint.Endpoint[I, O] {
+		return func(ctx context.Context, request I) (O, error) {
+			return next(withDecision(ctx, flag, eval(ctx, request)), request)
+		}
+	}
+}
+
+func evaluator[I any](p Provider, flag string, ecf EvalContextFunc[I], opts []Option) func(context.Context, I) bool {
+	var o options
+	for _, option := range opts {
+		option(&o)
+	}
+	return func(ctx context.Context, request I) bool {
+		var ec EvalContext
+		if ecf != nil {
+			ec = ecf(ctx, request)
+		}
+		enabled, err := p.Enabled(ctx, flag, ec)
+		if err != nil {
+			if o.errorHandler != nil {
+				o.errorHandler(ctx, flag, err)
+			}
+			return false
+		}
+		return enabled
+	}
+}
+
+type contextKey int
+
+const decisionsKey contextKey = 0
+
+// withDecision returns a context with the decision for the flag, as well as
+// any decisions already in it.
+func withDecision(ctx context.Context, flag string, enabled bool) context.Context {
+	prev, _ := ctx.Value(decisionsKey).(map[string]bool)
+	decisions := make(map[string]bool, len(prev)+1)
+	for k, v := range prev {
+		decisions[k] = v
+	}
+	decisions[flag] = enabled
+	return context.WithValue(ctx, decisionsKey, decisions)
+}
+
+// FromContext returns the decision for the flag stored in the context by
+// NewMiddleware, and whether there is one.
+func FromContext(ctx context.Context, flag string) (enabled, ok bool) {
+	decisions, _ := ctx.Value(decisionsKey).(map[string]bool)
+	enabled, ok = decisions[flag]
+	return enabled, ok
+}
@@ -0,0 +1,150 @@
+package feature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+func tenant(ctx context.Context, request string) EvalContext {
+	return EvalContext{TargetingKey: request, Attributes: map[string]string{"tenant": request}}
+}
+
+func named(name string) func(context.Context, string) (string, error) {
+	return func(context.Context, string) (string, error) { return name, nil }
+}
+
+func TestRouter(t *testing.T) {
+	p := NewMemory(map[string]Rule{
+		"new-checkout": {Enabled: true, Attributes: map[string][]string{"tenant": {"acme"}}},
+	})
+	e := NewRouter[string, string](p, "new-checkout", tenant, named("new"))(named("old"))
+	for request, want := range map[string]string{"acme": "new", "initech": "old"} {
+		if have, _ := e(context.Background(), request); want != have {
+			t.Errorf("%s: want %q, have %q", request, want, have)
+		}
+	}
+
+	p.Set("new-checkout", Rule{Enabled: false})
+	if want, have := "old", must(e(context.Background(), "acme")); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestRouterError(t *testing.T) {
+	var (
+		errEval = errors.New("unavailable")
+		p       = ProviderFunc(func(context.Context, string, EvalContext) (bool, error) { return true, errEval })
+		handled error
+		e       = NewRouter[string, string](p, "f", nil, named("new"), ErrorHandler(func(_ context.Context, _ string, err error) { handled = err }))(named("old"))
+	)
+	if want, have := "old", must(e(context.Background(), "")); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := errEval, handled; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	p := NewMemory(map[string]Rule{"a": {Enabled: true}, "b": {Enabled: false}})
+	next := func(ctx context.Context, request string) (string, error) {
+		a, aok := FromContext(ctx, "a")
+		b, bok := FromContext(ctx, "b")
+		_, cok := FromContext(ctx, "c")
+		return fmt.Sprint(a, aok, b, bok, cok), nil
+	}
+	e := NewMiddleware[string, string](p, "a", tenant)(NewMiddleware[string, string](p, "b", tenant)(next))
+	if want, have := "true true false true false", must(e(context.Background(), "")); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestRulePercentage(t *testing.T) {
+	r := Rule{Enabled: true, Percentage: 10}
+	var on int
+	for i := 0; i < 10000; i++ {
+		ec := EvalContext{TargetingKey: fmt.Sprint("user-", i)}
+		enabled := r.match("f", ec)
+		if enabled != r.match("f", ec) {
+			t.Fatalf("%s: want a stable decision", ec.TargetingKey)
+		}
+		if enabled {
+			on++
+		}
+	}
+	if on < 800 || on > 1200 { // 1000 is exact
+		t.Errorf("want about 10%% on, have %d", on)
+	}
+
+	// Growing the rollout keeps the keys which were already on.
+	wider := Rule{Enabled: true, Percentage: 50}
+	for i := 0; i < 1000; i++ {
+		ec := EvalContext{TargetingKey: fmt.Sprint("user-", i)}
+		if r.match("f", ec) && !wider.match("f", ec) {
+			t.Fatalf("%s: want on at 50%%, as at 10%%", ec.TargetingKey)
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.json")
+	write := func(s string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(s), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	enabled := func(p Provider) bool {
+		on, _ := p.Enabled(context.Background(), "f", EvalContext{})
+		return on
+	}
+
+	if _, err := NewFile(path, time.Millisecond, log.NewNopLogger()); err == nil {
+		t.Error("want error for a missing file, have none")
+	}
+
+	write(`{"f": {"enabled": true}}`)
+	f, err := NewFile(path, time.Millisecond, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Stop()
+	if !enabled(f) {
+		t.Error("want f on, have off")
+	}
+
+	write(`{"f": {"enabled": false}}`)
+	waitFor(t, func() bool { return !enabled(f) })
+
+	// A bad file keeps the last good rules.
+	write(`{"f": `)
+	time.Sleep(10 * time.Millisecond)
+	if enabled(f) {
+		t.Error("want f off, have on")
+	}
+}
+
+func must(s string, err error) string {
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,74 @@
+package feature
+
+import (
+	"bytes"
+	"os"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+// File is a Provider of flags from a JSON file, in the format of
+// Memory.LoadJSON, which is read again every interval, so flags are changed
+// by editing it, or the ConfigMap it's mounted from. If the file can't be
+// read or decoded, the last good rules are kept.
+type File struct {
+	*Memory
+	path   string
+	logger log.Logger
+	last   []byte
+	quit   chan struct{}
+	done   chan struct{}
+}
+
+// NewFile returns a File Provider of the flags in the file at path. The
+// file is read at once, and an error is returned if it can't be.
+func NewFile(path string, interval time.Duration, logger log.Logger) (*File, error) {
+	f := &File{
+		Memory: NewMemory(nil),
+		path:   path,
+		logger: log.With(logger, "path", path),
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if err := f.read(); err != nil {
+		return nil, err
+	}
+	go f.loop(interval)
+	return f, nil
+}
+
+func (f *File) loop(interval time.Duration) {
+	defer close(f.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := f.read(); err != nil {
+				f.logger.Log("err", err)
+			}
+		case <-f.quit:
+			return
+		}
+	}
+}
+
+// read reads the file, and loads its rules if it has changed.
+func (f *File) read() error {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	if f.last != nil && bytes.Equal(data, f.last) {
+		return nil
+	}
+	f.last = data // so a bad file is only reported once, until it's changed
+	return f.LoadJSON(bytes.NewReader(data))
+}
+
+// Stop stops reading the file.
+func (f *File) Stop() {
+	close(f.quit)
+	<-f.done
+}
@@ -0,0 +1,107 @@
+package feature
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"io"
+	"sync"
+)
+
+// Rule decides whether a flag is on for an EvalContext.
+type Rule struct {
+	// Enabled switches the flag on. If it's false, the flag is off for all.
+	Enabled bool `json:"enabled"`
+
+	// Percentage limits the flag to a percentage of targeting keys, if it's
+	// between 0 and 100, exclusive; otherwise, it's on for all. The same keys
+	// are chosen each time, and more keys are added as it grows.
+	Percentage float64 `json:"percentage,omitempty"`
+
+	// Attributes limits the flag to EvalContexts whose attributes have one of
+	// the values, e.g. {"tenant": ["acme", "initech"]}.
+	Attributes map[string][]string `json:"attributes,omitempty"`
+}
+
+// match returns true if the flag is on for the EvalContext.
+func (r Rule) match(flag string, ec EvalContext) bool {
+	if !r.Enabled {
+		return false
+	}
+	for k, values := range r.Attributes {
+		if !contains(values, ec.Attributes[k]) {
+			return false
+		}
+	}
+	if r.Percentage > 0 && r.Percentage < 100 {
+		h := fnv.New32a()
+		io.WriteString(h, flag+"/"+ec.TargetingKey)
+		return float64(h.Sum32()%10000) < r.Percentage*100
+	}
+	return true
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Memory is a Provider of flags held in memory, whose rules may be changed at
+// runtime, e.g. from an admin endpoint.
+type Memory struct {
+	mtx   sync.RWMutex
+	rules map[string]Rule
+}
+
+// NewMemory returns a Memory Provider with the rules, by flag.
+func NewMemory(rules map[string]Rule) *Memory {
+	m := &Memory{}
+	m.SetAll(rules)
+	return m
+}
+
+// Set sets the rule of the flag.
+func (m *Memory) Set(flag string, r Rule) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.rules == nil {
+		m.rules = map[string]Rule{}
+	}
+	m.rules[flag] = r
+}
+
+// SetAll replaces all the rules, by flag.
+func (m *Memory) SetAll(rules map[string]Rule) {
+	copied := make(map[string]Rule, len(rules))
+	for flag, r := range rules {
+		copied[flag] = r
+	}
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.rules = copied
+}
+
+// LoadJSON replaces all the rules with those decoded from r, as a JSON object
+// of rules by flag:
+//
+//	{"new-checkout": {"enabled": true, "percentage": 10}}
+func (m *Memory) LoadJSON(r io.Reader) error {
+	var rules map[string]Rule
+	if err := json.NewDecoder(r).Decode(&rules); err != nil {
+		return err
+	}
+	m.SetAll(rules)
+	return nil
+}
+
+// Enabled implements Provider.
+func (m *Memory) Enabled(ctx context.Context, flag string, ec EvalContext) (bool, error) {
+	m.mtx.RLock()
+	r, ok := m.rules[flag]
+	m.mtx.RUnlock()
+	return ok && r.match(flag, ec), nil
+}
@@ -0,0 +1,8 @@
+// Package health aggregates the liveness and readiness checks of a service,
+// and reports them over HTTP, and to gRPC health servers, Registrars, and
+// shutdown sequences.
+//
+// Liveness checks say whether the process is working at all, and should be
+// restarted if not; readiness checks say whether it can serve requests, and
+// should be sent them, e.g. whether its database is reachable.
+package health
@@ -0,0 +1,50 @@
+package health
+
+import "context"
+
+// ServingStatus is the status of a service in the gRPC health checking
+// protocol. Its values are those of grpc_health_v1's
+// HealthCheckResponse_ServingStatus, so it converts directly, without this
+// package depending on gRPC:
+//
+//	func (s *server) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+//		status := s.health.ServingStatus(ctx, req.Service)
+//		return &grpc_health_v1.HealthCheckResponse{
+//			Status: grpc_health_v1.HealthCheckResponse_ServingStatus(status),
+//		}, nil
+//	}
+type ServingStatus int32
+
+// Serving statuses of the gRPC health checking protocol.
+const (
+	StatusUnknown        ServingStatus = 0
+	StatusServing        ServingStatus = 1
+	StatusNotServing     ServingStatus = 2
+	StatusServiceUnknown ServingStatus = 3
+)
+
+// ServingStatus returns the serving status of the service, for the gRPC
+// health checking protocol. The empty service is the server as a whole,
+// which is serving if it's ready; other services are the names of readiness
+// checks.
+func (h *Health) ServingStatus(ctx context.Context, service string) ServingStatus {
+	report := h.Readiness(ctx)
+	if service == "" {
+		return servingStatus(report.Up())
+	}
+	result, ok := report.Checks[service]
+	if !ok {
+		if _, draining := report.Checks["draining"]; draining {
+			return StatusNotServing
+		}
+		return StatusServiceUnknown
+	}
+	return servingStatus(result.Status == StatusUp)
+}
+
+func servingStatus(up bool) ServingStatus {
+	if up {
+		return StatusServing
+	}
+	return StatusNotServing
+}
@@ -0,0 +1,242 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout is the timeout of a check, unless it's set with CheckTimeout.
+const DefaultTimeout = time.Second
+
+// Statuses of checks, and of reports.
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// ErrDraining is the readiness error of a service which is shutting down.
+var ErrDraining = errors.New("shutting down")
+
+// Checker checks a dependency or an invariant of a service, and returns nil if
+// it's healthy. It should respect the context's deadline.
+type Checker func(ctx context.Context) error
+
+// CheckOption sets an optional parameter for a check.
+type CheckOption func(*check)
+
+// CheckTimeout sets the timeout of the check. By default, it's
+// DefaultTimeout.
+func CheckTimeout(d time.Duration) CheckOption {
+	return func(c *check) { c.timeout = d }
+}
+
+// CheckCache reuses the result of the check for d, so that expensive checks,
+// e.g. queries, aren't run by every probe. By default, results aren't reused.
+func CheckCache(d time.Duration) CheckOption {
+	return func(c *check) { c.ttl = d }
+}
+
+// Result is the result of a check.
+type Result struct {
+	Status   string        `json:"status"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Time     time.Time     `json:"time"`
+}
+
+// Report is the aggregated result of checks: up if they're all up.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// Up returns true if the status of the report is up.
+func (r Report) Up() bool {
+	return r.Status == StatusUp
+}
+
+// Err returns an error naming the checks which are down, or nil if the
+// report is up.
+func (r Report) Err() error {
+	if r.Up() {
+		return nil
+	}
+	var down []string
+	for name, result := range r.Checks {
+		if result.Status != StatusUp {
+			down = append(down, name+": "+result.Error)
+		}
+	}
+	sort.Strings(down)
+	return &DownError{Down: down}
+}
+
+// DownError is the error of a report which is down.
+type DownError struct {
+	Down []string // each check which is down, and its error
+}
+
+func (e *DownError) Error() string {
+	s := "health checks failed"
+	for i, d := range e.Down {
+		if i == 0 {
+			s += ": "
+		} else {
+			s += "; "
+		}
+		s += d
+	}
+	return s
+}
+
+type check struct {
+	name    string
+	checker Checker
+	timeout time.Duration
+	ttl     time.Duration
+
+	mtx    sync.Mutex
+	last   Result
+	cached bool
+}
+
+// run runs the check, or returns its cached result.
+func (c *check) run(ctx context.Context) Result {
+	c.mtx.Lock()
+	if c.cached && time.Since(c.last.Time) < c.ttl {
+		defer c.mtx.Unlock()
+		return c.last
+	}
+	c.mtx.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	begin := time.Now()
+	errc := make(chan error, 1) // so a check which ignores the context doesn't block
+	go func() { errc <- c.checker(ctx) }()
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := Result{Status: StatusUp, Duration: time.Since(begin), Time: begin}
+	if err != nil {
+		result.Status, result.Error = StatusDown, err.Error()
+	}
+	if c.ttl > 0 {
+		c.mtx.Lock()
+		c.last, c.cached = result, true
+		c.mtx.Unlock()
+	}
+	return result
+}
+
+// Health is a set of named liveness and readiness checks. It's safe for
+// concurrent use, and checks may be added at any time, e.g. as components are
+// constructed.
+type Health struct {
+	mtx       sync.RWMutex
+	liveness  []*check
+	readiness []*check
+	draining  bool
+}
+
+// New returns a Health with no checks, which is live and ready.
+func New() *Health {
+	return &Health{}
+}
+
+// AddLiveness adds a named liveness check.
+func (h *Health) AddLiveness(name string, checker Checker, options ...CheckOption) {
+	c := newCheck(name, checker, options)
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.liveness = append(h.liveness, c)
+}
+
+// AddReadiness adds a named readiness check.
+func (h *Health) AddReadiness(name string, checker Checker, options ...CheckOption) {
+	c := newCheck(name, checker, options)
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.readiness = append(h.readiness, c)
+}
+
+func newCheck(name string, checker Checker, options []CheckOption) *check {
+	c := &check{name: name, checker: checker, timeout: DefaultTimeout}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// Liveness runs the liveness checks, concurrently, and reports their results.
+func (h *Health) Liveness(ctx context.Context) Report {
+	h.mtx.RLock()
+	checks := h.liveness
+	h.mtx.RUnlock()
+	return run(ctx, checks)
+}
+
+// Readiness runs the readiness checks, concurrently, and reports their
+// results. Once the service is draining, it's down, whatever the checks say.
+func (h *Health) Readiness(ctx context.Context) Report {
+	h.mtx.RLock()
+	checks, draining := h.readiness, h.draining
+	h.mtx.RUnlock()
+	if draining {
+		return Report{Status: StatusDown, Checks: map[string]Result{
+			"draining": {Status: StatusDown, Error: ErrDraining.Error(), Time: time.Now()},
+		}}
+	}
+	return run(ctx, checks)
+}
+
+// Live returns nil if the service is live, or else an error naming the
+// checks which failed.
+func (h *Health) Live(ctx context.Context) error {
+	return h.Liveness(ctx).Err()
+}
+
+// Ready returns nil if the service is ready, or else an error naming the
+// checks which failed. It's an sd.Check, so it can gate the registration of
+// the service, with sd.RegistrarGroupChecks(h.Ready).
+func (h *Health) Ready(ctx context.Context) error {
+	return h.Readiness(ctx).Err()
+}
+
+// Drain makes the service unready from now on, so that load balancers and
+// registrars stop sending it requests, before it's shut down.
+func (h *Health) Drain() {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.draining = true
+}
+
+func run(ctx context.Context, checks []*check) Report {
+	var (
+		report  = Report{Status: StatusUp, Checks: make(map[string]Result, len(checks))}
+		results = make([]Result, len(checks))
+		wg      sync.WaitGroup
+	)
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			results[i] = c.run(ctx)
+		}(i, c)
+	}
+	wg.Wait()
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
@@ -0,0 +1,139 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/barrett370/kit/v2/sd"
+)
+
+var _ sd.Check = New().Ready // API check
+
+func up(context.Context) error { return nil }
+
+func TestReadiness(t *testing.T) {
+	h := New()
+	if err := h.Ready(context.Background()); err != nil {
+		t.Fatalf("want ready with no checks, have %v", err)
+	}
+
+	h.AddReadiness("db", up)
+	h.AddReadiness("cache", func(context.Context) error { return errors.New("connection refused") })
+	h.AddLiveness("loop", up)
+
+	report := h.Readiness(context.Background())
+	if want, have := StatusDown, report.Status; want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	if want, have := StatusUp, report.Checks["db"].Status; want != have {
+		t.Errorf("db: want %s, have %s", want, have)
+	}
+	if want, have := "health checks failed: cache: connection refused", h.Ready(context.Background()).Error(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if err := h.Live(context.Background()); err != nil {
+		t.Errorf("want live, have %v", err)
+	}
+}
+
+func TestCheckTimeout(t *testing.T) {
+	var (
+		h     = New()
+		stuck = make(chan struct{})
+	)
+	defer close(stuck)
+	h.AddLiveness("stuck", func(context.Context) error { <-stuck; return nil }, CheckTimeout(10*time.Millisecond))
+	begin := time.Now()
+	result := h.Liveness(context.Background()).Checks["stuck"]
+	if want, have := context.DeadlineExceeded.Error(), result.Error; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if d := time.Since(begin); d > time.Second {
+		t.Errorf("check took %s, despite its timeout", d)
+	}
+}
+
+func TestCheckCache(t *testing.T) {
+	var (
+		h     = New()
+		calls int32
+	)
+	h.AddReadiness("db", func(context.Context) error { atomic.AddInt32(&calls, 1); return nil }, CheckCache(time.Hour))
+	for i := 0; i < 3; i++ {
+		h.Ready(context.Background())
+	}
+	if want, have := int32(1), atomic.LoadInt32(&calls); want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	h := New()
+	h.AddReadiness("db", up)
+	h.Drain()
+	if err := h.Ready(context.Background()); err == nil {
+		t.Error("want unready when draining, have ready")
+	}
+	if want, have := StatusNotServing, h.ServingStatus(context.Background(), "db"); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	var (
+		h    = New()
+		fail int32
+	)
+	h.AddReadiness("db", func(context.Context) error {
+		if atomic.LoadInt32(&fail) == 1 {
+			return errors.New("timeout")
+		}
+		return nil
+	})
+	server := httptest.NewServer(h.ReadinessHandler())
+	defer server.Close()
+
+	get := func() (int, Report) {
+		t.Helper()
+		resp, err := http.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var report Report
+		if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, report
+	}
+
+	if code, report := get(); code != http.StatusOK || report.Checks["db"].Status != StatusUp {
+		t.Errorf("want 200 and db up, have %d and %+v", code, report)
+	}
+	atomic.StoreInt32(&fail, 1)
+	if code, report := get(); code != http.StatusServiceUnavailable || report.Checks["db"].Error != "timeout" {
+		t.Errorf("want 503 and db timeout, have %d and %+v", code, report)
+	}
+}
+
+func TestServingStatus(t *testing.T) {
+	h := New()
+	h.AddReadiness("orders", up)
+	h.AddReadiness("payments", func(context.Context) error { return errors.New("down") })
+	for service, want := range map[string]ServingStatus{
+		"":         StatusNotServing,
+		"orders":   StatusServing,
+		"payments": StatusNotServing,
+		"shipping": StatusServiceUnknown,
+	} {
+		if have := h.ServingStatus(context.Background(), service); want != have {
+			t.Errorf("%q: want %d, have %d", service, want, have)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Package instrumenting provides endpoint middleware and transport hooks which
+// record RED metrics: the rate of requests, the rate of errors, and the
+// duration of requests. It works with any of the metrics backends in package
+// metrics.
+package instrumenting
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/metrics"
+	httptransport "github.com/barrett370/kit/v2/transport/http"
+)
+
+// Label names used by the metrics in this package.
+const (
+	LabelMethod    = "method"
+	LabelTransport = "transport"
+	LabelSuccess   = "success"
+	LabelCode      = "code"
+)
+
+// Metrics collects the metrics recorded by the middleware and transport hooks.
+//
+// Requests, Errors and Duration are labeled with method, transport, and
+// success. Responses is labeled with method and code. Backends which need to
+// know label names up front, such as Prometheus, should construct the metrics
+// directly with those label names, rather than via NewMetrics.
+type Metrics struct {
+	Requests  metrics.Counter   // all requests
+	Errors    metrics.Counter   // failed requests
+	Duration  metrics.Histogram // request duration, in seconds
+	Responses metrics.Counter   // transport responses, by status code
+}
+
+// Provider constructs metrics. It's implemented by provider.Provider, and by
+// most of the metrics backends.
+type Provider interface {
+	NewCounter(name string) metrics.Counter
+	NewHistogram(name string, buckets int) metrics.Histogram
+}
+
+// NewMetrics constructs the RED metrics from the provider. Metric names are
+// prefixed with the given prefix and an underscore, unless it's empty.
+func NewMetrics(p Provider, prefix string) *Metrics {
+	if prefix != "" {
+		prefix += "_"
+	}
+	return &Metrics{
+		Requests:  p.NewCounter(prefix + "requests_total"),
+		Errors:    p.NewCounter(prefix + "errors_total"),
+		Duration:  p.NewHistogram(prefix+"request_duration_seconds", 50),
+		Responses: p.NewCounter(prefix + "responses_total"),
+	}
+}
+
+// Middleware returns an endpoint middleware that records a request, its
+// duration, and, if it failed, an error, each labeled with the given method
+// and transport names. A request has failed if the endpoint returned an error,
+// or if the response implements endpoint.Failer and Failed returns an error.
+func Middleware[I, O any](m *Metrics, method, transport string) endpoint.Middleware[I, O] {
+	return func(next endpoint.Endpoint[I, O]) endpoint.Endpoint[I, O] {
+		return func(ctx context.Context, request I) (response O, err error) {
+			defer func(begin time.Time) {
+				failed := err != nil
+				if f, ok := any(response).(endpoint.Failer); ok && f.Failed() != nil {
+					failed = true
+				}
+				lvs := []string{
+					LabelMethod, method,
+					LabelTransport, transport,
+					LabelSuccess, strconv.FormatBool(!failed),
+				}
+				m.Requests.With(lvs...).Add(1)
+				if failed {
+					m.Errors.With(lvs...).Add(1)
+				}
+				m.Duration.With(lvs...).Observe(time.Since(begin).Seconds())
+			}(time.Now())
+			return next(ctx, request)
+		}
+	}
+}
+
+// HTTPServerFinalizer returns a ServerFinalizerFunc which counts responses by
+// status code, labeled with the given method name. Install it on an HTTP
+// server via httptransport.ServerFinalizer.
+func HTTPServerFinalizer(m *Metrics, method string) httptransport.ServerFinalizerFunc {
+	return func(ctx context.Context, code int, _ *http.Request) {
+		m.Responses.With(LabelMethod, method, LabelCode, strconv.Itoa(code)).Add(1)
+	}
+}
@@ -0,0 +1,160 @@
+package instrumenting_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/instrumenting"
+	"github.com/barrett370/kit/v2/metrics"
+	httptransport "github.com/barrett370/kit/v2/transport/http"
+)
+
+func TestMiddleware(t *testing.T) {
+	p := newProvider()
+	m := instrumenting.NewMetrics(p, "svc")
+
+	e := instrumenting.Middleware[string, string](m, "echo", "http")(func(_ context.Context, s string) (string, error) {
+		if s == "" {
+			return "", errors.New("empty")
+		}
+		return s, nil
+	})
+	e(context.Background(), "a")
+	e(context.Background(), "b")
+	e(context.Background(), "")
+
+	for key, want := range map[string]float64{
+		"svc_requests_total{method=echo,transport=http,success=true}":  2,
+		"svc_requests_total{method=echo,transport=http,success=false}": 1,
+		"svc_errors_total{method=echo,transport=http,success=false}":   1,
+		"svc_errors_total{method=echo,transport=http,success=true}":    0,
+	} {
+		if have := p.value(key); want != have {
+			t.Errorf("%s: want %v, have %v", key, want, have)
+		}
+	}
+	if want, have := 3, p.observations("svc_request_duration_seconds"); want != have {
+		t.Errorf("duration observations: want %d, have %d", want, have)
+	}
+}
+
+type failerResponse struct{ err error }
+
+func (r failerResponse) Failed() error { return r.err }
+
+func TestMiddlewareFailer(t *testing.T) {
+	p := newProvider()
+	m := instrumenting.NewMetrics(p, "")
+
+	e := instrumenting.Middleware[struct{}, failerResponse](m, "f", "grpc")(func(context.Context, struct{}) (failerResponse, error) {
+		return failerResponse{errors.New("business error")}, nil
+	})
+	e(context.Background(), struct{}{})
+
+	if want, have := 1.0, p.value("errors_total{method=f,transport=grpc,success=false}"); want != have {
+		t.Errorf("errors: want %v, have %v", want, have)
+	}
+}
+
+func TestHTTPServerFinalizer(t *testing.T) {
+	p := newProvider()
+	m := instrumenting.NewMetrics(p, "")
+
+	server := httptransport.NewServer(
+		endpoint.Endpoint[struct{}, struct{}](func(context.Context, struct{}) (struct{}, error) {
+			return struct{}{}, errors.New("dang")
+		}),
+		func(context.Context, *http.Request) (struct{}, error) { return struct{}{}, nil },
+		func(context.Context, http.ResponseWriter, struct{}) error { return nil },
+		httptransport.ServerFinalizer[struct{}, struct{}](instrumenting.HTTPServerFinalizer(m, "dang")),
+	)
+	server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if want, have := 1.0, p.value("responses_total{method=dang,code=500}"); want != have {
+		t.Errorf("responses: want %v, have %v", want, have)
+	}
+}
+
+// provider records counter values and histogram observations, keyed by
+// metric name and label values.
+type provider struct {
+	mtx  sync.Mutex
+	vals map[string]float64
+	obs  map[string]int
+}
+
+func newProvider() *provider {
+	return &provider{vals: map[string]float64{}, obs: map[string]int{}}
+}
+
+func (p *provider) NewCounter(name string) metrics.Counter {
+	return counter{p: p, name: name}
+}
+
+func (p *provider) NewHistogram(name string, _ int) metrics.Histogram {
+	return histogram{p: p, name: name}
+}
+
+func (p *provider) value(key string) float64 {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.vals[key]
+}
+
+func (p *provider) observations(name string) int {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	var n int
+	for key, count := range p.obs {
+		if strings.HasPrefix(key, name+"{") {
+			n += count
+		}
+	}
+	return n
+}
+
+func key(name string, lvs []string) string {
+	var pairs []string
+	for i := 0; i < len(lvs); i += 2 {
+		pairs = append(pairs, lvs[i]+"="+lvs[i+1])
+	}
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+type counter struct {
+	p    *provider
+	name string
+	lvs  []string
+}
+
+func (c counter) With(labelValues ...string) metrics.Counter {
+	return counter{p: c.p, name: c.name, lvs: append(append([]string{}, c.lvs...), labelValues...)}
+}
+
+func (c counter) Add(delta float64) {
+	c.p.mtx.Lock()
+	defer c.p.mtx.Unlock()
+	c.p.vals[key(c.name, c.lvs)] += delta
+}
+
+type histogram struct {
+	p    *provider
+	name string
+	lvs  []string
+}
+
+func (h histogram) With(labelValues ...string) metrics.Histogram {
+	return histogram{p: h.p, name: h.name, lvs: append(append([]string{}, h.lvs...), labelValues...)}
+}
+
+func (h histogram) Observe(float64) {
+	h.p.mtx.Lock()
+	defer h.p.mtx.Unlock()
+	h.p.obs[key(h.name, h.lvs)]++
+}
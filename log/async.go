@@ -0,0 +1,187 @@
+package log
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/barrett370/kit/v2/metrics"
+)
+
+// ErrAsyncLoggerClosed is returned by the Log method of an AsyncLogger which
+// has been closed.
+var ErrAsyncLoggerClosed = errors.New("async logger closed")
+
+// OverflowPolicy decides what an AsyncLogger does with a log event when its
+// queue is full.
+type OverflowPolicy int
+
+const (
+	// DropNewest discards the event being logged.
+	DropNewest OverflowPolicy = iota
+
+	// DropOldest discards the oldest queued event, to make room for the one
+	// being logged.
+	DropOldest
+
+	// Block waits for room in the queue, applying backpressure to the
+	// caller, as a synchronous logger would.
+	Block
+)
+
+// AsyncOption sets an optional parameter for NewAsyncLogger.
+type AsyncOption func(*AsyncLogger)
+
+// AsyncDroppedCounter counts the log events dropped by the overflow policy.
+func AsyncDroppedCounter(c metrics.Counter) AsyncOption {
+	return func(l *AsyncLogger) { l.droppedCounter = c }
+}
+
+// AsyncErrorHandler sets a func which is called with errors returned by the
+// wrapped logger, which can't be returned to the caller of Log. By default,
+// they're discarded.
+func AsyncErrorHandler(f func(err error)) AsyncOption {
+	return func(l *AsyncLogger) { l.errorHandler = f }
+}
+
+// AsyncLogger moves the encoding and writing of log events off the caller's
+// goroutine, onto a single goroutine fed by a bounded queue. Events are
+// passed to the wrapped logger in the order they're logged, less any dropped
+// by the overflow policy. Since that logger is only called from one
+// goroutine, it needn't be safe for concurrent use.
+type AsyncLogger struct {
+	next           Logger
+	policy         OverflowPolicy
+	droppedCounter metrics.Counter
+	errorHandler   func(error)
+
+	mtx     sync.RWMutex // guards closed, and sends on queue
+	closed  bool
+	queue   chan asyncEvent
+	done    chan struct{}
+	dropped uint64
+}
+
+// asyncEvent is either a log event, or, if flushed is set, a marker which is
+// closed once every event queued before it has been logged.
+type asyncEvent struct {
+	keyvals []interface{}
+	flushed chan struct{}
+}
+
+// NewAsyncLogger returns an AsyncLogger which queues up to bufferSize events
+// for the wrapped logger, and applies the policy to events logged while the
+// queue is full. Call Close to log the queued events and stop the logger's
+// goroutine, e.g. before the program exits.
+func NewAsyncLogger(logger Logger, bufferSize int, policy OverflowPolicy, options ...AsyncOption) *AsyncLogger {
+	l := &AsyncLogger{
+		next:         logger,
+		policy:       policy,
+		errorHandler: func(error) {},
+		queue:        make(chan asyncEvent, bufferSize),
+		done:         make(chan struct{}),
+	}
+	for _, option := range options {
+		option(l)
+	}
+	go l.run()
+	return l
+}
+
+func (l *AsyncLogger) run() {
+	defer close(l.done)
+	for e := range l.queue {
+		if e.flushed != nil {
+			close(e.flushed)
+			continue
+		}
+		if err := l.next.Log(e.keyvals...); err != nil {
+			l.errorHandler(err)
+		}
+	}
+}
+
+// Log implements Logger. It queues the event, and returns without waiting
+// for it to be logged, unless the queue is full and the policy is Block.
+func (l *AsyncLogger) Log(keyvals ...interface{}) error {
+	// The caller may reuse keyvals once Log returns.
+	kvs := make([]interface{}, len(keyvals))
+	copy(kvs, keyvals)
+	e := asyncEvent{keyvals: kvs}
+
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	if l.closed {
+		return ErrAsyncLoggerClosed
+	}
+
+	switch l.policy {
+	case Block:
+		l.queue <- e
+	case DropOldest:
+		for {
+			select {
+			case l.queue <- e:
+				return nil
+			default:
+			}
+			select {
+			case old := <-l.queue:
+				if old.flushed != nil {
+					// Everything queued before the marker has been logged.
+					close(old.flushed)
+				} else {
+					l.drop()
+				}
+			default:
+			}
+		}
+	default:
+		select {
+		case l.queue <- e:
+		default:
+			l.drop()
+		}
+	}
+	return nil
+}
+
+func (l *AsyncLogger) drop() {
+	atomic.AddUint64(&l.dropped, 1)
+	if l.droppedCounter != nil {
+		l.droppedCounter.Add(1)
+	}
+}
+
+// Dropped returns the number of events dropped by the overflow policy.
+func (l *AsyncLogger) Dropped() uint64 {
+	return atomic.LoadUint64(&l.dropped)
+}
+
+// Flush blocks until every event logged before it was called has been passed
+// to the wrapped logger. It returns immediately if the logger is closed.
+func (l *AsyncLogger) Flush() {
+	flushed := make(chan struct{})
+	l.mtx.RLock()
+	if l.closed {
+		l.mtx.RUnlock()
+		return
+	}
+	l.queue <- asyncEvent{flushed: flushed}
+	l.mtx.RUnlock()
+	<-flushed
+}
+
+// Close logs the queued events, and stops the logger's goroutine. Events
+// logged after Close fail with ErrAsyncLoggerClosed. It's safe to call Close
+// more than once.
+func (l *AsyncLogger) Close() error {
+	l.mtx.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.queue)
+	}
+	l.mtx.Unlock()
+	<-l.done
+	return nil
+}
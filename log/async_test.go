@@ -0,0 +1,105 @@
+package log_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/barrett370/kit/v2/log"
+	"github.com/barrett370/kit/v2/metrics/generic"
+)
+
+// gatedLogger records the value of each event. It signals on started when it
+// receives an event, and then waits for the gate to open.
+type gatedLogger struct {
+	started chan struct{}
+	gate    chan struct{}
+	mtx     sync.Mutex
+	values  []interface{}
+}
+
+func newGatedLogger() *gatedLogger {
+	return &gatedLogger{started: make(chan struct{}, 100), gate: make(chan struct{})}
+}
+
+func (l *gatedLogger) Log(keyvals ...interface{}) error {
+	l.started <- struct{}{}
+	<-l.gate
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.values = append(l.values, keyvals[1])
+	return nil
+}
+
+func TestAsyncLoggerOverflow(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		policy log.OverflowPolicy
+		want   []interface{}
+	}{
+		{"drop newest", log.DropNewest, []interface{}{0, 1, 2}},
+		{"drop oldest", log.DropOldest, []interface{}{0, 3, 4}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				next    = newGatedLogger()
+				dropped = generic.NewCounter("dropped")
+				logger  = log.NewAsyncLogger(next, 2, tc.policy, log.AsyncDroppedCounter(dropped))
+			)
+
+			// The first event blocks in the wrapped logger, so the queue
+			// of 2 overflows on the fourth.
+			logger.Log("i", 0)
+			<-next.started
+			for i := 1; i <= 4; i++ {
+				logger.Log("i", i)
+			}
+			close(next.gate)
+			logger.Close()
+
+			if want, have := tc.want, next.values; !equal(want, have) {
+				t.Errorf("want %v, have %v", want, have)
+			}
+			if want, have := uint64(2), logger.Dropped(); want != have {
+				t.Errorf("dropped: want %d, have %d", want, have)
+			}
+			if want, have := 2.0, dropped.Value(); want != have {
+				t.Errorf("dropped counter: want %v, have %v", want, have)
+			}
+		})
+	}
+}
+
+func TestAsyncLoggerFlushClose(t *testing.T) {
+	next := newGatedLogger()
+	close(next.gate)
+	logger := log.NewAsyncLogger(next, 10, log.Block)
+
+	for i := 0; i < 50; i++ {
+		logger.Log("i", i)
+	}
+	logger.Flush()
+	next.mtx.Lock()
+	if want, have := 50, len(next.values); want != have {
+		t.Errorf("after flush: want %d, have %d", want, have)
+	}
+	next.mtx.Unlock()
+
+	logger.Close()
+	if want, have := log.ErrAsyncLoggerClosed, logger.Log("i", 50); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	logger.Flush() // doesn't block once closed
+	logger.Close() // nor does a second Close
+}
+
+func equal(a, b []interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,380 @@
+package log
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// The encoders in this file are fast paths for NewLogfmtLogger and
+// NewJSONLogger. They handle log events whose keys are strings and whose
+// values are of the common types below without reflection or intermediate
+// allocations, and produce exactly the output of the Go kit encoders they
+// replace. For any other event, they report false, and the event is encoded
+// by the Go kit encoder instead.
+
+// maxPooledBuffer is the capacity above which buffers aren't returned to the
+// pool, so that one huge event doesn't pin memory for the life of the process.
+const maxPooledBuffer = 64 << 10
+
+type encodeBuffer struct {
+	b     []byte
+	pairs []jsonPair
+}
+
+var encodeBufferPool = sync.Pool{
+	New: func() interface{} { return &encodeBuffer{b: make([]byte, 0, 512)} },
+}
+
+func getEncodeBuffer() *encodeBuffer {
+	buf := encodeBufferPool.Get().(*encodeBuffer)
+	buf.b = buf.b[:0]
+	buf.pairs = buf.pairs[:0]
+	return buf
+}
+
+func putEncodeBuffer(buf *encodeBuffer) {
+	if cap(buf.b) > maxPooledBuffer {
+		return
+	}
+	for i := range buf.pairs {
+		buf.pairs[i].v = nil // don't retain values
+	}
+	encodeBufferPool.Put(buf)
+}
+
+// appendLogfmt appends the event as a logfmt record to b.
+func appendLogfmt(b []byte, keyvals []interface{}) ([]byte, bool) {
+	for i := 0; i < len(keyvals); i += 2 {
+		k, ok := keyvals[i].(string)
+		if !ok || !validLogfmtKey(k) {
+			return b, false
+		}
+		var v interface{}
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		if i > 0 {
+			b = append(b, ' ')
+		}
+		b = append(b, k...)
+		b = append(b, '=')
+		if b, ok = appendLogfmtValue(b, v); !ok {
+			return b, false
+		}
+	}
+	return append(b, '\n'), true
+}
+
+func appendLogfmtValue(b []byte, v interface{}) ([]byte, bool) {
+	switch x := v.(type) {
+	case nil:
+		return append(b, "null"...), true
+	case string:
+		return appendLogfmtString(b, x)
+	case bool:
+		return strconv.AppendBool(b, x), true
+	case int:
+		return strconv.AppendInt(b, int64(x), 10), true
+	case int64:
+		return strconv.AppendInt(b, x, 10), true
+	case int32:
+		return strconv.AppendInt(b, int64(x), 10), true
+	case uint:
+		return strconv.AppendUint(b, uint64(x), 10), true
+	case uint64:
+		return strconv.AppendUint(b, x, 10), true
+	case uint32:
+		return strconv.AppendUint(b, uint64(x), 10), true
+	case float64:
+		return strconv.AppendFloat(b, x, 'g', -1, 64), true
+	case float32:
+		return strconv.AppendFloat(b, float64(x), 'g', -1, 32), true
+	case time.Time:
+		// time.Time is encoded via MarshalText, which only fails for years
+		// outside [0,9999], and never needs quoting otherwise.
+		if y := x.Year(); y < 0 || y > 9999 {
+			return b, false
+		}
+		return x.AppendFormat(b, time.RFC3339Nano), true
+	case encoding.TextMarshaler:
+		return b, false
+	case error:
+		s, ok := safeErrorString(x)
+		if !ok {
+			return b, false
+		}
+		return appendLogfmtString(b, s)
+	case fmt.Stringer:
+		s, ok := safeStringerString(x)
+		if !ok {
+			return b, false
+		}
+		return appendLogfmtString(b, s)
+	default:
+		return b, false
+	}
+}
+
+func validLogfmtKey(k string) bool {
+	if k == "" {
+		return false
+	}
+	for _, r := range k {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			return false
+		}
+	}
+	return true
+}
+
+// appendLogfmtString appends s, quoted if need be. Strings containing invalid
+// UTF-8, or U+FFFD, are left to the Go kit encoder.
+func appendLogfmtString(b []byte, s string) ([]byte, bool) {
+	if s == "null" {
+		return append(b, `"null"`...), true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			return appendLogfmtQuoted(b, s)
+		}
+	}
+	return append(b, s...), true
+}
+
+const hexDigits = "0123456789abcdef"
+
+func appendLogfmtQuoted(b []byte, s string) ([]byte, bool) {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '\\' && c != '"' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '\\', '"':
+				b = append(b, '\\', c)
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError {
+			return b, false
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"'), true
+}
+
+type jsonPair struct {
+	k string
+	v interface{}
+}
+
+// appendJSON appends the event as a JSON object to buf.b. Like the Go kit
+// encoder, which goes via a map, keys are sorted and the last value of a
+// repeated key wins.
+func appendJSON(buf *encodeBuffer, keyvals []interface{}) bool {
+	for i := 0; i < len(keyvals); i += 2 {
+		k, ok := keyvals[i].(string)
+		if !ok {
+			return false
+		}
+		var v interface{} = ErrMissingValue
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		// Insertion sort, which is stable, and fast for the handful of
+		// keys in a typical event.
+		buf.pairs = append(buf.pairs, jsonPair{k, v})
+		for j := len(buf.pairs) - 1; j > 0 && buf.pairs[j-1].k > k; j-- {
+			buf.pairs[j], buf.pairs[j-1] = buf.pairs[j-1], buf.pairs[j]
+		}
+	}
+
+	b := append(buf.b, '{')
+	first := true
+	for i, p := range buf.pairs {
+		if i+1 < len(buf.pairs) && buf.pairs[i+1].k == p.k {
+			continue
+		}
+		if !first {
+			b = append(b, ',')
+		}
+		first = false
+		var ok bool
+		if b, ok = appendJSONString(b, p.k); !ok {
+			return false
+		}
+		b = append(b, ':')
+		if b, ok = appendJSONValue(b, p.v); !ok {
+			return false
+		}
+	}
+	buf.b = append(b, '}', '\n')
+	return true
+}
+
+func appendJSONValue(b []byte, v interface{}) ([]byte, bool) {
+	switch x := v.(type) {
+	case nil:
+		return append(b, "null"...), true
+	case string:
+		return appendJSONString(b, x)
+	case bool:
+		return strconv.AppendBool(b, x), true
+	case int:
+		return strconv.AppendInt(b, int64(x), 10), true
+	case int64:
+		return strconv.AppendInt(b, x, 10), true
+	case int32:
+		return strconv.AppendInt(b, int64(x), 10), true
+	case uint:
+		return strconv.AppendUint(b, uint64(x), 10), true
+	case uint64:
+		return strconv.AppendUint(b, x, 10), true
+	case uint32:
+		return strconv.AppendUint(b, uint64(x), 10), true
+	case float64:
+		return appendJSONFloat(b, x, 64)
+	case float32:
+		return appendJSONFloat(b, float64(x), 32)
+	case time.Time:
+		if y := x.Year(); y < 0 || y > 9999 {
+			return b, false
+		}
+		b = append(b, '"')
+		b = x.AppendFormat(b, time.RFC3339Nano)
+		return append(b, '"'), true
+	case json.Marshaler, encoding.TextMarshaler:
+		return b, false
+	case error:
+		s, ok := safeErrorString(x)
+		if !ok {
+			return b, false
+		}
+		return appendJSONString(b, s)
+	case fmt.Stringer:
+		s, ok := safeStringerString(x)
+		if !ok {
+			return b, false
+		}
+		return appendJSONString(b, s)
+	default:
+		return b, false
+	}
+}
+
+// appendJSONFloat formats floats like encoding/json.
+func appendJSONFloat(b []byte, f float64, bits int) ([]byte, bool) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return b, false
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	b = strconv.AppendFloat(b, f, format, -1, bits)
+	if format == 'e' {
+		// Clean up e-09 to e-9.
+		if n := len(b); n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	return b, true
+}
+
+// appendJSONString quotes strings like encoding/json, without HTML escaping.
+// Strings containing \b or \f are left to encoding/json, whose escaping of
+// them depends on the Go version.
+func appendJSONString(b []byte, s string) ([]byte, bool) {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '\\' && c != '"' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '\\', '"':
+				b = append(b, '\\', c)
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			case '\b', '\f':
+				return b, false
+			default:
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hexDigits[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"'), true
+}
+
+// safeErrorString returns err.Error(), or false if it panics, e.g. for a nil
+// pointer, which the Go kit encoders handle specially.
+func safeErrorString(err error) (s string, ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	return err.Error(), true
+}
+
+// safeStringerString is like safeErrorString, for fmt.Stringers.
+func safeStringerString(str fmt.Stringer) (s string, ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	return str.String(), true
+}
@@ -0,0 +1,117 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+type stringer string
+
+func (s stringer) String() string { return string(s) }
+
+var ts = time.Date(2021, 2, 3, 4, 5, 6, 700000000, time.UTC)
+
+func TestLogfmtEncoder(t *testing.T) {
+	for _, tc := range []struct {
+		keyvals []interface{}
+		want    string
+	}{
+		{[]interface{}{"a", 1, "b", "c d", "e", true}, `a=1 b="c d" e=true` + "\n"},
+		{[]interface{}{"f", 1.5, "g", float32(0.1), "h", uint64(7), "i", int32(-2)}, "f=1.5 g=0.1 h=7 i=-2\n"},
+		{[]interface{}{"ts", ts, "err", errors.New("no such file"), "s", stringer("x=y")}, `ts=2021-02-03T04:05:06.7Z err="no such file" s="x=y"` + "\n"},
+		{[]interface{}{"q", `say "hi"` + "\n\t\x01", "n", nil, "s", "null", "e", ""}, `q="say \"hi\"\n\t\u0001" n=null s="null" e=` + "\n"},
+		{[]interface{}{"odd"}, "odd=null\n"},
+		{[]interface{}{"unicode", "héllo wörld"}, `unicode="héllo wörld"` + "\n"},
+	} {
+		var buf bytes.Buffer
+		if err := NewLogfmtLogger(&buf).Log(tc.keyvals...); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := tc.want, buf.String(); want != have {
+			t.Errorf("want %#v, have %#v", want, have)
+		}
+	}
+}
+
+func TestJSONEncoder(t *testing.T) {
+	for _, tc := range []struct {
+		keyvals []interface{}
+		want    string
+	}{
+		{[]interface{}{"b", 1, "a", "x", "c", false}, `{"a":"x","b":1,"c":false}` + "\n"},
+		{[]interface{}{"k", 1, "k", 2}, `{"k":2}` + "\n"},
+		{[]interface{}{"f", 1.5, "g", 1e21, "h", 1e-7, "i", 100.0}, `{"f":1.5,"g":1e+21,"h":1e-7,"i":100}` + "\n"},
+		{[]interface{}{"ts", ts, "err", errors.New("oops"), "s", stringer("<b>")}, `{"err":"oops","s":"<b>","ts":"2021-02-03T04:05:06.7Z"}` + "\n"},
+		{[]interface{}{"q", "say \"hi\"\n\x01\u2028", "n", nil}, `{"n":null,"q":"say \"hi\"\n\u0001\u2028"}` + "\n"},
+		{[]interface{}{"odd"}, `{"odd":"(MISSING)"}` + "\n"},
+	} {
+		var buf bytes.Buffer
+		if err := NewJSONLogger(&buf).Log(tc.keyvals...); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := tc.want, buf.String(); want != have {
+			t.Errorf("want %#v, have %#v", want, have)
+		}
+	}
+}
+
+func TestEncoderFallback(t *testing.T) {
+	// Values the fast paths don't handle are encoded by Go kit.
+	keyvals := []interface{}{"m", map[string]int{"a": 1}, 42, "int key"}
+	for name, loggers := range map[string][2]func(w *bytes.Buffer) Logger{
+		"logfmt": {
+			func(w *bytes.Buffer) Logger { return NewLogfmtLogger(w) },
+			func(w *bytes.Buffer) Logger { return log.NewLogfmtLogger(w) },
+		},
+		"json": {
+			func(w *bytes.Buffer) Logger { return NewJSONLogger(w) },
+			func(w *bytes.Buffer) Logger { return log.NewJSONLogger(w) },
+		},
+	} {
+		var want, have bytes.Buffer
+		loggers[1](&want).Log(keyvals...)
+		loggers[0](&have).Log(keyvals...)
+		if want.String() != have.String() {
+			t.Errorf("%s: want %q, have %q", name, want.String(), have.String())
+		}
+	}
+}
+
+var benchKeyvals = []interface{}{
+	"ts", ts,
+	"level", "info",
+	"caller", "service.go:42",
+	"method", "GetUser",
+	"id", 12345,
+	"took", 0.0123,
+	"err", errors.New("not found"),
+}
+
+func BenchmarkLogfmtLogger(b *testing.B) {
+	benchmarkLogger(b, NewLogfmtLogger(ioutil.Discard))
+}
+
+func BenchmarkKitLogfmtLogger(b *testing.B) {
+	benchmarkLogger(b, log.NewLogfmtLogger(ioutil.Discard))
+}
+
+func BenchmarkJSONLogger(b *testing.B) {
+	benchmarkLogger(b, NewJSONLogger(ioutil.Discard))
+}
+
+func BenchmarkKitJSONLogger(b *testing.B) {
+	benchmarkLogger(b, log.NewJSONLogger(ioutil.Discard))
+}
+
+func benchmarkLogger(b *testing.B, logger Logger) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Log(benchKeyvals...)
+	}
+}
@@ -6,10 +6,29 @@ import (
 	"github.com/go-kit/log"
 )
 
+type jsonLogger struct {
+	w    io.Writer
+	slow Logger
+}
+
 // NewJSONLogger returns a Logger that encodes keyvals to the Writer as a
 // single JSON object. Each log event produces no more than one call to
 // w.Write. The passed Writer must be safe for concurrent use by multiple
 // goroutines if the returned Logger will be used concurrently.
+//
+// As with NewLogfmtLogger, common events are encoded without reflection, and
+// others by the Go kit JSON logger, with the same output.
 func NewJSONLogger(w io.Writer) Logger {
-	return log.NewJSONLogger(w)
+	return &jsonLogger{w: w, slow: log.NewJSONLogger(w)}
+}
+
+func (l *jsonLogger) Log(keyvals ...interface{}) error {
+	buf := getEncodeBuffer()
+	defer putEncodeBuffer(buf)
+
+	if !appendJSON(buf, keyvals) {
+		return l.slow.Log(keyvals...)
+	}
+	_, err := l.w.Write(buf.b)
+	return err
 }
@@ -0,0 +1,116 @@
+package level
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// Handler is an http.Handler which gets and sets the level of a LevelVar,
+// and optionally of per-component LevelVars, so that log levels may be
+// changed in production without a restart. Mount it on an admin listener.
+//
+//	GET  /                      {"level":"info","components":{"db":"info"}}
+//	GET  /?component=db         {"level":"info"}
+//	PUT  /?level=debug          {"level":"debug"}
+//	PUT  /?component=db         with body {"level":"debug"}
+//
+// POST is accepted as well as PUT. An unknown component is a 404, and an
+// unknown level a 400.
+type Handler struct {
+	root *LevelVar
+
+	mtx        sync.RWMutex
+	components map[string]*LevelVar
+}
+
+// NewHandler returns a Handler for the root LevelVar.
+func NewHandler(root *LevelVar) *Handler {
+	return &Handler{
+		root:       root,
+		components: map[string]*LevelVar{},
+	}
+}
+
+// Component returns the LevelVar of the named component, which is created
+// with the current level of the root LevelVar on first use. Filter the
+// component's logger with NewVarFilter to make its level adjustable.
+func (h *Handler) Component(name string) *LevelVar {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	v, ok := h.components[name]
+	if !ok {
+		v = &LevelVar{}
+		v.Set(h.root.String())
+		h.components[name] = v
+	}
+	return v
+}
+
+type levelResponse struct {
+	Level      string            `json:"level"`
+	Components map[string]string `json:"components,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v := h.root
+	if name := r.URL.Query().Get("component"); name != "" {
+		h.mtx.RLock()
+		v = h.components[name]
+		h.mtx.RUnlock()
+		if v == nil {
+			writeLevelResponse(w, http.StatusNotFound, levelResponse{Error: "unknown component " + name})
+			return
+		}
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		resp := levelResponse{Level: v.String()}
+		if v == h.root {
+			resp.Components = h.levels()
+		}
+		writeLevelResponse(w, http.StatusOK, resp)
+
+	case http.MethodPut, http.MethodPost:
+		name := r.URL.Query().Get("level")
+		if name == "" {
+			var req levelResponse
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeLevelResponse(w, http.StatusBadRequest, levelResponse{Error: err.Error()})
+				return
+			}
+			name = req.Level
+		}
+		if err := v.Set(name); err != nil {
+			writeLevelResponse(w, http.StatusBadRequest, levelResponse{Error: err.Error()})
+			return
+		}
+		writeLevelResponse(w, http.StatusOK, levelResponse{Level: v.String()})
+
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+		writeLevelResponse(w, http.StatusMethodNotAllowed, levelResponse{Error: "method not allowed"})
+	}
+}
+
+func (h *Handler) levels() map[string]string {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+	if len(h.components) == 0 {
+		return nil
+	}
+	levels := make(map[string]string, len(h.components))
+	for name, v := range h.components {
+		levels[name] = v.String()
+	}
+	return levels
+}
+
+func writeLevelResponse(w http.ResponseWriter, code int, resp levelResponse) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,82 @@
+package level
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/go-kit/log"
+)
+
+// Names of the levels which may be set on a LevelVar. "none" squelches all
+// leveled log events.
+var levelNames = []string{"debug", "info", "warn", "error", "none"}
+
+// LevelVar is a level which may be changed at runtime, e.g. via Handler, to
+// turn on debug logging without a restart. The zero value allows error, warn
+// and info level log events, like AllowInfo. It's safe for concurrent use,
+// and implements flag.Value, so the initial level may be set from a flag.
+type LevelVar struct {
+	allowed int32 // index into levelNames, offset from info
+}
+
+// NewLevelVar returns a LevelVar set to the named level, which is one of
+// "debug", "info", "warn", "error" or "none".
+func NewLevelVar(name string) (*LevelVar, error) {
+	v := &LevelVar{}
+	if err := v.Set(name); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// String returns the name of the current level.
+func (v *LevelVar) String() string {
+	return levelNames[v.index()]
+}
+
+// Set changes the level to the named level, which is one of "debug", "info",
+// "warn", "error" or "none".
+func (v *LevelVar) Set(name string) error {
+	for i, n := range levelNames {
+		if n == name {
+			atomic.StoreInt32(&v.allowed, int32(i-1))
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown level %q", name)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (v *LevelVar) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (v *LevelVar) UnmarshalText(text []byte) error {
+	return v.Set(string(text))
+}
+
+func (v *LevelVar) index() int {
+	return int(atomic.LoadInt32(&v.allowed)) + 1
+}
+
+// NewVarFilter is like NewFilter, but allows the log events permitted by the
+// current level of v, rather than a fixed level, so that changes to v take
+// effect immediately. Allow[Level] options are ignored; other options apply
+// as for NewFilter.
+func NewVarFilter(next log.Logger, v *LevelVar, options ...Option) log.Logger {
+	l := &varFilter{v: v}
+	for i, allow := range []func() Option{AllowDebug, AllowInfo, AllowWarn, AllowError, AllowNone} {
+		l.filters[i] = NewFilter(next, append(append([]Option(nil), options...), allow())...)
+	}
+	return l
+}
+
+type varFilter struct {
+	v       *LevelVar
+	filters [5]log.Logger // indexed like levelNames
+}
+
+func (l *varFilter) Log(keyvals ...interface{}) error {
+	return l.filters[l.v.index()].Log(keyvals...)
+}
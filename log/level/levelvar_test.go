@@ -0,0 +1,98 @@
+package level_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/barrett370/kit/v2/log"
+	"github.com/barrett370/kit/v2/log/level"
+)
+
+func TestVarFilter(t *testing.T) {
+	var buf bytes.Buffer
+	v, err := level.NewLevelVar("warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := level.NewVarFilter(log.NewLogfmtLogger(&buf), v)
+
+	level.Info(logger).Log("n", 1)
+	level.Warn(logger).Log("n", 2)
+	v.Set("debug")
+	level.Debug(logger).Log("n", 3)
+	v.Set("none")
+	level.Error(logger).Log("n", 4)
+
+	if want, have := "level=warn n=2\nlevel=debug n=3\n", buf.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if err := v.Set("verbose"); err == nil {
+		t.Error("want error for unknown level, have nil")
+	}
+	if want, have := "none", v.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	root := &level.LevelVar{}
+	h := level.NewHandler(root)
+	db := h.Component("db")
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	get := func(query string) (int, map[string]interface{}) {
+		resp, err := http.Get(server.URL + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var body map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+	put := func(query, body string) int {
+		req, _ := http.NewRequest("PUT", server.URL+query, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if want, have := http.StatusOK, put("?component=db", `{"level":"debug"}`); want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+	if want, have := "debug", db.String(); want != have {
+		t.Errorf("db: want %q, have %q", want, have)
+	}
+	if want, have := http.StatusOK, put("?level=error", ""); want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+	if want, have := "error", root.String(); want != have {
+		t.Errorf("root: want %q, have %q", want, have)
+	}
+
+	code, body := get("")
+	if want, have := http.StatusOK, code; want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+	if want, have := "error", body["level"]; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "debug", body["components"].(map[string]interface{})["db"]; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	if want, have := http.StatusNotFound, put("?component=cache&level=debug", ""); want != have {
+		t.Errorf("unknown component: want %d, have %d", want, have)
+	}
+	if want, have := http.StatusBadRequest, put("?level=verbose", ""); want != have {
+		t.Errorf("unknown level: want %d, have %d", want, have)
+	}
+}
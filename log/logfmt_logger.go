@@ -6,10 +6,32 @@ import (
 	"github.com/go-kit/log"
 )
 
+type logfmtLogger struct {
+	w    io.Writer
+	slow Logger
+}
+
 // NewLogfmtLogger returns a logger that encodes keyvals to the Writer in
 // logfmt format. Each log event produces no more than one call to w.Write.
 // The passed Writer must be safe for concurrent use by multiple goroutines if
 // the returned Logger will be used concurrently.
+//
+// Events with string keys and values of common types, such as strings,
+// numbers, time.Time and errors, are encoded into pooled buffers without
+// reflection; others are encoded by the Go kit logfmt logger, with the same
+// output.
 func NewLogfmtLogger(w io.Writer) Logger {
-	return log.NewLogfmtLogger(w)
+	return &logfmtLogger{w: w, slow: log.NewLogfmtLogger(w)}
+}
+
+func (l *logfmtLogger) Log(keyvals ...interface{}) error {
+	buf := getEncodeBuffer()
+	defer putEncodeBuffer(buf)
+
+	var ok bool
+	if buf.b, ok = appendLogfmt(buf.b, keyvals); !ok {
+		return l.slow.Log(keyvals...)
+	}
+	_, err := l.w.Write(buf.b)
+	return err
 }
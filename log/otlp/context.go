@@ -0,0 +1,31 @@
+package otlp
+
+import (
+	"context"
+
+	"github.com/go-kit/log"
+)
+
+// SpanContextFunc returns the hex encoded trace and span IDs of the span in
+// the context, or empty strings if there is none. With OpenTelemetry, it's
+//
+//	func(ctx context.Context) (string, string) {
+//		sc := trace.SpanContextFromContext(ctx)
+//		if !sc.IsValid() {
+//			return "", ""
+//		}
+//		return sc.TraceID().String(), sc.SpanID().String()
+//	}
+type SpanContextFunc func(ctx context.Context) (traceID, spanID string)
+
+// WithSpanContext returns a logger which adds the trace and span IDs of the
+// span in the context to each event, under TraceIDKey and SpanIDKey, so that
+// records exported by a Logger are correlated with the trace. The IDs are
+// looked up when each event is logged, via valuers, so the logger may be
+// created before the span is started, e.g. at the start of a request.
+func WithSpanContext(ctx context.Context, logger log.Logger, f SpanContextFunc) log.Logger {
+	return log.With(logger,
+		TraceIDKey, log.Valuer(func() interface{} { traceID, _ := f(ctx); return traceID }),
+		SpanIDKey, log.Valuer(func() interface{} { _, spanID := f(ctx); return spanID }),
+	)
+}
@@ -0,0 +1,388 @@
+// Package otlp provides a Go kit log.Logger which exports log events to an
+// OpenTelemetry collector, or any other receiver of the OpenTelemetry
+// protocol (OTLP), in batches.
+//
+// Events are exported via OTLP/HTTP with JSON encoding, which needs nothing
+// beyond the standard library; receivers which only accept OTLP/gRPC should be
+// fed via a collector. The value of level.Key() sets the severity of each
+// record, the value of "msg" its body, and the remaining key/value pairs its
+// attributes. Trace and span IDs under TraceIDKey and SpanIDKey, e.g. as added
+// by WithSpanContext, correlate the record with a trace.
+//
+// See https://opentelemetry.io/docs/specs/otlp/ for details of the protocol.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// Defaults for Loggers.
+const (
+	DefaultBatchSize     = 512
+	DefaultMaxQueueSize  = 2048
+	DefaultFlushInterval = 5 * time.Second
+	DefaultTimeout       = 10 * time.Second
+)
+
+// Keys whose values set the trace and span IDs of a record, as hex strings.
+const (
+	TraceIDKey = "trace_id"
+	SpanIDKey  = "span_id"
+)
+
+// ErrClosed is returned by the Log method of a Logger which has been closed.
+var ErrClosed = errors.New("otlp logger closed")
+
+// HTTPClient is an interface that models *http.Client.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Option sets an optional parameter for Loggers.
+type Option func(*Logger)
+
+// WithResource adds attributes describing the entity producing the logs, e.g.
+// "deployment.environment", "production". A trailing key without a value is
+// ignored.
+func WithResource(keyvals ...string) Option {
+	return func(l *Logger) {
+		for i := 0; i+1 < len(keyvals); i += 2 {
+			l.resource = append(l.resource, stringAttribute(keyvals[i], keyvals[i+1]))
+		}
+	}
+}
+
+// WithHTTPClient sets the client used to send logs to the receiver. By
+// default, a client with a timeout of DefaultTimeout is used.
+func WithHTTPClient(client HTTPClient) Option {
+	return func(l *Logger) { l.client = client }
+}
+
+// WithHeader adds a header to every export request, e.g. for authentication.
+func WithHeader(key, value string) Option {
+	return func(l *Logger) { l.header.Add(key, value) }
+}
+
+// WithBatchSize sets the maximum number of records per export request. A
+// batch is exported as soon as it's full. By default, it's DefaultBatchSize.
+func WithBatchSize(n int) Option {
+	return func(l *Logger) { l.batchSize = n }
+}
+
+// WithMaxQueueSize sets the maximum number of records waiting to be exported.
+// Records logged while the queue is full, e.g. because the receiver is down,
+// are dropped. By default, it's DefaultMaxQueueSize.
+func WithMaxQueueSize(n int) Option {
+	return func(l *Logger) { l.maxQueueSize = n }
+}
+
+// WithFlushInterval sets the interval at which queued records are exported,
+// even if they don't fill a batch. By default, it's DefaultFlushInterval.
+func WithFlushInterval(d time.Duration) Option {
+	return func(l *Logger) { l.flushInterval = d }
+}
+
+// WithErrorHandler sets a func which is called with errors from exports,
+// which can't be returned to the caller of Log. Don't make it log to the
+// Logger itself. By default, errors are discarded.
+func WithErrorHandler(f func(error)) Option {
+	return func(l *Logger) { l.errorHandler = f }
+}
+
+// Logger is a Go kit log.Logger which exports log events via OTLP.
+type Logger struct {
+	url           string
+	client        HTTPClient
+	header        http.Header
+	resource      []attribute
+	scope         string
+	batchSize     int
+	maxQueueSize  int
+	flushInterval time.Duration
+	errorHandler  func(error)
+
+	mtx     sync.Mutex
+	queue   []logRecord
+	closed  bool
+	dropped uint64
+
+	full    chan struct{}
+	flushes chan chan error
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// New returns a Logger which exports to the receiver's logs endpoint at the
+// URL, e.g. http://localhost:4318/v1/logs. The service name is set as the
+// service.name resource attribute. Call Close to export the queued records
+// before the program exits.
+func New(serviceName, url string, options ...Option) *Logger {
+	l := &Logger{
+		url:           url,
+		client:        &http.Client{Timeout: DefaultTimeout},
+		header:        http.Header{},
+		resource:      []attribute{stringAttribute("service.name", serviceName)},
+		scope:         "github.com/barrett370/kit/v2/log/otlp",
+		batchSize:     DefaultBatchSize,
+		maxQueueSize:  DefaultMaxQueueSize,
+		flushInterval: DefaultFlushInterval,
+		errorHandler:  func(error) {},
+		full:          make(chan struct{}, 1),
+		flushes:       make(chan chan error),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	for _, option := range options {
+		option(l)
+	}
+	go l.run()
+	return l
+}
+
+// Log implements log.Logger. It converts the event to a record and queues it
+// for export, without waiting for it to be exported.
+func (l *Logger) Log(keyvals ...interface{}) error {
+	r := newRecord(keyvals)
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.closed {
+		return ErrClosed
+	}
+	if len(l.queue) >= l.maxQueueSize {
+		l.dropped++
+		return nil
+	}
+	l.queue = append(l.queue, r)
+	if len(l.queue) >= l.batchSize {
+		select {
+		case l.full <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Dropped returns the number of records dropped because the queue was full.
+func (l *Logger) Dropped() uint64 {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.dropped
+}
+
+// Flush exports all queued records, and returns the first error.
+func (l *Logger) Flush() error {
+	errc := make(chan error, 1)
+	select {
+	case l.flushes <- errc:
+		return <-errc
+	case <-l.done:
+		return ErrClosed
+	}
+}
+
+// Close exports all queued records, and stops the logger. Events logged after
+// Close fail with ErrClosed.
+func (l *Logger) Close() error {
+	l.mtx.Lock()
+	if l.closed {
+		l.mtx.Unlock()
+		<-l.done
+		return nil
+	}
+	l.closed = true
+	l.mtx.Unlock()
+	close(l.stop)
+	<-l.done
+	return nil
+}
+
+func (l *Logger) run() {
+	defer close(l.done)
+	ticker := time.NewTicker(l.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.handle(l.export())
+		case <-l.full:
+			l.handle(l.export())
+		case errc := <-l.flushes:
+			errc <- l.export()
+		case <-l.stop:
+			l.handle(l.export())
+			return
+		}
+	}
+}
+
+func (l *Logger) handle(err error) {
+	if err != nil {
+		l.errorHandler(err)
+	}
+}
+
+// export exports the queued records, in batches, and returns the first error.
+// Records in failed batches are dropped, rather than retried, so that a down
+// receiver can't make the queue grow.
+func (l *Logger) export() error {
+	l.mtx.Lock()
+	records := l.queue
+	l.queue = nil
+	l.mtx.Unlock()
+
+	var first error
+	for len(records) > 0 {
+		n := l.batchSize
+		if n > len(records) {
+			n = len(records)
+		}
+		if err := l.send(records[:n]); err != nil && first == nil {
+			first = err
+		}
+		records = records[n:]
+	}
+	return first
+}
+
+func (l *Logger) send(records []logRecord) error {
+	var buf bytes.Buffer
+	if _, err := l.writeTo(&buf, records); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(context.Background(), "POST", l.url, &buf)
+	if err != nil {
+		return err
+	}
+	for k, vs := range l.header {
+		req.Header[k] = append([]string(nil), vs...)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("OTLP receiver returned %s", resp.Status)
+	}
+	return nil
+}
+
+// writeTo writes the records as an OTLP JSON export request to w.
+func (l *Logger) writeTo(w io.Writer, records []logRecord) (int64, error) {
+	if records == nil {
+		records = []logRecord{}
+	}
+	buf, err := json.Marshal(exportRequest{ResourceLogs: []resourceLogs{{
+		Resource: resource{Attributes: l.resource},
+		ScopeLogs: []scopeLogs{{
+			Scope:      scope{Name: l.scope},
+			LogRecords: records,
+		}},
+	}}})
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(buf)
+	return int64(n), err
+}
+
+func newRecord(keyvals []interface{}) logRecord {
+	now := strconv.FormatInt(time.Now().UnixNano(), 10)
+	r := logRecord{
+		TimeUnixNano:         now,
+		ObservedTimeUnixNano: now,
+		Attributes:           make([]attribute, 0, len(keyvals)/2),
+	}
+	var body bool
+	for i := 0; i < len(keyvals); i += 2 {
+		k := keyvals[i]
+		var v interface{} = log.ErrMissingValue
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		if lv, ok := v.(level.Value); ok && k == level.Key() {
+			r.SeverityNumber, r.SeverityText = severity(lv)
+			continue
+		}
+		key := fmt.Sprint(k)
+		switch {
+		case key == "msg" && !body:
+			r.Body, body = value(v), true
+		case key == TraceIDKey:
+			r.TraceID = fmt.Sprint(v)
+		case key == SpanIDKey:
+			r.SpanID = fmt.Sprint(v)
+		default:
+			if t, ok := v.(time.Time); ok && key == "ts" {
+				r.TimeUnixNano = strconv.FormatInt(t.UnixNano(), 10)
+				continue
+			}
+			r.Attributes = append(r.Attributes, attribute{Key: key, Value: value(v)})
+		}
+	}
+	return r
+}
+
+func severity(v level.Value) (int, string) {
+	switch v {
+	case level.DebugValue():
+		return severityDebug, "DEBUG"
+	case level.WarnValue():
+		return severityWarn, "WARN"
+	case level.ErrorValue():
+		return severityError, "ERROR"
+	default:
+		return severityInfo, "INFO"
+	}
+}
+
+func value(v interface{}) anyValue {
+	switch v := v.(type) {
+	case string:
+		return stringValue(v)
+	case bool:
+		return anyValue{BoolValue: &v}
+	case int:
+		s := strconv.FormatInt(int64(v), 10)
+		return anyValue{IntValue: &s}
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		return anyValue{IntValue: &s}
+	case int32:
+		s := strconv.FormatInt(int64(v), 10)
+		return anyValue{IntValue: &s}
+	case uint:
+		s := strconv.FormatUint(uint64(v), 10)
+		return anyValue{IntValue: &s}
+	case uint32:
+		s := strconv.FormatUint(uint64(v), 10)
+		return anyValue{IntValue: &s}
+	case float64:
+		return anyValue{DoubleValue: &v}
+	case float32:
+		f := float64(v)
+		return anyValue{DoubleValue: &f}
+	case error:
+		return stringValue(v.Error())
+	case fmt.Stringer:
+		return stringValue(v.String())
+	default:
+		return stringValue(fmt.Sprint(v))
+	}
+}
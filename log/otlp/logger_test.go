@@ -0,0 +1,146 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log/level"
+)
+
+type receiver struct {
+	mtx      sync.Mutex
+	requests []exportRequest
+	status   int
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req exportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	rc.mtx.Lock()
+	defer rc.mtx.Unlock()
+	rc.requests = append(rc.requests, req)
+	if rc.status != 0 {
+		w.WriteHeader(rc.status)
+	}
+}
+
+func (rc *receiver) records() []logRecord {
+	rc.mtx.Lock()
+	defer rc.mtx.Unlock()
+	var records []logRecord
+	for _, req := range rc.requests {
+		records = append(records, req.ResourceLogs[0].ScopeLogs[0].LogRecords...)
+	}
+	return records
+}
+
+func TestLogger(t *testing.T) {
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	logger := New("orders", server.URL, WithResource("deployment.environment", "test"), WithFlushInterval(time.Hour))
+	type spanKey struct{}
+	ctx := context.WithValue(context.Background(), spanKey{}, [2]string{"4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"})
+	spans := func(ctx context.Context) (string, string) {
+		ids := ctx.Value(spanKey{}).([2]string)
+		return ids[0], ids[1]
+	}
+
+	level.Warn(WithSpanContext(ctx, logger, spans)).Log("msg", "slow query", "took", 1.5, "rows", 3, "cached", false, "err", errors.New("timeout"))
+	if err := logger.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, have := 1, len(rc.requests); want != have {
+		t.Fatalf("requests: want %d, have %d", want, have)
+	}
+	res := rc.requests[0].ResourceLogs[0].Resource.Attributes
+	if want, have := 2, len(res); want != have || res[0].Key != "service.name" || *res[1].Value.StringValue != "test" {
+		t.Errorf("resource: have %+v", res)
+	}
+
+	r := rc.records()[0]
+	if want, have := severityWarn, r.SeverityNumber; want != have {
+		t.Errorf("severity: want %d, have %d", want, have)
+	}
+	if want, have := "slow query", *r.Body.StringValue; want != have {
+		t.Errorf("body: want %q, have %q", want, have)
+	}
+	if want, have := "4bf92f3577b34da6a3ce929d0e0e4736", r.TraceID; want != have {
+		t.Errorf("trace ID: want %q, have %q", want, have)
+	}
+	if want, have := "00f067aa0ba902b7", r.SpanID; want != have {
+		t.Errorf("span ID: want %q, have %q", want, have)
+	}
+	attrs := map[string]anyValue{}
+	for _, a := range r.Attributes {
+		attrs[a.Key] = a.Value
+	}
+	if want, have := 4, len(attrs); want != have {
+		t.Fatalf("attributes: want %d, have %+v", want, r.Attributes)
+	}
+	if want, have := 1.5, *attrs["took"].DoubleValue; want != have {
+		t.Errorf("took: want %v, have %v", want, have)
+	}
+	if want, have := "3", *attrs["rows"].IntValue; want != have {
+		t.Errorf("rows: want %q, have %q", want, have)
+	}
+	if want, have := false, *attrs["cached"].BoolValue; want != have {
+		t.Errorf("cached: want %v, have %v", want, have)
+	}
+	if want, have := "timeout", *attrs["err"].StringValue; want != have {
+		t.Errorf("err: want %q, have %q", want, have)
+	}
+}
+
+func TestBatching(t *testing.T) {
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	logger := New("orders", server.URL, WithBatchSize(3), WithFlushInterval(time.Hour))
+	for i := 0; i < 7; i++ {
+		logger.Log("i", i)
+	}
+	logger.Close()
+
+	if want, have := 7, len(rc.records()); want != have {
+		t.Errorf("records: want %d, have %d", want, have)
+	}
+	for _, req := range rc.requests {
+		if n := len(req.ResourceLogs[0].ScopeLogs[0].LogRecords); n > 3 {
+			t.Errorf("batch of %d records exceeds the batch size", n)
+		}
+	}
+	if want, have := ErrClosed, logger.Log("i", 7); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	rc := &receiver{status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	logger := New("orders", server.URL, WithMaxQueueSize(2), WithFlushInterval(time.Hour))
+	defer logger.Close()
+	for i := 0; i < 5; i++ {
+		logger.Log("i", i)
+	}
+	if want, have := uint64(3), logger.Dropped(); want != have {
+		t.Errorf("dropped: want %d, have %d", want, have)
+	}
+	if err := logger.Flush(); err == nil {
+		t.Error("want export error, have nil")
+	}
+}
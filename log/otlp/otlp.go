@@ -0,0 +1,68 @@
+package otlp
+
+// The types in this file model the subset of the OTLP logs data model which
+// is produced by this package, in its JSON encoding. Field names follow the
+// lowerCamelCase protobuf JSON mapping, and 64-bit integers are encoded as
+// strings.
+
+// Severity numbers, from the OTLP logs data model.
+const (
+	severityDebug = 5
+	severityInfo  = 9
+	severityWarn  = 13
+	severityError = 17
+)
+
+type exportRequest struct {
+	ResourceLogs []resourceLogs `json:"resourceLogs"`
+}
+
+type resourceLogs struct {
+	Resource  resource    `json:"resource"`
+	ScopeLogs []scopeLogs `json:"scopeLogs"`
+}
+
+type resource struct {
+	Attributes []attribute `json:"attributes"`
+}
+
+type scopeLogs struct {
+	Scope      scope       `json:"scope"`
+	LogRecords []logRecord `json:"logRecords"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type logRecord struct {
+	TimeUnixNano         string      `json:"timeUnixNano"`
+	ObservedTimeUnixNano string      `json:"observedTimeUnixNano"`
+	SeverityNumber       int         `json:"severityNumber,omitempty"`
+	SeverityText         string      `json:"severityText,omitempty"`
+	Body                 anyValue    `json:"body"`
+	Attributes           []attribute `json:"attributes"`
+	TraceID              string      `json:"traceId,omitempty"`
+	SpanID               string      `json:"spanId,omitempty"`
+}
+
+type attribute struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+// anyValue is a oneof; exactly one field is set.
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func stringAttribute(key, value string) attribute {
+	return attribute{Key: key, Value: stringValue(value)}
+}
+
+func stringValue(s string) anyValue {
+	return anyValue{StringValue: &s}
+}
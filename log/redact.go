@@ -0,0 +1,120 @@
+package log
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultRedactMask replaces redacted values, unless RedactMask or RedactHash
+// is given.
+const DefaultRedactMask = "[REDACTED]"
+
+// RedactOption sets an optional parameter for NewRedactLogger.
+type RedactOption func(*redactLogger)
+
+// RedactKeys redacts the whole value of each of the keys, e.g. "password",
+// "authorization" or "ssn". Keys are matched case-insensitively.
+func RedactKeys(keys ...string) RedactOption {
+	return func(l *redactLogger) {
+		for _, k := range keys {
+			l.keys[strings.ToLower(k)] = struct{}{}
+		}
+	}
+}
+
+// RedactPattern redacts the parts of values which match the regular
+// expression, e.g. card numbers embedded in a message. Patterns apply to
+// string, error and fmt.Stringer values, which are replaced by strings.
+func RedactPattern(re *regexp.Regexp) RedactOption {
+	return func(l *redactLogger) { l.patterns = append(l.patterns, re) }
+}
+
+// RedactMask sets the string which replaces redacted values. By default, it's
+// DefaultRedactMask.
+func RedactMask(mask string) RedactOption {
+	return func(l *redactLogger) { l.mask = func(string) string { return mask } }
+}
+
+// RedactHash replaces redacted values with an HMAC-SHA256 of the value,
+// keyed by secret, rather than a fixed mask. Equal values get equal hashes,
+// so they may still be correlated across log events without being revealed.
+func RedactHash(secret []byte) RedactOption {
+	return func(l *redactLogger) {
+		l.mask = func(s string) string {
+			mac := hmac.New(sha256.New, secret)
+			mac.Write([]byte(s))
+			return "hmac:" + hex.EncodeToString(mac.Sum(nil)[:8])
+		}
+	}
+}
+
+type redactLogger struct {
+	next     Logger
+	keys     map[string]struct{}
+	patterns []*regexp.Regexp
+	mask     func(string) string
+}
+
+// NewRedactLogger returns a logger which redacts the values of the
+// configured keys, and the parts of values matching the configured patterns,
+// before passing log events to next. Values bound with With are redacted
+// too, as long as the redact logger is wrapped by, rather than wraps, the
+// contextual logger.
+func NewRedactLogger(next Logger, options ...RedactOption) Logger {
+	l := &redactLogger{
+		next: next,
+		keys: map[string]struct{}{},
+		mask: func(string) string { return DefaultRedactMask },
+	}
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+func (l *redactLogger) Log(keyvals ...interface{}) error {
+	var redacted []interface{} // copy of keyvals, made on first change
+	set := func(i int, v interface{}) {
+		if redacted == nil {
+			redacted = append([]interface{}(nil), keyvals...)
+		}
+		redacted[i] = v
+	}
+	for i := 1; i < len(keyvals); i += 2 {
+		if k, ok := keyvals[i-1].(string); ok {
+			if _, ok := l.keys[strings.ToLower(k)]; ok {
+				set(i, l.mask(fmt.Sprint(keyvals[i])))
+				continue
+			}
+		}
+		if len(l.patterns) == 0 {
+			continue
+		}
+		var s string
+		switch v := keyvals[i].(type) {
+		case string:
+			s = v
+		case error:
+			s = v.Error()
+		case fmt.Stringer:
+			s = v.String()
+		default:
+			continue
+		}
+		r := s
+		for _, re := range l.patterns {
+			r = re.ReplaceAllStringFunc(r, l.mask)
+		}
+		if r != s {
+			set(i, r)
+		}
+	}
+	if redacted == nil {
+		return l.next.Log(keyvals...)
+	}
+	return l.next.Log(redacted...)
+}
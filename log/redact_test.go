@@ -0,0 +1,52 @@
+package log_test
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/barrett370/kit/v2/log"
+)
+
+func TestRedactLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewRedactLogger(log.NewLogfmtLogger(&buf),
+		log.RedactKeys("password", "Authorization"),
+		log.RedactPattern(regexp.MustCompile(`\d{3}-\d{2}-\d{4}`)),
+	)
+
+	keyvals := []interface{}{"user", "alice", "password", "hunter2", "authorization", "Bearer abc", "err", errors.New("bad ssn 123-45-6789")}
+	logger.Log(keyvals...)
+
+	if want, have := `user=alice password=[REDACTED] authorization=[REDACTED] err="bad ssn [REDACTED]"`+"\n", buf.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "hunter2", keyvals[3]; want != have {
+		t.Errorf("keyvals modified: want %q, have %q", want, have)
+	}
+}
+
+func TestRedactHash(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewRedactLogger(log.NewLogfmtLogger(&buf), log.RedactKeys("ssn"), log.RedactHash([]byte("secret")))
+
+	logger.Log("ssn", "123-45-6789")
+	logger.Log("ssn", "123-45-6789")
+	logger.Log("ssn", "987-65-4321")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if want, have := 3, len(lines); want != have {
+		t.Fatalf("want %d lines, have %d", want, have)
+	}
+	if !strings.HasPrefix(lines[0], "ssn=hmac:") || strings.Contains(lines[0], "6789") {
+		t.Errorf("unexpected line %q", lines[0])
+	}
+	if lines[0] != lines[1] {
+		t.Errorf("equal values: want equal hashes, have %q and %q", lines[0], lines[1])
+	}
+	if lines[0] == lines[2] {
+		t.Errorf("different values: want different hashes, have %q", lines[0])
+	}
+}
@@ -0,0 +1,336 @@
+// Package rotate provides an io.Writer for log files which rotates them by
+// size and age, keeps a bounded number of backups, optionally compressed, and
+// can reopen its file on a signal, for use with external tools like
+// logrotate. It's suitable as the writer of NewLogfmtLogger or NewJSONLogger.
+package rotate
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxSize is the default size at which a file is rotated.
+const DefaultMaxSize = 100 << 20 // 100MiB
+
+// backupTimeFormat is the format of the time in the names of backups. It
+// sorts lexically in time order, and avoids characters which aren't allowed
+// in file names on some platforms, e.g. ':'.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// Option sets an optional parameter for Writers.
+type Option func(*Writer)
+
+// MaxSize sets the size in bytes beyond which the file is rotated. A single
+// write larger than the size goes to a file of its own. By default, it's
+// DefaultMaxSize. If it's zero or less, files aren't rotated by size.
+func MaxSize(bytes int64) Option {
+	return func(w *Writer) { w.maxSize = bytes }
+}
+
+// MaxAge rotates the file once it has been open for the duration, e.g. daily.
+// By default, files aren't rotated by age.
+func MaxAge(d time.Duration) Option {
+	return func(w *Writer) { w.maxAge = d }
+}
+
+// MaxBackups sets the number of rotated files to keep; older ones are
+// removed. By default, all backups are kept.
+func MaxBackups(n int) Option {
+	return func(w *Writer) { w.maxBackups = n }
+}
+
+// Compress gzips rotated files, in the background.
+func Compress() Option {
+	return func(w *Writer) { w.compress = true }
+}
+
+// ReopenOn reopens the file whenever the process receives one of the
+// signals, typically syscall.SIGHUP, after an external tool has moved it.
+func ReopenOn(sigs ...os.Signal) Option {
+	return func(w *Writer) { w.signals = sigs }
+}
+
+// Writer is an io.Writer which writes to a file, rotating it as configured.
+// It's safe for concurrent use.
+type Writer struct {
+	filename   string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	compress   bool
+	signals    []os.Signal
+	now        func() time.Time
+
+	mtx    sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+
+	wg      sync.WaitGroup // background compression and pruning
+	cleanup chan struct{}  // requests a cleanup pass, see cleanupLoop
+	sigc    chan os.Signal
+	stop    chan struct{}
+}
+
+// New returns a Writer which appends to the file, creating it, and its
+// directory, if necessary. Rotated files are named after it, with the time of
+// rotation inserted before the extension, e.g. app-2006-01-02T15-04-05.000.log
+// for app.log.
+func New(filename string, options ...Option) (*Writer, error) {
+	w := &Writer{
+		filename: filename,
+		maxSize:  DefaultMaxSize,
+		now:      time.Now,
+		cleanup:  make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+	}
+	for _, option := range options {
+		option(w)
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	w.wg.Add(1)
+	go w.cleanupLoop()
+
+	if len(w.signals) > 0 {
+		w.sigc = make(chan os.Signal, 1)
+		signal.Notify(w.sigc, w.signals...)
+		w.wg.Add(1)
+		go w.reopenLoop()
+	}
+	return w, nil
+}
+
+// Write implements io.Writer.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	if w.due(int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *Writer) due(n int64) bool {
+	if w.size == 0 {
+		return false
+	}
+	if w.maxSize > 0 && w.size+n > w.maxSize {
+		return true
+	}
+	return w.maxAge > 0 && w.now().Sub(w.opened) >= w.maxAge
+}
+
+// Rotate rotates the file immediately.
+func (w *Writer) Rotate() error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.file == nil {
+		return os.ErrClosed
+	}
+	return w.rotate()
+}
+
+// Reopen closes and reopens the file, e.g. after it has been moved by an
+// external tool.
+func (w *Writer) Reopen() error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.file == nil {
+		return os.ErrClosed
+	}
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+// Close closes the file, and waits for any compression of rotated files to
+// finish.
+func (w *Writer) Close() error {
+	w.mtx.Lock()
+	if w.file == nil {
+		w.mtx.Unlock()
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	if w.sigc != nil {
+		signal.Stop(w.sigc)
+	}
+	close(w.stop)
+	w.mtx.Unlock()
+	w.wg.Wait()
+	return err
+}
+
+// open opens the file. It must be called with the mutex held, or before the
+// Writer is shared.
+func (w *Writer) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.filename), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file, w.size, w.opened = f, info.Size(), w.now()
+	return nil
+}
+
+// rotate must be called with the mutex held.
+func (w *Writer) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(w.filename, w.backupName(w.now())); err != nil {
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	select {
+	case w.cleanup <- struct{}{}:
+	default: // a pass is already pending, and will see this backup
+	}
+	return nil
+}
+
+func (w *Writer) backupName(t time.Time) string {
+	dir, prefix, ext := w.parts()
+	return filepath.Join(dir, prefix+t.Format(backupTimeFormat)+ext)
+}
+
+// parts returns the directory of the file, the prefix of its backups'
+// names, and its extension.
+func (w *Writer) parts() (dir, prefix, ext string) {
+	dir = filepath.Dir(w.filename)
+	base := filepath.Base(w.filename)
+	ext = filepath.Ext(base)
+	return dir, strings.TrimSuffix(base, ext) + "-", ext
+}
+
+func (w *Writer) cleanupLoop() {
+	defer w.wg.Done()
+	for {
+		select {
+		case <-w.cleanup:
+			w.compressAndPrune()
+		case <-w.stop:
+			select {
+			case <-w.cleanup:
+				w.compressAndPrune()
+			default:
+			}
+			return
+		}
+	}
+}
+
+func (w *Writer) reopenLoop() {
+	defer w.wg.Done()
+	for {
+		select {
+		case <-w.sigc:
+			w.Reopen()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// compressAndPrune compresses uncompressed backups, if enabled, and removes
+// the oldest backups beyond the maximum. Errors are ignored, as there's no
+// one to report them to; the next pass retries.
+func (w *Writer) compressAndPrune() {
+	backups := w.backups()
+	if w.compress {
+		for i, name := range backups {
+			if strings.HasSuffix(name, ".gz") {
+				continue
+			}
+			if err := compressFile(name); err == nil {
+				backups[i] = name + ".gz"
+			}
+		}
+	}
+	if w.maxBackups > 0 && len(backups) > w.maxBackups {
+		for _, name := range backups[:len(backups)-w.maxBackups] {
+			os.Remove(name)
+		}
+	}
+}
+
+// backups returns the paths of the backups, oldest first.
+func (w *Writer) backups() []string {
+	dir, prefix, ext := w.parts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var backups []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		ts := strings.TrimSuffix(strings.TrimSuffix(name[len(prefix):], ".gz"), ext)
+		if _, err := time.Parse(backupTimeFormat, ts); err != nil {
+			continue
+		}
+		backups = append(backups, filepath.Join(dir, name))
+	}
+	sort.Strings(backups)
+	return backups
+}
+
+func compressFile(name string) (err error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(name + ".gz")
+		}
+	}()
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
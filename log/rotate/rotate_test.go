@@ -0,0 +1,126 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func files(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// clock returns a now func which advances by a millisecond per call, so that
+// backups get distinct names.
+func clock() func() time.Time {
+	t := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	return func() time.Time {
+		t = t.Add(time.Millisecond)
+		return t
+	}
+}
+
+func TestRotateBySize(t *testing.T) {
+	dir := t.TempDir()
+	w, err := New(filepath.Join(dir, "app.log"), MaxSize(10), MaxBackups(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.now = clock()
+
+	for _, s := range []string{"aaaaaa\n", "bbbbbb\n", "cccccc\n", "dddddd\n"} {
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	names := files(t, dir)
+	if want, have := 3, len(names); want != have {
+		t.Fatalf("want %d files, have %v", want, names)
+	}
+	for i, want := range map[int]string{0: "bbbbbb\n", 1: "cccccc\n", 2: "dddddd\n"} {
+		b, _ := ioutil.ReadFile(filepath.Join(dir, names[i]))
+		if have := string(b); want != have {
+			t.Errorf("%s: want %q, have %q", names[i], want, have)
+		}
+	}
+	if want, have := "app.log", names[2]; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if !strings.HasPrefix(names[0], "app-2021-01-02T03-04-05.") || !strings.HasSuffix(names[0], ".log") {
+		t.Errorf("unexpected backup name %q", names[0])
+	}
+}
+
+func TestRotateByAge(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)
+	w, err := New(filepath.Join(dir, "app.log"), MaxAge(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.now = func() time.Time { return now }
+	w.Reopen() // to take opened from the fake clock
+
+	w.Write([]byte("first\n"))
+	now = now.Add(30 * time.Minute)
+	w.Write([]byte("second\n"))
+	now = now.Add(30 * time.Minute)
+	w.Write([]byte("third\n"))
+	w.Close()
+
+	if want, have := []string{"app-2021-01-02T01-00-00.000.log", "app.log"}, files(t, dir); strings.Join(want, " ") != strings.Join(have, " ") {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestCompress(t *testing.T) {
+	dir := t.TempDir()
+	w, err := New(filepath.Join(dir, "app.log"), MaxSize(1), Compress())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.now = clock()
+	w.Write([]byte("rotated\n"))
+	w.Write([]byte("current\n"))
+	w.Close()
+
+	names := files(t, dir)
+	if want, have := 2, len(names); want != have {
+		t.Fatalf("want %d files, have %v", want, names)
+	}
+	if !strings.HasSuffix(names[0], ".log.gz") {
+		t.Fatalf("want a compressed backup, have %v", names)
+	}
+	f, err := os.Open(filepath.Join(dir, names[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(zr)
+	if want, have := "rotated\n", string(b); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
@@ -0,0 +1,45 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package rotate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReopenOnSignal(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	w, err := New(filename, ReopenOn(syscall.SIGHUP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.Write([]byte("before\n"))
+
+	// An external tool moves the file, and signals the process.
+	if err := os.Rename(filename, filename+".1"); err != nil {
+		t.Fatal(err)
+	}
+	syscall.Kill(os.Getpid(), syscall.SIGHUP)
+	for i := 0; ; i++ {
+		if _, err := os.Stat(filename); err == nil {
+			break
+		}
+		if i == 100 {
+			t.Fatal("file wasn't reopened")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	w.Write([]byte("after\n"))
+
+	b, _ := ioutil.ReadFile(filename)
+	if want, have := "after\n", string(b); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
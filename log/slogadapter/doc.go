@@ -0,0 +1,9 @@
+// Package slogadapter bridges Go kit loggers and the standard library's
+// log/slog, in both directions, preserving levels and key/value pairs. A
+// service can standardize on slog and still pass a log.Logger to kit
+// components, or keep kit loggers and hand an slog.Handler to libraries which
+// want one.
+//
+// The package requires Go 1.21, which added log/slog. With earlier toolchains
+// it's empty, so the module still builds with them.
+package slogadapter
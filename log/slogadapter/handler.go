@@ -0,0 +1,107 @@
+//go:build go1.21
+
+package slogadapter
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// HandlerOption sets an optional parameter for NewHandler.
+type HandlerOption func(*handlerOptions)
+
+type handlerOptions struct {
+	level   slog.Leveler
+	timeKey string
+}
+
+// HandlerLevel sets the minimum level of records which are handled. By
+// default, all records are handled, and may be filtered by the Go kit logger,
+// e.g. with level.NewFilter.
+func HandlerLevel(lvl slog.Leveler) HandlerOption {
+	return func(o *handlerOptions) { o.level = lvl }
+}
+
+// HandlerTimeKey includes the time of each record under the key. By default,
+// it's omitted, as Go kit loggers usually add their own timestamp, e.g. with
+// log.DefaultTimestampUTC.
+func HandlerTimeKey(key string) HandlerOption {
+	return func(o *handlerOptions) { o.timeKey = key }
+}
+
+type handler struct {
+	logger  log.Logger
+	opts    *handlerOptions
+	keyvals []interface{} // from WithAttrs
+	group   string        // prefix for keys, from WithGroup, e.g. "req."
+}
+
+// NewHandler returns an slog.Handler that sends records to the Go kit logger.
+// Each record is logged as level.Key() with the level package's value for
+// the record's level (see KitLevel), "msg" with the message, and the
+// attributes. Attributes in groups are logged with dotted keys, e.g.
+// "req.method".
+func NewHandler(logger log.Logger, options ...HandlerOption) slog.Handler {
+	opts := &handlerOptions{}
+	for _, option := range options {
+		option(opts)
+	}
+	return &handler{logger: logger, opts: opts}
+}
+
+func (h *handler) Enabled(_ context.Context, lvl slog.Level) bool {
+	return h.opts.level == nil || lvl >= h.opts.level.Level()
+}
+
+func (h *handler) Handle(_ context.Context, r slog.Record) error {
+	keyvals := make([]interface{}, 0, 4+len(h.keyvals)+2*r.NumAttrs())
+	keyvals = append(keyvals, level.Key(), KitLevel(r.Level))
+	if h.opts.timeKey != "" && !r.Time.IsZero() {
+		keyvals = append(keyvals, h.opts.timeKey, r.Time)
+	}
+	keyvals = append(keyvals, "msg", r.Message)
+	keyvals = append(keyvals, h.keyvals...)
+	r.Attrs(func(a slog.Attr) bool {
+		keyvals = appendAttr(keyvals, h.group, a)
+		return true
+	})
+	return h.logger.Log(keyvals...)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	keyvals := make([]interface{}, len(h.keyvals), len(h.keyvals)+2*len(attrs))
+	copy(keyvals, h.keyvals)
+	for _, a := range attrs {
+		keyvals = appendAttr(keyvals, h.group, a)
+	}
+	return &handler{logger: h.logger, opts: h.opts, keyvals: keyvals, group: h.group}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &handler{logger: h.logger, opts: h.opts, keyvals: h.keyvals, group: h.group + name + "."}
+}
+
+// appendAttr appends the attribute to the keyvals, flattening groups into
+// dotted keys, and omitting empty attributes, as slog handlers should.
+func appendAttr(keyvals []interface{}, prefix string, a slog.Attr) []interface{} {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return keyvals
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			keyvals = appendAttr(keyvals, prefix, ga)
+		}
+		return keyvals
+	}
+	return append(keyvals, prefix+a.Key, a.Value.Any())
+}
@@ -0,0 +1,127 @@
+//go:build go1.21
+
+package slogadapter
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// LoggerOption sets an optional parameter for NewLogger.
+type LoggerOption func(*logger)
+
+// MessageKey sets the key whose value becomes the message of the slog
+// record. By default, it's "msg".
+func MessageKey(key string) LoggerOption {
+	return func(l *logger) { l.messageKey = key }
+}
+
+// DefaultLevel sets the level of log events without a level key. By default,
+// it's slog.LevelInfo.
+func DefaultLevel(lvl slog.Level) LoggerOption {
+	return func(l *logger) { l.defaultLevel = lvl }
+}
+
+type logger struct {
+	slog         *slog.Logger
+	messageKey   string
+	defaultLevel slog.Level
+}
+
+// NewLogger returns a Go kit log.Logger that sends log events to the
+// slog.Logger. The value of level.Key(), as added by level.Debug, Info, Warn
+// and Error, sets the level of the record, and the value of the message key
+// its message; the remaining key/value pairs become its attributes.
+func NewLogger(l *slog.Logger, options ...LoggerOption) log.Logger {
+	lg := &logger{slog: l, messageKey: "msg", defaultLevel: slog.LevelInfo}
+	for _, option := range options {
+		option(lg)
+	}
+	return lg
+}
+
+func (l *logger) Log(keyvals ...interface{}) error {
+	var (
+		lvl   = l.defaultLevel
+		msg   string
+		attrs = make([]slog.Attr, 0, len(keyvals)/2)
+	)
+	for i := 0; i < len(keyvals); i += 2 {
+		k := keyvals[i]
+		var v interface{} = log.ErrMissingValue
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		if k == level.Key() {
+			if sl, ok := toSlogLevel(v); ok {
+				lvl = sl
+				continue
+			}
+		}
+		key := fmt.Sprint(k)
+		if key == l.messageKey {
+			if s, ok := v.(string); ok && msg == "" {
+				msg = s
+				continue
+			}
+		}
+		attrs = append(attrs, slog.Any(key, v))
+	}
+	ctx := context.Background()
+	if !l.slog.Enabled(ctx, lvl) {
+		return nil
+	}
+	l.slog.LogAttrs(ctx, lvl, msg, attrs...)
+	return nil
+}
+
+// toSlogLevel converts a level value, as added by the level package, or a
+// slog.Level, or a level name, to a slog.Level.
+func toSlogLevel(v interface{}) (slog.Level, bool) {
+	switch v := v.(type) {
+	case slog.Level:
+		return v, true
+	case level.Value:
+		return slogLevelByName(v.String())
+	case string:
+		return slogLevelByName(v)
+	default:
+		return 0, false
+	}
+}
+
+func slogLevelByName(name string) (slog.Level, bool) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug, true
+	case "info":
+		return slog.LevelInfo, true
+	case "warn", "warning":
+		return slog.LevelWarn, true
+	case "error":
+		return slog.LevelError, true
+	default:
+		return 0, false
+	}
+}
+
+// KitLevel returns the level package's value for the slog level, rounding
+// levels between the named ones down, e.g. slog.LevelInfo+2 to
+// level.InfoValue(). Levels below slog.LevelDebug map to level.DebugValue().
+func KitLevel(lvl slog.Level) level.Value {
+	switch {
+	case lvl >= slog.LevelError:
+		return level.ErrorValue()
+	case lvl >= slog.LevelWarn:
+		return level.WarnValue()
+	case lvl >= slog.LevelInfo:
+		return level.InfoValue()
+	default:
+		return level.DebugValue()
+	}
+}
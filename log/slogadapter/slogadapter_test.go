@@ -0,0 +1,86 @@
+//go:build go1.21
+
+package slogadapter_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	"github.com/barrett370/kit/v2/log/slogadapter"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	sl := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	logger := slogadapter.NewLogger(sl)
+
+	level.Warn(logger).Log("msg", "disk low", "free", 42)
+	level.Debug(logger).Log("msg", "squelched by the slog handler")
+	logger.Log("request", "GET /")
+
+	want := `{"level":"WARN","msg":"disk low","free":42}` + "\n" +
+		`{"level":"INFO","msg":"","request":"GET /"}` + "\n"
+	if have := buf.String(); want != have {
+		t.Errorf("want\n%s\nhave\n%s", want, have)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := level.NewFilter(log.NewJSONLogger(&buf), level.AllowInfo())
+	sl := slog.New(slogadapter.NewHandler(logger))
+
+	sl.With("component", "orders").WithGroup("req").Info("served", "method", "GET", slog.Group("user", "id", 7))
+	sl.Debug("squelched by the kit filter")
+
+	var have map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &have); err != nil {
+		t.Fatalf("%v: %s", err, buf.String())
+	}
+	want := map[string]interface{}{
+		"level":       "info",
+		"msg":         "served",
+		"component":   "orders",
+		"req.method":  "GET",
+		"req.user.id": float64(7),
+	}
+	if len(want) != len(have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	for k, v := range want {
+		if have[k] != v {
+			t.Errorf("%s: want %v, have %v", k, v, have[k])
+		}
+	}
+}
+
+func TestKitLevel(t *testing.T) {
+	for _, tc := range []struct {
+		lvl  slog.Level
+		want level.Value
+	}{
+		{slog.LevelDebug - 4, level.DebugValue()},
+		{slog.LevelDebug, level.DebugValue()},
+		{slog.LevelInfo, level.InfoValue()},
+		{slog.LevelInfo + 2, level.InfoValue()},
+		{slog.LevelWarn, level.WarnValue()},
+		{slog.LevelError + 4, level.ErrorValue()},
+	} {
+		if have := slogadapter.KitLevel(tc.lvl); tc.want != have {
+			t.Errorf("%v: want %v, have %v", tc.lvl, tc.want, have)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+//go:build !windows && !plan9 && !nacl
+// +build !windows,!plan9,!nacl
+
+package syslog
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/log"
+
+	"github.com/barrett370/kit/v2/util/conn"
+)
+
+// RemoteWriter writes syslog messages to a remote syslog server, e.g. for an
+// RFC 5424 logger. Over a stream network, such as TCP, or TCP with TLS, each
+// message is framed with its length, per RFC 5425 and RFC 6587, and the
+// connection is re-established with backoff when it fails. Over a datagram
+// network, such as UDP, each message is sent as a datagram.
+type RemoteWriter struct {
+	m      *conn.Manager
+	stream bool
+
+	mtx sync.Mutex
+	buf []byte
+}
+
+// NewRemoteWriter returns a RemoteWriter to the syslog server at the
+// address. Connection errors are logged to logger. Options configure the
+// underlying connection manager; for TLS, pass
+//
+//	conn.WithDialer(conn.TLSDialer(nil, tlsConfig))
+//
+// with the "tcp" network.
+func NewRemoteWriter(network, address string, logger log.Logger, options ...conn.Option) *RemoteWriter {
+	return &RemoteWriter{
+		m:      conn.NewDefaultManager(network, address, logger, options...),
+		stream: !strings.HasPrefix(network, "udp") && network != "unixgram",
+	}
+}
+
+// Write sends one message. It fails with conn.ErrConnectionUnavailable while
+// the server can't be reached.
+func (w *RemoteWriter) Write(p []byte) (int, error) {
+	if !w.stream {
+		return w.m.Write(p)
+	}
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	w.buf = strconv.AppendInt(w.buf[:0], int64(len(p)), 10)
+	w.buf = append(w.buf, ' ')
+	w.buf = append(w.buf, p...)
+	if _, err := w.m.Write(w.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,266 @@
+//go:build !windows && !plan9 && !nacl
+// +build !windows,!plan9,!nacl
+
+package syslog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	gosyslog "log/syslog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// RFC5424Option sets an optional parameter for RFC 5424 loggers.
+type RFC5424Option func(*rfc5424Logger)
+
+// RFC5424Facility sets the facility of every message. By default, it's
+// LOG_USER.
+func RFC5424Facility(facility gosyslog.Priority) RFC5424Option {
+	return func(l *rfc5424Logger) { l.facility = facility & facilityMask }
+}
+
+// RFC5424PrioritySelector sets the func which chooses the severity of each
+// message. Any facility bits it returns are ignored. By default, the
+// severity follows the level of the log event, and is LOG_INFO for events
+// without one.
+func RFC5424PrioritySelector(selector PrioritySelector) RFC5424Option {
+	return func(l *rfc5424Logger) { l.prioritySelector = selector }
+}
+
+// RFC5424Hostname sets the HOSTNAME field. By default, it's os.Hostname.
+func RFC5424Hostname(hostname string) RFC5424Option {
+	return func(l *rfc5424Logger) { l.hostname = hostname }
+}
+
+// RFC5424AppName sets the APP-NAME field. By default, it's the base name of
+// the program.
+func RFC5424AppName(appName string) RFC5424Option {
+	return func(l *rfc5424Logger) { l.appName = appName }
+}
+
+// RFC5424ProcID sets the PROCID field. By default, it's the process ID.
+func RFC5424ProcID(procID string) RFC5424Option {
+	return func(l *rfc5424Logger) { l.procID = procID }
+}
+
+// RFC5424MsgIDKey sets the key whose value, if present, is the MSGID field
+// of the message, rather than part of its body.
+func RFC5424MsgIDKey(key string) RFC5424Option {
+	return func(l *rfc5424Logger) { l.msgIDKey = key }
+}
+
+// RFC5424StructuredData moves the key/value pairs with the given keys out of
+// the message body and into a structured data element with the ID, e.g.
+// "request@32473", as its parameters. It may be given more than once, for
+// more than one element; a key belongs to the first element listing it.
+func RFC5424StructuredData(id string, keys ...string) RFC5424Option {
+	return func(l *rfc5424Logger) {
+		l.elements = append(l.elements, sdElement{id: id, keys: keys})
+	}
+}
+
+const facilityMask = 0xf8
+
+type sdElement struct {
+	id   string
+	keys []string
+}
+
+type rfc5424Logger struct {
+	w                io.Writer
+	newLogger        func(io.Writer) log.Logger
+	facility         gosyslog.Priority
+	prioritySelector PrioritySelector
+	hostname         string
+	appName          string
+	procID           string
+	msgIDKey         string
+	elements         []sdElement
+	now              func() time.Time
+	bufPool          sync.Pool
+}
+
+type rfc5424Buf struct {
+	header bytes.Buffer
+	body   bytes.Buffer
+	logger log.Logger
+	rest   []interface{}
+}
+
+// NewRFC5424Logger returns a Logger which writes RFC 5424 syslog messages to
+// w, one per Write, e.g. to a RemoteWriter. The body of each message is the
+// formatted output of the Logger returned by newLogger, without a trailing
+// newline. Key/value pairs may be moved into the message's structured data
+// with RFC5424StructuredData.
+func NewRFC5424Logger(w io.Writer, newLogger func(io.Writer) log.Logger, options ...RFC5424Option) log.Logger {
+	hostname, _ := os.Hostname()
+	l := &rfc5424Logger{
+		w:                w,
+		newLogger:        newLogger,
+		facility:         gosyslog.LOG_USER,
+		prioritySelector: defaultPrioritySelector,
+		hostname:         hostname,
+		appName:          filepath.Base(os.Args[0]),
+		procID:           strconv.Itoa(os.Getpid()),
+		now:              time.Now,
+	}
+	for _, option := range options {
+		option(l)
+	}
+	l.bufPool.New = func() interface{} {
+		b := &rfc5424Buf{}
+		b.logger = l.newLogger(&b.body)
+		return b
+	}
+	return l
+}
+
+func (l *rfc5424Logger) Log(keyvals ...interface{}) error {
+	b := l.bufPool.Get().(*rfc5424Buf)
+	defer l.bufPool.Put(b)
+	b.header.Reset()
+	b.body.Reset()
+	b.rest = b.rest[:0]
+
+	severity := l.prioritySelector(keyvals...) &^ facilityMask
+	fmt.Fprintf(&b.header, "<%d>1 %s %s %s %s ",
+		l.facility|severity,
+		l.now().Format("2006-01-02T15:04:05.000000Z07:00"),
+		headerField(l.hostname, 255),
+		headerField(l.appName, 48),
+		headerField(l.procID, 128),
+	)
+
+	msgID := ""
+	sd := make([][]interface{}, len(l.elements))
+	for i := 0; i < len(keyvals); i += 2 {
+		k := keyvals[i]
+		var v interface{} = log.ErrMissingValue
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		key := fmt.Sprint(k)
+		if l.msgIDKey != "" && key == l.msgIDKey {
+			msgID = fmt.Sprint(v)
+			continue
+		}
+		if j := l.element(key); j >= 0 {
+			sd[j] = append(sd[j], key, v)
+			continue
+		}
+		b.rest = append(b.rest, k, v)
+	}
+	b.header.WriteString(headerField(msgID, 32))
+	b.header.WriteByte(' ')
+
+	var any bool
+	for j, params := range sd {
+		if len(params) == 0 {
+			continue
+		}
+		any = true
+		b.header.WriteByte('[')
+		b.header.WriteString(l.elements[j].id)
+		for i := 0; i < len(params); i += 2 {
+			fmt.Fprintf(&b.header, ` %s="`, sdName(params[i].(string)))
+			sdEscape(&b.header, params[i+1])
+			b.header.WriteByte('"')
+		}
+		b.header.WriteByte(']')
+	}
+	if !any {
+		b.header.WriteByte('-')
+	}
+
+	if len(b.rest) > 0 {
+		if err := b.logger.Log(b.rest...); err != nil {
+			return err
+		}
+		b.header.WriteByte(' ')
+		b.header.Write(bytes.TrimRight(b.body.Bytes(), "\n"))
+	}
+
+	_, err := l.w.Write(b.header.Bytes())
+	return err
+}
+
+func (l *rfc5424Logger) element(key string) int {
+	for i, e := range l.elements {
+		for _, k := range e.keys {
+			if k == key {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// headerField returns s as a header field: printable US-ASCII, at most max
+// characters, or the NILVALUE "-" if empty.
+func headerField(s string, max int) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return -1
+		}
+		return r
+	}, s)
+	if len(s) > max {
+		s = s[:max]
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// sdName returns s as a PARAM-NAME, which excludes '=', ' ', ']' and '"'.
+func sdName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || r == '=' || r == ']' || r == '"' {
+			return -1
+		}
+		return r
+	}, s)
+	if len(s) > 32 {
+		s = s[:32]
+	}
+	return s
+}
+
+// sdEscape writes v as a PARAM-VALUE, escaping '"', '\' and ']'.
+func sdEscape(w *bytes.Buffer, v interface{}) {
+	for _, r := range fmt.Sprint(v) {
+		if r == '"' || r == '\\' || r == ']' {
+			w.WriteByte('\\')
+		}
+		w.WriteRune(r)
+	}
+}
+
+func defaultPrioritySelector(keyvals ...interface{}) gosyslog.Priority {
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if keyvals[i] != level.Key() {
+			continue
+		}
+		switch keyvals[i+1] {
+		case level.DebugValue():
+			return gosyslog.LOG_DEBUG
+		case level.InfoValue():
+			return gosyslog.LOG_INFO
+		case level.WarnValue():
+			return gosyslog.LOG_WARNING
+		case level.ErrorValue():
+			return gosyslog.LOG_ERR
+		}
+	}
+	return gosyslog.LOG_INFO
+}
@@ -0,0 +1,96 @@
+//go:build !windows && !plan9 && !nacl
+// +build !windows,!plan9,!nacl
+
+package syslog
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	gosyslog "log/syslog"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+func newTestLogger(w io.Writer, options ...RFC5424Option) log.Logger {
+	options = append([]RFC5424Option{
+		RFC5424Hostname("host"),
+		RFC5424AppName("app"),
+		RFC5424ProcID("42"),
+	}, options...)
+	l := NewRFC5424Logger(w, log.NewLogfmtLogger, options...).(*rfc5424Logger)
+	l.now = func() time.Time { return time.Date(2021, 2, 3, 4, 5, 6, 7000, time.UTC) }
+	return l
+}
+
+func TestRFC5424Logger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf,
+		RFC5424Facility(gosyslog.LOG_LOCAL0),
+		RFC5424MsgIDKey("event"),
+		RFC5424StructuredData("req@32473", "method", "path"),
+	)
+
+	level.Warn(logger).Log("event", "slow", "method", "GET", "path", `/a"b]`, "msg", "took too long")
+	if want, have := `<132>1 2021-02-03T04:05:06.000007Z host app 42 slow [req@32473 method="GET" path="/a\"b\]"] level=warn msg="took too long"`, buf.String(); want != have {
+		t.Errorf("want\n%s\nhave\n%s", want, have)
+	}
+
+	buf.Reset()
+	logger.Log("msg", "hello")
+	if want, have := `<134>1 2021-02-03T04:05:06.000007Z host app 42 - - msg=hello`, buf.String(); want != have {
+		t.Errorf("want\n%s\nhave\n%s", want, have)
+	}
+}
+
+func TestRemoteWriter(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan string)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		r := bufio.NewReader(c)
+		for {
+			length, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(length[:len(length)-1])
+			msg := make([]byte, n)
+			if _, err := io.ReadFull(r, msg); err != nil {
+				return
+			}
+			received <- string(msg)
+		}
+	}()
+
+	logger := newTestLogger(NewRemoteWriter("tcp", ln.Addr().String(), log.NewNopLogger()))
+	for _, msg := range []string{"one", "two"} {
+		if err := logger.Log("msg", msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, msg := range []string{"one", "two"} {
+		select {
+		case have := <-received:
+			if want := "<14>1 2021-02-03T04:05:06.000007Z host app 42 - - msg=" + msg; want != have {
+				t.Errorf("want %q, have %q", want, have)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout")
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package log
+
+import (
+	"sync/atomic"
+
+	"github.com/barrett370/kit/v2/metrics"
+)
+
+type teeLogger []Logger
+
+// NewTeeLogger returns a logger which passes each log event to every one of
+// the loggers, e.g. to stdout and a network sink. Every logger is called,
+// even if an earlier one fails, and the first error is returned.
+func NewTeeLogger(loggers ...Logger) Logger {
+	return teeLogger(append([]Logger(nil), loggers...))
+}
+
+func (t teeLogger) Log(keyvals ...interface{}) error {
+	var first error
+	for _, logger := range t {
+		if err := logger.Log(keyvals...); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// FailoverOption sets an optional parameter for NewFailoverLogger.
+type FailoverOption func(*FailoverLogger)
+
+// FailoverErrorCounter counts the log events which the primary logger failed
+// to log.
+func FailoverErrorCounter(c metrics.Counter) FailoverOption {
+	return func(l *FailoverLogger) { l.errorCounter = c }
+}
+
+// FailoverLogger passes log events to a primary logger, e.g. a remote sink,
+// and to a fallback logger, e.g. stderr, when the primary fails.
+type FailoverLogger struct {
+	primary      Logger
+	fallback     Logger
+	errorCounter metrics.Counter
+	errors       uint64
+}
+
+// NewFailoverLogger returns a FailoverLogger for the primary and fallback
+// loggers. The primary is tried for every event, so that logging returns to
+// it as soon as it recovers.
+func NewFailoverLogger(primary, fallback Logger, options ...FailoverOption) *FailoverLogger {
+	l := &FailoverLogger{
+		primary:  primary,
+		fallback: fallback,
+	}
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+// Log implements Logger. If the primary logger fails, the event is passed to
+// the fallback logger, and its error, if any, is returned.
+func (l *FailoverLogger) Log(keyvals ...interface{}) error {
+	if err := l.primary.Log(keyvals...); err == nil {
+		return nil
+	}
+	atomic.AddUint64(&l.errors, 1)
+	if l.errorCounter != nil {
+		l.errorCounter.Add(1)
+	}
+	return l.fallback.Log(keyvals...)
+}
+
+// Errors returns the number of log events which the primary logger failed to
+// log.
+func (l *FailoverLogger) Errors() uint64 {
+	return atomic.LoadUint64(&l.errors)
+}
@@ -0,0 +1,62 @@
+package log_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/barrett370/kit/v2/log"
+	"github.com/barrett370/kit/v2/metrics/generic"
+)
+
+func TestTeeLogger(t *testing.T) {
+	var a, b bytes.Buffer
+	errFailed := errors.New("failed")
+	failing := log.LoggerFunc(func(...interface{}) error { return errFailed })
+	logger := log.NewTeeLogger(log.NewLogfmtLogger(&a), failing, log.NewLogfmtLogger(&b))
+
+	if want, have := errFailed, logger.Log("k", "v"); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	for _, buf := range []*bytes.Buffer{&a, &b} {
+		if want, have := "k=v\n", buf.String(); want != have {
+			t.Errorf("want %q, have %q", want, have)
+		}
+	}
+}
+
+func TestFailoverLogger(t *testing.T) {
+	var (
+		primary, fallback bytes.Buffer
+		down              bool
+		errDown           = errors.New("down")
+		errors            = generic.NewCounter("errors")
+	)
+	remote := log.LoggerFunc(func(keyvals ...interface{}) error {
+		if down {
+			return errDown
+		}
+		return log.NewLogfmtLogger(&primary).Log(keyvals...)
+	})
+	logger := log.NewFailoverLogger(remote, log.NewLogfmtLogger(&fallback), log.FailoverErrorCounter(errors))
+
+	logger.Log("n", 1)
+	down = true
+	logger.Log("n", 2)
+	logger.Log("n", 3)
+	down = false
+	logger.Log("n", 4)
+
+	if want, have := "n=1\nn=4\n", primary.String(); want != have {
+		t.Errorf("primary: want %q, have %q", want, have)
+	}
+	if want, have := "n=2\nn=3\n", fallback.String(); want != have {
+		t.Errorf("fallback: want %q, have %q", want, have)
+	}
+	if want, have := uint64(2), logger.Errors(); want != have {
+		t.Errorf("errors: want %d, have %d", want, have)
+	}
+	if want, have := 2.0, errors.Value(); want != have {
+		t.Errorf("error counter: want %v, have %v", want, have)
+	}
+}
@@ -0,0 +1,108 @@
+package zap
+
+import (
+	"fmt"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Option sets an optional parameter for NewZapLogger.
+type Option func(*zapLogger)
+
+// MessageKey sets the key whose value becomes the message of the zap entry.
+// By default, it's "msg".
+func MessageKey(key string) Option {
+	return func(l *zapLogger) { l.messageKey = key }
+}
+
+// DefaultLevel sets the level of log events without a level key. By default,
+// it's zapcore.InfoLevel.
+func DefaultLevel(lvl zapcore.Level) Option {
+	return func(l *zapLogger) { l.defaultLevel = lvl }
+}
+
+type zapLogger struct {
+	logger       *zap.Logger
+	messageKey   string
+	defaultLevel zapcore.Level
+}
+
+// NewZapLogger returns a Go kit log.Logger that sends log events to a
+// zap.Logger. Unlike NewZapSugarLogger, which logs every event at a fixed
+// level, the level of each event is taken from the value of level.Key(), as
+// added by level.Debug, Info, Warn and Error, so that one logger serves all
+// levels, and events below the zap logger's level are discarded without being
+// encoded. The value of the message key becomes the entry's message, and the
+// remaining key/value pairs its fields.
+func NewZapLogger(logger *zap.Logger, options ...Option) log.Logger {
+	l := &zapLogger{
+		logger:       logger.WithOptions(zap.AddCallerSkip(1)),
+		messageKey:   "msg",
+		defaultLevel: zapcore.InfoLevel,
+	}
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+func (l *zapLogger) Log(keyvals ...interface{}) error {
+	var (
+		lvl    = l.defaultLevel
+		msg    string
+		fields = make([]zap.Field, 0, len(keyvals)/2)
+	)
+	for i := 0; i < len(keyvals); i += 2 {
+		k := keyvals[i]
+		var v interface{} = log.ErrMissingValue
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		if lv, ok := v.(level.Value); ok && k == level.Key() {
+			lvl = ZapLevel(lv)
+			continue
+		}
+		key := fmt.Sprint(k)
+		if s, ok := v.(string); ok && key == l.messageKey && msg == "" {
+			msg = s
+			continue
+		}
+		fields = append(fields, zap.Any(key, v))
+	}
+	if ce := l.logger.Check(lvl, msg); ce != nil {
+		ce.Write(fields...)
+	}
+	return nil
+}
+
+// ZapLevel returns the zap level for a value of the level package.
+func ZapLevel(v level.Value) zapcore.Level {
+	switch v {
+	case level.DebugValue():
+		return zapcore.DebugLevel
+	case level.WarnValue():
+		return zapcore.WarnLevel
+	case level.ErrorValue():
+		return zapcore.ErrorLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
+
+// KitLevel returns the value of the level package for a zap level. Levels
+// above error, e.g. zapcore.DPanicLevel, map to level.ErrorValue().
+func KitLevel(lvl zapcore.Level) level.Value {
+	switch {
+	case lvl <= zapcore.DebugLevel:
+		return level.DebugValue()
+	case lvl == zapcore.InfoLevel:
+		return level.InfoValue()
+	case lvl == zapcore.WarnLevel:
+		return level.WarnValue()
+	default:
+		return level.ErrorValue()
+	}
+}
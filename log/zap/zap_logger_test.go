@@ -0,0 +1,55 @@
+package zap_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log/level"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	kitzap "github.com/barrett370/kit/v2/log/zap"
+)
+
+func TestZapLogger(t *testing.T) {
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.TimeKey = ""
+	writer := &tbWriter{tb: t}
+	logger := zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), zapcore.AddSync(writer), zap.InfoLevel))
+	kitLogger := kitzap.NewZapLogger(logger)
+
+	level.Warn(kitLogger).Log("msg", "disk low", "free", "42")
+	level.Debug(kitLogger).Log("msg", "discarded by zap")
+	kitLogger.Log("request", "GET /")
+
+	lines := strings.Split(strings.TrimSpace(writer.sb.String()), "\n")
+	if want, have := 2, len(lines); want != have {
+		t.Fatalf("want %d entries, have %d: %q", want, have, lines)
+	}
+	for i, want := range []map[string]string{
+		{"level": "warn", "msg": "disk low", "free": "42"},
+		{"level": "info", "msg": "", "request": "GET /"},
+	} {
+		have := map[string]string{}
+		if err := json.Unmarshal([]byte(lines[i]), &have); err != nil {
+			t.Fatalf("unmarshal error: %v", err)
+		}
+		for k, v := range want {
+			if have[k] != v {
+				t.Errorf("entry %d, %s: want %q, have %q", i, k, v, have[k])
+			}
+		}
+	}
+}
+
+func TestZapLevels(t *testing.T) {
+	for _, v := range []level.Value{level.DebugValue(), level.InfoValue(), level.WarnValue(), level.ErrorValue()} {
+		if want, have := v, kitzap.KitLevel(kitzap.ZapLevel(v)); want != have {
+			t.Errorf("want %v, have %v", want, have)
+		}
+	}
+	if want, have := level.ErrorValue(), kitzap.KitLevel(zapcore.DPanicLevel); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
@@ -0,0 +1,122 @@
+// Package zerolog provides an adapter to the
+// go-kit log.Logger interface.
+package zerolog
+
+import (
+	"fmt"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/rs/zerolog"
+)
+
+// Option sets an optional parameter for NewLogger.
+type Option func(*Logger)
+
+// MessageKey sets the key whose value becomes the message of the zerolog
+// event. By default, it's "msg".
+func MessageKey(key string) Option {
+	return func(l *Logger) { l.messageKey = key }
+}
+
+// DefaultLevel sets the level of log events without a level key. By default,
+// it's zerolog.InfoLevel.
+func DefaultLevel(lvl zerolog.Level) Option {
+	return func(l *Logger) { l.defaultLevel = lvl }
+}
+
+// Logger is a Go kit log.Logger that sends log events to a zerolog.Logger.
+type Logger struct {
+	logger       zerolog.Logger
+	messageKey   string
+	defaultLevel zerolog.Level
+}
+
+// NewLogger returns a Go kit log.Logger that sends log events to a
+// zerolog.Logger. The level of each event is taken from the value of
+// level.Key(), as added by level.Debug, Info, Warn and Error, so events below
+// the zerolog logger's level are discarded without being encoded. The value
+// of the message key becomes the event's message, and the remaining key/value
+// pairs its fields.
+func NewLogger(logger zerolog.Logger, options ...Option) log.Logger {
+	l := &Logger{
+		logger:       logger,
+		messageKey:   "msg",
+		defaultLevel: zerolog.InfoLevel,
+	}
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+// Log implements log.Logger.
+func (l *Logger) Log(keyvals ...interface{}) error {
+	lvl := l.defaultLevel
+	for i := 0; i < len(keyvals)-1; i += 2 {
+		if lv, ok := keyvals[i+1].(level.Value); ok && keyvals[i] == level.Key() {
+			lvl = ZerologLevel(lv)
+		}
+	}
+
+	e := l.logger.WithLevel(lvl)
+	if e == nil {
+		return nil // disabled
+	}
+	var msg string
+	for i := 0; i < len(keyvals); i += 2 {
+		k := keyvals[i]
+		var v interface{} = log.ErrMissingValue
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		if _, ok := v.(level.Value); ok && k == level.Key() {
+			continue
+		}
+		key := fmt.Sprint(k)
+		if s, ok := v.(string); ok && key == l.messageKey && msg == "" {
+			msg = s
+			continue
+		}
+		switch v := v.(type) {
+		case error:
+			e = e.AnErr(key, v)
+		case fmt.Stringer:
+			e = e.Stringer(key, v)
+		default:
+			e = e.Interface(key, v)
+		}
+	}
+	e.Msg(msg)
+	return nil
+}
+
+// ZerologLevel returns the zerolog level for a value of the level package.
+func ZerologLevel(v level.Value) zerolog.Level {
+	switch v {
+	case level.DebugValue():
+		return zerolog.DebugLevel
+	case level.WarnValue():
+		return zerolog.WarnLevel
+	case level.ErrorValue():
+		return zerolog.ErrorLevel
+	default:
+		return zerolog.InfoLevel
+	}
+}
+
+// KitLevel returns the value of the level package for a zerolog level.
+// Levels below debug, i.e. trace, map to level.DebugValue(), and levels above
+// error, e.g. zerolog.FatalLevel, to level.ErrorValue().
+func KitLevel(lvl zerolog.Level) level.Value {
+	switch lvl {
+	case zerolog.TraceLevel, zerolog.DebugLevel:
+		return level.DebugValue()
+	case zerolog.InfoLevel, zerolog.NoLevel:
+		return level.InfoValue()
+	case zerolog.WarnLevel:
+		return level.WarnValue()
+	default:
+		return level.ErrorValue()
+	}
+}
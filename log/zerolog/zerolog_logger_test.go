@@ -0,0 +1,52 @@
+package zerolog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log/level"
+	"github.com/rs/zerolog"
+
+	kitzerolog "github.com/barrett370/kit/v2/log/zerolog"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := kitzerolog.NewLogger(zerolog.New(&buf).Level(zerolog.InfoLevel))
+
+	level.Warn(logger).Log("msg", "disk low", "free", "42")
+	level.Debug(logger).Log("msg", "discarded by zerolog")
+	level.Error(logger).Log("err", errors.New("boom"))
+	logger.Log("odd")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if want, have := 3, len(lines); want != have {
+		t.Fatalf("want %d events, have %d: %q", want, have, lines)
+	}
+	for i, want := range []map[string]string{
+		{"level": "warn", "message": "disk low", "free": "42"},
+		{"level": "error", "err": "boom"},
+		{"level": "info", "odd": "(MISSING)"},
+	} {
+		have := map[string]string{}
+		if err := json.Unmarshal([]byte(lines[i]), &have); err != nil {
+			t.Fatalf("unmarshal error: %v", err)
+		}
+		for k, v := range want {
+			if have[k] != v {
+				t.Errorf("event %d, %s: want %q, have %q", i, k, v, have[k])
+			}
+		}
+	}
+}
+
+func TestLevels(t *testing.T) {
+	for _, v := range []level.Value{level.DebugValue(), level.InfoValue(), level.WarnValue(), level.ErrorValue()} {
+		if want, have := v, kitzerolog.KitLevel(kitzerolog.ZerologLevel(v)); want != have {
+			t.Errorf("want %v, have %v", want, have)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Package logging provides an endpoint middleware which logs every request:
+// the method name, how long it took, and any error, along with redacted
+// representations of the request and response.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/go-kit/log"
+)
+
+// LogSafer may be implemented by request and response types to control how
+// they're represented in logs. LogSafe should return a value which contains no
+// sensitive data, e.g. a copy of the receiver with personal information
+// masked, or a small struct of IDs.
+//
+// Values which don't implement LogSafer are logged only as their type name,
+// unless the middleware is constructed with LogUnsafe. That way, adding a
+// field containing personal information to a request type never results in
+// that information being logged by accident.
+type LogSafer interface {
+	LogSafe() interface{}
+}
+
+// Option sets an optional parameter for the middleware.
+type Option func(*config)
+
+type config struct {
+	request  bool
+	response bool
+	unsafe   bool
+	success  bool
+}
+
+// LogRequest sets whether the request is logged. By default, it is.
+func LogRequest(b bool) Option {
+	return func(c *config) { c.request = b }
+}
+
+// LogResponse sets whether the response is logged. By default, it isn't.
+func LogResponse(b bool) Option {
+	return func(c *config) { c.response = b }
+}
+
+// LogSuccess sets whether successful requests are logged. If false, only
+// requests which return an error, or a response whose Failed method returns
+// an error, are logged. By default, all requests are logged.
+func LogSuccess(b bool) Option {
+	return func(c *config) { c.success = b }
+}
+
+// LogUnsafe causes requests and responses which don't implement LogSafer to be
+// logged in full, formatted with the %+v verb. It should only be used for
+// types known to contain no sensitive data, or during development.
+func LogUnsafe() Option {
+	return func(c *config) { c.unsafe = true }
+}
+
+// Middleware returns an endpoint middleware that logs each request with the
+// keys "method", "took", "err", and optionally "request" and "response".
+func Middleware[I, O any](logger log.Logger, method string, options ...Option) endpoint.Middleware[I, O] {
+	c := config{request: true, success: true}
+	for _, option := range options {
+		option(&c)
+	}
+	return func(next endpoint.Endpoint[I, O]) endpoint.Endpoint[I, O] {
+		return func(ctx context.Context, request I) (response O, err error) {
+			defer func(begin time.Time) {
+				logErr := err
+				if f, ok := any(response).(endpoint.Failer); ok && logErr == nil {
+					logErr = f.Failed()
+				}
+				if logErr == nil && !c.success {
+					return
+				}
+				keyvals := []interface{}{"method", method, "took", time.Since(begin)}
+				if c.request {
+					keyvals = append(keyvals, "request", c.safe(request))
+				}
+				if c.response && err == nil {
+					keyvals = append(keyvals, "response", c.safe(response))
+				}
+				keyvals = append(keyvals, "err", logErr)
+				logger.Log(keyvals...)
+			}(time.Now())
+			return next(ctx, request)
+		}
+	}
+}
+
+// safe returns the representation of v to log.
+func (c config) safe(v interface{}) interface{} {
+	if s, ok := v.(LogSafer); ok {
+		return s.LogSafe()
+	}
+	if c.unsafe {
+		return fmt.Sprintf("%+v", v)
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,83 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/barrett370/kit/v2/logging"
+	"github.com/go-kit/log"
+)
+
+type signupRequest struct {
+	Email    string
+	Password string
+}
+
+type safeRequest struct {
+	UserID string
+	Token  string
+}
+
+func (r safeRequest) LogSafe() interface{} { return r.UserID }
+
+func TestMiddleware(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		options []logging.Option
+		request interface{}
+		err     error
+		want    string
+	}{
+		{
+			name:    "redacted by default",
+			request: signupRequest{"a@example.com", "hunter2"},
+			want:    `^method=test took=\S+ request=logging_test.signupRequest err=null\n$`,
+		},
+		{
+			name:    "LogSafer",
+			request: safeRequest{"user-1", "secret"},
+			want:    `^method=test took=\S+ request=user-1 err=null\n$`,
+		},
+		{
+			name:    "unsafe",
+			options: []logging.Option{logging.LogUnsafe()},
+			request: signupRequest{"a@example.com", "hunter2"},
+			want:    `^method=test took=\S+ request="{Email:a@example.com Password:hunter2}" err=null\n$`,
+		},
+		{
+			name:    "response",
+			options: []logging.Option{logging.LogRequest(false), logging.LogResponse(true)},
+			request: "abc",
+			want:    `^method=test took=\S+ response=string err=null\n$`,
+		},
+		{
+			name:    "error",
+			options: []logging.Option{logging.LogSuccess(false)},
+			request: "abc",
+			err:     errors.New("dang"),
+			want:    `err=dang`,
+		},
+		{
+			name:    "success suppressed",
+			options: []logging.Option{logging.LogSuccess(false)},
+			request: "abc",
+			want:    `^$`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := log.NewLogfmtLogger(&buf)
+			e := logging.Middleware[interface{}, interface{}](logger, "test", tc.options...)(func(_ context.Context, request interface{}) (interface{}, error) {
+				return "ok", tc.err
+			})
+			e(context.Background(), tc.request)
+
+			if have := buf.String(); !regexp.MustCompile(tc.want).MatchString(have) {
+				t.Errorf("want match for %q, have %q", tc.want, have)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package metadata
+
+import (
+	"context"
+	"strings"
+)
+
+// HeadersToContext reads metadata from a set of headers into the context,
+// like HTTPToContext. The headers may be gRPC metadata (metadata.MD), NATS
+// headers (nats.Header), or any other map[string][]string; names are matched
+// case-insensitively. Call it from the server before funcs, or interceptors,
+// of those transports, e.g.
+//
+//	md, _ := metadata.FromIncomingContext(ctx)
+//	ctx = kitmetadata.HeadersToContext(ctx, md)
+//
+// Particularly useful for servers.
+func HeadersToContext(ctx context.Context, headers map[string][]string, options ...Option) context.Context {
+	return newCodec(options).decodeContext(ctx, func(name string) []string {
+		if vs, ok := headers[strings.ToLower(name)]; ok {
+			return vs
+		}
+		for k, vs := range headers {
+			if strings.EqualFold(k, name) {
+				return vs
+			}
+		}
+		return nil
+	})
+}
+
+// ContextToHeaders writes the metadata in the context to a set of headers,
+// such as gRPC metadata or NATS headers, like ContextToHTTP; see
+// HeadersToContext. Header names are lower-case, as gRPC requires. It returns
+// the headers, which are allocated if they're nil and there's metadata, e.g.
+//
+//	md, _ := metadata.FromOutgoingContext(ctx)
+//	ctx = metadata.NewOutgoingContext(ctx, kitmetadata.ContextToHeaders(ctx, md.Copy()))
+//
+// Particularly useful for clients.
+func ContextToHeaders(ctx context.Context, headers map[string][]string, options ...Option) map[string][]string {
+	md := FromContext(ctx)
+	if len(md) == 0 {
+		return headers
+	}
+	if headers == nil {
+		headers = map[string][]string{}
+	}
+	newCodec(options).encode(md, func(name, value string) {
+		headers[strings.ToLower(name)] = []string{value}
+	}, true)
+	return headers
+}
@@ -0,0 +1,177 @@
+package metadata
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	httptransport "github.com/barrett370/kit/v2/transport/http"
+)
+
+// DefaultBaggageHeader is the header which carries metadata keys that aren't
+// mapped to their own header. Its format follows the W3C Baggage
+// specification, i.e. a comma-separated list of key=value pairs with
+// percent-encoded values.
+const DefaultBaggageHeader = "Baggage"
+
+// DefaultHeaders maps the well-known metadata keys to the headers which carry
+// them. The request ID header is that of httptransport.ServerRequestID.
+var DefaultHeaders = map[string]string{
+	KeyRequestID: "X-Request-Id",
+	KeyTenant:    "X-Tenant-Id",
+	KeyUser:      "X-User-Id",
+}
+
+// Option sets an optional parameter for the HTTP request and response funcs.
+type Option func(*codec)
+
+// Header maps the metadata key to the given header, instead of carrying it in
+// the baggage header. An empty header name carries the key in the baggage
+// header, even if it's one of the well-known keys.
+func Header(key, header string) Option {
+	return func(c *codec) {
+		if header == "" {
+			delete(c.headers, key)
+			return
+		}
+		c.headers[key] = http.CanonicalHeaderKey(header)
+	}
+}
+
+// TrustIdentity makes HTTPToContext and HeadersToContext read the identity
+// keys, KeyTenant and KeyUser, from incoming requests. Without it, they're
+// ignored, in their own headers as well as in the baggage header, since any
+// client could claim any tenant or user. Only use it for servers which are
+// reached exclusively through trusted hops, e.g. other services, or a gateway
+// which authenticates callers and strips these headers from their requests.
+func TrustIdentity() Option {
+	return func(c *codec) { c.trusted = true }
+}
+
+// BaggageHeader sets the name of the header which carries the keys that
+// aren't mapped to a header. By default, DefaultBaggageHeader is used. An
+// empty name disables the baggage header, so unmapped keys aren't propagated.
+func BaggageHeader(header string) Option {
+	return func(c *codec) { c.baggage = header }
+}
+
+type codec struct {
+	headers map[string]string
+	baggage string
+	trusted bool
+}
+
+// identityKeys are only decoded by codecs with TrustIdentity.
+var identityKeys = map[string]bool{
+	KeyTenant: true,
+	KeyUser:   true,
+}
+
+func newCodec(options []Option) codec {
+	c := codec{
+		headers: make(map[string]string, len(DefaultHeaders)),
+		baggage: DefaultBaggageHeader,
+	}
+	for k, v := range DefaultHeaders {
+		c.headers[k] = v
+	}
+	for _, option := range options {
+		option(&c)
+	}
+	return c
+}
+
+// HTTPToContext returns a RequestFunc for servers which reads metadata from
+// the incoming request headers into the context. Metadata already in the
+// context is retained, unless overwritten by a header.
+func HTTPToContext(options ...Option) httptransport.RequestFunc {
+	c := newCodec(options)
+	return func(ctx context.Context, r *http.Request) context.Context {
+		return c.decodeContext(ctx, r.Header.Values)
+	}
+}
+
+// ContextToHTTP returns a RequestFunc for clients which writes the metadata
+// in the context to the outgoing request headers.
+func ContextToHTTP(options ...Option) httptransport.RequestFunc {
+	c := newCodec(options)
+	return func(ctx context.Context, r *http.Request) context.Context {
+		c.encode(FromContext(ctx), r.Header.Set, true)
+		return ctx
+	}
+}
+
+// ContextToHTTPResponse returns a ServerResponseFunc which echoes the given
+// metadata keys to the response headers, e.g. so a caller can correlate the
+// response with a request ID. Only keys mapped to their own header are
+// written; if no keys are given, all mapped keys are.
+func ContextToHTTPResponse(keys []string, options ...Option) httptransport.ServerResponseFunc {
+	c := newCodec(options)
+	return func(ctx context.Context, w http.ResponseWriter) context.Context {
+		md := FromContext(ctx)
+		if len(keys) > 0 {
+			filtered := Metadata{}
+			for _, key := range keys {
+				if v, ok := md[key]; ok {
+					filtered[key] = v
+				}
+			}
+			md = filtered
+		}
+		c.encode(md, w.Header().Set, false)
+		return ctx
+	}
+}
+
+func (c codec) decodeContext(ctx context.Context, values func(name string) []string) context.Context {
+	md := FromContext(ctx).Clone()
+	c.decode(md, values)
+	if len(md) == 0 {
+		return ctx
+	}
+	return NewContext(ctx, md)
+}
+
+func (c codec) decode(md Metadata, values func(name string) []string) {
+	if c.baggage != "" {
+		for _, v := range values(c.baggage) {
+			for _, member := range strings.Split(v, ",") {
+				// Properties, separated by semicolons, aren't supported.
+				member, _, _ = strings.Cut(member, ";")
+				k, v, ok := strings.Cut(strings.TrimSpace(member), "=")
+				k = strings.TrimSpace(k)
+				if !ok || k == "" || (identityKeys[k] && !c.trusted) {
+					continue
+				}
+				if unescaped, err := url.PathUnescape(strings.TrimSpace(v)); err == nil {
+					md[k] = unescaped
+				}
+			}
+		}
+	}
+	for key, name := range c.headers {
+		if identityKeys[key] && !c.trusted {
+			continue
+		}
+		if vs := values(name); len(vs) > 0 && vs[0] != "" {
+			md[key] = vs[0]
+		}
+	}
+}
+
+func (c codec) encode(md Metadata, set func(name, value string), baggage bool) {
+	var members []string
+	for k, v := range md {
+		if name, ok := c.headers[k]; ok {
+			set(name, v)
+			continue
+		}
+		members = append(members, k+"="+url.PathEscape(v))
+	}
+	if baggage && c.baggage != "" && len(members) > 0 {
+		sort.Strings(members)
+		set(c.baggage, strings.Join(members, ","))
+	}
+}
@@ -0,0 +1,165 @@
+// Package metadata propagates request metadata, such as a request ID, tenant,
+// user, or arbitrary baggage, across service boundaries.
+//
+// Metadata is carried in the context. On the server side, a RequestFunc reads
+// it from incoming headers; on the client side, a RequestFunc writes it to
+// outgoing headers. That way, every service agrees on the header names, and
+// metadata received by a service is forwarded to the services it calls.
+// HeadersToContext and ContextToHeaders do the same for gRPC metadata, or any
+// other map[string][]string.
+//
+// The identity keys, KeyTenant and KeyUser, are only read from incoming
+// requests when the server trusts its callers to assert them; see
+// TrustIdentity. Otherwise, set them from authenticated credentials, e.g. the
+// claims of a JWT, with AppendToContext.
+//
+// The request ID is kept under the RequestIDKey of package transport/http, so
+// it's shared with ServerRequestID, RequestIDFromContext and the access log.
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	httptransport "github.com/barrett370/kit/v2/transport/http"
+)
+
+// Well-known metadata keys. Each is carried in its own header; all other keys
+// are carried in the baggage header. KeyTenant and KeyUser are identity keys,
+// which are only read from incoming requests with TrustIdentity.
+const (
+	KeyRequestID = "request-id"
+	KeyTenant    = "tenant"
+	KeyUser      = "user"
+)
+
+// Metadata is a set of key/value pairs describing a request. Keys are
+// case-sensitive, and by convention lower-case.
+type Metadata map[string]string
+
+// New returns metadata containing the given key/value pairs. A trailing key
+// without a value is ignored.
+func New(keyvals ...string) Metadata {
+	md := make(Metadata, len(keyvals)/2)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		md[keyvals[i]] = keyvals[i+1]
+	}
+	return md
+}
+
+// Get returns the value for the key, or the empty string.
+func (md Metadata) Get(key string) string {
+	return md[key]
+}
+
+// Clone returns a copy of the metadata.
+func (md Metadata) Clone() Metadata {
+	c := make(Metadata, len(md))
+	for k, v := range md {
+		c[k] = v
+	}
+	return c
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying the metadata, replacing any metadata
+// already in the context. The metadata mustn't be modified afterwards. A
+// request ID is stored under httptransport.RequestIDKey instead; if the
+// metadata has none, the context's request ID, if any, is retained.
+func NewContext(ctx context.Context, md Metadata) context.Context {
+	if id, ok := md[KeyRequestID]; ok {
+		ctx = httptransport.RequestIDKey.Set(ctx, id)
+		md = md.Clone()
+		delete(md, KeyRequestID)
+	}
+	return context.WithValue(ctx, contextKey{}, md)
+}
+
+// FromContext returns the metadata carried by the context, including its
+// request ID, or nil. The returned metadata mustn't be modified; use Clone or
+// AppendToContext instead.
+func FromContext(ctx context.Context) Metadata {
+	md := fromContext(ctx)
+	if id, ok := httptransport.RequestIDKey.Get(ctx); ok {
+		md = md.Clone()
+		md[KeyRequestID] = id
+	}
+	return md
+}
+
+func fromContext(ctx context.Context) Metadata {
+	md, _ := ctx.Value(contextKey{}).(Metadata)
+	return md
+}
+
+// lookup returns the value of the key in the context's metadata, without
+// copying the metadata as FromContext does.
+func lookup(ctx context.Context, md Metadata, key string) string {
+	if key == KeyRequestID {
+		return httptransport.RequestIDKey.Value(ctx)
+	}
+	return md.Get(key)
+}
+
+// AppendToContext returns a context carrying the metadata of ctx, with the
+// given key/value pairs added.
+func AppendToContext(ctx context.Context, keyvals ...string) context.Context {
+	md := FromContext(ctx).Clone()
+	for k, v := range New(keyvals...) {
+		md[k] = v
+	}
+	return NewContext(ctx, md)
+}
+
+// MissingError is returned by Require when a request lacks a required key. It
+// implements the StatusCoder interface of package transport/http, so the
+// DefaultErrorEncoder responds with 400 Bad Request.
+type MissingError struct {
+	Key string
+}
+
+// Error implements the error interface.
+func (e MissingError) Error() string {
+	return fmt.Sprintf("missing request metadata %q", e.Key)
+}
+
+// StatusCode implements StatusCoder.
+func (MissingError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// Require returns an endpoint middleware which rejects requests whose context
+// doesn't carry a non-empty value for each of the given keys, e.g. to ensure
+// every request to a multi-tenant service is attributed to a tenant.
+func Require[I, O any](keys ...string) endpoint.Middleware[I, O] {
+	return func(next endpoint.Endpoint[I, O]) endpoint.Endpoint[I, O] {
+		return func(ctx context.Context, request I) (O, error) {
+			md := fromContext(ctx)
+			for _, key := range keys {
+				if lookup(ctx, md, key) == "" {
+					var zero O
+					return zero, MissingError{Key: key}
+				}
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// LabelValues returns a func which reads the values of the given keys from the
+// metadata in the context, for use as metric label values, e.g. with
+// metrics.ContextLabels. Missing keys yield the empty string, so the number of
+// label values is always the number of keys.
+func LabelValues(keys ...string) func(context.Context) []string {
+	return func(ctx context.Context) []string {
+		md := fromContext(ctx)
+		lvs := make([]string, len(keys))
+		for i, key := range keys {
+			lvs[i] = lookup(ctx, md, key)
+		}
+		return lvs
+	}
+}
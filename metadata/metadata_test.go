@@ -0,0 +1,164 @@
+package metadata_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/barrett370/kit/v2/metadata"
+	httptransport "github.com/barrett370/kit/v2/transport/http"
+)
+
+func TestHTTPRoundTrip(t *testing.T) {
+	ctx := metadata.NewContext(context.Background(), metadata.New(
+		metadata.KeyRequestID, "abc",
+		metadata.KeyTenant, "acme",
+		"region", "eu west",
+		"shard", "7",
+	))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	metadata.ContextToHTTP()(ctx, req)
+
+	for header, want := range map[string]string{
+		"X-Request-Id": "abc",
+		"X-Tenant-Id":  "acme",
+		"Baggage":      "region=eu%20west,shard=7",
+	} {
+		if have := req.Header.Get(header); want != have {
+			t.Errorf("%s: want %q, have %q", header, want, have)
+		}
+	}
+
+	have := metadata.FromContext(metadata.HTTPToContext(metadata.TrustIdentity())(context.Background(), req))
+	if want := metadata.FromContext(ctx); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestHTTPUntrustedIdentity(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Tenant-Id", "acme")
+	req.Header.Set("X-User-Id", "alice")
+	req.Header.Set("Baggage", "user=mallory,region=eu")
+
+	ctx := metadata.AppendToContext(context.Background(), metadata.KeyUser, "bob")
+	ctx = metadata.HTTPToContext()(ctx, req)
+
+	want := metadata.New(metadata.KeyUser, "bob", "region", "eu")
+	if have := metadata.FromContext(ctx); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-Id", "abc")
+
+	ctx := metadata.HTTPToContext()(context.Background(), req)
+	if want, have := "abc", httptransport.RequestIDFromContext(ctx); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	// An ID set by ServerRequestID is seen as metadata, and forwarded.
+	ctx = httptransport.RequestIDKey.Set(context.Background(), "def")
+	if want, have := "def", metadata.FromContext(ctx).Get(metadata.KeyRequestID); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	out := httptest.NewRequest("GET", "/", nil)
+	metadata.ContextToHTTP()(ctx, out)
+	if want, have := "def", out.Header.Get("X-Request-Id"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestHeadersRoundTrip(t *testing.T) {
+	ctx := metadata.NewContext(context.Background(), metadata.New(
+		metadata.KeyRequestID, "abc",
+		metadata.KeyUser, "alice",
+		"region", "eu west",
+	))
+
+	headers := metadata.ContextToHeaders(ctx, nil)
+	want := map[string][]string{
+		"x-request-id": {"abc"},
+		"x-user-id":    {"alice"},
+		"baggage":      {"region=eu%20west"},
+	}
+	if !reflect.DeepEqual(want, headers) {
+		t.Errorf("want %v, have %v", want, headers)
+	}
+
+	have := metadata.FromContext(metadata.HeadersToContext(context.Background(), headers, metadata.TrustIdentity()))
+	if want := metadata.FromContext(ctx); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	have = metadata.FromContext(metadata.HeadersToContext(context.Background(), headers))
+	if want := metadata.New(metadata.KeyRequestID, "abc", "region", "eu west"); !reflect.DeepEqual(want, have) {
+		t.Errorf("untrusted: want %v, have %v", want, have)
+	}
+}
+
+func TestHTTPCustomHeaders(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Org", "acme")
+	req.Header.Set("X-Request-Id", "abc")
+
+	ctx := metadata.HTTPToContext(
+		metadata.TrustIdentity(),
+		metadata.Header(metadata.KeyTenant, "X-Org"),
+		metadata.Header(metadata.KeyRequestID, ""),
+	)(context.Background(), req)
+
+	want := metadata.New(metadata.KeyTenant, "acme")
+	if have := metadata.FromContext(ctx); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestContextToHTTPResponse(t *testing.T) {
+	ctx := metadata.NewContext(context.Background(), metadata.New(
+		metadata.KeyRequestID, "abc",
+		metadata.KeyUser, "alice",
+		"region", "eu",
+	))
+	rec := httptest.NewRecorder()
+	metadata.ContextToHTTPResponse([]string{metadata.KeyRequestID})(ctx, rec)
+
+	want := http.Header{"X-Request-Id": {"abc"}}
+	if have := rec.Header(); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	e := metadata.Require[struct{}, struct{}](metadata.KeyTenant)(func(context.Context, struct{}) (struct{}, error) {
+		return struct{}{}, nil
+	})
+
+	_, err := e(context.Background(), struct{}{})
+	var missing metadata.MissingError
+	if !errors.As(err, &missing) || missing.Key != metadata.KeyTenant {
+		t.Errorf("want MissingError for %q, have %v", metadata.KeyTenant, err)
+	}
+	if want, have := http.StatusBadRequest, missing.StatusCode(); want != have {
+		t.Errorf("status code: want %d, have %d", want, have)
+	}
+
+	ctx := metadata.AppendToContext(context.Background(), metadata.KeyTenant, "acme")
+	if _, err := e(ctx, struct{}{}); err != nil {
+		t.Errorf("want no error, have %v", err)
+	}
+}
+
+func TestLabelValues(t *testing.T) {
+	ctx := metadata.NewContext(context.Background(), metadata.New(metadata.KeyTenant, "acme"))
+	want := []string{"acme", ""}
+	if have := metadata.LabelValues(metadata.KeyTenant, metadata.KeyUser)(ctx); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
@@ -14,6 +14,8 @@ import (
 
 	"github.com/barrett370/kit/v2/metrics"
 	"github.com/barrett370/kit/v2/metrics/generic"
+	"github.com/barrett370/kit/v2/metrics/internal/gaugefunc"
+	"github.com/barrett370/kit/v2/metrics/internal/loop"
 	"github.com/barrett370/kit/v2/metrics/internal/lv"
 	"github.com/go-kit/log"
 )
@@ -37,8 +39,12 @@ type CloudWatch struct {
 	gauges                *lv.Space
 	histograms            *lv.Space
 	percentiles           []float64 // percentiles to track
+	statisticSets         bool
 	logger                log.Logger
 	numConcurrentRequests int
+	gaugeFuncs            gaugefunc.Set
+	flushFunc             metrics.FlushFunc
+	jitter                time.Duration
 }
 
 // Option is a function adapter to change config of the CloudWatch struct
@@ -68,6 +74,18 @@ func WithPercentiles(percentiles ...float64) Option {
 	}
 }
 
+// WithStatisticSets aggregates the observations of each gauge and histogram
+// into a single StatisticSet (sample count, sum, minimum and maximum) per
+// send, rather than emitting the distinct values of gauges, and a gauge per
+// percentile of histograms. That cuts the number of datums, and thus the
+// cost, of PutMetricData calls, at the expense of percentiles, which
+// CloudWatch can't compute from StatisticSets. WithPercentiles has no effect.
+func WithStatisticSets() Option {
+	return func(c *CloudWatch) {
+		c.statisticSets = true
+	}
+}
+
 // WithConcurrentRequests sets the upper limit on how many
 // cloudwatch.PutMetricDataRequest may be under way at any
 // given time. If n is greater than 20, 20 is used. By default,
@@ -81,6 +99,24 @@ func WithConcurrentRequests(n int) Option {
 	}
 }
 
+// WithFlushFunc sets a func which is called after each Send, with the number
+// of metric datums sent, the duration of the requests, and the first error,
+// if any, e.g. one returned by metrics.NewFlushFunc. By default, failures are
+// only logged by WriteLoop.
+func WithFlushFunc(f metrics.FlushFunc) Option {
+	return func(c *CloudWatch) {
+		c.flushFunc = f
+	}
+}
+
+// WithJitter delays each write made by the loop helper methods by a random
+// offset in [0, jitter), chosen once when the loop starts, so that processes
+// started together don't all flush at the same instant. By default, there's
+// no delay.
+func WithJitter(jitter time.Duration) Option {
+	return func(c *CloudWatch) { c.jitter = jitter }
+}
+
 // New returns a CloudWatch object that may be used to create metrics.
 // Namespace is applied to all created metrics and maps to the CloudWatch namespace.
 // Callers must ensure that regular calls to Send are performed, either
@@ -125,6 +161,13 @@ func (cw *CloudWatch) NewGauge(name string) metrics.Gauge {
 	}
 }
 
+// NewGaugeFunc adds a gauge whose value is sampled from f immediately before
+// each send, for values such as queue depth which aren't naturally
+// event-driven. The label values are applied to the gauge via With.
+func (cw *CloudWatch) NewGaugeFunc(name string, f func() float64, labelValues ...string) {
+	cw.gaugeFuncs.Add(cw.NewGauge(name).With(labelValues...), f)
+}
+
 // NewHistogram returns a histogram.
 func (cw *CloudWatch) NewHistogram(name string) metrics.Histogram {
 	return &Histogram{
@@ -137,22 +180,25 @@ func (cw *CloudWatch) NewHistogram(name string) metrics.Histogram {
 // channel fires. This method blocks until ctx is canceled, so clients
 // probably want to run it in its own goroutine. For typical usage, create a
 // time.Ticker and pass its C channel to this method.
+// When ctx is canceled, a final write is made, so that the observations
+// made since the last tick aren't lost.
 func (cw *CloudWatch) WriteLoop(ctx context.Context, c <-chan time.Time) {
-	for {
-		select {
-		case <-c:
-			if err := cw.Send(); err != nil {
-				cw.logger.Log("during", "Send", "err", err)
-			}
-		case <-ctx.Done():
-			return
+	loop.Run(ctx, c, cw.jitter, func(context.Context) {
+		if err := cw.Send(); err != nil {
+			cw.logger.Log("during", "Send", "err", err)
 		}
-	}
+	})
 }
 
 // Send will fire an API request to CloudWatch with the latest stats for
 // all metrics. It is preferred that the WriteLoop method is used.
-func (cw *CloudWatch) Send() error {
+func (cw *CloudWatch) Send() (err error) {
+	var sent int64
+	if cw.flushFunc != nil {
+		defer func(begin time.Time) { cw.flushFunc(sent, time.Since(begin), err) }(time.Now())
+	}
+	cw.gaugeFuncs.Sample()
+
 	cw.mtx.RLock()
 	defer cw.mtx.RUnlock()
 	now := time.Now()
@@ -181,6 +227,12 @@ func (cw *CloudWatch) Send() error {
 			Timestamp:  aws.Time(now),
 		}
 
+		if cw.statisticSets {
+			datum.StatisticValues = statisticSet(values)
+			datums = append(datums, datum)
+			return true
+		}
+
 		// CloudWatch Put Metrics API (https://docs.aws.amazon.com/AmazonCloudWatch/latest/APIReference/API_MetricDatum.html)
 		// expects batch of unique values including the array of corresponding counts
 		valuesCounter := make(map[float64]int)
@@ -209,6 +261,16 @@ func (cw *CloudWatch) Send() error {
 	}
 
 	cw.histograms.Reset().Walk(func(name string, lvs lv.LabelValues, values []float64) bool {
+		if cw.statisticSets {
+			datums = append(datums, &cloudwatch.MetricDatum{
+				MetricName:      aws.String(name),
+				Dimensions:      makeDimensions(lvs...),
+				StatisticValues: statisticSet(values),
+				Timestamp:       aws.Time(now),
+			})
+			return true
+		}
+
 		histogram := generic.NewHistogram(name, 50)
 
 		for _, v := range values {
@@ -227,6 +289,8 @@ func (cw *CloudWatch) Send() error {
 		return true
 	})
 
+	sent = int64(len(datums))
+
 	var batches [][]*cloudwatch.MetricDatum
 	for len(datums) > 0 {
 		var batch []*cloudwatch.MetricDatum
@@ -267,6 +331,24 @@ func sum(a []float64) float64 {
 	return v
 }
 
+func statisticSet(values []float64) *cloudwatch.StatisticSet {
+	lo, hi := values[0], values[0]
+	for _, v := range values[1:] {
+		if v < lo {
+			lo = v
+		}
+		if v > hi {
+			hi = v
+		}
+	}
+	return &cloudwatch.StatisticSet{
+		SampleCount: aws.Float64(float64(len(values))),
+		Sum:         aws.Float64(sum(values)),
+		Minimum:     aws.Float64(lo),
+		Maximum:     aws.Float64(hi),
+	}
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -23,12 +23,14 @@ type mockCloudWatch struct {
 	cloudwatchiface.CloudWatchAPI
 	mtx                sync.RWMutex
 	valuesReceived     map[string][]float64
+	statisticsReceived map[string]*cloudwatch.StatisticSet
 	dimensionsReceived map[string][]*cloudwatch.Dimension
 }
 
 func newMockCloudWatch() *mockCloudWatch {
 	return &mockCloudWatch{
 		valuesReceived:     map[string][]float64{},
+		statisticsReceived: map[string]*cloudwatch.StatisticSet{},
 		dimensionsReceived: map[string][]*cloudwatch.Dimension{},
 	}
 }
@@ -41,7 +43,9 @@ func (mcw *mockCloudWatch) PutMetricData(input *cloudwatch.PutMetricDataInput) (
 			return nil, errTest
 		}
 
-		if len(datum.Values) > 0 {
+		if datum.StatisticValues != nil {
+			mcw.statisticsReceived[*datum.MetricName] = datum.StatisticValues
+		} else if len(datum.Values) > 0 {
 			for _, v := range datum.Values {
 				mcw.valuesReceived[*datum.MetricName] = append(mcw.valuesReceived[*datum.MetricName], *v)
 			}
@@ -297,3 +301,37 @@ func TestErrorLog(t *testing.T) {
 		t.Fatal("Expected error, but didn't get one")
 	}
 }
+
+func TestStatisticSets(t *testing.T) {
+	svc := newMockCloudWatch()
+	cw := New("abc", svc, WithStatisticSets(), WithLogger(log.NewNopLogger()))
+	g := cw.NewGauge("g").With("golf", "giraffe")
+	h := cw.NewHistogram("h").With("hotel", "horse")
+	for _, v := range []float64{3, 1, 2} {
+		g.Set(v)
+		h.Observe(v * 10)
+	}
+	if err := cw.Send(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string][4]float64{
+		"g": {3, 6, 1, 3},
+		"h": {3, 60, 10, 30},
+	} {
+		s, ok := svc.statisticsReceived[name]
+		if !ok {
+			t.Errorf("%s: no StatisticSet received", name)
+			continue
+		}
+		if have := [4]float64{*s.SampleCount, *s.Sum, *s.Minimum, *s.Maximum}; want != have {
+			t.Errorf("%s: want %v, have %v", name, want, have)
+		}
+	}
+	if _, ok := svc.valuesReceived["h_50"]; ok {
+		t.Errorf("want no percentile gauges, have %v", svc.valuesReceived)
+	}
+	if d := svc.dimensionsReceived["h"]; len(d) != 1 || *d[0].Name != "hotel" || *d[0].Value != "horse" {
+		t.Errorf("want dimension hotel=horse, have %v", d)
+	}
+}
@@ -1,5 +1,7 @@
 // Package cloudwatch2 emits all data as a StatisticsSet (rather than
-// a singular Value) to CloudWatch via the aws-sdk-go-v2 SDK.
+// a singular Value) to CloudWatch via the aws-sdk-go-v2 SDK. As aws-sdk-go
+// is in maintenance mode, new code should prefer this package to package
+// cloudwatch.
 package cloudwatch2
 
 import (
@@ -15,6 +17,8 @@ import (
 
 	"github.com/barrett370/kit/v2/metrics"
 	"github.com/barrett370/kit/v2/metrics/internal/convert"
+	"github.com/barrett370/kit/v2/metrics/internal/gaugefunc"
+	"github.com/barrett370/kit/v2/metrics/internal/loop"
 	"github.com/barrett370/kit/v2/metrics/internal/lv"
 	"github.com/go-kit/log"
 )
@@ -41,6 +45,10 @@ type CloudWatch struct {
 	counters              *lv.Space
 	logger                log.Logger
 	numConcurrentRequests int
+	timeout               time.Duration
+	optFns                []func(*cloudwatch.Options)
+	gaugeFuncs            gaugefunc.Set
+	jitter                time.Duration
 }
 
 // Option is a function adapter to change config of the CloudWatch struct
@@ -67,6 +75,35 @@ func WithConcurrentRequests(n int) Option {
 	}
 }
 
+// WithTimeout sets the time limit of each PutMetricData call, including its
+// retries. By default, calls are limited only by the context passed to
+// SendContext.
+func WithTimeout(d time.Duration) Option {
+	return func(cw *CloudWatch) {
+		cw.timeout = d
+	}
+}
+
+// WithClientOptions adds functional options which are applied to the client
+// for each PutMetricData call, e.g. to configure retries:
+//
+//	cloudwatch2.WithClientOptions(func(o *cloudwatch.Options) {
+//	    o.Retryer = retry.AddWithMaxAttempts(o.Retryer, 5)
+//	})
+func WithClientOptions(optFns ...func(*cloudwatch.Options)) Option {
+	return func(cw *CloudWatch) {
+		cw.optFns = append(cw.optFns, optFns...)
+	}
+}
+
+// WithJitter delays each write made by the loop helper methods by a random
+// offset in [0, jitter), chosen once when the loop starts, so that processes
+// started together don't all flush at the same instant. By default, there's
+// no delay.
+func WithJitter(jitter time.Duration) Option {
+	return func(c *CloudWatch) { c.jitter = jitter }
+}
+
 // New returns a CloudWatch object that may be used to create metrics.
 // Namespace is applied to all created metrics and maps to the CloudWatch namespace.
 // Callers must ensure that regular calls to Send are performed, either
@@ -105,6 +142,13 @@ func (cw *CloudWatch) NewGauge(name string) metrics.Gauge {
 	return convert.NewCounterAsGauge(cw.NewCounter(name))
 }
 
+// NewGaugeFunc adds a gauge whose value is sampled from f immediately before
+// each send, for values such as queue depth which aren't naturally
+// event-driven. The label values are applied to the gauge via With.
+func (cw *CloudWatch) NewGaugeFunc(name string, f func() float64, labelValues ...string) {
+	cw.gaugeFuncs.Add(cw.NewGauge(name).With(labelValues...), f)
+}
+
 // NewHistogram returns a histogram. Under the covers, there is no distinctions
 // in CloudWatch for how Counters/Histograms/Gauges are reported, so this
 // just wraps a cloudwatch2.Counter.
@@ -112,26 +156,31 @@ func (cw *CloudWatch) NewHistogram(name string) metrics.Histogram {
 	return convert.NewCounterAsHistogram(cw.NewCounter(name))
 }
 
-// WriteLoop is a helper method that invokes Send every time the passed
+// WriteLoop is a helper method that invokes SendContext every time the passed
 // channel fires. This method blocks until ctx is canceled, so clients
 // probably want to run it in its own goroutine. For typical usage, create a
 // time.Ticker and pass its C channel to this method.
+// When ctx is canceled, a final write is made, so that the observations
+// made since the last tick aren't lost.
 func (cw *CloudWatch) WriteLoop(ctx context.Context, c <-chan time.Time) {
-	for {
-		select {
-		case <-c:
-			if err := cw.Send(); err != nil {
-				cw.logger.Log("during", "Send", "err", err)
-			}
-		case <-ctx.Done():
-			return
+	loop.Run(ctx, c, cw.jitter, func(ctx context.Context) {
+		if err := cw.SendContext(ctx); err != nil {
+			cw.logger.Log("during", "Send", "err", err)
 		}
-	}
+	})
 }
 
 // Send will fire an API request to CloudWatch with the latest stats for
 // all metrics. It is preferred that the WriteLoop method is used.
 func (cw *CloudWatch) Send() error {
+	return cw.SendContext(context.Background())
+}
+
+// SendContext is like Send, but the API requests are made with the given
+// context, so they're abandoned when it's canceled.
+func (cw *CloudWatch) SendContext(ctx context.Context) error {
+	cw.gaugeFuncs.Sample()
+
 	cw.mtx.RLock()
 	defer cw.mtx.RUnlock()
 	now := time.Now()
@@ -163,20 +212,33 @@ func (cw *CloudWatch) Send() error {
 	for _, batch := range batches {
 		batch := batch
 		g.Go(func() error {
-			cw.sem <- struct{}{}
+			select {
+			case cw.sem <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
 			defer func() {
 				<-cw.sem
 			}()
-			_, err := cw.svc.PutMetricData(context.TODO(), &cloudwatch.PutMetricDataInput{
-				Namespace:  aws.String(cw.namespace),
-				MetricData: batch,
-			})
-			return err
+			return cw.put(ctx, batch)
 		})
 	}
 	return g.Wait()
 }
 
+func (cw *CloudWatch) put(ctx context.Context, batch []types.MetricDatum) error {
+	if cw.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cw.timeout)
+		defer cancel()
+	}
+	_, err := cw.svc.PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
+		Namespace:  aws.String(cw.namespace),
+		MetricData: batch,
+	}, cw.optFns...)
+	return err
+}
+
 var zero = float64(0.0)
 
 // Just build this once to reduce construction costs whenever
@@ -4,6 +4,7 @@ import (
 	"context"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
@@ -137,3 +138,41 @@ func TestSend(t *testing.T) {
 		}
 	}
 }
+
+type deadlineCloudWatch struct {
+	CloudWatchAPI
+	deadline bool
+	optFns   int
+}
+
+func (dcw *deadlineCloudWatch) PutMetricData(ctx context.Context, params *cloudwatch.PutMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.PutMetricDataOutput, error) {
+	_, dcw.deadline = ctx.Deadline()
+	dcw.optFns = len(optFns)
+	return nil, ctx.Err()
+}
+
+func TestSendContext(t *testing.T) {
+	svc := &deadlineCloudWatch{}
+	cw := New("example-namespace", svc,
+		WithTimeout(time.Second),
+		WithClientOptions(func(*cloudwatch.Options) {}),
+	)
+	cw.NewCounter("c").Add(1)
+
+	if err := cw.SendContext(context.Background()); err != nil {
+		t.Fatalf("unexpected: %v\n", err)
+	}
+	if !svc.deadline {
+		t.Errorf("expected PutMetricData context to have a deadline\n")
+	}
+	if svc.optFns != 1 {
+		t.Errorf("expected 1 client option; not %d\n", svc.optFns)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cw.NewCounter("c").Add(1)
+	if err := cw.SendContext(ctx); err != context.Canceled {
+		t.Errorf("expected %v; not %v\n", context.Canceled, err)
+	}
+}
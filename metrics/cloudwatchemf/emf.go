@@ -0,0 +1,483 @@
+// Package cloudwatchemf provides a CloudWatch backend for metrics, which
+// writes observations as log events in the CloudWatch Embedded Metric Format
+// (EMF). CloudWatch Logs extracts the metrics from the log events
+// asynchronously, so emitting metrics isn't subject to the rate limits and
+// per-call costs of PutMetricData.
+//
+// In AWS Lambda, and wherever the CloudWatch agent collects a process's
+// output, it's enough to write the log events to stdout. Elsewhere, pass an
+// io.Writer which forwards each line to the CloudWatch Logs PutLogEvents API.
+//
+// Every distinct set of label values becomes one log event per write, with
+// the label values as its dimensions. Counters are written as the sum of their
+// observations, gauges as their last observation, and histograms as the array
+// of their observations, from which CloudWatch computes statistics and
+// percentiles.
+//
+// See https://docs.aws.amazon.com/AmazonCloudWatch/latest/monitoring/CloudWatch_Embedded_Metric_Format_Specification.html
+// for details of the format.
+package cloudwatchemf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/barrett370/kit/v2/metrics"
+	"github.com/barrett370/kit/v2/metrics/internal/gaugefunc"
+	"github.com/barrett370/kit/v2/metrics/internal/loop"
+	"github.com/barrett370/kit/v2/metrics/internal/lv"
+	"github.com/go-kit/log"
+)
+
+// Limits imposed by the format on every log event.
+const (
+	maxMetrics = 100 // metrics per log event
+	maxValues  = 100 // values per metric per log event
+)
+
+// EMF receives metrics observations and writes them as log events in the
+// CloudWatch Embedded Metric Format. Create an EMF object, use it to create
+// metrics, and pass those metrics as dependencies to the components that
+// will use them.
+//
+// To regularly write metrics, use the WriteLoop helper method.
+type EMF struct {
+	mtx           sync.Mutex
+	namespace     string
+	counters      *lv.Space
+	gauges        *lv.Space
+	histograms    *lv.Space
+	dimensionSets [][]string
+	resolution    int
+	resolutions   map[string]int
+	units         map[string]string
+	properties    map[string]map[string]string // by label values
+	logger        log.Logger
+	gaugeFuncs    gaugefunc.Set
+	jitter        time.Duration
+}
+
+// Option sets an optional parameter for the EMF object.
+type Option func(*EMF)
+
+// WithDimensionSets sets the combinations of label names by which metrics are
+// aggregated in CloudWatch, e.g. {"service"} and {"service", "method"}. A set
+// is applied to a log event only if the event carries all of its labels;
+// labels outside any applicable set are written as properties, which can be
+// queried with CloudWatch Logs Insights but don't become dimensions. By
+// default, every event is aggregated by all of its labels.
+func WithDimensionSets(sets ...[]string) Option {
+	return func(e *EMF) { e.dimensionSets = sets }
+}
+
+// WithStorageResolution sets the storage resolution, in seconds, of the named
+// metrics, or of all metrics if no names are given. CloudWatch supports 60,
+// the default, and 1, i.e. high resolution metrics.
+func WithStorageResolution(seconds int, names ...string) Option {
+	return func(e *EMF) {
+		if len(names) == 0 {
+			e.resolution = seconds
+		}
+		for _, name := range names {
+			e.resolutions[name] = seconds
+		}
+	}
+}
+
+// WithUnit sets the unit of the named metrics, e.g. "Milliseconds" or
+// "Count". See the CloudWatch documentation for the valid units.
+func WithUnit(unit string, names ...string) Option {
+	return func(e *EMF) {
+		for _, name := range names {
+			e.units[name] = unit
+		}
+	}
+}
+
+// WithJitter delays each write made by the loop helper methods by a random
+// offset in [0, jitter), chosen once when the loop starts, so that processes
+// started together don't all flush at the same instant. By default, there's
+// no delay.
+func WithJitter(jitter time.Duration) Option {
+	return func(e *EMF) { e.jitter = jitter }
+}
+
+// New returns an EMF object that may be used to create metrics. Namespace is
+// applied to all created metrics and maps to the CloudWatch namespace.
+// Callers must ensure that regular calls to WriteTo are performed, either
+// manually or with the WriteLoop helper method.
+func New(namespace string, logger log.Logger, options ...Option) *EMF {
+	e := &EMF{
+		namespace:   namespace,
+		counters:    lv.NewSpace(),
+		gauges:      lv.NewSpace(),
+		histograms:  lv.NewSpace(),
+		resolutions: map[string]int{},
+		units:       map[string]string{},
+		properties:  map[string]map[string]string{},
+		logger:      logger,
+	}
+	for _, option := range options {
+		option(e)
+	}
+	return e
+}
+
+// NewCounter returns a counter. Observations are aggregated and written as
+// their sum once per write.
+func (e *EMF) NewCounter(name string) *Counter {
+	return &Counter{
+		name: name,
+		obs:  e.counters.Observe,
+		ex:   e.exemplar,
+	}
+}
+
+// NewGauge returns a gauge. The last observation is written once per write.
+func (e *EMF) NewGauge(name string) *Gauge {
+	return &Gauge{
+		name: name,
+		obs:  e.gauges.Observe,
+		add:  e.gauges.Add,
+	}
+}
+
+// NewGaugeFunc adds a gauge whose value is sampled from f immediately before
+// each write, for values such as queue depth which aren't naturally
+// event-driven. The label values are applied to the gauge via With.
+func (e *EMF) NewGaugeFunc(name string, f func() float64, labelValues ...string) {
+	e.gaugeFuncs.Add(e.NewGauge(name).With(labelValues...), f)
+}
+
+// NewHistogram returns a histogram. All observations are written once per
+// write, and CloudWatch computes their statistics.
+func (e *EMF) NewHistogram(name string) *Histogram {
+	return &Histogram{
+		name: name,
+		obs:  e.histograms.Observe,
+		ex:   e.exemplar,
+	}
+}
+
+// exemplar records the exemplar labels, e.g. trace_id, as properties of the
+// log event for the label values. They don't become dimensions, but link the
+// event to the trace in CloudWatch Logs Insights.
+func (e *EMF) exemplar(lvs lv.LabelValues, labels map[string]string) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	key := strings.Join(lvs, "\x00")
+	p, ok := e.properties[key]
+	if !ok {
+		p = map[string]string{}
+		e.properties[key] = p
+	}
+	for k, v := range labels {
+		p[k] = v
+	}
+}
+
+// WriteLoop is a helper method that invokes WriteTo to the passed writer every
+// time the passed channel fires. This method blocks until ctx is canceled,
+// so clients probably want to run it in its own goroutine. For typical usage,
+// create a time.Ticker and pass its C channel to this method.
+// When ctx is canceled, a final write is made, so that the observations
+// made since the last tick aren't lost.
+func (e *EMF) WriteLoop(ctx context.Context, c <-chan time.Time, w io.Writer) {
+	loop.Run(ctx, c, e.jitter, func(context.Context) {
+		if _, err := e.WriteTo(w); err != nil {
+			e.logger.Log("during", "WriteTo", "err", err)
+		}
+	})
+}
+
+// WriteTo flushes the buffered observations to the writer as EMF log events,
+// one JSON document per line. The events are written with a single call to
+// Write. WriteTo abides best-effort semantics, so observations are lost if
+// there is a problem with the write.
+func (e *EMF) WriteTo(w io.Writer) (int64, error) {
+	e.gaugeFuncs.Sample()
+
+	e.mtx.Lock()
+	properties := e.properties
+	e.properties = map[string]map[string]string{}
+	e.mtx.Unlock()
+
+	events := map[string]*event{}
+	eventFor := func(lvs lv.LabelValues) *event {
+		key := strings.Join(lvs, "\x00")
+		ev, ok := events[key]
+		if !ok {
+			ev = &event{key: key, lvs: append(lv.LabelValues{}, lvs...), values: map[string][]float64{}}
+			events[key] = ev
+		}
+		return ev
+	}
+	e.counters.Reset().Walk(func(name string, lvs lv.LabelValues, values []float64) bool {
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		eventFor(lvs).values[name] = []float64{sum}
+		return true
+	})
+	e.gauges.Reset().Walk(func(name string, lvs lv.LabelValues, values []float64) bool {
+		eventFor(lvs).values[name] = []float64{values[len(values)-1]}
+		return true
+	})
+	e.histograms.Reset().Walk(func(name string, lvs lv.LabelValues, values []float64) bool {
+		eventFor(lvs).values[name] = append([]float64{}, values...)
+		return true
+	})
+
+	keys := make([]string, 0, len(events))
+	for key := range events {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var (
+		buf bytes.Buffer
+		enc = json.NewEncoder(&buf)
+		now = time.Now().UnixNano() / int64(time.Millisecond)
+	)
+	for _, key := range keys {
+		for _, doc := range e.documents(events[key], properties[key], now) {
+			if err := enc.Encode(doc); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if buf.Len() == 0 {
+		return 0, nil
+	}
+	return buf.WriteTo(w)
+}
+
+type event struct {
+	key    string
+	lvs    lv.LabelValues
+	values map[string][]float64 // by metric name
+}
+
+type metadata struct {
+	Timestamp         int64       `json:"Timestamp"`
+	CloudWatchMetrics []directive `json:"CloudWatchMetrics"`
+}
+
+type directive struct {
+	Namespace  string       `json:"Namespace"`
+	Dimensions [][]string   `json:"Dimensions"`
+	Metrics    []definition `json:"Metrics"`
+}
+
+type definition struct {
+	Name              string `json:"Name"`
+	Unit              string `json:"Unit,omitempty"`
+	StorageResolution int    `json:"StorageResolution,omitempty"`
+}
+
+// documents returns the JSON documents for the event, split as necessary to
+// abide by the limits on the number of metrics and values per document.
+func (e *EMF) documents(ev *event, properties map[string]string, timestamp int64) []map[string]interface{} {
+	names := make([]string, 0, len(ev.values))
+	for name := range ev.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	dimensions := e.dimensions(ev.lvs)
+
+	var docs []map[string]interface{}
+	for len(names) > 0 {
+		doc := map[string]interface{}{}
+		for k, v := range properties {
+			doc[k] = v
+		}
+		for i := 0; i < len(ev.lvs); i += 2 {
+			doc[ev.lvs[i]] = ev.lvs[i+1]
+		}
+
+		var (
+			definitions []definition
+			remaining   []string
+		)
+		for _, name := range names {
+			if len(definitions) == maxMetrics {
+				remaining = append(remaining, name)
+				continue
+			}
+			values := ev.values[name]
+			n := len(values)
+			if n > maxValues {
+				n = maxValues
+				remaining = append(remaining, name)
+			}
+			if n == 1 {
+				doc[name] = values[0]
+			} else {
+				doc[name] = values[:n]
+			}
+			ev.values[name] = values[n:]
+			definitions = append(definitions, e.definition(name))
+		}
+		names = remaining
+
+		doc["_aws"] = metadata{
+			Timestamp: timestamp,
+			CloudWatchMetrics: []directive{{
+				Namespace:  e.namespace,
+				Dimensions: dimensions,
+				Metrics:    definitions,
+			}},
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+func (e *EMF) dimensions(lvs lv.LabelValues) [][]string {
+	labels := make([]string, 0, len(lvs)/2)
+	for i := 0; i < len(lvs); i += 2 {
+		labels = append(labels, lvs[i])
+	}
+	if e.dimensionSets == nil {
+		if len(labels) == 0 {
+			return [][]string{}
+		}
+		return [][]string{labels}
+	}
+
+	dimensions := [][]string{}
+	for _, set := range e.dimensionSets {
+		if containsAll(labels, set) {
+			dimensions = append(dimensions, set)
+		}
+	}
+	return dimensions
+}
+
+func (e *EMF) definition(name string) definition {
+	d := definition{Name: name, Unit: e.units[name], StorageResolution: e.resolution}
+	if seconds, ok := e.resolutions[name]; ok {
+		d.StorageResolution = seconds
+	}
+	return d
+}
+
+func containsAll(labels, set []string) bool {
+	for _, s := range set {
+		found := false
+		for _, l := range labels {
+			if l == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+type observeFunc func(name string, lvs lv.LabelValues, value float64)
+
+type exemplarFunc func(lvs lv.LabelValues, labels map[string]string)
+
+// Counter is an EMF counter. Observations are forwarded to an EMF object,
+// and aggregated (summed) per timeseries.
+type Counter struct {
+	name string
+	lvs  lv.LabelValues
+	obs  observeFunc
+	ex   exemplarFunc
+}
+
+// With implements metrics.Counter.
+func (c *Counter) With(labelValues ...string) metrics.Counter {
+	return &Counter{
+		name: c.name,
+		lvs:  c.lvs.With(labelValues...),
+		obs:  c.obs,
+		ex:   c.ex,
+	}
+}
+
+// Add implements metrics.Counter.
+func (c *Counter) Add(delta float64) {
+	c.obs(c.name, c.lvs, delta)
+}
+
+// AddWithExemplar implements metrics.CounterWithExemplar. The exemplar labels
+// are written as properties of the log event, rather than as dimensions.
+func (c *Counter) AddWithExemplar(delta float64, exemplar map[string]string) {
+	c.obs(c.name, c.lvs, delta)
+	c.ex(c.lvs, exemplar)
+}
+
+// Gauge is an EMF gauge. Observations are forwarded to an EMF object, and
+// the last observation per timeseries is written.
+type Gauge struct {
+	name string
+	lvs  lv.LabelValues
+	obs  observeFunc
+	add  observeFunc
+}
+
+// With implements metrics.Gauge.
+func (g *Gauge) With(labelValues ...string) metrics.Gauge {
+	return &Gauge{
+		name: g.name,
+		lvs:  g.lvs.With(labelValues...),
+		obs:  g.obs,
+		add:  g.add,
+	}
+}
+
+// Set implements metrics.Gauge.
+func (g *Gauge) Set(value float64) {
+	g.obs(g.name, g.lvs, value)
+}
+
+// Add implements metrics.Gauge.
+func (g *Gauge) Add(delta float64) {
+	g.add(g.name, g.lvs, delta)
+}
+
+// Histogram is an EMF histogram. Observations are forwarded to an EMF object,
+// and all observations per timeseries are written.
+type Histogram struct {
+	name string
+	lvs  lv.LabelValues
+	obs  observeFunc
+	ex   exemplarFunc
+}
+
+// With implements metrics.Histogram.
+func (h *Histogram) With(labelValues ...string) metrics.Histogram {
+	return &Histogram{
+		name: h.name,
+		lvs:  h.lvs.With(labelValues...),
+		obs:  h.obs,
+		ex:   h.ex,
+	}
+}
+
+// Observe implements metrics.Histogram.
+func (h *Histogram) Observe(value float64) {
+	h.obs(h.name, h.lvs, value)
+}
+
+// ObserveWithExemplar implements metrics.HistogramWithExemplar. The exemplar
+// labels are written as properties of the log event, rather than as
+// dimensions.
+func (h *Histogram) ObserveWithExemplar(value float64, exemplar map[string]string) {
+	h.obs(h.name, h.lvs, value)
+	h.ex(h.lvs, exemplar)
+}
@@ -0,0 +1,158 @@
+package cloudwatchemf_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/barrett370/kit/v2/metrics"
+	"github.com/barrett370/kit/v2/metrics/cloudwatchemf"
+	"github.com/go-kit/log"
+)
+
+func TestWriteTo(t *testing.T) {
+	e := cloudwatchemf.New("svc", log.NewNopLogger(), cloudwatchemf.WithUnit("Milliseconds", "latency"))
+	e.NewCounter("requests").With("method", "get").Add(2)
+	e.NewCounter("requests").With("method", "get").Add(3)
+	e.NewGauge("inflight").With("method", "get").Set(7)
+	e.NewHistogram("latency").With("method", "get").Observe(10)
+	e.NewHistogram("latency").With("method", "get").Observe(20)
+	e.NewCounter("restarts").Add(1)
+
+	docs := write(t, e)
+	if want, have := 2, len(docs); want != have {
+		t.Fatalf("want %d documents, have %d", want, have)
+	}
+
+	// Documents are ordered by label values, so the one without any is first.
+	if want, have := 1.0, docs[0]["restarts"]; want != have {
+		t.Errorf("restarts: want %v, have %v", want, have)
+	}
+	if want, have := []interface{}{}, directive(t, docs[0])["Dimensions"]; !reflect.DeepEqual(want, have) {
+		t.Errorf("restarts dimensions: want %v, have %v", want, have)
+	}
+
+	doc := docs[1]
+	for name, want := range map[string]interface{}{
+		"method":   "get",
+		"requests": 5.0,
+		"inflight": 7.0,
+		"latency":  []interface{}{10.0, 20.0},
+	} {
+		if have := doc[name]; !reflect.DeepEqual(want, have) {
+			t.Errorf("%s: want %v, have %v", name, want, have)
+		}
+	}
+	d := directive(t, doc)
+	if want, have := "svc", d["Namespace"]; want != have {
+		t.Errorf("namespace: want %v, have %v", want, have)
+	}
+	if want, have := []interface{}{[]interface{}{"method"}}, d["Dimensions"]; !reflect.DeepEqual(want, have) {
+		t.Errorf("dimensions: want %v, have %v", want, have)
+	}
+	want := []interface{}{
+		map[string]interface{}{"Name": "inflight"},
+		map[string]interface{}{"Name": "latency", "Unit": "Milliseconds"},
+		map[string]interface{}{"Name": "requests"},
+	}
+	if have := d["Metrics"]; !reflect.DeepEqual(want, have) {
+		t.Errorf("metrics: want %v, have %v", want, have)
+	}
+
+	// Observations are flushed by each write.
+	if have := write(t, e); len(have) != 0 {
+		t.Errorf("want no documents, have %v", have)
+	}
+}
+
+func TestDimensionSets(t *testing.T) {
+	e := cloudwatchemf.New("svc", log.NewNopLogger(),
+		cloudwatchemf.WithDimensionSets([]string{"service"}, []string{"service", "method"}, []string{"region"}),
+		cloudwatchemf.WithStorageResolution(1),
+	)
+	e.NewCounter("requests").With("service", "users", "method", "get", "pod", "a1").Add(1)
+
+	d := directive(t, write(t, e)[0])
+	want := []interface{}{[]interface{}{"service"}, []interface{}{"service", "method"}}
+	if have := d["Dimensions"]; !reflect.DeepEqual(want, have) {
+		t.Errorf("dimensions: want %v, have %v", want, have)
+	}
+	if want, have := 1.0, d["Metrics"].([]interface{})[0].(map[string]interface{})["StorageResolution"]; want != have {
+		t.Errorf("storage resolution: want %v, have %v", want, have)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	e := cloudwatchemf.New("svc", log.NewNopLogger())
+	h := e.NewHistogram("latency")
+	for i := 0; i < 150; i++ {
+		h.Observe(float64(i))
+	}
+	for i := 0; i < 120; i++ {
+		e.NewCounter(strings.Repeat("m", i+1)).Add(1)
+	}
+
+	var values, definitions int
+	for _, doc := range write(t, e) {
+		ms := directive(t, doc)["Metrics"].([]interface{})
+		if len(ms) > 100 {
+			t.Errorf("want at most 100 metrics per document, have %d", len(ms))
+		}
+		definitions += len(ms)
+		if v, ok := doc["latency"].([]interface{}); ok {
+			if len(v) > 100 {
+				t.Errorf("want at most 100 values per metric, have %d", len(v))
+			}
+			values += len(v)
+		}
+	}
+	if want, have := 150, values; want != have {
+		t.Errorf("latency values: want %d, have %d", want, have)
+	}
+	// The latency histogram is split across two documents.
+	if want, have := 2+120, definitions; want != have {
+		t.Errorf("metrics: want %d, have %d", want, have)
+	}
+}
+
+func TestExemplars(t *testing.T) {
+	e := cloudwatchemf.New("svc", log.NewNopLogger())
+	metrics.ObserveWithExemplar(e.NewHistogram("latency").With("method", "get"), 5, map[string]string{"trace_id": "abc"})
+
+	doc := write(t, e)[0]
+	if want, have := "abc", doc["trace_id"]; want != have {
+		t.Errorf("trace_id: want %v, have %v", want, have)
+	}
+	if want, have := []interface{}{[]interface{}{"method"}}, directive(t, doc)["Dimensions"]; !reflect.DeepEqual(want, have) {
+		t.Errorf("dimensions: want %v, have %v", want, have)
+	}
+}
+
+func write(t *testing.T, e *cloudwatchemf.EMF) []map[string]interface{} {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := e.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var docs []map[string]interface{}
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var doc map[string]interface{}
+		if err := dec.Decode(&doc); err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+func directive(t *testing.T, doc map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	aws, ok := doc["_aws"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("missing _aws metadata: %v", doc)
+	}
+	return aws["CloudWatchMetrics"].([]interface{})[0].(map[string]interface{})
+}
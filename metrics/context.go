@@ -0,0 +1,159 @@
+package metrics
+
+import (
+	"context"
+)
+
+// ContextCounter is a Counter whose observations take a context, from which
+// additional label values or an exemplar may be derived.
+type ContextCounter interface {
+	With(labelValues ...string) ContextCounter
+	Add(ctx context.Context, delta float64)
+}
+
+// ContextGauge is a Gauge whose observations take a context, from which
+// additional label values may be derived.
+type ContextGauge interface {
+	With(labelValues ...string) ContextGauge
+	Set(ctx context.Context, value float64)
+	Add(ctx context.Context, delta float64)
+}
+
+// ContextHistogram is a Histogram whose observations take a context, from
+// which additional label values or an exemplar may be derived.
+type ContextHistogram interface {
+	With(labelValues ...string) ContextHistogram
+	Observe(ctx context.Context, value float64)
+}
+
+// ContextOption sets an optional parameter for the context-aware adapters.
+type ContextOption func(*contextOptions)
+
+type contextOptions struct {
+	labels   []func(context.Context) []string
+	exemplar func(context.Context) map[string]string
+}
+
+// ContextLabels adds a func which derives label values from the context of
+// each observation, e.g. a tenant carried in request metadata. The returned
+// label values are applied via With before the observation is made. Multiple
+// funcs may be added; their label values are applied in order.
+//
+// Note that every distinct label value creates a new timeseries, so the
+// values should be drawn from a small set.
+func ContextLabels(f func(ctx context.Context) []string) ContextOption {
+	return func(o *contextOptions) { o.labels = append(o.labels, f) }
+}
+
+// ContextExemplar sets a func which derives an exemplar from the context of
+// each observation, typically the trace_id and span_id of the current span.
+// If the func returns a non-nil exemplar and the underlying counter or
+// histogram supports exemplars, the exemplar is attached to the observation.
+func ContextExemplar(f func(ctx context.Context) map[string]string) ContextOption {
+	return func(o *contextOptions) { o.exemplar = f }
+}
+
+func newContextOptions(options []ContextOption) *contextOptions {
+	o := &contextOptions{}
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
+
+func (o *contextOptions) labelValues(ctx context.Context) []string {
+	var lvs []string
+	for _, f := range o.labels {
+		lvs = append(lvs, f(ctx)...)
+	}
+	return lvs
+}
+
+func (o *contextOptions) exemplarFor(ctx context.Context) map[string]string {
+	if o.exemplar == nil {
+		return nil
+	}
+	return o.exemplar(ctx)
+}
+
+// NewContextCounter adapts the counter to a ContextCounter.
+func NewContextCounter(c Counter, options ...ContextOption) ContextCounter {
+	return contextCounter{c: c, o: newContextOptions(options)}
+}
+
+type contextCounter struct {
+	c Counter
+	o *contextOptions
+}
+
+func (c contextCounter) With(labelValues ...string) ContextCounter {
+	return contextCounter{c: c.c.With(labelValues...), o: c.o}
+}
+
+func (c contextCounter) Add(ctx context.Context, delta float64) {
+	counter := c.c
+	if lvs := c.o.labelValues(ctx); len(lvs) > 0 {
+		counter = counter.With(lvs...)
+	}
+	if exemplar := c.o.exemplarFor(ctx); exemplar != nil {
+		AddWithExemplar(counter, delta, exemplar)
+		return
+	}
+	counter.Add(delta)
+}
+
+// NewContextGauge adapts the gauge to a ContextGauge. Exemplars are ignored,
+// as gauges don't support them.
+func NewContextGauge(g Gauge, options ...ContextOption) ContextGauge {
+	return contextGauge{g: g, o: newContextOptions(options)}
+}
+
+type contextGauge struct {
+	g Gauge
+	o *contextOptions
+}
+
+func (g contextGauge) With(labelValues ...string) ContextGauge {
+	return contextGauge{g: g.g.With(labelValues...), o: g.o}
+}
+
+func (g contextGauge) Set(ctx context.Context, value float64) {
+	g.gauge(ctx).Set(value)
+}
+
+func (g contextGauge) Add(ctx context.Context, delta float64) {
+	g.gauge(ctx).Add(delta)
+}
+
+func (g contextGauge) gauge(ctx context.Context) Gauge {
+	if lvs := g.o.labelValues(ctx); len(lvs) > 0 {
+		return g.g.With(lvs...)
+	}
+	return g.g
+}
+
+// NewContextHistogram adapts the histogram to a ContextHistogram.
+func NewContextHistogram(h Histogram, options ...ContextOption) ContextHistogram {
+	return contextHistogram{h: h, o: newContextOptions(options)}
+}
+
+type contextHistogram struct {
+	h Histogram
+	o *contextOptions
+}
+
+func (h contextHistogram) With(labelValues ...string) ContextHistogram {
+	return contextHistogram{h: h.h.With(labelValues...), o: h.o}
+}
+
+func (h contextHistogram) Observe(ctx context.Context, value float64) {
+	histogram := h.h
+	if lvs := h.o.labelValues(ctx); len(lvs) > 0 {
+		histogram = histogram.With(lvs...)
+	}
+	if exemplar := h.o.exemplarFor(ctx); exemplar != nil {
+		ObserveWithExemplar(histogram, value, exemplar)
+		return
+	}
+	histogram.Observe(value)
+}
@@ -0,0 +1,130 @@
+package metrics_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/barrett370/kit/v2/metrics"
+)
+
+type ctxKey struct{}
+
+func tenant(ctx context.Context) []string {
+	v, _ := ctx.Value(ctxKey{}).(string)
+	return []string{"tenant", v}
+}
+
+func traceExemplar(ctx context.Context) map[string]string {
+	if ctx.Value(ctxKey{}) == nil {
+		return nil
+	}
+	return map[string]string{"trace_id": "abc"}
+}
+
+func TestContextCounter(t *testing.T) {
+	var obs []observation
+	c := metrics.NewContextCounter(
+		recordingCounter{recorder{obs: &obs}},
+		metrics.ContextLabels(tenant),
+		metrics.ContextExemplar(traceExemplar),
+	).With("method", "get")
+
+	c.Add(context.WithValue(context.Background(), ctxKey{}, "acme"), 2)
+	c.Add(context.Background(), 3)
+
+	want := []observation{
+		{lvs: []string{"method", "get", "tenant", "acme"}, value: 2, exemplar: map[string]string{"trace_id": "abc"}},
+		{lvs: []string{"method", "get", "tenant", ""}, value: 3},
+	}
+	if have := obs; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestContextGauge(t *testing.T) {
+	var obs []observation
+	g := metrics.NewContextGauge(recordingGauge{recorder{obs: &obs}}, metrics.ContextLabels(tenant))
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "acme")
+	g.Set(ctx, 5)
+	g.Add(ctx, -1)
+
+	want := []observation{
+		{lvs: []string{"tenant", "acme"}, value: 5},
+		{lvs: []string{"tenant", "acme"}, value: -1},
+	}
+	if have := obs; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestContextHistogram(t *testing.T) {
+	var obs []observation
+	h := metrics.NewContextHistogram(recordingHistogram{recorder{obs: &obs}}, metrics.ContextExemplar(traceExemplar))
+
+	h.Observe(context.WithValue(context.Background(), ctxKey{}, "acme"), 0.5)
+	h.With("code", "200").Observe(context.Background(), 1.5)
+
+	want := []observation{
+		{value: 0.5, exemplar: map[string]string{"trace_id": "abc"}},
+		{lvs: []string{"code", "200"}, value: 1.5},
+	}
+	if have := obs; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+type observation struct {
+	lvs      []string
+	value    float64
+	exemplar map[string]string
+}
+
+// recorder records every observation along with its label values.
+type recorder struct {
+	lvs []string
+	obs *[]observation
+}
+
+func (r recorder) with(labelValues ...string) recorder {
+	return recorder{lvs: append(append([]string{}, r.lvs...), labelValues...), obs: r.obs}
+}
+
+func (r recorder) record(value float64, exemplar map[string]string) {
+	*r.obs = append(*r.obs, observation{lvs: r.lvs, value: value, exemplar: exemplar})
+}
+
+type recordingCounter struct{ recorder }
+
+func (c recordingCounter) With(labelValues ...string) metrics.Counter {
+	return recordingCounter{c.with(labelValues...)}
+}
+
+func (c recordingCounter) Add(delta float64) { c.record(delta, nil) }
+
+func (c recordingCounter) AddWithExemplar(delta float64, exemplar map[string]string) {
+	c.record(delta, exemplar)
+}
+
+type recordingGauge struct{ recorder }
+
+func (g recordingGauge) With(labelValues ...string) metrics.Gauge {
+	return recordingGauge{g.with(labelValues...)}
+}
+
+func (g recordingGauge) Set(value float64) { g.record(value, nil) }
+
+func (g recordingGauge) Add(delta float64) { g.record(delta, nil) }
+
+type recordingHistogram struct{ recorder }
+
+func (h recordingHistogram) With(labelValues ...string) metrics.Histogram {
+	return recordingHistogram{h.with(labelValues...)}
+}
+
+func (h recordingHistogram) Observe(value float64) { h.record(value, nil) }
+
+func (h recordingHistogram) ObserveWithExemplar(value float64, exemplar map[string]string) {
+	h.record(value, exemplar)
+}
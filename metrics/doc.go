@@ -88,10 +88,13 @@
 //    dogstatsd   n    batch, push-aggregate  batch, push-aggregate  native, batch, push-each
 //    statsd      1    batch, push-aggregate  batch, push-aggregate  native, batch, push-each
 //    graphite    1    batch, push-aggregate  batch, push-aggregate  synthetic, batch, push-aggregate
-//    expvar      1    atomic                 atomic                 synthetic, batch, in-place expose
+//    expvar      n    atomic                 atomic                 synthetic, batch, in-place expose
 //    influx      n    custom                 custom                 custom
 //    prometheus  n    native                 native                 native
 //    pcp         1    native                 native                 native
 //    cloudwatch  n    batch push-aggregate   batch push-aggregate   synthetic, batch, push-aggregate
+//    otel        n    native                 native                 native
+//    emf         n    batch, push-aggregate  batch, push-aggregate  native, batch, push-each
+//    remotewrite n    batch, push-cumulative batch, push-cumulative synthetic, batch, push-cumulative
 //
 package metrics
@@ -8,6 +8,9 @@
 // remote server. This is useful even if you connect to your DogStatsD server
 // over UDP. Emitting one network packet per observation can quickly overwhelm
 // even the fastest internal network.
+//
+// To send to a DogStatsD server listening on a Unix domain socket, pass the
+// "unixgram" network and the path of the socket to the SendLoop helper method.
 package dogstatsd
 
 import (
@@ -15,6 +18,7 @@ import (
 	"fmt"
 	"io"
 	"math/rand"
+	"os"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -22,6 +26,9 @@ import (
 
 	"github.com/barrett370/kit/v2/metrics"
 	"github.com/barrett370/kit/v2/metrics/generic"
+	"github.com/barrett370/kit/v2/metrics/internal/buffer"
+	"github.com/barrett370/kit/v2/metrics/internal/gaugefunc"
+	"github.com/barrett370/kit/v2/metrics/internal/loop"
 	"github.com/barrett370/kit/v2/metrics/internal/lv"
 	"github.com/barrett370/kit/v2/metrics/internal/ratemap"
 	"github.com/barrett370/kit/v2/util/conn"
@@ -39,15 +46,21 @@ import (
 // To regularly report metrics to an io.Writer, use the WriteLoop helper method.
 // To send to a DogStatsD server, use the SendLoop helper method.
 type Dogstatsd struct {
-	mtx        sync.RWMutex
-	prefix     string
-	rates      *ratemap.RateMap
-	counters   *lv.Space
-	gauges     map[string]*gaugeNode
-	timings    *lv.Space
-	histograms *lv.Space
-	logger     log.Logger
-	lvs        lv.LabelValues
+	mtx           sync.RWMutex
+	prefix        string
+	rates         *ratemap.RateMap
+	counters      *lv.Space
+	gauges        map[string]*gaugeNode
+	timings       *lv.Space
+	histograms    *lv.Space
+	distributions *lv.Space
+	logger        log.Logger
+	lvs           lv.LabelValues
+	containerID   string
+	maxPacketSize int
+	gaugeFuncs    gaugefunc.Set
+	buffer        *buffer.Buffer
+	jitter        time.Duration
 }
 
 // New returns a Dogstatsd object that may be used to create metrics. Prefix is
@@ -58,15 +71,90 @@ func New(prefix string, logger log.Logger, lvs ...string) *Dogstatsd {
 		panic("odd number of LabelValues; programmer error!")
 	}
 	return &Dogstatsd{
-		prefix:     prefix,
-		rates:      ratemap.New(),
-		counters:   lv.NewSpace(),
-		gauges:     map[string]*gaugeNode{},
-		timings:    lv.NewSpace(),
-		histograms: lv.NewSpace(),
-		logger:     logger,
-		lvs:        lvs,
+		prefix:        prefix,
+		rates:         ratemap.New(),
+		counters:      lv.NewSpace(),
+		gauges:        map[string]*gaugeNode{},
+		timings:       lv.NewSpace(),
+		histograms:    lv.NewSpace(),
+		distributions: lv.NewSpace(),
+		logger:        logger,
+		lvs:           lvs,
+	}
+}
+
+// Option sets an optional parameter for Dogstatsd objects constructed via
+// NewWithOptions.
+type Option func(*Dogstatsd)
+
+// WithLabelValues sets label values which are applied to all metrics, as the
+// variadic parameter of New does.
+func WithLabelValues(lvs ...string) Option {
+	if len(lvs)%2 != 0 {
+		panic("odd number of LabelValues; programmer error!")
+	}
+	return func(d *Dogstatsd) { d.lvs = append(d.lvs, lvs...) }
+}
+
+// WithContainerID sets the ID of the container emitting the metrics, which
+// the Datadog agent uses to tag them with the container's tags, even when it
+// can't detect their origin on its own, e.g. when sending over UDP.
+func WithContainerID(id string) Option {
+	return func(d *Dogstatsd) { d.containerID = id }
+}
+
+// WithMaxPacketSize batches the lines written by WriteTo into writes of at
+// most n bytes each, so that each packet sent over a datagram connection
+// carries as many lines as fit, without exceeding the MTU. See
+// conn.MaxUDPPacketSize and conn.MaxUDSPacketSize. By default, each line is
+// written separately.
+func WithMaxPacketSize(n int) Option {
+	return func(d *Dogstatsd) { d.maxPacketSize = n }
+}
+
+// WithBuffer queues observations of counters, timings, histograms and
+// distributions in a buffer of the given size, from which they're applied by a
+// separate goroutine, so that observing never blocks on a concurrent write. If
+// the buffer is full, observations are dropped, and counted; see Dropped. The
+// goroutine stops when WriteLoop or SendLoop returns, after the final write.
+// By default, observations are applied directly.
+func WithBuffer(size int) Option {
+	return func(d *Dogstatsd) { d.buffer = buffer.New(size) }
+}
+
+// WithJitter delays each write made by the loop helper methods by a random
+// offset in [0, jitter), chosen once when the loop starts, so that processes
+// started together don't all flush at the same instant. By default, there's
+// no delay.
+func WithJitter(jitter time.Duration) Option {
+	return func(d *Dogstatsd) { d.jitter = jitter }
+}
+
+// WithOriginDetection enables the detection of the origin of the metrics,
+// which the Datadog agent uses to tag them with the tags of the emitting
+// container or pod. The container ID is read from /proc/self/cgroup, and the
+// entity ID from the DD_ENTITY_ID environment variable, which is typically
+// set to the pod UID via the Kubernetes downward API. Either is omitted if it
+// can't be detected.
+func WithOriginDetection() Option {
+	return func(d *Dogstatsd) {
+		if id := os.Getenv("DD_ENTITY_ID"); id != "" {
+			d.lvs = append(d.lvs, "dd.internal.entity_id", id)
+		}
+		if d.containerID == "" {
+			d.containerID = detectContainerID()
+		}
+	}
+}
+
+// NewWithOptions is like New, but takes options rather than label values,
+// which may be set via WithLabelValues.
+func NewWithOptions(prefix string, logger log.Logger, options ...Option) *Dogstatsd {
+	d := New(prefix, logger)
+	for _, option := range options {
+		option(d)
 	}
+	return d
 }
 
 // NewCounter returns a counter, sending observations to this Dogstatsd object.
@@ -74,7 +162,7 @@ func (d *Dogstatsd) NewCounter(name string, sampleRate float64) *Counter {
 	d.rates.Set(name, sampleRate)
 	return &Counter{
 		name: name,
-		obs:  sampleObservations(d.counters.Observe, sampleRate),
+		obs:  sampleObservations(d.buffered(d.counters.Observe), sampleRate),
 	}
 }
 
@@ -90,13 +178,20 @@ func (d *Dogstatsd) NewGauge(name string) *Gauge {
 	return n.gauge
 }
 
+// NewGaugeFunc adds a gauge whose value is sampled from f immediately before
+// each write, for values such as queue depth which aren't naturally
+// event-driven. The label values are applied to the gauge via With.
+func (d *Dogstatsd) NewGaugeFunc(name string, f func() float64, labelValues ...string) {
+	d.gaugeFuncs.Add(d.NewGauge(name).With(labelValues...), f)
+}
+
 // NewTiming returns a histogram whose observations are interpreted as
 // millisecond durations, and are forwarded to this Dogstatsd object.
 func (d *Dogstatsd) NewTiming(name string, sampleRate float64) *Timing {
 	d.rates.Set(name, sampleRate)
 	return &Timing{
 		name: name,
-		obs:  sampleObservations(d.timings.Observe, sampleRate),
+		obs:  sampleObservations(d.buffered(d.timings.Observe), sampleRate),
 	}
 }
 
@@ -106,34 +201,72 @@ func (d *Dogstatsd) NewHistogram(name string, sampleRate float64) *Histogram {
 	d.rates.Set(name, sampleRate)
 	return &Histogram{
 		name: name,
-		obs:  sampleObservations(d.histograms.Observe, sampleRate),
+		obs:  sampleObservations(d.buffered(d.histograms.Observe), sampleRate),
 	}
 }
 
+// NewDistribution returns a histogram whose observations are forwarded to
+// this Dogstatsd object, and aggregated by the Datadog servers rather than the
+// agent, so that percentiles are computed over all hosts. Unlike histograms
+// and timings, distributions are global, and their percentiles accurate.
+func (d *Dogstatsd) NewDistribution(name string, sampleRate float64) *Distribution {
+	d.rates.Set(name, sampleRate)
+	return &Distribution{
+		name: name,
+		obs:  sampleObservations(d.buffered(d.distributions.Observe), sampleRate),
+	}
+}
+
+// Dropped returns the number of observations dropped because the buffer set
+// by WithBuffer was full. Without a buffer, it's always zero.
+func (d *Dogstatsd) Dropped() uint64 {
+	if d.buffer == nil {
+		return 0
+	}
+	return d.buffer.Dropped()
+}
+
+func (d *Dogstatsd) buffered(obs observeFunc) observeFunc {
+	if d.buffer == nil {
+		return obs
+	}
+	return observeFunc(d.buffer.Wrap(buffer.ObserveFunc(obs)))
+}
+
 // WriteLoop is a helper method that invokes WriteTo to the passed writer every
 // time the passed channel fires. This method blocks until ctx is canceled,
 // so clients probably want to run it in its own goroutine. For typical
 // usage, create a time.Ticker and pass its C channel to this method.
+// When ctx is canceled, a final write is made, so that the observations
+// made since the last tick aren't lost.
 func (d *Dogstatsd) WriteLoop(ctx context.Context, c <-chan time.Time, w io.Writer) {
-	for {
-		select {
-		case <-c:
-			if _, err := d.WriteTo(w); err != nil {
-				d.logger.Log("during", "WriteTo", "err", err)
-			}
-		case <-ctx.Done():
-			return
-		}
-	}
+	d.writeLoop(ctx, c, w, d.maxPacketSize)
 }
 
 // SendLoop is a helper method that wraps WriteLoop, passing a managed
 // connection to the network and address. Like WriteLoop, this method blocks
 // until ctx is canceled, so clients probably want to start it in its own
 // goroutine. For typical usage, create a time.Ticker and pass its C channel to
-// this method.
+// this method. Unless WithMaxPacketSize is given, lines sent over a datagram
+// network are batched into packets of the conventional maximum size for the
+// network; see conn.MaxPacketSize.
 func (d *Dogstatsd) SendLoop(ctx context.Context, c <-chan time.Time, network, address string) {
-	d.WriteLoop(ctx, c, conn.NewDefaultManager(network, address, d.logger))
+	size := d.maxPacketSize
+	if size == 0 {
+		size = conn.MaxPacketSize(network)
+	}
+	d.writeLoop(ctx, c, conn.NewDefaultManager(network, address, d.logger), size)
+}
+
+func (d *Dogstatsd) writeLoop(ctx context.Context, c <-chan time.Time, w io.Writer, maxPacketSize int) {
+	loop.Run(ctx, c, d.jitter, func(context.Context) {
+		if _, err := d.writeTo(w, maxPacketSize); err != nil {
+			d.logger.Log("during", "WriteTo", "err", err)
+		}
+	})
+	if d.buffer != nil {
+		d.buffer.Close()
+	}
 }
 
 // WriteTo flushes the buffered content of the metrics to the writer, in
@@ -141,10 +274,33 @@ func (d *Dogstatsd) SendLoop(ctx context.Context, c <-chan time.Time, network, a
 // lost if there is a problem with the write. Clients should be sure to call
 // WriteTo regularly, ideally through the WriteLoop or SendLoop helper methods.
 func (d *Dogstatsd) WriteTo(w io.Writer) (count int64, err error) {
+	return d.writeTo(w, d.maxPacketSize)
+}
+
+func (d *Dogstatsd) writeTo(w io.Writer, maxPacketSize int) (count int64, err error) {
+	d.gaugeFuncs.Sample()
+	if d.buffer != nil {
+		d.buffer.Flush()
+	}
+
+	// Swap out the observations of every space at once, before any time is
+	// spent writing, so they're all attributed to this write.
+	counters, timings, histograms, distributions := d.counters.Reset(), d.timings.Reset(), d.histograms.Reset(), d.distributions.Reset()
+
 	var n int
 
-	d.counters.Reset().Walk(func(name string, lvs lv.LabelValues, values []float64) bool {
-		n, err = fmt.Fprintf(w, "%s%s:%f|c%s%s\n", d.prefix, name, sum(values), sampling(d.rates.Get(name)), d.tagValues(lvs))
+	if maxPacketSize > 0 {
+		pw := conn.NewPacketWriter(w, maxPacketSize)
+		defer func() {
+			if ferr := pw.Flush(); err == nil {
+				err = ferr
+			}
+		}()
+		w = pw
+	}
+
+	counters.Walk(func(name string, lvs lv.LabelValues, values []float64) bool {
+		n, err = fmt.Fprintf(w, "%s%s:%f|c%s%s\n", d.prefix, name, sum(values), sampling(d.rates.Get(name)), d.fields(lvs))
 		if err != nil {
 			return false
 		}
@@ -158,7 +314,7 @@ func (d *Dogstatsd) WriteTo(w io.Writer) (count int64, err error) {
 	d.mtx.RLock()
 	for _, root := range d.gauges {
 		root.walk(func(name string, lvs lv.LabelValues, value float64) bool {
-			n, err = fmt.Fprintf(w, "%s%s:%f|g%s\n", d.prefix, name, value, d.tagValues(lvs))
+			n, err = fmt.Fprintf(w, "%s%s:%f|g%s\n", d.prefix, name, value, d.fields(lvs))
 			if err != nil {
 				return false
 			}
@@ -168,10 +324,10 @@ func (d *Dogstatsd) WriteTo(w io.Writer) (count int64, err error) {
 	}
 	d.mtx.RUnlock()
 
-	d.timings.Reset().Walk(func(name string, lvs lv.LabelValues, values []float64) bool {
+	timings.Walk(func(name string, lvs lv.LabelValues, values []float64) bool {
 		sampleRate := d.rates.Get(name)
 		for _, value := range values {
-			n, err = fmt.Fprintf(w, "%s%s:%f|ms%s%s\n", d.prefix, name, value, sampling(sampleRate), d.tagValues(lvs))
+			n, err = fmt.Fprintf(w, "%s%s:%f|ms%s%s\n", d.prefix, name, value, sampling(sampleRate), d.fields(lvs))
 			if err != nil {
 				return false
 			}
@@ -183,10 +339,25 @@ func (d *Dogstatsd) WriteTo(w io.Writer) (count int64, err error) {
 		return count, err
 	}
 
-	d.histograms.Reset().Walk(func(name string, lvs lv.LabelValues, values []float64) bool {
+	histograms.Walk(func(name string, lvs lv.LabelValues, values []float64) bool {
 		sampleRate := d.rates.Get(name)
 		for _, value := range values {
-			n, err = fmt.Fprintf(w, "%s%s:%f|h%s%s\n", d.prefix, name, value, sampling(sampleRate), d.tagValues(lvs))
+			n, err = fmt.Fprintf(w, "%s%s:%f|h%s%s\n", d.prefix, name, value, sampling(sampleRate), d.fields(lvs))
+			if err != nil {
+				return false
+			}
+			count += int64(n)
+		}
+		return true
+	})
+	if err != nil {
+		return count, err
+	}
+
+	distributions.Walk(func(name string, lvs lv.LabelValues, values []float64) bool {
+		sampleRate := d.rates.Get(name)
+		for _, value := range values {
+			n, err = fmt.Fprintf(w, "%s%s:%f|d%s%s\n", d.prefix, name, value, sampling(sampleRate), d.fields(lvs))
 			if err != nil {
 				return false
 			}
@@ -217,6 +388,14 @@ func sampling(r float64) string {
 	return sv
 }
 
+// fields returns the tags and, if known, the container ID field of a line.
+func (d *Dogstatsd) fields(labelValues []string) string {
+	if d.containerID == "" {
+		return d.tagValues(labelValues)
+	}
+	return d.tagValues(labelValues) + "|c:" + d.containerID
+}
+
 func (d *Dogstatsd) tagValues(labelValues []string) string {
 	if len(labelValues) == 0 && len(d.lvs) == 0 {
 		return ""
@@ -346,6 +525,29 @@ func (h *Histogram) Observe(value float64) {
 	h.obs(h.name, h.lvs, value)
 }
 
+// Distribution is a DogStatsD distribution, or metrics.Histogram.
+// Observations are forwarded to a Dogstatsd object, and collected (but not
+// aggregated) per timeseries.
+type Distribution struct {
+	name string
+	lvs  lv.LabelValues
+	obs  observeFunc
+}
+
+// With implements metrics.Histogram.
+func (d *Distribution) With(labelValues ...string) metrics.Histogram {
+	return &Distribution{
+		name: d.name,
+		lvs:  d.lvs.With(labelValues...),
+		obs:  d.obs,
+	}
+}
+
+// Observe implements metrics.Histogram.
+func (d *Distribution) Observe(value float64) {
+	d.obs(d.name, d.lvs, value)
+}
+
 type pair struct{ label, value string }
 
 type gaugeNode struct {
@@ -1,6 +1,9 @@
 package dogstatsd
 
 import (
+	"bytes"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/barrett370/kit/v2/metrics/teststat"
@@ -88,3 +91,109 @@ func TestTimingSampled(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestDistribution(t *testing.T) {
+	prefix, name := "dogstatsd.", "distribution_test"
+	label, value := "abc", "def"
+	regex := `^` + prefix + name + `:([0-9\.]+)\|d\|#` + label + `:` + value + `$`
+	d := New(prefix, log.NewNopLogger())
+	histogram := d.NewDistribution(name, 1.0).With(label, value)
+	quantiles := teststat.Quantiles(d, regex, 50) // no |@0.X
+	if err := teststat.TestHistogram(histogram, quantiles, 0.01); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestContainerID(t *testing.T) {
+	d := NewWithOptions("abc.", log.NewNopLogger(), WithLabelValues("env", "prod"), WithContainerID("3726184226f5"))
+	d.NewCounter("def", 1.0).With("ghi", "jkl").Add(1)
+
+	var buf bytes.Buffer
+	if _, err := d.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "abc.def:1.000000|c|#env:prod,ghi:jkl|c:3726184226f5\n", buf.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestParseContainerID(t *testing.T) {
+	for _, tc := range []struct {
+		name, cgroup, want string
+	}{
+		{
+			"docker",
+			"12:memory:/docker/3726184226f5d3147c25fdeab5b60097e378e8a720503a5e19ecfdf29f869860\n",
+			"3726184226f5d3147c25fdeab5b60097e378e8a720503a5e19ecfdf29f869860",
+		},
+		{
+			"kubernetes systemd",
+			"1:name=systemd:/kubepods.slice/kubepods-pod2d3da189.slice/cri-containerd-3726184226f5d3147c25fdeab5b60097e378e8a720503a5e19ecfdf29f869860.scope\n",
+			"3726184226f5d3147c25fdeab5b60097e378e8a720503a5e19ecfdf29f869860",
+		},
+		{
+			"fargate",
+			"11:hugetlb:/ecs/55091c13-b8cf-4801-b527-f4601742204d/432624d2150b349fe35ba397284dea788c2bf66b885d14dfc1569b01890ca7da\n",
+			"432624d2150b349fe35ba397284dea788c2bf66b885d14dfc1569b01890ca7da",
+		},
+		{
+			"fargate 1.4",
+			"1:name=systemd:/ecs/34dc0b5e626f2c5c4c5170e34b10e765-1234567890\n",
+			"34dc0b5e626f2c5c4c5170e34b10e765-1234567890",
+		},
+		{
+			"host",
+			"0::/user.slice/user-1000.slice/session-2.scope\n",
+			"",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if want, have := tc.want, parseContainerID(strings.NewReader(tc.cgroup)); want != have {
+				t.Errorf("want %q, have %q", want, have)
+			}
+		})
+	}
+}
+
+type packetRecorder struct{ packets []string }
+
+func (r *packetRecorder) Write(p []byte) (int, error) {
+	r.packets = append(r.packets, string(p))
+	return len(p), nil
+}
+
+func TestMaxPacketSize(t *testing.T) {
+	s := NewWithOptions("abc.", log.NewNopLogger(), WithMaxPacketSize(30))
+	for i := 0; i < 10; i++ {
+		s.NewCounter("c"+strconv.Itoa(i), 1.0).Add(1)
+	}
+	var r packetRecorder
+	if _, err := s.WriteTo(&r); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.packets) < 2 {
+		t.Fatalf("want lines split across packets, have %q", r.packets)
+	}
+	var lines int
+	for _, p := range r.packets {
+		if len(p) > 30 {
+			t.Errorf("packet of %d bytes exceeds the maximum: %q", len(p), p)
+		}
+		lines += strings.Count(p, "\n")
+	}
+	if want, have := 10, lines; want != have {
+		t.Errorf("want %d lines, have %d", want, have)
+	}
+}
+
+func TestGaugeFunc(t *testing.T) {
+	d := New("abc.", log.NewNopLogger())
+	d.NewGaugeFunc("depth", func() float64 { return 3 }, "queue", "jobs")
+	var buf bytes.Buffer
+	if _, err := d.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "abc.depth:3.000000|g|#queue:jobs\n", buf.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
@@ -0,0 +1,42 @@
+package dogstatsd
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// containerIDRegexp matches the container ID at the end of a cgroup path, in
+// the formats used by Docker and containerd (64 hex digits), by the CRI with
+// systemd (UUIDs), and by ECS on Fargate (32 hex digits and a task suffix).
+var containerIDRegexp = regexp.MustCompile(`([0-9a-f]{64}|[0-9a-f]{8}(?:-[0-9a-f]{4}){3}-[0-9a-f]{12}|[0-9a-f]{32}-[0-9]+)(?:\.scope)?$`)
+
+// detectContainerID returns the ID of the container in which the process
+// runs, or the empty string.
+func detectContainerID() string {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	return parseContainerID(f)
+}
+
+// parseContainerID reads lines in the format of /proc/self/cgroup, i.e.
+// hierarchy-ID:controllers:path, and returns the first container ID found at
+// the end of a path, or the empty string.
+func parseContainerID(r io.Reader) string {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		fields := strings.SplitN(s.Text(), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if m := containerIDRegexp.FindStringSubmatch(fields[2]); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
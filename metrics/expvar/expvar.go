@@ -1,51 +1,107 @@
 // Package expvar provides expvar backends for metrics.
-// Label values are not supported.
+//
+// Each unique set of label values is published as a separate variable, with
+// the labels appended to the name, sorted by label, e.g.
+// requests{code=200,method=GET}. Metrics without label values are published
+// under their plain name.
 package expvar
 
 import (
 	"expvar"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/barrett370/kit/v2/metrics"
 	"github.com/barrett370/kit/v2/metrics/generic"
+	"github.com/barrett370/kit/v2/metrics/internal/lv"
 )
 
-// Counter implements the counter metric with an expvar float.
-// Label values are not supported.
+// OverflowLabels are the labels of the variable into which observations are
+// aggregated once a metric has reached the maximum number of series set via
+// WithMaxSeries.
+const OverflowLabels = "{overflow=true}"
+
+// Option sets an optional parameter for expvar metrics.
+type Option func(*options)
+
+type options struct {
+	maxSeries int
+}
+
+// WithMaxSeries caps the number of sets of label values published for a
+// metric at n. Once it's reached, observations with new label values are
+// aggregated into a single additional series, with the labels
+// OverflowLabels, so that unbounded label values, e.g. user IDs, can't
+// exhaust memory. By default, the number of series is unbounded.
+func WithMaxSeries(n int) Option {
+	return func(o *options) { o.maxSeries = n }
+}
+
+// Counter implements the counter metric with an expvar float per set of label
+// values.
 type Counter struct {
-	f *expvar.Float
+	f   *expvar.Float
+	lvs lv.LabelValues
+	fam *family[*expvar.Float]
 }
 
 // NewCounter creates an expvar Float with the given name, and returns an object
 // that implements the Counter interface.
-func NewCounter(name string) *Counter {
+func NewCounter(name string, options ...Option) *Counter {
+	fam := newFamily(func(labels string) *expvar.Float {
+		return expvar.NewFloat(name + labels)
+	}, options)
 	return &Counter{
-		f: expvar.NewFloat(name),
+		f:   fam.get(nil),
+		fam: fam,
 	}
 }
 
-// With is a no-op.
-func (c *Counter) With(labelValues ...string) metrics.Counter { return c }
+// With implements Counter. It publishes a new variable for each unique set of
+// label values.
+func (c *Counter) With(labelValues ...string) metrics.Counter {
+	lvs := c.lvs.With(labelValues...)
+	return &Counter{
+		f:   c.fam.get(lvs),
+		lvs: lvs,
+		fam: c.fam,
+	}
+}
 
 // Add implements Counter.
 func (c *Counter) Add(delta float64) { c.f.Add(delta) }
 
-// Gauge implements the gauge metric with an expvar float.
-// Label values are not supported.
+// Gauge implements the gauge metric with an expvar float per set of label
+// values.
 type Gauge struct {
-	f *expvar.Float
+	f   *expvar.Float
+	lvs lv.LabelValues
+	fam *family[*expvar.Float]
 }
 
 // NewGauge creates an expvar Float with the given name, and returns an object
 // that implements the Gauge interface.
-func NewGauge(name string) *Gauge {
+func NewGauge(name string, options ...Option) *Gauge {
+	fam := newFamily(func(labels string) *expvar.Float {
+		return expvar.NewFloat(name + labels)
+	}, options)
 	return &Gauge{
-		f: expvar.NewFloat(name),
+		f:   fam.get(nil),
+		fam: fam,
 	}
 }
 
-// With is a no-op.
-func (g *Gauge) With(labelValues ...string) metrics.Gauge { return g }
+// With implements Gauge. It publishes a new variable for each unique set of
+// label values.
+func (g *Gauge) With(labelValues ...string) metrics.Gauge {
+	lvs := g.lvs.With(labelValues...)
+	return &Gauge{
+		f:   g.fam.get(lvs),
+		lvs: lvs,
+		fam: g.fam,
+	}
+}
 
 // Set implements Gauge.
 func (g *Gauge) Set(value float64) { g.f.Set(value) }
@@ -53,11 +109,24 @@ func (g *Gauge) Set(value float64) { g.f.Set(value) }
 // Add implements metrics.Gauge.
 func (g *Gauge) Add(delta float64) { g.f.Add(delta) }
 
+// NewGaugeFunc publishes an expvar Func with the given name, whose value is
+// sampled from f whenever the expvars are read. It's useful for values such
+// as queue depth which aren't naturally event-driven.
+func NewGaugeFunc(name string, f func() float64) {
+	expvar.Publish(name, expvar.Func(func() interface{} { return f() }))
+}
+
 // Histogram implements the histogram metric with a combination of the generic
 // Histogram object and several expvar Floats, one for each of the 50th, 90th,
 // 95th, and 99th quantiles of observed values, with the quantile attached to
-// the name as a suffix. Label values are not supported.
+// the name as a suffix, before any labels, e.g. latency.p99{method=GET}.
 type Histogram struct {
+	*histogramSeries
+	lvs lv.LabelValues
+	fam *family[*histogramSeries]
+}
+
+type histogramSeries struct {
 	mtx sync.Mutex
 	h   *generic.Histogram
 	p50 *expvar.Float
@@ -69,18 +138,32 @@ type Histogram struct {
 // NewHistogram returns a Histogram object with the given name and number of
 // buckets in the underlying histogram object. 50 is a good default number of
 // buckets.
-func NewHistogram(name string, buckets int) *Histogram {
+func NewHistogram(name string, buckets int, options ...Option) *Histogram {
+	fam := newFamily(func(labels string) *histogramSeries {
+		return &histogramSeries{
+			h:   generic.NewHistogram(name, buckets),
+			p50: expvar.NewFloat(name + ".p50" + labels),
+			p90: expvar.NewFloat(name + ".p90" + labels),
+			p95: expvar.NewFloat(name + ".p95" + labels),
+			p99: expvar.NewFloat(name + ".p99" + labels),
+		}
+	}, options)
 	return &Histogram{
-		h:   generic.NewHistogram(name, buckets),
-		p50: expvar.NewFloat(name + ".p50"),
-		p90: expvar.NewFloat(name + ".p90"),
-		p95: expvar.NewFloat(name + ".p95"),
-		p99: expvar.NewFloat(name + ".p99"),
+		histogramSeries: fam.get(nil),
+		fam:             fam,
 	}
 }
 
-// With is a no-op.
-func (h *Histogram) With(labelValues ...string) metrics.Histogram { return h }
+// With implements Histogram. It publishes new variables for each unique set
+// of label values.
+func (h *Histogram) With(labelValues ...string) metrics.Histogram {
+	lvs := h.lvs.With(labelValues...)
+	return &Histogram{
+		histogramSeries: h.fam.get(lvs),
+		lvs:             lvs,
+		fam:             h.fam,
+	}
+}
 
 // Observe implements Histogram.
 func (h *Histogram) Observe(value float64) {
@@ -92,3 +175,74 @@ func (h *Histogram) Observe(value float64) {
 	h.p95.Set(h.h.Quantile(0.95))
 	h.p99.Set(h.h.Quantile(0.99))
 }
+
+// family is the set of series of a metric, one per unique set of label
+// values, created on first use.
+type family[T any] struct {
+	mtx       sync.Mutex
+	create    func(labels string) T
+	series    map[string]T // by labels
+	maxSeries int
+}
+
+func newFamily[T any](create func(labels string) T, opts []Option) *family[T] {
+	var o options
+	for _, option := range opts {
+		option(&o)
+	}
+	return &family[T]{
+		create:    create,
+		series:    map[string]T{},
+		maxSeries: o.maxSeries,
+	}
+}
+
+func (f *family[T]) get(lvs lv.LabelValues) T {
+	labels := formatLabels(lvs)
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if s, ok := f.series[labels]; ok {
+		return s
+	}
+	if labels != "" && f.maxSeries > 0 && f.labeled() >= f.maxSeries {
+		labels = OverflowLabels
+		if s, ok := f.series[labels]; ok {
+			return s
+		}
+	}
+	s := f.create(labels)
+	f.series[labels] = s
+	return s
+}
+
+// labeled returns the number of series with label values.
+func (f *family[T]) labeled() int {
+	n := len(f.series)
+	if _, ok := f.series[""]; ok {
+		n--
+	}
+	return n
+}
+
+// formatLabels formats the label values as {k1=v1,k2=v2}, sorted by label, or
+// as the empty string if there are none. If a label is given more than once,
+// the last value wins.
+func formatLabels(lvs lv.LabelValues) string {
+	if len(lvs) == 0 {
+		return ""
+	}
+	m := make(map[string]string, len(lvs)/2)
+	for i := 0; i < len(lvs); i += 2 {
+		m[lvs[i]] = lvs[i+1]
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + m[k]
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
@@ -1,6 +1,7 @@
 package expvar
 
 import (
+	"expvar"
 	"strconv"
 	"testing"
 
@@ -36,3 +37,67 @@ func TestHistogram(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestGaugeFunc(t *testing.T) {
+	depth := 3.0
+	NewGaugeFunc("expvar_gauge_func", func() float64 { return depth })
+	for _, want := range []string{"3", "5"} {
+		depth, _ = strconv.ParseFloat(want, 64)
+		if have := expvar.Get("expvar_gauge_func").String(); want != have {
+			t.Errorf("want %s, have %s", want, have)
+		}
+	}
+}
+
+func TestWith(t *testing.T) {
+	counter := NewCounter("expvar_labeled_counter")
+	counter.With("method", "GET", "code", "200").Add(1)
+	counter.With("code", "200").With("method", "GET").Add(2)
+	counter.With("method", "POST", "code", "500").Add(3)
+	counter.Add(4)
+
+	for name, want := range map[string]string{
+		"expvar_labeled_counter":                       "4",
+		"expvar_labeled_counter{code=200,method=GET}":  "3",
+		"expvar_labeled_counter{code=500,method=POST}": "3",
+	} {
+		v := expvar.Get(name)
+		if v == nil {
+			t.Errorf("%s: not published", name)
+			continue
+		}
+		if have := v.String(); want != have {
+			t.Errorf("%s: want %s, have %s", name, want, have)
+		}
+	}
+
+	NewHistogram("expvar_labeled_histogram", 50).With("method", "GET").Observe(1)
+	if v := expvar.Get("expvar_labeled_histogram.p99{method=GET}"); v == nil {
+		t.Error("labeled histogram quantile not published")
+	}
+}
+
+func TestMaxSeries(t *testing.T) {
+	gauge := NewGauge("expvar_capped_gauge", WithMaxSeries(2))
+	for _, user := range []string{"a", "b", "c", "d"} {
+		gauge.With("user", user).Add(1)
+	}
+
+	for name, want := range map[string]string{
+		"expvar_capped_gauge{user=a}":          "1",
+		"expvar_capped_gauge{user=b}":          "1",
+		"expvar_capped_gauge" + OverflowLabels: "2",
+	} {
+		v := expvar.Get(name)
+		if v == nil {
+			t.Errorf("%s: not published", name)
+			continue
+		}
+		if have := v.String(); want != have {
+			t.Errorf("%s: want %s, have %s", name, want, have)
+		}
+	}
+	if v := expvar.Get("expvar_capped_gauge{user=c}"); v != nil {
+		t.Error("series beyond the cap was published")
+	}
+}
@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"time"
+)
+
+// FlushFunc is called by push-based backends after each flush of their
+// metrics, with the amount of data sent, the time the flush took, and its
+// error, if any. Backends which write to an io.Writer report the number of
+// bytes written; backends which call an API report the number of datapoints
+// sent. It makes the health of the metrics pipeline itself observable, rather
+// than only logged.
+type FlushFunc func(n int64, took time.Duration, err error)
+
+// NewFlushFunc returns a FlushFunc which reports flushes through metrics:
+// failed flushes are counted by failures, the amount of data sent is added to
+// sent, and the duration of each flush is observed by duration, in seconds.
+// Any of the metrics may be nil, in which case it's skipped.
+func NewFlushFunc(failures, sent Counter, duration Histogram) FlushFunc {
+	return func(n int64, took time.Duration, err error) {
+		if err != nil && failures != nil {
+			failures.Add(1)
+		}
+		if sent != nil {
+			sent.Add(float64(n))
+		}
+		if duration != nil {
+			duration.Observe(took.Seconds())
+		}
+	}
+}
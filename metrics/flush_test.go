@@ -0,0 +1,33 @@
+package metrics_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/barrett370/kit/v2/metrics"
+	"github.com/barrett370/kit/v2/metrics/generic"
+)
+
+func TestFlushFunc(t *testing.T) {
+	var (
+		failures = generic.NewCounter("failures")
+		sent     = generic.NewCounter("sent")
+		duration = generic.NewSimpleHistogram()
+	)
+	f := metrics.NewFlushFunc(failures, sent, duration)
+	f(100, time.Second, nil)
+	f(20, 3*time.Second, errors.New("broken pipe"))
+
+	if want, have := 1.0, failures.Value(); want != have {
+		t.Errorf("failures: want %f, have %f", want, have)
+	}
+	if want, have := 120.0, sent.Value(); want != have {
+		t.Errorf("sent: want %f, have %f", want, have)
+	}
+	if want, have := 2.0, duration.ApproximateMovingAverage(); want != have {
+		t.Errorf("duration: want %f, have %f", want, have)
+	}
+
+	metrics.NewFlushFunc(nil, nil, nil)(1, time.Second, errors.New("ignored"))
+}
@@ -0,0 +1,195 @@
+package generic
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/barrett370/kit/v2/metrics"
+	"github.com/barrett370/kit/v2/metrics/internal/lv"
+)
+
+// ErrBoundsMismatch is returned when merging histograms with different
+// bucket bounds.
+var ErrBoundsMismatch = errors.New("histogram bucket bounds don't match")
+
+// BucketedHistogram is an in-memory implementation of a Histogram which counts
+// observations in fixed buckets, and tracks their sum and count. Unlike
+// Histogram, its snapshots can be aggregated, so it's suitable for backends
+// which emit bucket distributions.
+type BucketedHistogram struct {
+	Name string
+	lvs  lv.LabelValues
+	b    *buckets
+}
+
+// NewBucketedHistogram returns a histogram with buckets of the given upper
+// bounds, e.g. 0.005, 0.01, 0.025 ... 10 for latencies in seconds. The bounds
+// are sorted, and an implicit +Inf bucket counts all observations.
+func NewBucketedHistogram(name string, bounds ...float64) *BucketedHistogram {
+	bs := make([]float64, 0, len(bounds))
+	for _, b := range bounds {
+		if !math.IsInf(b, +1) {
+			bs = append(bs, b)
+		}
+	}
+	sort.Float64s(bs)
+	return &BucketedHistogram{
+		Name: name,
+		b:    &buckets{bounds: bs, counts: make([]uint64, len(bs))},
+	}
+}
+
+// With implements Histogram.
+func (h *BucketedHistogram) With(labelValues ...string) metrics.Histogram {
+	return &BucketedHistogram{
+		Name: h.Name,
+		lvs:  h.lvs.With(labelValues...),
+		b:    h.b,
+	}
+}
+
+// Observe implements Histogram.
+func (h *BucketedHistogram) Observe(value float64) {
+	h.b.mtx.Lock()
+	defer h.b.mtx.Unlock()
+	if i := sort.SearchFloat64s(h.b.bounds, value); i < len(h.b.bounds) {
+		h.b.counts[i]++
+	}
+	h.b.count++
+	h.b.sum += value
+}
+
+// Snapshot returns the current state of the histogram.
+func (h *BucketedHistogram) Snapshot() HistogramSnapshot {
+	h.b.mtx.Lock()
+	defer h.b.mtx.Unlock()
+	return h.b.snapshot()
+}
+
+// Reset empties the histogram and returns its previous state. It's useful for
+// backends which emit the observations made since their previous write.
+func (h *BucketedHistogram) Reset() HistogramSnapshot {
+	h.b.mtx.Lock()
+	defer h.b.mtx.Unlock()
+	s := h.b.snapshot()
+	for i := range h.b.counts {
+		h.b.counts[i] = 0
+	}
+	h.b.count, h.b.sum = 0, 0
+	return s
+}
+
+// Merge adds the observations of the snapshot to the histogram. The snapshot
+// must have the same bounds as the histogram.
+func (h *BucketedHistogram) Merge(s HistogramSnapshot) error {
+	h.b.mtx.Lock()
+	defer h.b.mtx.Unlock()
+	if !equalBounds(h.b.bounds, s.Bounds) {
+		return ErrBoundsMismatch
+	}
+	var prev uint64
+	for i, c := range s.Counts {
+		h.b.counts[i] += c - prev
+		prev = c
+	}
+	h.b.count += s.Count
+	h.b.sum += s.Sum
+	return nil
+}
+
+// LabelValues returns the set of label values attached to the histogram.
+func (h *BucketedHistogram) LabelValues() []string {
+	return h.lvs
+}
+
+type buckets struct {
+	mtx    sync.Mutex
+	bounds []float64
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+func (b *buckets) snapshot() HistogramSnapshot {
+	s := HistogramSnapshot{
+		Bounds: append([]float64{}, b.bounds...),
+		Counts: make([]uint64, len(b.counts)),
+		Count:  b.count,
+		Sum:    b.sum,
+	}
+	var cumulative uint64
+	for i, c := range b.counts {
+		cumulative += c
+		s.Counts[i] = cumulative
+	}
+	return s
+}
+
+// HistogramSnapshot is the state of a BucketedHistogram at a point in time.
+type HistogramSnapshot struct {
+	Bounds []float64 // upper bounds of the buckets, excluding +Inf
+	Counts []uint64  // cumulative counts of observations <= each bound
+	Count  uint64    // count of all observations, i.e. the +Inf bucket
+	Sum    float64   // sum of all observations
+}
+
+// Merge returns a snapshot with the observations of both snapshots, which
+// must have the same bounds.
+func (s HistogramSnapshot) Merge(o HistogramSnapshot) (HistogramSnapshot, error) {
+	if !equalBounds(s.Bounds, o.Bounds) {
+		return HistogramSnapshot{}, ErrBoundsMismatch
+	}
+	m := HistogramSnapshot{
+		Bounds: append([]float64{}, s.Bounds...),
+		Counts: make([]uint64, len(s.Counts)),
+		Count:  s.Count + o.Count,
+		Sum:    s.Sum + o.Sum,
+	}
+	for i := range s.Counts {
+		m.Counts[i] = s.Counts[i] + o.Counts[i]
+	}
+	return m, nil
+}
+
+// Quantile returns an estimate of the quantile q, 0.0 < q < 1.0, by linear
+// interpolation within the bucket which contains it, as Prometheus'
+// histogram_quantile does. If the quantile falls in the +Inf bucket, the
+// largest bound is returned. If there are no observations, NaN is returned.
+func (s HistogramSnapshot) Quantile(q float64) float64 {
+	if s.Count == 0 {
+		return math.NaN()
+	}
+	rank := q * float64(s.Count)
+	i := sort.Search(len(s.Counts), func(i int) bool { return float64(s.Counts[i]) >= rank })
+	if i == len(s.Counts) {
+		if len(s.Bounds) == 0 {
+			return math.NaN()
+		}
+		return s.Bounds[len(s.Bounds)-1]
+	}
+	var lower, below float64
+	if i > 0 {
+		lower, below = s.Bounds[i-1], float64(s.Counts[i-1])
+	} else if s.Bounds[0] < 0 {
+		lower = s.Bounds[0]
+	}
+	inBucket := float64(s.Counts[i]) - below
+	if inBucket == 0 {
+		return s.Bounds[i]
+	}
+	return lower + (s.Bounds[i]-lower)*(rank-below)/inBucket
+}
+
+func equalBounds(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -13,8 +13,10 @@ import (
 	"io/ioutil"
 	"math"
 	"math/rand"
+	"reflect"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/barrett370/kit/v2/metrics/generic"
 	"github.com/barrett370/kit/v2/metrics/teststat"
@@ -114,6 +116,79 @@ func TestSimpleHistogram(t *testing.T) {
 	}
 }
 
+func TestBucketedHistogram(t *testing.T) {
+	histogram := generic.NewBucketedHistogram("bucketed", 10, 1, 5).With("label", "bucketed").(*generic.BucketedHistogram)
+	for _, value := range []float64{0.5, 2, 3, 4, 7, 20} {
+		histogram.Observe(value)
+	}
+	s := histogram.Snapshot()
+	if want, have := []float64{1, 5, 10}, s.Bounds; !reflect.DeepEqual(want, have) {
+		t.Errorf("Bounds: want %v, have %v", want, have)
+	}
+	if want, have := []uint64{1, 4, 5}, s.Counts; !reflect.DeepEqual(want, have) {
+		t.Errorf("Counts: want %v, have %v", want, have)
+	}
+	if want, have := uint64(6), s.Count; want != have {
+		t.Errorf("Count: want %d, have %d", want, have)
+	}
+	if want, have := 36.5, s.Sum; want != have {
+		t.Errorf("Sum: want %f, have %f", want, have)
+	}
+	// The median, the 3rd observation, is the 2nd of the 3 observations in
+	// (1, 5], so it's interpolated two thirds of the way into that bucket.
+	if want, have := 1+4*2.0/3, s.Quantile(0.5); want != have {
+		t.Errorf("Quantile(0.5): want %f, have %f", want, have)
+	}
+	if want, have := 10.0, s.Quantile(0.99); want != have {
+		t.Errorf("Quantile(0.99): want %f, have %f", want, have)
+	}
+
+	if want, have := s, histogram.Reset(); !reflect.DeepEqual(want, have) {
+		t.Errorf("Reset: want %v, have %v", want, have)
+	}
+	if want, have := uint64(0), histogram.Snapshot().Count; want != have {
+		t.Errorf("Count after Reset: want %d, have %d", want, have)
+	}
+
+	// Merging the snapshot restores the observations.
+	if err := histogram.Merge(s); err != nil {
+		t.Fatal(err)
+	}
+	m, err := s.Merge(histogram.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := []uint64{2, 8, 10}, m.Counts; !reflect.DeepEqual(want, have) {
+		t.Errorf("merged Counts: want %v, have %v", want, have)
+	}
+	if want, have := generic.ErrBoundsMismatch, histogram.Merge(generic.NewBucketedHistogram("other", 1).Snapshot()); want != have {
+		t.Errorf("Merge: want %v, have %v", want, have)
+	}
+}
+
+func TestWindowedHistogram(t *testing.T) {
+	window := 200 * time.Millisecond
+	histogram := generic.NewWindowedHistogram("windowed", window, 4, 1, 2, 3).With("label", "windowed").(*generic.WindowedHistogram)
+	histogram.Observe(1.5)
+	histogram.Observe(2.5)
+	if want, have := uint64(2), histogram.Snapshot().Count; want != have {
+		t.Errorf("Count: want %d, have %d", want, have)
+	}
+	if want, have := 2.0, histogram.Quantile(0.5); want != have {
+		t.Errorf("Quantile(0.5): want %f, have %f", want, have)
+	}
+
+	// Once the window has passed, the observations are dropped.
+	time.Sleep(window + window/4)
+	if want, have := uint64(0), histogram.Snapshot().Count; want != have {
+		t.Errorf("Count after window: want %d, have %d", want, have)
+	}
+	histogram.Observe(0.5)
+	if want, have := 1.0, histogram.Quantile(1); want != have {
+		t.Errorf("Quantile(1): want %f, have %f", want, have)
+	}
+}
+
 // Naive atomic alignment test.
 // The problem is related to the use of `atomic.*` and not directly to a structure.
 // But currently works for Counter and Gauge.
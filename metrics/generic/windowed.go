@@ -0,0 +1,109 @@
+package generic
+
+import (
+	"sync"
+	"time"
+
+	"github.com/barrett370/kit/v2/metrics"
+	"github.com/barrett370/kit/v2/metrics/internal/lv"
+)
+
+// WindowedHistogram is an in-memory implementation of a Histogram which only
+// reflects the observations made within a sliding time window. The window is
+// divided into a number of slots, each of which is a BucketedHistogram; when
+// a slot expires, its observations are dropped. Long-running processes which
+// report percentiles should prefer it to Histogram, whose quantiles are
+// computed over all observations since the process started, and so barely
+// move.
+type WindowedHistogram struct {
+	Name string
+	lvs  lv.LabelValues
+	w    *slidingWindow
+}
+
+// NewWindowedHistogram returns a histogram over the given window, divided
+// into the given number of slots, with buckets of the given upper bounds. The
+// window slides by window/slots at a time, so more slots make it smoother. A
+// good default is a window of one minute, with 6 slots.
+func NewWindowedHistogram(name string, window time.Duration, slots int, bounds ...float64) *WindowedHistogram {
+	if slots < 1 {
+		slots = 1
+	}
+	w := &slidingWindow{
+		slots:    make([]*BucketedHistogram, slots),
+		duration: window / time.Duration(slots),
+		rotated:  time.Now(),
+	}
+	for i := range w.slots {
+		w.slots[i] = NewBucketedHistogram(name, bounds...)
+	}
+	return &WindowedHistogram{
+		Name: name,
+		w:    w,
+	}
+}
+
+// With implements Histogram.
+func (h *WindowedHistogram) With(labelValues ...string) metrics.Histogram {
+	return &WindowedHistogram{
+		Name: h.Name,
+		lvs:  h.lvs.With(labelValues...),
+		w:    h.w,
+	}
+}
+
+// Observe implements Histogram.
+func (h *WindowedHistogram) Observe(value float64) {
+	h.w.mtx.Lock()
+	defer h.w.mtx.Unlock()
+	h.w.rotate(time.Now())
+	h.w.slots[h.w.current].Observe(value)
+}
+
+// Snapshot returns the observations made within the window.
+func (h *WindowedHistogram) Snapshot() HistogramSnapshot {
+	h.w.mtx.Lock()
+	defer h.w.mtx.Unlock()
+	h.w.rotate(time.Now())
+	s := h.w.slots[0].Snapshot()
+	for _, slot := range h.w.slots[1:] {
+		s, _ = s.Merge(slot.Snapshot()) // the slots share their bounds
+	}
+	return s
+}
+
+// Quantile returns an estimate of the quantile q, 0.0 < q < 1.0, of the
+// observations made within the window. See HistogramSnapshot.Quantile.
+func (h *WindowedHistogram) Quantile(q float64) float64 {
+	return h.Snapshot().Quantile(q)
+}
+
+// LabelValues returns the set of label values attached to the histogram.
+func (h *WindowedHistogram) LabelValues() []string {
+	return h.lvs
+}
+
+type slidingWindow struct {
+	mtx      sync.Mutex
+	slots    []*BucketedHistogram
+	current  int
+	duration time.Duration // per slot
+	rotated  time.Time     // when the current slot started
+}
+
+// rotate advances the current slot once for every slot duration elapsed
+// since the last rotation, emptying the slots it advances to.
+func (w *slidingWindow) rotate(now time.Time) {
+	n := int(now.Sub(w.rotated) / w.duration)
+	if n <= 0 {
+		return
+	}
+	w.rotated = w.rotated.Add(time.Duration(n) * w.duration)
+	if n > len(w.slots) {
+		n = len(w.slots)
+	}
+	for i := 0; i < n; i++ {
+		w.current = (w.current + 1) % len(w.slots)
+		w.slots[w.current].Reset()
+	}
+}
@@ -2,24 +2,37 @@
 // and emitted in the plaintext protocol. For more information, see
 // http://graphite.readthedocs.io/en/latest/feeding-carbon.html#the-plaintext-protocol
 //
-// Graphite does not have a native understanding of metric parameterization, so
-// label values not supported. Use distinct metrics for each unique combination
-// of label values.
+// By default, label values are not supported, as older versions of Graphite do
+// not have a native understanding of metric parameterization. Use distinct
+// metrics for each unique combination of label values, or enable the Graphite
+// 1.1 tag syntax with the WithTags option. The pickle protocol may be used
+// instead of the plaintext protocol with the WithPickle option.
 package graphite
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/barrett370/kit/v2/metrics"
 	"github.com/barrett370/kit/v2/metrics/generic"
+	"github.com/barrett370/kit/v2/metrics/internal/gaugefunc"
+	"github.com/barrett370/kit/v2/metrics/internal/loop"
+	"github.com/barrett370/kit/v2/metrics/internal/lv"
 	"github.com/barrett370/kit/v2/util/conn"
 	"github.com/go-kit/log"
 )
 
+// DefaultPickleBatchSize is the maximum number of datapoints per pickle
+// message, if the pickle protocol is used and no batch size is given. It
+// matches the default MAX_DATAPOINTS_PER_MESSAGE of carbon-relay.
+const DefaultPickleBatchSize = 500
+
 // Graphite receives metrics observations and forwards them to a Graphite server.
 // Create a Graphite object, use it to create metrics, and pass those metrics as
 // dependencies to the components that will use them.
@@ -37,67 +50,179 @@ type Graphite struct {
 	gauges     map[string]*Gauge
 	histograms map[string]*Histogram
 	logger     log.Logger
+	tags       bool
+	pickle     bool
+	batchSize  int
+	gaugeFuncs gaugefunc.Set
+	flushFunc  metrics.FlushFunc
+	jitter     time.Duration
+}
+
+// Option sets an optional parameter for Graphite objects.
+type Option func(*Graphite)
+
+// WithTags makes With functional on all metrics created by the Graphite
+// object. Each unique combination of label values is a separate series, and
+// is named with the Graphite 1.1 tag syntax, e.g. name;method=get;code=200.
+// Tags are sorted by name, and tags with an empty value are omitted.
+func WithTags() Option {
+	return func(g *Graphite) { g.tags = true }
+}
+
+// WithPickle emits metrics in the pickle protocol, rather than the plaintext
+// protocol. Each batch of datapoints is written as a single length-prefixed
+// pickle message. Carbon typically listens for pickle messages on port 2004.
+// For more information, see
+// http://graphite.readthedocs.io/en/latest/feeding-carbon.html#the-pickle-protocol
+func WithPickle() Option {
+	return func(g *Graphite) { g.pickle = true }
+}
+
+// WithBatchSize limits the number of datapoints emitted by a single write to
+// the underlying writer. In the plaintext protocol, by default, all datapoints
+// are written at once. In the pickle protocol, by default, the limit is
+// DefaultPickleBatchSize.
+func WithBatchSize(n int) Option {
+	return func(g *Graphite) { g.batchSize = n }
+}
+
+// WithFlushFunc sets a func which is called after each WriteTo, with the
+// number of bytes written, the duration of the write, and its error, if any,
+// e.g. one returned by metrics.NewFlushFunc. By default, failed writes are
+// only logged by the helper methods.
+func WithFlushFunc(f metrics.FlushFunc) Option {
+	return func(g *Graphite) { g.flushFunc = f }
+}
+
+// WithJitter delays each write made by the loop helper methods by a random
+// offset in [0, jitter), chosen once when the loop starts, so that processes
+// started together don't all flush at the same instant. By default, there's
+// no delay.
+func WithJitter(jitter time.Duration) Option {
+	return func(g *Graphite) { g.jitter = jitter }
 }
 
 // New returns a Graphite object that may be used to create metrics. Prefix is
 // applied to all created metrics. Callers must ensure that regular calls to
 // WriteTo are performed, either manually or with one of the helper methods.
-func New(prefix string, logger log.Logger) *Graphite {
-	return &Graphite{
+func New(prefix string, logger log.Logger, options ...Option) *Graphite {
+	g := &Graphite{
 		prefix:     prefix,
 		counters:   map[string]*Counter{},
 		gauges:     map[string]*Gauge{},
 		histograms: map[string]*Histogram{},
 		logger:     logger,
 	}
+	for _, option := range options {
+		option(g)
+	}
+	if g.pickle && g.batchSize <= 0 {
+		g.batchSize = DefaultPickleBatchSize
+	}
+	return g
 }
 
 // NewCounter returns a counter. Observations are aggregated and emitted once
 // per write invocation.
 func (g *Graphite) NewCounter(name string) *Counter {
 	c := NewCounter(g.prefix + name)
+	if g.tags {
+		c.with = func(lvs lv.LabelValues) metrics.Counter { return g.counter(g.prefix+name, lvs) }
+	}
 	g.mtx.Lock()
 	g.counters[g.prefix+name] = c
 	g.mtx.Unlock()
 	return c
 }
 
+func (g *Graphite) counter(name string, lvs lv.LabelValues) *Counter {
+	key := series(name, lvs)
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	if c, ok := g.counters[key]; ok {
+		return c
+	}
+	c := NewCounter(name)
+	c.lvs = copyLabelValues(lvs)
+	c.with = func(lvs lv.LabelValues) metrics.Counter { return g.counter(name, lvs) }
+	g.counters[key] = c
+	return c
+}
+
 // NewGauge returns a gauge. Observations are aggregated and emitted once per
 // write invocation.
 func (g *Graphite) NewGauge(name string) *Gauge {
 	ga := NewGauge(g.prefix + name)
+	if g.tags {
+		ga.with = func(lvs lv.LabelValues) metrics.Gauge { return g.gauge(g.prefix+name, lvs) }
+	}
 	g.mtx.Lock()
 	g.gauges[g.prefix+name] = ga
 	g.mtx.Unlock()
 	return ga
 }
 
+// NewGaugeFunc adds a gauge whose value is sampled from f immediately before
+// each write, for values such as queue depth which aren't naturally
+// event-driven. The label values are applied to the gauge via With.
+func (g *Graphite) NewGaugeFunc(name string, f func() float64, labelValues ...string) {
+	g.gaugeFuncs.Add(g.NewGauge(name).With(labelValues...), f)
+}
+
+func (g *Graphite) gauge(name string, lvs lv.LabelValues) *Gauge {
+	key := series(name, lvs)
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	if ga, ok := g.gauges[key]; ok {
+		return ga
+	}
+	ga := NewGauge(name)
+	ga.lvs = copyLabelValues(lvs)
+	ga.with = func(lvs lv.LabelValues) metrics.Gauge { return g.gauge(name, lvs) }
+	g.gauges[key] = ga
+	return ga
+}
+
 // NewHistogram returns a histogram. Observations are aggregated and emitted as
 // per-quantile gauges, once per write invocation. 50 is a good default value
 // for buckets.
 func (g *Graphite) NewHistogram(name string, buckets int) *Histogram {
 	h := NewHistogram(g.prefix+name, buckets)
+	if g.tags {
+		h.with = func(lvs lv.LabelValues) metrics.Histogram { return g.histogram(g.prefix+name, buckets, lvs) }
+	}
 	g.mtx.Lock()
 	g.histograms[g.prefix+name] = h
 	g.mtx.Unlock()
 	return h
 }
 
+func (g *Graphite) histogram(name string, buckets int, lvs lv.LabelValues) *Histogram {
+	key := series(name, lvs)
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	if h, ok := g.histograms[key]; ok {
+		return h
+	}
+	h := NewHistogram(name, buckets)
+	h.lvs = copyLabelValues(lvs)
+	h.with = func(lvs lv.LabelValues) metrics.Histogram { return g.histogram(name, buckets, lvs) }
+	g.histograms[key] = h
+	return h
+}
+
 // WriteLoop is a helper method that invokes WriteTo to the passed writer every
 // time the passed channel fires. This method blocks until ctx is canceled,
 // so clients probably want to run it in its own goroutine. For typical
 // usage, create a time.Ticker and pass its C channel to this method.
+// When ctx is canceled, a final write is made, so that the observations
+// made since the last tick aren't lost.
 func (g *Graphite) WriteLoop(ctx context.Context, c <-chan time.Time, w io.Writer) {
-	for {
-		select {
-		case <-c:
-			if _, err := g.WriteTo(w); err != nil {
-				g.logger.Log("during", "WriteTo", "err", err)
-			}
-		case <-ctx.Done():
-			return
+	loop.Run(ctx, c, g.jitter, func(context.Context) {
+		if _, err := g.WriteTo(w); err != nil {
+			g.logger.Log("during", "WriteTo", "err", err)
 		}
-	}
+	})
 }
 
 // SendLoop is a helper method that wraps WriteLoop, passing a managed
@@ -110,32 +235,29 @@ func (g *Graphite) SendLoop(ctx context.Context, c <-chan time.Time, network, ad
 }
 
 // WriteTo flushes the buffered content of the metrics to the writer, in
-// Graphite plaintext format. WriteTo abides best-effort semantics, so
-// observations are lost if there is a problem with the write. Clients should be
-// sure to call WriteTo regularly, ideally through the WriteLoop or SendLoop
-// helper methods.
+// Graphite plaintext format, or in the pickle format if WithPickle is given.
+// WriteTo abides best-effort semantics, so observations are lost if there is a
+// problem with the write. Clients should be sure to call WriteTo regularly,
+// ideally through the WriteLoop or SendLoop helper methods.
 func (g *Graphite) WriteTo(w io.Writer) (count int64, err error) {
+	if g.flushFunc != nil {
+		defer func(begin time.Time) { g.flushFunc(count, time.Since(begin), err) }(time.Now())
+	}
+	g.gaugeFuncs.Sample()
+
 	g.mtx.RLock()
-	defer g.mtx.RUnlock()
 	now := time.Now().Unix()
 
-	for name, c := range g.counters {
-		n, err := fmt.Fprintf(w, "%s %f %d\n", name, c.c.ValueReset(), now)
-		if err != nil {
-			return count, err
-		}
-		count += int64(n)
+	var points []datapoint
+	for _, c := range g.counters {
+		points = append(points, datapoint{series(c.c.Name, c.lvs), c.c.ValueReset(), now})
 	}
 
-	for name, ga := range g.gauges {
-		n, err := fmt.Fprintf(w, "%s %f %d\n", name, ga.g.Value(), now)
-		if err != nil {
-			return count, err
-		}
-		count += int64(n)
+	for _, ga := range g.gauges {
+		points = append(points, datapoint{series(ga.g.Name, ga.lvs), ga.g.Value(), now})
 	}
 
-	for name, h := range g.histograms {
+	for _, h := range g.histograms {
 		for _, p := range []struct {
 			s string
 			f float64
@@ -145,45 +267,127 @@ func (g *Graphite) WriteTo(w io.Writer) (count int64, err error) {
 			{"95", 0.95},
 			{"99", 0.99},
 		} {
-			n, err := fmt.Fprintf(w, "%s.p%s %f %d\n", name, p.s, h.h.Quantile(p.f), now)
-			if err != nil {
-				return count, err
+			points = append(points, datapoint{series(h.h.Name+".p"+p.s, h.lvs), h.h.Quantile(p.f), now})
+		}
+	}
+	g.mtx.RUnlock()
+
+	size := g.batchSize
+	if size <= 0 {
+		size = len(points)
+	}
+	var buf bytes.Buffer
+	for len(points) > 0 {
+		if size > len(points) {
+			size = len(points)
+		}
+		buf.Reset()
+		if g.pickle {
+			writePickle(&buf, points[:size])
+		} else {
+			for _, p := range points[:size] {
+				fmt.Fprintf(&buf, "%s %f %d\n", p.path, p.value, p.timestamp)
 			}
-			count += int64(n)
 		}
+		n, err := w.Write(buf.Bytes())
+		count += int64(n)
+		if err != nil {
+			return count, err
+		}
+		points = points[size:]
 	}
 
-	return count, err
+	return count, nil
+}
+
+// datapoint is a single observation of a series, as emitted to Graphite.
+type datapoint struct {
+	path      string
+	value     float64
+	timestamp int64
+}
+
+var (
+	tagNameReplacer  = strings.NewReplacer(";", "_", "!", "_", "^", "_", "=", "_", " ", "_")
+	tagValueReplacer = strings.NewReplacer(";", "_", " ", "_")
+)
+
+// copyLabelValues returns a copy of lvs without spare capacity, so that
+// subsequent calls to With never share its backing array.
+func copyLabelValues(lvs lv.LabelValues) lv.LabelValues {
+	c := make(lv.LabelValues, len(lvs))
+	copy(c, lvs)
+	return c
+}
+
+// series returns the name of the series in the Graphite 1.1 tag syntax. The
+// label values are interpreted as key/value pairs, and are sorted by key.
+func series(name string, lvs lv.LabelValues) string {
+	if len(lvs) == 0 {
+		return name
+	}
+	tags := make([]string, 0, len(lvs)/2)
+	for i := 0; i < len(lvs); i += 2 {
+		if lvs[i+1] == "" {
+			continue
+		}
+		value := tagValueReplacer.Replace(lvs[i+1])
+		if strings.HasPrefix(value, "~") {
+			value = "_" + value[1:]
+		}
+		tags = append(tags, tagNameReplacer.Replace(lvs[i])+"="+value)
+	}
+	sort.Strings(tags)
+	if len(tags) == 0 {
+		return name
+	}
+	return name + ";" + strings.Join(tags, ";")
 }
 
 // Counter is a Graphite counter metric.
 type Counter struct {
-	c *generic.Counter
+	c    *generic.Counter
+	lvs  lv.LabelValues
+	with func(lv.LabelValues) metrics.Counter
 }
 
 // NewCounter returns a new usable counter metric.
 func NewCounter(name string) *Counter {
-	return &Counter{generic.NewCounter(name)}
+	return &Counter{c: generic.NewCounter(name)}
 }
 
-// With is a no-op.
-func (c *Counter) With(...string) metrics.Counter { return c }
+// With is a no-op, unless the counter was created by a Graphite object with
+// the WithTags option.
+func (c *Counter) With(labelValues ...string) metrics.Counter {
+	if c.with == nil {
+		return c
+	}
+	return c.with(c.lvs.With(labelValues...))
+}
 
 // Add implements counter.
 func (c *Counter) Add(delta float64) { c.c.Add(delta) }
 
 // Gauge is a Graphite gauge metric.
 type Gauge struct {
-	g *generic.Gauge
+	g    *generic.Gauge
+	lvs  lv.LabelValues
+	with func(lv.LabelValues) metrics.Gauge
 }
 
 // NewGauge returns a new usable Gauge metric.
 func NewGauge(name string) *Gauge {
-	return &Gauge{generic.NewGauge(name)}
+	return &Gauge{g: generic.NewGauge(name)}
 }
 
-// With is a no-op.
-func (g *Gauge) With(...string) metrics.Gauge { return g }
+// With is a no-op, unless the gauge was created by a Graphite object with the
+// WithTags option.
+func (g *Gauge) With(labelValues ...string) metrics.Gauge {
+	if g.with == nil {
+		return g
+	}
+	return g.with(g.lvs.With(labelValues...))
+}
 
 // Set implements gauge.
 func (g *Gauge) Set(value float64) { g.g.Set(value) }
@@ -194,16 +398,24 @@ func (g *Gauge) Add(delta float64) { g.g.Add(delta) }
 // Histogram is a Graphite histogram metric. Observations are bucketed into
 // per-quantile gauges.
 type Histogram struct {
-	h *generic.Histogram
+	h    *generic.Histogram
+	lvs  lv.LabelValues
+	with func(lv.LabelValues) metrics.Histogram
 }
 
 // NewHistogram returns a new usable Histogram metric.
 func NewHistogram(name string, buckets int) *Histogram {
-	return &Histogram{generic.NewHistogram(name, buckets)}
+	return &Histogram{h: generic.NewHistogram(name, buckets)}
 }
 
-// With is a no-op.
-func (h *Histogram) With(...string) metrics.Histogram { return h }
+// With is a no-op, unless the histogram was created by a Graphite object with
+// the WithTags option.
+func (h *Histogram) With(labelValues ...string) metrics.Histogram {
+	if h.with == nil {
+		return h
+	}
+	return h.with(h.lvs.With(labelValues...))
+}
 
 // Observe implements histogram.
 func (h *Histogram) Observe(value float64) { h.h.Observe(value) }
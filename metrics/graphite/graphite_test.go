@@ -2,6 +2,7 @@ package graphite
 
 import (
 	"bytes"
+	"encoding/binary"
 	"regexp"
 	"strconv"
 	"testing"
@@ -12,7 +13,7 @@ import (
 
 func TestCounter(t *testing.T) {
 	prefix, name := "abc.", "def"
-	label, value := "label", "value" // ignored for Graphite
+	label, value := "label", "value" // ignored without WithTags
 	regex := `^` + prefix + name + ` ([0-9\.]+) [0-9]+$`
 	g := New(prefix, log.NewNopLogger())
 	counter := g.NewCounter(name).With(label, value)
@@ -24,7 +25,7 @@ func TestCounter(t *testing.T) {
 
 func TestGauge(t *testing.T) {
 	prefix, name := "ghi.", "jkl"
-	label, value := "xyz", "abc" // ignored for Graphite
+	label, value := "xyz", "abc" // ignored without WithTags
 	regex := `^` + prefix + name + ` ([0-9\.]+) [0-9]+$`
 	g := New(prefix, log.NewNopLogger())
 	gauge := g.NewGauge(name).With(label, value)
@@ -37,7 +38,7 @@ func TestGauge(t *testing.T) {
 func TestHistogram(t *testing.T) {
 	// The histogram test is actually like 4 gauge tests.
 	prefix, name := "graphite.", "histogram_test"
-	label, value := "abc", "def" // ignored for Graphite
+	label, value := "abc", "def" // ignored without WithTags
 	re50 := regexp.MustCompile(prefix + name + `.p50 ([0-9\.]+) [0-9]+`)
 	re90 := regexp.MustCompile(prefix + name + `.p90 ([0-9\.]+) [0-9]+`)
 	re95 := regexp.MustCompile(prefix + name + `.p95 ([0-9\.]+) [0-9]+`)
@@ -61,3 +62,77 @@ func TestHistogram(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestTags(t *testing.T) {
+	g := New("prefix.", log.NewNopLogger(), WithTags())
+	c := g.NewCounter("requests").With("method", "get")
+	c.With("code", "200").Add(1)
+	c.With("code", "200").Add(2)
+	c.With("code", "500", "path", "").Add(4)
+	g.NewGauge("inflight").With("region", "eu;west").Set(3)
+	g.NewHistogram("latency", 50).With("method", "get").Observe(5)
+
+	var buf bytes.Buffer
+	if _, err := g.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`^prefix\.requests;code=200;method=get 3\.000000 [0-9]+$`,
+		`^prefix\.requests;code=500;method=get 4\.000000 [0-9]+$`,
+		`^prefix\.inflight;region=eu_west 3\.000000 [0-9]+$`,
+		`^prefix\.latency\.p99;method=get 5\.000000 [0-9]+$`,
+	} {
+		if !regexp.MustCompile(`(?m)` + want).MatchString(buf.String()) {
+			t.Errorf("want match for %s, have\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestPickle(t *testing.T) {
+	g := New("", log.NewNopLogger(), WithPickle())
+	g.NewGauge("abc").Set(1.5)
+
+	var buf bytes.Buffer
+	if _, err := g.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	p := buf.Bytes()
+	if want, have := len(p)-4, int(binary.BigEndian.Uint32(p)); want != have {
+		t.Fatalf("length header: want %d, have %d", want, have)
+	}
+	want := []byte{0x80, 2, ']', '(', 'X', 3, 0, 0, 0, 'a', 'b', 'c', 0x8a, 8}
+	if have := p[4 : 4+len(want)]; !bytes.Equal(want, have) {
+		t.Errorf("want % x, have % x", want, have)
+	}
+	want = []byte{'G', 0x3f, 0xf8, 0, 0, 0, 0, 0, 0, 0x86, 0x86, 'e', '.'}
+	if have := p[len(p)-len(want):]; !bytes.Equal(want, have) {
+		t.Errorf("want % x, have % x", want, have)
+	}
+}
+
+func TestBatchSize(t *testing.T) {
+	for _, pickle := range []bool{false, true} {
+		options := []Option{WithBatchSize(2)}
+		if pickle {
+			options = append(options, WithPickle())
+		}
+		g := New("", log.NewNopLogger(), options...)
+		for i := 0; i < 5; i++ {
+			g.NewCounter(strconv.Itoa(i)).Add(1)
+		}
+		w := &recordingWriter{}
+		if _, err := g.WriteTo(w); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := 3, len(w.writes); want != have {
+			t.Errorf("pickle=%v: want %d writes, have %d", pickle, want, have)
+		}
+	}
+}
+
+type recordingWriter struct{ writes [][]byte }
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, append([]byte(nil), p...))
+	return len(p), nil
+}
@@ -0,0 +1,56 @@
+package graphite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// Opcodes of the pickle protocol, version 2, which are sufficient to encode a
+// list of (path, (timestamp, value)) tuples.
+const (
+	pickleProto      = 0x80
+	pickleEmptyList  = ']'
+	pickleMark       = '('
+	pickleAppends    = 'e'
+	pickleBinUnicode = 'X'
+	pickleBinFloat   = 'G'
+	pickleLong1      = 0x8a
+	pickleTuple2     = 0x86
+	pickleStop       = '.'
+)
+
+// writePickle writes the datapoints to buf as a single message in the pickle
+// protocol: a 4-byte big-endian length header, followed by the pickled list.
+func writePickle(buf *bytes.Buffer, points []datapoint) {
+	start := buf.Len()
+	buf.Write([]byte{0, 0, 0, 0}) // length header, filled in below
+
+	buf.WriteByte(pickleProto)
+	buf.WriteByte(2)
+	buf.WriteByte(pickleEmptyList)
+	buf.WriteByte(pickleMark)
+	var b [8]byte
+	for _, p := range points {
+		buf.WriteByte(pickleBinUnicode)
+		binary.LittleEndian.PutUint32(b[:4], uint32(len(p.path)))
+		buf.Write(b[:4])
+		buf.WriteString(p.path)
+
+		buf.WriteByte(pickleLong1)
+		buf.WriteByte(8)
+		binary.LittleEndian.PutUint64(b[:], uint64(p.timestamp))
+		buf.Write(b[:])
+
+		buf.WriteByte(pickleBinFloat)
+		binary.BigEndian.PutUint64(b[:], math.Float64bits(p.value))
+		buf.Write(b[:])
+
+		buf.WriteByte(pickleTuple2)
+		buf.WriteByte(pickleTuple2)
+	}
+	buf.WriteByte(pickleAppends)
+	buf.WriteByte(pickleStop)
+
+	binary.BigEndian.PutUint32(buf.Bytes()[start:], uint32(buf.Len()-start-4))
+}
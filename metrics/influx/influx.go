@@ -1,6 +1,9 @@
 // Package influx provides an InfluxDB implementation for metrics. The model is
 // similar to other push-based instrumentation systems. Observations are
 // aggregated locally and emitted to the Influx server on regular intervals.
+//
+// Observations are written via the InfluxDB 1.x client by default. To write
+// to InfluxDB 2.x, or InfluxDB Cloud, pass a V2Writer to WriteTo or WriteLoop.
 package influx
 
 import (
@@ -11,6 +14,8 @@ import (
 
 	"github.com/barrett370/kit/v2/metrics"
 	"github.com/barrett370/kit/v2/metrics/generic"
+	"github.com/barrett370/kit/v2/metrics/internal/gaugefunc"
+	"github.com/barrett370/kit/v2/metrics/internal/loop"
 	"github.com/barrett370/kit/v2/metrics/internal/lv"
 	"github.com/go-kit/log"
 )
@@ -38,6 +43,7 @@ type Influx struct {
 	tags       map[string]string
 	conf       influxdb.BatchPointsConfig
 	logger     log.Logger
+	gaugeFuncs gaugefunc.Set
 }
 
 // New returns an Influx, ready to create metrics and collect observations. Tags
@@ -71,6 +77,13 @@ func (in *Influx) NewGauge(name string) *Gauge {
 	}
 }
 
+// NewGaugeFunc adds a gauge whose value is sampled from f immediately before
+// each write, for values such as queue depth which aren't naturally
+// event-driven. The label values are applied to the gauge via With.
+func (in *Influx) NewGaugeFunc(name string, f func() float64, labelValues ...string) {
+	in.gaugeFuncs.Add(in.NewGauge(name).With(labelValues...), f)
+}
+
 // NewHistogram returns an Influx histogram.
 func (in *Influx) NewHistogram(name string) *Histogram {
 	return &Histogram{
@@ -89,17 +102,14 @@ type BatchPointsWriter interface {
 // time the passed channel fires. This method blocks until the channel is
 // closed, so clients probably want to run it in its own goroutine. For typical
 // usage, create a time.Ticker and pass its C channel to this method.
+// When ctx is canceled, a final write is made, so that the observations
+// made since the last tick aren't lost.
 func (in *Influx) WriteLoop(ctx context.Context, c <-chan time.Time, w BatchPointsWriter) {
-	for {
-		select {
-		case <-c:
-			if err := in.WriteTo(w); err != nil {
-				in.logger.Log("during", "WriteTo", "err", err)
-			}
-		case <-ctx.Done():
-			return
+	loop.Run(ctx, c, 0, func(context.Context) {
+		if err := in.WriteTo(w); err != nil {
+			in.logger.Log("during", "WriteTo", "err", err)
 		}
-	}
+	})
 }
 
 // WriteTo flushes the buffered content of the metrics to the writer, in an
@@ -107,6 +117,8 @@ func (in *Influx) WriteLoop(ctx context.Context, c <-chan time.Time, w BatchPoin
 // observations are lost if there is a problem with the write. Clients should be
 // sure to call WriteTo regularly, ideally through the WriteLoop helper method.
 func (in *Influx) WriteTo(w BatchPointsWriter) (err error) {
+	in.gaugeFuncs.Sample()
+
 	bp, err := influxdb.NewBatchPoints(in.conf)
 	if err != nil {
 		return err
@@ -2,7 +2,11 @@ package influx
 
 import (
 	"bytes"
+	"compress/gzip"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"regexp"
 	"strconv"
 	"strings"
@@ -123,3 +127,69 @@ func (w *bufWriter) Write(bp influxdb.BatchPoints) error {
 	}
 	return nil
 }
+
+func TestV2Writer(t *testing.T) {
+	var (
+		path, query, auth, encoding string
+		bodies                      []string
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, query, auth, encoding = r.URL.Path, r.URL.RawQuery, r.Header.Get("Authorization"), r.Header.Get("Content-Encoding")
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		body, _ := ioutil.ReadAll(zr)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer s.Close()
+
+	in := New(map[string]string{"a": "b"}, influxdb.BatchPointsConfig{Precision: "s"}, log.NewNopLogger())
+	in.NewCounter("c1").Add(1)
+	in.NewCounter("c2").Add(2)
+	in.NewCounter("c3").Add(3)
+	w := NewV2Writer(s.URL+"/", "acme", "metrics", "secret", WithV2Gzip(), WithV2BatchSize(2))
+	if err := in.WriteTo(w); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, have := "/api/v2/write", path; want != have {
+		t.Errorf("path: want %q, have %q", want, have)
+	}
+	if want, have := "bucket=metrics&org=acme&precision=s", query; want != have {
+		t.Errorf("query: want %q, have %q", want, have)
+	}
+	if want, have := "Token secret", auth; want != have {
+		t.Errorf("authorization: want %q, have %q", want, have)
+	}
+	if want, have := "gzip", encoding; want != have {
+		t.Errorf("content encoding: want %q, have %q", want, have)
+	}
+	if want, have := 2, len(bodies); want != have {
+		t.Fatalf("want %d requests, have %d", want, have)
+	}
+	re := regexp.MustCompile(`^c[123],a=b count=[123] [0-9]{10}$`)
+	for _, body := range bodies {
+		for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+			if !re.MatchString(line) {
+				t.Errorf("unexpected line %q", line)
+			}
+		}
+	}
+}
+
+func TestV2WriterError(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"code":"unauthorized"}`, http.StatusUnauthorized)
+	}))
+	defer s.Close()
+
+	in := New(nil, influxdb.BatchPointsConfig{}, log.NewNopLogger())
+	in.NewCounter("c").Add(1)
+	err := in.WriteTo(NewV2Writer(s.URL, "acme", "metrics", "wrong"))
+	if err == nil || !strings.Contains(err.Error(), "unauthorized") {
+		t.Errorf("want unauthorized error, have %v", err)
+	}
+}
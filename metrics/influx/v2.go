@@ -0,0 +1,162 @@
+package influx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	influxdb "github.com/influxdata/influxdb1-client/v2"
+)
+
+// DefaultV2BatchSize is the number of points written per request by a
+// V2Writer, unless overridden with WithV2BatchSize. It's the batch size
+// recommended by InfluxData.
+const DefaultV2BatchSize = 5000
+
+// HTTPClient is an interface that models *http.Client.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// V2Writer is a BatchPointsWriter which writes points to the /api/v2/write
+// endpoint of InfluxDB 2.x, or InfluxDB Cloud, in line protocol. Pass it to
+// the WriteTo or WriteLoop methods of an Influx object. The database and
+// retention policy of the batch points are ignored; points are written to the
+// bucket of the V2Writer. Their precision is honored.
+type V2Writer struct {
+	url       string
+	org       string
+	bucket    string
+	token     string
+	client    HTTPClient
+	gzip      bool
+	batchSize int
+}
+
+// V2Option sets an optional parameter for V2Writers.
+type V2Option func(*V2Writer)
+
+// WithV2HTTPClient sets the client used to write points. By default,
+// http.DefaultClient is used.
+func WithV2HTTPClient(client HTTPClient) V2Option {
+	return func(w *V2Writer) { w.client = client }
+}
+
+// WithV2Gzip compresses the body of each write request with gzip, which
+// typically shrinks line protocol by an order of magnitude.
+func WithV2Gzip() V2Option {
+	return func(w *V2Writer) { w.gzip = true }
+}
+
+// WithV2BatchSize sets the maximum number of points written per request. By
+// default, DefaultV2BatchSize is used.
+func WithV2BatchSize(n int) V2Option {
+	return func(w *V2Writer) { w.batchSize = n }
+}
+
+// NewV2Writer returns a V2Writer which writes to the bucket of the
+// organization of the InfluxDB server at the base URL, e.g.
+// http://localhost:8086, authenticating with the API token.
+func NewV2Writer(baseURL, org, bucket, token string, options ...V2Option) *V2Writer {
+	w := &V2Writer{
+		url:       strings.TrimSuffix(baseURL, "/") + "/api/v2/write",
+		org:       org,
+		bucket:    bucket,
+		token:     token,
+		client:    http.DefaultClient,
+		batchSize: DefaultV2BatchSize,
+	}
+	for _, option := range options {
+		option(w)
+	}
+	return w
+}
+
+// Write implements BatchPointsWriter.
+func (w *V2Writer) Write(bp influxdb.BatchPoints) error {
+	precision, err := v2Precision(bp.Precision())
+	if err != nil {
+		return err
+	}
+	points := bp.Points()
+	for len(points) > 0 {
+		n := len(points)
+		if w.batchSize > 0 && n > w.batchSize {
+			n = w.batchSize
+		}
+		if err := w.write(points[:n], precision); err != nil {
+			return err
+		}
+		points = points[n:]
+	}
+	return nil
+}
+
+func (w *V2Writer) write(points []*influxdb.Point, precision string) error {
+	var buf bytes.Buffer
+	var body io.Writer = &buf
+	var zw *gzip.Writer
+	if w.gzip {
+		zw = gzip.NewWriter(&buf)
+		body = zw
+	}
+	for _, p := range points {
+		if _, err := io.WriteString(body, p.PrecisionString(precision)+"\n"); err != nil {
+			return err
+		}
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return err
+		}
+	}
+
+	query := url.Values{
+		"org":       {w.org},
+		"bucket":    {w.bucket},
+		"precision": {precision},
+	}
+	req, err := http.NewRequest("POST", w.url+"?"+query.Encode(), &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Token "+w.token)
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if w.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("InfluxDB responded %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
+
+// v2Precision maps the precision of batch points to that of the v2 API,
+// which supports ns, us, ms and s.
+func v2Precision(precision string) (string, error) {
+	switch precision {
+	case "", "n", "ns":
+		return "ns", nil
+	case "u", "us", "µs":
+		return "us", nil
+	case "ms":
+		return "ms", nil
+	case "s":
+		return "s", nil
+	default:
+		return "", fmt.Errorf("precision %q isn't supported by the InfluxDB v2 API", precision)
+	}
+}
@@ -14,6 +14,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"math"
+	"math/rand"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -21,6 +23,9 @@ import (
 
 	"github.com/barrett370/kit/v2/metrics"
 	"github.com/barrett370/kit/v2/metrics/generic"
+	"github.com/barrett370/kit/v2/metrics/internal/buffer"
+	"github.com/barrett370/kit/v2/metrics/internal/gaugefunc"
+	"github.com/barrett370/kit/v2/metrics/internal/loop"
 	"github.com/barrett370/kit/v2/metrics/internal/lv"
 	"github.com/barrett370/kit/v2/metrics/internal/ratemap"
 	"github.com/barrett370/kit/v2/util/conn"
@@ -38,15 +43,22 @@ import (
 // To regularly report metrics to an io.Writer, use the WriteLoop helper method.
 // To send to a InfluxStatsD server, use the SendLoop helper method.
 type Influxstatsd struct {
-	mtx        sync.RWMutex
-	prefix     string
-	rates      *ratemap.RateMap
-	counters   *lv.Space
-	gauges     map[string]*gaugeNode
-	timings    *lv.Space
-	histograms *lv.Space
-	logger     log.Logger
-	lvs        lv.LabelValues
+	mtx           sync.RWMutex
+	prefix        string
+	rates         *ratemap.RateMap
+	counters      *lv.Space
+	gauges        map[string]*gaugeNode
+	timings       *lv.Space
+	histograms    *lv.Space
+	logger        log.Logger
+	lvs           lv.LabelValues
+	maxPacketSize int
+	aggregate     bool
+	maxSamples    int
+	gaugeFuncs    gaugefunc.Set
+	buffer        *buffer.Buffer
+	flushFunc     metrics.FlushFunc
+	jitter        time.Duration
 }
 
 // New returns a Influxstatsd object that may be used to create metrics. Prefix is
@@ -68,12 +80,88 @@ func New(prefix string, logger log.Logger, lvs ...string) *Influxstatsd {
 	}
 }
 
+// Option sets an optional parameter for Influxstatsd objects constructed via
+// NewWithOptions.
+type Option func(*Influxstatsd)
+
+// WithLabelValues sets label values which are applied to all metrics, as the
+// variadic parameter of New does.
+func WithLabelValues(lvs ...string) Option {
+	if len(lvs)%2 != 0 {
+		panic("odd number of LabelValues; programmer error!")
+	}
+	return func(d *Influxstatsd) { d.lvs = append(d.lvs, lvs...) }
+}
+
+// WithMaxPacketSize batches the lines written by WriteTo into writes of at
+// most n bytes each, so that each packet sent over a datagram connection
+// carries as many lines as fit, without exceeding the MTU. See
+// conn.MaxUDPPacketSize and conn.MaxUDSPacketSize. By default, each line is
+// written separately.
+func WithMaxPacketSize(n int) Option {
+	return func(d *Influxstatsd) { d.maxPacketSize = n }
+}
+
+// WithAggregation aggregates the observations of each timing and histogram
+// per write, rather than writing a line per observation. Each timeseries is
+// written as four lines, with the suffixes .count and .sum as counters, which
+// Telegraf sums across writes, and .min and .max as gauges. That bounds the
+// size of a write by the number of timeseries, rather than observations, at
+// the expense of percentiles.
+func WithAggregation() Option {
+	return func(d *Influxstatsd) { d.aggregate = true }
+}
+
+// WithMaxSamples limits the number of observations written per timing and
+// histogram timeseries per write to n. If more were made, n of them are
+// selected at random, and written with a correspondingly lower sample rate,
+// so Telegraf's counts remain accurate. WithAggregation takes precedence.
+func WithMaxSamples(n int) Option {
+	return func(d *Influxstatsd) { d.maxSamples = n }
+}
+
+// WithBuffer queues observations of counters, timings and histograms in a
+// buffer of the given size, from which they're applied by a separate
+// goroutine, so that observing never blocks on a concurrent write. If the
+// buffer is full, observations are dropped, and counted; see Dropped. The
+// goroutine stops when WriteLoop or SendLoop returns, after the final write.
+// By default, observations are applied directly.
+func WithBuffer(size int) Option {
+	return func(d *Influxstatsd) { d.buffer = buffer.New(size) }
+}
+
+// WithFlushFunc sets a func which is called after each WriteTo, with the
+// number of bytes written, the duration of the write, and its error, if any,
+// e.g. one returned by metrics.NewFlushFunc. By default, failed writes are
+// only logged by the helper methods.
+func WithFlushFunc(f metrics.FlushFunc) Option {
+	return func(d *Influxstatsd) { d.flushFunc = f }
+}
+
+// WithJitter delays each write made by the loop helper methods by a random
+// offset in [0, jitter), chosen once when the loop starts, so that processes
+// started together don't all flush at the same instant. By default, there's
+// no delay.
+func WithJitter(jitter time.Duration) Option {
+	return func(d *Influxstatsd) { d.jitter = jitter }
+}
+
+// NewWithOptions is like New, but takes options rather than label values,
+// which may be set via WithLabelValues.
+func NewWithOptions(prefix string, logger log.Logger, options ...Option) *Influxstatsd {
+	d := New(prefix, logger)
+	for _, option := range options {
+		option(d)
+	}
+	return d
+}
+
 // NewCounter returns a counter, sending observations to this Influxstatsd object.
 func (d *Influxstatsd) NewCounter(name string, sampleRate float64) *Counter {
 	d.rates.Set(name, sampleRate)
 	return &Counter{
 		name: name,
-		obs:  d.counters.Observe,
+		obs:  d.buffered(d.counters.Observe),
 	}
 }
 
@@ -89,13 +177,20 @@ func (d *Influxstatsd) NewGauge(name string) *Gauge {
 	return n.gauge
 }
 
+// NewGaugeFunc adds a gauge whose value is sampled from f immediately before
+// each write, for values such as queue depth which aren't naturally
+// event-driven. The label values are applied to the gauge via With.
+func (d *Influxstatsd) NewGaugeFunc(name string, f func() float64, labelValues ...string) {
+	d.gaugeFuncs.Add(d.NewGauge(name).With(labelValues...), f)
+}
+
 // NewTiming returns a histogram whose observations are interpreted as
 // millisecond durations, and are forwarded to this Influxstatsd object.
 func (d *Influxstatsd) NewTiming(name string, sampleRate float64) *Timing {
 	d.rates.Set(name, sampleRate)
 	return &Timing{
 		name: name,
-		obs:  d.timings.Observe,
+		obs:  d.buffered(d.timings.Observe),
 	}
 }
 
@@ -105,34 +200,60 @@ func (d *Influxstatsd) NewHistogram(name string, sampleRate float64) *Histogram
 	d.rates.Set(name, sampleRate)
 	return &Histogram{
 		name: name,
-		obs:  d.histograms.Observe,
+		obs:  d.buffered(d.histograms.Observe),
+	}
+}
+
+// Dropped returns the number of observations dropped because the buffer set
+// by WithBuffer was full. Without a buffer, it's always zero.
+func (d *Influxstatsd) Dropped() uint64 {
+	if d.buffer == nil {
+		return 0
+	}
+	return d.buffer.Dropped()
+}
+
+func (d *Influxstatsd) buffered(obs observeFunc) observeFunc {
+	if d.buffer == nil {
+		return obs
 	}
+	return observeFunc(d.buffer.Wrap(buffer.ObserveFunc(obs)))
 }
 
 // WriteLoop is a helper method that invokes WriteTo to the passed writer every
 // time the passed channel fires. This method blocks until ctx is canceled,
 // so clients probably want to run it in its own goroutine. For typical
 // usage, create a time.Ticker and pass its C channel to this method.
+// When ctx is canceled, a final write is made, so that the observations
+// made since the last tick aren't lost.
 func (d *Influxstatsd) WriteLoop(ctx context.Context, c <-chan time.Time, w io.Writer) {
-	for {
-		select {
-		case <-c:
-			if _, err := d.WriteTo(w); err != nil {
-				d.logger.Log("during", "WriteTo", "err", err)
-			}
-		case <-ctx.Done():
-			return
-		}
-	}
+	d.writeLoop(ctx, c, w, d.maxPacketSize)
 }
 
 // SendLoop is a helper method that wraps WriteLoop, passing a managed
 // connection to the network and address. Like WriteLoop, this method blocks
 // until ctx is canceled, so clients probably want to start it in its own
 // goroutine. For typical usage, create a time.Ticker and pass its C channel to
-// this method.
+// this method. Unless WithMaxPacketSize is given, lines sent over a datagram
+// network are batched into packets of the conventional maximum size for the
+// network; see conn.MaxPacketSize.
 func (d *Influxstatsd) SendLoop(ctx context.Context, c <-chan time.Time, network, address string) {
-	d.WriteLoop(ctx, c, conn.NewDefaultManager(network, address, d.logger))
+	size := d.maxPacketSize
+	if size == 0 {
+		size = conn.MaxPacketSize(network)
+	}
+	d.writeLoop(ctx, c, conn.NewDefaultManager(network, address, d.logger), size)
+}
+
+func (d *Influxstatsd) writeLoop(ctx context.Context, c <-chan time.Time, w io.Writer, maxPacketSize int) {
+	loop.Run(ctx, c, d.jitter, func(context.Context) {
+		if _, err := d.writeTo(w, maxPacketSize); err != nil {
+			d.logger.Log("during", "WriteTo", "err", err)
+		}
+	})
+	if d.buffer != nil {
+		d.buffer.Close()
+	}
 }
 
 // WriteTo flushes the buffered content of the metrics to the writer, in
@@ -140,9 +261,35 @@ func (d *Influxstatsd) SendLoop(ctx context.Context, c <-chan time.Time, network
 // lost if there is a problem with the write. Clients should be sure to call
 // WriteTo regularly, ideally through the WriteLoop or SendLoop helper methods.
 func (d *Influxstatsd) WriteTo(w io.Writer) (count int64, err error) {
+	return d.writeTo(w, d.maxPacketSize)
+}
+
+func (d *Influxstatsd) writeTo(w io.Writer, maxPacketSize int) (count int64, err error) {
+	if d.flushFunc != nil {
+		defer func(begin time.Time) { d.flushFunc(count, time.Since(begin), err) }(time.Now())
+	}
+	d.gaugeFuncs.Sample()
+	if d.buffer != nil {
+		d.buffer.Flush()
+	}
+
+	// Swap out the observations of every space at once, before any time is
+	// spent writing, so they're all attributed to this write.
+	counters, timings, histograms := d.counters.Reset(), d.timings.Reset(), d.histograms.Reset()
+
 	var n int
 
-	d.counters.Reset().Walk(func(name string, lvs lv.LabelValues, values []float64) bool {
+	if maxPacketSize > 0 {
+		pw := conn.NewPacketWriter(w, maxPacketSize)
+		defer func() {
+			if ferr := pw.Flush(); err == nil {
+				err = ferr
+			}
+		}()
+		w = pw
+	}
+
+	counters.Walk(func(name string, lvs lv.LabelValues, values []float64) bool {
 		n, err = fmt.Fprintf(w, "%s%s%s:%f|c%s\n", d.prefix, name, d.tagValues(lvs), sum(values), sampling(d.rates.Get(name)))
 		if err != nil {
 			return false
@@ -167,31 +314,21 @@ func (d *Influxstatsd) WriteTo(w io.Writer) (count int64, err error) {
 	}
 	d.mtx.RUnlock()
 
-	d.timings.Reset().Walk(func(name string, lvs lv.LabelValues, values []float64) bool {
-		sampleRate := d.rates.Get(name)
-		for _, value := range values {
-			n, err = fmt.Fprintf(w, "%s%s%s:%f|ms%s\n", d.prefix, name, d.tagValues(lvs), value, sampling(sampleRate))
-			if err != nil {
-				return false
-			}
-			count += int64(n)
-		}
-		return true
+	timings.Walk(func(name string, lvs lv.LabelValues, values []float64) bool {
+		var c int64
+		c, err = d.writeObservations(w, name, lvs, values, "ms")
+		count += c
+		return err == nil
 	})
 	if err != nil {
 		return count, err
 	}
 
-	d.histograms.Reset().Walk(func(name string, lvs lv.LabelValues, values []float64) bool {
-		sampleRate := d.rates.Get(name)
-		for _, value := range values {
-			n, err = fmt.Fprintf(w, "%s%s%s:%f|h%s\n", d.prefix, name, d.tagValues(lvs), value, sampling(sampleRate))
-			if err != nil {
-				return false
-			}
-			count += int64(n)
-		}
-		return true
+	histograms.Walk(func(name string, lvs lv.LabelValues, values []float64) bool {
+		var c int64
+		c, err = d.writeObservations(w, name, lvs, values, "h")
+		count += c
+		return err == nil
 	})
 	if err != nil {
 		return count, err
@@ -200,6 +337,63 @@ func (d *Influxstatsd) WriteTo(w io.Writer) (count int64, err error) {
 	return count, err
 }
 
+// writeObservations writes the observations of a timing or histogram
+// timeseries, of the given type, as configured by WithAggregation and
+// WithMaxSamples.
+func (d *Influxstatsd) writeObservations(w io.Writer, name string, lvs lv.LabelValues, values []float64, typ string) (count int64, err error) {
+	var (
+		n          int
+		tags       = d.tagValues(lvs)
+		sampleRate = d.rates.Get(name)
+	)
+
+	if d.aggregate {
+		lo, hi := math.Inf(+1), math.Inf(-1)
+		for _, v := range values {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+		for _, line := range []struct {
+			suffix, typ, sampling string
+			value                 float64
+		}{
+			{".count", "c", sampling(sampleRate), float64(len(values))},
+			{".sum", "c", sampling(sampleRate), sum(values)},
+			{".min", "g", "", lo},
+			{".max", "g", "", hi},
+		} {
+			n, err = fmt.Fprintf(w, "%s%s%s%s:%f|%s%s\n", d.prefix, name, line.suffix, tags, line.value, line.typ, line.sampling)
+			if err != nil {
+				return count, err
+			}
+			count += int64(n)
+		}
+		return count, nil
+	}
+
+	if d.maxSamples > 0 && len(values) > d.maxSamples {
+		sampleRate *= float64(d.maxSamples) / float64(len(values))
+		values = sample(values, d.maxSamples)
+	}
+	for _, value := range values {
+		n, err = fmt.Fprintf(w, "%s%s%s:%f|%s%s\n", d.prefix, name, tags, value, typ, sampling(sampleRate))
+		if err != nil {
+			return count, err
+		}
+		count += int64(n)
+	}
+	return count, nil
+}
+
+// sample returns n of the values, selected at random.
+func sample(values []float64, n int) []float64 {
+	s := append([]float64{}, values...)
+	for i := 0; i < n; i++ {
+		j := i + rand.Intn(len(s)-i)
+		s[i], s[j] = s[j], s[i]
+	}
+	return s[:n]
+}
+
 func sum(a []float64) float64 {
 	var v float64
 	for _, f := range a {
@@ -1,6 +1,9 @@
 package influxstatsd
 
 import (
+	"bytes"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/barrett370/kit/v2/metrics/teststat"
@@ -88,3 +91,74 @@ func TestTimingSampled(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+type packetRecorder struct{ packets []string }
+
+func (r *packetRecorder) Write(p []byte) (int, error) {
+	r.packets = append(r.packets, string(p))
+	return len(p), nil
+}
+
+func TestMaxPacketSize(t *testing.T) {
+	s := NewWithOptions("abc.", log.NewNopLogger(), WithMaxPacketSize(30))
+	for i := 0; i < 10; i++ {
+		s.NewCounter("c"+strconv.Itoa(i), 1.0).Add(1)
+	}
+	var r packetRecorder
+	if _, err := s.WriteTo(&r); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.packets) < 2 {
+		t.Fatalf("want lines split across packets, have %q", r.packets)
+	}
+	var lines int
+	for _, p := range r.packets {
+		if len(p) > 30 {
+			t.Errorf("packet of %d bytes exceeds the maximum: %q", len(p), p)
+		}
+		lines += strings.Count(p, "\n")
+	}
+	if want, have := 10, lines; want != have {
+		t.Errorf("want %d lines, have %d", want, have)
+	}
+}
+
+func TestAggregation(t *testing.T) {
+	d := NewWithOptions("abc.", log.NewNopLogger(), WithAggregation())
+	h := d.NewTiming("def", 1.0).With("ghi", "jkl")
+	for _, v := range []float64{3, 1, 2} {
+		h.Observe(v)
+	}
+	var buf bytes.Buffer
+	if _, err := d.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "abc.def.count,ghi=jkl:3.000000|c\n" +
+		"abc.def.sum,ghi=jkl:6.000000|c\n" +
+		"abc.def.min,ghi=jkl:1.000000|g\n" +
+		"abc.def.max,ghi=jkl:3.000000|g\n"
+	if have := buf.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestMaxSamples(t *testing.T) {
+	d := NewWithOptions("abc.", log.NewNopLogger(), WithMaxSamples(10))
+	h := d.NewHistogram("def", 0.5)
+	for i := 0; i < 40; i++ {
+		h.Observe(float64(i))
+	}
+	var buf bytes.Buffer
+	if _, err := d.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if want, have := 10, len(lines); want != have {
+		t.Fatalf("want %d lines, have %d", want, have)
+	}
+	for _, line := range lines {
+		if !strings.HasSuffix(line, "|h|@0.125000") {
+			t.Errorf("want sample rate 0.125, have %q", line)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Package buffer provides a bounded, non-blocking queue of observations, for
+// backends which aggregate observations in an lv.Space.
+package buffer
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/barrett370/kit/v2/metrics/internal/lv"
+)
+
+// ObserveFunc records an observation, e.g. lv.Space.Observe.
+type ObserveFunc func(name string, lvs lv.LabelValues, value float64)
+
+// Buffer is a bounded queue of observations, which are applied by a single
+// goroutine. Observers never block: if the queue is full, the observation is
+// dropped and counted. Buffer moves contention on the Space off of the
+// observers' hot path.
+type Buffer struct {
+	c       chan observation
+	dropped uint64
+	closed  uint32
+	once    sync.Once
+	quit    chan struct{}
+	done    chan struct{}
+}
+
+type observation struct {
+	f       ObserveFunc
+	name    string
+	lvs     lv.LabelValues
+	value   float64
+	flushed chan struct{}
+}
+
+// New returns a Buffer with room for size observations, and starts the
+// goroutine which applies them. Close stops the goroutine.
+func New(size int) *Buffer {
+	b := &Buffer{
+		c:    make(chan observation, size),
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go b.loop()
+	return b
+}
+
+func (b *Buffer) loop() {
+	defer close(b.done)
+	for {
+		select {
+		case o := <-b.c:
+			o.apply()
+		case <-b.quit:
+			for {
+				select {
+				case o := <-b.c:
+					o.apply()
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (o observation) apply() {
+	if o.flushed != nil {
+		close(o.flushed)
+		return
+	}
+	o.f(o.name, o.lvs, o.value)
+}
+
+// Wrap returns an ObserveFunc which queues each observation, to be applied
+// to f by the Buffer's goroutine.
+func (b *Buffer) Wrap(f ObserveFunc) ObserveFunc {
+	return func(name string, lvs lv.LabelValues, value float64) {
+		if atomic.LoadUint32(&b.closed) == 1 {
+			atomic.AddUint64(&b.dropped, 1)
+			return
+		}
+		select {
+		case b.c <- observation{f: f, name: name, lvs: lvs, value: value}:
+		default:
+			atomic.AddUint64(&b.dropped, 1)
+		}
+	}
+}
+
+// Flush blocks until every observation queued before the call has been
+// applied. Backends call it before writing, so the write includes them.
+// After Close, it returns immediately.
+func (b *Buffer) Flush() {
+	flushed := make(chan struct{})
+	select {
+	case b.c <- observation{flushed: flushed}:
+	case <-b.done:
+		return
+	}
+	select {
+	case <-flushed:
+	case <-b.done:
+	}
+}
+
+// Close applies the observations already queued, and stops the Buffer's
+// goroutine, returning once it has exited. Observations made afterwards are
+// dropped and counted. Backends close their buffer when their write loop
+// ends, after its final write.
+func (b *Buffer) Close() {
+	b.once.Do(func() {
+		atomic.StoreUint32(&b.closed, 1)
+		close(b.quit)
+	})
+	<-b.done
+}
+
+// Dropped returns the number of observations dropped because the queue was
+// full, or the Buffer was closed.
+func (b *Buffer) Dropped() uint64 {
+	return atomic.LoadUint64(&b.dropped)
+}
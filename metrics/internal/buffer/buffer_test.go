@@ -0,0 +1,80 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/barrett370/kit/v2/metrics/internal/lv"
+)
+
+func TestBuffer(t *testing.T) {
+	var (
+		b     = New(16)
+		s     = lv.NewSpace()
+		obs   = b.Wrap(s.Observe)
+		count = 10
+	)
+	for i := 0; i < count; i++ {
+		obs("a", lv.LabelValues{"k", "v"}, 1)
+	}
+	b.Flush()
+
+	var sum float64
+	s.Reset().Walk(func(_ string, _ lv.LabelValues, values []float64) bool {
+		for _, v := range values {
+			sum += v
+		}
+		return true
+	})
+	if want, have := float64(count), sum; want != have {
+		t.Errorf("want %f, have %f", want, have)
+	}
+	if want, have := uint64(0), b.Dropped(); want != have {
+		t.Errorf("Dropped: want %d, have %d", want, have)
+	}
+}
+
+func TestBufferDrops(t *testing.T) {
+	var (
+		b       = New(1)
+		block   = make(chan struct{})
+		applied = make(chan struct{}, 2)
+		obs     = b.Wrap(func(string, lv.LabelValues, float64) { applied <- struct{}{}; <-block })
+	)
+	obs("a", nil, 1) // taken by the goroutine, which blocks applying it
+	<-applied
+	obs("a", nil, 2) // queued
+	obs("a", nil, 3) // dropped
+	close(block)
+	b.Flush()
+	if want, have := uint64(1), b.Dropped(); want != have {
+		t.Errorf("Dropped: want %d, have %d", want, have)
+	}
+}
+
+func TestBufferClose(t *testing.T) {
+	var (
+		b   = New(16)
+		s   = lv.NewSpace()
+		obs = b.Wrap(s.Observe)
+	)
+	obs("a", nil, 1)
+	obs("a", nil, 2)
+	b.Close()
+	b.Close() // idempotent
+	obs("a", nil, 3)
+	b.Flush() // doesn't block
+
+	var sum float64
+	s.Reset().Walk(func(_ string, _ lv.LabelValues, values []float64) bool {
+		for _, v := range values {
+			sum += v
+		}
+		return true
+	})
+	if want, have := 3.0, sum; want != have {
+		t.Errorf("want %f, have %f", want, have)
+	}
+	if want, have := uint64(1), b.Dropped(); want != have {
+		t.Errorf("Dropped: want %d, have %d", want, have)
+	}
+}
@@ -0,0 +1,34 @@
+// Package gaugefunc provides a set of gauges whose values are sampled from
+// funcs, for backends which push their metrics.
+package gaugefunc
+
+import (
+	"sync"
+
+	"github.com/barrett370/kit/v2/metrics"
+)
+
+// Set is a set of gauges whose values are sampled from funcs. Backends call
+// Sample before each write, so the gauges carry the current values.
+type Set struct {
+	mtx    sync.Mutex
+	gauges []metrics.Gauge
+	funcs  []func() float64
+}
+
+// Add adds the gauge to the set, to be set from f whenever the set is sampled.
+func (s *Set) Add(g metrics.Gauge, f func() float64) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.gauges = append(s.gauges, g)
+	s.funcs = append(s.funcs, f)
+}
+
+// Sample invokes each func, and sets its gauge to the returned value.
+func (s *Set) Sample() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for i, f := range s.funcs {
+		s.gauges[i].Set(f())
+	}
+}
@@ -0,0 +1,28 @@
+package gaugefunc
+
+import (
+	"testing"
+
+	"github.com/barrett370/kit/v2/metrics/generic"
+)
+
+func TestSample(t *testing.T) {
+	var (
+		s     Set
+		g     = generic.NewGauge("depth")
+		depth = 3.0
+	)
+	s.Add(g, func() float64 { return depth })
+	if want, have := 0.0, g.Value(); want != have {
+		t.Errorf("before Sample: want %f, have %f", want, have)
+	}
+	s.Sample()
+	if want, have := 3.0, g.Value(); want != have {
+		t.Errorf("want %f, have %f", want, have)
+	}
+	depth = 5
+	s.Sample()
+	if want, have := 5.0, g.Value(); want != have {
+		t.Errorf("want %f, have %f", want, have)
+	}
+}
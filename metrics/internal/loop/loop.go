@@ -0,0 +1,60 @@
+// Package loop provides the ticker-driven flush loop shared by the WriteLoop
+// and SendLoop helper methods of backends which push their metrics.
+package loop
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// FinalFlushTimeout bounds the final flush made by Run once its context is
+// canceled.
+const FinalFlushTimeout = 5 * time.Second
+
+// Run calls flush with ctx every time c fires, until ctx is canceled, and then
+// once more, so that observations made since the last tick aren't discarded.
+// As ctx is canceled by then, the final flush is given a new context, which
+// times out after FinalFlushTimeout.
+//
+// If jitter is positive, a random offset in [0, jitter) is chosen when Run
+// starts, and each flush is delayed by that offset. That spreads the flushes
+// of many processes started at the same time, e.g. by a deployment, which
+// would otherwise all flush at the same instant.
+func Run(ctx context.Context, c <-chan time.Time, jitter time.Duration, flush func(context.Context)) {
+	var offset time.Duration
+	if jitter > 0 {
+		offset = time.Duration(rand.Int63n(int64(jitter)))
+	}
+	for {
+		select {
+		case <-c:
+			if offset > 0 && !sleep(ctx, offset) {
+				final(flush)
+				return
+			}
+			flush(ctx)
+		case <-ctx.Done():
+			final(flush)
+			return
+		}
+	}
+}
+
+func final(flush func(context.Context)) {
+	ctx, cancel := context.WithTimeout(context.Background(), FinalFlushTimeout)
+	defer cancel()
+	flush(ctx)
+}
+
+// sleep waits for d, and returns false if ctx is canceled first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,78 @@
+package loop
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	var (
+		ctx, cancel = context.WithCancel(context.Background())
+		c           = make(chan time.Time)
+		flushes     = make(chan struct{}, 10)
+		done        = make(chan struct{})
+	)
+	go func() {
+		defer close(done)
+		Run(ctx, c, 0, func(context.Context) { flushes <- struct{}{} })
+	}()
+
+	c <- time.Now()
+	c <- time.Now()
+	cancel()
+	<-done
+
+	if want, have := 3, len(flushes); want != have {
+		t.Errorf("want %d flushes, have %d", want, have)
+	}
+}
+
+func TestRunJitter(t *testing.T) {
+	var (
+		ctx, cancel = context.WithCancel(context.Background())
+		c           = make(chan time.Time, 1)
+		flushed     = make(chan time.Time, 10)
+		done        = make(chan struct{})
+		jitter      = 50 * time.Millisecond
+	)
+	go func() {
+		defer close(done)
+		Run(ctx, c, jitter, func(context.Context) { flushed <- time.Now() })
+	}()
+
+	tick := time.Now()
+	c <- tick
+	select {
+	case at := <-flushed:
+		if delay := at.Sub(tick); delay > jitter+50*time.Millisecond {
+			t.Errorf("flush delayed by %s, want less than %s", delay, jitter)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for flush")
+	}
+
+	cancel()
+	<-done
+	if want, have := 1, len(flushed); want != have {
+		t.Errorf("want %d final flush, have %d", want, have)
+	}
+}
+
+func TestRunFinalContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var flushes int
+	Run(ctx, nil, 0, func(ctx context.Context) {
+		flushes++
+		if err := ctx.Err(); err != nil {
+			t.Errorf("final flush context: %v", err)
+		}
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("final flush context has no deadline")
+		}
+	})
+	if want, have := 1, flushes; want != have {
+		t.Errorf("want %d flushes, have %d", want, have)
+	}
+}
@@ -11,13 +11,16 @@ func NewSpace() *Space {
 // value pair establishes a new dimension and point within that dimension. Order
 // matters, i.e. [a=1 b=2] identifies a different timeseries than [b=2 a=1].
 type Space struct {
-	mtx   sync.RWMutex
-	nodes map[string]*node
+	mtx      sync.RWMutex // held for writing by Reset, and reading by the rest
+	nodesMtx sync.RWMutex // protects nodes from concurrent observations
+	nodes    map[string]*node
 }
 
 // Observe locates the time series identified by the name and label values in
 // the vector space, and appends the value to the list of observations.
 func (s *Space) Observe(name string, lvs LabelValues, value float64) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
 	s.nodeFor(name).observe(lvs, value)
 }
 
@@ -25,6 +28,8 @@ func (s *Space) Observe(name string, lvs LabelValues, value float64) {
 // the vector space, and appends the delta to the last value in the list of
 // observations.
 func (s *Space) Add(name string, lvs LabelValues, delta float64) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
 	s.nodeFor(name).add(lvs, delta)
 }
 
@@ -33,7 +38,13 @@ func (s *Space) Add(name string, lvs LabelValues, delta float64) {
 func (s *Space) Walk(fn func(name string, lvs LabelValues, observations []float64) bool) {
 	s.mtx.RLock()
 	defer s.mtx.RUnlock()
+	s.nodesMtx.RLock()
+	nodes := make(map[string]*node, len(s.nodes))
 	for name, node := range s.nodes {
+		nodes[name] = node
+	}
+	s.nodesMtx.RUnlock()
+	for name, node := range nodes {
 		f := func(lvs LabelValues, observations []float64) bool { return fn(name, lvs, observations) }
 		if !node.walk(LabelValues{}, f) {
 			return
@@ -43,6 +54,10 @@ func (s *Space) Walk(fn func(name string, lvs LabelValues, observations []float6
 
 // Reset empties the current space and returns a new Space with the old
 // contents. Reset a Space to get an immutable copy suitable for walking.
+//
+// The swap is atomic with respect to Observe and Add: every observation is
+// recorded either in the returned Space, or in the emptied one, so none are
+// lost or attributed to the wrong interval when Reset is called concurrently.
 func (s *Space) Reset() *Space {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
@@ -51,13 +66,23 @@ func (s *Space) Reset() *Space {
 	return n
 }
 
+// nodeFor returns the node for the name, creating it if necessary. The
+// caller must hold a read lock on s.mtx, so that the node can't be swapped out
+// by Reset before the caller is done with it.
 func (s *Space) nodeFor(name string) *node {
-	s.mtx.Lock()
-	defer s.mtx.Unlock()
+	s.nodesMtx.RLock()
+	n, ok := s.nodes[name]
+	s.nodesMtx.RUnlock()
+	if ok {
+		return n
+	}
+
+	s.nodesMtx.Lock()
+	defer s.nodesMtx.Unlock()
 	if s.nodes == nil {
 		s.nodes = map[string]*node{}
 	}
-	n, ok := s.nodes[name]
+	n, ok = s.nodes[name]
 	if !ok {
 		n = &node{}
 		s.nodes[name] = n
@@ -2,6 +2,7 @@ package lv
 
 import (
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -83,3 +84,51 @@ func sum(a []float64) (v float64) {
 	}
 	return
 }
+
+func TestSpaceResetConcurrent(t *testing.T) {
+	var (
+		s          = NewSpace()
+		writers    = 8
+		perWriter  = 1000
+		wg         sync.WaitGroup
+		done       = make(chan struct{})
+		totalSum   float64
+		resetsDone = make(chan struct{})
+	)
+	sum := func(space *Space) {
+		space.Walk(func(_ string, _ LabelValues, obs []float64) bool {
+			for _, v := range obs {
+				totalSum += v
+			}
+			return true
+		})
+	}
+	go func() {
+		defer close(resetsDone)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				sum(s.Reset())
+			}
+		}
+	}()
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWriter; j++ {
+				s.Observe("a", LabelValues{"k", "v"}, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	<-resetsDone
+	sum(s.Reset())
+
+	if want, have := float64(writers*perWriter), totalSum; want != have {
+		t.Errorf("want %f, have %f", want, have)
+	}
+}
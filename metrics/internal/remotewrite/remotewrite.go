@@ -0,0 +1,142 @@
+// Package remotewrite implements the client side of the Prometheus remote
+// write protocol, i.e. snappy-compressed protobuf WriteRequests sent via HTTP
+// POST. Only the subset of the protocol needed to send samples is supported.
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+
+	"github.com/golang/snappy"
+)
+
+// Label is a name/value pair identifying a time series. The metric name is
+// carried in the label named "__name__".
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is a single value of a time series, at a timestamp in milliseconds
+// since the Unix epoch.
+type Sample struct {
+	Value     float64
+	Timestamp int64
+}
+
+// TimeSeries is a set of samples for a unique set of labels.
+type TimeSeries struct {
+	Labels  []Label
+	Samples []Sample
+}
+
+// SortLabels sorts the labels of the time series by name, as required by the
+// protocol.
+func (ts TimeSeries) SortLabels() {
+	sort.Slice(ts.Labels, func(i, j int) bool { return ts.Labels[i].Name < ts.Labels[j].Name })
+}
+
+// Marshal returns the protobuf encoding of a WriteRequest containing the
+// given time series.
+func Marshal(series []TimeSeries) []byte {
+	var b []byte
+	for _, ts := range series {
+		b = appendBytes(b, 1, marshalTimeSeries(ts))
+	}
+	return b
+}
+
+func marshalTimeSeries(ts TimeSeries) []byte {
+	var b []byte
+	for _, l := range ts.Labels {
+		var lb []byte
+		lb = appendBytes(lb, 1, []byte(l.Name))
+		lb = appendBytes(lb, 2, []byte(l.Value))
+		b = appendBytes(b, 1, lb)
+	}
+	for _, s := range ts.Samples {
+		var sb []byte
+		sb = appendTag(sb, 1, wireFixed64)
+		sb = appendFixed64(sb, math.Float64bits(s.Value))
+		sb = appendTag(sb, 2, wireVarint)
+		sb = appendVarint(sb, uint64(s.Timestamp))
+		b = appendBytes(b, 2, sb)
+	}
+	return b
+}
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+func appendTag(b []byte, field, wireType int) []byte {
+	return appendVarint(b, uint64(field)<<3|uint64(wireType))
+}
+
+func appendBytes(b []byte, field int, v []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = appendVarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendFixed64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+// Snappy returns the snappy block encoding of p, as required by the protocol.
+func Snappy(p []byte) []byte {
+	return snappy.Encode(nil, p)
+}
+
+// HTTPClient is the subset of *http.Client used to send write requests.
+type HTTPClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// Send encodes the time series as a WriteRequest and POSTs it to the URL. The
+// header, which may be nil, is added to the request, e.g. for authorization.
+// Responses other than 2xx are returned as errors.
+func Send(ctx context.Context, client HTTPClient, url string, header http.Header, series []TimeSeries) error {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(Snappy(Marshal(series))))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	for k, vs := range header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("remote write: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
@@ -0,0 +1,102 @@
+package remotewrite_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/snappy"
+
+	"github.com/barrett370/kit/v2/metrics/internal/remotewrite"
+)
+
+func TestMarshal(t *testing.T) {
+	have := remotewrite.Marshal([]remotewrite.TimeSeries{{
+		Labels:  []remotewrite.Label{{Name: "__name__", Value: "up"}},
+		Samples: []remotewrite.Sample{{Value: 1, Timestamp: 1000}},
+	}})
+	want := []byte{
+		0x0a, 0x1e, // timeseries, 30 bytes
+		0x0a, 0x0e, // label, 14 bytes
+		0x0a, 0x08, '_', '_', 'n', 'a', 'm', 'e', '_', '_',
+		0x12, 0x02, 'u', 'p',
+		0x12, 0x0c, // sample, 12 bytes
+		0x09, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf0, 0x3f, // 1.0
+		0x10, 0xe8, 0x07, // 1000
+	}
+	if !bytes.Equal(want, have) {
+		t.Errorf("want %x, have %x", want, have)
+	}
+}
+
+func TestSnappy(t *testing.T) {
+	for _, n := range []int{0, 1, 60, 61, 256, 257, 70000} {
+		p := bytes.Repeat([]byte{'x'}, n)
+		b := remotewrite.Snappy(p)
+		if want, have := p, unsnappy(t, b); !bytes.Equal(want, have) {
+			t.Errorf("%d bytes: round trip failed", n)
+		}
+		if n > 256 && len(b) >= n/10 {
+			t.Errorf("%d bytes: compressed to %d bytes, want fewer than %d", n, len(b), n/10)
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	var (
+		header http.Header
+		body   []byte
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer s.Close()
+
+	series := []remotewrite.TimeSeries{{
+		Labels:  []remotewrite.Label{{Name: "__name__", Value: "up"}},
+		Samples: []remotewrite.Sample{{Value: 1, Timestamp: 1000}},
+	}}
+	extra := http.Header{"Authorization": []string{"Bearer token"}}
+	if err := remotewrite.Send(context.Background(), http.DefaultClient, s.URL, extra, series); err != nil {
+		t.Fatal(err)
+	}
+	for k, want := range map[string]string{
+		"Authorization":                     "Bearer token",
+		"Content-Encoding":                  "snappy",
+		"Content-Type":                      "application/x-protobuf",
+		"X-Prometheus-Remote-Write-Version": "0.1.0",
+	} {
+		if have := header.Get(k); want != have {
+			t.Errorf("%s: want %q, have %q", k, want, have)
+		}
+	}
+	if want, have := remotewrite.Marshal(series), unsnappy(t, body); !bytes.Equal(want, have) {
+		t.Errorf("want %x, have %x", want, have)
+	}
+}
+
+func TestSendError(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer s.Close()
+
+	err := remotewrite.Send(context.Background(), http.DefaultClient, s.URL, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "out of order sample") {
+		t.Errorf("want error containing response body, have %v", err)
+	}
+}
+
+func unsnappy(t *testing.T, b []byte) []byte {
+	t.Helper()
+	p, err := snappy.Decode(nil, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
@@ -23,3 +23,40 @@ type Histogram interface {
 	With(labelValues ...string) Histogram
 	Observe(value float64)
 }
+
+// CounterWithExemplar is implemented by counters which can attach an
+// exemplar to an observation. An exemplar is a set of labels identifying an
+// example of the thing being counted, typically the trace_id (and optionally
+// span_id) of a traced request.
+type CounterWithExemplar interface {
+	Counter
+	AddWithExemplar(delta float64, exemplar map[string]string)
+}
+
+// HistogramWithExemplar is implemented by histograms which can attach an
+// exemplar to an observation. It's typically used to link a latency bucket to
+// the trace of a request which fell into it.
+type HistogramWithExemplar interface {
+	Histogram
+	ObserveWithExemplar(value float64, exemplar map[string]string)
+}
+
+// AddWithExemplar adds delta to the counter, attaching the exemplar if the
+// counter implements CounterWithExemplar.
+func AddWithExemplar(c Counter, delta float64, exemplar map[string]string) {
+	if ce, ok := c.(CounterWithExemplar); ok {
+		ce.AddWithExemplar(delta, exemplar)
+		return
+	}
+	c.Add(delta)
+}
+
+// ObserveWithExemplar observes the value, attaching the exemplar if the
+// histogram implements HistogramWithExemplar.
+func ObserveWithExemplar(h Histogram, value float64, exemplar map[string]string) {
+	if he, ok := h.(HistogramWithExemplar); ok {
+		he.ObserveWithExemplar(value, exemplar)
+		return
+	}
+	h.Observe(value)
+}
@@ -0,0 +1,187 @@
+// Package otel provides OpenTelemetry implementations for metrics. Counters,
+// gauges and histograms are instruments of a metric.Meter, typically from the
+// OpenTelemetry SDK's MeterProvider, so they're aggregated and exported by the
+// same pipeline, e.g. OTLP, as the service's other OpenTelemetry telemetry.
+//
+// Label values are recorded as string attributes. Aggregation, including the
+// buckets of histograms, is configured on the MeterProvider, e.g. with views,
+// rather than per metric. Gauges are observable gauges: the MeterProvider
+// reads the value last set for each set of label values when it collects.
+package otel
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/instrument"
+
+	"github.com/barrett370/kit/v2/metrics"
+	"github.com/barrett370/kit/v2/metrics/internal/lv"
+)
+
+// Counter implements Counter, via an OpenTelemetry Float64Counter.
+type Counter struct {
+	c   instrument.Float64Counter
+	lvs lv.LabelValues
+}
+
+// NewCounterFrom constructs a Float64Counter with the given name and options
+// from the meter, and returns a usable Counter object.
+func NewCounterFrom(meter metric.Meter, name string, options ...instrument.Float64Option) (*Counter, error) {
+	c, err := meter.Float64Counter(name, options...)
+	if err != nil {
+		return nil, err
+	}
+	return NewCounter(c), nil
+}
+
+// NewCounter wraps the Float64Counter and returns a usable Counter object.
+func NewCounter(c instrument.Float64Counter) *Counter {
+	return &Counter{
+		c: c,
+	}
+}
+
+// With implements Counter.
+func (c *Counter) With(labelValues ...string) metrics.Counter {
+	return &Counter{
+		c:   c.c,
+		lvs: c.lvs.With(labelValues...),
+	}
+}
+
+// Add implements Counter.
+func (c *Counter) Add(delta float64) {
+	c.c.Add(context.Background(), delta, attributes(c.lvs)...)
+}
+
+// Gauge implements Gauge, via an OpenTelemetry Float64ObservableGauge, which
+// observes the value last set for each set of label values.
+type Gauge struct {
+	values *gaugeValues
+	lvs    lv.LabelValues
+}
+
+type gaugeValues struct {
+	mtx    sync.Mutex
+	values map[attribute.Distinct]gaugeValue
+}
+
+type gaugeValue struct {
+	attrs []attribute.KeyValue
+	value float64
+}
+
+// NewGaugeFrom constructs a Float64ObservableGauge with the given name and
+// options from the meter, and returns a usable Gauge object.
+func NewGaugeFrom(meter metric.Meter, name string, options ...instrument.Float64ObserverOption) (*Gauge, error) {
+	values := &gaugeValues{values: map[attribute.Distinct]gaugeValue{}}
+	options = append(options, instrument.WithFloat64Callback(values.observe))
+	if _, err := meter.Float64ObservableGauge(name, options...); err != nil {
+		return nil, err
+	}
+	return &Gauge{
+		values: values,
+	}, nil
+}
+
+// NewGaugeFuncFrom constructs a Float64ObservableGauge with the given name
+// from the meter, which observes the value returned by f, with the given label
+// values, whenever the MeterProvider collects.
+func NewGaugeFuncFrom(meter metric.Meter, name string, f func() float64, labelValues ...string) error {
+	attrs := attributes(lv.LabelValues{}.With(labelValues...))
+	_, err := meter.Float64ObservableGauge(name, instrument.WithFloat64Callback(func(_ context.Context, o instrument.Float64Observer) error {
+		o.Observe(f(), attrs...)
+		return nil
+	}))
+	return err
+}
+
+// With implements Gauge.
+func (g *Gauge) With(labelValues ...string) metrics.Gauge {
+	return &Gauge{
+		values: g.values,
+		lvs:    g.lvs.With(labelValues...),
+	}
+}
+
+// Set implements Gauge.
+func (g *Gauge) Set(value float64) {
+	g.values.update(g.lvs, func(float64) float64 { return value })
+}
+
+// Add implements Gauge.
+func (g *Gauge) Add(delta float64) {
+	g.values.update(g.lvs, func(v float64) float64 { return v + delta })
+}
+
+func (v *gaugeValues) update(lvs lv.LabelValues, f func(float64) float64) {
+	attrs := attributes(lvs)
+	set := attribute.NewSet(attrs...)
+	key := set.Equivalent()
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	gv, ok := v.values[key]
+	if !ok {
+		gv.attrs = attrs
+	}
+	gv.value = f(gv.value)
+	v.values[key] = gv
+}
+
+func (v *gaugeValues) observe(_ context.Context, o instrument.Float64Observer) error {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	for _, gv := range v.values {
+		o.Observe(gv.value, gv.attrs...)
+	}
+	return nil
+}
+
+// Histogram implements Histogram, via an OpenTelemetry Float64Histogram. Its
+// buckets are those of the MeterProvider's aggregation for the instrument.
+type Histogram struct {
+	h   instrument.Float64Histogram
+	lvs lv.LabelValues
+}
+
+// NewHistogramFrom constructs a Float64Histogram with the given name and
+// options from the meter, and returns a usable Histogram object.
+func NewHistogramFrom(meter metric.Meter, name string, options ...instrument.Float64Option) (*Histogram, error) {
+	h, err := meter.Float64Histogram(name, options...)
+	if err != nil {
+		return nil, err
+	}
+	return NewHistogram(h), nil
+}
+
+// NewHistogram wraps the Float64Histogram and returns a usable Histogram
+// object.
+func NewHistogram(h instrument.Float64Histogram) *Histogram {
+	return &Histogram{
+		h: h,
+	}
+}
+
+// With implements Histogram.
+func (h *Histogram) With(labelValues ...string) metrics.Histogram {
+	return &Histogram{
+		h:   h.h,
+		lvs: h.lvs.With(labelValues...),
+	}
+}
+
+// Observe implements Histogram.
+func (h *Histogram) Observe(value float64) {
+	h.h.Record(context.Background(), value, attributes(h.lvs)...)
+}
+
+func attributes(lvs lv.LabelValues) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(lvs)/2)
+	for i := 0; i < len(lvs); i += 2 {
+		attrs = append(attrs, attribute.String(lvs[i], lvs[i+1]))
+	}
+	return attrs
+}
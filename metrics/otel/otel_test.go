@@ -0,0 +1,137 @@
+package otel
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/barrett370/kit/v2/metrics/teststat"
+)
+
+func TestCounter(t *testing.T) {
+	reader, meter := newMeter()
+	counter, err := NewCounterFrom(meter, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := func() float64 {
+		points := collect(t, reader, "foo").(metricdata.Sum[float64]).DataPoints
+		return pointFor(t, points, "alpha", "alpha-value", "beta", "beta-value").Value
+	}
+	counter = counter.With("beta", "beta-value", "alpha", "alpha-value").(*Counter) // order shouldn't matter
+	if err := teststat.TestCounter(counter, value); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGauge(t *testing.T) {
+	reader, meter := newMeter()
+	gauge, err := NewGaugeFrom(meter, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gauge.With("beta", "other").Set(-1)
+
+	value := func() []float64 {
+		points := collect(t, reader, "foo").(metricdata.Gauge[float64]).DataPoints
+		return []float64{pointFor(t, points, "alpha", "alpha-value").Value}
+	}
+	if err := teststat.TestGauge(gauge.With("alpha", "alpha-value"), value); err != nil {
+		t.Fatal(err)
+	}
+
+	points := collect(t, reader, "foo").(metricdata.Gauge[float64]).DataPoints
+	if want, have := -1.0, pointFor(t, points, "beta", "other").Value; want != have {
+		t.Errorf("want %f, have %f", want, have)
+	}
+}
+
+func TestGaugeFunc(t *testing.T) {
+	reader, meter := newMeter()
+	var depth float64
+	if err := NewGaugeFuncFrom(meter, "queue_depth", func() float64 { return depth }, "queue", "jobs"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []float64{0, 12, 3} {
+		depth = want
+		points := collect(t, reader, "queue_depth").(metricdata.Gauge[float64]).DataPoints
+		if have := pointFor(t, points, "queue", "jobs").Value; want != have {
+			t.Errorf("want %f, have %f", want, have)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	reader, meter := newMeter()
+	histogram, err := NewHistogramFrom(meter, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := histogram.With("alpha", "alpha-value")
+	for _, v := range []float64{1, 7, 30, 30, 900} {
+		h.Observe(v)
+	}
+
+	hist := collect(t, reader, "foo").(metricdata.Histogram)
+	if want, have := 1, len(hist.DataPoints); want != have {
+		t.Fatalf("want %d data points, have %d", want, have)
+	}
+	point := hist.DataPoints[0]
+	if want, have := attribute.NewSet(attribute.String("alpha", "alpha-value")), point.Attributes; !want.Equals(&have) {
+		t.Errorf("want attributes %v, have %v", want, have)
+	}
+	if want, have := uint64(5), point.Count; want != have {
+		t.Errorf("want count %d, have %d", want, have)
+	}
+	if want, have := 968.0, point.Sum; want != have {
+		t.Errorf("want sum %f, have %f", want, have)
+	}
+	var total uint64
+	for _, n := range point.BucketCounts {
+		total += n
+	}
+	if want, have := point.Count, total; want != have {
+		t.Errorf("want %d observations in buckets, have %d", want, have)
+	}
+}
+
+func newMeter() (sdkmetric.Reader, metric.Meter) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	return reader, provider.Meter("github.com/barrett370/kit/v2/metrics/otel")
+}
+
+func collect(t *testing.T, reader sdkmetric.Reader, name string) metricdata.Aggregation {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m.Data
+			}
+		}
+	}
+	t.Fatalf("%s: not collected", name)
+	return nil
+}
+
+func pointFor(t *testing.T, points []metricdata.DataPoint[float64], keyvals ...string) metricdata.DataPoint[float64] {
+	t.Helper()
+	want := attribute.NewSet(attributes(keyvals)...)
+	for _, p := range points {
+		if p.Attributes.Equals(&want) {
+			return p
+		}
+	}
+	t.Fatalf("no data point with attributes %v in %v", keyvals, points)
+	return metricdata.DataPoint[float64]{}
+}
@@ -0,0 +1,88 @@
+package prometheus
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// SummaryOption sets an optional parameter for a summary constructed via
+// NewSummaryFrom.
+type SummaryOption func(*prometheus.SummaryOpts)
+
+// Objectives sets the quantiles computed by the summary, mapped to their
+// absolute error, e.g. {0.5: 0.05, 0.9: 0.01, 0.99: 0.001}. By default,
+// Prometheus summaries compute no quantiles, and only report a count and sum.
+func Objectives(objectives map[float64]float64) SummaryOption {
+	return func(opts *prometheus.SummaryOpts) { opts.Objectives = objectives }
+}
+
+// MaxAge sets the duration for which an observation stays relevant to the
+// quantiles computed by the summary. By default, prometheus.DefMaxAge is used.
+func MaxAge(d time.Duration) SummaryOption {
+	return func(opts *prometheus.SummaryOpts) { opts.MaxAge = d }
+}
+
+// AgeBuckets sets the number of buckets used to exclude observations older
+// than MaxAge from the summary. By default, prometheus.DefAgeBuckets is used.
+func AgeBuckets(n uint32) SummaryOption {
+	return func(opts *prometheus.SummaryOpts) { opts.AgeBuckets = n }
+}
+
+// BufCap sets the capacity of the buffer which holds observations before
+// they're inserted into the quantile streams. By default,
+// prometheus.DefBufCap is used.
+func BufCap(n uint32) SummaryOption {
+	return func(opts *prometheus.SummaryOpts) { opts.BufCap = n }
+}
+
+// HistogramOption sets an optional parameter for a histogram constructed via
+// NewHistogramFrom.
+type HistogramOption func(*prometheus.HistogramOpts)
+
+// Buckets sets the upper bounds of the histogram's buckets, which must be
+// sorted in increasing order. By default, prometheus.DefBuckets is used.
+func Buckets(buckets ...float64) HistogramOption {
+	return func(opts *prometheus.HistogramOpts) { opts.Buckets = buckets }
+}
+
+// LinearBuckets sets count buckets, each width wide, with the lowest upper
+// bound at start.
+func LinearBuckets(start, width float64, count int) HistogramOption {
+	return Buckets(prometheus.LinearBuckets(start, width, count)...)
+}
+
+// ExponentialBuckets sets count buckets, with the lowest upper bound at start
+// and each following upper bound a factor larger than the last. They
+// approximate the resolution of a native histogram at a fixed range.
+func ExponentialBuckets(start, factor float64, count int) HistogramOption {
+	return Buckets(prometheus.ExponentialBuckets(start, factor, count)...)
+}
+
+// NativeBucketFactor makes the histogram a native (sparse) histogram, whose
+// exponential buckets each grow by at most factor, e.g. 1.1, over the last.
+// Native histograms are ingested by Prometheus servers with the feature
+// enabled. Unless buckets are also set explicitly, the histogram has no
+// classic buckets. Native histograms are experimental in client_golang.
+func NativeBucketFactor(factor float64) HistogramOption {
+	return func(opts *prometheus.HistogramOpts) { opts.NativeHistogramBucketFactor = factor }
+}
+
+// NativeZeroThreshold sets the absolute value up to which observations of a
+// native histogram are counted in its zero bucket. By default,
+// prometheus.DefNativeHistogramZeroThreshold is used.
+func NativeZeroThreshold(threshold float64) HistogramOption {
+	return func(opts *prometheus.HistogramOpts) { opts.NativeHistogramZeroThreshold = threshold }
+}
+
+// NativeMaxBuckets limits the number of populated buckets of a native
+// histogram to n. Once exceeded, the histogram is reset if it was last reset
+// at least minResetDuration ago, and otherwise its resolution is reduced. By
+// default, the number of buckets isn't limited; histograms of values which
+// depend on external input should set a limit.
+func NativeMaxBuckets(n uint32, minResetDuration time.Duration) HistogramOption {
+	return func(opts *prometheus.HistogramOpts) {
+		opts.NativeHistogramMaxBucketNumber = n
+		opts.NativeHistogramMinResetDuration = minResetDuration
+	}
+}
@@ -5,7 +5,11 @@
 package prometheus
 
 import (
+	"strings"
+	"unicode/utf8"
+
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
 
 	"github.com/barrett370/kit/v2/metrics"
 	"github.com/barrett370/kit/v2/metrics/internal/lv"
@@ -45,6 +49,19 @@ func (c *Counter) Add(delta float64) {
 	c.cv.With(makeLabels(c.lvs...)).Add(delta)
 }
 
+// AddWithExemplar implements metrics.CounterWithExemplar. The exemplar is
+// exposed only when the registry is scraped in the OpenMetrics format. An
+// invalid exemplar, e.g. one exceeding prometheus.ExemplarMaxRunes, is
+// dropped, and delta is added without it.
+func (c *Counter) AddWithExemplar(delta float64, exemplar map[string]string) {
+	counter := c.cv.With(makeLabels(c.lvs...))
+	if adder, ok := counter.(prometheus.ExemplarAdder); ok && validExemplar(exemplar) {
+		adder.AddWithExemplar(delta, exemplar)
+		return
+	}
+	counter.Add(delta)
+}
+
 // Gauge implements Gauge, via a Prometheus GaugeVec.
 type Gauge struct {
 	gv  *prometheus.GaugeVec
@@ -59,6 +76,16 @@ func NewGaugeFrom(opts prometheus.GaugeOpts, labelNames []string) *Gauge {
 	return NewGauge(gv)
 }
 
+// NewGaugeFuncFrom constructs and registers a Prometheus GaugeFunc, whose
+// value is sampled from f at scrape time. It's useful for values such as queue
+// depth which aren't naturally event-driven. The GaugeFunc is returned so it
+// may be unregistered.
+func NewGaugeFuncFrom(opts prometheus.GaugeOpts, f func() float64) prometheus.GaugeFunc {
+	gf := prometheus.NewGaugeFunc(opts, f)
+	prometheus.MustRegister(gf)
+	return gf
+}
+
 // NewGauge wraps the GaugeVec and returns a usable Gauge object.
 func NewGauge(gv *prometheus.GaugeVec) *Gauge {
 	return &Gauge{
@@ -93,8 +120,12 @@ type Summary struct {
 }
 
 // NewSummaryFrom constructs and registers a Prometheus SummaryVec,
-// and returns a usable Summary object.
-func NewSummaryFrom(opts prometheus.SummaryOpts, labelNames []string) *Summary {
+// and returns a usable Summary object. Options are applied to opts before the
+// SummaryVec is constructed, and take precedence over its fields.
+func NewSummaryFrom(opts prometheus.SummaryOpts, labelNames []string, options ...SummaryOption) *Summary {
+	for _, option := range options {
+		option(&opts)
+	}
 	sv := prometheus.NewSummaryVec(opts, labelNames)
 	prometheus.MustRegister(sv)
 	return NewSummary(sv)
@@ -129,8 +160,12 @@ type Histogram struct {
 }
 
 // NewHistogramFrom constructs and registers a Prometheus HistogramVec,
-// and returns a usable Histogram object.
-func NewHistogramFrom(opts prometheus.HistogramOpts, labelNames []string) *Histogram {
+// and returns a usable Histogram object. Options are applied to opts before
+// the HistogramVec is constructed, and take precedence over its fields.
+func NewHistogramFrom(opts prometheus.HistogramOpts, labelNames []string, options ...HistogramOption) *Histogram {
+	for _, option := range options {
+		option(&opts)
+	}
 	hv := prometheus.NewHistogramVec(opts, labelNames)
 	prometheus.MustRegister(hv)
 	return NewHistogram(hv)
@@ -156,6 +191,33 @@ func (h *Histogram) Observe(value float64) {
 	h.hv.With(makeLabels(h.lvs...)).Observe(value)
 }
 
+// ObserveWithExemplar implements metrics.HistogramWithExemplar. The exemplar
+// is exposed only when the registry is scraped in the OpenMetrics format.
+// Summaries don't support exemplars. An invalid exemplar is dropped, and the
+// value is observed without it.
+func (h *Histogram) ObserveWithExemplar(value float64, exemplar map[string]string) {
+	observer := h.hv.With(makeLabels(h.lvs...))
+	if eo, ok := observer.(prometheus.ExemplarObserver); ok && validExemplar(exemplar) {
+		eo.ObserveWithExemplar(value, exemplar)
+		return
+	}
+	observer.Observe(value)
+}
+
+// validExemplar reports whether the client library accepts the exemplar,
+// rather than panicking: its label names must be valid and not reserved, its
+// values valid UTF-8, and it mustn't exceed prometheus.ExemplarMaxRunes.
+func validExemplar(exemplar map[string]string) bool {
+	var runes int
+	for name, value := range exemplar {
+		if !model.LabelName(name).IsValid() || strings.HasPrefix(name, model.ReservedLabelPrefix) || !utf8.ValidString(value) {
+			return false
+		}
+		runes += utf8.RuneCountInString(name) + utf8.RuneCountInString(value)
+	}
+	return runes <= prometheus.ExemplarMaxRunes
+}
+
 func makeLabels(labelValues ...string) prometheus.Labels {
 	labels := prometheus.Labels{}
 	for i := 0; i < len(labelValues); i += 2 {
@@ -1,6 +1,7 @@
 package prometheus
 
 import (
+	"context"
 	"io/ioutil"
 	"math"
 	"math/rand"
@@ -11,8 +12,12 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/barrett370/kit/v2/metrics"
+	"github.com/barrett370/kit/v2/metrics/internal/remotewrite"
 	"github.com/barrett370/kit/v2/metrics/teststat"
+	"github.com/go-kit/log"
 	stdprometheus "github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
@@ -213,3 +218,258 @@ func TestInconsistentLabelCardinality(t *testing.T) {
 		"a", "1", "b", "2", "c", "KABOOM!",
 	).Add(123)
 }
+
+func TestExemplars(t *testing.T) {
+	hv := stdprometheus.NewHistogramVec(stdprometheus.HistogramOpts{
+		Name:    "exemplar_histogram",
+		Help:    "This is the help string for the histogram.",
+		Buckets: []float64{1, 10},
+	}, []string{"a"})
+	cv := stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+		Name: "exemplar_counter",
+		Help: "This is the help string for the counter.",
+	}, []string{"a"})
+	registry := stdprometheus.NewRegistry()
+	registry.MustRegister(hv, cv)
+
+	exemplar := map[string]string{"trace_id": "abc123"}
+	metrics.ObserveWithExemplar(NewHistogram(hv).With("a", "b"), 5, exemplar)
+	metrics.AddWithExemplar(NewCounter(cv).With("a", "b"), 1, exemplar)
+
+	mfs, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mf := range mfs {
+		m := mf.GetMetric()[0]
+		switch mf.GetName() {
+		case "exemplar_histogram":
+			e := m.GetHistogram().GetBucket()[1].GetExemplar()
+			if want, have := "abc123", e.GetLabel()[0].GetValue(); want != have {
+				t.Errorf("histogram exemplar: want %q, have %q", want, have)
+			}
+			if want, have := 5.0, e.GetValue(); want != have {
+				t.Errorf("histogram exemplar value: want %v, have %v", want, have)
+			}
+		case "exemplar_counter":
+			e := m.GetCounter().GetExemplar()
+			if want, have := "abc123", e.GetLabel()[0].GetValue(); want != have {
+				t.Errorf("counter exemplar: want %q, have %q", want, have)
+			}
+		}
+	}
+}
+
+func TestInvalidExemplars(t *testing.T) {
+	hv := stdprometheus.NewHistogramVec(stdprometheus.HistogramOpts{
+		Name: "invalid_exemplar_histogram",
+		Help: "This is the help string for the histogram.",
+	}, []string{})
+	sv := stdprometheus.NewSummaryVec(stdprometheus.SummaryOpts{
+		Name: "invalid_exemplar_summary",
+		Help: "This is the help string for the summary.",
+	}, []string{})
+	cv := stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+		Name: "invalid_exemplar_counter",
+		Help: "This is the help string for the counter.",
+	}, []string{})
+	registry := stdprometheus.NewRegistry()
+	registry.MustRegister(hv, sv, cv)
+
+	for _, exemplar := range []map[string]string{
+		{"trace_id": strings.Repeat("x", 200)},
+		{"trace-id": "abc123"},
+		{"__trace_id": "abc123"},
+	} {
+		metrics.ObserveWithExemplar(NewHistogram(hv), 5, exemplar)
+		metrics.AddWithExemplar(NewCounter(cv), 1, exemplar)
+	}
+	metrics.ObserveWithExemplar(NewSummary(sv), 5, map[string]string{"trace_id": "abc123"})
+
+	mfs, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mf := range mfs {
+		m := mf.GetMetric()[0]
+		switch mf.GetName() {
+		case "invalid_exemplar_histogram":
+			if want, have := uint64(3), m.GetHistogram().GetSampleCount(); want != have {
+				t.Errorf("histogram: want %d observations, have %d", want, have)
+			}
+			for _, b := range m.GetHistogram().GetBucket() {
+				if b.GetExemplar() != nil {
+					t.Errorf("histogram: want no exemplar, have %v", b.GetExemplar())
+				}
+			}
+		case "invalid_exemplar_summary":
+			if want, have := uint64(1), m.GetSummary().GetSampleCount(); want != have {
+				t.Errorf("summary: want %d observations, have %d", want, have)
+			}
+		case "invalid_exemplar_counter":
+			if want, have := 3.0, m.GetCounter().GetValue(); want != have {
+				t.Errorf("counter: want %v, have %v", want, have)
+			}
+			if e := m.GetCounter().GetExemplar(); e != nil {
+				t.Errorf("counter: want no exemplar, have %v", e)
+			}
+		}
+	}
+}
+
+func TestOptions(t *testing.T) {
+	NewSummaryFrom(stdprometheus.SummaryOpts{
+		Name: "options_summary",
+		Help: "This is the help string for the summary.",
+	}, []string{}, Objectives(map[float64]float64{0.5: 0.05, 0.99: 0.001}), MaxAge(time.Minute)).Observe(1)
+	NewHistogramFrom(stdprometheus.HistogramOpts{
+		Name: "options_histogram",
+		Help: "This is the help string for the histogram.",
+	}, []string{}, ExponentialBuckets(1, 10, 3)).Observe(5)
+	native := NewHistogramFrom(stdprometheus.HistogramOpts{
+		Name: "options_native_histogram",
+		Help: "This is the help string for the native histogram.",
+	}, []string{}, NativeBucketFactor(1.1), NativeMaxBuckets(2, time.Hour))
+	for _, v := range []float64{1, 10, 100, 1000} {
+		native.Observe(v)
+	}
+
+	mfs, err := stdprometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mf := range mfs {
+		m := mf.GetMetric()
+		switch mf.GetName() {
+		case "options_summary":
+			var have []float64
+			for _, q := range m[0].GetSummary().GetQuantile() {
+				have = append(have, q.GetQuantile())
+			}
+			if want := []float64{0.5, 0.99}; !reflect.DeepEqual(want, have) {
+				t.Errorf("summary quantiles: want %v, have %v", want, have)
+			}
+		case "options_histogram":
+			var have []float64
+			for _, b := range m[0].GetHistogram().GetBucket() {
+				have = append(have, b.GetUpperBound())
+			}
+			if want := []float64{1, 10, 100}; !reflect.DeepEqual(want, have) {
+				t.Errorf("histogram buckets: want %v, have %v", want, have)
+			}
+		case "options_native_histogram":
+			h := m[0].GetHistogram()
+			if n := len(h.GetBucket()); n != 0 {
+				t.Errorf("native histogram: want no classic buckets, have %d", n)
+			}
+			// A factor of 1.1 is schema 3; exceeding the limit of 2 buckets
+			// reduces the resolution, since the last reset is too recent.
+			if schema := h.GetSchema(); schema >= 3 {
+				t.Errorf("native histogram: want schema reduced below 3, have %d", schema)
+			}
+			if want, have := uint64(4), h.GetSampleCount(); want != have {
+				t.Errorf("native histogram: want %d observations, have %d", want, have)
+			}
+		}
+	}
+}
+
+func TestPushLoop(t *testing.T) {
+	var (
+		pushes = make(chan error, 2)
+		c      = make(chan time.Time)
+		done   = make(chan struct{})
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		defer close(done)
+		PushLoop(ctx, c, pusherFunc(func(ctx context.Context) error {
+			pushes <- ctx.Err()
+			return nil
+		}), log.NewNopLogger())
+	}()
+
+	c <- time.Now()
+	if err := <-pushes; err != nil {
+		t.Errorf("push: %v", err)
+	}
+	cancel()
+	<-done
+	select {
+	case err := <-pushes:
+		if err != nil {
+			t.Errorf("final push: want a live context, have %v", err)
+		}
+	default:
+		t.Error("want a final push once the context is canceled, have none")
+	}
+}
+
+type pusherFunc func(context.Context) error
+
+func (f pusherFunc) Push(ctx context.Context) error { return f(ctx) }
+
+func TestToTimeSeries(t *testing.T) {
+	registry := stdprometheus.NewRegistry()
+	cv := stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+		Name: "push_counter",
+		Help: "This is the help string for the counter.",
+	}, []string{"job"})
+	hv := stdprometheus.NewHistogramVec(stdprometheus.HistogramOpts{
+		Name:    "push_histogram",
+		Help:    "This is the help string for the histogram.",
+		Buckets: []float64{1},
+	}, []string{})
+	registry.MustRegister(cv, hv)
+	NewCounter(cv).With("job", "override").Add(3)
+	NewHistogram(hv).Observe(2)
+
+	mfs, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have []string
+	for _, ts := range toTimeSeries(mfs, []remotewrite.Label{{Name: "job", Value: "batch"}, {Name: "instance", Value: "a"}}, 1000) {
+		var labels []string
+		for _, l := range ts.Labels {
+			labels = append(labels, l.Name+"="+l.Value)
+		}
+		have = append(have, strings.Join(labels, ",")+" "+strconv.FormatFloat(ts.Samples[0].Value, 'g', -1, 64))
+	}
+	want := []string{
+		"__name__=push_counter,instance=a,job=override 3",
+		"__name__=push_histogram_bucket,instance=a,job=batch,le=1 0",
+		"__name__=push_histogram_bucket,instance=a,job=batch,le=+Inf 1",
+		"__name__=push_histogram_sum,instance=a,job=batch 2",
+		"__name__=push_histogram_count,instance=a,job=batch 1",
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestPushgateway(t *testing.T) {
+	var path string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+
+	registry := stdprometheus.NewRegistry()
+	registry.MustRegister(stdprometheus.NewCounter(stdprometheus.CounterOpts{Name: "pushed_total"}))
+	p := NewPushgateway(s.URL, "batch", PushGatherer(registry), PushGrouping("instance", "a"))
+
+	if err := p.Push(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "/metrics/job/batch/instance/a", path; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := p.Push(ctx); err == nil {
+		t.Error("want error from canceled context, have none")
+	}
+}
@@ -0,0 +1,213 @@
+package prometheus
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/barrett370/kit/v2/metrics/internal/loop"
+	"github.com/barrett370/kit/v2/metrics/internal/remotewrite"
+)
+
+// Pusher pushes metrics to a remote destination, for processes such as batch
+// jobs which can't be scraped.
+type Pusher interface {
+	Push(ctx context.Context) error
+}
+
+// PushOption sets an optional parameter for Pushers.
+type PushOption func(*pushOptions)
+
+type pushOptions struct {
+	gatherer prometheus.Gatherer
+	grouping []remotewrite.Label
+	client   *http.Client
+}
+
+// PushGatherer sets the gatherer whose metrics are pushed. By default,
+// prometheus.DefaultGatherer is used, to which the NewXFrom constructors
+// register.
+func PushGatherer(g prometheus.Gatherer) PushOption {
+	return func(o *pushOptions) { o.gatherer = g }
+}
+
+// PushGrouping adds a grouping label, such as "instance", to the pushed
+// metrics, in addition to the job.
+func PushGrouping(name, value string) PushOption {
+	return func(o *pushOptions) { o.grouping = append(o.grouping, remotewrite.Label{Name: name, Value: value}) }
+}
+
+// PushHTTPClient sets the client used to push metrics. By default,
+// http.DefaultClient is used.
+func PushHTTPClient(c *http.Client) PushOption {
+	return func(o *pushOptions) { o.client = c }
+}
+
+func newPushOptions(options []PushOption) *pushOptions {
+	o := &pushOptions{
+		gatherer: prometheus.DefaultGatherer,
+		client:   http.DefaultClient,
+	}
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
+
+// NewPushgateway returns a Pusher which pushes metrics to the Pushgateway at
+// the URL, grouped by the job and any grouping labels. Each push replaces all
+// metrics previously pushed with the same grouping.
+func NewPushgateway(url, job string, options ...PushOption) Pusher {
+	return pushgateway{url: url, job: job, options: newPushOptions(options)}
+}
+
+type pushgateway struct {
+	url     string
+	job     string
+	options *pushOptions
+}
+
+// Push pushes via a push.Pusher bound to ctx, as push.Pusher only takes a
+// context from client_golang v1.13 on.
+func (p pushgateway) Push(ctx context.Context) error {
+	pusher := push.New(p.url, p.job).
+		Gatherer(p.options.gatherer).
+		Client(contextDoer{ctx: ctx, client: p.options.client})
+	for _, l := range p.options.grouping {
+		pusher = pusher.Grouping(l.Name, l.Value)
+	}
+	return pusher.Push()
+}
+
+// contextDoer is a push.HTTPDoer which makes requests with its context.
+type contextDoer struct {
+	ctx    context.Context
+	client *http.Client
+}
+
+func (d contextDoer) Do(req *http.Request) (*http.Response, error) {
+	return d.client.Do(req.WithContext(d.ctx))
+}
+
+// NewRemoteWrite returns a Pusher which pushes metrics to the URL via the
+// Prometheus remote write protocol, e.g. to Prometheus itself, Cortex, Mimir,
+// or VictoriaMetrics. Every series is labeled with the job and any grouping
+// labels, unless it already has a label of the same name. Summaries and
+// histograms are flattened into series the way Prometheus does when scraping.
+func NewRemoteWrite(url, job string, options ...PushOption) Pusher {
+	o := newPushOptions(options)
+	return &remoteWrite{
+		url:      url,
+		labels:   append([]remotewrite.Label{{Name: "job", Value: job}}, o.grouping...),
+		gatherer: o.gatherer,
+		client:   o.client,
+	}
+}
+
+type remoteWrite struct {
+	url      string
+	labels   []remotewrite.Label
+	gatherer prometheus.Gatherer
+	client   *http.Client
+}
+
+func (rw *remoteWrite) Push(ctx context.Context) error {
+	mfs, err := rw.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+	series := toTimeSeries(mfs, rw.labels, time.Now().UnixNano()/int64(time.Millisecond))
+	if len(series) == 0 {
+		return nil
+	}
+	return remotewrite.Send(ctx, rw.client, rw.url, nil, series)
+}
+
+// PushLoop is a helper method that pushes metrics every time c fires, logging
+// any errors. This method blocks until ctx is canceled, so clients probably
+// want to run it in its own goroutine. Once ctx is canceled, it pushes a final
+// time, with a new context which times out after a few seconds, so the
+// observations made since the last push aren't lost.
+func PushLoop(ctx context.Context, c <-chan time.Time, p Pusher, logger log.Logger) {
+	loop.Run(ctx, c, 0, func(ctx context.Context) {
+		if err := p.Push(ctx); err != nil {
+			logger.Log("during", "Push", "err", err)
+		}
+	})
+}
+
+func toTimeSeries(mfs []*dto.MetricFamily, extra []remotewrite.Label, now int64) []remotewrite.TimeSeries {
+	var series []remotewrite.TimeSeries
+	for _, mf := range mfs {
+		name := mf.GetName()
+		for _, m := range mf.GetMetric() {
+			labels := make([]remotewrite.Label, 0, len(m.GetLabel())+len(extra))
+			present := map[string]bool{}
+			for _, lp := range m.GetLabel() {
+				labels = append(labels, remotewrite.Label{Name: lp.GetName(), Value: lp.GetValue()})
+				present[lp.GetName()] = true
+			}
+			for _, l := range extra {
+				if !present[l.Name] {
+					labels = append(labels, l)
+				}
+			}
+			ts := m.GetTimestampMs()
+			if ts == 0 {
+				ts = now
+			}
+			add := func(name string, value float64, lvs ...string) {
+				s := remotewrite.TimeSeries{
+					Labels:  append([]remotewrite.Label{{Name: "__name__", Value: name}}, labels...),
+					Samples: []remotewrite.Sample{{Value: value, Timestamp: ts}},
+				}
+				for i := 0; i < len(lvs); i += 2 {
+					s.Labels = append(s.Labels, remotewrite.Label{Name: lvs[i], Value: lvs[i+1]})
+				}
+				s.SortLabels()
+				series = append(series, s)
+			}
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				add(name, m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add(name, m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add(name, m.GetUntyped().GetValue())
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.GetQuantile() {
+					add(name, q.GetValue(), "quantile", formatFloat(q.GetQuantile()))
+				}
+				add(name+"_sum", s.GetSampleSum())
+				add(name+"_count", float64(s.GetSampleCount()))
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				for _, b := range h.GetBucket() {
+					if math.IsInf(b.GetUpperBound(), +1) {
+						continue
+					}
+					add(name+"_bucket", float64(b.GetCumulativeCount()), "le", formatFloat(b.GetUpperBound()))
+				}
+				add(name+"_bucket", float64(h.GetSampleCount()), "le", "+Inf")
+				add(name+"_sum", h.GetSampleSum())
+				add(name+"_count", float64(h.GetSampleCount()))
+			}
+		}
+	}
+	return series
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, +1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,43 @@
+package provider
+
+import (
+	"github.com/barrett370/kit/v2/metrics"
+	"github.com/barrett370/kit/v2/metrics/cloudwatchemf"
+)
+
+type emfProvider struct {
+	e    *cloudwatchemf.EMF
+	stop func()
+}
+
+// NewEMFProvider wraps the given EMF object and stop func and returns a
+// Provider that produces CloudWatch Embedded Metric Format metrics. A typical
+// stop function would be ticker.Stop from the ticker passed to the WriteLoop
+// helper method.
+func NewEMFProvider(e *cloudwatchemf.EMF, stop func()) Provider {
+	return &emfProvider{
+		e:    e,
+		stop: stop,
+	}
+}
+
+// NewCounter implements Provider.
+func (p *emfProvider) NewCounter(name string) metrics.Counter {
+	return p.e.NewCounter(name)
+}
+
+// NewGauge implements Provider.
+func (p *emfProvider) NewGauge(name string) metrics.Gauge {
+	return p.e.NewGauge(name)
+}
+
+// NewHistogram implements Provider. Buckets are ignored; CloudWatch computes
+// statistics from the raw observations.
+func (p *emfProvider) NewHistogram(name string, _ int) metrics.Histogram {
+	return p.e.NewHistogram(name)
+}
+
+// Stop implements Provider, invoking the stop function passed at construction.
+func (p *emfProvider) Stop() {
+	p.stop()
+}
@@ -0,0 +1,163 @@
+package provider
+
+import (
+	"time"
+
+	"github.com/barrett370/kit/v2/metrics"
+)
+
+type prefixProvider struct {
+	prefix string
+	next   Provider
+}
+
+// WithPrefix returns a Provider which prepends the prefix to the name of every
+// metric it creates, and otherwise delegates to the given provider. It allows
+// subsystems to namespace their metrics without threading the prefix through
+// every constructor.
+func WithPrefix(prefix string, p Provider) Provider {
+	return prefixProvider{prefix: prefix, next: p}
+}
+
+// NewCounter implements Provider.
+func (p prefixProvider) NewCounter(name string) metrics.Counter {
+	return p.next.NewCounter(p.prefix + name)
+}
+
+// NewGauge implements Provider.
+func (p prefixProvider) NewGauge(name string) metrics.Gauge {
+	return p.next.NewGauge(p.prefix + name)
+}
+
+// NewHistogram implements Provider.
+func (p prefixProvider) NewHistogram(name string, buckets int) metrics.Histogram {
+	return p.next.NewHistogram(p.prefix+name, buckets)
+}
+
+// Stop implements Provider, stopping the wrapped provider.
+func (p prefixProvider) Stop() {
+	p.next.Stop()
+}
+
+type constLabelsProvider struct {
+	labelValues []string
+	next        Provider
+}
+
+// WithConstLabels returns a Provider which applies the label values to every
+// metric it creates, and otherwise delegates to the given provider. Label
+// values passed to With are appended to them, except that a value for a label
+// name which is already set overrides the constant value. The provider comes
+// first, as the label values are variadic.
+//
+// Note that the Prometheus provider declares no label names, so its metrics
+// don't accept label values, and it can't be wrapped.
+func WithConstLabels(p Provider, labelValues ...string) Provider {
+	return constLabelsProvider{labelValues: mergeLabelValues(nil, labelValues), next: p}
+}
+
+// NewCounter implements Provider.
+func (p constLabelsProvider) NewCounter(name string) metrics.Counter {
+	return newLabeledCounter(p.next.NewCounter(name), p.labelValues)
+}
+
+// NewGauge implements Provider.
+func (p constLabelsProvider) NewGauge(name string) metrics.Gauge {
+	return newLabeledGauge(p.next.NewGauge(name), p.labelValues)
+}
+
+// NewHistogram implements Provider.
+func (p constLabelsProvider) NewHistogram(name string, buckets int) metrics.Histogram {
+	return newLabeledHistogram(p.next.NewHistogram(name, buckets), p.labelValues)
+}
+
+// Stop implements Provider, stopping the wrapped provider.
+func (p constLabelsProvider) Stop() {
+	p.next.Stop()
+}
+
+// labeledCounter is a counter with the label values applied, which keeps the
+// unlabeled counter, so that With can merge label values rather than append
+// them.
+type labeledCounter struct {
+	metrics.Counter
+	base        metrics.Counter
+	labelValues []string
+}
+
+func newLabeledCounter(base metrics.Counter, labelValues []string) labeledCounter {
+	return labeledCounter{Counter: base.With(labelValues...), base: base, labelValues: labelValues}
+}
+
+func (c labeledCounter) With(labelValues ...string) metrics.Counter {
+	return newLabeledCounter(c.base, mergeLabelValues(c.labelValues, labelValues))
+}
+
+type labeledGauge struct {
+	metrics.Gauge
+	base        metrics.Gauge
+	labelValues []string
+}
+
+func newLabeledGauge(base metrics.Gauge, labelValues []string) labeledGauge {
+	return labeledGauge{Gauge: base.With(labelValues...), base: base, labelValues: labelValues}
+}
+
+func (g labeledGauge) With(labelValues ...string) metrics.Gauge {
+	return newLabeledGauge(g.base, mergeLabelValues(g.labelValues, labelValues))
+}
+
+type labeledHistogram struct {
+	metrics.Histogram
+	base        metrics.Histogram
+	labelValues []string
+}
+
+func newLabeledHistogram(base metrics.Histogram, labelValues []string) labeledHistogram {
+	return labeledHistogram{Histogram: base.With(labelValues...), base: base, labelValues: labelValues}
+}
+
+func (h labeledHistogram) With(labelValues ...string) metrics.Histogram {
+	return newLabeledHistogram(h.base, mergeLabelValues(h.labelValues, labelValues))
+}
+
+// mergeLabelValues returns a copy of the label values with the more label
+// values merged in: the values of names already present are replaced in
+// place, and new names are appended in order. A missing final value is
+// "unknown", as in the With methods of metrics.
+func mergeLabelValues(labelValues, more []string) []string {
+	merged := append([]string{}, labelValues...)
+	if len(more)%2 != 0 {
+		more = append(more[:len(more):len(more)], "unknown")
+	}
+next:
+	for i := 0; i < len(more); i += 2 {
+		for j := 0; j < len(merged); j += 2 {
+			if merged[j] == more[i] {
+				merged[j+1] = more[i+1]
+				continue next
+			}
+		}
+		merged = append(merged, more[i], more[i+1])
+	}
+	return merged
+}
+
+type unitProvider struct {
+	unit time.Duration
+	Provider
+}
+
+// WithUnit returns a Provider whose histograms convert each observation from
+// seconds to the given unit, e.g. time.Millisecond, before delegating to the
+// histograms of the given provider. Instrumentation may then consistently
+// observe seconds, e.g. via metrics.Timer, regardless of the unit expected by
+// the backend. Counters and gauges are unaffected.
+func WithUnit(unit time.Duration, p Provider) Provider {
+	return unitProvider{unit: unit, Provider: p}
+}
+
+// NewHistogram implements Provider.
+func (p unitProvider) NewHistogram(name string, buckets int) metrics.Histogram {
+	return metrics.NewUnitHistogram(p.Provider.NewHistogram(name, buckets), p.unit)
+}
@@ -0,0 +1,90 @@
+package provider_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/barrett370/kit/v2/metrics"
+	"github.com/barrett370/kit/v2/metrics/discard"
+	"github.com/barrett370/kit/v2/metrics/provider"
+	"github.com/barrett370/kit/v2/metrics/teststat"
+)
+
+func TestWithPrefix(t *testing.T) {
+	r := teststat.NewRecordingProvider()
+	p := provider.WithPrefix("api_", r)
+	p.NewCounter("requests").Add(1)
+	p.NewGauge("inflight").Set(2)
+	p.NewHistogram("latency", 50).Observe(3)
+	p.Stop()
+
+	if want, have := 1.0, r.CounterValue("api_requests"); want != have {
+		t.Errorf("counter: want %f, have %f", want, have)
+	}
+	if want, have := 2.0, r.GaugeValue("api_inflight"); want != have {
+		t.Errorf("gauge: want %f, have %f", want, have)
+	}
+	if want, have := []float64{3}, r.HistogramObservations("api_latency"); !reflect.DeepEqual(want, have) {
+		t.Errorf("histogram: want %v, have %v", want, have)
+	}
+	if !r.Stopped() {
+		t.Error("want stopped")
+	}
+}
+
+func TestWithConstLabels(t *testing.T) {
+	r := teststat.NewRecordingProvider()
+	p := provider.WithConstLabels(r, "env", "prod", "region", "eu")
+	p.NewCounter("requests").Add(1)
+	p.NewCounter("requests").With("method", "get").Add(2)
+	p.NewGauge("inflight").With("region", "us").Set(3)
+	p.NewHistogram("latency", 50).With("region", "us").With("region", "ap").Observe(4)
+
+	if want, have := 1.0, r.CounterValue("requests", "env", "prod", "region", "eu"); want != have {
+		t.Errorf("counter: want %f, have %f", want, have)
+	}
+	if want, have := 2.0, r.CounterValue("requests", "env", "prod", "region", "eu", "method", "get"); want != have {
+		t.Errorf("counter with labels: want %f, have %f", want, have)
+	}
+	if want, have := 3.0, r.GaugeValue("inflight", "env", "prod", "region", "us"); want != have {
+		t.Errorf("gauge with overridden label: want %f, have %f", want, have)
+	}
+	if want, have := []float64{4}, r.HistogramObservations("latency", "env", "prod", "region", "ap"); !reflect.DeepEqual(want, have) {
+		t.Errorf("histogram with overridden label: want %v, have %v", want, have)
+	}
+}
+
+func TestWithConstLabelsOrder(t *testing.T) {
+	var (
+		seen []string
+		p    = provider.WithConstLabels(orderProvider{&seen}, "env", "prod", "region", "eu")
+	)
+	p.NewCounter("requests").With("method", "get", "region", "us").With("code").Add(1)
+	if want, have := []string{"env", "prod", "region", "us", "method", "get", "code", "unknown"}, seen; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+// orderProvider creates counters which record the label values they're
+// incremented with, in order.
+type orderProvider struct {
+	seen *[]string
+}
+
+func (p orderProvider) NewCounter(string) metrics.Counter { return orderCounter{seen: p.seen} }
+func (orderProvider) NewGauge(string) metrics.Gauge       { return discard.NewGauge() }
+func (orderProvider) NewHistogram(string, int) metrics.Histogram {
+	return discard.NewHistogram()
+}
+func (orderProvider) Stop() {}
+
+type orderCounter struct {
+	labelValues []string
+	seen        *[]string
+}
+
+func (c orderCounter) With(labelValues ...string) metrics.Counter {
+	return orderCounter{labelValues: append(append([]string{}, c.labelValues...), labelValues...), seen: c.seen}
+}
+
+func (c orderCounter) Add(float64) { *c.seen = c.labelValues }
@@ -5,26 +5,29 @@ import (
 	"github.com/barrett370/kit/v2/metrics/expvar"
 )
 
-type expvarProvider struct{}
+type expvarProvider struct {
+	options []expvar.Option
+}
 
-// NewExpvarProvider returns a Provider that produces expvar metrics.
-func NewExpvarProvider() Provider {
-	return expvarProvider{}
+// NewExpvarProvider returns a Provider that produces expvar metrics. The
+// options, e.g. expvar.WithMaxSeries, are applied to every metric.
+func NewExpvarProvider(options ...expvar.Option) Provider {
+	return expvarProvider{options: options}
 }
 
 // NewCounter implements Provider.
 func (p expvarProvider) NewCounter(name string) metrics.Counter {
-	return expvar.NewCounter(name)
+	return expvar.NewCounter(name, p.options...)
 }
 
 // NewGauge implements Provider.
 func (p expvarProvider) NewGauge(name string) metrics.Gauge {
-	return expvar.NewGauge(name)
+	return expvar.NewGauge(name, p.options...)
 }
 
 // NewHistogram implements Provider.
 func (p expvarProvider) NewHistogram(name string, buckets int) metrics.Histogram {
-	return expvar.NewHistogram(name, buckets)
+	return expvar.NewHistogram(name, buckets, p.options...)
 }
 
 // Stop implements Provider, but is a no-op.
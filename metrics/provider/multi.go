@@ -0,0 +1,51 @@
+package provider
+
+import (
+	"github.com/barrett370/kit/v2/metrics"
+	"github.com/barrett370/kit/v2/metrics/multi"
+)
+
+type multiProvider []Provider
+
+// NewMultiProvider returns a Provider whose metrics forward every observation
+// to the corresponding metrics of all of the given providers. This is useful
+// when transitioning from one metrics backend to another, e.g. from StatsD to
+// Prometheus, as both may be fed side by side without changing any
+// instrumentation code. Stop stops all of the given providers.
+func NewMultiProvider(providers ...Provider) Provider {
+	return multiProvider(providers)
+}
+
+// NewCounter implements Provider.
+func (p multiProvider) NewCounter(name string) metrics.Counter {
+	c := make(multi.Counter, len(p))
+	for i, provider := range p {
+		c[i] = provider.NewCounter(name)
+	}
+	return c
+}
+
+// NewGauge implements Provider.
+func (p multiProvider) NewGauge(name string) metrics.Gauge {
+	g := make(multi.Gauge, len(p))
+	for i, provider := range p {
+		g[i] = provider.NewGauge(name)
+	}
+	return g
+}
+
+// NewHistogram implements Provider.
+func (p multiProvider) NewHistogram(name string, buckets int) metrics.Histogram {
+	h := make(multi.Histogram, len(p))
+	for i, provider := range p {
+		h[i] = provider.NewHistogram(name, buckets)
+	}
+	return h
+}
+
+// Stop implements Provider, stopping each of the providers in order.
+func (p multiProvider) Stop() {
+	for _, provider := range p {
+		provider.Stop()
+	}
+}
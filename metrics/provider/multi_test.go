@@ -0,0 +1,36 @@
+package provider_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/barrett370/kit/v2/metrics/provider"
+	"github.com/barrett370/kit/v2/metrics/teststat"
+)
+
+func TestMultiProvider(t *testing.T) {
+	var (
+		a = teststat.NewRecordingProvider()
+		b = teststat.NewRecordingProvider()
+		p = provider.NewMultiProvider(a, b)
+	)
+	p.NewCounter("requests").With("method", "get").Add(2)
+	p.NewGauge("inflight").With("method", "get").Set(3)
+	p.NewHistogram("latency", 50).With("method", "get").Observe(0.5)
+	p.Stop()
+
+	for name, r := range map[string]*teststat.RecordingProvider{"a": a, "b": b} {
+		if want, have := 2.0, r.CounterValue("requests", "method", "get"); want != have {
+			t.Errorf("%s: counter: want %f, have %f", name, want, have)
+		}
+		if want, have := 3.0, r.GaugeValue("inflight", "method", "get"); want != have {
+			t.Errorf("%s: gauge: want %f, have %f", name, want, have)
+		}
+		if want, have := []float64{0.5}, r.HistogramObservations("latency", "method", "get"); !reflect.DeepEqual(want, have) {
+			t.Errorf("%s: histogram: want %v, have %v", name, want, have)
+		}
+		if !r.Stopped() {
+			t.Errorf("%s: want stopped", name)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/barrett370/kit/v2/metrics"
+	"github.com/barrett370/kit/v2/metrics/otel"
+)
+
+type otelProvider struct {
+	meter metric.Meter
+	stop  func()
+}
+
+// NewOTelProvider returns a Provider that produces metrics as instruments of
+// the given OpenTelemetry meter, which are aggregated and exported by its
+// MeterProvider. Stop calls stop, if it isn't nil; a typical stop function
+// would shut down the MeterProvider, which exports its final observations.
+func NewOTelProvider(meter metric.Meter, stop func()) Provider {
+	return &otelProvider{
+		meter: meter,
+		stop:  stop,
+	}
+}
+
+// NewCounter implements Provider via otel.NewCounterFrom. It panics if the
+// meter refuses to create the instrument, e.g. since the name is invalid.
+func (p *otelProvider) NewCounter(name string) metrics.Counter {
+	c, err := otel.NewCounterFrom(p.meter, name)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// NewGauge implements Provider via otel.NewGaugeFrom. It panics if the meter
+// refuses to create the instrument, e.g. since the name is invalid.
+func (p *otelProvider) NewGauge(name string) metrics.Gauge {
+	g, err := otel.NewGaugeFrom(p.meter, name)
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// NewHistogram implements Provider via otel.NewHistogramFrom. Buckets are
+// ignored; the buckets are those of the MeterProvider's aggregation. It panics
+// if the meter refuses to create the instrument, e.g. since the name is
+// invalid.
+func (p *otelProvider) NewHistogram(name string, _ int) metrics.Histogram {
+	h, err := otel.NewHistogramFrom(p.meter, name)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+// Stop implements Provider, invoking the stop function passed at
+// construction, if any.
+func (p *otelProvider) Stop() {
+	if p.stop != nil {
+		p.stop()
+	}
+}
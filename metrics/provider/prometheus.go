@@ -10,14 +10,32 @@ import (
 type prometheusProvider struct {
 	namespace string
 	subsystem string
+	options   []prometheus.SummaryOption
+	stop      func()
 }
 
 // NewPrometheusProvider returns a Provider that produces Prometheus metrics.
-// Namespace and subsystem are applied to all produced metrics.
-func NewPrometheusProvider(namespace, subsystem string) Provider {
+// Namespace and subsystem are applied to all produced metrics. Options are
+// applied to all produced summaries, e.g. to set their objectives.
+func NewPrometheusProvider(namespace, subsystem string, options ...prometheus.SummaryOption) Provider {
 	return &prometheusProvider{
 		namespace: namespace,
 		subsystem: subsystem,
+		options:   options,
+	}
+}
+
+// NewPrometheusPushProvider returns a Provider that produces Prometheus
+// metrics like NewPrometheusProvider, for processes which push their metrics
+// via a prometheus.Pusher rather than being scraped. Stop calls stop, which
+// should cancel the prometheus.PushLoop and wait for it to return, after its
+// final push of the observations made since the last one.
+func NewPrometheusPushProvider(namespace, subsystem string, stop func(), options ...prometheus.SummaryOption) Provider {
+	return &prometheusProvider{
+		namespace: namespace,
+		subsystem: subsystem,
+		options:   options,
+		stop:      stop,
 	}
 }
 
@@ -49,15 +67,20 @@ func (p *prometheusProvider) NewGauge(name string) metrics.Gauge {
 // NewHistogram implements Provider via prometheus.NewSummaryFrom, i.e. the summary
 // is registered. The metric's namespace and subsystem are taken from the
 // Provider. Help is set to the name of the metric, and no const label names are
-// set. Buckets are ignored.
+// set. Buckets are ignored, and the Provider's summary options are applied.
 func (p *prometheusProvider) NewHistogram(name string, _ int) metrics.Histogram {
 	return prometheus.NewSummaryFrom(stdprometheus.SummaryOpts{
 		Namespace: p.namespace,
 		Subsystem: p.subsystem,
 		Name:      name,
 		Help:      name,
-	}, []string{})
+	}, []string{}, p.options...)
 }
 
-// Stop implements Provider, but is a no-op.
-func (p *prometheusProvider) Stop() {}
+// Stop implements Provider. It calls the stop func given to
+// NewPrometheusPushProvider, if any, and is otherwise a no-op.
+func (p *prometheusProvider) Stop() {
+	if p.stop != nil {
+		p.stop()
+	}
+}
@@ -0,0 +1,43 @@
+package provider
+
+import (
+	"github.com/barrett370/kit/v2/metrics"
+	"github.com/barrett370/kit/v2/metrics/remotewrite"
+)
+
+type remoteWriteProvider struct {
+	r    *remotewrite.RemoteWrite
+	stop func()
+}
+
+// NewRemoteWriteProvider wraps the given RemoteWrite object and stop func and
+// returns a Provider that produces metrics which are pushed to a Prometheus
+// remote write endpoint. A typical stop function would be ticker.Stop from
+// the ticker passed to the SendLoop helper method.
+func NewRemoteWriteProvider(r *remotewrite.RemoteWrite, stop func()) Provider {
+	return &remoteWriteProvider{
+		r:    r,
+		stop: stop,
+	}
+}
+
+// NewCounter implements Provider.
+func (p *remoteWriteProvider) NewCounter(name string) metrics.Counter {
+	return p.r.NewCounter(name)
+}
+
+// NewGauge implements Provider.
+func (p *remoteWriteProvider) NewGauge(name string) metrics.Gauge {
+	return p.r.NewGauge(name)
+}
+
+// NewHistogram implements Provider. The number of buckets is ignored, and
+// remotewrite.DefaultBuckets are used.
+func (p *remoteWriteProvider) NewHistogram(name string, _ int) metrics.Histogram {
+	return p.r.NewHistogram(name)
+}
+
+// Stop implements Provider, invoking the stop function passed at construction.
+func (p *remoteWriteProvider) Stop() {
+	p.stop()
+}
@@ -0,0 +1,203 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"go.opentelemetry.io/otel/metric/global"
+
+	"github.com/barrett370/kit/v2/metrics/cloudwatchemf"
+	"github.com/barrett370/kit/v2/metrics/dogstatsd"
+	"github.com/barrett370/kit/v2/metrics/expvar"
+	"github.com/barrett370/kit/v2/metrics/graphite"
+	"github.com/barrett370/kit/v2/metrics/remotewrite"
+	"github.com/barrett370/kit/v2/metrics/statsd"
+)
+
+// DefaultInterval is the interval at which push-based providers created by
+// NewFromURI send their metrics, if the URI doesn't specify one.
+const DefaultInterval = 5 * time.Second
+
+// NewFromURI returns a Provider configured by a URI, so that the metrics
+// backend of a binary may be chosen purely by configuration, e.g. a flag. The
+// scheme selects the backend:
+//
+//	discard://
+//	expvar://?max_series=100
+//	prometheus://?namespace=myapp&subsystem=api
+//	statsd://statsd.local:8125?prefix=myapp.&interval=5s&network=udp
+//	dogstatsd://localhost:8125?prefix=myapp.&interval=5s&network=udp
+//	graphite://carbon.local:2003?prefix=myapp.&pickle=true&tags=true
+//	remotewrite+https://vm.local/api/v1/write?job=myapp&instance=host1
+//	otel://?scope=myapp
+//	emf://MyNamespace
+//
+// Push-based backends send their metrics every interval, DefaultInterval by
+// default; Stop stops sending, after a final send. The emf scheme writes the
+// CloudWatch Embedded Metric Format to stdout, from which CloudWatch extracts
+// metrics in Lambda, or wherever the CloudWatch agent collects the process's
+// output. The PutMetricData API needs an AWS client, so there's no scheme for
+// it; construct a cloudwatch2.CloudWatch directly. The otel scheme creates
+// instruments with a meter of the global OpenTelemetry MeterProvider, which
+// the service configures, and shuts down, itself.
+func NewFromURI(uri string, logger log.Logger) (Provider, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	interval := DefaultInterval
+	if s := q.Get("interval"); s != "" {
+		if interval, err = time.ParseDuration(s); err != nil {
+			return nil, fmt.Errorf("invalid interval: %w", err)
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("invalid interval %s", interval)
+		}
+	}
+	network := func(def string) string {
+		if n := q.Get("network"); n != "" {
+			return n
+		}
+		return def
+	}
+
+	switch scheme := strings.ToLower(u.Scheme); scheme {
+	case "discard":
+		return NewDiscardProvider(), nil
+
+	case "expvar":
+		var options []expvar.Option
+		if s := q.Get("max_series"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid max_series: %w", err)
+			}
+			options = append(options, expvar.WithMaxSeries(n))
+		}
+		return NewExpvarProvider(options...), nil
+
+	case "prometheus":
+		return NewPrometheusProvider(q.Get("namespace"), q.Get("subsystem")), nil
+
+	case "statsd":
+		if u.Host == "" {
+			return nil, fmt.Errorf("%s: missing host", uri)
+		}
+		s := statsd.New(q.Get("prefix"), logger)
+		stop := startLoop(interval, func(ctx context.Context, c <-chan time.Time) {
+			s.SendLoop(ctx, c, network("udp"), u.Host)
+		})
+		return NewStatsdProvider(s, stop), nil
+
+	case "dogstatsd":
+		if u.Host == "" {
+			return nil, fmt.Errorf("%s: missing host", uri)
+		}
+		d := dogstatsd.New(q.Get("prefix"), logger)
+		stop := startLoop(interval, func(ctx context.Context, c <-chan time.Time) {
+			d.SendLoop(ctx, c, network("udp"), u.Host)
+		})
+		return NewDogstatsdProvider(d, stop), nil
+
+	case "graphite":
+		if u.Host == "" {
+			return nil, fmt.Errorf("%s: missing host", uri)
+		}
+		var options []graphite.Option
+		if ok, err := boolParam(q, "pickle"); err != nil {
+			return nil, err
+		} else if ok {
+			options = append(options, graphite.WithPickle())
+		}
+		if ok, err := boolParam(q, "tags"); err != nil {
+			return nil, err
+		} else if ok {
+			options = append(options, graphite.WithTags())
+		}
+		g := graphite.New(q.Get("prefix"), logger, options...)
+		stop := startLoop(interval, func(ctx context.Context, c <-chan time.Time) {
+			g.SendLoop(ctx, c, network("tcp"), u.Host)
+		})
+		return NewGraphiteProvider(g, stop), nil
+
+	case "remotewrite+http", "remotewrite+https":
+		var labelValues []string
+		for _, name := range []string{"job", "instance"} {
+			if v := q.Get(name); v != "" {
+				labelValues = append(labelValues, name, v)
+			}
+		}
+		r := remotewrite.New(endpoint(u, "remotewrite+"), logger, remotewrite.WithExternalLabels(labelValues...))
+		stop := startLoop(interval, r.SendLoop)
+		return NewRemoteWriteProvider(r, stop), nil
+
+	case "otel":
+		scope := q.Get("scope")
+		if scope == "" {
+			scope = "github.com/barrett370/kit/v2/metrics/otel"
+		}
+		return NewOTelProvider(global.Meter(scope), nil), nil
+
+	case "cloudwatch":
+		return nil, fmt.Errorf("%s: construct a cloudwatch2.CloudWatch with an AWS client, or use the emf scheme", uri)
+
+	case "emf":
+		if u.Host == "" {
+			return nil, fmt.Errorf("%s: missing namespace", uri)
+		}
+		e := cloudwatchemf.New(u.Host, logger)
+		stop := startLoop(interval, func(ctx context.Context, c <-chan time.Time) {
+			e.WriteLoop(ctx, c, os.Stdout)
+		})
+		return NewEMFProvider(e, stop), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported metrics provider scheme %q", scheme)
+	}
+}
+
+// startLoop runs loop in a new goroutine with a ticker firing every interval,
+// and returns a func which stops the ticker and the loop, and waits for the
+// loop's final write.
+func startLoop(interval time.Duration, loop func(context.Context, <-chan time.Time)) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		loop(ctx, ticker.C)
+	}()
+	return func() {
+		ticker.Stop()
+		cancel()
+		<-done
+	}
+}
+
+// endpoint returns the URL with the scheme prefix, e.g. "remotewrite+",
+// trimmed, and without the query parameters which configure the provider.
+func endpoint(u *url.URL, prefix string) string {
+	e := *u
+	e.Scheme = strings.TrimPrefix(strings.ToLower(u.Scheme), prefix)
+	e.RawQuery = ""
+	return e.String()
+}
+
+func boolParam(q url.Values, name string) (bool, error) {
+	s := q.Get(name)
+	if s == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return b, nil
+}
@@ -0,0 +1,68 @@
+package provider_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+
+	"github.com/barrett370/kit/v2/metrics/provider"
+)
+
+func TestNewFromURI(t *testing.T) {
+	for i, uri := range []string{
+		"discard://",
+		"expvar://",
+		"expvar://?max_series=100",
+		"prometheus://?namespace=myapp&subsystem=uri",
+		"statsd://127.0.0.1:1?prefix=myapp.&interval=1m",
+		"statsd://127.0.0.1:1?network=tcp",
+		"dogstatsd://127.0.0.1:1?prefix=myapp.&interval=1m",
+		"graphite://127.0.0.1:1?prefix=myapp.&pickle=true&tags=true",
+		"remotewrite+http://127.0.0.1:1/api/v1/write?job=myapp&instance=host1",
+		"otel://",
+		"otel://?scope=myapp",
+		"emf://MyNamespace?interval=1m",
+		"STATSD://127.0.0.1:1",
+	} {
+		t.Run(uri, func(t *testing.T) {
+			p, err := provider.NewFromURI(uri, log.NewNopLogger())
+			if err != nil {
+				t.Fatal(err)
+			}
+			// expvar and Prometheus register metrics globally, by name. The
+			// emf scheme writes observations to stdout, so it gets none.
+			c := p.NewCounter("uri_requests_" + strconv.Itoa(i))
+			if !strings.HasPrefix(uri, "emf:") {
+				c.Add(1)
+			}
+			p.Stop()
+		})
+	}
+}
+
+func TestNewFromURIErrors(t *testing.T) {
+	for _, uri := range []string{
+		"",
+		"unknown://host",
+		"statsd://",
+		"statsd://127.0.0.1:port",
+		"statsd://127.0.0.1:1?interval=soon",
+		"statsd://127.0.0.1:1?interval=-1s",
+		"dogstatsd://",
+		"graphite://",
+		"graphite://127.0.0.1:1?pickle=maybe",
+		"graphite://127.0.0.1:1?tags=maybe",
+		"expvar://?max_series=many",
+		"cloudwatch://MyNamespace",
+		"emf://",
+	} {
+		t.Run(uri, func(t *testing.T) {
+			if p, err := provider.NewFromURI(uri, log.NewNopLogger()); err == nil {
+				p.Stop()
+				t.Errorf("want error, have provider %T", p)
+			}
+		})
+	}
+}
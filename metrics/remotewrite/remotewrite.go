@@ -0,0 +1,397 @@
+// Package remotewrite provides a metrics backend which pushes observations to
+// a Prometheus remote write endpoint, e.g. Prometheus with the remote write
+// receiver enabled, VictoriaMetrics, Thanos, Cortex or Mimir. It's intended
+// for environments where the process can't be scraped, and there's no StatsD
+// relay to push to.
+//
+// Observations are aggregated locally, and sent as snappy-compressed protobuf
+// WriteRequests on a schedule. Unlike the StatsD-like backends, the values
+// sent are cumulative, as Prometheus expects: counters are sent as their
+// running total, gauges as their current value, and histograms as cumulative
+// buckets, a sum and a count. A failed send therefore loses no observations;
+// they're included in the next send.
+//
+// See https://prometheus.io/docs/concepts/remote_write_spec/ for details of
+// the protocol.
+package remotewrite
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/barrett370/kit/v2/metrics"
+	"github.com/barrett370/kit/v2/metrics/internal/gaugefunc"
+	"github.com/barrett370/kit/v2/metrics/internal/loop"
+	"github.com/barrett370/kit/v2/metrics/internal/lv"
+	rw "github.com/barrett370/kit/v2/metrics/internal/remotewrite"
+	"github.com/go-kit/log"
+)
+
+// DefaultBuckets are the default upper bounds of histogram buckets. They
+// match the default buckets of the Prometheus client, and are tailored to
+// measuring network request latencies in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// HTTPClient is the subset of *http.Client used to send write requests.
+type HTTPClient = rw.HTTPClient
+
+// RemoteWrite receives metrics observations and sends them to a Prometheus
+// remote write endpoint. Create a RemoteWrite object, use it to create
+// metrics, and pass those metrics as dependencies to the components that will
+// use them.
+//
+// To regularly send metrics, use the SendLoop helper method.
+type RemoteWrite struct {
+	url    string
+	client HTTPClient
+	header http.Header
+	labels []rw.Label
+	logger log.Logger
+
+	counters   *lv.Space
+	histograms *lv.Space
+
+	mtx        sync.Mutex
+	totals     map[string]*counterState
+	gauges     map[string]*gaugeState
+	histStates map[string]*histogramState
+	buckets    map[string][]float64 // by histogram name
+	gaugeFuncs gaugefunc.Set
+	jitter     time.Duration
+}
+
+// Option sets an optional parameter for the RemoteWrite object.
+type Option func(*RemoteWrite)
+
+// WithHTTPClient sets the client used to send write requests. By default,
+// http.DefaultClient is used.
+func WithHTTPClient(client HTTPClient) Option {
+	return func(r *RemoteWrite) { r.client = client }
+}
+
+// WithHeader adds a header to every write request, e.g. for authorization.
+func WithHeader(key, value string) Option {
+	return func(r *RemoteWrite) { r.header.Add(key, value) }
+}
+
+// WithExternalLabels adds the label name/value pairs, typically "job" and
+// "instance", to every time series. Prometheus adds these labels to scraped
+// series, but they must be provided explicitly when pushing.
+func WithExternalLabels(labelValues ...string) Option {
+	return func(r *RemoteWrite) {
+		lvs := lv.LabelValues{}.With(labelValues...)
+		for i := 0; i < len(lvs); i += 2 {
+			r.labels = append(r.labels, rw.Label{Name: lvs[i], Value: lvs[i+1]})
+		}
+	}
+}
+
+// WithJitter delays each write made by the loop helper methods by a random
+// offset in [0, jitter), chosen once when the loop starts, so that processes
+// started together don't all flush at the same instant. By default, there's
+// no delay.
+func WithJitter(jitter time.Duration) Option {
+	return func(r *RemoteWrite) { r.jitter = jitter }
+}
+
+// New returns a RemoteWrite object that may be used to create metrics, which
+// are sent to the remote write URL. Callers must ensure that regular calls to
+// Send are performed, either manually or with the SendLoop helper method.
+func New(url string, logger log.Logger, options ...Option) *RemoteWrite {
+	r := &RemoteWrite{
+		url:        url,
+		client:     http.DefaultClient,
+		header:     http.Header{},
+		logger:     logger,
+		counters:   lv.NewSpace(),
+		histograms: lv.NewSpace(),
+		totals:     map[string]*counterState{},
+		gauges:     map[string]*gaugeState{},
+		histStates: map[string]*histogramState{},
+		buckets:    map[string][]float64{},
+	}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// NewCounter returns a counter. Observations are aggregated, and the running
+// total is sent on each send. By convention, counter names end in _total.
+func (r *RemoteWrite) NewCounter(name string) *Counter {
+	return &Counter{
+		name: name,
+		obs:  r.counters.Observe,
+	}
+}
+
+// NewGauge returns a gauge. The current value is sent on each send.
+func (r *RemoteWrite) NewGauge(name string) *Gauge {
+	return &Gauge{
+		name: name,
+		obs:  r.gauge,
+	}
+}
+
+// NewGaugeFunc adds a gauge whose value is sampled from f immediately before
+// each send, for values such as queue depth which aren't naturally
+// event-driven. The label values are applied to the gauge via With.
+func (r *RemoteWrite) NewGaugeFunc(name string, f func() float64, labelValues ...string) {
+	r.gaugeFuncs.Add(r.NewGauge(name).With(labelValues...), f)
+}
+
+// NewHistogram returns a histogram with the given bucket upper bounds, or
+// DefaultBuckets if none are given. Observations are aggregated, and sent as
+// the cumulative name_bucket, name_sum and name_count series on each send.
+func (r *RemoteWrite) NewHistogram(name string, buckets ...float64) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	// The +Inf bucket is always sent, so it's dropped if given explicitly.
+	bounds := make([]float64, 0, len(buckets))
+	for _, b := range buckets {
+		if !math.IsInf(b, +1) {
+			bounds = append(bounds, b)
+		}
+	}
+	sort.Float64s(bounds)
+	r.mtx.Lock()
+	r.buckets[name] = bounds
+	r.mtx.Unlock()
+	return &Histogram{
+		name: name,
+		obs:  r.histograms.Observe,
+	}
+}
+
+// gauge applies a Set or Add to the current value of the gauge.
+func (r *RemoteWrite) gauge(name string, lvs lv.LabelValues, value float64, add bool) {
+	key := seriesKey(name, lvs)
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	g, ok := r.gauges[key]
+	if !ok {
+		g = &gaugeState{name: name, lvs: append(lv.LabelValues{}, lvs...)}
+		r.gauges[key] = g
+	}
+	if add {
+		g.value += value
+	} else {
+		g.value = value
+	}
+}
+
+// SendLoop is a helper method that invokes Send every time the passed channel
+// fires. This method blocks until ctx is canceled, so clients probably want
+// to run it in its own goroutine. For typical usage, create a time.Ticker and
+// pass its C channel to this method.
+// When ctx is canceled, a final write is made, so that the observations
+// made since the last tick aren't lost.
+func (r *RemoteWrite) SendLoop(ctx context.Context, c <-chan time.Time) {
+	loop.Run(ctx, c, r.jitter, func(ctx context.Context) {
+		if err := r.Send(ctx); err != nil {
+			r.logger.Log("during", "Send", "err", err)
+		}
+	})
+}
+
+// Send aggregates the buffered observations and sends the current value of
+// every time series to the remote write endpoint in a single request. If
+// there are no time series, no request is made.
+func (r *RemoteWrite) Send(ctx context.Context) error {
+	series := r.timeSeries(time.Now().UnixNano() / int64(time.Millisecond))
+	if len(series) == 0 {
+		return nil
+	}
+	return rw.Send(ctx, r.client, r.url, r.header, series)
+}
+
+// timeSeries folds the buffered observations into the cumulative state, and
+// returns the state as time series with a single sample at the timestamp.
+func (r *RemoteWrite) timeSeries(timestamp int64) []rw.TimeSeries {
+	r.gaugeFuncs.Sample()
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.counters.Reset().Walk(func(name string, lvs lv.LabelValues, values []float64) bool {
+		key := seriesKey(name, lvs)
+		c, ok := r.totals[key]
+		if !ok {
+			c = &counterState{name: name, lvs: append(lv.LabelValues{}, lvs...)}
+			r.totals[key] = c
+		}
+		for _, v := range values {
+			c.value += v
+		}
+		return true
+	})
+	r.histograms.Reset().Walk(func(name string, lvs lv.LabelValues, values []float64) bool {
+		key := seriesKey(name, lvs)
+		h, ok := r.histStates[key]
+		if !ok {
+			buckets := r.buckets[name]
+			h = &histogramState{name: name, lvs: append(lv.LabelValues{}, lvs...), buckets: buckets, counts: make([]uint64, len(buckets))}
+			r.histStates[key] = h
+		}
+		for _, v := range values {
+			h.observe(v)
+		}
+		return true
+	})
+
+	var series []rw.TimeSeries
+	add := func(name string, lvs lv.LabelValues, value float64, extra ...rw.Label) {
+		labels := make([]rw.Label, 0, 1+len(r.labels)+len(lvs)/2+len(extra))
+		labels = append(labels, rw.Label{Name: "__name__", Value: name})
+		labels = append(labels, r.labels...)
+		for i := 0; i < len(lvs); i += 2 {
+			labels = append(labels, rw.Label{Name: lvs[i], Value: lvs[i+1]})
+		}
+		labels = append(labels, extra...)
+		ts := rw.TimeSeries{Labels: labels, Samples: []rw.Sample{{Value: value, Timestamp: timestamp}}}
+		ts.SortLabels()
+		series = append(series, ts)
+	}
+	for _, key := range sortedKeys(r.totals) {
+		c := r.totals[key]
+		add(c.name, c.lvs, c.value)
+	}
+	for _, key := range sortedKeys(r.gauges) {
+		g := r.gauges[key]
+		add(g.name, g.lvs, g.value)
+	}
+	for _, key := range sortedKeys(r.histStates) {
+		h := r.histStates[key]
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += h.counts[i]
+			add(h.name+"_bucket", h.lvs, float64(cumulative), rw.Label{Name: "le", Value: strconv.FormatFloat(upper, 'g', -1, 64)})
+		}
+		add(h.name+"_bucket", h.lvs, float64(h.count), rw.Label{Name: "le", Value: "+Inf"})
+		add(h.name+"_sum", h.lvs, h.sum)
+		add(h.name+"_count", h.lvs, float64(h.count))
+	}
+	return series
+}
+
+type counterState struct {
+	name  string
+	lvs   lv.LabelValues
+	value float64
+}
+
+type gaugeState struct {
+	name  string
+	lvs   lv.LabelValues
+	value float64
+}
+
+type histogramState struct {
+	name    string
+	lvs     lv.LabelValues
+	buckets []float64
+	counts  []uint64 // per bucket, not cumulative
+	count   uint64
+	sum     float64
+}
+
+func (h *histogramState) observe(value float64) {
+	if i := sort.SearchFloat64s(h.buckets, value); i < len(h.buckets) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += value
+}
+
+func seriesKey(name string, lvs lv.LabelValues) string {
+	return name + "\x00" + strings.Join(lvs, "\x00")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type observeFunc func(name string, lvs lv.LabelValues, value float64)
+
+// Counter is a remote write counter. Observations are forwarded to a
+// RemoteWrite object, and aggregated into a running total per timeseries.
+type Counter struct {
+	name string
+	lvs  lv.LabelValues
+	obs  observeFunc
+}
+
+// With implements metrics.Counter.
+func (c *Counter) With(labelValues ...string) metrics.Counter {
+	return &Counter{
+		name: c.name,
+		lvs:  c.lvs.With(labelValues...),
+		obs:  c.obs,
+	}
+}
+
+// Add implements metrics.Counter.
+func (c *Counter) Add(delta float64) {
+	c.obs(c.name, c.lvs, delta)
+}
+
+// Gauge is a remote write gauge. Observations are forwarded to a RemoteWrite
+// object, which maintains the current value per timeseries.
+type Gauge struct {
+	name string
+	lvs  lv.LabelValues
+	obs  func(name string, lvs lv.LabelValues, value float64, add bool)
+}
+
+// With implements metrics.Gauge.
+func (g *Gauge) With(labelValues ...string) metrics.Gauge {
+	return &Gauge{
+		name: g.name,
+		lvs:  g.lvs.With(labelValues...),
+		obs:  g.obs,
+	}
+}
+
+// Set implements metrics.Gauge.
+func (g *Gauge) Set(value float64) {
+	g.obs(g.name, g.lvs, value, false)
+}
+
+// Add implements metrics.Gauge.
+func (g *Gauge) Add(delta float64) {
+	g.obs(g.name, g.lvs, delta, true)
+}
+
+// Histogram is a remote write histogram. Observations are forwarded to a
+// RemoteWrite object, and aggregated into cumulative buckets per timeseries.
+type Histogram struct {
+	name string
+	lvs  lv.LabelValues
+	obs  observeFunc
+}
+
+// With implements metrics.Histogram.
+func (h *Histogram) With(labelValues ...string) metrics.Histogram {
+	return &Histogram{
+		name: h.name,
+		lvs:  h.lvs.With(labelValues...),
+		obs:  h.obs,
+	}
+}
+
+// Observe implements metrics.Histogram.
+func (h *Histogram) Observe(value float64) {
+	h.obs(h.name, h.lvs, value)
+}
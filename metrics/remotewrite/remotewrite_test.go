@@ -0,0 +1,120 @@
+package remotewrite
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	rw "github.com/barrett370/kit/v2/metrics/internal/remotewrite"
+	"github.com/go-kit/log"
+)
+
+func TestCounter(t *testing.T) {
+	r := New("", log.NewNopLogger(), WithExternalLabels("job", "test"))
+	c := r.NewCounter("requests_total").With("method", "get")
+	c.Add(1)
+	c.Add(2)
+	if want, have := []string{`requests_total{job="test",method="get"} 3`}, format(r.timeSeries(1)); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// Counters are cumulative across sends.
+	c.Add(4)
+	if want, have := []string{`requests_total{job="test",method="get"} 7`}, format(r.timeSeries(2)); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestGauge(t *testing.T) {
+	r := New("", log.NewNopLogger())
+	g := r.NewGauge("inflight")
+	g.Set(5)
+	g.Add(-2)
+	g.With("pool", "a").Add(1)
+	want := []string{`inflight 3`, `inflight{pool="a"} 1`}
+	if have := format(r.timeSeries(1)); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if have := format(r.timeSeries(2)); !reflect.DeepEqual(want, have) {
+		t.Errorf("after second send: want %v, have %v", want, have)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	r := New("", log.NewNopLogger())
+	h := r.NewHistogram("latency_seconds", 1, 0.1)
+	for _, v := range []float64{0.05, 0.5, 2, 0.1} {
+		h.Observe(v)
+	}
+	want := []string{
+		`latency_seconds_bucket{le="0.1"} 2`,
+		`latency_seconds_bucket{le="1"} 3`,
+		`latency_seconds_bucket{le="+Inf"} 4`,
+		`latency_seconds_sum 2.65`,
+		`latency_seconds_count 4`,
+	}
+	if have := format(r.timeSeries(1)); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestSend(t *testing.T) {
+	var requests int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		if want, have := "Bearer abc", req.Header.Get("Authorization"); want != have {
+			t.Errorf("Authorization: want %q, have %q", want, have)
+		}
+		if want, have := "snappy", req.Header.Get("Content-Encoding"); want != have {
+			t.Errorf("Content-Encoding: want %q, have %q", want, have)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer s.Close()
+
+	r := New(s.URL, log.NewNopLogger(), WithHeader("Authorization", "Bearer abc"))
+	if err := r.Send(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 0, requests; want != have {
+		t.Errorf("want %d requests without series, have %d", want, have)
+	}
+
+	r.NewCounter("requests_total").Add(1)
+	if err := r.Send(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, requests; want != have {
+		t.Errorf("want %d requests, have %d", want, have)
+	}
+}
+
+// format returns the time series in the Prometheus exposition format,
+// without timestamps.
+func format(series []rw.TimeSeries) []string {
+	var lines []string
+	for _, ts := range series {
+		var name string
+		var labels []string
+		for _, l := range ts.Labels {
+			if l.Name == "__name__" {
+				name = l.Value
+				continue
+			}
+			labels = append(labels, l.Name+`="`+l.Value+`"`)
+		}
+		line := name
+		if len(labels) > 0 {
+			line += "{" + strings.Join(labels, ",") + "}"
+		}
+		for _, s := range ts.Samples {
+			line += " " + strconv.FormatFloat(s.Value, 'g', -1, 64)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
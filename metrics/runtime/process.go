@@ -0,0 +1,26 @@
+package runtime
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strconv"
+)
+
+// residentBytes returns the resident set size of the process. It's only
+// supported on Linux, where it's read from /proc.
+func residentBytes() (float64, bool) {
+	b, err := ioutil.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0, false
+	}
+	fields := bytes.Fields(b)
+	if len(fields) < 2 {
+		return 0, false
+	}
+	pages, err := strconv.ParseUint(string(fields[1]), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return float64(pages) * float64(os.Getpagesize()), true
+}
@@ -0,0 +1,8 @@
+//go:build !(aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris)
+
+package runtime
+
+// cpuSeconds isn't supported on this platform.
+func cpuSeconds() (float64, bool) {
+	return 0, false
+}
@@ -0,0 +1,241 @@
+// Package runtime provides a Collector of Go runtime and process metrics,
+// such as the number of goroutines, heap size, GC pauses, scheduler latency,
+// CPU time and resident memory. The Collector periodically samples them, and
+// reports them through any metrics provider, e.g. a provider.Provider.
+//
+// Runtime metrics are read via the runtime/metrics package. Metrics which
+// aren't supported by the running version of Go are skipped.
+package runtime
+
+import (
+	"context"
+	"math"
+	rtmetrics "runtime/metrics"
+	"time"
+
+	"github.com/barrett370/kit/v2/metrics"
+)
+
+// DefaultInterval is the interval at which a Collector samples metrics, if no
+// interval is given.
+const DefaultInterval = 10 * time.Second
+
+// Provider constructs the metrics through which a Collector reports. It's
+// satisfied by provider.Provider, and by most metrics backends.
+type Provider interface {
+	NewCounter(name string) metrics.Counter
+	NewGauge(name string) metrics.Gauge
+}
+
+// Collector samples Go runtime and process metrics, and reports them through
+// the metrics created by a Provider. Cumulative values, such as the number of
+// GC cycles, are reported via counters; all other values via gauges.
+// Distributions, such as GC pauses, are reported as gauges of their p50, p99
+// and maximum over each interval.
+type Collector struct {
+	prefix   string
+	interval time.Duration
+
+	samples    []rtmetrics.Sample
+	gauges     map[string]metrics.Gauge   // by runtime/metrics name
+	counters   map[string]metrics.Counter // by runtime/metrics name
+	last       map[string]float64         // by runtime/metrics name
+	histograms map[string]*histogram      // by runtime/metrics name
+
+	cpu     metrics.Counter
+	lastCPU float64
+	rss     metrics.Gauge
+}
+
+// Option sets an optional parameter for Collectors.
+type Option func(*Collector)
+
+// WithPrefix sets a prefix which is applied to the name of every metric, e.g.
+// "myapp_". By default, there's no prefix.
+func WithPrefix(prefix string) Option {
+	return func(c *Collector) { c.prefix = prefix }
+}
+
+// WithInterval sets the interval at which Run samples metrics. By default,
+// DefaultInterval is used.
+func WithInterval(d time.Duration) Option {
+	return func(c *Collector) { c.interval = d }
+}
+
+type metric struct {
+	runtime string // runtime/metrics name
+	name    string
+}
+
+var (
+	gaugeMetrics = []metric{
+		{"/sched/goroutines:goroutines", "go_goroutines"},
+		{"/memory/classes/total:bytes", "go_memory_total_bytes"},
+		{"/memory/classes/heap/objects:bytes", "go_memory_heap_objects_bytes"},
+		{"/gc/heap/objects:objects", "go_gc_heap_objects"},
+		{"/gc/heap/goal:bytes", "go_gc_heap_goal_bytes"},
+	}
+	counterMetrics = []metric{
+		{"/gc/cycles/total:gc-cycles", "go_gc_cycles_total"},
+		{"/gc/heap/allocs:bytes", "go_gc_heap_allocs_bytes_total"},
+		{"/gc/heap/allocs:objects", "go_gc_heap_allocs_objects_total"},
+	}
+	histogramMetrics = []metric{
+		{"/gc/pauses:seconds", "go_gc_pause_seconds"},
+		{"/sched/latencies:seconds", "go_sched_latency_seconds"},
+	}
+)
+
+// NewCollector returns a Collector which reports through metrics created by
+// the provider. Callers must ensure that metrics are sampled regularly, via
+// Run or Collect.
+func NewCollector(p Provider, options ...Option) *Collector {
+	c := &Collector{
+		interval:   DefaultInterval,
+		gauges:     map[string]metrics.Gauge{},
+		counters:   map[string]metrics.Counter{},
+		last:       map[string]float64{},
+		histograms: map[string]*histogram{},
+	}
+	for _, option := range options {
+		option(c)
+	}
+
+	supported := map[string]bool{}
+	for _, d := range rtmetrics.All() {
+		supported[d.Name] = true
+	}
+	add := func(ms []metric, f func(metric)) {
+		for _, m := range ms {
+			if supported[m.runtime] {
+				c.samples = append(c.samples, rtmetrics.Sample{Name: m.runtime})
+				f(m)
+			}
+		}
+	}
+	add(gaugeMetrics, func(m metric) { c.gauges[m.runtime] = p.NewGauge(c.prefix + m.name) })
+	add(counterMetrics, func(m metric) { c.counters[m.runtime] = p.NewCounter(c.prefix + m.name) })
+	add(histogramMetrics, func(m metric) {
+		c.histograms[m.runtime] = &histogram{
+			p50: p.NewGauge(c.prefix + m.name + "_p50"),
+			p99: p.NewGauge(c.prefix + m.name + "_p99"),
+			max: p.NewGauge(c.prefix + m.name + "_max"),
+		}
+	})
+
+	if _, ok := cpuSeconds(); ok {
+		c.cpu = p.NewCounter(c.prefix + "process_cpu_seconds_total")
+	}
+	if _, ok := residentBytes(); ok {
+		c.rss = p.NewGauge(c.prefix + "process_resident_memory_bytes")
+	}
+	return c
+}
+
+// Run samples metrics every interval, until ctx is canceled. This method
+// blocks, so clients probably want to run it in its own goroutine.
+func (c *Collector) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	c.Collect()
+	for {
+		select {
+		case <-ticker.C:
+			c.Collect()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Collect samples all metrics once, and reports them. Counters are increased
+// by the change since the previous call, and distributions summarize the
+// observations made since the previous call. Collect isn't safe for
+// concurrent use.
+func (c *Collector) Collect() {
+	rtmetrics.Read(c.samples)
+	for _, s := range c.samples {
+		switch s.Value.Kind() {
+		case rtmetrics.KindUint64:
+			c.report(s.Name, float64(s.Value.Uint64()))
+		case rtmetrics.KindFloat64:
+			c.report(s.Name, s.Value.Float64())
+		case rtmetrics.KindFloat64Histogram:
+			if h, ok := c.histograms[s.Name]; ok {
+				h.report(s.Value.Float64Histogram())
+			}
+		}
+	}
+
+	if c.cpu != nil {
+		if seconds, ok := cpuSeconds(); ok {
+			c.cpu.Add(seconds - c.lastCPU)
+			c.lastCPU = seconds
+		}
+	}
+	if c.rss != nil {
+		if bytes, ok := residentBytes(); ok {
+			c.rss.Set(bytes)
+		}
+	}
+}
+
+func (c *Collector) report(name string, value float64) {
+	if g, ok := c.gauges[name]; ok {
+		g.Set(value)
+	}
+	if ctr, ok := c.counters[name]; ok {
+		ctr.Add(value - c.last[name])
+		c.last[name] = value
+	}
+}
+
+// histogram reports a cumulative runtime/metrics histogram as gauges of the
+// quantiles of the observations made since it was last reported.
+type histogram struct {
+	p50, p99, max metrics.Gauge
+	last          []uint64
+}
+
+func (h *histogram) report(v *rtmetrics.Float64Histogram) {
+	delta := make([]uint64, len(v.Counts))
+	var total uint64
+	for i, n := range v.Counts {
+		if i < len(h.last) {
+			n -= h.last[i]
+		}
+		delta[i] = n
+		total += n
+	}
+	h.last = append(h.last[:0], v.Counts...)
+	if total == 0 {
+		return
+	}
+	h.p50.Set(quantile(0.50, v.Buckets, delta, total))
+	h.p99.Set(quantile(0.99, v.Buckets, delta, total))
+	h.max.Set(quantile(1, v.Buckets, delta, total))
+}
+
+// quantile returns the upper bound of the bucket containing the q-quantile of
+// the counts, or its lower bound if the bucket is unbounded. Buckets are the
+// len(counts)+1 boundaries of the buckets, as in runtime/metrics.
+func quantile(q float64, buckets []float64, counts []uint64, total uint64) float64 {
+	rank := uint64(q * float64(total))
+	if rank == 0 {
+		rank = 1
+	}
+	var (
+		cumulative uint64
+		i          int
+	)
+	for i = range counts {
+		cumulative += counts[i]
+		if cumulative >= rank {
+			break
+		}
+	}
+	if upper := buckets[i+1]; !math.IsInf(upper, +1) {
+		return upper
+	}
+	return buckets[i]
+}
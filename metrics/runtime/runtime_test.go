@@ -0,0 +1,75 @@
+package runtime
+
+import (
+	"math"
+	goruntime "runtime"
+	"testing"
+
+	"github.com/barrett370/kit/v2/metrics"
+	"github.com/barrett370/kit/v2/metrics/generic"
+)
+
+func TestCollector(t *testing.T) {
+	p := newProvider()
+	c := NewCollector(p, WithPrefix("test_"))
+
+	c.Collect()
+	if have := p.gauges["test_go_goroutines"].Value(); have < 1 {
+		t.Errorf("go_goroutines: want at least 1, have %f", have)
+	}
+	if have := p.gauges["test_go_memory_total_bytes"].Value(); have <= 0 {
+		t.Errorf("go_memory_total_bytes: want > 0, have %f", have)
+	}
+
+	cycles := p.counters["test_go_gc_cycles_total"].Value()
+	goruntime.GC()
+	c.Collect()
+	if have := p.counters["test_go_gc_cycles_total"].Value(); have <= cycles {
+		t.Errorf("go_gc_cycles_total: want > %f, have %f", cycles, have)
+	}
+	if have := p.gauges["test_go_gc_pause_seconds_max"].Value(); have <= 0 {
+		t.Errorf("go_gc_pause_seconds_max: want > 0, have %f", have)
+	}
+}
+
+func TestQuantile(t *testing.T) {
+	buckets := []float64{0, 1, 2, 3, math.Inf(+1)}
+	counts := []uint64{2, 6, 1, 1}
+	for _, tc := range []struct {
+		q    float64
+		want float64
+	}{
+		{0.1, 1},
+		{0.5, 2},
+		{0.9, 3},
+		{1, 3}, // the unbounded bucket reports its lower bound
+	} {
+		if have := quantile(tc.q, buckets, counts, 10); tc.want != have {
+			t.Errorf("q=%.2f: want %f, have %f", tc.q, tc.want, have)
+		}
+	}
+}
+
+type provider struct {
+	counters map[string]*generic.Counter
+	gauges   map[string]*generic.Gauge
+}
+
+func newProvider() *provider {
+	return &provider{
+		counters: map[string]*generic.Counter{},
+		gauges:   map[string]*generic.Gauge{},
+	}
+}
+
+func (p *provider) NewCounter(name string) metrics.Counter {
+	c := generic.NewCounter(name)
+	p.counters[name] = c
+	return c
+}
+
+func (p *provider) NewGauge(name string) metrics.Gauge {
+	g := generic.NewGauge(name)
+	p.gauges[name] = g
+	return g
+}
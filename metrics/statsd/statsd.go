@@ -15,6 +15,9 @@ import (
 	"time"
 
 	"github.com/barrett370/kit/v2/metrics"
+	"github.com/barrett370/kit/v2/metrics/internal/buffer"
+	"github.com/barrett370/kit/v2/metrics/internal/gaugefunc"
+	"github.com/barrett370/kit/v2/metrics/internal/loop"
 	"github.com/barrett370/kit/v2/metrics/internal/lv"
 	"github.com/barrett370/kit/v2/metrics/internal/ratemap"
 	"github.com/barrett370/kit/v2/util/conn"
@@ -44,14 +47,57 @@ type Statsd struct {
 	gauges   *lv.Space
 	timings  *lv.Space
 
-	logger log.Logger
+	logger        log.Logger
+	maxPacketSize int
+	gaugeFuncs    gaugefunc.Set
+	buffer        *buffer.Buffer
+	flushFunc     metrics.FlushFunc
+	jitter        time.Duration
+}
+
+// Option sets an optional parameter for Statsd objects.
+type Option func(*Statsd)
+
+// WithMaxPacketSize batches the lines written by WriteTo into writes of at
+// most n bytes each, so that each packet sent over a datagram connection
+// carries as many lines as fit, without exceeding the MTU. See
+// conn.MaxUDPPacketSize and conn.MaxUDSPacketSize. By default, each line is
+// written separately.
+func WithMaxPacketSize(n int) Option {
+	return func(s *Statsd) { s.maxPacketSize = n }
+}
+
+// WithBuffer queues observations in a buffer of the given size, from which
+// they're applied by a separate goroutine, so that observing never blocks on
+// a concurrent write. If the buffer is full, observations are dropped, and
+// counted; see Dropped. The goroutine stops when WriteLoop or SendLoop
+// returns, after the final write. By default, observations are applied
+// directly.
+func WithBuffer(size int) Option {
+	return func(s *Statsd) { s.buffer = buffer.New(size) }
+}
+
+// WithFlushFunc sets a func which is called after each WriteTo, with the
+// number of bytes written, the duration of the write, and its error, if any,
+// e.g. one returned by metrics.NewFlushFunc. By default, failed writes are
+// only logged by the helper methods.
+func WithFlushFunc(f metrics.FlushFunc) Option {
+	return func(s *Statsd) { s.flushFunc = f }
+}
+
+// WithJitter delays each write made by the loop helper methods by a random
+// offset in [0, jitter), chosen once when the loop starts, so that processes
+// started together don't all flush at the same instant. By default, there's
+// no delay.
+func WithJitter(jitter time.Duration) Option {
+	return func(s *Statsd) { s.jitter = jitter }
 }
 
 // New returns a Statsd object that may be used to create metrics. Prefix is
 // applied to all created metrics. Callers must ensure that regular calls to
 // WriteTo are performed, either manually or with one of the helper methods.
-func New(prefix string, logger log.Logger) *Statsd {
-	return &Statsd{
+func New(prefix string, logger log.Logger, options ...Option) *Statsd {
+	s := &Statsd{
 		prefix:   prefix,
 		rates:    ratemap.New(),
 		counters: lv.NewSpace(),
@@ -59,6 +105,10 @@ func New(prefix string, logger log.Logger) *Statsd {
 		timings:  lv.NewSpace(),
 		logger:   logger,
 	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
 }
 
 // NewCounter returns a counter, sending observations to this Statsd object.
@@ -66,7 +116,7 @@ func (s *Statsd) NewCounter(name string, sampleRate float64) *Counter {
 	s.rates.Set(s.prefix+name, sampleRate)
 	return &Counter{
 		name: s.prefix + name,
-		obs:  s.counters.Observe,
+		obs:  s.buffered(s.counters.Observe),
 	}
 }
 
@@ -74,45 +124,78 @@ func (s *Statsd) NewCounter(name string, sampleRate float64) *Counter {
 func (s *Statsd) NewGauge(name string) *Gauge {
 	return &Gauge{
 		name: s.prefix + name,
-		obs:  s.gauges.Observe,
-		add:  s.gauges.Add,
+		obs:  s.buffered(s.gauges.Observe),
+		add:  s.buffered(s.gauges.Add),
 	}
 }
 
+// NewGaugeFunc adds a gauge whose value is sampled from f immediately before
+// each write, for values such as queue depth which aren't naturally
+// event-driven.
+func (s *Statsd) NewGaugeFunc(name string, f func() float64) {
+	s.gaugeFuncs.Add(s.NewGauge(name), f)
+}
+
 // NewTiming returns a histogram whose observations are interpreted as
 // millisecond durations, and are forwarded to this Statsd object.
 func (s *Statsd) NewTiming(name string, sampleRate float64) *Timing {
 	s.rates.Set(s.prefix+name, sampleRate)
 	return &Timing{
 		name: s.prefix + name,
-		obs:  s.timings.Observe,
+		obs:  s.buffered(s.timings.Observe),
+	}
+}
+
+// Dropped returns the number of observations dropped because the buffer set
+// by WithBuffer was full. Without a buffer, it's always zero.
+func (s *Statsd) Dropped() uint64 {
+	if s.buffer == nil {
+		return 0
+	}
+	return s.buffer.Dropped()
+}
+
+func (s *Statsd) buffered(obs observeFunc) observeFunc {
+	if s.buffer == nil {
+		return obs
 	}
+	return observeFunc(s.buffer.Wrap(buffer.ObserveFunc(obs)))
 }
 
 // WriteLoop is a helper method that invokes WriteTo to the passed writer every
 // time the passed channel fires. This method blocks until ctx is canceled,
 // so clients probably want to run it in its own goroutine. For typical
 // usage, create a time.Ticker and pass its C channel to this method.
+// When ctx is canceled, a final write is made, so that the observations
+// made since the last tick aren't lost.
 func (s *Statsd) WriteLoop(ctx context.Context, c <-chan time.Time, w io.Writer) {
-	for {
-		select {
-		case <-c:
-			if _, err := s.WriteTo(w); err != nil {
-				s.logger.Log("during", "WriteTo", "err", err)
-			}
-		case <-ctx.Done():
-			return
-		}
-	}
+	s.writeLoop(ctx, c, w, s.maxPacketSize)
 }
 
 // SendLoop is a helper method that wraps WriteLoop, passing a managed
 // connection to the network and address. Like WriteLoop, this method blocks
 // until ctx is canceled, so clients probably want to start it in its own
 // goroutine. For typical usage, create a time.Ticker and pass its C channel to
-// this method.
+// this method. Unless WithMaxPacketSize is given, lines sent over a datagram
+// network are batched into packets of the conventional maximum size for the
+// network; see conn.MaxPacketSize.
 func (s *Statsd) SendLoop(ctx context.Context, c <-chan time.Time, network, address string) {
-	s.WriteLoop(ctx, c, conn.NewDefaultManager(network, address, s.logger))
+	size := s.maxPacketSize
+	if size == 0 {
+		size = conn.MaxPacketSize(network)
+	}
+	s.writeLoop(ctx, c, conn.NewDefaultManager(network, address, s.logger), size)
+}
+
+func (s *Statsd) writeLoop(ctx context.Context, c <-chan time.Time, w io.Writer, maxPacketSize int) {
+	loop.Run(ctx, c, s.jitter, func(context.Context) {
+		if _, err := s.writeTo(w, maxPacketSize); err != nil {
+			s.logger.Log("during", "WriteTo", "err", err)
+		}
+	})
+	if s.buffer != nil {
+		s.buffer.Close()
+	}
 }
 
 // WriteTo flushes the buffered content of the metrics to the writer, in
@@ -120,9 +203,35 @@ func (s *Statsd) SendLoop(ctx context.Context, c <-chan time.Time, network, addr
 // lost if there is a problem with the write. Clients should be sure to call
 // WriteTo regularly, ideally through the WriteLoop or SendLoop helper methods.
 func (s *Statsd) WriteTo(w io.Writer) (count int64, err error) {
+	return s.writeTo(w, s.maxPacketSize)
+}
+
+func (s *Statsd) writeTo(w io.Writer, maxPacketSize int) (count int64, err error) {
+	if s.flushFunc != nil {
+		defer func(begin time.Time) { s.flushFunc(count, time.Since(begin), err) }(time.Now())
+	}
+	s.gaugeFuncs.Sample()
+	if s.buffer != nil {
+		s.buffer.Flush()
+	}
+
+	// Swap out the observations of every space at once, before any time is
+	// spent writing, so they're all attributed to this write.
+	counters, gauges, timings := s.counters.Reset(), s.gauges.Reset(), s.timings.Reset()
+
 	var n int
 
-	s.counters.Reset().Walk(func(name string, _ lv.LabelValues, values []float64) bool {
+	if maxPacketSize > 0 {
+		pw := conn.NewPacketWriter(w, maxPacketSize)
+		defer func() {
+			if ferr := pw.Flush(); err == nil {
+				err = ferr
+			}
+		}()
+		w = pw
+	}
+
+	counters.Walk(func(name string, _ lv.LabelValues, values []float64) bool {
 		n, err = fmt.Fprintf(w, "%s:%f|c%s\n", name, sum(values), sampling(s.rates.Get(name)))
 		if err != nil {
 			return false
@@ -134,7 +243,7 @@ func (s *Statsd) WriteTo(w io.Writer) (count int64, err error) {
 		return count, err
 	}
 
-	s.gauges.Reset().Walk(func(name string, _ lv.LabelValues, values []float64) bool {
+	gauges.Walk(func(name string, _ lv.LabelValues, values []float64) bool {
 		n, err = fmt.Fprintf(w, "%s:%f|g\n", name, last(values))
 		if err != nil {
 			return false
@@ -146,7 +255,7 @@ func (s *Statsd) WriteTo(w io.Writer) (count int64, err error) {
 		return count, err
 	}
 
-	s.timings.Reset().Walk(func(name string, _ lv.LabelValues, values []float64) bool {
+	timings.Walk(func(name string, _ lv.LabelValues, values []float64) bool {
 		sampleRate := s.rates.Get(name)
 		for _, value := range values {
 			n, err = fmt.Fprintf(w, "%s:%f|ms%s\n", name, value, sampling(sampleRate))
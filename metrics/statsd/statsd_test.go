@@ -1,7 +1,13 @@
 package statsd
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/barrett370/kit/v2/metrics/teststat"
 	"github.com/go-kit/log"
@@ -64,3 +70,104 @@ func TestTimingSampled(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+type packetRecorder struct{ packets []string }
+
+func (r *packetRecorder) Write(p []byte) (int, error) {
+	r.packets = append(r.packets, string(p))
+	return len(p), nil
+}
+
+func TestMaxPacketSize(t *testing.T) {
+	s := New("abc.", log.NewNopLogger(), WithMaxPacketSize(30))
+	for i := 0; i < 10; i++ {
+		s.NewCounter("c"+strconv.Itoa(i), 1.0).Add(1)
+	}
+	var r packetRecorder
+	if _, err := s.WriteTo(&r); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.packets) < 2 {
+		t.Fatalf("want lines split across packets, have %q", r.packets)
+	}
+	var lines int
+	for _, p := range r.packets {
+		if len(p) > 30 {
+			t.Errorf("packet of %d bytes exceeds the maximum: %q", len(p), p)
+		}
+		lines += strings.Count(p, "\n")
+	}
+	if want, have := 10, lines; want != have {
+		t.Errorf("want %d lines, have %d", want, have)
+	}
+}
+
+func TestGaugeFunc(t *testing.T) {
+	s := New("abc.", log.NewNopLogger())
+	depth := 3.0
+	s.NewGaugeFunc("depth", func() float64 { return depth })
+	for _, want := range []float64{3, 5} {
+		depth = want
+		var buf bytes.Buffer
+		if _, err := s.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := fmt.Sprintf("abc.depth:%f|g\n", want), buf.String(); want != have {
+			t.Errorf("want %q, have %q", want, have)
+		}
+	}
+}
+
+func TestBuffer(t *testing.T) {
+	prefix, name := "abc.", "def"
+	regex := `^` + prefix + name + `:([0-9\.]+)\|c$`
+	s := New(prefix, log.NewNopLogger(), WithBuffer(1024))
+	counter := s.NewCounter(name, 1.0)
+	valuef := teststat.SumLines(s, regex)
+	if err := teststat.TestCounter(counter, valuef); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := uint64(0), s.Dropped(); want != have {
+		t.Errorf("dropped: want %d, have %d", want, have)
+	}
+}
+
+func TestFlushFunc(t *testing.T) {
+	var (
+		flushes int
+		written int64
+	)
+	s := New("abc.", log.NewNopLogger(), WithFlushFunc(func(n int64, took time.Duration, err error) {
+		flushes++
+		written += n
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}))
+	s.NewCounter("def", 1.0).Add(1)
+
+	var buf bytes.Buffer
+	if _, err := s.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, flushes; want != have {
+		t.Errorf("flushes: want %d, have %d", want, have)
+	}
+	if want, have := int64(buf.Len()), written; want != have {
+		t.Errorf("written: want %d, have %d", want, have)
+	}
+}
+
+func TestWriteLoopFinalFlush(t *testing.T) {
+	s := New("abc.", log.NewNopLogger())
+	s.NewCounter("def", 1.0).Add(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var buf bytes.Buffer
+	s.WriteLoop(ctx, nil, &buf)
+
+	if want, have := "abc.def:1.000000|c\n", buf.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
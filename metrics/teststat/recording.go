@@ -0,0 +1,163 @@
+package teststat
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/barrett370/kit/v2/metrics"
+	"github.com/barrett370/kit/v2/metrics/internal/lv"
+)
+
+// RecordingProvider is a metrics provider which records all observations in
+// memory, so that tests can assert on instrumentation without a real backend.
+// It implements provider.Provider.
+//
+// Observations are recorded per name and set of label values. The query
+// methods take label values as key/value pairs, in any order.
+type RecordingProvider struct {
+	mtx        sync.Mutex
+	counters   map[string]float64
+	gauges     map[string]float64
+	histograms map[string][]float64
+	stopped    bool
+}
+
+// NewRecordingProvider returns a new, empty RecordingProvider.
+func NewRecordingProvider() *RecordingProvider {
+	return &RecordingProvider{
+		counters:   map[string]float64{},
+		gauges:     map[string]float64{},
+		histograms: map[string][]float64{},
+	}
+}
+
+// NewCounter implements Provider.
+func (p *RecordingProvider) NewCounter(name string) metrics.Counter {
+	return &recordingCounter{name: name, p: p}
+}
+
+// NewGauge implements Provider.
+func (p *RecordingProvider) NewGauge(name string) metrics.Gauge {
+	return &recordingGauge{name: name, p: p}
+}
+
+// NewHistogram implements Provider. Buckets are ignored, as all observations
+// are recorded.
+func (p *RecordingProvider) NewHistogram(name string, _ int) metrics.Histogram {
+	return &recordingHistogram{name: name, p: p}
+}
+
+// Stop implements Provider. It only records that it was called.
+func (p *RecordingProvider) Stop() {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.stopped = true
+}
+
+// Stopped returns true if Stop has been called.
+func (p *RecordingProvider) Stopped() bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.stopped
+}
+
+// CounterValue returns the sum of the deltas added to the named counter with
+// the given label values.
+func (p *RecordingProvider) CounterValue(name string, labelValues ...string) float64 {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.counters[recordingKey(name, labelValues)]
+}
+
+// GaugeValue returns the current value of the named gauge with the given
+// label values.
+func (p *RecordingProvider) GaugeValue(name string, labelValues ...string) float64 {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.gauges[recordingKey(name, labelValues)]
+}
+
+// HistogramObservations returns all of the values observed by the named
+// histogram with the given label values, in the order they were observed.
+func (p *RecordingProvider) HistogramObservations(name string, labelValues ...string) []float64 {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return append([]float64{}, p.histograms[recordingKey(name, labelValues)]...)
+}
+
+// Reset discards all recorded observations.
+func (p *RecordingProvider) Reset() {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.counters = map[string]float64{}
+	p.gauges = map[string]float64{}
+	p.histograms = map[string][]float64{}
+}
+
+// recordingKey identifies a timeseries by its name and label values, with the
+// label pairs sorted, so that queries don't depend on the order of With calls.
+func recordingKey(name string, labelValues []string) string {
+	lvs := lv.LabelValues{}.With(labelValues...)
+	pairs := make([]string, 0, len(lvs)/2)
+	for i := 0; i < len(lvs); i += 2 {
+		pairs = append(pairs, lvs[i]+"\x00"+lvs[i+1])
+	}
+	sort.Strings(pairs)
+	return name + "\x01" + strings.Join(pairs, "\x01")
+}
+
+type recordingCounter struct {
+	name string
+	lvs  lv.LabelValues
+	p    *RecordingProvider
+}
+
+func (c *recordingCounter) With(labelValues ...string) metrics.Counter {
+	return &recordingCounter{name: c.name, lvs: c.lvs.With(labelValues...), p: c.p}
+}
+
+func (c *recordingCounter) Add(delta float64) {
+	c.p.mtx.Lock()
+	defer c.p.mtx.Unlock()
+	c.p.counters[recordingKey(c.name, c.lvs)] += delta
+}
+
+type recordingGauge struct {
+	name string
+	lvs  lv.LabelValues
+	p    *RecordingProvider
+}
+
+func (g *recordingGauge) With(labelValues ...string) metrics.Gauge {
+	return &recordingGauge{name: g.name, lvs: g.lvs.With(labelValues...), p: g.p}
+}
+
+func (g *recordingGauge) Set(value float64) {
+	g.p.mtx.Lock()
+	defer g.p.mtx.Unlock()
+	g.p.gauges[recordingKey(g.name, g.lvs)] = value
+}
+
+func (g *recordingGauge) Add(delta float64) {
+	g.p.mtx.Lock()
+	defer g.p.mtx.Unlock()
+	g.p.gauges[recordingKey(g.name, g.lvs)] += delta
+}
+
+type recordingHistogram struct {
+	name string
+	lvs  lv.LabelValues
+	p    *RecordingProvider
+}
+
+func (h *recordingHistogram) With(labelValues ...string) metrics.Histogram {
+	return &recordingHistogram{name: h.name, lvs: h.lvs.With(labelValues...), p: h.p}
+}
+
+func (h *recordingHistogram) Observe(value float64) {
+	h.p.mtx.Lock()
+	defer h.p.mtx.Unlock()
+	key := recordingKey(h.name, h.lvs)
+	h.p.histograms[key] = append(h.p.histograms[key], value)
+}
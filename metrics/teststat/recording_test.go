@@ -0,0 +1,76 @@
+package teststat_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/barrett370/kit/v2/metrics/teststat"
+)
+
+func TestRecordingProviderLabelOrder(t *testing.T) {
+	p := teststat.NewRecordingProvider()
+	p.NewCounter("requests").With("method", "get").With("code", "200").Add(1)
+	p.NewCounter("requests").With("code", "200", "method", "get").Add(2)
+	p.NewGauge("inflight").With("b", "2", "a", "1").Set(3)
+	p.NewHistogram("latency", 50).With("a", "1").With("b", "2").Observe(4)
+
+	if want, have := 3.0, p.CounterValue("requests", "method", "get", "code", "200"); want != have {
+		t.Errorf("counter: want %f, have %f", want, have)
+	}
+	if want, have := 3.0, p.GaugeValue("inflight", "a", "1", "b", "2"); want != have {
+		t.Errorf("gauge: want %f, have %f", want, have)
+	}
+	if want, have := []float64{4}, p.HistogramObservations("latency", "b", "2", "a", "1"); !reflect.DeepEqual(want, have) {
+		t.Errorf("histogram: want %v, have %v", want, have)
+	}
+	if want, have := 0.0, p.CounterValue("requests", "method", "get"); want != have {
+		t.Errorf("counter with other labels: want %f, have %f", want, have)
+	}
+}
+
+func TestRecordingProviderReset(t *testing.T) {
+	p := teststat.NewRecordingProvider()
+	p.NewCounter("requests").Add(1)
+	p.NewGauge("inflight").Set(2)
+	p.NewHistogram("latency", 50).Observe(3)
+	p.Reset()
+
+	if want, have := 0.0, p.CounterValue("requests"); want != have {
+		t.Errorf("counter: want %f, have %f", want, have)
+	}
+	if want, have := 0.0, p.GaugeValue("inflight"); want != have {
+		t.Errorf("gauge: want %f, have %f", want, have)
+	}
+	if have := p.HistogramObservations("latency"); len(have) != 0 {
+		t.Errorf("histogram: want no observations, have %v", have)
+	}
+}
+
+func TestRecordingProviderObservationsCopy(t *testing.T) {
+	p := teststat.NewRecordingProvider()
+	h := p.NewHistogram("latency", 50)
+	h.Observe(1)
+	h.Observe(2)
+
+	observations := p.HistogramObservations("latency")
+	observations[0] = 99
+	h.Observe(3)
+
+	if want, have := []float64{1, 2, 3}, p.HistogramObservations("latency"); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := []float64{99, 2}, observations; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestRecordingProviderStop(t *testing.T) {
+	p := teststat.NewRecordingProvider()
+	if p.Stopped() {
+		t.Error("want not stopped")
+	}
+	p.Stop()
+	if !p.Stopped() {
+		t.Error("want stopped")
+	}
+}
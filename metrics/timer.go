@@ -29,8 +29,30 @@ func (t *Timer) ObserveDuration() {
 	t.h.Observe(d)
 }
 
-// Unit sets the unit of the float64 emitted by the timer.
-// By default, the timer emits seconds.
+// Unit sets the unit of the float64 emitted by the timer, typically
+// time.Second, time.Millisecond or time.Microsecond. By default, the timer
+// emits seconds.
 func (t *Timer) Unit(u time.Duration) {
 	t.u = u
 }
+
+// NewUnitHistogram wraps the given histogram, converting each observation
+// from seconds, as emitted by a Timer by default, to the given unit, e.g.
+// time.Millisecond. It allows instrumentation to consistently observe seconds,
+// even when the backend expects another unit, such as StatsD timings.
+func NewUnitHistogram(h Histogram, u time.Duration) Histogram {
+	return unitHistogram{h: h, factor: float64(time.Second) / float64(u)}
+}
+
+type unitHistogram struct {
+	h      Histogram
+	factor float64
+}
+
+func (h unitHistogram) With(labelValues ...string) Histogram {
+	return unitHistogram{h: h.h.With(labelValues...), factor: h.factor}
+}
+
+func (h unitHistogram) Observe(value float64) {
+	h.h.Observe(value * h.factor)
+}
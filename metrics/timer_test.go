@@ -56,3 +56,23 @@ func TestTimerUnit(t *testing.T) {
 		})
 	}
 }
+
+func TestUnitHistogram(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		unit time.Duration
+		want float64
+	}{
+		{"Seconds", time.Second, 1.5},
+		{"Milliseconds", time.Millisecond, 1500},
+		{"Microseconds", time.Microsecond, 1500000},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h := generic.NewSimpleHistogram()
+			metrics.NewUnitHistogram(h, tc.unit).Observe(1.5)
+			if want, have := tc.want, h.ApproximateMovingAverage(); want != have {
+				t.Errorf("want %f, have %f", want, have)
+			}
+		})
+	}
+}
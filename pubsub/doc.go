@@ -0,0 +1,11 @@
+// Package pubsub provides typed, broker-agnostic publishers and subscribers
+// for asynchronous messaging, so business code publishes and handles values,
+// and only the wiring of a service knows its broker.
+//
+// Brokers, such as NATS, AMQP, Kafka, or SQS, are adapted to the Sender and
+// Receiver interfaces, which deal in Messages of bytes. NewPublisher and
+// NewSubscriber build typed Publishers and Subscribers on them, with funcs to
+// encode and decode values, and endpoints and endpoint middlewares to handle
+// them, as in the other transports of this module. Memory is a broker in
+// memory, for tests and for messaging within a process.
+package pubsub
@@ -0,0 +1,5 @@
+// Package inbox deduplicates the messages received by subscribers, so
+// handlers of brokers which deliver messages at least once needn't each be
+// idempotent. The IDs of messages are recorded in a Store as they're
+// handled, and messages whose IDs are recorded are skipped.
+package inbox
@@ -0,0 +1,126 @@
+package inbox
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/barrett370/kit/v2/metrics"
+	"github.com/barrett370/kit/v2/pubsub"
+)
+
+// DefaultTTL is the default time for which the IDs of messages are recorded.
+const DefaultTTL = 24 * time.Hour
+
+// Store records the IDs of messages, for a time.
+type Store interface {
+	// Claim records the ID, for the TTL, and returns true, unless it's
+	// already recorded, in which case it returns false.
+	Claim(ctx context.Context, id string, ttl time.Duration) (bool, error)
+
+	// Release forgets the ID, e.g. after handling the message failed, so it
+	// may be handled again when it's redelivered.
+	Release(ctx context.Context, id string) error
+}
+
+// Option sets an optional parameter for Receivers.
+type Option func(*Receiver)
+
+// TTL sets the time for which IDs are recorded, which should exceed the time
+// in which the broker may redeliver a message. By default, it's DefaultTTL.
+func TTL(d time.Duration) Option {
+	return func(r *Receiver) { r.ttl = d }
+}
+
+// Duplicates sets a counter of the duplicate messages which are skipped. By
+// default, they're not counted.
+func Duplicates(counter metrics.Counter) Option {
+	return func(r *Receiver) { r.duplicates = counter }
+}
+
+// Receiver is a pubsub.Receiver which skips the messages of another whose
+// IDs are recorded in a Store, so they're handled once, within the TTL.
+// Messages without IDs are always handled. If the Store fails, messages are
+// returned to the broker with the error, to be redelivered.
+type Receiver struct {
+	next       pubsub.Receiver
+	store      Store
+	ttl        time.Duration
+	duplicates metrics.Counter
+}
+
+// NewReceiver returns a Receiver which deduplicates the messages of next.
+// Pass it to pubsub.NewSubscriber in place of next.
+func NewReceiver(next pubsub.Receiver, store Store, options ...Option) *Receiver {
+	r := &Receiver{next: next, store: store, ttl: DefaultTTL}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// Receive implements pubsub.Receiver.
+func (r *Receiver) Receive(ctx context.Context, topic string, handler func(ctx context.Context, m *pubsub.Message) error) error {
+	return r.next.Receive(ctx, topic, func(ctx context.Context, m *pubsub.Message) error {
+		if m.ID == "" {
+			return handler(ctx, m)
+		}
+		claimed, err := r.store.Claim(ctx, m.ID, r.ttl)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			if r.duplicates != nil {
+				r.duplicates.Add(1)
+			}
+			return nil
+		}
+		if err := handler(ctx, m); err != nil {
+			r.store.Release(ctx, m.ID)
+			return err
+		}
+		return nil
+	})
+}
+
+// MemoryStore is a Store in memory, which deduplicates the messages of a
+// single process.
+type MemoryStore struct {
+	mtx  sync.Mutex
+	ids  map[string]time.Time // expiry, by ID
+	next time.Time            // of the next sweep of expired IDs
+	now  func() time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{ids: map[string]time.Time{}, now: time.Now}
+}
+
+// Claim implements Store.
+func (s *MemoryStore) Claim(_ context.Context, id string, ttl time.Duration) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	now := s.now()
+	if now.After(s.next) {
+		for id, expiry := range s.ids {
+			if !now.Before(expiry) {
+				delete(s.ids, id)
+			}
+		}
+		s.next = now.Add(time.Minute)
+	}
+	if expiry, ok := s.ids[id]; ok && now.Before(expiry) {
+		return false, nil
+	}
+	s.ids[id] = now.Add(ttl)
+	return true, nil
+}
+
+// Release implements Store.
+func (s *MemoryStore) Release(_ context.Context, id string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	delete(s.ids, id)
+	return nil
+}
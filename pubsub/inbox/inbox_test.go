@@ -0,0 +1,73 @@
+package inbox
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/barrett370/kit/v2/metrics/generic"
+	"github.com/barrett370/kit/v2/pubsub"
+)
+
+// replay is a pubsub.Receiver which delivers its messages once, in order.
+type replay []*pubsub.Message
+
+func (r replay) Receive(ctx context.Context, _ string, handler func(context.Context, *pubsub.Message) error) error {
+	for _, m := range r {
+		handler(ctx, m)
+	}
+	return nil
+}
+
+func TestReceiver(t *testing.T) {
+	var (
+		messages = replay{
+			{ID: "1"}, {ID: "1"}, // a duplicate
+			{ID: "2"}, {ID: "2"}, // whose first handling fails
+			{}, {}, // without IDs
+		}
+		duplicates = generic.NewCounter("duplicates")
+		r          = NewReceiver(messages, NewMemoryStore(), Duplicates(duplicates))
+		failed     bool
+		handled    []string
+	)
+	r.Receive(context.Background(), "events", func(_ context.Context, m *pubsub.Message) error {
+		if m.ID == "2" && !failed {
+			failed = true
+			return errors.New("fail")
+		}
+		handled = append(handled, m.ID)
+		return nil
+	})
+	if want := []string{"1", "2", "", ""}; !reflect.DeepEqual(want, handled) {
+		t.Errorf("want %q, have %q", want, handled)
+	}
+	if want, have := 1.0, duplicates.Value(); want != have {
+		t.Errorf("want %v duplicates, have %v", want, have)
+	}
+}
+
+func TestMemoryStoreTTL(t *testing.T) {
+	var (
+		now   = time.Now()
+		s     = NewMemoryStore()
+		ctx   = context.Background()
+		claim = func() bool { ok, _ := s.Claim(ctx, "1", time.Hour); return ok }
+	)
+	s.now = func() time.Time { return now }
+	if !claim() {
+		t.Error("want the first claim to succeed")
+	}
+	if claim() {
+		t.Error("want the second claim to fail")
+	}
+	now = now.Add(time.Hour)
+	if !claim() {
+		t.Error("want a claim after the TTL to succeed")
+	}
+	if want, have := 1, len(s.ids); want != have {
+		t.Errorf("want %d IDs, have %d", want, have)
+	}
+}
@@ -0,0 +1,108 @@
+package pubsub
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+// DefaultMaxAttempts is the default number of times Memory delivers a message
+// whose handling fails.
+const DefaultMaxAttempts = 3
+
+// Memory is a broker in memory. Each call to Receive is a subscription of its
+// own, which receives all the messages sent to its topic from then on, in the
+// order they're sent; messages sent to a topic without subscriptions are
+// dropped. A message whose handling fails is delivered again, up to the max
+// attempts, and then dropped.
+type Memory struct {
+	maxAttempts int
+
+	mtx  sync.Mutex
+	seq  uint64
+	subs map[string]map[chan *Message]struct{} // by topic
+}
+
+// NewMemory returns a Memory broker which delivers messages up to maxAttempts
+// times, or DefaultMaxAttempts if it's 0.
+func NewMemory(maxAttempts int) *Memory {
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	return &Memory{
+		maxAttempts: maxAttempts,
+		subs:        map[string]map[chan *Message]struct{}{},
+	}
+}
+
+// Send implements Sender. It blocks while the buffer of a subscription is
+// full, until the context is done. Messages without IDs are given them.
+func (b *Memory) Send(ctx context.Context, m *Message) error {
+	b.mtx.Lock()
+	if m.ID == "" {
+		b.seq++
+		m.ID = strconv.FormatUint(b.seq, 10)
+	}
+	subs := make([]chan *Message, 0, len(b.subs[m.Topic]))
+	for c := range b.subs[m.Topic] {
+		subs = append(subs, c)
+	}
+	b.mtx.Unlock()
+
+	for _, c := range subs {
+		select {
+		case c <- clone(m):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Receive implements Receiver.
+func (b *Memory) Receive(ctx context.Context, topic string, handler func(ctx context.Context, m *Message) error) error {
+	c := make(chan *Message, 64)
+	b.mtx.Lock()
+	if b.subs[topic] == nil {
+		b.subs[topic] = map[chan *Message]struct{}{}
+	}
+	b.subs[topic][c] = struct{}{}
+	b.mtx.Unlock()
+	defer func() {
+		b.mtx.Lock()
+		delete(b.subs[topic], c)
+		b.mtx.Unlock()
+	}()
+
+	for {
+		select {
+		case m := <-c:
+			for attempt := 1; attempt <= b.maxAttempts && ctx.Err() == nil; attempt++ {
+				if handler(ctx, m) == nil {
+					break
+				}
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Subscribers returns the number of subscriptions to the topic, e.g. to wait
+// until subscribers are running.
+func (b *Memory) Subscribers(topic string) int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return len(b.subs[topic])
+}
+
+func clone(m *Message) *Message {
+	c := *m
+	if m.Headers != nil {
+		c.Headers = make(map[string]string, len(m.Headers))
+		for k, v := range m.Headers {
+			c.Headers[k] = v
+		}
+	}
+	return &c
+}
@@ -0,0 +1,12 @@
+// Package outbox implements the transactional outbox pattern for publishers,
+// so a service which changes its database and publishes an event about it
+// never does one without the other.
+//
+// Rather than publishing a message directly, a service enqueues it into an
+// outbox table, in the transaction of its change, so the message is stored
+// if and only if the change is committed. A Relay then publishes the pending
+// messages of the outbox, in order, and marks them sent once the broker has
+// confirmed them. Messages are published at least once: a message may be
+// published again if the relay stops after publishing it, but before marking
+// it sent, so subscribers should deduplicate them by ID.
+package outbox
@@ -0,0 +1,94 @@
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+
+	"github.com/barrett370/kit/v2/pubsub"
+)
+
+// Record is a message in an outbox.
+type Record struct {
+	Message  pubsub.Message // with the ID of the record
+	Attempts int            // failed attempts to publish it
+}
+
+// Store is an outbox. Messages are enqueued into it by means specific to the
+// store, e.g. in a database transaction.
+type Store interface {
+	// Pending returns up to n pending records, oldest first.
+	Pending(ctx context.Context, n int) ([]Record, error)
+
+	// MarkSent marks the record as sent, so it's no longer pending.
+	MarkSent(ctx context.Context, id string) error
+
+	// MarkFailed records a failed attempt to publish the record.
+	MarkFailed(ctx context.Context, id string, err error) error
+}
+
+// NewID returns a random ID for a message.
+func NewID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// MemoryStore is a Store in memory, for tests, and for services without a
+// database, whose messages aren't durable.
+type MemoryStore struct {
+	mtx     sync.Mutex
+	records []*Record
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Enqueue adds the message to the outbox, with an ID if it has none.
+func (s *MemoryStore) Enqueue(m pubsub.Message) {
+	if m.ID == "" {
+		m.ID = NewID()
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.records = append(s.records, &Record{Message: m})
+}
+
+// Pending implements Store.
+func (s *MemoryStore) Pending(_ context.Context, n int) ([]Record, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	var records []Record
+	for i := 0; i < len(s.records) && len(records) < n; i++ {
+		records = append(records, *s.records[i])
+	}
+	return records, nil
+}
+
+// MarkSent implements Store.
+func (s *MemoryStore) MarkSent(_ context.Context, id string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for i, r := range s.records {
+		if r.Message.ID == id {
+			s.records = append(s.records[:i], s.records[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// MarkFailed implements Store.
+func (s *MemoryStore) MarkFailed(_ context.Context, id string, _ error) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for _, r := range s.records {
+		if r.Message.ID == id {
+			r.Attempts++
+		}
+	}
+	return nil
+}
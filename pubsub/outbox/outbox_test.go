@@ -0,0 +1,96 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/barrett370/kit/v2/pubsub"
+	"github.com/barrett370/kit/v2/pubsub/outbox"
+	"github.com/go-kit/log"
+)
+
+// sender records the messages it sends, and fails while failing is set.
+type sender struct {
+	mtx     sync.Mutex
+	failing bool
+	sent    []string
+}
+
+func (s *sender) Send(_ context.Context, m *pubsub.Message) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.failing {
+		return errors.New("unavailable")
+	}
+	s.sent = append(s.sent, m.ID)
+	return nil
+}
+
+func (s *sender) setFailing(failing bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.failing = failing
+}
+
+func (s *sender) messages() []string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]string(nil), s.sent...)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRelay(t *testing.T) {
+	var (
+		store       = outbox.NewMemoryStore()
+		s           = &sender{failing: true}
+		relay       = outbox.NewRelay(store, s, log.NewNopLogger(), outbox.RelayInterval(time.Millisecond), outbox.RelayBatchSize(2))
+		ctx, cancel = context.WithCancel(context.Background())
+		errc        = make(chan error, 1)
+	)
+	for _, id := range []string{"a", "b", "c"} {
+		store.Enqueue(pubsub.Message{ID: id, Topic: "events"})
+	}
+	go func() { errc <- relay.Run(ctx) }()
+
+	// Failures are recorded, and nothing is published out of order.
+	waitFor(t, func() bool {
+		records, _ := store.Pending(ctx, 1)
+		return records[0].Attempts >= 2
+	})
+	if have := s.messages(); len(have) != 0 {
+		t.Errorf("want nothing sent, have %v", have)
+	}
+
+	s.setFailing(false)
+	waitFor(t, func() bool { return len(s.messages()) == 3 })
+	if want, have := []string{"a", "b", "c"}, s.messages(); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if records, _ := store.Pending(ctx, 10); len(records) != 0 {
+		t.Errorf("want no pending records, have %v", records)
+	}
+
+	// Notify publishes at once.
+	store.Enqueue(pubsub.Message{ID: "d", Topic: "events"})
+	relay.Notify()
+	waitFor(t, func() bool { return len(s.messages()) == 4 })
+
+	cancel()
+	if want, have := context.Canceled, <-errc; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
@@ -0,0 +1,116 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/barrett370/kit/v2/pubsub"
+	"github.com/go-kit/log"
+)
+
+// Defaults of Relays.
+const (
+	DefaultInterval  = time.Second
+	DefaultBatchSize = 100
+)
+
+// RelayOption sets an optional parameter for Relays.
+type RelayOption func(*Relay)
+
+// RelayInterval sets the interval at which the outbox is polled for pending
+// messages, which is also the delay before a failed message is retried. By
+// default, it's DefaultInterval.
+func RelayInterval(d time.Duration) RelayOption {
+	return func(r *Relay) { r.interval = d }
+}
+
+// RelayBatchSize sets the max number of pending messages read from the
+// outbox at once. By default, it's DefaultBatchSize.
+func RelayBatchSize(n int) RelayOption {
+	return func(r *Relay) { r.batch = n }
+}
+
+// Relay publishes the pending messages of an outbox.
+type Relay struct {
+	store    Store
+	sender   pubsub.Sender
+	logger   log.Logger
+	interval time.Duration
+	batch    int
+	notify   chan struct{}
+}
+
+// NewRelay returns a Relay which publishes the pending messages of the store
+// with the sender, which does nothing until it's Run.
+func NewRelay(store Store, sender pubsub.Sender, logger log.Logger, options ...RelayOption) *Relay {
+	r := &Relay{
+		store:    store,
+		sender:   sender,
+		logger:   logger,
+		interval: DefaultInterval,
+		batch:    DefaultBatchSize,
+		notify:   make(chan struct{}, 1),
+	}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// Notify makes the relay publish pending messages at once, rather than at
+// its next poll, e.g. after a transaction which enqueued one is committed.
+// It never blocks.
+func (r *Relay) Notify() {
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Run publishes pending messages until the context is done, and returns the
+// context's error. Messages are published in order: if one fails, it's
+// retried before any later message is published.
+func (r *Relay) Run(ctx context.Context) error {
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+	for {
+		for r.flush(ctx) {
+		}
+		select {
+		case <-t.C:
+		case <-r.notify:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// flush publishes a batch of pending messages, and returns true if there may
+// be more to publish at once.
+func (r *Relay) flush(ctx context.Context) bool {
+	records, err := r.store.Pending(ctx, r.batch)
+	if err != nil {
+		if ctx.Err() == nil {
+			r.logger.Log("during", "pending", "err", err)
+		}
+		return false
+	}
+	for _, record := range records {
+		m := record.Message
+		if err := r.sender.Send(ctx, &m); err != nil {
+			if ctx.Err() != nil {
+				return false
+			}
+			r.logger.Log("during", "send", "id", m.ID, "topic", m.Topic, "attempts", record.Attempts+1, "err", err)
+			if err := r.store.MarkFailed(ctx, m.ID, err); err != nil {
+				r.logger.Log("during", "mark_failed", "id", m.ID, "err", err)
+			}
+			return false
+		}
+		if err := r.store.MarkSent(ctx, m.ID); err != nil {
+			r.logger.Log("during", "mark_sent", "id", m.ID, "err", err)
+			return false
+		}
+	}
+	return len(records) == r.batch
+}
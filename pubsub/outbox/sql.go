@@ -0,0 +1,148 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/barrett370/kit/v2/pubsub"
+)
+
+// Execer executes statements, like *sql.DB, *sql.Tx, and *sql.Conn.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// SQLStore is a Store in a table of an SQL database, with the schema, e.g.
+// for PostgreSQL,
+//
+//	CREATE TABLE outbox (
+//		id         VARCHAR(64) PRIMARY KEY,
+//		topic      VARCHAR(255) NOT NULL,
+//		msg_key    VARCHAR(255) NOT NULL,
+//		headers    TEXT NOT NULL,
+//		data       BYTEA NOT NULL,
+//		created_at TIMESTAMP NOT NULL,
+//		sent_at    TIMESTAMP,
+//		attempts   INTEGER NOT NULL DEFAULT 0
+//	);
+//	CREATE INDEX outbox_pending ON outbox (created_at) WHERE sent_at IS NULL;
+//
+// Sent records are kept, for auditing; delete them periodically.
+type SQLStore struct {
+	db          *sql.DB
+	table       string
+	placeholder func(n int) string
+	now         func() time.Time
+}
+
+// SQLStoreOption sets an optional parameter for SQLStores.
+type SQLStoreOption func(*SQLStore)
+
+// SQLStoreDollarPlaceholders makes the store use placeholders of the form
+// $1, as PostgreSQL requires. By default, placeholders are ?, as in MySQL
+// and SQLite.
+func SQLStoreDollarPlaceholders() SQLStoreOption {
+	return func(s *SQLStore) { s.placeholder = func(n int) string { return fmt.Sprintf("$%d", n) } }
+}
+
+// NewSQLStore returns an SQLStore of the table of the database.
+func NewSQLStore(db *sql.DB, table string, options ...SQLStoreOption) *SQLStore {
+	s := &SQLStore{
+		db:          db,
+		table:       table,
+		placeholder: func(int) string { return "?" },
+		now:         time.Now,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// query returns the query, with its placeholders, written as ?, replaced with
+// those of the store.
+func (s *SQLStore) query(q string) string {
+	var (
+		b strings.Builder
+		n int
+	)
+	for _, c := range q {
+		if c == '?' {
+			n++
+			b.WriteString(s.placeholder(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// Enqueue adds the message to the outbox, with an ID if it has none, in the
+// transaction of the change it's about, so it's stored if and only if the
+// change is committed:
+//
+//	tx, err := db.BeginTx(ctx, nil)
+//	...
+//	if _, err := tx.ExecContext(ctx, "UPDATE orders SET status = 'paid' WHERE id = $1", id); err != nil {
+//		return err
+//	}
+//	if err := store.Enqueue(ctx, tx, &pubsub.Message{Topic: "orders.paid", Data: data}); err != nil {
+//		return err
+//	}
+//	return tx.Commit()
+func (s *SQLStore) Enqueue(ctx context.Context, tx Execer, m *pubsub.Message) error {
+	if m.ID == "" {
+		m.ID = NewID()
+	}
+	headers, err := json.Marshal(m.Headers)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, s.query(`INSERT INTO `+s.table+
+		` (id, topic, msg_key, headers, data, created_at, attempts) VALUES (?, ?, ?, ?, ?, ?, 0)`),
+		m.ID, m.Topic, m.Key, string(headers), m.Data, s.now().UTC(),
+	)
+	return err
+}
+
+// Pending implements Store.
+func (s *SQLStore) Pending(ctx context.Context, n int) ([]Record, error) {
+	rows, err := s.db.QueryContext(ctx, s.query(`SELECT id, topic, msg_key, headers, data, attempts FROM `+s.table+
+		` WHERE sent_at IS NULL ORDER BY created_at, id LIMIT ?`), n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		var (
+			r       Record
+			headers string
+		)
+		if err := rows.Scan(&r.Message.ID, &r.Message.Topic, &r.Message.Key, &headers, &r.Message.Data, &r.Attempts); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(headers), &r.Message.Headers); err != nil {
+			return nil, fmt.Errorf("outbox record %s: %w", r.Message.ID, err)
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// MarkSent implements Store.
+func (s *SQLStore) MarkSent(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, s.query(`UPDATE `+s.table+` SET sent_at = ? WHERE id = ?`), s.now().UTC(), id)
+	return err
+}
+
+// MarkFailed implements Store.
+func (s *SQLStore) MarkFailed(ctx context.Context, id string, _ error) error {
+	_, err := s.db.ExecContext(ctx, s.query(`UPDATE `+s.table+` SET attempts = attempts + 1 WHERE id = ?`), id)
+	return err
+}
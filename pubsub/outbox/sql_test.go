@@ -0,0 +1,134 @@
+package outbox_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/barrett370/kit/v2/pubsub"
+	"github.com/barrett370/kit/v2/pubsub/outbox"
+)
+
+// recorder is a database/sql driver which records the statements it
+// executes, and answers queries with its rows.
+type recorder struct {
+	mtx        sync.Mutex
+	statements []string
+	args       [][]driver.Value
+	rows       [][]driver.Value
+}
+
+func (r *recorder) Open(string) (driver.Conn, error)             { return conn{r}, nil }
+func (r *recorder) Connect(context.Context) (driver.Conn, error) { return conn{r}, nil }
+func (r *recorder) Driver() driver.Driver                        { return r }
+
+type conn struct{ r *recorder }
+
+func (c conn) Prepare(query string) (driver.Stmt, error) { return stmt{c.r, query}, nil }
+func (c conn) Close() error                              { return nil }
+func (c conn) Begin() (driver.Tx, error)                 { return tx{}, nil }
+
+type tx struct{}
+
+func (tx) Commit() error   { return nil }
+func (tx) Rollback() error { return nil }
+
+type stmt struct {
+	r     *recorder
+	query string
+}
+
+func (s stmt) Close() error  { return nil }
+func (s stmt) NumInput() int { return -1 }
+
+func (s stmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.record(args)
+	return driver.RowsAffected(1), nil
+}
+
+func (s stmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.record(args)
+	return &rows{values: s.r.rows}, nil
+}
+
+func (s stmt) record(args []driver.Value) {
+	s.r.mtx.Lock()
+	defer s.r.mtx.Unlock()
+	s.r.statements = append(s.r.statements, s.query)
+	s.r.args = append(s.r.args, args)
+}
+
+type rows struct{ values [][]driver.Value }
+
+func (r *rows) Columns() []string {
+	return []string{"id", "topic", "msg_key", "headers", "data", "attempts"}
+}
+func (r *rows) Close() error { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func TestSQLStore(t *testing.T) {
+	r := &recorder{rows: [][]driver.Value{
+		{"a", "events", "k", `{"Tenant":"acme"}`, []byte("data"), int64(2)},
+	}}
+	db := sql.OpenDB(r)
+	defer db.Close()
+
+	var (
+		ctx   = context.Background()
+		store = outbox.NewSQLStore(db, "outbox", outbox.SQLStoreDollarPlaceholders())
+		m     = &pubsub.Message{Topic: "events", Data: []byte("data")}
+	)
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Enqueue(ctx, tx, m); err != nil {
+		t.Fatal(err)
+	}
+	tx.Commit()
+	if m.ID == "" {
+		t.Error("want an ID, have none")
+	}
+
+	records, err := store.Pending(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []outbox.Record{{
+		Message:  pubsub.Message{ID: "a", Topic: "events", Key: "k", Headers: map[string]string{"Tenant": "acme"}, Data: []byte("data")},
+		Attempts: 2,
+	}}
+	if !reflect.DeepEqual(want, records) {
+		t.Errorf("want %+v, have %+v", want, records)
+	}
+
+	store.MarkSent(ctx, "a")
+	store.MarkFailed(ctx, "b", nil)
+
+	for i, prefix := range []string{
+		"INSERT INTO outbox (id, topic, msg_key, headers, data, created_at, attempts) VALUES ($1, $2, $3, $4, $5, $6, 0)",
+		"SELECT id, topic, msg_key, headers, data, attempts FROM outbox WHERE sent_at IS NULL ORDER BY created_at, id LIMIT $1",
+		"UPDATE outbox SET sent_at = $1 WHERE id = $2",
+		"UPDATE outbox SET attempts = attempts + 1 WHERE id = $1",
+	} {
+		if !strings.HasPrefix(r.statements[i], prefix) {
+			t.Errorf("want %q, have %q", prefix, r.statements[i])
+		}
+	}
+	if want, have := m.ID, r.args[0][0]; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
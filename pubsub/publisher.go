@@ -0,0 +1,85 @@
+package pubsub
+
+import (
+	"context"
+
+	"github.com/barrett370/kit/v2/endpoint"
+)
+
+// Publisher publishes values.
+type Publisher[T any] interface {
+	Publish(ctx context.Context, v T) error
+}
+
+// PublisherFunc is an adapter to allow the use of ordinary functions as
+// Publishers.
+type PublisherFunc[T any] func(ctx context.Context, v T) error
+
+// Publish implements Publisher.
+func (f PublisherFunc[T]) Publish(ctx context.Context, v T) error {
+	return f(ctx, v)
+}
+
+// PublisherOption sets an optional parameter for publishers.
+type PublisherOption[T any] func(*publisher[T])
+
+// PublisherBefore functions are executed on each message after the value is
+// encoded, before it's sent, e.g. to propagate metadata in its headers.
+func PublisherBefore[T any](before ...MessageFunc) PublisherOption[T] {
+	return func(p *publisher[T]) { p.before = append(p.before, before...) }
+}
+
+// PublisherMiddleware applies endpoint middlewares to publishing, e.g. rate
+// limiting, circuit breaking, or instrumentation. The first is outermost.
+func PublisherMiddleware[T any](mw ...endpoint.Middleware[T, struct{}]) PublisherOption[T] {
+	return func(p *publisher[T]) { p.middleware = append(p.middleware, mw...) }
+}
+
+// PublisherKey sets a func which derives the key of each message from the
+// value, e.g. its ID, for brokers which partition or order messages by key.
+func PublisherKey[T any](key func(v T) string) PublisherOption[T] {
+	return func(p *publisher[T]) { p.key = key }
+}
+
+type publisher[T any] struct {
+	sender     Sender
+	topic      string
+	enc        EncodeFunc[T]
+	before     []MessageFunc
+	middleware []endpoint.Middleware[T, struct{}]
+	key        func(T) string
+	e          endpoint.Endpoint[T, struct{}]
+}
+
+// NewPublisher returns a Publisher which encodes values into messages, and
+// sends them to the topic.
+func NewPublisher[T any](s Sender, topic string, enc EncodeFunc[T], options ...PublisherOption[T]) Publisher[T] {
+	p := &publisher[T]{sender: s, topic: topic, enc: enc}
+	for _, option := range options {
+		option(p)
+	}
+	p.e = p.send
+	for i := len(p.middleware) - 1; i >= 0; i-- {
+		p.e = p.middleware[i](p.e)
+	}
+	return p
+}
+
+func (p *publisher[T]) Publish(ctx context.Context, v T) error {
+	_, err := p.e(ctx, v)
+	return err
+}
+
+func (p *publisher[T]) send(ctx context.Context, v T) (struct{}, error) {
+	m := &Message{Topic: p.topic}
+	if p.key != nil {
+		m.Key = p.key(v)
+	}
+	if err := p.enc(ctx, v, m); err != nil {
+		return struct{}{}, err
+	}
+	for _, f := range p.before {
+		ctx = f(ctx, m)
+	}
+	return struct{}{}, p.sender.Send(ctx, m)
+}
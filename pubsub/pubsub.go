@@ -0,0 +1,62 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Message is a message of a broker.
+type Message struct {
+	ID      string            // unique, for deduplication, if the broker sets it
+	Topic   string            // the topic, subject, queue, or stream
+	Key     string            // the partition or ordering key, if any
+	Headers map[string]string // e.g. metadata, or trace context
+	Data    []byte
+}
+
+// Sender sends messages to a broker. It's implemented by adapters of
+// brokers. Send returns once the broker has accepted the message, e.g. once
+// it's confirmed it, so it's not lost.
+type Sender interface {
+	Send(ctx context.Context, m *Message) error
+}
+
+// Receiver receives messages from a broker. It's implemented by adapters of
+// brokers. Receive passes the messages of the topic to the handler until the
+// context is done, and returns the context's error, or an error of the
+// broker. Messages for which the handler returns nil are acknowledged, and
+// others are redelivered, or dead-lettered, as the broker is configured.
+type Receiver interface {
+	Receive(ctx context.Context, topic string, handler func(ctx context.Context, m *Message) error) error
+}
+
+// EncodeFunc encodes a value into the data, and maybe headers, of a message.
+type EncodeFunc[T any] func(ctx context.Context, v T, m *Message) error
+
+// DecodeFunc decodes a value from a message.
+type DecodeFunc[T any] func(ctx context.Context, m *Message) (T, error)
+
+// MessageFunc may take information from a message, e.g. its headers, and
+// put it in the context, or the other way around.
+type MessageFunc func(ctx context.Context, m *Message) context.Context
+
+// EncodeJSON is an EncodeFunc which encodes values as JSON.
+func EncodeJSON[T any](_ context.Context, v T, m *Message) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	m.Data = data
+	if m.Headers == nil {
+		m.Headers = map[string]string{}
+	}
+	m.Headers["Content-Type"] = "application/json"
+	return nil
+}
+
+// DecodeJSON is a DecodeFunc which decodes values from JSON.
+func DecodeJSON[T any](_ context.Context, m *Message) (T, error) {
+	var v T
+	err := json.Unmarshal(m.Data, &v)
+	return v, err
+}
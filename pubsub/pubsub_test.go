@@ -0,0 +1,131 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/pubsub"
+	"github.com/barrett370/kit/v2/transport"
+)
+
+type event struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// subscribe runs the subscriber until the test ends, once it's receiving.
+func subscribe(t *testing.T, b *pubsub.Memory, s pubsub.Subscriber[event]) {
+	t.Helper()
+	var (
+		n           = b.Subscribers("events")
+		ctx, cancel = context.WithCancel(context.Background())
+		done        = make(chan struct{})
+	)
+	go func() { defer close(done); s.Run(ctx) }()
+	t.Cleanup(func() { cancel(); <-done })
+	for b.Subscribers("events") == n {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	var (
+		b        = pubsub.NewMemory(0)
+		received = make(chan event, 1)
+		headers  = make(chan string, 1)
+		s        = pubsub.NewSubscriber(b, "events", pubsub.DecodeJSON[event], func(ctx context.Context, e event) (struct{}, error) {
+			received <- e
+			return struct{}{}, nil
+		}, pubsub.SubscriberBefore[event](func(ctx context.Context, m *pubsub.Message) context.Context {
+			headers <- m.Headers["Tenant"] + " " + m.Key
+			return ctx
+		}))
+		p = pubsub.NewPublisher(b, "events", pubsub.EncodeJSON[event],
+			pubsub.PublisherKey(func(e event) string { return e.ID }),
+			pubsub.PublisherBefore[event](func(ctx context.Context, m *pubsub.Message) context.Context {
+				m.Headers["Tenant"] = "acme"
+				return ctx
+			}),
+		)
+	)
+	subscribe(t, b, s)
+
+	want := event{ID: "1", Name: "created"}
+	if err := p.Publish(context.Background(), want); err != nil {
+		t.Fatal(err)
+	}
+	if have := <-received; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := "acme 1", <-headers; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestPublisherMiddleware(t *testing.T) {
+	var (
+		errDenied = errors.New("denied")
+		deny      = func(endpoint.Endpoint[event, struct{}]) endpoint.Endpoint[event, struct{}] {
+			return func(context.Context, event) (struct{}, error) { return struct{}{}, errDenied }
+		}
+		p = pubsub.NewPublisher(pubsub.NewMemory(0), "events", pubsub.EncodeJSON[event], pubsub.PublisherMiddleware(deny))
+	)
+	if err := p.Publish(context.Background(), event{}); err != errDenied {
+		t.Errorf("want %v, have %v", errDenied, err)
+	}
+}
+
+func TestDelivery(t *testing.T) {
+	for _, tc := range []struct {
+		delivery pubsub.Delivery
+		want     int
+	}{
+		{pubsub.AtLeastOnce, 3},
+		{pubsub.AtMostOnce, 1},
+	} {
+		var (
+			mtx      sync.Mutex
+			attempts int
+			handled  = make(chan struct{}, 10)
+			b        = pubsub.NewMemory(3)
+			s        = pubsub.NewSubscriber(b, "events", pubsub.DecodeJSON[event], func(context.Context, event) (struct{}, error) {
+				mtx.Lock()
+				attempts++
+				mtx.Unlock()
+				handled <- struct{}{}
+				return struct{}{}, errors.New("fail")
+			}, pubsub.SubscriberDelivery[event](tc.delivery))
+		)
+		subscribe(t, b, s)
+		pubsub.NewPublisher(b, "events", pubsub.EncodeJSON[event]).Publish(context.Background(), event{})
+		<-handled
+		time.Sleep(20 * time.Millisecond)
+		mtx.Lock()
+		if want, have := tc.want, attempts; want != have {
+			t.Errorf("delivery %d: want %d attempts, have %d", tc.delivery, want, have)
+		}
+		mtx.Unlock()
+	}
+}
+
+func TestSubscriberDecodeError(t *testing.T) {
+	var (
+		b      = pubsub.NewMemory(0)
+		stages = make(chan transport.Stage, 1)
+		s      = pubsub.NewSubscriber(b, "events", pubsub.DecodeJSON[event], func(context.Context, event) (struct{}, error) {
+			t.Error("want no call of the endpoint")
+			return struct{}{}, nil
+		}, pubsub.SubscriberErrorHandler[event](transport.ErrorHandlerFunc(func(ctx context.Context, err error) {
+			stages <- transport.StageFromContext(ctx)
+		})))
+	)
+	subscribe(t, b, s)
+	b.Send(context.Background(), &pubsub.Message{Topic: "events", Data: []byte("{")})
+	if want, have := transport.StageDecode, <-stages; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
@@ -0,0 +1,101 @@
+package pubsub
+
+import (
+	"context"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/transport"
+	"github.com/go-kit/log"
+)
+
+// Subscriber receives values, and handles them.
+type Subscriber[T any] interface {
+	// Run receives and handles values until the context is done, and
+	// returns the context's error, or an error of the broker.
+	Run(ctx context.Context) error
+}
+
+// Delivery is the delivery semantics of a subscriber.
+type Delivery int
+
+const (
+	// AtLeastOnce acknowledges messages once they're handled, so messages
+	// whose handling fails, or is interrupted, are redelivered. Handlers
+	// must be idempotent, or deduplicated.
+	AtLeastOnce Delivery = iota
+
+	// AtMostOnce acknowledges messages whether or not their handling
+	// fails, so they're never redelivered, but may be lost.
+	AtMostOnce
+)
+
+// SubscriberOption sets an optional parameter for subscribers.
+type SubscriberOption[T any] func(*subscriber[T])
+
+// SubscriberBefore functions are executed on each message before it's
+// decoded, e.g. to take metadata or a trace context from its headers.
+func SubscriberBefore[T any](before ...MessageFunc) SubscriberOption[T] {
+	return func(s *subscriber[T]) { s.before = append(s.before, before...) }
+}
+
+// SubscriberDelivery sets the delivery semantics. By default, it's
+// AtLeastOnce.
+func SubscriberDelivery[T any](d Delivery) SubscriberOption[T] {
+	return func(s *subscriber[T]) { s.delivery = d }
+}
+
+// SubscriberErrorHandler is used to handle errors decoding and handling
+// messages. By default, they're ignored.
+func SubscriberErrorHandler[T any](errorHandler transport.ErrorHandler) SubscriberOption[T] {
+	return func(s *subscriber[T]) { s.errorHandler = errorHandler }
+}
+
+type subscriber[T any] struct {
+	receiver     Receiver
+	topic        string
+	dec          DecodeFunc[T]
+	e            endpoint.Endpoint[T, struct{}]
+	before       []MessageFunc
+	delivery     Delivery
+	errorHandler transport.ErrorHandler
+}
+
+// NewSubscriber returns a Subscriber which receives the messages of the
+// topic, decodes them, and handles the values with the endpoint, to which
+// middlewares may be applied as usual.
+func NewSubscriber[T any](r Receiver, topic string, dec DecodeFunc[T], e endpoint.Endpoint[T, struct{}], options ...SubscriberOption[T]) Subscriber[T] {
+	s := &subscriber[T]{
+		receiver:     r,
+		topic:        topic,
+		dec:          dec,
+		e:            e,
+		errorHandler: transport.NewLogErrorHandler(log.NewNopLogger()),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+func (s *subscriber[T]) Run(ctx context.Context) error {
+	return s.receiver.Receive(ctx, s.topic, s.handle)
+}
+
+func (s *subscriber[T]) handle(ctx context.Context, m *Message) error {
+	for _, f := range s.before {
+		ctx = f(ctx, m)
+	}
+	v, err := s.dec(ctx, m)
+	if err != nil {
+		// Messages which can't be decoded would fail again if redelivered.
+		s.errorHandler.Handle(transport.ContextWithStage(ctx, transport.StageDecode), err)
+		return nil
+	}
+	if _, err := s.e(ctx, v); err != nil {
+		s.errorHandler.Handle(transport.ContextWithStage(ctx, transport.StageEndpoint), err)
+		if s.delivery == AtLeastOnce {
+			return err
+		}
+	}
+	return nil
+}
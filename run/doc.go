@@ -0,0 +1,8 @@
+// Package run runs the components of a service, such as servers, registrars
+// and metric loops, as a group: when any of them stops, or the process is
+// signaled, they're all stopped, in the reverse order they were added.
+//
+// It's in the style of github.com/oklog/run, with contexts for interruption,
+// so components with Run(ctx) methods, e.g. admin.Server and
+// sd.RegistrarGroup, are added as they are.
+package run
@@ -0,0 +1,185 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+// DefaultShutdownTimeout is how long each actor has to return once it's
+// interrupted.
+const DefaultShutdownTimeout = 10 * time.Second
+
+// SignalError is returned by Run when the process receives a signal.
+type SignalError struct {
+	Signal os.Signal
+}
+
+func (e SignalError) Error() string {
+	return fmt.Sprintf("received signal %s", e.Signal)
+}
+
+// Option sets an optional parameter for Groups.
+type Option func(*Group)
+
+// WithSignals sets the signals which stop the group. By default, they're
+// os.Interrupt and syscall.SIGTERM; with none, signals aren't handled.
+func WithSignals(signals ...os.Signal) Option {
+	return func(g *Group) { g.signals = signals }
+}
+
+// WithShutdownTimeout sets how long each actor has to return once it's
+// interrupted, before the next one is interrupted regardless. By default,
+// it's DefaultShutdownTimeout.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(g *Group) { g.timeout = d }
+}
+
+// WithDrain calls drain when the group starts to stop, and waits for the
+// delay before interrupting any actor, e.g. to fail readiness with
+// health.Health's Drain, and give load balancers time to notice, so that
+// requests in flight aren't refused.
+func WithDrain(drain func(), delay time.Duration) Option {
+	return func(g *Group) { g.drain, g.drainDelay = drain, delay }
+}
+
+// WithLogger logs the actors as they start and stop. By default, nothing is
+// logged.
+func WithLogger(logger log.Logger) Option {
+	return func(g *Group) { g.logger = logger }
+}
+
+type actor struct {
+	name      string
+	execute   func(ctx context.Context) error
+	interrupt func(error) // if execute doesn't stop when its context is done
+}
+
+// Group is a group of actors, which run concurrently until the first of them
+// returns, and are then all interrupted. Its zero value isn't usable; use
+// NewGroup.
+type Group struct {
+	actors     []actor
+	signals    []os.Signal
+	timeout    time.Duration
+	drain      func()
+	drainDelay time.Duration
+	logger     log.Logger
+}
+
+// NewGroup returns an empty Group.
+func NewGroup(options ...Option) *Group {
+	g := &Group{
+		signals: []os.Signal{os.Interrupt, syscall.SIGTERM},
+		timeout: DefaultShutdownTimeout,
+		logger:  log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(g)
+	}
+	return g
+}
+
+// Add adds an actor, which runs until its context is done, and then returns
+// promptly, e.g. admin.Server's Run. Actors are started in the order they're
+// added, and interrupted in the reverse order, so add servers before the
+// registrars which advertise them, so they're deregistered before they stop.
+func (g *Group) Add(name string, execute func(ctx context.Context) error) {
+	g.actors = append(g.actors, actor{name: name, execute: execute})
+}
+
+// AddInterrupt adds an actor in the style of github.com/oklog/run: execute
+// runs until interrupt is called, with the error which is stopping the group,
+// e.g. an http.Server's ListenAndServe and Shutdown.
+func (g *Group) AddInterrupt(name string, execute func() error, interrupt func(error)) {
+	g.actors = append(g.actors, actor{
+		name:      name,
+		execute:   func(context.Context) error { return execute() },
+		interrupt: interrupt,
+	})
+}
+
+// Run runs the actors until the first of them returns, the context is done,
+// or the process receives one of the signals, and then interrupts them all,
+// in the reverse order they were added, waiting for each to return before
+// interrupting the next. It returns the error which stopped the group: that
+// of the first actor to return, the context's, or a SignalError.
+func (g *Group) Run(ctx context.Context) error {
+	if len(g.actors) == 0 {
+		return nil
+	}
+
+	type exit struct {
+		i   int
+		err error
+	}
+	var (
+		exits   = make(chan exit, len(g.actors))
+		cancels = make([]context.CancelFunc, len(g.actors))
+		done    = make([]chan struct{}, len(g.actors))
+	)
+	for i, a := range g.actors {
+		actx, cancel := context.WithCancel(context.Background())
+		cancels[i], done[i] = cancel, make(chan struct{})
+		g.logger.Log("actor", a.name, "action", "start")
+		go func(i int, a actor) {
+			defer close(done[i])
+			exits <- exit{i, a.execute(actx)}
+		}(i, a)
+	}
+
+	var sigs chan os.Signal
+	if len(g.signals) > 0 {
+		sigs = make(chan os.Signal, 1)
+		signal.Notify(sigs, g.signals...)
+		defer signal.Stop(sigs)
+	}
+
+	var (
+		cause   error
+		stopped = -1 // the actor which returned first, if any
+	)
+	select {
+	case e := <-exits:
+		cause, stopped = e.err, e.i
+		g.logger.Log("actor", g.actors[e.i].name, "action", "stop", "err", e.err)
+	case <-ctx.Done():
+		cause = ctx.Err()
+	case sig := <-sigs:
+		cause = SignalError{Signal: sig}
+	}
+	g.logger.Log("action", "shutdown", "cause", cause)
+
+	if g.drain != nil {
+		g.drain()
+		if g.drainDelay > 0 && stopped < 0 {
+			time.Sleep(g.drainDelay)
+		}
+	}
+
+	for i := len(g.actors) - 1; i >= 0; i-- {
+		a := g.actors[i]
+		if i != stopped {
+			cancels[i]()
+			if a.interrupt != nil {
+				a.interrupt(cause)
+			}
+		}
+		t := time.NewTimer(g.timeout)
+		select {
+		case <-done[i]:
+			if i != stopped {
+				g.logger.Log("actor", a.name, "action", "stop")
+			}
+		case <-t.C:
+			g.logger.Log("actor", a.name, "action", "stop", "err", "timeout")
+		}
+		t.Stop()
+	}
+	return cause
+}
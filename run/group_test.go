@@ -0,0 +1,108 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	mtx    sync.Mutex
+	events []string
+}
+
+func (r *recorder) record(event string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) get() []string {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+// waiter returns an actor which runs until its context is done.
+func waiter(r *recorder, name string) func(context.Context) error {
+	return func(ctx context.Context) error {
+		<-ctx.Done()
+		r.record("stop " + name)
+		return ctx.Err()
+	}
+}
+
+func TestGroupFirstError(t *testing.T) {
+	var (
+		r    recorder
+		g    = NewGroup(WithSignals())
+		boom = errors.New("boom")
+	)
+	g.Add("a", waiter(&r, "a"))
+	g.Add("b", func(context.Context) error { time.Sleep(10 * time.Millisecond); return boom })
+	g.Add("c", waiter(&r, "c"))
+
+	if want, have := boom, g.Run(context.Background()); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := []string{"stop c", "stop a"}, r.get(); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestGroupContext(t *testing.T) {
+	var (
+		r           recorder
+		g           = NewGroup(WithSignals(), WithDrain(func() { r.record("drain") }, time.Millisecond))
+		ctx, cancel = context.WithCancel(context.Background())
+		interrupted error
+	)
+	g.Add("server", waiter(&r, "server"))
+	quit := make(chan struct{})
+	g.AddInterrupt("loop", func() error { <-quit; r.record("stop loop"); return nil }, func(err error) { interrupted = err; close(quit) })
+	g.Add("registrar", waiter(&r, "registrar"))
+
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if want, have := context.Canceled, g.Run(ctx); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := []string{"drain", "stop registrar", "stop loop", "stop server"}, r.get(); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := context.Canceled, interrupted; want != have {
+		t.Errorf("interrupt: want %v, have %v", want, have)
+	}
+}
+
+func TestGroupShutdownTimeout(t *testing.T) {
+	var (
+		r     recorder
+		g     = NewGroup(WithSignals(), WithShutdownTimeout(10*time.Millisecond))
+		stuck = make(chan struct{})
+	)
+	defer close(stuck)
+	g.Add("a", waiter(&r, "a"))
+	g.Add("stuck", func(context.Context) error { <-stuck; return nil })
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	errc := make(chan error, 1)
+	go func() { errc <- g.Run(ctx) }()
+	select {
+	case <-errc:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't return despite the shutdown timeout")
+	}
+	if want, have := []string{"stop a"}, r.get(); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestGroupEmpty(t *testing.T) {
+	if err := NewGroup().Run(context.Background()); err != nil {
+		t.Errorf("want nil, have %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package run
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestGroupSignal(t *testing.T) {
+	var (
+		r recorder
+		g = NewGroup(WithSignals(syscall.SIGUSR1))
+	)
+	g.Add("a", waiter(&r, "a"))
+	time.AfterFunc(10*time.Millisecond, func() { syscall.Kill(os.Getpid(), syscall.SIGUSR1) })
+	err := g.Run(context.Background())
+	if want, have := (SignalError{Signal: syscall.SIGUSR1}), err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
@@ -0,0 +1,241 @@
+package consul
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Health check statuses.
+const (
+	HealthPassing  = "passing"
+	HealthWarning  = "warning"
+	HealthCritical = "critical"
+)
+
+// AgentServiceRegistration describes a service to register with the local
+// agent. It mirrors the type of the same name in the Consul API package.
+type AgentServiceRegistration struct {
+	ID        string             `json:",omitempty"`
+	Name      string             `json:",omitempty"`
+	Tags      []string           `json:",omitempty"`
+	Port      int                `json:",omitempty"`
+	Address   string             `json:",omitempty"`
+	Meta      map[string]string  `json:",omitempty"`
+	Namespace string             `json:",omitempty"`
+	Check     *AgentServiceCheck `json:",omitempty"`
+}
+
+// AgentServiceCheck describes the health check of a registered service. For
+// a TTL check, set TTL, and the Registrar will send heartbeats.
+type AgentServiceCheck struct {
+	CheckID                        string `json:",omitempty"`
+	Name                           string `json:",omitempty"`
+	TTL                            string `json:",omitempty"`
+	HTTP                           string `json:",omitempty"`
+	TCP                            string `json:",omitempty"`
+	Interval                       string `json:",omitempty"`
+	Timeout                        string `json:",omitempty"`
+	DeregisterCriticalServiceAfter string `json:",omitempty"`
+}
+
+// ServiceEntry is an instance of a service, with its health checks.
+type ServiceEntry struct {
+	Node    *Node
+	Service *AgentService
+	Checks  []*HealthCheck
+}
+
+// Node is the node a service instance runs on.
+type Node struct {
+	Node    string
+	Address string
+}
+
+// AgentService is a service instance.
+type AgentService struct {
+	ID      string
+	Service string
+	Tags    []string
+	Address string
+	Port    int
+	Meta    map[string]string
+	Weights AgentWeights
+}
+
+// AgentWeights are the weights of a service instance, for load balancing,
+// when its health is passing, or when it has warnings.
+type AgentWeights struct {
+	Passing int
+	Warning int
+}
+
+// HealthCheck is the state of a health check.
+type HealthCheck struct {
+	CheckID string
+	Status  string
+}
+
+// QueryOptions are the parameters of a query.
+type QueryOptions struct {
+	// WaitIndex makes the query a blocking query, which returns once the
+	// results change from those at the index, or WaitTime elapses.
+	WaitIndex uint64
+	WaitTime  time.Duration
+
+	// Filter is a filter expression, e.g. `"primary" in Service.Tags`.
+	Filter string
+
+	// Namespace is the Consul Enterprise namespace to query.
+	Namespace string
+}
+
+// QueryMeta is the metadata of a query's results.
+type QueryMeta struct {
+	// LastIndex is the index of the results, for the next blocking query.
+	LastIndex uint64
+}
+
+// Client is a wrapper around the Consul API.
+type Client interface {
+	// Register a service with the local agent.
+	Register(r *AgentServiceRegistration) error
+
+	// Deregister a service with the local agent.
+	Deregister(r *AgentServiceRegistration) error
+
+	// UpdateTTL updates the status of a TTL check, as a heartbeat.
+	UpdateTTL(checkID, output, status string) error
+
+	// Service returns the instances of a service, and their health.
+	Service(ctx context.Context, service, tag string, passingOnly bool, opts *QueryOptions) ([]*ServiceEntry, *QueryMeta, error)
+}
+
+type client struct {
+	address string
+	http    *http.Client
+	token   string
+}
+
+// NewClient returns a Client for the Consul agent at the address, e.g.
+// http://127.0.0.1:8500, which authenticates requests with the ACL token, if
+// it isn't empty. If httpClient is nil, http.DefaultClient is used; blocking
+// queries need it not to time out before their wait time.
+func NewClient(address string, httpClient *http.Client, token string) Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &client{
+		address: strings.TrimSuffix(address, "/"),
+		http:    httpClient,
+		token:   token,
+	}
+}
+
+func (c *client) Register(r *AgentServiceRegistration) error {
+	query := url.Values{}
+	if r.Namespace != "" {
+		query.Set("ns", r.Namespace)
+	}
+	_, err := c.do(context.Background(), "PUT", "/v1/agent/service/register", query, r, nil)
+	return err
+}
+
+func (c *client) Deregister(r *AgentServiceRegistration) error {
+	query := url.Values{}
+	if r.Namespace != "" {
+		query.Set("ns", r.Namespace)
+	}
+	_, err := c.do(context.Background(), "PUT", "/v1/agent/service/deregister/"+url.PathEscape(r.ID), query, nil, nil)
+	return err
+}
+
+func (c *client) UpdateTTL(checkID, output, status string) error {
+	body := struct {
+		Status string
+		Output string `json:",omitempty"`
+	}{status, output}
+	_, err := c.do(context.Background(), "PUT", "/v1/agent/check/update/"+url.PathEscape(checkID), url.Values{}, body, nil)
+	return err
+}
+
+func (c *client) Service(ctx context.Context, service, tag string, passingOnly bool, opts *QueryOptions) ([]*ServiceEntry, *QueryMeta, error) {
+	query := url.Values{}
+	if tag != "" {
+		query.Set("tag", tag)
+	}
+	if passingOnly {
+		query.Set("passing", "1")
+	}
+	if opts != nil {
+		if opts.WaitIndex != 0 {
+			query.Set("index", strconv.FormatUint(opts.WaitIndex, 10))
+		}
+		if opts.WaitTime != 0 {
+			query.Set("wait", strconv.FormatInt(opts.WaitTime.Milliseconds(), 10)+"ms")
+		}
+		if opts.Filter != "" {
+			query.Set("filter", opts.Filter)
+		}
+		if opts.Namespace != "" {
+			query.Set("ns", opts.Namespace)
+		}
+	}
+	var entries []*ServiceEntry
+	header, err := c.do(ctx, "GET", "/v1/health/service/"+url.PathEscape(service), query, nil, &entries)
+	if err != nil {
+		return nil, nil, err
+	}
+	meta := &QueryMeta{}
+	if index := header.Get("X-Consul-Index"); index != "" {
+		if meta.LastIndex, err = strconv.ParseUint(index, 10, 64); err != nil {
+			return nil, nil, fmt.Errorf("invalid X-Consul-Index %q: %w", index, err)
+		}
+	}
+	return entries, meta, nil
+}
+
+func (c *client) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) (http.Header, error) {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+	u := c.address + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("consul returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return nil, err
+		}
+	}
+	return resp.Header, nil
+}
@@ -0,0 +1,3 @@
+// Package consul provides Instancer and Registrar implementations for Consul,
+// with a small client for the parts of the Consul HTTP API they use.
+package consul
@@ -0,0 +1,273 @@
+package consul
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/barrett370/kit/v2/sd"
+	"github.com/barrett370/kit/v2/sd/internal/instance"
+	"github.com/barrett370/kit/v2/util/conn"
+)
+
+const defaultIndex = 0
+
+// DefaultWaitTime is the longest a blocking query waits for a change.
+const DefaultWaitTime = 5 * time.Minute
+
+// InstancerOption sets an optional parameter for Instancers.
+type InstancerOption func(*Instancer)
+
+// InstancerFilter sets a filter expression, evaluated by Consul, which
+// instances must match, e.g. `"primary" in Service.Tags and Service.Meta.version == "2"`.
+// It's more expressive than the tags passed to NewInstancer.
+func InstancerFilter(expr string) InstancerOption {
+	return func(s *Instancer) { s.opts.Filter = expr }
+}
+
+// InstancerNamespace sets the Consul Enterprise namespace of the service.
+func InstancerNamespace(ns string) InstancerOption {
+	return func(s *Instancer) { s.opts.Namespace = ns }
+}
+
+// InstancerWaitTime sets the longest a blocking query waits for a change.
+// By default, it's DefaultWaitTime.
+func InstancerWaitTime(d time.Duration) InstancerOption {
+	return func(s *Instancer) { s.opts.WaitTime = d }
+}
+
+// InstancerHealthStatus yields only the instances whose health, the worst
+// status of their checks, is one of the statuses, e.g. HealthPassing and
+// HealthWarning, to keep instances with warnings in rotation. It's an
+// alternative to passingOnly, which keeps only passing instances.
+func InstancerHealthStatus(statuses ...string) InstancerOption {
+	return func(s *Instancer) {
+		s.statuses = map[string]bool{}
+		for _, status := range statuses {
+			s.statuses[status] = true
+		}
+	}
+}
+
+// Instancer yields instances for a service in Consul. It watches the service
+// with blocking queries, so changes are seen as soon as Consul makes them.
+type Instancer struct {
+	cache       *instance.Cache
+	client      Client
+	logger      log.Logger
+	service     string
+	tags        []string
+	passingOnly bool
+	statuses    map[string]bool
+	opts        QueryOptions
+	cancel      context.CancelFunc
+	done        chan struct{}
+}
+
+// NewInstancer returns a Consul instancer that publishes instances for the
+// requested service. It only returns instances for which all of the passed
+// tags are present.
+func NewInstancer(client Client, logger log.Logger, service string, tags []string, passingOnly bool, options ...InstancerOption) *Instancer {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Instancer{
+		cache:       instance.NewCache(),
+		client:      client,
+		logger:      log.With(logger, "service", service, "tags", fmt.Sprint(tags)),
+		service:     service,
+		tags:        tags,
+		passingOnly: passingOnly,
+		opts:        QueryOptions{WaitTime: DefaultWaitTime},
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+	for _, option := range options {
+		option(s)
+	}
+
+	instances, metadata, index, err := s.getInstances(ctx, defaultIndex)
+	if err == nil {
+		s.logger.Log("instances", len(instances))
+	} else {
+		s.logger.Log("err", err)
+	}
+
+	s.cache.Update(sd.Event{Instances: instances, Metadata: metadata, Err: err})
+	go s.loop(ctx, index)
+	return s
+}
+
+// Stop terminates the instancer.
+func (s *Instancer) Stop() {
+	s.cancel()
+	<-s.done
+}
+
+func (s *Instancer) loop(ctx context.Context, lastIndex uint64) {
+	defer close(s.done)
+	var (
+		instances []string
+		metadata  map[string]sd.Metadata
+		err       error
+		d         time.Duration = 10 * time.Millisecond
+		index     uint64
+	)
+	for {
+		instances, metadata, index, err = s.getInstances(ctx, lastIndex)
+		switch {
+		case ctx.Err() != nil:
+			return // stopped
+		case err != nil:
+			s.logger.Log("err", err)
+			select {
+			case <-time.After(d):
+			case <-ctx.Done():
+				return
+			}
+			d = conn.Exponential(d)
+			s.cache.Update(sd.Event{Err: err})
+		case index == defaultIndex:
+			s.logger.Log("err", "index is not sane")
+			select {
+			case <-time.After(d):
+			case <-ctx.Done():
+				return
+			}
+			d = conn.Exponential(d)
+		case index < lastIndex:
+			s.logger.Log("err", "index is less than previous; resetting to default")
+			lastIndex = defaultIndex
+			select {
+			case <-time.After(d):
+			case <-ctx.Done():
+				return
+			}
+			d = conn.Exponential(d)
+		default:
+			lastIndex = index
+			s.cache.Update(sd.Event{Instances: instances, Metadata: metadata})
+			d = 10 * time.Millisecond
+		}
+	}
+}
+
+func (s *Instancer) getInstances(ctx context.Context, lastIndex uint64) ([]string, map[string]sd.Metadata, uint64, error) {
+	// Consul doesn't support more than one tag in its service query method.
+	// https://github.com/hashicorp/consul/issues/294
+	// Hashi suggest prepared queries, but they don't support blocking.
+	// https://www.consul.io/docs/agent/http/query.html#execute
+	// If we want blocking for efficiency, we must filter tags manually.
+	var tag string
+	if len(s.tags) > 0 {
+		tag = s.tags[0]
+	}
+
+	opts := s.opts
+	opts.WaitIndex = lastIndex
+	entries, meta, err := s.client.Service(ctx, s.service, tag, s.passingOnly, &opts)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	if len(s.tags) > 1 {
+		entries = filterEntries(entries, s.tags[1:]...)
+	}
+	if s.statuses != nil {
+		entries = filterStatuses(entries, s.statuses)
+	}
+
+	instances, metadata := makeInstances(entries)
+	return instances, metadata, meta.LastIndex, nil
+}
+
+// Register implements Instancer.
+func (s *Instancer) Register(ch chan<- sd.Event) {
+	s.cache.Register(ch)
+}
+
+// Deregister implements Instancer.
+func (s *Instancer) Deregister(ch chan<- sd.Event) {
+	s.cache.Deregister(ch)
+}
+
+func filterEntries(entries []*ServiceEntry, tags ...string) []*ServiceEntry {
+	var es []*ServiceEntry
+
+ENTRIES:
+	for _, entry := range entries {
+		ts := make(map[string]struct{}, len(entry.Service.Tags))
+		for _, tag := range entry.Service.Tags {
+			ts[tag] = struct{}{}
+		}
+
+		for _, tag := range tags {
+			if _, ok := ts[tag]; !ok {
+				continue ENTRIES
+			}
+		}
+		es = append(es, entry)
+	}
+
+	return es
+}
+
+func filterStatuses(entries []*ServiceEntry, statuses map[string]bool) []*ServiceEntry {
+	var es []*ServiceEntry
+	for _, entry := range entries {
+		if statuses[aggregatedStatus(entry.Checks)] {
+			es = append(es, entry)
+		}
+	}
+	return es
+}
+
+// aggregatedStatus returns the worst status of the checks.
+func aggregatedStatus(checks []*HealthCheck) string {
+	status := HealthPassing
+	for _, check := range checks {
+		switch check.Status {
+		case HealthCritical:
+			return HealthCritical
+		case HealthWarning:
+			status = HealthWarning
+		case HealthPassing:
+		default:
+			return HealthCritical // unknown statuses, e.g. maintenance
+		}
+	}
+	return status
+}
+
+// makeInstances returns the addresses of the entries, and their metadata:
+// their service metadata, and, unless it has a weight, their weight for the
+// status of their checks.
+func makeInstances(entries []*ServiceEntry) ([]string, map[string]sd.Metadata) {
+	instances := make([]string, len(entries))
+	metadata := make(map[string]sd.Metadata, len(entries))
+	for i, entry := range entries {
+		addr := entry.Node.Address
+		if entry.Service.Address != "" {
+			addr = entry.Service.Address
+		}
+		instances[i] = net.JoinHostPort(addr, strconv.Itoa(entry.Service.Port))
+
+		m := make(sd.Metadata, len(entry.Service.Meta)+1)
+		for k, v := range entry.Service.Meta {
+			m[k] = v
+		}
+		if _, ok := m[sd.MetadataWeight]; !ok {
+			weight := entry.Service.Weights.Passing
+			if aggregatedStatus(entry.Checks) == HealthWarning {
+				weight = entry.Service.Weights.Warning
+			}
+			if weight > 0 {
+				m[sd.MetadataWeight] = strconv.Itoa(weight)
+			}
+		}
+		metadata[instances[i]] = m
+	}
+	return instances, metadata
+}
@@ -0,0 +1,209 @@
+package consul
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/barrett370/kit/v2/sd"
+)
+
+var _ sd.Instancer = (*Instancer)(nil) // API check
+
+var consulState = []*ServiceEntry{
+	{
+		Node:    &Node{Address: "10.0.0.0", Node: "app00.local"},
+		Service: &AgentService{ID: "search-api-0", Port: 8000, Service: "search", Tags: []string{"api", "v1"}},
+		Checks:  []*HealthCheck{{Status: HealthPassing}},
+	},
+	{
+		Node:    &Node{Address: "10.0.0.1", Node: "app01.local"},
+		Service: &AgentService{ID: "search-api-1", Port: 8001, Service: "search", Tags: []string{"api", "v2"}},
+		Checks:  []*HealthCheck{{Status: HealthPassing}, {Status: HealthWarning}},
+	},
+	{
+		Node:    &Node{Address: "10.0.0.1", Node: "app01.local"},
+		Service: &AgentService{Address: "10.0.0.10", ID: "search-db-0", Port: 9000, Service: "search", Tags: []string{"db"}},
+		Checks:  []*HealthCheck{{Status: HealthCritical}},
+	},
+}
+
+// testClient serves entries, and blocks queries at the current index until
+// the entries are changed with set.
+type testClient struct {
+	mtx     sync.Mutex
+	entries []*ServiceEntry
+	index   uint64
+	changed chan struct{}
+	queries []QueryOptions
+}
+
+func newTestClient(entries []*ServiceEntry) *testClient {
+	return &testClient{entries: entries, index: 1, changed: make(chan struct{})}
+}
+
+func (c *testClient) set(entries []*ServiceEntry) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.entries = entries
+	c.index++
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+func (c *testClient) Service(ctx context.Context, service, tag string, passingOnly bool, opts *QueryOptions) ([]*ServiceEntry, *QueryMeta, error) {
+	c.mtx.Lock()
+	c.queries = append(c.queries, *opts)
+	if opts.WaitIndex == c.index {
+		changed := c.changed
+		c.mtx.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+		c.mtx.Lock()
+	}
+	defer c.mtx.Unlock()
+
+	var results []*ServiceEntry
+	for _, entry := range c.entries {
+		if entry.Service.Service != service {
+			continue
+		}
+		if tag != "" && !contains(entry.Service.Tags, tag) {
+			continue
+		}
+		if passingOnly && aggregatedStatus(entry.Checks) != HealthPassing {
+			continue
+		}
+		results = append(results, entry)
+	}
+	return results, &QueryMeta{LastIndex: c.index}, nil
+}
+
+func (c *testClient) Register(r *AgentServiceRegistration) error   { return nil }
+func (c *testClient) Deregister(r *AgentServiceRegistration) error { return nil }
+func (c *testClient) UpdateTTL(checkID, output, status string) error {
+	return nil
+}
+
+func contains(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func TestInstancer(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		tags        []string
+		passingOnly bool
+		options     []InstancerOption
+		want        []string
+	}{
+		{"all", nil, false, nil, []string{"10.0.0.0:8000", "10.0.0.10:9000", "10.0.0.1:8001"}},
+		{"tags", []string{"api", "v2"}, false, nil, []string{"10.0.0.1:8001"}},
+		{"passing only", nil, true, nil, []string{"10.0.0.0:8000"}},
+		{"health status", nil, false, []InstancerOption{InstancerHealthStatus(HealthPassing, HealthWarning)}, []string{"10.0.0.0:8000", "10.0.0.1:8001"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewInstancer(newTestClient(consulState), log.NewNopLogger(), "search", tc.tags, tc.passingOnly, tc.options...)
+			defer s.Stop()
+			if want, have := tc.want, s.cache.State().Instances; !reflect.DeepEqual(want, have) {
+				t.Errorf("want %v, have %v", want, have)
+			}
+		})
+	}
+}
+
+func TestInstancerBlockingQuery(t *testing.T) {
+	client := newTestClient(consulState[:1])
+	s := NewInstancer(client, log.NewNopLogger(), "search", nil, false, InstancerFilter(`"api" in Service.Tags`), InstancerNamespace("team-a"))
+	defer s.Stop()
+
+	events := make(chan sd.Event, 2)
+	s.Register(events)
+	defer s.Deregister(events)
+	if want, have := []string{"10.0.0.0:8000"}, (<-events).Instances; !reflect.DeepEqual(want, have) {
+		t.Fatalf("want %v, have %v", want, have)
+	}
+
+	client.set(consulState[:2])
+	select {
+	case e := <-events:
+		if want, have := []string{"10.0.0.0:8000", "10.0.0.1:8001"}, e.Instances; !reflect.DeepEqual(want, have) {
+			t.Errorf("want %v, have %v", want, have)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the blocking query to return")
+	}
+
+	client.mtx.Lock()
+	defer client.mtx.Unlock()
+	if want, have := uint64(0), client.queries[0].WaitIndex; want != have {
+		t.Errorf("first query: want index %d, have %d", want, have)
+	}
+	if want, have := uint64(1), client.queries[1].WaitIndex; want != have {
+		t.Errorf("second query: want index %d, have %d", want, have)
+	}
+	for _, q := range client.queries {
+		if q.Filter != `"api" in Service.Tags` || q.Namespace != "team-a" || q.WaitTime != DefaultWaitTime {
+			t.Errorf("unexpected query options %+v", q)
+		}
+	}
+}
+
+type errClient struct{ testClient }
+
+func (c *errClient) Service(ctx context.Context, service, tag string, passingOnly bool, opts *QueryOptions) ([]*ServiceEntry, *QueryMeta, error) {
+	return nil, nil, errors.New("boom")
+}
+
+func TestInstancerError(t *testing.T) {
+	s := NewInstancer(&errClient{}, log.NewNopLogger(), "search", nil, false)
+	defer s.Stop()
+	if s.cache.State().Err == nil {
+		t.Error("want error, have nil")
+	}
+}
+
+func TestInstancerMetadata(t *testing.T) {
+	entries := []*ServiceEntry{
+		{
+			Node:    &Node{Address: "10.0.0.0"},
+			Service: &AgentService{Port: 8000, Service: "search", Meta: map[string]string{"version": "2.1.0"}, Weights: AgentWeights{Passing: 10, Warning: 1}},
+			Checks:  []*HealthCheck{{Status: HealthPassing}},
+		},
+		{
+			Node:    &Node{Address: "10.0.0.1"},
+			Service: &AgentService{Port: 8000, Service: "search", Weights: AgentWeights{Passing: 10, Warning: 1}},
+			Checks:  []*HealthCheck{{Status: HealthWarning}},
+		},
+		{
+			Node:    &Node{Address: "10.0.0.2"},
+			Service: &AgentService{Port: 8000, Service: "search", Meta: map[string]string{"weight": "3"}, Weights: AgentWeights{Passing: 10}},
+		},
+	}
+	s := NewInstancer(newTestClient(entries), log.NewNopLogger(), "search", nil, false)
+	defer s.Stop()
+
+	e := s.cache.State()
+	for instance, want := range map[string]sd.Metadata{
+		"10.0.0.0:8000": {"version": "2.1.0", "weight": "10"},
+		"10.0.0.1:8000": {"weight": "1"},
+		"10.0.0.2:8000": {"weight": "3"},
+	} {
+		if have := e.MetadataOf(instance); !reflect.DeepEqual(want, have) {
+			t.Errorf("%s: want %v, have %v", instance, want, have)
+		}
+	}
+}
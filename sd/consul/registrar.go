@@ -0,0 +1,120 @@
+package consul
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+// RegistrarOption sets an optional parameter for Registrars.
+type RegistrarOption func(*Registrar)
+
+// RegistrarHeartbeat sets the interval between heartbeats for a TTL check.
+// By default, it's half the check's TTL.
+func RegistrarHeartbeat(interval time.Duration) RegistrarOption {
+	return func(p *Registrar) { p.interval = interval }
+}
+
+// Registrar registers service instance liveness information to Consul. If
+// the registration has a TTL check, the Registrar keeps it passing with
+// heartbeats while the service is registered.
+type Registrar struct {
+	client       Client
+	registration *AgentServiceRegistration
+	logger       log.Logger
+	interval     time.Duration
+
+	mtx  sync.Mutex
+	quit chan struct{}
+	done chan struct{}
+}
+
+// NewRegistrar returns a Consul Registrar acting on the provided catalog
+// registration.
+func NewRegistrar(client Client, r *AgentServiceRegistration, logger log.Logger, options ...RegistrarOption) *Registrar {
+	p := &Registrar{
+		client:       client,
+		registration: r,
+		logger:       log.With(logger, "service", r.Name, "tags", fmt.Sprint(r.Tags), "address", r.Address),
+	}
+	if r.Check != nil && r.Check.TTL != "" {
+		if ttl, err := time.ParseDuration(r.Check.TTL); err == nil {
+			p.interval = ttl / 2
+		} else {
+			p.logger.Log("err", err)
+		}
+	}
+	for _, option := range options {
+		option(p)
+	}
+	return p
+}
+
+// Register implements sd.Registrar interface.
+func (p *Registrar) Register() {
+	if err := p.client.Register(p.registration); err != nil {
+		p.logger.Log("err", err)
+		return
+	}
+	p.logger.Log("action", "register")
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.interval > 0 && p.quit == nil {
+		p.quit, p.done = make(chan struct{}), make(chan struct{})
+		go p.heartbeat(p.quit, p.done)
+	}
+}
+
+// Deregister implements sd.Registrar interface.
+func (p *Registrar) Deregister() {
+	p.mtx.Lock()
+	if p.quit != nil {
+		close(p.quit)
+		<-p.done
+		p.quit, p.done = nil, nil
+	}
+	p.mtx.Unlock()
+
+	if err := p.client.Deregister(p.registration); err != nil {
+		p.logger.Log("err", err)
+	} else {
+		p.logger.Log("action", "deregister")
+	}
+}
+
+// checkID returns the ID of the TTL check, which Consul defaults to
+// "service:" and the ID of the service.
+func (p *Registrar) checkID() string {
+	if id := p.registration.Check.CheckID; id != "" {
+		return id
+	}
+	id := p.registration.ID
+	if id == "" {
+		id = p.registration.Name
+	}
+	return "service:" + id
+}
+
+func (p *Registrar) heartbeat(quit, done chan struct{}) {
+	defer close(done)
+	checkID := p.checkID()
+	pass := func() {
+		if err := p.client.UpdateTTL(checkID, "", HealthPassing); err != nil {
+			p.logger.Log("during", "heartbeat", "err", err)
+		}
+	}
+	pass()
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			pass()
+		case <-quit:
+			return
+		}
+	}
+}
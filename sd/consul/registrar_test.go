@@ -0,0 +1,111 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/barrett370/kit/v2/sd"
+)
+
+var _ sd.Registrar = (*Registrar)(nil) // API check
+
+// fakeAgent records the requests made to it by a Client.
+type fakeAgent struct {
+	mtx      sync.Mutex
+	requests []string
+	tokens   []string
+}
+
+func (a *fakeAgent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mtx.Lock()
+	a.requests = append(a.requests, r.Method+" "+r.URL.Path)
+	a.tokens = append(a.tokens, r.Header.Get("X-Consul-Token"))
+	a.mtx.Unlock()
+	if r.URL.Path == "/v1/health/service/search" {
+		w.Header().Set("X-Consul-Index", "42")
+		json.NewEncoder(w).Encode(consulState[:1])
+	}
+}
+
+func (a *fakeAgent) count(request string) int {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	var n int
+	for _, r := range a.requests {
+		if r == request {
+			n++
+		}
+	}
+	return n
+}
+
+func TestRegistrarHeartbeat(t *testing.T) {
+	agent := &fakeAgent{}
+	server := httptest.NewServer(agent)
+	defer server.Close()
+
+	client := NewClient(server.URL, nil, "secret")
+	r := NewRegistrar(client, &AgentServiceRegistration{
+		ID:    "search-1",
+		Name:  "search",
+		Check: &AgentServiceCheck{TTL: "10s"},
+	}, log.NewNopLogger(), RegistrarHeartbeat(5*time.Millisecond))
+
+	r.Register()
+	deadline := time.Now().Add(5 * time.Second)
+	for agent.count("PUT /v1/agent/check/update/service:search-1") < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for heartbeats")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	r.Deregister()
+	heartbeats := agent.count("PUT /v1/agent/check/update/service:search-1")
+	time.Sleep(20 * time.Millisecond)
+
+	if want, have := heartbeats, agent.count("PUT /v1/agent/check/update/service:search-1"); want != have {
+		t.Errorf("heartbeats after Deregister: want %d, have %d", want, have)
+	}
+	if want, have := 1, agent.count("PUT /v1/agent/service/register"); want != have {
+		t.Errorf("register: want %d, have %d", want, have)
+	}
+	if want, have := 1, agent.count("PUT /v1/agent/service/deregister/search-1"); want != have {
+		t.Errorf("deregister: want %d, have %d", want, have)
+	}
+	for _, token := range agent.tokens {
+		if want, have := "secret", token; want != have {
+			t.Fatalf("token: want %q, have %q", want, have)
+		}
+	}
+}
+
+func TestClientService(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		w.Header().Set("X-Consul-Index", "42")
+		json.NewEncoder(w).Encode(consulState[:1])
+	}))
+	defer server.Close()
+
+	entries, meta, err := NewClient(server.URL, nil, "").Service(context.Background(), "search", "api", true, &QueryOptions{WaitIndex: 7, WaitTime: time.Second, Namespace: "ns"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "index=7&ns=ns&passing=1&tag=api&wait=1000ms", query; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := uint64(42), meta.LastIndex; want != have {
+		t.Errorf("want index %d, have %d", want, have)
+	}
+	if want, have := "search-api-0", entries[0].Service.ID; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
@@ -0,0 +1,3 @@
+// Package dnssrv provides an Instancer implementation for DNS SRV records,
+// and for A and AAAA records with a fixed port.
+package dnssrv
@@ -0,0 +1,199 @@
+package dnssrv
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/barrett370/kit/v2/sd"
+	"github.com/barrett370/kit/v2/sd/internal/instance"
+)
+
+// ErrPortZero is returned by the resolve machinery when a DNS resolver returns
+// an SRV record with its port set to zero.
+var ErrPortZero = errors.New("resolver returned SRV record with port 0")
+
+// Defaults for Instancers.
+const (
+	DefaultJitter     = 0.1
+	DefaultMinRefresh = time.Second
+	DefaultTimeout    = 5 * time.Second
+)
+
+// Option sets an optional parameter for Instancers.
+type Option func(*Instancer)
+
+// WithResolver sets the resolver used to look up records. By default, it's
+// NetResolver(nil).
+func WithResolver(r Resolver) Option {
+	return func(s *Instancer) { s.resolver = r }
+}
+
+// WithJitter sets the fraction by which each refresh is brought forward at
+// random, so that many instancers don't all query the DNS server at once.
+// By default, it's DefaultJitter.
+func WithJitter(fraction float64) Option {
+	return func(s *Instancer) { s.jitter = fraction }
+}
+
+// WithMinRefresh sets the minimum interval between lookups, however short
+// the TTLs of the records are. By default, it's DefaultMinRefresh.
+func WithMinRefresh(d time.Duration) Option {
+	return func(s *Instancer) { s.minRefresh = d }
+}
+
+// WithTimeout sets the timeout of each lookup. By default, it's
+// DefaultTimeout.
+func WithTimeout(d time.Duration) Option {
+	return func(s *Instancer) { s.timeout = d }
+}
+
+// Instancer yields instances from the named DNS records. It looks them up
+// again when the shortest TTL of the previous records expires, or, if the
+// resolver doesn't report TTLs, every refresh interval.
+type Instancer struct {
+	cache      *instance.Cache
+	name       string
+	port       string // for address records; empty for SRV
+	refresh    time.Duration
+	resolver   Resolver
+	jitter     float64
+	minRefresh time.Duration
+	timeout    time.Duration
+	logger     log.Logger
+	quit       chan struct{}
+	done       chan struct{}
+}
+
+// NewInstancer returns a DNS SRV instancer for the name, e.g.
+// "_http._tcp.orders.service.consul", yielding instances as target:port, with
+// the weights of the records as metadata.
+func NewInstancer(name string, refresh time.Duration, logger log.Logger, options ...Option) *Instancer {
+	return newInstancer(name, "", refresh, logger, options)
+}
+
+// NewHostInstancer returns an instancer which looks up the A and AAAA records
+// of the host, yielding instances as address:port, for services whose
+// instances all listen on the same port.
+func NewHostInstancer(host string, port int, refresh time.Duration, logger log.Logger, options ...Option) *Instancer {
+	return newInstancer(host, strconv.Itoa(port), refresh, logger, options)
+}
+
+func newInstancer(name, port string, refresh time.Duration, logger log.Logger, options []Option) *Instancer {
+	s := &Instancer{
+		cache:      instance.NewCache(),
+		name:       name,
+		port:       port,
+		refresh:    refresh,
+		resolver:   NetResolver(nil),
+		jitter:     DefaultJitter,
+		minRefresh: DefaultMinRefresh,
+		timeout:    DefaultTimeout,
+		logger:     log.With(logger, "name", name),
+		quit:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	for _, option := range options {
+		option(s)
+	}
+
+	next := s.resolve()
+	go s.loop(next)
+	return s
+}
+
+// Stop terminates the Instancer.
+func (s *Instancer) Stop() {
+	close(s.quit)
+	<-s.done
+}
+
+func (s *Instancer) loop(next time.Duration) {
+	defer close(s.done)
+	for {
+		t := time.NewTimer(next)
+		select {
+		case <-t.C:
+			next = s.resolve()
+		case <-s.quit:
+			t.Stop()
+			return
+		}
+	}
+}
+
+// resolve looks up the records, updates the cache, and returns the interval
+// until the next lookup.
+func (s *Instancer) resolve() time.Duration {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	var (
+		records []Record
+		err     error
+	)
+	if s.port == "" {
+		records, err = s.resolver.LookupSRV(ctx, s.name)
+	} else {
+		records, err = s.resolver.LookupHost(ctx, s.name)
+	}
+	if err != nil {
+		s.logger.Log("err", err)
+		s.cache.Update(sd.Event{Err: err})
+		return s.next(0)
+	}
+
+	var ttl time.Duration
+	instances := make([]string, len(records))
+	metadata := map[string]sd.Metadata{}
+	for i, r := range records {
+		port := s.port
+		if port == "" {
+			if r.Port == 0 {
+				s.logger.Log("err", ErrPortZero)
+				s.cache.Update(sd.Event{Err: ErrPortZero})
+				return s.next(0)
+			}
+			port = strconv.Itoa(int(r.Port))
+		}
+		instances[i] = net.JoinHostPort(r.Target, port)
+		if r.Weight > 0 {
+			metadata[instances[i]] = sd.Metadata{sd.MetadataWeight: strconv.Itoa(int(r.Weight))}
+		}
+		if r.TTL > 0 && (ttl == 0 || r.TTL < ttl) {
+			ttl = r.TTL
+		}
+	}
+	s.cache.Update(sd.Event{Instances: instances, Metadata: metadata})
+	return s.next(ttl)
+}
+
+// next returns the interval until the next lookup, given the shortest TTL of
+// the records, or zero if unknown. Jitter only brings lookups forward, so
+// that records are never used beyond their TTL.
+func (s *Instancer) next(ttl time.Duration) time.Duration {
+	d := s.refresh
+	if ttl > 0 {
+		d = ttl
+	}
+	d -= time.Duration(rand.Float64() * s.jitter * float64(d))
+	if d < s.minRefresh {
+		d = s.minRefresh
+	}
+	return d
+}
+
+// Register implements Instancer.
+func (s *Instancer) Register(ch chan<- sd.Event) {
+	s.cache.Register(ch)
+}
+
+// Deregister implements Instancer.
+func (s *Instancer) Deregister(ch chan<- sd.Event) {
+	s.cache.Deregister(ch)
+}
@@ -0,0 +1,121 @@
+package dnssrv
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/barrett370/kit/v2/sd"
+)
+
+var _ sd.Instancer = (*Instancer)(nil) // API check
+
+type fakeResolver struct {
+	mtx     sync.Mutex
+	records []Record
+	err     error
+	lookups []string
+}
+
+func (r *fakeResolver) lookup(kind string) ([]Record, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.lookups = append(r.lookups, kind)
+	return r.records, r.err
+}
+
+func (r *fakeResolver) LookupSRV(ctx context.Context, name string) ([]Record, error) {
+	return r.lookup("SRV")
+}
+
+func (r *fakeResolver) LookupHost(ctx context.Context, host string) ([]Record, error) {
+	return r.lookup("host")
+}
+
+func (r *fakeResolver) set(records []Record, err error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.records, r.err = records, err
+}
+
+func TestInstancerSRV(t *testing.T) {
+	r := &fakeResolver{records: []Record{{Target: "a.example.", Port: 8080, Weight: 10}, {Target: "b.example.", Port: 8081}}}
+	s := NewInstancer("_http._tcp.orders", time.Hour, log.NewNopLogger(), WithResolver(r))
+	defer s.Stop()
+
+	state := s.cache.State()
+	if want, have := []string{"a.example.:8080", "b.example.:8081"}, state.Instances; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := []string{"SRV"}, r.lookups; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := 10, state.MetadataOf("a.example.:8080").Weight(); want != have {
+		t.Errorf("want weight %d, have %d", want, have)
+	}
+	if want, have := 1, state.MetadataOf("b.example.:8081").Weight(); want != have {
+		t.Errorf("want weight %d, have %d", want, have)
+	}
+}
+
+func TestInstancerHostTTL(t *testing.T) {
+	r := &fakeResolver{records: []Record{{Target: "10.0.0.1", TTL: time.Hour}, {Target: "fd00::1", TTL: 20 * time.Millisecond}}}
+	s := NewHostInstancer("orders", 8080, time.Hour, log.NewNopLogger(), WithResolver(r), WithMinRefresh(time.Millisecond))
+	defer s.Stop()
+
+	if want, have := []string{"10.0.0.1:8080", "[fd00::1]:8080"}, s.cache.State().Instances; !reflect.DeepEqual(want, have) {
+		t.Fatalf("want %v, have %v", want, have)
+	}
+
+	// The shortest TTL, rather than the hour-long refresh, sets the time of
+	// the next lookup.
+	r.set([]Record{{Target: "10.0.0.2", TTL: time.Hour}}, nil)
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if reflect.DeepEqual([]string{"10.0.0.2:8080"}, s.cache.State().Instances) {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("records not refreshed after their TTL, have %v", s.cache.State().Instances)
+}
+
+func TestInstancerErrors(t *testing.T) {
+	errBoom := errors.New("boom")
+	r := &fakeResolver{err: errBoom}
+	s := NewInstancer("_http._tcp.orders", time.Hour, log.NewNopLogger(), WithResolver(r))
+	defer s.Stop()
+	if want, have := errBoom, s.cache.State().Err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	r = &fakeResolver{records: []Record{{Target: "a.example."}}}
+	s2 := NewInstancer("_http._tcp.orders", time.Hour, log.NewNopLogger(), WithResolver(r))
+	defer s2.Stop()
+	if want, have := ErrPortZero, s2.cache.State().Err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestNext(t *testing.T) {
+	s := &Instancer{refresh: 30 * time.Second, jitter: 0.1, minRefresh: time.Second}
+	for _, tc := range []struct {
+		ttl      time.Duration
+		min, max time.Duration
+	}{
+		{0, 27 * time.Second, 30 * time.Second},
+		{10 * time.Second, 9 * time.Second, 10 * time.Second},
+		{time.Millisecond, time.Second, time.Second},
+	} {
+		for i := 0; i < 100; i++ {
+			if d := s.next(tc.ttl); d < tc.min || d > tc.max {
+				t.Fatalf("ttl %v: want [%v, %v], have %v", tc.ttl, tc.min, tc.max, d)
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package dnssrv
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// Record is a resolved SRV or address record. For address records, Port and
+// Weight are zero. TTL is how long the record may be cached, or zero if
+// unknown.
+type Record struct {
+	Target string
+	Port   uint16
+	Weight uint16
+	TTL    time.Duration
+}
+
+// Resolver looks up DNS records. Implement it to query a particular server,
+// e.g. Consul's DNS interface, or to report record TTLs, which the standard
+// library's resolver doesn't expose.
+type Resolver interface {
+	// LookupSRV returns the SRV records of the name, e.g.
+	// "_http._tcp.orders.service.consul".
+	LookupSRV(ctx context.Context, name string) ([]Record, error)
+
+	// LookupHost returns the A and AAAA records of the host, with their IP
+	// addresses as targets.
+	LookupHost(ctx context.Context, host string) ([]Record, error)
+}
+
+type netResolver struct {
+	r *net.Resolver
+}
+
+// NetResolver returns a Resolver which looks up records with r, or with
+// net.DefaultResolver if r is nil. Its records have unknown TTLs.
+func NetResolver(r *net.Resolver) Resolver {
+	if r == nil {
+		r = net.DefaultResolver
+	}
+	return netResolver{r: r}
+}
+
+func (r netResolver) LookupSRV(ctx context.Context, name string) ([]Record, error) {
+	_, srvs, err := r.r.LookupSRV(ctx, "", "", name)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]Record, len(srvs))
+	for i, srv := range srvs {
+		records[i] = Record{Target: srv.Target, Port: srv.Port, Weight: srv.Weight}
+	}
+	return records, nil
+}
+
+func (r netResolver) LookupHost(ctx context.Context, host string) ([]Record, error) {
+	addrs, err := r.r.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]Record, len(addrs))
+	for i, addr := range addrs {
+		records[i] = Record{Target: addr}
+	}
+	return records, nil
+}
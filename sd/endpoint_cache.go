@@ -0,0 +1,163 @@
+package sd
+
+import (
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/barrett370/kit/v2/endpoint"
+)
+
+// endpointCache collects the most recent set of instances from a service discovery
+// system, creates endpoints for them using a factory function, and makes
+// them available to consumers.
+type endpointCache[I, O any] struct {
+	options            endpointerOptions
+	mtx                sync.RWMutex
+	factory            Factory[I, O]
+	cache              map[string]endpointCloser[I, O]
+	err                error
+	endpoints          []endpoint.Endpoint[I, O]
+	instances          []InstanceEndpoint[I, O]
+	logger             log.Logger
+	invalidateDeadline time.Time
+	timeNow            func() time.Time
+}
+
+type endpointCloser[I, O any] struct {
+	endpoint.Endpoint[I, O]
+	io.Closer
+}
+
+// newEndpointCache returns a new, empty endpointCache.
+func newEndpointCache[I, O any](factory Factory[I, O], logger log.Logger, options endpointerOptions) *endpointCache[I, O] {
+	return &endpointCache[I, O]{
+		options: options,
+		factory: factory,
+		cache:   map[string]endpointCloser[I, O]{},
+		logger:  logger,
+		timeNow: time.Now,
+	}
+}
+
+// Update should be invoked by clients with a complete set of current instance
+// strings whenever that set changes. The cache manufactures new endpoints via
+// the factory, closes old endpoints when they disappear, and persists existing
+// endpoints if they survive through an update.
+func (c *endpointCache[I, O]) Update(event Event) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	// Happy path.
+	if event.Err == nil {
+		c.updateCache(event.Instances, event.Metadata)
+		c.err = nil
+		return
+	}
+
+	// Sad path. Something's gone wrong in sd.
+	c.logger.Log("err", event.Err)
+	if !c.options.invalidateOnError {
+		return // keep returning the last known endpoints on error
+	}
+	if c.err != nil {
+		return // already in the error state, do nothing & keep original error
+	}
+	c.err = event.Err
+	// set new deadline to invalidate Endpoints unless non-error Event is received
+	c.invalidateDeadline = c.timeNow().Add(c.options.invalidateTimeout)
+}
+
+func (c *endpointCache[I, O]) updateCache(instances []string, metadata map[string]Metadata) {
+	// Deterministic order (for later load balancing).
+	sort.Strings(instances)
+
+	// Produce the current set of services.
+	cache := make(map[string]endpointCloser[I, O], len(instances))
+	for _, instance := range instances {
+		// If it already exists, just copy it over.
+		if sc, ok := c.cache[instance]; ok {
+			cache[instance] = sc
+			delete(c.cache, instance)
+			continue
+		}
+
+		// If it doesn't exist, create it.
+		service, closer, err := c.factory(instance)
+		if err != nil {
+			c.logger.Log("instance", instance, "err", err)
+			continue
+		}
+		cache[instance] = endpointCloser[I, O]{service, closer}
+	}
+
+	// Close any leftover endpoints.
+	for _, sc := range c.cache {
+		if sc.Closer != nil {
+			sc.Closer.Close()
+		}
+	}
+
+	// Populate the slices of endpoints.
+	endpoints := make([]endpoint.Endpoint[I, O], 0, len(cache))
+	instanceEndpoints := make([]InstanceEndpoint[I, O], 0, len(cache))
+	for _, instance := range instances {
+		// A bad factory may mean an instance is not present.
+		if _, ok := cache[instance]; !ok {
+			continue
+		}
+		endpoints = append(endpoints, cache[instance].Endpoint)
+		instanceEndpoints = append(instanceEndpoints, InstanceEndpoint[I, O]{
+			Instance: instance,
+			Metadata: metadata[instance],
+			Endpoint: cache[instance].Endpoint,
+		})
+	}
+
+	// Swap and trigger GC for old copies.
+	c.endpoints = endpoints
+	c.instances = instanceEndpoints
+	c.cache = cache
+}
+
+// Endpoints yields the current set of (presumably identical) endpoints, ordered
+// lexicographically by the corresponding instance string.
+func (c *endpointCache[I, O]) Endpoints() ([]endpoint.Endpoint[I, O], error) {
+	endpoints, _, err := c.get()
+	return endpoints, err
+}
+
+// InstanceEndpoints is like Endpoints, but yields each endpoint with its
+// instance and metadata.
+func (c *endpointCache[I, O]) InstanceEndpoints() ([]InstanceEndpoint[I, O], error) {
+	_, instances, err := c.get()
+	return instances, err
+}
+
+func (c *endpointCache[I, O]) get() ([]endpoint.Endpoint[I, O], []InstanceEndpoint[I, O], error) {
+	// in the steady state we're going to have many goroutines calling Endpoints()
+	// concurrently, so to minimize contention we use a shared R-lock.
+	c.mtx.RLock()
+
+	if c.err == nil || c.timeNow().Before(c.invalidateDeadline) {
+		defer c.mtx.RUnlock()
+		return c.endpoints, c.instances, nil
+	}
+
+	c.mtx.RUnlock()
+
+	// in case of an error, switch to an exclusive lock.
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	// re-check condition due to a race between RUnlock() and Lock().
+	if c.err == nil || c.timeNow().Before(c.invalidateDeadline) {
+		return c.endpoints, c.instances, nil
+	}
+
+	c.updateCache(nil, nil) // close any remaining active endpoints
+	return nil, nil, c.err
+}
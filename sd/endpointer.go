@@ -0,0 +1,130 @@
+package sd
+
+import (
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/barrett370/kit/v2/endpoint"
+)
+
+// Endpointer listens to a service discovery system and yields a set of
+// identical endpoints on demand. An error indicates a problem with connectivity
+// to the service discovery system, or within the system itself; an Endpointer
+// may yield no endpoints without error.
+type Endpointer[I, O any] interface {
+	Endpoints() ([]endpoint.Endpoint[I, O], error)
+}
+
+// InstanceEndpoint is an endpoint, with the instance it was created for, and
+// the instance's metadata, if any.
+type InstanceEndpoint[I, O any] struct {
+	Instance string
+	Metadata Metadata
+	Endpoint endpoint.Endpoint[I, O]
+}
+
+// InstanceEndpointer is an Endpointer which also yields the instances of its
+// endpoints, for balancers which use them, e.g. to weight endpoints. The
+// instance endpoints are in the same order as the endpoints.
+type InstanceEndpointer[I, O any] interface {
+	Endpointer[I, O]
+	InstanceEndpoints() ([]InstanceEndpoint[I, O], error)
+}
+
+// FixedEndpointer yields a fixed set of endpoints.
+type FixedEndpointer[I, O any] []endpoint.Endpoint[I, O]
+
+// Endpoints implements Endpointer.
+func (s FixedEndpointer[I, O]) Endpoints() ([]endpoint.Endpoint[I, O], error) { return s, nil }
+
+// FixedInstanceEndpointer yields a fixed set of endpoints, with their
+// instances and metadata.
+type FixedInstanceEndpointer[I, O any] []InstanceEndpoint[I, O]
+
+// Endpoints implements Endpointer.
+func (s FixedInstanceEndpointer[I, O]) Endpoints() ([]endpoint.Endpoint[I, O], error) {
+	endpoints := make([]endpoint.Endpoint[I, O], len(s))
+	for i, e := range s {
+		endpoints[i] = e.Endpoint
+	}
+	return endpoints, nil
+}
+
+// InstanceEndpoints implements InstanceEndpointer.
+func (s FixedInstanceEndpointer[I, O]) InstanceEndpoints() ([]InstanceEndpoint[I, O], error) {
+	return s, nil
+}
+
+// NewEndpointer creates an Endpointer that subscribes to updates from Instancer src
+// and uses factory f to create Endpoints. If src notifies of an error, the Endpointer
+// keeps returning previously created Endpoints assuming they are still good, unless
+// this behavior is disabled via InvalidateOnError option.
+func NewEndpointer[I, O any](src Instancer, f Factory[I, O], logger log.Logger, options ...EndpointerOption) *DefaultEndpointer[I, O] {
+	opts := endpointerOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	se := &DefaultEndpointer[I, O]{
+		cache:     newEndpointCache(f, logger, opts),
+		instancer: src,
+		ch:        make(chan Event),
+	}
+	go se.receive()
+	src.Register(se.ch)
+	return se
+}
+
+// EndpointerOption allows control of endpointCache behavior.
+type EndpointerOption func(*endpointerOptions)
+
+// InvalidateOnError returns EndpointerOption that controls how the Endpointer
+// behaves when then Instancer publishes an Event containing an error.
+// Without this option the Endpointer continues returning the last known
+// endpoints. With this option, the Endpointer continues returning the last
+// known endpoints until the timeout elapses, then closes all active endpoints
+// and starts returning an error. Once the Instancer sends a new update with
+// valid resource instances, the normal operation is resumed.
+func InvalidateOnError(timeout time.Duration) EndpointerOption {
+	return func(opts *endpointerOptions) {
+		opts.invalidateOnError = true
+		opts.invalidateTimeout = timeout
+	}
+}
+
+type endpointerOptions struct {
+	invalidateOnError bool
+	invalidateTimeout time.Duration
+}
+
+// DefaultEndpointer implements an Endpointer interface.
+// When created with NewEndpointer function, it automatically registers
+// as a subscriber to events from the Instances and maintains a list
+// of active Endpoints.
+type DefaultEndpointer[I, O any] struct {
+	cache     *endpointCache[I, O]
+	instancer Instancer
+	ch        chan Event
+}
+
+func (de *DefaultEndpointer[I, O]) receive() {
+	for event := range de.ch {
+		de.cache.Update(event)
+	}
+}
+
+// Close deregisters DefaultEndpointer from the Instancer and stops the internal go-routine.
+func (de *DefaultEndpointer[I, O]) Close() {
+	de.instancer.Deregister(de.ch)
+	close(de.ch)
+}
+
+// Endpoints implements Endpointer.
+func (de *DefaultEndpointer[I, O]) Endpoints() ([]endpoint.Endpoint[I, O], error) {
+	return de.cache.Endpoints()
+}
+
+// InstanceEndpoints implements InstanceEndpointer.
+func (de *DefaultEndpointer[I, O]) InstanceEndpoints() ([]InstanceEndpoint[I, O], error) {
+	return de.cache.InstanceEndpoints()
+}
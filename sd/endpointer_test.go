@@ -0,0 +1,147 @@
+package sd_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/sd"
+	"github.com/barrett370/kit/v2/sd/internal/instance"
+)
+
+func TestDefaultEndpointer(t *testing.T) {
+	var (
+		ca = make(closer)
+		cb = make(closer)
+		c  = map[string]io.Closer{"a": ca, "b": cb}
+		f  = func(instance string) (endpoint.Endpoint[string, int], io.Closer, error) {
+			return func(_ context.Context, request string) (int, error) { return len(request), nil }, c[instance], nil
+		}
+		instancer = &mockInstancer{instance.NewCache()}
+	)
+	// set initial state
+	instancer.Update(sd.Event{Instances: []string{"a", "b"}})
+
+	endpointer := sd.NewEndpointer(instancer, f, log.NewNopLogger(), sd.InvalidateOnError(time.Minute))
+
+	var (
+		endpoints []endpoint.Endpoint[string, int]
+		err       error
+	)
+	if !within(time.Second, func() bool {
+		endpoints, err = endpointer.Endpoints()
+		return err == nil && len(endpoints) == 2
+	}) {
+		t.Errorf("want 2 endpoints, have %d (%v)", len(endpoints), err)
+	}
+
+	// The endpoints are typed: no assertion is needed on the response.
+	if n, err := endpoints[0](context.Background(), "four"); n != 4 || err != nil {
+		t.Errorf("want 4, nil, have %d, %v", n, err)
+	}
+
+	instancer.Update(sd.Event{Instances: []string{}})
+	select {
+	case <-ca:
+		t.Logf("endpoint a closed, good")
+	case <-time.After(time.Second):
+		t.Errorf("didn't close the deleted instance in time")
+	}
+	select {
+	case <-cb:
+		t.Logf("endpoint b closed, good")
+	case <-time.After(time.Second):
+		t.Errorf("didn't close the deleted instance in time")
+	}
+	if endpoints, err := endpointer.Endpoints(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if want, have := 0, len(endpoints); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+
+	endpointer.Close()
+	instancer.Update(sd.Event{Instances: []string{"a"}})
+}
+
+func TestDefaultEndpointerMetadata(t *testing.T) {
+	var (
+		f = func(instance string) (endpoint.Endpoint[string, int], io.Closer, error) {
+			return func(context.Context, string) (int, error) { return 0, nil }, nil, nil
+		}
+		instancer = &mockInstancer{instance.NewCache()}
+	)
+	instancer.Update(sd.Event{
+		Instances: []string{"b", "a"},
+		Metadata:  map[string]sd.Metadata{"a": {sd.MetadataWeight: "2"}},
+	})
+	endpointer := sd.NewEndpointer(instancer, f, log.NewNopLogger())
+	defer endpointer.Close()
+
+	var instances []sd.InstanceEndpoint[string, int]
+	if !within(time.Second, func() bool {
+		instances, _ = endpointer.InstanceEndpoints()
+		return len(instances) == 2
+	}) {
+		t.Fatalf("want 2 instance endpoints, have %d", len(instances))
+	}
+	if want, have := "a", instances[0].Instance; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := 2, instances[0].Metadata.Weight(); want != have {
+		t.Errorf("want weight %d, have %d", want, have)
+	}
+	if want, have := 1, instances[1].Metadata.Weight(); want != have {
+		t.Errorf("want weight %d, have %d", want, have)
+	}
+}
+
+func TestFixedEndpointer(t *testing.T) {
+	e := sd.FixedEndpointer[string, int]{func(context.Context, string) (int, error) { return 1, nil }}
+	endpoints, err := e.Endpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, len(endpoints); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+type mockInstancer struct{ *instance.Cache }
+
+type closer chan struct{}
+
+func (c closer) Close() error { close(c); return nil }
+
+func within(d time.Duration, f func() bool) bool {
+	deadline := time.Now().Add(d)
+	for time.Now().Before(deadline) {
+		if f() {
+			return true
+		}
+		time.Sleep(d / 10)
+	}
+	return false
+}
+
+func TestFixedInstancer(t *testing.T) {
+	var (
+		f = func(instance string) (endpoint.Endpoint[string, string], io.Closer, error) {
+			return func(context.Context, string) (string, error) { return instance, nil }, nil, nil
+		}
+		instancer  = sd.NewFixedInstancer([]string{"a:80", "b:80"})
+		endpointer = sd.NewEndpointer(instancer, f, log.NewNopLogger())
+	)
+	defer endpointer.Close()
+
+	var endpoints []endpoint.Endpoint[string, string]
+	if !within(time.Second, func() bool {
+		endpoints, _ = endpointer.Endpoints()
+		return len(endpoints) == 2
+	}) {
+		t.Fatalf("want 2 endpoints, have %d", len(endpoints))
+	}
+}
@@ -0,0 +1,431 @@
+package etcdv3
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNoKey indicates a client method needs a key but receives none.
+	ErrNoKey = errors.New("no key provided")
+
+	// ErrNoValue indicates a client method needs a value but receives none.
+	ErrNoValue = errors.New("no value provided")
+)
+
+// Client is a wrapper around the etcd v3 API.
+type Client interface {
+	// List returns the values of all keys under the prefix, by key, and the
+	// revision of the store they were read at.
+	List(ctx context.Context, prefix string) (map[string]string, int64, error)
+
+	// Watch sends the changes to keys under the prefix, from the revision,
+	// on ch, until the context is canceled, or the watch fails. If the
+	// revision has been compacted, it returns ErrCompacted, and the caller
+	// must List again.
+	Watch(ctx context.Context, prefix string, revision int64, ch chan<- WatchEvent) error
+
+	// Register a service with etcd. If it has a TTL, it's put under a lease,
+	// which is kept alive until the service is deregistered, or the client
+	// is closed. If the lease expires anyway, e.g. after a partition, the
+	// service is registered again under a new lease.
+	Register(s Service) error
+
+	// Deregister a service with etcd, revoking its lease.
+	Deregister(s Service) error
+
+	// LeaseID returns the lease ID created for this service instance.
+	LeaseID() int64
+
+	// Close revokes the lease of any registered service, so that it's
+	// deregistered at once, rather than when the lease expires.
+	Close() error
+}
+
+// WatchEvent is a change to a key.
+type WatchEvent struct {
+	Type     EventType
+	Key      string
+	Value    string // empty for deletes
+	Revision int64
+}
+
+// EventType is the type of a WatchEvent.
+type EventType int
+
+// The types of WatchEvent.
+const (
+	EventPut EventType = iota
+	EventDelete
+)
+
+// ErrCompacted is returned by Watch if the revision it starts from has been
+// compacted away.
+var ErrCompacted = errors.New("required revision has been compacted")
+
+// ClientOptions defines options for the etcd client. All values are
+// optional. If any duration is not specified, a default will be used.
+type ClientOptions struct {
+	HTTPClient  *http.Client
+	DialTimeout time.Duration
+
+	// Username and Password authenticate with etcd, if it has auth enabled.
+	Username string
+	Password string
+}
+
+type client struct {
+	ctx      context.Context
+	machines []string
+	http     *http.Client
+	username string
+	password string
+
+	mtx     sync.Mutex
+	token   string
+	leaseID int64
+	cancel  context.CancelFunc // stops keepalives
+}
+
+// NewClient returns a Client for the etcd cluster at the machines, e.g.
+// http://127.0.0.1:2379, via its gRPC gateway, which serves the v3 API as
+// JSON. The context bounds the lifetime of watches and keepalives.
+func NewClient(ctx context.Context, machines []string, options ClientOptions) (Client, error) {
+	if len(machines) == 0 {
+		return nil, errors.New("no etcd machines")
+	}
+	if options.DialTimeout == 0 {
+		options.DialTimeout = 3 * time.Second
+	}
+	httpClient := options.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	c := &client{
+		ctx:      ctx,
+		machines: machines,
+		http:     httpClient,
+		username: options.Username,
+		password: options.Password,
+	}
+	if c.username != "" {
+		tctx, cancel := context.WithTimeout(ctx, options.DialTimeout)
+		defer cancel()
+		if err := c.authenticate(tctx); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+type keyValue struct {
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+type responseHeader struct {
+	Revision string `json:"revision"`
+}
+
+func encode(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+func decode(s string) string {
+	b, _ := base64.StdEncoding.DecodeString(s)
+	return string(b)
+}
+
+// prefixEnd returns the end of the range of keys with the prefix.
+func prefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+	return "\x00" // the prefix is all 0xff; range to the end of the keyspace
+}
+
+func (c *client) List(ctx context.Context, prefix string) (map[string]string, int64, error) {
+	var resp struct {
+		Header responseHeader `json:"header"`
+		KVs    []keyValue     `json:"kvs"`
+	}
+	err := c.call(ctx, "/v3/kv/range", map[string]string{
+		"key":       encode(prefix),
+		"range_end": encode(prefixEnd(prefix)),
+	}, &resp)
+	if err != nil {
+		return nil, 0, err
+	}
+	entries := make(map[string]string, len(resp.KVs))
+	for _, kv := range resp.KVs {
+		entries[decode(kv.Key)] = decode(kv.Value)
+	}
+	revision, err := strconv.ParseInt(resp.Header.Revision, 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid revision %q", resp.Header.Revision)
+	}
+	return entries, revision, nil
+}
+
+func (c *client) Watch(ctx context.Context, prefix string, revision int64, ch chan<- WatchEvent) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"create_request": map[string]interface{}{
+			"key":            encode(prefix),
+			"range_end":      encode(prefixEnd(prefix)),
+			"start_revision": strconv.FormatInt(revision, 10),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := c.post(ctx, "/v3/watch", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(resp.Body))
+	for {
+		var msg struct {
+			Result struct {
+				Canceled        bool   `json:"canceled"`
+				CompactRevision string `json:"compact_revision"`
+				CancelReason    string `json:"cancel_reason"`
+				Events          []struct {
+					Type string `json:"type"`
+					KV   struct {
+						keyValue
+						ModRevision string `json:"mod_revision"`
+					} `json:"kv"`
+				} `json:"events"`
+			} `json:"result"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := dec.Decode(&msg); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF // watches don't end by themselves
+			}
+			return err
+		}
+		switch {
+		case msg.Error != nil:
+			return errors.New(msg.Error.Message)
+		case msg.Result.CompactRevision != "" && msg.Result.CompactRevision != "0":
+			return ErrCompacted
+		case msg.Result.Canceled:
+			return fmt.Errorf("watch canceled: %s", msg.Result.CancelReason)
+		}
+		for _, e := range msg.Result.Events {
+			event := WatchEvent{Key: decode(e.KV.Key)}
+			event.Revision, _ = strconv.ParseInt(e.KV.ModRevision, 10, 64)
+			if e.Type == "DELETE" {
+				event.Type = EventDelete
+			} else {
+				event.Type, event.Value = EventPut, decode(e.KV.Value)
+			}
+			select {
+			case ch <- event:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+func (c *client) Register(s Service) error {
+	if s.Key == "" {
+		return ErrNoKey
+	}
+	if s.Value == "" {
+		return ErrNoValue
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.cancel != nil {
+		c.cancel() // a new registration replaces the previous one
+		c.cancel = nil
+	}
+	if err := c.put(s); err != nil {
+		return err
+	}
+
+	if s.TTL != nil {
+		ctx, cancel := context.WithCancel(c.ctx)
+		c.cancel = cancel
+		go c.keepAlive(ctx, s)
+	}
+	return nil
+}
+
+// put puts the service, under a new lease if it has a TTL. The caller must
+// hold the mutex.
+func (c *client) put(s Service) error {
+	put := map[string]interface{}{"key": encode(s.Key), "value": encode(s.Value)}
+	if s.TTL != nil {
+		var grant struct {
+			ID string `json:"ID"`
+		}
+		ttl := strconv.FormatInt(int64(s.TTL.ttl/time.Second), 10)
+		if err := c.call(c.ctx, "/v3/lease/grant", map[string]string{"TTL": ttl}, &grant); err != nil {
+			return err
+		}
+		id, err := strconv.ParseInt(grant.ID, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid lease ID %q", grant.ID)
+		}
+		c.leaseID = id
+		put["lease"] = grant.ID
+	}
+	return c.call(c.ctx, "/v3/kv/put", put, nil)
+}
+
+func (c *client) keepAlive(ctx context.Context, s Service) {
+	ticker := time.NewTicker(s.TTL.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		c.mtx.Lock()
+		leaseID := c.leaseID
+		c.mtx.Unlock()
+		var resp struct {
+			Result struct {
+				TTL string `json:"TTL"`
+			} `json:"result"`
+		}
+		// Errors are retried at the next heartbeat, which, with a TTL of
+		// several heartbeats, keeps the lease alive through blips.
+		if err := c.call(ctx, "/v3/lease/keepalive", map[string]string{"ID": strconv.FormatInt(leaseID, 10)}, &resp); err != nil {
+			continue
+		}
+		if resp.Result.TTL != "" && resp.Result.TTL != "0" {
+			continue
+		}
+
+		// The lease has expired, and the key with it: register again.
+		c.mtx.Lock()
+		if ctx.Err() == nil {
+			c.put(s)
+		}
+		c.mtx.Unlock()
+	}
+}
+
+func (c *client) Deregister(s Service) error {
+	if s.Key == "" {
+		return ErrNoKey
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.revoke()
+	return c.call(context.Background(), "/v3/kv/deleterange", map[string]string{"key": encode(s.Key)}, nil)
+}
+
+// revoke stops keepalives, and revokes the lease, if any. The caller must
+// hold the mutex.
+func (c *client) revoke() error {
+	if c.cancel != nil {
+		c.cancel()
+		c.cancel = nil
+	}
+	if c.leaseID == 0 {
+		return nil
+	}
+	leaseID := c.leaseID
+	c.leaseID = 0
+	return c.call(context.Background(), "/v3/lease/revoke", map[string]string{"ID": strconv.FormatInt(leaseID, 10)}, nil)
+}
+
+func (c *client) LeaseID() int64 {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.leaseID
+}
+
+func (c *client) Close() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.revoke()
+}
+
+func (c *client) authenticate(ctx context.Context) error {
+	var resp struct {
+		Token string `json:"token"`
+	}
+	if err := c.call(ctx, "/v3/auth/authenticate", map[string]string{"name": c.username, "password": c.password}, &resp); err != nil {
+		return err
+	}
+	c.token = resp.Token
+	return nil
+}
+
+// call posts the request to the API, on each machine in turn until one
+// responds, and decodes the response into resp, if it isn't nil.
+func (c *client) call(ctx context.Context, path string, req, resp interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	r, err := c.post(ctx, path, body)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	if resp == nil {
+		io.Copy(ioutil.Discard, r.Body)
+		return nil
+	}
+	return json.NewDecoder(r.Body).Decode(resp)
+}
+
+func (c *client) post(ctx context.Context, path string, body []byte) (*http.Response, error) {
+	var lastErr error
+	for _, machine := range c.machines {
+		req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimSuffix(machine, "/")+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if c.token != "" {
+			req.Header.Set("Authorization", c.token)
+		}
+		resp, err := c.http.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+			resp.Body.Close()
+			return nil, fmt.Errorf("etcd returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+		}
+		return resp, nil
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,5 @@
+// Package etcdv3 provides an Instancer and Registrar implementation for etcd v3,
+// with a small client for the parts of the etcd v3 API they use, spoken as
+// JSON through etcd's gRPC gateway. Registrations are put under a lease, which
+// the client keeps alive, and Instancers apply the events of a watch.
+package etcdv3
@@ -0,0 +1,149 @@
+package etcdv3
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/barrett370/kit/v2/sd"
+	"github.com/barrett370/kit/v2/sd/internal/instance"
+	"github.com/barrett370/kit/v2/util/conn"
+)
+
+// Instancer yields instances stored in a certain etcd keyspace. Any kind of
+// change in that keyspace is watched and will update the subscribers.
+// Changes are applied from the events of a watch, so the keyspace is only
+// listed again if the watch falls behind a compaction.
+type Instancer struct {
+	cache  *instance.Cache
+	client Client
+	prefix string
+	logger log.Logger
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewInstancer returns an etcd instancer. It will start watching the given
+// prefix for changes, and update the subscribers.
+func NewInstancer(c Client, prefix string, logger log.Logger) (*Instancer, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Instancer{
+		client: c,
+		prefix: prefix,
+		cache:  instance.NewCache(),
+		logger: log.With(logger, "prefix", prefix),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	entries, revision, err := s.client.List(ctx, s.prefix)
+	if err == nil {
+		s.logger.Log("instances", len(entries))
+	} else {
+		s.logger.Log("err", err)
+	}
+	s.cache.Update(sd.Event{Instances: values(entries), Err: err})
+
+	go s.loop(ctx, entries, revision)
+	return s, nil
+}
+
+func (s *Instancer) loop(ctx context.Context, entries map[string]string, revision int64) {
+	defer close(s.done)
+	var (
+		d      = 10 * time.Millisecond
+		events = make(chan WatchEvent)
+		errc   = make(chan error, 1)
+	)
+	for {
+		if entries == nil {
+			var err error
+			if entries, revision, err = s.client.List(ctx, s.prefix); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				s.logger.Log("err", err)
+				s.cache.Update(sd.Event{Err: err})
+				entries = nil
+				if !sleep(ctx, d) {
+					return
+				}
+				d = conn.Exponential(d)
+				continue
+			}
+			s.cache.Update(sd.Event{Instances: values(entries)})
+		}
+
+		go func(revision int64) { errc <- s.client.Watch(ctx, s.prefix, revision, events) }(revision + 1)
+	WATCH:
+		for {
+			select {
+			case e := <-events:
+				switch e.Type {
+				case EventPut:
+					entries[e.Key] = e.Value
+				case EventDelete:
+					delete(entries, e.Key)
+				}
+				revision = e.Revision
+				s.cache.Update(sd.Event{Instances: values(entries)})
+				d = 10 * time.Millisecond // the watch works; reset the backoff
+			case err := <-errc:
+				if ctx.Err() != nil {
+					return
+				}
+				s.logger.Log("during", "watch", "err", err)
+				if err == ErrCompacted {
+					entries = nil // changes have been lost: list again
+				}
+				break WATCH
+			}
+		}
+		if !sleep(ctx, d) {
+			return
+		}
+		d = conn.Exponential(d)
+	}
+}
+
+// sleep waits for d, and reports whether the context is still live.
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// values returns the values of the entries in the order of their keys.
+func values(entries map[string]string) []string {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	instances := make([]string, len(keys))
+	for i, key := range keys {
+		instances[i] = entries[key]
+	}
+	return instances
+}
+
+// Stop terminates the Instancer.
+func (s *Instancer) Stop() {
+	s.cancel()
+	<-s.done
+}
+
+// Register implements Instancer.
+func (s *Instancer) Register(ch chan<- sd.Event) {
+	s.cache.Register(ch)
+}
+
+// Deregister implements Instancer.
+func (s *Instancer) Deregister(ch chan<- sd.Event) {
+	s.cache.Deregister(ch)
+}
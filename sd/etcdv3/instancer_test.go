@@ -0,0 +1,132 @@
+package etcdv3
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/barrett370/kit/v2/sd"
+)
+
+var _ sd.Instancer = (*Instancer)(nil) // API check
+
+// testClient serves entries from List, and forwards events to watches until
+// an error is sent on errs.
+type testClient struct {
+	mtx       sync.Mutex
+	entries   map[string]string
+	revision  int64
+	lists     int
+	revisions []int64 // of each watch
+
+	events chan WatchEvent
+	errs   chan error
+}
+
+func newTestClient(entries map[string]string) *testClient {
+	return &testClient{
+		entries:  entries,
+		revision: 10,
+		events:   make(chan WatchEvent),
+		errs:     make(chan error),
+	}
+}
+
+func (c *testClient) List(ctx context.Context, prefix string) (map[string]string, int64, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.lists++
+	entries := make(map[string]string, len(c.entries))
+	for k, v := range c.entries {
+		entries[k] = v
+	}
+	return entries, c.revision, nil
+}
+
+func (c *testClient) Watch(ctx context.Context, prefix string, revision int64, ch chan<- WatchEvent) error {
+	c.mtx.Lock()
+	c.revisions = append(c.revisions, revision)
+	c.mtx.Unlock()
+	for {
+		select {
+		case e := <-c.events:
+			select {
+			case ch <- e:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		case err := <-c.errs:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (c *testClient) Register(s Service) error   { return nil }
+func (c *testClient) Deregister(s Service) error { return nil }
+func (c *testClient) LeaseID() int64             { return 0 }
+func (c *testClient) Close() error               { return nil }
+
+func expect(t *testing.T, events <-chan sd.Event, want []string) {
+	t.Helper()
+	select {
+	case e := <-events:
+		if have := e.Instances; !reflect.DeepEqual(want, have) {
+			t.Fatalf("want %v, have %v", want, have)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout waiting for %v", want)
+	}
+}
+
+func TestInstancer(t *testing.T) {
+	client := newTestClient(map[string]string{
+		"/svc/b": "10.0.0.2:80",
+		"/svc/a": "10.0.0.1:80",
+	})
+	s, err := NewInstancer(client, "/svc/", log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	events := make(chan sd.Event, 1)
+	s.Register(events)
+	defer s.Deregister(events)
+	expect(t, events, []string{"10.0.0.1:80", "10.0.0.2:80"})
+
+	client.events <- WatchEvent{Type: EventPut, Key: "/svc/c", Value: "10.0.0.3:80", Revision: 11}
+	expect(t, events, []string{"10.0.0.1:80", "10.0.0.2:80", "10.0.0.3:80"})
+	client.events <- WatchEvent{Type: EventDelete, Key: "/svc/a", Revision: 12}
+	expect(t, events, []string{"10.0.0.2:80", "10.0.0.3:80"})
+
+	// A failed watch resumes after the last event, without listing again.
+	client.errs <- errors.New("connection reset")
+	client.events <- WatchEvent{Type: EventPut, Key: "/svc/a", Value: "10.0.0.4:80", Revision: 13}
+	expect(t, events, []string{"10.0.0.2:80", "10.0.0.3:80", "10.0.0.4:80"})
+
+	// A compacted watch lists again.
+	client.mtx.Lock()
+	client.entries = map[string]string{"/svc/d": "10.0.0.5:80"}
+	client.revision = 20
+	client.mtx.Unlock()
+	client.errs <- ErrCompacted
+	expect(t, events, []string{"10.0.0.5:80"})
+	client.events <- WatchEvent{Type: EventDelete, Key: "/svc/d", Revision: 21}
+	expect(t, events, []string{})
+
+	client.mtx.Lock()
+	defer client.mtx.Unlock()
+	if want, have := []int64{11, 13, 21}, client.revisions; !reflect.DeepEqual(want, have) {
+		t.Errorf("watch revisions: want %v, have %v", want, have)
+	}
+	if want, have := 2, client.lists; want != have {
+		t.Errorf("lists: want %d, have %d", want, have)
+	}
+}
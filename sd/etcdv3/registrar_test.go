@@ -0,0 +1,211 @@
+package etcdv3
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/barrett370/kit/v2/sd"
+)
+
+var _ sd.Registrar = (*Registrar)(nil) // API check
+
+// fakeGateway is an etcd gRPC gateway serving one key and one lease, which
+// expires when expired is set.
+type fakeGateway struct {
+	mtx      sync.Mutex
+	requests []string
+	kvs      map[string]string
+	leases   int64
+	expired  bool
+}
+
+func (g *fakeGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&req)
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	g.requests = append(g.requests, r.URL.Path)
+
+	enc := json.NewEncoder(w)
+	switch r.URL.Path {
+	case "/v3/auth/authenticate":
+		if req["name"] != "root" || req["password"] != "hunter2" {
+			http.Error(w, `{"error":"authentication failed"}`, http.StatusBadRequest)
+			return
+		}
+		enc.Encode(map[string]string{"token": "t0ken"})
+	case "/v3/lease/grant":
+		g.leases++
+		g.expired = false
+		enc.Encode(map[string]string{"ID": strconv.FormatInt(g.leases, 10), "TTL": req["TTL"].(string)})
+	case "/v3/lease/keepalive":
+		if g.expired {
+			enc.Encode(map[string]interface{}{"result": map[string]string{"ID": req["ID"].(string)}})
+			return
+		}
+		enc.Encode(map[string]interface{}{"result": map[string]string{"ID": req["ID"].(string), "TTL": "3"}})
+	case "/v3/lease/revoke":
+		g.kvs = map[string]string{}
+	case "/v3/kv/put":
+		g.kvs[decode(req["key"].(string))] = decode(req["value"].(string))
+	case "/v3/kv/deleterange":
+		delete(g.kvs, decode(req["key"].(string)))
+	case "/v3/kv/range":
+		var kvs []keyValue
+		for k, v := range g.kvs {
+			kvs = append(kvs, keyValue{Key: encode(k), Value: encode(v)})
+		}
+		enc.Encode(map[string]interface{}{"header": responseHeader{Revision: "7"}, "kvs": kvs})
+	case "/v3/watch":
+		w.(http.Flusher).Flush()
+		enc.Encode(map[string]interface{}{"result": map[string]interface{}{"created": true}})
+		enc.Encode(map[string]interface{}{"result": map[string]interface{}{"events": []interface{}{
+			map[string]interface{}{"kv": map[string]string{"key": encode("/svc/a"), "value": encode("10.0.0.1:80"), "mod_revision": "8"}},
+			map[string]interface{}{"type": "DELETE", "kv": map[string]string{"key": encode("/svc/b"), "mod_revision": "9"}},
+		}}})
+		enc.Encode(map[string]interface{}{"result": map[string]interface{}{"canceled": true, "compact_revision": "5"}})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (g *fakeGateway) count(path string) int {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	var n int
+	for _, r := range g.requests {
+		if r == path {
+			n++
+		}
+	}
+	return n
+}
+
+func waitFor(t *testing.T, what string, f func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !f() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRegistrarLease(t *testing.T) {
+	gateway := &fakeGateway{kvs: map[string]string{}}
+	server := httptest.NewServer(gateway)
+	defer server.Close()
+
+	client, err := NewClient(context.Background(), []string{"http://127.0.0.1:1", server.URL}, ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRegistrar(client, Service{
+		Key:   "/svc/a",
+		Value: "10.0.0.1:80",
+		TTL:   NewTTLOption(0, 0),
+	}, log.NewNopLogger())
+	r.Register()
+	if want, have := int64(1), client.LeaseID(); want != have {
+		t.Errorf("lease: want %d, have %d", want, have)
+	}
+	entries, revision, err := client.List(context.Background(), "/svc/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := map[string]string{"/svc/a": "10.0.0.1:80"}, entries; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := int64(7), revision; want != have {
+		t.Errorf("revision: want %d, have %d", want, have)
+	}
+
+	waitFor(t, "keepalive", func() bool { return gateway.count("/v3/lease/keepalive") > 0 })
+
+	// An expired lease is granted again, and the key put under it.
+	gateway.mtx.Lock()
+	gateway.expired = true
+	delete(gateway.kvs, "/svc/a")
+	gateway.mtx.Unlock()
+	waitFor(t, "registration", func() bool { return client.LeaseID() == 2 })
+	waitFor(t, "put", func() bool { return gateway.count("/v3/kv/put") == 2 })
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	keepalives := gateway.count("/v3/lease/keepalive")
+	if want, have := 1, gateway.count("/v3/lease/revoke"); want != have {
+		t.Errorf("revoke: want %d, have %d", want, have)
+	}
+	if want, have := int64(0), client.LeaseID(); want != have {
+		t.Errorf("lease: want %d, have %d", want, have)
+	}
+	time.Sleep(600 * time.Millisecond)
+	if want, have := keepalives, gateway.count("/v3/lease/keepalive"); want != have {
+		t.Errorf("keepalives after Close: want %d, have %d", want, have)
+	}
+}
+
+func TestClientWatch(t *testing.T) {
+	server := httptest.NewServer(&fakeGateway{kvs: map[string]string{}})
+	defer server.Close()
+
+	client, err := NewClient(context.Background(), []string{server.URL}, ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan WatchEvent, 2)
+	if want, have := ErrCompacted, client.Watch(context.Background(), "/svc/", 8, events); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	close(events)
+	var have []WatchEvent
+	for e := range events {
+		have = append(have, e)
+	}
+	want := []WatchEvent{
+		{Type: EventPut, Key: "/svc/a", Value: "10.0.0.1:80", Revision: 8},
+		{Type: EventDelete, Key: "/svc/b", Revision: 9},
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestClientAuth(t *testing.T) {
+	server := httptest.NewServer(&fakeGateway{kvs: map[string]string{}})
+	defer server.Close()
+
+	if _, err := NewClient(context.Background(), []string{server.URL}, ClientOptions{Username: "root", Password: "wrong"}); err == nil || !strings.Contains(err.Error(), "authentication failed") {
+		t.Errorf("want authentication error, have %v", err)
+	}
+	if _, err := NewClient(context.Background(), []string{server.URL}, ClientOptions{Username: "root", Password: "hunter2"}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTTLOption(t *testing.T) {
+	for _, tc := range []struct {
+		heartbeat, ttl time.Duration
+		want           TTLOption
+	}{
+		{0, 0, TTLOption{500 * time.Millisecond, 1500 * time.Millisecond}},
+		{3 * time.Second, 10 * time.Second, TTLOption{3 * time.Second, 10 * time.Second}},
+		{3 * time.Second, 5 * time.Second, TTLOption{3 * time.Second, 9 * time.Second}},
+	} {
+		if want, have := tc.want, *NewTTLOption(tc.heartbeat, tc.ttl); want != have {
+			t.Errorf("NewTTLOption(%v, %v): want %+v, have %+v", tc.heartbeat, tc.ttl, want, have)
+		}
+	}
+}
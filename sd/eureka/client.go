@@ -0,0 +1,286 @@
+package eureka
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Instance statuses.
+const (
+	StatusUp           = "UP"
+	StatusDown         = "DOWN"
+	StatusStarting     = "STARTING"
+	StatusOutOfService = "OUT_OF_SERVICE"
+	StatusUnknown      = "UNKNOWN"
+)
+
+// Delta action types.
+const (
+	ActionAdded    = "ADDED"
+	ActionModified = "MODIFIED"
+	ActionDeleted  = "DELETED"
+)
+
+// ErrNotFound is returned by Heartbeat if Eureka doesn't know the instance,
+// e.g. because its lease expired, and it must be registered again.
+var ErrNotFound = errors.New("instance not found")
+
+// Instance is an instance of an application, as Eureka's JSON represents it.
+type Instance struct {
+	InstanceID       string            `json:"instanceId,omitempty"`
+	HostName         string            `json:"hostName"`
+	App              string            `json:"app"`
+	IPAddr           string            `json:"ipAddr"`
+	VIPAddress       string            `json:"vipAddress,omitempty"`
+	SecureVIPAddress string            `json:"secureVipAddress,omitempty"`
+	Status           string            `json:"status"`
+	Port             *Port             `json:"port,omitempty"`
+	SecurePort       *Port             `json:"securePort,omitempty"`
+	HomePageURL      string            `json:"homePageUrl,omitempty"`
+	StatusPageURL    string            `json:"statusPageUrl,omitempty"`
+	HealthCheckURL   string            `json:"healthCheckUrl,omitempty"`
+	DataCenterInfo   DataCenterInfo    `json:"dataCenterInfo"`
+	LeaseInfo        *LeaseInfo        `json:"leaseInfo,omitempty"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+	ActionType       string            `json:"actionType,omitempty"` // of deltas
+}
+
+// ID returns the ID of the instance, which defaults to its host name.
+func (i *Instance) ID() string {
+	if i.InstanceID != "" {
+		return i.InstanceID
+	}
+	return i.HostName
+}
+
+// Port is a port of an instance, and whether it's enabled.
+type Port struct {
+	Port    int  `json:"$"`
+	Enabled bool `json:"@enabled,string"`
+}
+
+// DataCenterInfo describes where an instance runs. The zero value describes
+// an instance in your own data center.
+type DataCenterInfo struct {
+	Class string `json:"@class"`
+	Name  string `json:"name"`
+}
+
+// MarshalJSON implements json.Marshaler, defaulting the class and name of
+// the zero value, which Eureka requires.
+func (d DataCenterInfo) MarshalJSON() ([]byte, error) {
+	type dataCenterInfo DataCenterInfo
+	if d.Class == "" {
+		d.Class = "com.netflix.appinfo.InstanceInfo$DefaultDataCenterInfo"
+	}
+	if d.Name == "" {
+		d.Name = "MyOwn"
+	}
+	return json.Marshal(dataCenterInfo(d))
+}
+
+// LeaseInfo is the lease of an instance. Eureka expires instances which
+// haven't sent a heartbeat for DurationInSecs, by default 90s; they're
+// expected to send one every RenewalIntervalInSecs, by default 30s.
+type LeaseInfo struct {
+	RenewalIntervalInSecs int `json:"renewalIntervalInSecs,omitempty"`
+	DurationInSecs        int `json:"durationInSecs,omitempty"`
+}
+
+// Application is a named set of instances.
+type Application struct {
+	Name      string      `json:"name"`
+	Instances []*Instance `json:"instance"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Eureka encodes a single
+// instance as an object, rather than an array of one.
+func (a *Application) UnmarshalJSON(b []byte) error {
+	var app struct {
+		Name      string          `json:"name"`
+		Instances json.RawMessage `json:"instance"`
+	}
+	if err := json.Unmarshal(b, &app); err != nil {
+		return err
+	}
+	a.Name, a.Instances = app.Name, nil
+	return unmarshalOneOrMany(app.Instances, &a.Instances)
+}
+
+// Applications is a set of applications, e.g. the delta of the registry.
+type Applications struct {
+	VersionsDelta string         `json:"versions__delta"`
+	AppsHashcode  string         `json:"apps__hashcode"`
+	Applications  []*Application `json:"application"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Eureka encodes a single
+// application as an object, rather than an array of one.
+func (a *Applications) UnmarshalJSON(b []byte) error {
+	var apps struct {
+		VersionsDelta json.Number     `json:"versions__delta"`
+		AppsHashcode  string          `json:"apps__hashcode"`
+		Applications  json.RawMessage `json:"application"`
+	}
+	if err := json.Unmarshal(b, &apps); err != nil {
+		return err
+	}
+	a.VersionsDelta, a.AppsHashcode, a.Applications = apps.VersionsDelta.String(), apps.AppsHashcode, nil
+	return unmarshalOneOrMany(apps.Applications, &a.Applications)
+}
+
+func unmarshalOneOrMany[T any](b json.RawMessage, v *[]*T) error {
+	b = bytes.TrimSpace(b)
+	switch {
+	case len(b) == 0 || string(b) == "null":
+		return nil
+	case b[0] == '[':
+		return json.Unmarshal(b, v)
+	default:
+		var one T
+		if err := json.Unmarshal(b, &one); err != nil {
+			return err
+		}
+		*v = []*T{&one}
+		return nil
+	}
+}
+
+// Client is a wrapper around the Eureka REST API.
+type Client interface {
+	// Register an instance.
+	Register(ctx context.Context, i *Instance) error
+
+	// Deregister an instance.
+	Deregister(ctx context.Context, i *Instance) error
+
+	// Heartbeat renews the lease of an instance. It returns ErrNotFound if
+	// the instance isn't registered.
+	Heartbeat(ctx context.Context, i *Instance) error
+
+	// Application returns the instances of an application.
+	Application(ctx context.Context, app string) (*Application, error)
+
+	// Delta returns the instances of all applications which have changed in
+	// the last few minutes, each with the action of its change.
+	Delta(ctx context.Context) (*Applications, error)
+}
+
+type client struct {
+	address string
+	http    *http.Client
+}
+
+// NewClient returns a Client for the Eureka server at the address, including
+// the path of its API, e.g. http://127.0.0.1:8761/eureka. If httpClient is
+// nil, http.DefaultClient is used.
+func NewClient(address string, httpClient *http.Client) Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &client{
+		address: strings.TrimSuffix(address, "/"),
+		http:    httpClient,
+	}
+}
+
+func (c *client) Register(ctx context.Context, i *Instance) error {
+	body := struct {
+		Instance *Instance `json:"instance"`
+	}{i}
+	_, err := c.do(ctx, "POST", "/apps/"+url.PathEscape(i.App), nil, body, nil)
+	return err
+}
+
+func (c *client) Deregister(ctx context.Context, i *Instance) error {
+	_, err := c.do(ctx, "DELETE", c.instancePath(i), nil, nil, nil)
+	return err
+}
+
+func (c *client) Heartbeat(ctx context.Context, i *Instance) error {
+	status, err := c.do(ctx, "PUT", c.instancePath(i), nil, nil, nil)
+	if status == http.StatusNotFound {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (c *client) instancePath(i *Instance) string {
+	return "/apps/" + url.PathEscape(i.App) + "/" + url.PathEscape(i.ID())
+}
+
+func (c *client) Application(ctx context.Context, app string) (*Application, error) {
+	var resp struct {
+		Application *Application `json:"application"`
+	}
+	status, err := c.do(ctx, "GET", "/apps/"+url.PathEscape(app), nil, nil, &resp)
+	if status == http.StatusNotFound {
+		return &Application{Name: app}, nil // no instances
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp.Application == nil {
+		return &Application{Name: app}, nil
+	}
+	return resp.Application, nil
+}
+
+func (c *client) Delta(ctx context.Context) (*Applications, error) {
+	var resp struct {
+		Applications *Applications `json:"applications"`
+	}
+	if _, err := c.do(ctx, "GET", "/apps/delta", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Applications == nil {
+		return &Applications{}, nil
+	}
+	return resp.Applications, nil
+}
+
+func (c *client) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) (int, error) {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return 0, err
+		}
+		body = bytes.NewReader(b)
+	}
+	u := c.address + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp.StatusCode, fmt.Errorf("eureka returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, err
+		}
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,3 @@
+// Package eureka provides Instancer and Registrar implementations for Netflix
+// OSS's Eureka, with a small client for the parts of its REST API they use.
+package eureka
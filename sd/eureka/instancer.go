@@ -0,0 +1,204 @@
+package eureka
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/barrett370/kit/v2/sd"
+	"github.com/barrett370/kit/v2/sd/internal/instance"
+)
+
+// DefaultRefreshInterval is the interval between fetches of the registry,
+// which matches the Eureka Java client.
+const DefaultRefreshInterval = 30 * time.Second
+
+// deltaRetention is how long Eureka keeps changes in the delta, by default.
+// If a delta isn't fetched within it, changes may have been missed.
+const deltaRetention = 3 * time.Minute
+
+// InstancerOption sets an optional parameter for Instancers.
+type InstancerOption func(*Instancer)
+
+// InstancerRefreshInterval sets the interval between fetches of the
+// registry. By default, it's DefaultRefreshInterval.
+func InstancerRefreshInterval(d time.Duration) InstancerOption {
+	return func(s *Instancer) { s.refresh = d }
+}
+
+// InstancerDisableDelta makes the Instancer fetch all instances of the
+// application every refresh, rather than the delta of the registry, for
+// servers with deltas disabled.
+func InstancerDisableDelta() InstancerOption {
+	return func(s *Instancer) { s.delta = false }
+}
+
+// Instancer yields instances stored in the Eureka registry for the given
+// application. Changes in that application are watched and will update the
+// subscribers. After the first fetch, the Instancer fetches the delta of the
+// registry, as the Eureka Java client does, and applies the changes to the
+// application; it fetches the application in full again if fetching a delta
+// fails, or one hasn't been fetched within the time Eureka keeps changes.
+//
+// Only instances which are UP, with an enabled port, are yielded.
+type Instancer struct {
+	cache   *instance.Cache
+	client  Client
+	app     string
+	logger  log.Logger
+	refresh time.Duration
+	delta   bool
+
+	instances map[string]*Instance // by ID; nil if a full fetch is needed
+	fetched   time.Time            // of the last successful fetch
+
+	quit chan struct{}
+	done chan struct{}
+}
+
+// NewInstancer returns a Eureka Instancer for the application.
+func NewInstancer(client Client, app string, logger log.Logger, options ...InstancerOption) *Instancer {
+	s := &Instancer{
+		cache:   instance.NewCache(),
+		client:  client,
+		app:     app,
+		logger:  log.With(logger, "app", app),
+		refresh: DefaultRefreshInterval,
+		delta:   true,
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	for _, option := range options {
+		option(s)
+	}
+
+	if err := s.update(); err == nil {
+		s.logger.Log("instances", len(s.instances))
+	} else {
+		s.logger.Log("during", "fetch", "err", err)
+	}
+	go s.loop()
+	return s
+}
+
+func (s *Instancer) loop() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.update(); err != nil {
+				s.logger.Log("during", "fetch", "err", err)
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// update fetches the delta of the registry, or the application in full, and
+// updates the cache.
+func (s *Instancer) update() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.refresh)
+	defer cancel()
+
+	var err error
+	if s.delta && s.instances != nil && time.Since(s.fetched) < deltaRetention {
+		if err = s.fetchDelta(ctx); err == nil {
+			s.fetched = time.Now()
+			s.cache.Update(s.event())
+			return nil
+		}
+		s.logger.Log("during", "delta", "err", err)
+	}
+
+	app, err := s.client.Application(ctx, s.app)
+	if err != nil {
+		s.cache.Update(sd.Event{Err: err})
+		return err
+	}
+	s.instances = make(map[string]*Instance, len(app.Instances))
+	for _, i := range app.Instances {
+		s.instances[i.ID()] = i
+	}
+	s.fetched = time.Now()
+	s.cache.Update(s.event())
+	return nil
+}
+
+func (s *Instancer) fetchDelta(ctx context.Context) error {
+	delta, err := s.client.Delta(ctx)
+	if err != nil {
+		return err
+	}
+	for _, app := range delta.Applications {
+		if !strings.EqualFold(app.Name, s.app) { // Eureka upper-cases names
+			continue
+		}
+		for _, i := range app.Instances {
+			switch i.ActionType {
+			case ActionDeleted:
+				delete(s.instances, i.ID())
+			default:
+				s.instances[i.ID()] = i
+			}
+		}
+	}
+	return nil
+}
+
+// event returns the addresses of the instances which are UP, with their
+// Eureka metadata, which is where Spring Cloud, for example, puts their zones.
+func (s *Instancer) event() sd.Event {
+	instances := make([]string, 0, len(s.instances))
+	metadata := make(map[string]sd.Metadata, len(s.instances))
+	for _, i := range s.instances {
+		if i.Status != StatusUp {
+			continue
+		}
+		if addr, ok := address(i); ok {
+			instances = append(instances, addr)
+			if len(i.Metadata) > 0 {
+				metadata[addr] = i.Metadata // instances are replaced, not modified
+			}
+		}
+	}
+	return sd.Event{Instances: instances, Metadata: metadata}
+}
+
+// address returns the address of an instance: its IP address, and its port,
+// if enabled, or else its secure port.
+func address(i *Instance) (string, bool) {
+	host := i.IPAddr
+	if host == "" {
+		host = i.HostName
+	}
+	switch {
+	case i.Port != nil && i.Port.Enabled:
+		return net.JoinHostPort(host, strconv.Itoa(i.Port.Port)), true
+	case i.SecurePort != nil && i.SecurePort.Enabled:
+		return net.JoinHostPort(host, strconv.Itoa(i.SecurePort.Port)), true
+	}
+	return "", false
+}
+
+// Stop terminates the Instancer.
+func (s *Instancer) Stop() {
+	close(s.quit)
+	<-s.done
+}
+
+// Register implements Instancer.
+func (s *Instancer) Register(ch chan<- sd.Event) {
+	s.cache.Register(ch)
+}
+
+// Deregister implements Instancer.
+func (s *Instancer) Deregister(ch chan<- sd.Event) {
+	s.cache.Deregister(ch)
+}
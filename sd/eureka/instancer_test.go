@@ -0,0 +1,169 @@
+package eureka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/barrett370/kit/v2/sd"
+)
+
+var _ sd.Instancer = (*Instancer)(nil) // API check
+
+func newInstance(id, ip string, port int, status string) *Instance {
+	return &Instance{
+		InstanceID: id,
+		HostName:   id + ".local",
+		App:        "ORDERS",
+		IPAddr:     ip,
+		Status:     status,
+		Port:       &Port{Port: port, Enabled: true},
+	}
+}
+
+// testClient serves an application, and then the queued deltas.
+type testClient struct {
+	mtx          sync.Mutex
+	app          *Application
+	deltas       []*Applications
+	deltaErr     error
+	applications int
+}
+
+func (c *testClient) Application(ctx context.Context, app string) (*Application, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.applications++
+	return c.app, nil
+}
+
+func (c *testClient) Delta(ctx context.Context) (*Applications, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.deltaErr != nil {
+		return nil, c.deltaErr
+	}
+	if len(c.deltas) == 0 {
+		return &Applications{}, nil
+	}
+	delta := c.deltas[0]
+	c.deltas = c.deltas[1:]
+	return delta, nil
+}
+
+func (c *testClient) Register(ctx context.Context, i *Instance) error   { return nil }
+func (c *testClient) Deregister(ctx context.Context, i *Instance) error { return nil }
+func (c *testClient) Heartbeat(ctx context.Context, i *Instance) error  { return nil }
+
+func expect(t *testing.T, events <-chan sd.Event, want []string) sd.Event {
+	t.Helper()
+	select {
+	case e := <-events:
+		if have := e.Instances; !reflect.DeepEqual(want, have) {
+			t.Fatalf("want %v, have %v", want, have)
+		}
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout waiting for %v", want)
+		return sd.Event{}
+	}
+}
+
+func TestInstancerDelta(t *testing.T) {
+	added := newInstance("c", "10.0.0.3", 80, StatusUp)
+	added.ActionType = ActionAdded
+	added.Metadata = map[string]string{"version": "2.0.0"}
+	down := newInstance("a", "10.0.0.1", 80, StatusDown)
+	down.ActionType = ActionModified
+	deleted := newInstance("b", "10.0.0.2", 80, StatusUp)
+	deleted.ActionType = ActionDeleted
+	other := newInstance("d", "10.0.0.4", 80, StatusUp)
+	other.ActionType = ActionAdded
+
+	client := &testClient{
+		app: &Application{Name: "ORDERS", Instances: []*Instance{
+			newInstance("a", "10.0.0.1", 80, StatusUp),
+			newInstance("b", "10.0.0.2", 80, StatusUp),
+			newInstance("x", "10.0.0.9", 80, StatusStarting),
+		}},
+		deltas: []*Applications{
+			{Applications: []*Application{{Name: "ORDERS", Instances: []*Instance{added}}}},
+			{Applications: []*Application{
+				{Name: "ORDERS", Instances: []*Instance{down, deleted}},
+				{Name: "PAYMENTS", Instances: []*Instance{other}},
+			}},
+		},
+	}
+	s := NewInstancer(client, "orders", log.NewNopLogger(), InstancerRefreshInterval(5*time.Millisecond))
+	defer s.Stop()
+
+	events := make(chan sd.Event, 1)
+	s.Register(events)
+	defer s.Deregister(events)
+	expect(t, events, []string{"10.0.0.1:80", "10.0.0.2:80"})
+	expect(t, events, []string{"10.0.0.1:80", "10.0.0.2:80", "10.0.0.3:80"})
+	e := expect(t, events, []string{"10.0.0.3:80"})
+	if want, have := "2.0.0", e.MetadataOf("10.0.0.3:80").Version(); want != have {
+		t.Errorf("want version %q, have %q", want, have)
+	}
+
+	client.mtx.Lock()
+	defer client.mtx.Unlock()
+	if want, have := 1, client.applications; want != have {
+		t.Errorf("full fetches: want %d, have %d", want, have)
+	}
+}
+
+func TestInstancerDeltaError(t *testing.T) {
+	client := &testClient{
+		app:      &Application{Name: "ORDERS", Instances: []*Instance{newInstance("a", "10.0.0.1", 80, StatusUp)}},
+		deltaErr: errors.New("boom"),
+	}
+	s := NewInstancer(client, "ORDERS", log.NewNopLogger(), InstancerRefreshInterval(5*time.Millisecond))
+	defer s.Stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		client.mtx.Lock()
+		n := client.applications
+		client.mtx.Unlock()
+		if n >= 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for full fetches")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if want, have := []string{"10.0.0.1:80"}, s.cache.State().Instances; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestApplicationJSON(t *testing.T) {
+	for _, tc := range []struct {
+		name, json string
+	}{
+		{"object", `{"name":"ORDERS","instance":{"instanceId":"a","ipAddr":"10.0.0.1","status":"UP","port":{"$":8080,"@enabled":"true"}}}`},
+		{"array", `{"name":"ORDERS","instance":[{"instanceId":"a","ipAddr":"10.0.0.1","status":"UP","port":{"$":8080,"@enabled":"true"}}]}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var app Application
+			if err := json.Unmarshal([]byte(tc.json), &app); err != nil {
+				t.Fatal(err)
+			}
+			if want, have := 1, len(app.Instances); want != have {
+				t.Fatalf("want %d instances, have %d", want, have)
+			}
+			if addr, _ := address(app.Instances[0]); addr != "10.0.0.1:8080" {
+				t.Errorf("want %q, have %q", "10.0.0.1:8080", addr)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package eureka
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+// DefaultRenewalInterval is the interval between heartbeats, if the
+// instance's lease doesn't set one.
+const DefaultRenewalInterval = 30 * time.Second
+
+// RegistrarOption sets an optional parameter for Registrars.
+type RegistrarOption func(*Registrar)
+
+// RegistrarHeartbeat sets the interval between heartbeats, overriding the
+// renewal interval of the instance's lease.
+func RegistrarHeartbeat(interval time.Duration) RegistrarOption {
+	return func(r *Registrar) { r.interval = interval }
+}
+
+// RegistrarTimeout sets the timeout of each request to Eureka. By default,
+// it's the heartbeat interval.
+func RegistrarTimeout(d time.Duration) RegistrarOption {
+	return func(r *Registrar) { r.timeout = d }
+}
+
+// Registrar maintains service instance liveness information in Eureka. While
+// the instance is registered, it renews its lease with heartbeats, and
+// registers it again if Eureka has expired it.
+type Registrar struct {
+	client   Client
+	instance *Instance
+	logger   log.Logger
+	interval time.Duration
+	timeout  time.Duration
+
+	mtx  sync.Mutex
+	quit chan struct{}
+	done chan struct{}
+}
+
+// NewRegistrar returns a Eureka Registrar acting on the provided instance. The
+// heartbeat interval is the renewal interval of its lease, or, if it has
+// none, DefaultRenewalInterval.
+func NewRegistrar(client Client, i *Instance, logger log.Logger, options ...RegistrarOption) *Registrar {
+	r := &Registrar{
+		client:   client,
+		instance: i,
+		logger:   log.With(logger, "service", i.App, "address", i.HostName),
+		interval: DefaultRenewalInterval,
+	}
+	if i.LeaseInfo != nil && i.LeaseInfo.RenewalIntervalInSecs > 0 {
+		r.interval = time.Duration(i.LeaseInfo.RenewalIntervalInSecs) * time.Second
+	}
+	for _, option := range options {
+		option(r)
+	}
+	if r.timeout == 0 {
+		r.timeout = r.interval
+	}
+	return r
+}
+
+// Register implements sd.Registrar.
+func (r *Registrar) Register() {
+	if err := r.register(); err != nil {
+		r.logger.Log("during", "register", "err", err)
+		return
+	}
+	r.logger.Log("action", "register")
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.quit == nil {
+		r.quit, r.done = make(chan struct{}), make(chan struct{})
+		go r.loop(r.quit, r.done)
+	}
+}
+
+// Deregister implements sd.Registrar.
+func (r *Registrar) Deregister() {
+	r.mtx.Lock()
+	if r.quit != nil {
+		close(r.quit)
+		<-r.done
+		r.quit, r.done = nil, nil
+	}
+	r.mtx.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	if err := r.client.Deregister(ctx, r.instance); err != nil {
+		r.logger.Log("during", "deregister", "err", err)
+	} else {
+		r.logger.Log("action", "deregister")
+	}
+}
+
+func (r *Registrar) register() error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	return r.client.Register(ctx, r.instance)
+}
+
+func (r *Registrar) loop(quit, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.heartbeat()
+		case <-quit:
+			return
+		}
+	}
+}
+
+func (r *Registrar) heartbeat() {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	err := r.client.Heartbeat(ctx, r.instance)
+	if err == ErrNotFound {
+		r.logger.Log("during", "heartbeat", "err", err, "action", "reregister")
+		err = r.register()
+	}
+	if err != nil {
+		r.logger.Log("during", "heartbeat", "err", err)
+	}
+}
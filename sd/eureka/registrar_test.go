@@ -0,0 +1,130 @@
+package eureka
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/barrett370/kit/v2/sd"
+)
+
+var _ sd.Registrar = (*Registrar)(nil) // API check
+
+// fakeServer is a Eureka server which forgets instances when expired is set.
+type fakeServer struct {
+	mtx        sync.Mutex
+	requests   []string
+	registered map[string]bool
+	expired    bool
+}
+
+func (s *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	switch r.Method {
+	case "POST":
+		var body struct {
+			Instance *Instance `json:"instance"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Instance.DataCenterInfo.Name != "MyOwn" {
+			http.Error(w, "bad instance", http.StatusBadRequest)
+			return
+		}
+		s.registered[body.Instance.ID()] = true
+		s.expired = false
+		w.WriteHeader(http.StatusNoContent)
+	case "PUT":
+		if s.expired {
+			http.NotFound(w, r)
+		}
+	case "DELETE":
+		s.registered = map[string]bool{}
+	}
+}
+
+func (s *fakeServer) count(request string) int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	var n int
+	for _, r := range s.requests {
+		if r == request {
+			n++
+		}
+	}
+	return n
+}
+
+func TestRegistrarHeartbeat(t *testing.T) {
+	server := &fakeServer{registered: map[string]bool{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	r := NewRegistrar(NewClient(ts.URL+"/eureka/", nil), newInstance("orders-1", "10.0.0.1", 80, StatusUp), log.NewNopLogger(), RegistrarHeartbeat(5*time.Millisecond))
+	r.Register()
+	waitFor(t, "heartbeats", func() bool { return server.count("PUT /eureka/apps/ORDERS/orders-1") >= 2 })
+
+	// An expired instance is registered again.
+	server.mtx.Lock()
+	server.expired = true
+	server.registered = map[string]bool{}
+	server.mtx.Unlock()
+	waitFor(t, "registration", func() bool { return server.count("POST /eureka/apps/ORDERS") == 2 })
+
+	r.Deregister()
+	heartbeats := server.count("PUT /eureka/apps/ORDERS/orders-1")
+	time.Sleep(20 * time.Millisecond)
+	if want, have := heartbeats, server.count("PUT /eureka/apps/ORDERS/orders-1"); want != have {
+		t.Errorf("heartbeats after Deregister: want %d, have %d", want, have)
+	}
+	if want, have := 1, server.count("DELETE /eureka/apps/ORDERS/orders-1"); want != have {
+		t.Errorf("deregister: want %d, have %d", want, have)
+	}
+}
+
+func TestRegistrarLeaseInfo(t *testing.T) {
+	i := newInstance("orders-1", "10.0.0.1", 80, StatusUp)
+	i.LeaseInfo = &LeaseInfo{RenewalIntervalInSecs: 10, DurationInSecs: 30}
+	r := NewRegistrar(&testClient{}, i, log.NewNopLogger())
+	if want, have := 10*time.Second, r.interval; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestClientDelta(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if want, have := "application/json", r.Header.Get("Accept"); want != have {
+			t.Errorf("Accept: want %q, have %q", want, have)
+		}
+		w.Write([]byte(`{"applications":{"versions__delta":3,"apps__hashcode":"UP_1_","application":{"name":"ORDERS","instance":{"instanceId":"a","actionType":"DELETED"}}}}`))
+	}))
+	defer ts.Close()
+
+	delta, err := NewClient(ts.URL, nil).Delta(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "3", delta.VersionsDelta; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if len(delta.Applications) != 1 || len(delta.Applications[0].Instances) != 1 || delta.Applications[0].Instances[0].ActionType != ActionDeleted {
+		t.Errorf("unexpected delta %+v", delta)
+	}
+}
+
+func waitFor(t *testing.T, what string, f func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !f() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,17 @@
+package sd
+
+import (
+	"io"
+
+	"github.com/barrett370/kit/v2/endpoint"
+)
+
+// Factory is a function that converts an instance string (e.g. host:port) to a
+// specific endpoint. Instances that provide multiple endpoints require multiple
+// factories. A factory also returns an io.Closer that's invoked when the
+// instance goes away and needs to be cleaned up. Factories may return nil
+// closers.
+//
+// Users are expected to provide their own factory functions that assume
+// specific transports, or can deduce transports by parsing the instance string.
+type Factory[I, O any] func(instance string) (endpoint.Endpoint[I, O], io.Closer, error)
@@ -0,0 +1,11 @@
+// Package file provides an Instancer implementation which reads instances
+// from a file, and reloads them when it changes, for environments without a
+// discovery system.
+//
+// The Instancer polls the file, every second by default, rather than watching
+// it with fsnotify. Polling costs a read per interval, and sees changes up to
+// an interval late, but it needs no extra dependency, and it sees changes
+// however they're made: file watches are lost when an editor or Kubernetes
+// replaces the file, or the symlink to it, rather than writing it in place.
+// Set a shorter interval with InstancerInterval if changes must be seen sooner.
+package file
@@ -0,0 +1,190 @@
+package file
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/barrett370/kit/v2/sd"
+	"github.com/barrett370/kit/v2/sd/internal/instance"
+)
+
+// DefaultInterval is the interval between reads of the file.
+const DefaultInterval = time.Second
+
+// InstancerOption sets an optional parameter for Instancers.
+type InstancerOption func(*Instancer)
+
+// InstancerInterval sets the interval between reads of the file. By default,
+// it's DefaultInterval.
+func InstancerInterval(d time.Duration) InstancerOption {
+	return func(s *Instancer) { s.interval = d }
+}
+
+// InstancerUnmarshal sets the func which decodes the file, e.g. the Unmarshal
+// func of a YAML package, for files in YAML. By default, it's json.Unmarshal.
+func InstancerUnmarshal(unmarshal func(data []byte, v interface{}) error) InstancerOption {
+	return func(s *Instancer) { s.unmarshal = unmarshal }
+}
+
+// Instancer yields the instances listed in a file, which is a list of
+// instances, as strings, or as objects with their metadata:
+//
+//	[
+//	  "10.0.0.1:8080",
+//	  {"instance": "10.0.0.2:8080", "metadata": {"zone": "eu-west-1a"}}
+//	]
+//
+// The file is read again every interval, and changes to it are published. It
+// polls, rather than watching the file, so it sees changes however they're
+// made, e.g. as Kubernetes updates mounted ConfigMaps, by swapping symlinks.
+// If the file can't be read, or decoded, an error is published, and the last
+// good instances are kept by Endpointers, as usual, until it's fixed.
+type Instancer struct {
+	cache     *instance.Cache
+	path      string
+	interval  time.Duration
+	unmarshal func(data []byte, v interface{}) error
+	logger    log.Logger
+	last      []byte // contents of the file
+	quit      chan struct{}
+	done      chan struct{}
+}
+
+// NewInstancer returns an Instancer of the instances in the file at path.
+func NewInstancer(path string, logger log.Logger, options ...InstancerOption) *Instancer {
+	s := &Instancer{
+		cache:     instance.NewCache(),
+		path:      path,
+		interval:  DefaultInterval,
+		unmarshal: json.Unmarshal,
+		logger:    log.With(logger, "path", path),
+		quit:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	for _, option := range options {
+		option(s)
+	}
+
+	if err := s.read(); err == nil {
+		s.logger.Log("instances", len(s.cache.State().Instances))
+	} else {
+		s.logger.Log("err", err)
+	}
+	go s.loop()
+	return s
+}
+
+func (s *Instancer) loop() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.read(); err != nil {
+				s.logger.Log("err", err)
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// read reads the file, and updates the cache if it has changed.
+func (s *Instancer) read() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		s.last = nil
+		s.cache.Update(sd.Event{Err: err})
+		return err
+	}
+	if s.last != nil && bytes.Equal(data, s.last) {
+		return nil
+	}
+	s.last = data // so a bad file is only reported once, until it's changed
+	event, err := s.decode(data)
+	if err != nil {
+		s.cache.Update(sd.Event{Err: err})
+		return err
+	}
+	s.cache.Update(event)
+	return nil
+}
+
+func (s *Instancer) decode(data []byte) (sd.Event, error) {
+	var entries []interface{}
+	if err := s.unmarshal(data, &entries); err != nil {
+		return sd.Event{}, fmt.Errorf("decoding %s: %w", s.path, err)
+	}
+	event := sd.Event{
+		Instances: make([]string, 0, len(entries)),
+		Metadata:  map[string]sd.Metadata{},
+	}
+	for i, entry := range entries {
+		var (
+			instance string
+			metadata sd.Metadata
+		)
+		switch entry := entry.(type) {
+		case string:
+			instance = entry
+		default:
+			fields, ok := stringMap(entry)
+			if !ok {
+				return sd.Event{}, fmt.Errorf("%s: entry %d: want a string or an object, have %T", s.path, i, entry)
+			}
+			instance, _ = fields["instance"].(string)
+			if m, ok := stringMap(fields["metadata"]); ok {
+				metadata = make(sd.Metadata, len(m))
+				for k, v := range m {
+					metadata[k] = fmt.Sprint(v)
+				}
+			}
+		}
+		if instance == "" {
+			return sd.Event{}, fmt.Errorf("%s: entry %d: no instance", s.path, i)
+		}
+		event.Instances = append(event.Instances, instance)
+		if len(metadata) > 0 {
+			event.Metadata[instance] = metadata
+		}
+	}
+	return event, nil
+}
+
+// stringMap returns v as a map with string keys, as decoded from an object by
+// encoding/json, or by YAML packages, some of which have interface{} keys.
+func stringMap(v interface{}) (map[string]interface{}, bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return v, true
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, v := range v {
+			m[fmt.Sprint(k)] = v
+		}
+		return m, true
+	}
+	return nil, false
+}
+
+// Stop terminates the Instancer.
+func (s *Instancer) Stop() {
+	close(s.quit)
+	<-s.done
+}
+
+// Register implements Instancer.
+func (s *Instancer) Register(ch chan<- sd.Event) {
+	s.cache.Register(ch)
+}
+
+// Deregister implements Instancer.
+func (s *Instancer) Deregister(ch chan<- sd.Event) {
+	s.cache.Deregister(ch)
+}
@@ -0,0 +1,87 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/barrett370/kit/v2/sd"
+)
+
+var _ sd.Instancer = (*Instancer)(nil) // API check
+
+func TestInstancer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "instances.json")
+	write := func(s string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(s), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`["10.0.0.2:80", {"instance": "10.0.0.1:80", "metadata": {"zone": "a", "weight": 3}}]`)
+
+	s := NewInstancer(path, log.NewNopLogger(), InstancerInterval(5*time.Millisecond))
+	defer s.Stop()
+	events := make(chan sd.Event, 1)
+	s.Register(events)
+	defer s.Deregister(events)
+
+	next := func() sd.Event {
+		t.Helper()
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for event")
+			return sd.Event{}
+		}
+	}
+
+	e := next()
+	if want, have := []string{"10.0.0.1:80", "10.0.0.2:80"}, e.Instances; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := (sd.Metadata{"zone": "a", "weight": "3"}), e.MetadataOf("10.0.0.1:80"); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// A bad file is an error, and fixing it recovers.
+	write(`["10.0.0.2:80",`)
+	if e := next(); e.Err == nil || !strings.Contains(e.Err.Error(), "decoding") {
+		t.Errorf("want decoding error, have %v", e.Err)
+	}
+	write(`["10.0.0.3:80"]`)
+	if want, have := []string{"10.0.0.3:80"}, next().Instances; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	os.Remove(path)
+	if e := next(); !os.IsNotExist(e.Err) {
+		t.Errorf("want not exist error, have %v", e.Err)
+	}
+}
+
+func TestInstancerUnmarshal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "instances.txt")
+	if err := os.WriteFile(path, []byte("10.0.0.1:80\n10.0.0.2:80\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	lines := func(data []byte, v interface{}) error {
+		var entries []interface{}
+		for _, line := range strings.Fields(string(data)) {
+			entries = append(entries, line)
+		}
+		*v.(*[]interface{}) = entries
+		return nil
+	}
+	s := NewInstancer(path, log.NewNopLogger(), InstancerUnmarshal(lines))
+	defer s.Stop()
+	if want, have := []string{"10.0.0.1:80", "10.0.0.2:80"}, s.cache.State().Instances; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
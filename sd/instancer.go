@@ -0,0 +1,62 @@
+package sd
+
+// Event represents a push notification generated from the underlying service
+// discovery implementation. It contains either a full set of available
+// resource instances, or an error indicating some issue with obtaining
+// information from the discovery layer.
+//
+// Example:
+//
+//	{Instances: {"10.0.0.1:8080", "10.0.0.2:8080"}, Err: nil}
+//
+// Instancers which know more about instances than their addresses, such as
+// their weights, zones, or versions, also provide it as Metadata. Observers
+// which only need addresses may ignore it.
+//
+// Events are pushed to subscribers registered with an Instancer.
+type Event struct {
+	Instances []string
+	Err       error
+
+	// Metadata optionally holds the metadata of instances, by instance.
+	// Instances without metadata may be missing from it.
+	Metadata map[string]Metadata
+}
+
+// MetadataOf returns the metadata of the instance, or nil if it has none.
+// Metadata accessors handle nil, so it's safe to call them on the result.
+func (e Event) MetadataOf(instance string) Metadata {
+	return e.Metadata[instance]
+}
+
+// Instancer listens to a service discovery system and notifies registered
+// observers of changes in the resource instances. Every event sent to the
+// channels contains a complete set of instances known to the Instancer.
+// That complete set is sent immediately upon registering the channel, and on
+// any future updates from discovery system.
+type Instancer interface {
+	Register(chan<- Event)
+	Deregister(chan<- Event)
+	Stop()
+}
+
+// FixedInstancer yields a fixed set of instances, for environments without a
+// discovery system, and for tests.
+type FixedInstancer []string
+
+// NewFixedInstancer returns a FixedInstancer of the instances.
+func NewFixedInstancer(instances []string) FixedInstancer {
+	return append(FixedInstancer(nil), instances...)
+}
+
+// Register implements Instancer. It sends the instances at once, so the
+// channel must be buffered, or be received from concurrently.
+func (d FixedInstancer) Register(ch chan<- Event) {
+	ch <- Event{Instances: append([]string(nil), d...)}
+}
+
+// Deregister implements Instancer.
+func (d FixedInstancer) Deregister(ch chan<- Event) {}
+
+// Stop implements Instancer.
+func (d FixedInstancer) Stop() {}
@@ -0,0 +1,71 @@
+package instance
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/barrett370/kit/v2/sd"
+)
+
+var _ sd.Instancer = (*Cache)(nil) // API check
+
+func TestCache(t *testing.T) {
+	c := NewCache()
+	if want, have := 0, len(c.State().Instances); want != have {
+		t.Fatalf("want %d instances, have %d", want, have)
+	}
+
+	ch := make(chan sd.Event, 3)
+	c.Register(ch)
+	if want, have := 0, len((<-ch).Instances); want != have {
+		t.Errorf("initial state: want %d instances, have %d", want, have)
+	}
+
+	c.Update(sd.Event{Instances: []string{"y", "x"}})
+	if want, have := []string{"x", "y"}, (<-ch).Instances; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	c.Update(sd.Event{Instances: []string{"x", "y"}}) // no change, no broadcast
+	errBoom := errors.New("boom")
+	c.Update(sd.Event{Err: errBoom})
+	if want, have := errBoom, (<-ch).Err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	c.Deregister(ch)
+	c.Update(sd.Event{Instances: []string{"z"}})
+	if want, have := 0, len(ch); want != have {
+		t.Errorf("after Deregister: want %d events, have %d", want, have)
+	}
+	if want, have := []string{"z"}, c.State().Instances; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	reg := registry{}
+	c1 := make(chan sd.Event, 1)
+	c2 := make(chan sd.Event, 1)
+	reg.register(c1)
+	reg.register(c2)
+
+	// validate that both channels receive the update, as separate copies
+	reg.broadcast(sd.Event{Instances: []string{"x", "y"}})
+	e1, e2 := <-c1, <-c2
+	if want, have := []string{"x", "y"}, e1.Instances; !reflect.DeepEqual(want, have) {
+		t.Fatalf("want %v, have %v", want, have)
+	}
+	e1.Instances[0] = "modified"
+	if want, have := "x", e2.Instances[0]; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	reg.deregister(c1)
+	reg.deregister(c2)
+	close(c1)
+	close(c2)
+	// if deregister didn't work, broadcast would panic on closed channels
+	reg.broadcast(sd.Event{Instances: []string{"x", "y"}})
+}
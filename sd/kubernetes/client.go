@@ -0,0 +1,232 @@
+package kubernetes
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Paths of the service account credentials mounted into pods.
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount/"
+	tokenFile         = serviceAccountDir + "token"
+	caFile            = serviceAccountDir + "ca.crt"
+	namespaceFile     = serviceAccountDir + "namespace"
+)
+
+// ErrGone is returned by Watch when the resource version to watch from is too
+// old, and the EndpointSlices must be listed again.
+var ErrGone = errors.New("resource version too old")
+
+// EndpointSlice is the subset of a discovery.k8s.io/v1 EndpointSlice used to
+// discover instances.
+type EndpointSlice struct {
+	Metadata struct {
+		Name            string `json:"name"`
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	AddressType string         `json:"addressType"`
+	Endpoints   []Endpoint     `json:"endpoints"`
+	Ports       []EndpointPort `json:"ports"`
+}
+
+// Endpoint is an endpoint of an EndpointSlice.
+type Endpoint struct {
+	Addresses  []string `json:"addresses"`
+	Conditions struct {
+		Ready *bool `json:"ready"`
+	} `json:"conditions"`
+	Zone *string `json:"zone"`
+}
+
+// EndpointPort is a port of an EndpointSlice.
+type EndpointPort struct {
+	Name *string `json:"name"`
+	Port *int32  `json:"port"`
+}
+
+// WatchEvent is a change to an EndpointSlice. Type is one of "ADDED",
+// "MODIFIED" or "DELETED".
+type WatchEvent struct {
+	Type   string
+	Object EndpointSlice
+}
+
+// Client is a wrapper around the Kubernetes API, providing the calls
+// required by the Instancer.
+type Client interface {
+	// List returns the EndpointSlices of the service, and the resource
+	// version from which to watch for changes to them.
+	List(ctx context.Context, namespace, service string) ([]EndpointSlice, string, error)
+
+	// Watch sends changes to the EndpointSlices of the service since the
+	// resource version to events, until the context is canceled or the API
+	// server ends the watch. It returns ErrGone if the resource version is
+	// too old.
+	Watch(ctx context.Context, namespace, service, resourceVersion string, events chan<- WatchEvent) error
+}
+
+type client struct {
+	server    string
+	http      *http.Client
+	tokenFile string
+}
+
+// NewClient returns a Client for the API server at the URL, e.g.
+// https://kubernetes.default.svc. Requests are made with the HTTP client,
+// which must be configured to trust the API server, and authenticated with
+// the bearer token read from tokenFile, which is re-read for each request, as
+// Kubernetes rotates it. An empty tokenFile makes unauthenticated requests.
+func NewClient(server string, httpClient *http.Client, tokenFile string) Client {
+	return &client{
+		server:    strings.TrimSuffix(server, "/"),
+		http:      httpClient,
+		tokenFile: tokenFile,
+	}
+}
+
+// NewInClusterClient returns a Client for the API server of the cluster the
+// program runs in, authenticated with the pod's service account, which needs
+// permission to list and watch endpointslices.
+func NewInClusterClient() (Client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running in a Kubernetes cluster")
+	}
+	ca, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates in %s", caFile)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	return NewClient("https://"+net.JoinHostPort(host, port), &http.Client{Transport: transport}, tokenFile), nil
+}
+
+// InClusterNamespace returns the namespace of the pod the program runs in.
+func InClusterNamespace() (string, error) {
+	b, err := ioutil.ReadFile(namespaceFile)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+type endpointSliceList struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Items []EndpointSlice `json:"items"`
+}
+
+type watchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+type status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (c *client) List(ctx context.Context, namespace, service string) ([]EndpointSlice, string, error) {
+	resp, err := c.get(ctx, namespace, service, url.Values{})
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	var list endpointSliceList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, "", err
+	}
+	return list.Items, list.Metadata.ResourceVersion, nil
+}
+
+func (c *client) Watch(ctx context.Context, namespace, service, resourceVersion string, events chan<- WatchEvent) error {
+	resp, err := c.get(ctx, namespace, service, url.Values{
+		"watch":               {"true"},
+		"resourceVersion":     {resourceVersion},
+		"allowWatchBookmarks": {"true"},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(resp.Body))
+	for {
+		var e watchEvent
+		if err := dec.Decode(&e); err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return nil // the watch timed out, or was canceled
+			}
+			return err
+		}
+		switch e.Type {
+		case "ADDED", "MODIFIED", "DELETED":
+			var slice EndpointSlice
+			if err := json.Unmarshal(e.Object, &slice); err != nil {
+				return err
+			}
+			select {
+			case events <- WatchEvent{Type: e.Type, Object: slice}:
+			case <-ctx.Done():
+				return nil
+			}
+		case "ERROR":
+			var s status
+			json.Unmarshal(e.Object, &s)
+			if s.Code == http.StatusGone {
+				return ErrGone
+			}
+			return fmt.Errorf("watch failed: %s", s.Message)
+		}
+		// BOOKMARK events only advance the resource version, which the
+		// Instancer doesn't track between watches, as it relists.
+	}
+}
+
+func (c *client) get(ctx context.Context, namespace, service string, query url.Values) (*http.Response, error) {
+	query.Set("labelSelector", "kubernetes.io/service-name="+service)
+	u := fmt.Sprintf("%s/apis/discovery.k8s.io/v1/namespaces/%s/endpointslices?%s", c.server, url.PathEscape(namespace), query.Encode())
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.tokenFile != "" {
+		token, err := ioutil.ReadFile(c.tokenFile)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusGone {
+			return nil, ErrGone
+		}
+		var s status
+		json.NewDecoder(resp.Body).Decode(&s)
+		return nil, fmt.Errorf("kubernetes API returned %s: %s", resp.Status, s.Message)
+	}
+	return resp, nil
+}
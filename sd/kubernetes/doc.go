@@ -0,0 +1,6 @@
+// Package kubernetes provides an Instancer which discovers the instances of a
+// Kubernetes service from its EndpointSlices, via a lightweight list and
+// watch against the Kubernetes API, rather than client-go. In a pod, create
+// the client with NewInClusterClient; its service account needs permission
+// to list and watch endpointslices in the service's namespace.
+package kubernetes
@@ -0,0 +1,163 @@
+package kubernetes
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/barrett370/kit/v2/sd"
+	"github.com/barrett370/kit/v2/sd/internal/instance"
+	"github.com/barrett370/kit/v2/util/conn"
+)
+
+// Instancer yields instances for a port of a Kubernetes service, from the
+// service's EndpointSlices. It lists them, and then watches them for
+// changes, relisting after any error.
+type Instancer struct {
+	cache     *instance.Cache
+	client    Client
+	logger    log.Logger
+	namespace string
+	service   string
+	port      string
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// NewInstancer returns a Kubernetes Instancer for the named port of the
+// service in the namespace. If the service has a single unnamed port, pass
+// an empty port name. Only the addresses of ready endpoints are yielded, with
+// their zones as metadata.
+func NewInstancer(client Client, logger log.Logger, namespace, service, port string) *Instancer {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Instancer{
+		cache:     instance.NewCache(),
+		client:    client,
+		logger:    log.With(logger, "namespace", namespace, "service", service, "port", port),
+		namespace: namespace,
+		service:   service,
+		port:      port,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	go s.loop(ctx)
+	return s
+}
+
+func (s *Instancer) loop(ctx context.Context) {
+	defer close(s.done)
+	backoff := time.Second
+	for {
+		err := s.listAndWatch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			backoff = time.Second // the watch ended normally; relist at once
+			continue
+		}
+		s.logger.Log("err", err)
+		if err != ErrGone {
+			s.cache.Update(sd.Event{Err: err})
+			backoff = conn.Exponential(backoff)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (s *Instancer) listAndWatch(ctx context.Context) error {
+	slices, resourceVersion, err := s.client.List(ctx, s.namespace, s.service)
+	if err != nil {
+		return err
+	}
+	state := map[string]EndpointSlice{}
+	for _, slice := range slices {
+		state[slice.Metadata.Name] = slice
+	}
+	s.cache.Update(s.event(state))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events := make(chan WatchEvent)
+	errc := make(chan error, 1)
+	go func() { errc <- s.client.Watch(ctx, s.namespace, s.service, resourceVersion, events) }()
+	for {
+		select {
+		case e := <-events:
+			if e.Type == "DELETED" {
+				delete(state, e.Object.Metadata.Name)
+			} else {
+				state[e.Object.Metadata.Name] = e.Object
+			}
+			s.cache.Update(s.event(state))
+		case err := <-errc:
+			return err
+		}
+	}
+}
+
+// event returns the host:port of each ready endpoint in the slices, with
+// their zones as metadata.
+func (s *Instancer) event(state map[string]EndpointSlice) sd.Event {
+	instances := []string{}
+	metadata := map[string]sd.Metadata{}
+	seen := map[string]bool{}
+	for _, slice := range state {
+		port, ok := s.portOf(slice)
+		if !ok {
+			continue
+		}
+		for _, e := range slice.Endpoints {
+			if e.Conditions.Ready != nil && !*e.Conditions.Ready {
+				continue
+			}
+			for _, addr := range e.Addresses {
+				instance := net.JoinHostPort(addr, port)
+				if !seen[instance] {
+					seen[instance] = true
+					instances = append(instances, instance)
+					if e.Zone != nil && *e.Zone != "" {
+						metadata[instance] = sd.Metadata{sd.MetadataZone: *e.Zone}
+					}
+				}
+			}
+		}
+	}
+	return sd.Event{Instances: instances, Metadata: metadata}
+}
+
+func (s *Instancer) portOf(slice EndpointSlice) (string, bool) {
+	for _, p := range slice.Ports {
+		name := ""
+		if p.Name != nil {
+			name = *p.Name
+		}
+		if name == s.port && p.Port != nil {
+			return strconv.Itoa(int(*p.Port)), true
+		}
+	}
+	return "", false
+}
+
+// Stop terminates the Instancer.
+func (s *Instancer) Stop() {
+	s.cancel()
+	<-s.done
+}
+
+// Register implements Instancer.
+func (s *Instancer) Register(ch chan<- sd.Event) {
+	s.cache.Register(ch)
+}
+
+// Deregister implements Instancer.
+func (s *Instancer) Deregister(ch chan<- sd.Event) {
+	s.cache.Deregister(ch)
+}
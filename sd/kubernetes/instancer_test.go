@@ -0,0 +1,111 @@
+package kubernetes
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/barrett370/kit/v2/sd"
+)
+
+var _ sd.Instancer = (*Instancer)(nil) // API check
+var _ sd.Registrar = (*Registrar)(nil) // API check
+
+const (
+	slice1 = `{"metadata":{"name":"orders-1"},"addressType":"IPv4",` +
+		`"endpoints":[{"addresses":["10.0.0.1"],"conditions":{"ready":true}},{"addresses":["10.0.0.2"],"conditions":{"ready":false}}],` +
+		`"ports":[{"name":"http","port":8080},{"name":"grpc","port":9090}]}`
+	slice2 = `{"metadata":{"name":"orders-2"},"addressType":"IPv6",` +
+		`"endpoints":[{"addresses":["fd00::1"]}],` +
+		`"ports":[{"name":"http","port":8080}]}`
+)
+
+func TestInstancer(t *testing.T) {
+	watch := make(chan string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if want, have := "/apis/discovery.k8s.io/v1/namespaces/shop/endpointslices", r.URL.Path; want != have {
+			t.Errorf("want %s, have %s", want, have)
+		}
+		if want, have := "kubernetes.io/service-name=orders", r.URL.Query().Get("labelSelector"); want != have {
+			t.Errorf("want %s, have %s", want, have)
+		}
+		if r.URL.Query().Get("watch") != "true" {
+			fmt.Fprintf(w, `{"metadata":{"resourceVersion":"7"},"items":[%s]}`, slice1)
+			return
+		}
+		if want, have := "7", r.URL.Query().Get("resourceVersion"); want != have {
+			t.Errorf("want resource version %s, have %s", want, have)
+		}
+		for {
+			select {
+			case e := <-watch:
+				fmt.Fprintln(w, e)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	s := NewInstancer(NewClient(server.URL, server.Client(), ""), log.NewNopLogger(), "shop", "orders", "http")
+	defer s.Stop()
+	events := make(chan sd.Event, 10)
+	s.Register(events)
+	defer s.Deregister(events)
+
+	next := func(want ...string) {
+		t.Helper()
+		for {
+			select {
+			case e := <-events:
+				if e.Err != nil {
+					t.Fatal(e.Err)
+				}
+				have := append([]string(nil), e.Instances...)
+				sort.Strings(have)
+				if len(have) == 0 && len(want) != 0 {
+					continue // the initial state, before the list
+				}
+				if !reflect.DeepEqual(want, have) {
+					t.Fatalf("want %v, have %v", want, have)
+				}
+				return
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timeout waiting for %v", want)
+			}
+		}
+	}
+
+	next("10.0.0.1:8080")
+	watch <- fmt.Sprintf(`{"type":"ADDED","object":%s}`, slice2)
+	next("10.0.0.1:8080", "[fd00::1]:8080")
+	watch <- fmt.Sprintf(`{"type":"DELETED","object":%s}`, slice1)
+	next("[fd00::1]:8080")
+}
+
+func TestInstancerPortName(t *testing.T) {
+	s := &Instancer{port: "grpc"}
+	var slice EndpointSlice
+	name, port := "grpc", int32(9090)
+	slice.Ports = []EndpointPort{{Name: &name, Port: &port}}
+	zone := "eu-west-1a"
+	slice.Endpoints = []Endpoint{{Addresses: []string{"10.0.0.1"}, Zone: &zone}}
+	e := s.event(map[string]EndpointSlice{"a": slice})
+	if want, have := []string{"10.0.0.1:9090"}, e.Instances; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := zone, e.MetadataOf("10.0.0.1:9090").Zone(); want != have {
+		t.Errorf("want zone %q, have %q", want, have)
+	}
+	s.port = ""
+	if want, have := 0, len(s.event(map[string]EndpointSlice{"a": slice}).Instances); want != have {
+		t.Errorf("unnamed port: want %d instances, have %d", want, have)
+	}
+}
@@ -0,0 +1,18 @@
+package kubernetes
+
+// Registrar is a no-op sd.Registrar. Kubernetes registers the pods of a
+// service in its EndpointSlices itself, once their readiness probes pass, so
+// there's nothing for a service to do. It exists so that code written against
+// sd.Registrar runs unchanged on Kubernetes.
+type Registrar struct{}
+
+// NewRegistrar returns a no-op Registrar.
+func NewRegistrar() *Registrar {
+	return &Registrar{}
+}
+
+// Register implements sd.Registrar. It does nothing.
+func (*Registrar) Register() {}
+
+// Deregister implements sd.Registrar. It does nothing.
+func (*Registrar) Deregister() {}
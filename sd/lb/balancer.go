@@ -0,0 +1,72 @@
+package lb
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/sd"
+)
+
+// Balancer yields endpoints according to some heuristic.
+type Balancer[I, O any] interface {
+	Endpoint() (endpoint.Endpoint[I, O], error)
+}
+
+// ErrNoEndpoints is returned when no qualifying endpoints are available.
+var ErrNoEndpoints = errors.New("no endpoints available")
+
+// instanceEndpoints returns the endpoints of s with their instances, if s is
+// an sd.InstanceEndpointer, or else with no instances or metadata.
+func instanceEndpoints[I, O any](s sd.Endpointer[I, O]) ([]sd.InstanceEndpoint[I, O], error) {
+	if s, ok := s.(sd.InstanceEndpointer[I, O]); ok {
+		return s.InstanceEndpoints()
+	}
+	endpoints, err := s.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+	instances := make([]sd.InstanceEndpoint[I, O], len(endpoints))
+	for i, e := range endpoints {
+		instances[i] = sd.InstanceEndpoint[I, O]{Endpoint: e}
+	}
+	return instances, nil
+}
+
+// key identifies the i'th endpoint for balancers that keep state per
+// endpoint: by its instance, if known, or else by its position.
+func key[I, O any](e sd.InstanceEndpoint[I, O], i int) string {
+	if e.Instance != "" {
+		return e.Instance
+	}
+	return "#" + strconv.Itoa(i)
+}
+
+// WeightFunc returns the relative weight of an instance, as a positive
+// integer, for weighted load balancing.
+type WeightFunc func(instance string) int
+
+// WeightOption sets an optional parameter for weighted balancers.
+type WeightOption func(*weightOptions)
+
+type weightOptions struct {
+	weight WeightFunc
+}
+
+// Weights sets the func which weights instances. By default, instances are
+// weighted by their metadata, with sd.Metadata.Weight.
+func Weights(f WeightFunc) WeightOption {
+	return func(o *weightOptions) { o.weight = f }
+}
+
+// weight returns the weight of the endpoint, by the func, if any, or else by
+// its metadata. Weights below 1 are taken as 1.
+func weight[I, O any](f WeightFunc, e sd.InstanceEndpoint[I, O]) int {
+	if f == nil {
+		return e.Metadata.Weight()
+	}
+	if w := f(e.Instance); w > 0 {
+		return w
+	}
+	return 1
+}
@@ -0,0 +1,87 @@
+package lb
+
+import (
+	"sync"
+	"time"
+)
+
+const budgetBuckets = 10
+
+// Budget limits retries to a ratio of the requests over a sliding window, plus
+// a minimum, so retries can't amplify an outage: once a service starts to
+// fail, at most that ratio of extra load is sent to it. A Budget is safe for
+// concurrent use, and may be shared by many retrying endpoints.
+type Budget struct {
+	ratio      float64
+	minRetries int
+	bucket     time.Duration
+	now        func() time.Time
+
+	mtx      sync.Mutex
+	start    time.Time // of the current bucket
+	current  int
+	requests [budgetBuckets]int
+	retries  [budgetBuckets]int
+}
+
+// NewBudget returns a Budget which allows retries of up to ratio of the
+// requests in the window, e.g. 0.2 for 20%, plus minRetries in the window,
+// which allows retries when there are few requests.
+func NewBudget(ratio float64, minRetries int, window time.Duration) *Budget {
+	bucket := window / budgetBuckets
+	if bucket <= 0 {
+		bucket = 1
+	}
+	return &Budget{
+		ratio:      ratio,
+		minRetries: minRetries,
+		bucket:     bucket,
+		now:        time.Now,
+	}
+}
+
+// request records a request.
+func (b *Budget) request() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.advance()
+	b.requests[b.current]++
+}
+
+// retry records a retry and returns true, if it's within the budget.
+func (b *Budget) retry() bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.advance()
+	var requests, retries int
+	for i := range b.requests {
+		requests += b.requests[i]
+		retries += b.retries[i]
+	}
+	if float64(retries+1) > b.ratio*float64(requests)+float64(b.minRetries) {
+		return false
+	}
+	b.retries[b.current]++
+	return true
+}
+
+// advance moves the window to now. The caller must hold the mutex.
+func (b *Budget) advance() {
+	now := b.now()
+	if b.start.IsZero() {
+		b.start = now
+		return
+	}
+	n := int(now.Sub(b.start) / b.bucket)
+	if n <= 0 {
+		return
+	}
+	if n > budgetBuckets {
+		n = budgetBuckets
+	}
+	for i := 0; i < n; i++ {
+		b.current = (b.current + 1) % budgetBuckets
+		b.requests[b.current], b.retries[b.current] = 0, 0
+	}
+	b.start = b.start.Add(time.Duration(int(now.Sub(b.start)/b.bucket)) * b.bucket)
+}
@@ -0,0 +1,209 @@
+package lb
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/sd"
+)
+
+// DefaultLoadFactor bounds the load of each instance in consistent hashing to
+// 25% above the average.
+const DefaultLoadFactor = 1.25
+
+// ConsistentHashOption sets an optional parameter for consistent hashing.
+type ConsistentHashOption func(*consistentHashOptions)
+
+type consistentHashOptions struct {
+	loadFactor float64
+	weight     WeightFunc
+}
+
+// ConsistentHashLoadFactor bounds the requests in flight to each instance to
+// the factor times the average, rounded up. Requests whose instance is at the
+// bound go to the next instance on the ring that isn't, so a hot key can't
+// overload an instance, at the cost of some locality. A factor of 0 disables
+// the bound. By default, it's DefaultLoadFactor.
+func ConsistentHashLoadFactor(c float64) ConsistentHashOption {
+	return func(o *consistentHashOptions) { o.loadFactor = c }
+}
+
+// ConsistentHashWeights sets the func which weights instances. By default,
+// instances are weighted by their metadata, with sd.Metadata.Weight.
+func ConsistentHashWeights(f WeightFunc) ConsistentHashOption {
+	return func(o *consistentHashOptions) { o.weight = f }
+}
+
+// NewConsistentHash returns an endpoint which routes each request to an
+// instance chosen by the key keyFn derives from it, e.g. a user or cache key.
+// Requests with the same key go to the same instance, and, as instances come
+// and go, only the keys of those instances move, which preserves the locality
+// of caches in the instances.
+//
+// Each instance is placed on a hash ring at replicas points per unit of its
+// weight, so more replicas spread keys more evenly. Instances, and their
+// metadata, are only known if the Endpointer is an sd.InstanceEndpointer;
+// otherwise, endpoints are placed by their position, with weight 1.
+//
+// Unlike a Balancer, the choice depends on the request, so the balancing is
+// done by the returned endpoint.
+func NewConsistentHash[I, O any](s sd.Endpointer[I, O], keyFn func(ctx context.Context, request I) string, replicas int, options ...ConsistentHashOption) endpoint.Endpoint[I, O] {
+	opts := consistentHashOptions{loadFactor: DefaultLoadFactor}
+	for _, option := range options {
+		option(&opts)
+	}
+	if replicas < 1 {
+		replicas = 1
+	}
+	ch := &consistentHash[I, O]{
+		s:        s,
+		replicas: replicas,
+		factor:   opts.loadFactor,
+		weight:   opts.weight,
+		inflight: map[string]*int64{},
+	}
+	return func(ctx context.Context, request I) (O, error) {
+		next, done, err := ch.pick(keyFn(ctx, request))
+		if err != nil {
+			var zero O
+			return zero, err
+		}
+		defer done()
+		return next(ctx, request)
+	}
+}
+
+type consistentHash[I, O any] struct {
+	s        sd.Endpointer[I, O]
+	replicas int
+	factor   float64
+	weight   WeightFunc
+
+	mtx       sync.Mutex
+	endpoints []sd.InstanceEndpoint[I, O] // of the ring
+	keys      []string                    // of the endpoints
+	weights   []int                       // of the endpoints
+	ring      []point                     // sorted by hash
+	inflight  map[string]*int64           // by key
+	total     int64                       // in flight
+}
+
+type point struct {
+	hash uint64
+	i    int // of the endpoint
+}
+
+// pick returns the endpoint for the key, and a func to call when the request
+// to it is done.
+func (ch *consistentHash[I, O]) pick(k string) (endpoint.Endpoint[I, O], func(), error) {
+	endpoints, err := instanceEndpoints(ch.s)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(endpoints) <= 0 {
+		return nil, nil, ErrNoEndpoints
+	}
+
+	ch.mtx.Lock()
+	defer ch.mtx.Unlock()
+	ch.update(endpoints)
+
+	h := hash(k)
+	start := sort.Search(len(ch.ring), func(i int) bool { return ch.ring[i].hash >= h })
+	capacity := int64(math.MaxInt64)
+	if ch.factor > 0 {
+		capacity = int64(math.Ceil(ch.factor * float64(ch.total+1) / float64(len(ch.endpoints))))
+	}
+	i := ch.ring[start%len(ch.ring)].i
+	for n := 0; n < len(ch.ring); n++ {
+		j := ch.ring[(start+n)%len(ch.ring)].i
+		if *ch.inflight[ch.keys[j]] < capacity {
+			i = j
+			break
+		}
+	}
+
+	inflight := ch.inflight[ch.keys[i]]
+	*inflight++
+	ch.total++
+	done := func() {
+		ch.mtx.Lock()
+		defer ch.mtx.Unlock()
+		*inflight--
+		ch.total--
+	}
+	return ch.endpoints[i].Endpoint, done, nil
+}
+
+// update rebuilds the ring if the endpoints have changed. The caller must
+// hold the mutex.
+func (ch *consistentHash[I, O]) update(endpoints []sd.InstanceEndpoint[I, O]) {
+	keys := make([]string, len(endpoints))
+	weights := make([]int, len(endpoints))
+	for i, e := range endpoints {
+		keys[i] = key(e, i)
+		weights[i] = weight(ch.weight, e)
+	}
+	if equal(keys, ch.keys) && sameWeights(weights, ch.weights) {
+		ch.endpoints = endpoints // for the latest endpoint funcs
+		return
+	}
+
+	ring := ch.ring[:0]
+	inflight := make(map[string]*int64, len(keys))
+	for i := range endpoints {
+		for r := 0; r < ch.replicas*weights[i]; r++ {
+			ring = append(ring, point{hash: hash(keys[i] + "#" + strconv.Itoa(r)), i: i})
+		}
+		if c, ok := ch.inflight[keys[i]]; ok {
+			inflight[keys[i]] = c
+		} else {
+			inflight[keys[i]] = new(int64)
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+	ch.endpoints, ch.keys, ch.weights, ch.ring, ch.inflight = endpoints, keys, weights, ring, inflight
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sameWeights(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// hash is FNV-1a, with the finalizer of MurmurHash3 to spread similar keys,
+// such as the replicas of an instance, around the ring.
+func hash(s string) uint64 {
+	f := fnv.New64a()
+	f.Write([]byte(s))
+	h := f.Sum64()
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
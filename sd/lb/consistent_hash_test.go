@@ -0,0 +1,126 @@
+package lb
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/barrett370/kit/v2/sd"
+)
+
+func keyOf(_ context.Context, request string) string { return request }
+
+func instances(names ...string) sd.FixedInstanceEndpointer[string, string] {
+	endpoints := make(sd.FixedInstanceEndpointer[string, string], len(names))
+	for i, name := range names {
+		name := name
+		endpoints[i] = sd.InstanceEndpoint[string, string]{
+			Instance: name,
+			Endpoint: func(context.Context, string) (string, error) { return name, nil },
+		}
+	}
+	return endpoints
+}
+
+func TestConsistentHashStable(t *testing.T) {
+	var (
+		before = NewConsistentHash[string, string](instances("a", "b", "c", "d"), keyOf, 100)
+		after  = NewConsistentHash[string, string](instances("a", "b", "c"), keyOf, 100)
+		counts = map[string]int{}
+		moved  int
+	)
+	for i := 0; i < 1000; i++ {
+		k := fmt.Sprintf("key-%d", i)
+		x, _ := before(context.Background(), k)
+		y, _ := after(context.Background(), k)
+		if z, _ := before(context.Background(), k); x != z {
+			t.Fatalf("%s: want %s, have %s", k, x, z)
+		}
+		counts[x]++
+		if x != y {
+			moved++
+			if x != "d" {
+				t.Errorf("%s moved from %s to %s, but only d's keys should move", k, x, y)
+			}
+		}
+	}
+	if want, have := counts["d"], moved; want != have {
+		t.Errorf("moved: want %d, have %d", want, have)
+	}
+	for name, n := range counts {
+		if n < 150 || n > 350 { // 250 is even
+			t.Errorf("%s: %d keys, which is uneven", name, n)
+		}
+	}
+}
+
+func TestConsistentHashBoundedLoad(t *testing.T) {
+	var (
+		block     = make(chan struct{})
+		mtx       sync.Mutex
+		seen      = map[string]int{}
+		endpoints = instances("a", "b", "c")
+	)
+	for i := range endpoints {
+		name, next := endpoints[i].Instance, endpoints[i].Endpoint
+		endpoints[i].Endpoint = func(ctx context.Context, request string) (string, error) {
+			mtx.Lock()
+			seen[name]++
+			mtx.Unlock()
+			<-block
+			return next(ctx, request)
+		}
+	}
+	e := NewConsistentHash[string, string](endpoints, keyOf, 100, ConsistentHashLoadFactor(1))
+
+	// Every request has the same key, but with a load factor of 1, each
+	// instance takes no more than its share of the requests in flight.
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() { defer wg.Done(); e(context.Background(), "hot") }()
+		for {
+			mtx.Lock()
+			n := seen["a"] + seen["b"] + seen["c"]
+			mtx.Unlock()
+			if n == i+1 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	close(block)
+	wg.Wait()
+	for _, name := range []string{"a", "b", "c"} {
+		if want, have := 1, seen[name]; want != have {
+			t.Errorf("%s: want %d requests, have %d", name, want, have)
+		}
+	}
+}
+
+func TestConsistentHashWeighted(t *testing.T) {
+	weights := ConsistentHashWeights(func(instance string) int {
+		if instance == "a" {
+			return 3
+		}
+		return 1
+	})
+	e := NewConsistentHash[string, string](instances("a", "b"), keyOf, 100, weights)
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		name, _ := e(context.Background(), fmt.Sprintf("key-%d", i))
+		counts[name]++
+	}
+	if counts["a"] < 2*counts["b"] {
+		t.Errorf("want a to have about 3 times the keys of b, have %v", counts)
+	}
+}
+
+func TestConsistentHashNoEndpoints(t *testing.T) {
+	e := NewConsistentHash[string, string](sd.FixedEndpointer[string, string]{}, keyOf, 1)
+	if _, err := e(context.Background(), "key"); err != ErrNoEndpoints {
+		t.Errorf("want %v, have %v", ErrNoEndpoints, err)
+	}
+}
@@ -0,0 +1,2 @@
+// Package lb implements the client-side load balancer pattern.
+package lb
@@ -0,0 +1,109 @@
+package lb
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/sd"
+)
+
+// NewLeastLoaded returns a load balancer that picks two services at random,
+// and returns the one with fewer requests in flight, relative to the weight of
+// its instance: the "power of two choices". It avoids both the herding of
+// always picking the least loaded service, and the hot spots of picking at
+// random, and adapts to services which are slow or small.
+//
+// Requests are counted while the returned endpoints are invoked, so they must
+// be invoked, or the counts are meaningless. Weights are from the instances'
+// metadata, or given by the Weights option, and instances are only known if
+// the Endpointer is an sd.InstanceEndpointer; otherwise, weights are all 1.
+func NewLeastLoaded[I, O any](s sd.Endpointer[I, O], seed int64, options ...WeightOption) Balancer[I, O] {
+	var opts weightOptions
+	for _, option := range options {
+		option(&opts)
+	}
+	return &leastLoaded[I, O]{
+		s:        s,
+		weight:   opts.weight,
+		r:        rand.New(rand.NewSource(seed)),
+		inflight: map[string]*int64{},
+	}
+}
+
+type leastLoaded[I, O any] struct {
+	s        sd.Endpointer[I, O]
+	weight   WeightFunc
+	mtx      sync.Mutex
+	r        *rand.Rand
+	inflight map[string]*int64 // by key
+}
+
+func (ll *leastLoaded[I, O]) Endpoint() (endpoint.Endpoint[I, O], error) {
+	endpoints, err := instanceEndpoints(ll.s)
+	if err != nil {
+		return nil, err
+	}
+	if len(endpoints) <= 0 {
+		return nil, ErrNoEndpoints
+	}
+
+	ll.mtx.Lock()
+	if len(ll.inflight) > len(endpoints) {
+		ll.prune(endpoints)
+	}
+	i := 0
+	if n := len(endpoints); n > 1 {
+		i = ll.r.Intn(n)
+		j := ll.r.Intn(n - 1)
+		if j >= i {
+			j++ // distinct from i
+		}
+		if ll.load(endpoints[j], j) < ll.load(endpoints[i], i) {
+			i = j
+		}
+	}
+	inflight := ll.counter(key(endpoints[i], i))
+	ll.mtx.Unlock()
+
+	next := endpoints[i].Endpoint
+	return func(ctx context.Context, request I) (O, error) {
+		atomic.AddInt64(inflight, 1)
+		defer atomic.AddInt64(inflight, -1)
+		return next(ctx, request)
+	}, nil
+}
+
+// load returns the requests in flight to the i'th endpoint, and the one it
+// would be given, relative to its weight. The caller must hold the mutex.
+func (ll *leastLoaded[I, O]) load(e sd.InstanceEndpoint[I, O], i int) float64 {
+	n := atomic.LoadInt64(ll.counter(key(e, i)))
+	return float64(n+1) / float64(weight(ll.weight, e))
+}
+
+// counter returns the count of requests in flight for the key. The caller
+// must hold the mutex.
+func (ll *leastLoaded[I, O]) counter(k string) *int64 {
+	c, ok := ll.inflight[k]
+	if !ok {
+		c = new(int64)
+		ll.inflight[k] = c
+	}
+	return c
+}
+
+// prune forgets the counts of endpoints which have gone away. Requests in
+// flight to them still decrement the forgotten counts.
+func (ll *leastLoaded[I, O]) prune(endpoints []sd.InstanceEndpoint[I, O]) {
+	keys := make(map[string]bool, len(endpoints))
+	for i, e := range endpoints {
+		keys[key(e, i)] = true
+	}
+	for k := range ll.inflight {
+		if !keys[k] {
+			delete(ll.inflight, k)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package lb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/sd"
+)
+
+func TestLeastLoaded(t *testing.T) {
+	var (
+		block    = make(chan struct{})
+		blocking = func(context.Context, struct{}) (string, error) { <-block; return "slow", nil }
+	)
+	endpointer := sd.FixedInstanceEndpointer[struct{}, string]{
+		{Instance: "slow", Endpoint: blocking},
+		{Instance: "fast", Endpoint: named("fast")},
+	}
+	balancer := NewLeastLoaded[struct{}, string](endpointer, 1)
+
+	// Requests to the slow endpoint pile up, so after the first, the fast
+	// endpoint is always picked.
+	var slow int
+	for i := 0; i < 100; i++ {
+		e, err := balancer.Endpoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		done := make(chan string, 1)
+		go func(e endpoint.Endpoint[struct{}, string]) {
+			name, _ := e(context.Background(), struct{}{})
+			done <- name
+		}(e)
+		select {
+		case <-done:
+		case <-time.After(50 * time.Millisecond):
+			slow++ // and in flight
+		}
+	}
+	close(block)
+	if want, have := 1, slow; want != have {
+		t.Errorf("requests to the slow endpoint: want %d, have %d", want, have)
+	}
+}
+
+func TestLeastLoadedWeighted(t *testing.T) {
+	endpointer := sd.FixedInstanceEndpointer[struct{}, string]{
+		{Instance: "a", Endpoint: named("a")},
+		{Instance: "b", Endpoint: named("b")},
+	}
+	weights := Weights(func(instance string) int {
+		if instance == "a" {
+			return 4
+		}
+		return 0 // taken as 1
+	})
+	ll := NewLeastLoaded[struct{}, string](endpointer, 1, weights).(*leastLoaded[struct{}, string])
+	*ll.counter("a") = 3
+	*ll.counter("b") = 1
+
+	// a has (3+1)/4 = 1 load, and b has (1+1)/1 = 2.
+	for i := 0; i < 10; i++ {
+		e, _ := ll.Endpoint()
+		if name, _ := e(context.Background(), struct{}{}); name != "a" {
+			t.Fatalf("want a, have %s", name)
+		}
+	}
+}
+
+func TestLeastLoadedNoEndpoints(t *testing.T) {
+	balancer := NewLeastLoaded[struct{}, string](sd.FixedEndpointer[struct{}, string]{}, 1)
+	if _, err := balancer.Endpoint(); err != ErrNoEndpoints {
+		t.Errorf("want %v, have %v", ErrNoEndpoints, err)
+	}
+}
@@ -0,0 +1,303 @@
+package lb
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/sd"
+)
+
+// OutlierOption sets an optional parameter for outlier detection.
+type OutlierOption func(*outlierOptions)
+
+type outlierOptions struct {
+	consecutiveErrors int
+	errorRate         float64
+	latencyFactor     float64
+	minRequests       int
+	interval          time.Duration
+	baseEjection      time.Duration
+	maxEjection       time.Duration
+	maxEjectedPercent int
+	isError           func(error) bool
+}
+
+// OutlierConsecutiveErrors ejects an instance after n errors in a row. By
+// default, n is 5; 0 disables it.
+func OutlierConsecutiveErrors(n int) OutlierOption {
+	return func(o *outlierOptions) { o.consecutiveErrors = n }
+}
+
+// OutlierErrorRate ejects an instance if at least the rate of its requests,
+// e.g. 0.5, fail in an interval, among at least minRequests. By default, it's
+// disabled.
+func OutlierErrorRate(rate float64, minRequests int) OutlierOption {
+	return func(o *outlierOptions) { o.errorRate, o.minRequests = rate, minRequests }
+}
+
+// OutlierLatency ejects an instance if its mean latency in an interval is
+// more than factor times the median of the instances' means. Only instances
+// with at least minRequests in the interval are compared, and only if there
+// are at least 3 of them. By default, it's disabled.
+func OutlierLatency(factor float64, minRequests int) OutlierOption {
+	return func(o *outlierOptions) { o.latencyFactor, o.minRequests = factor, minRequests }
+}
+
+// OutlierInterval sets the interval over which error rates and latencies are
+// measured. By default, it's 10s.
+func OutlierInterval(d time.Duration) OutlierOption {
+	return func(o *outlierOptions) { o.interval = d }
+}
+
+// OutlierEjectionTime sets the time an instance is first ejected for, which
+// doubles each time it's ejected again, up to max. By default, it's 30s, up
+// to 5m.
+func OutlierEjectionTime(base, max time.Duration) OutlierOption {
+	return func(o *outlierOptions) { o.baseEjection, o.maxEjection = base, max }
+}
+
+// OutlierMaxEjectedPercent sets the most instances that may be ejected at
+// once, as a percentage, so outlier detection can't take down a service whose
+// instances all fail. By default, it's 50.
+func OutlierMaxEjectedPercent(p int) OutlierOption {
+	return func(o *outlierOptions) { o.maxEjectedPercent = p }
+}
+
+// OutlierIsError sets the func which decides whether an error counts against
+// an instance. By default, all errors do; a func might exclude, for example,
+// errors due to a bad request, or the caller's canceled context.
+func OutlierIsError(f func(error) bool) OutlierOption {
+	return func(o *outlierOptions) { o.isError = f }
+}
+
+// NewOutlierEndpointer returns an Endpointer which yields the endpoints of s,
+// except for outliers: instances which fail, or are slow, when compared to
+// their peers. It's passive health checking, as in Envoy: the endpoints it
+// yields record the results of requests, and instances whose results are
+// outliers are ejected for a time, which grows exponentially each time they're
+// ejected again, and decays while they're not.
+//
+// After its ejection time, an instance is admitted again on probation: the
+// next requests to it are probes, and if one fails, it's ejected again at
+// once, rather than after more errors.
+//
+// Put it between the Endpointer of a service and its Balancer. Instances are
+// only known if s is an sd.InstanceEndpointer, such as the one returned by
+// sd.NewEndpointer; otherwise, endpoints are tracked by their position.
+func NewOutlierEndpointer[I, O any](s sd.Endpointer[I, O], options ...OutlierOption) sd.InstanceEndpointer[I, O] {
+	opts := outlierOptions{
+		consecutiveErrors: 5,
+		interval:          10 * time.Second,
+		baseEjection:      30 * time.Second,
+		maxEjection:       5 * time.Minute,
+		maxEjectedPercent: 50,
+		isError:           func(err error) bool { return err != nil },
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+	return &outlierEndpointer[I, O]{
+		s:      s,
+		opts:   opts,
+		states: map[string]*outlierState[I, O]{},
+		now:    time.Now,
+	}
+}
+
+type outlierEndpointer[I, O any] struct {
+	s    sd.Endpointer[I, O]
+	opts outlierOptions
+	now  func() time.Time
+
+	mtx       sync.Mutex
+	states    map[string]*outlierState[I, O] // by key
+	sigs      []string                       // of the endpoints of s, to detect changes
+	evaluated time.Time                      // at the start of the interval
+	dirty     bool                           // if the healthy set has changed
+	healthy   []sd.InstanceEndpoint[I, O]
+	endpoints []endpoint.Endpoint[I, O] // of healthy
+}
+
+type outlierState[I, O any] struct {
+	wrapped   endpoint.Endpoint[I, O] // which records results
+	next      endpoint.Endpoint[I, O]
+	ejections int       // recent ejections, for the ejection time
+	until     time.Time // if ejected
+	probation bool
+
+	consecutive int
+	requests    int // in the interval
+	errors      int
+	latency     time.Duration // total
+}
+
+func (oe *outlierEndpointer[I, O]) Endpoints() ([]endpoint.Endpoint[I, O], error) {
+	_, endpoints, err := oe.get()
+	return endpoints, err
+}
+
+func (oe *outlierEndpointer[I, O]) InstanceEndpoints() ([]sd.InstanceEndpoint[I, O], error) {
+	healthy, _, err := oe.get()
+	return healthy, err
+}
+
+func (oe *outlierEndpointer[I, O]) get() ([]sd.InstanceEndpoint[I, O], []endpoint.Endpoint[I, O], error) {
+	all, err := instanceEndpoints(oe.s)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	oe.mtx.Lock()
+	defer oe.mtx.Unlock()
+	now := oe.now()
+
+	sigs := make([]string, len(all))
+	for i, e := range all {
+		sigs[i] = key(e, i)
+	}
+	if !equal(sigs, oe.sigs) {
+		oe.sigs = sigs
+		oe.dirty = true
+		states := make(map[string]*outlierState[I, O], len(all))
+		for i, e := range all {
+			k := sigs[i]
+			state, ok := oe.states[k]
+			if !ok {
+				state = &outlierState[I, O]{}
+				state.wrapped = oe.wrap(state)
+			}
+			state.next = e.Endpoint
+			states[k] = state
+		}
+		oe.states = states
+	}
+
+	// Admit instances whose ejection is over on probation.
+	for _, state := range oe.states {
+		if !state.until.IsZero() && !now.Before(state.until) {
+			state.until = time.Time{}
+			state.probation = true
+			oe.dirty = true
+		}
+	}
+	if now.Sub(oe.evaluated) >= oe.opts.interval {
+		oe.evaluate(now)
+	}
+
+	if oe.dirty {
+		oe.dirty = false
+		oe.healthy = oe.healthy[:0:0]
+		oe.endpoints = oe.endpoints[:0:0]
+		for i, e := range all {
+			state := oe.states[sigs[i]]
+			if !state.until.IsZero() {
+				continue
+			}
+			e.Endpoint = state.wrapped
+			oe.healthy = append(oe.healthy, e)
+			oe.endpoints = append(oe.endpoints, state.wrapped)
+		}
+	}
+	return oe.healthy, oe.endpoints, nil
+}
+
+// wrap returns an endpoint which calls the state's endpoint, and records its
+// results.
+func (oe *outlierEndpointer[I, O]) wrap(state *outlierState[I, O]) endpoint.Endpoint[I, O] {
+	return func(ctx context.Context, request I) (O, error) {
+		oe.mtx.Lock()
+		next := state.next
+		oe.mtx.Unlock()
+
+		begin := oe.now()
+		response, err := next(ctx, request)
+		oe.record(state, oe.now().Sub(begin), oe.opts.isError(err))
+		return response, err
+	}
+}
+
+func (oe *outlierEndpointer[I, O]) record(state *outlierState[I, O], latency time.Duration, failed bool) {
+	oe.mtx.Lock()
+	defer oe.mtx.Unlock()
+	state.requests++
+	state.latency += latency
+	if !failed {
+		state.consecutive = 0
+		state.probation = false
+		return
+	}
+	state.errors++
+	state.consecutive++
+	if state.probation || (oe.opts.consecutiveErrors > 0 && state.consecutive >= oe.opts.consecutiveErrors) {
+		oe.eject(state, oe.now())
+	}
+}
+
+// evaluate ejects instances whose error rates or latencies in the interval
+// are outliers, and starts the next interval. The caller must hold the mutex.
+func (oe *outlierEndpointer[I, O]) evaluate(now time.Time) {
+	oe.evaluated = now
+
+	var means []float64
+	if oe.opts.latencyFactor > 0 {
+		for _, state := range oe.states {
+			if state.until.IsZero() && state.requests >= oe.opts.minRequests && state.requests > 0 {
+				means = append(means, float64(state.latency)/float64(state.requests))
+			}
+		}
+		sort.Float64s(means)
+	}
+
+	for _, state := range oe.states {
+		if !state.until.IsZero() {
+			continue // already ejected
+		}
+		switch {
+		case state.requests < oe.opts.minRequests || state.requests == 0:
+		case oe.opts.errorRate > 0 && float64(state.errors)/float64(state.requests) >= oe.opts.errorRate:
+			oe.eject(state, now)
+		case len(means) >= 3 && float64(state.latency)/float64(state.requests) > oe.opts.latencyFactor*means[len(means)/2]:
+			oe.eject(state, now)
+		default:
+			if state.ejections > 0 {
+				state.ejections-- // a good interval decays the ejection time
+			}
+		}
+	}
+	for _, state := range oe.states {
+		state.requests, state.errors, state.latency = 0, 0, 0
+	}
+}
+
+// eject ejects the instance, unless too many are ejected already. The caller
+// must hold the mutex.
+func (oe *outlierEndpointer[I, O]) eject(state *outlierState[I, O], now time.Time) {
+	if !state.until.IsZero() {
+		return
+	}
+	ejected := 0
+	for _, s := range oe.states {
+		if !s.until.IsZero() {
+			ejected++
+		}
+	}
+	if (ejected+1)*100 > oe.opts.maxEjectedPercent*len(oe.states) {
+		return
+	}
+
+	state.ejections++
+	d := oe.opts.baseEjection
+	for i := 1; i < state.ejections && d < oe.opts.maxEjection; i++ {
+		d *= 2
+	}
+	if d > oe.opts.maxEjection {
+		d = oe.opts.maxEjection
+	}
+	state.until = now.Add(d)
+	state.probation = false
+	state.consecutive = 0
+	oe.dirty = true
+}
@@ -0,0 +1,166 @@
+package lb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/barrett370/kit/v2/sd"
+)
+
+type fakeClock struct {
+	mtx sync.Mutex
+	t   time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) add(d time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.t = c.t.Add(d)
+}
+
+// failing returns instances whose endpoints fail if their name is in failing.
+func failing(failing map[string]bool, names ...string) sd.FixedInstanceEndpointer[string, string] {
+	endpoints := instances(names...)
+	for i := range endpoints {
+		name := endpoints[i].Instance
+		endpoints[i].Endpoint = func(context.Context, string) (string, error) {
+			if failing[name] {
+				return "", errors.New(name + " failed")
+			}
+			return name, nil
+		}
+	}
+	return endpoints
+}
+
+func newTestOutlierEndpointer(s sd.Endpointer[string, string], options ...OutlierOption) (sd.InstanceEndpointer[string, string], *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	oe := NewOutlierEndpointer[string, string](s, options...)
+	oe.(*outlierEndpointer[string, string]).now = clock.now
+	return oe, clock
+}
+
+// call calls the endpoint of the named instance n times.
+func call(t *testing.T, s sd.InstanceEndpointer[string, string], name string, n int) {
+	t.Helper()
+	endpoints, err := s.InstanceEndpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range endpoints {
+		if e.Instance == name {
+			for i := 0; i < n; i++ {
+				e.Endpoint(context.Background(), "")
+			}
+			return
+		}
+	}
+	t.Fatalf("%s isn't healthy", name)
+}
+
+func TestOutlierEndpointerConsecutiveErrors(t *testing.T) {
+	fail := map[string]bool{"b": true}
+	s, clock := newTestOutlierEndpointer(failing(fail, "a", "b", "c"), OutlierConsecutiveErrors(3), OutlierEjectionTime(time.Second, 10*time.Second))
+
+	call(t, s, "b", 2)
+	if want, have := []string{"a", "b", "c"}, names(t, s); !reflect.DeepEqual(want, have) {
+		t.Fatalf("want %v, have %v", want, have)
+	}
+	call(t, s, "b", 1)
+	if want, have := []string{"a", "c"}, names(t, s); !reflect.DeepEqual(want, have) {
+		t.Fatalf("want %v, have %v", want, have)
+	}
+
+	// After the ejection time, b is admitted on probation, and a single error
+	// ejects it again, for twice as long.
+	clock.add(time.Second)
+	if want, have := []string{"a", "b", "c"}, names(t, s); !reflect.DeepEqual(want, have) {
+		t.Fatalf("want %v, have %v", want, have)
+	}
+	call(t, s, "b", 1)
+	if want, have := []string{"a", "c"}, names(t, s); !reflect.DeepEqual(want, have) {
+		t.Fatalf("want %v, have %v", want, have)
+	}
+	clock.add(time.Second)
+	if want, have := []string{"a", "c"}, names(t, s); !reflect.DeepEqual(want, have) {
+		t.Fatalf("want %v, have %v", want, have)
+	}
+	clock.add(time.Second)
+
+	// A successful probe ends the probation.
+	fail["b"] = false
+	call(t, s, "b", 1)
+	fail["b"] = true
+	call(t, s, "b", 2)
+	if want, have := []string{"a", "b", "c"}, names(t, s); !reflect.DeepEqual(want, have) {
+		t.Fatalf("want %v, have %v", want, have)
+	}
+}
+
+func TestOutlierEndpointerMaxEjectedPercent(t *testing.T) {
+	fail := map[string]bool{"a": true, "b": true, "c": true}
+	s, _ := newTestOutlierEndpointer(failing(fail, "a", "b", "c"), OutlierConsecutiveErrors(1))
+	for _, name := range []string{"a", "b", "c"} {
+		if contains(names(t, s), name) {
+			call(t, s, name, 1)
+		}
+	}
+	if want, have := 2, len(names(t, s)); want != have {
+		t.Errorf("want %d healthy, have %d", want, have)
+	}
+}
+
+func TestOutlierEndpointerErrorRate(t *testing.T) {
+	fail := map[string]bool{}
+	s, clock := newTestOutlierEndpointer(failing(fail, "a", "b", "c"), OutlierConsecutiveErrors(0), OutlierErrorRate(0.5, 10), OutlierInterval(time.Second))
+
+	names(t, s) // starts the interval
+	call(t, s, "a", 10)
+	fail["b"] = true
+	call(t, s, "b", 5) // too few requests
+	fail["c"] = true
+	call(t, s, "c", 6)
+	fail["c"] = false
+	call(t, s, "c", 4)
+
+	clock.add(time.Second)
+	if want, have := []string{"a", "b"}, names(t, s); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestOutlierEndpointerLatency(t *testing.T) {
+	var clock *fakeClock
+	endpoints := instances("a", "b", "c", "d")
+	for i := range endpoints {
+		name := endpoints[i].Instance
+		endpoints[i].Endpoint = func(context.Context, string) (string, error) {
+			if name == "d" {
+				clock.add(time.Second)
+			} else {
+				clock.add(100 * time.Millisecond)
+			}
+			return name, nil
+		}
+	}
+	s, clock := newTestOutlierEndpointer(endpoints, OutlierLatency(3, 5), OutlierInterval(time.Minute))
+
+	names(t, s) // starts the interval
+	for _, name := range []string{"a", "b", "c", "d"} {
+		call(t, s, name, 5)
+	}
+	clock.add(time.Minute)
+	if want, have := []string{"a", "b", "c"}, names(t, s); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
@@ -0,0 +1,37 @@
+package lb
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/sd"
+)
+
+// NewRandom returns a load balancer that selects services randomly.
+func NewRandom[I, O any](s sd.Endpointer[I, O], seed int64) Balancer[I, O] {
+	return &random[I, O]{
+		s: s,
+		r: rand.New(rand.NewSource(seed)),
+	}
+}
+
+type random[I, O any] struct {
+	s   sd.Endpointer[I, O]
+	mtx sync.Mutex // rand.Rand isn't safe for concurrent use
+	r   *rand.Rand
+}
+
+func (r *random[I, O]) Endpoint() (endpoint.Endpoint[I, O], error) {
+	endpoints, err := r.s.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+	if len(endpoints) <= 0 {
+		return nil, ErrNoEndpoints
+	}
+	r.mtx.Lock()
+	i := r.r.Intn(len(endpoints))
+	r.mtx.Unlock()
+	return endpoints[i], nil
+}
@@ -0,0 +1,233 @@
+package lb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/tracing"
+)
+
+// RetryError is an error wrapper that is used by the retry mechanism. All
+// errors returned by the retry mechanism via its endpoint will be RetryErrors.
+type RetryError struct {
+	RawErrors []error // all errors encountered from endpoints directly
+	Final     error   // the final, terminating error
+}
+
+func (e RetryError) Error() string {
+	var suffix string
+	if len(e.RawErrors) > 1 {
+		a := make([]string, len(e.RawErrors)-1)
+		for i := 0; i < len(e.RawErrors)-1; i++ { // last one is Final
+			a[i] = e.RawErrors[i].Error()
+		}
+		suffix = fmt.Sprintf(" (previously: %s)", strings.Join(a, "; "))
+	}
+	return fmt.Sprintf("%v%s", e.Final, suffix)
+}
+
+// Callback is a function that is given the current attempt count and the error
+// received from the underlying endpoint. It should return whether the Retry
+// function should continue trying to get a working endpoint, and a custom error
+// if desired. The error message may be nil, but a true/false is always
+// expected. In all cases, if the replacement error is supplied, the received
+// error will be replaced in the calling context.
+type Callback func(n int, received error) (keepTrying bool, replacement error)
+
+// Retry wraps a service load balancer and returns an endpoint oriented load
+// balancer for the specified service method. Requests to the endpoint will be
+// automatically load balanced via the load balancer. Requests that return
+// errors will be retried until they succeed, up to max times, or until the
+// timeout is elapsed, whichever comes first.
+func Retry[I, O any](max int, timeout time.Duration, b Balancer[I, O]) endpoint.Endpoint[I, O] {
+	return RetryWithCallback(timeout, b, maxRetries(max))
+}
+
+func maxRetries(max int) Callback {
+	return func(n int, err error) (keepTrying bool, replacement error) {
+		return n < max, nil
+	}
+}
+
+func alwaysRetry(int, error) (keepTrying bool, replacement error) {
+	return true, nil
+}
+
+// RetryWithCallback wraps a service load balancer and returns an endpoint
+// oriented load balancer for the specified service method. Requests to the
+// endpoint will be automatically load balanced via the load balancer. Requests
+// that return errors will be retried until they succeed, up to max times, until
+// the callback returns false, or until the timeout is elapsed, whichever comes
+// first.
+func RetryWithCallback[I, O any](timeout time.Duration, b Balancer[I, O], cb Callback) endpoint.Endpoint[I, O] {
+	return RetryWithOptions(timeout, b, RetryCallback(cb))
+}
+
+// RetryOption sets an optional parameter for retries.
+type RetryOption func(*retryOptions)
+
+type retryOptions struct {
+	cb        Callback
+	perTry    time.Duration
+	budget    *Budget
+	retryable func(error) bool
+}
+
+// RetryMax retries requests up to max times. It replaces any Callback.
+func RetryMax(max int) RetryOption {
+	return func(o *retryOptions) { o.cb = maxRetries(max) }
+}
+
+// RetryCallback sets the callback which decides whether to retry, and may
+// replace the errors received. It replaces any max. By default, requests are
+// retried until the timeout is elapsed.
+func RetryCallback(cb Callback) RetryOption {
+	return func(o *retryOptions) { o.cb = cb }
+}
+
+// RetryPerTryTimeout bounds each try to d, as well as all of them to the
+// timeout, so a single slow endpoint can't use the whole timeout. A try which
+// times out is retried like any other error. By default, tries are only
+// bounded by the timeout.
+func RetryPerTryTimeout(d time.Duration) RetryOption {
+	return func(o *retryOptions) { o.perTry = d }
+}
+
+// RetryIf sets the func which decides whether an error is retryable. Other
+// errors are terminal, and returned at once, e.g. errors due to a bad request,
+// which would fail on any endpoint. By default, all errors are retryable.
+func RetryIf(retryable func(error) bool) RetryOption {
+	return func(o *retryOptions) { o.retryable = retryable }
+}
+
+// RetryBudget limits retries to the budget, which may be shared by many
+// endpoints, e.g. all the endpoints of a service. Once it's exhausted, errors
+// are returned rather than retried, so retries can't multiply the load on a
+// service which is already failing. By default, there's no budget.
+func RetryBudget(budget *Budget) RetryOption {
+	return func(o *retryOptions) { o.budget = budget }
+}
+
+// RetryWithOptions wraps a service load balancer and returns an endpoint
+// oriented load balancer for the specified service method. Requests to the
+// endpoint will be automatically load balanced via the load balancer. Requests
+// that return retryable errors will be retried until they succeed, until the
+// callback returns false, until the budget is exhausted, or until the timeout
+// is elapsed, whichever comes first.
+//
+// If the context carries a tracing.Span, each failed try is added to it as a
+// "retry.failed" event, with its attempt, error, and duration, and the span's
+// "retry.attempts" attribute is set to the number of tries, and its
+// "retry.outcome" attribute to why it stopped trying.
+func RetryWithOptions[I, O any](timeout time.Duration, b Balancer[I, O], options ...RetryOption) endpoint.Endpoint[I, O] {
+	opts := retryOptions{
+		retryable: func(error) bool { return true },
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+	if opts.cb == nil {
+		opts.cb = alwaysRetry
+	}
+	if b == nil {
+		panic("nil Balancer")
+	}
+
+	type result struct {
+		response O
+		err      error
+	}
+
+	return func(ctx context.Context, request I) (response O, err error) {
+		var (
+			newctx, cancel = context.WithTimeout(ctx, timeout)
+			final          RetryError
+		)
+		defer cancel()
+		if opts.budget != nil {
+			opts.budget.request()
+		}
+		span := tracing.SpanFromContext(ctx)
+
+		for i := 1; ; i++ {
+			var (
+				tryctx, trycancel = newctx, context.CancelFunc(func() {})
+				tryDone           <-chan struct{}        // nil, unless there's a per-try timeout
+				results           = make(chan result, 1) // so an abandoned try doesn't block
+			)
+			if opts.perTry > 0 {
+				tryctx, trycancel = context.WithTimeout(newctx, opts.perTry)
+				tryDone = tryctx.Done()
+			}
+			begin := time.Now()
+			go func() {
+				e, err := b.Endpoint()
+				if err != nil {
+					results <- result{err: err}
+					return
+				}
+				response, err := e(tryctx, request)
+				results <- result{response, err}
+			}()
+
+			var r result
+			select {
+			case <-newctx.Done():
+				trycancel()
+				retried(span, i, "timeout")
+				return response, newctx.Err()
+
+			case <-tryDone:
+				if newctx.Err() != nil {
+					trycancel()
+					retried(span, i, "timeout")
+					return response, newctx.Err()
+				}
+				r.err = tryctx.Err()
+
+			case r = <-results:
+			}
+			trycancel()
+			if r.err == nil {
+				if i > 1 {
+					retried(span, i, "success")
+				}
+				return r.response, nil
+			}
+			span.AddEvent("retry.failed",
+				tracing.Attr("retry.attempt", i),
+				tracing.Attr("error", r.err.Error()),
+				tracing.Attr("duration", time.Since(begin).String()),
+			)
+
+			final.RawErrors = append(final.RawErrors, r.err)
+			keepTrying, replacement := opts.cb(i, r.err)
+			final.Final = r.err
+			if replacement != nil {
+				final.Final = replacement
+			}
+			switch {
+			case !keepTrying:
+				retried(span, i, "gave up")
+				return response, final
+			case !opts.retryable(r.err):
+				retried(span, i, "terminal error")
+				return response, final
+			case opts.budget != nil && !opts.budget.retry():
+				retried(span, i, "budget exhausted")
+				return response, final
+			}
+		}
+	}
+}
+
+// retried annotates the span with the outcome of a request after its tries.
+func retried(span tracing.Span, attempts int, outcome string) {
+	span.SetAttributes(
+		tracing.Attr("retry.attempts", attempts),
+		tracing.Attr("retry.outcome", outcome),
+	)
+}
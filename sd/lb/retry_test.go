@@ -0,0 +1,259 @@
+package lb_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/sd"
+	"github.com/barrett370/kit/v2/sd/lb"
+	"github.com/barrett370/kit/v2/tracing"
+)
+
+type response struct{ n int }
+
+func TestRetryMaxTotalFail(t *testing.T) {
+	var (
+		endpoints = sd.FixedEndpointer[string, response]{} // no endpoints
+		rr        = lb.NewRoundRobin[string, response](endpoints)
+		retry     = lb.Retry[string, response](999, time.Second, rr) // lots of retries
+		ctx       = context.Background()
+	)
+	if _, err := retry(ctx, "request"); err == nil {
+		t.Errorf("expected error, got none") // should fail
+	}
+}
+
+func TestRetryMaxPartialFail(t *testing.T) {
+	var (
+		endpoints = []endpoint.Endpoint[string, response]{
+			func(context.Context, string) (response, error) { return response{}, errors.New("error one") },
+			func(context.Context, string) (response, error) { return response{}, errors.New("error two") },
+			func(context.Context, string) (response, error) { return response{n: 1}, nil /* OK */ },
+		}
+		endpointer = sd.FixedEndpointer[string, response]{
+			0: endpoints[0],
+			1: endpoints[1],
+			2: endpoints[2],
+		}
+		retries = len(endpoints) - 1 // not quite enough retries
+		rr      = lb.NewRoundRobin[string, response](endpointer)
+		ctx     = context.Background()
+	)
+	if _, err := lb.Retry[string, response](retries, time.Second, rr)(ctx, "request"); err == nil {
+		t.Errorf("expected error two, got none")
+	}
+}
+
+func TestRetryMaxSuccess(t *testing.T) {
+	var (
+		endpoints = []endpoint.Endpoint[string, response]{
+			func(context.Context, string) (response, error) { return response{}, errors.New("error one") },
+			func(context.Context, string) (response, error) { return response{}, errors.New("error two") },
+			func(context.Context, string) (response, error) { return response{n: 1}, nil /* OK */ },
+		}
+		endpointer = sd.FixedEndpointer[string, response]{
+			0: endpoints[0],
+			1: endpoints[1],
+			2: endpoints[2],
+		}
+		retries = len(endpoints) // exactly enough retries
+		rr      = lb.NewRoundRobin[string, response](endpointer)
+		ctx     = context.Background()
+	)
+	resp, err := lb.Retry[string, response](retries, time.Second, rr)(ctx, "request")
+	if err != nil {
+		t.Error(err)
+	}
+	if want, have := 1, resp.n; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestRetryTimeout(t *testing.T) {
+	var (
+		step    = make(chan struct{})
+		e       = func(context.Context, string) (response, error) { <-step; return response{}, nil }
+		timeout = time.Millisecond
+		retry   = lb.Retry[string, response](999, timeout, lb.NewRoundRobin[string, response](sd.FixedEndpointer[string, response]{e}))
+		errs    = make(chan error, 1)
+		invoke  = func() { _, err := retry(context.Background(), "request"); errs <- err }
+	)
+
+	go func() { step <- struct{}{} }() // queue up a flush of the endpoint
+	invoke()                           // invoke the endpoint and trigger the flush
+	if err := <-errs; err != nil {     // that should succeed
+		t.Error(err)
+	}
+
+	go func() { time.Sleep(10 * timeout); step <- struct{}{} }() // a delayed flush
+	invoke()                                                     // invoke the endpoint
+	if err := <-errs; err != context.DeadlineExceeded {          // that should not succeed
+		t.Errorf("wanted %v, got none", context.DeadlineExceeded)
+	}
+}
+
+func TestAbortEarlyCustomMessage(t *testing.T) {
+	var (
+		myErr     = errors.New("aborting early")
+		cb        = func(int, error) (bool, error) { return false, myErr }
+		endpoints = sd.FixedEndpointer[string, response]{} // no endpoints
+		rr        = lb.NewRoundRobin[string, response](endpoints)
+		retry     = lb.RetryWithCallback[string, response](time.Second, rr, cb) // lots of retries
+		ctx       = context.Background()
+	)
+	_, err := retry(ctx, "request")
+	if want, have := myErr, err.(lb.RetryError).Final; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestErrorPassedUnchangedToCallback(t *testing.T) {
+	var (
+		myErr = errors.New("my custom error")
+		cb    = func(_ int, err error) (bool, error) {
+			if want, have := myErr, err; want != have {
+				t.Errorf("want %v, have %v", want, have)
+			}
+			return false, nil
+		}
+		endpoint = func(ctx context.Context, request string) (response, error) {
+			return response{}, myErr
+		}
+		endpoints = sd.FixedEndpointer[string, response]{endpoint} // 1 endpoint
+		rr        = lb.NewRoundRobin[string, response](endpoints)
+		retry     = lb.RetryWithCallback[string, response](time.Second, rr, cb) // lots of retries
+		ctx       = context.Background()
+	)
+	_, err := retry(ctx, "request")
+	if want, have := myErr, err.(lb.RetryError).Final; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestHandleNilCallback(t *testing.T) {
+	var (
+		endpointer = sd.FixedEndpointer[string, response]{
+			func(context.Context, string) (response, error) { return response{n: 1}, nil /* OK */ },
+		}
+		rr  = lb.NewRoundRobin[string, response](endpointer)
+		ctx = context.Background()
+	)
+	retry := lb.RetryWithCallback[string, response](time.Second, rr, nil)
+	if _, err := retry(ctx, "request"); err != nil {
+		t.Error("Failed when callback is nil")
+	}
+}
+
+func TestRetryPerTryTimeout(t *testing.T) {
+	var (
+		calls int32
+		e     = func(ctx context.Context, _ string) (response, error) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				<-ctx.Done() // the first try hangs
+				return response{}, ctx.Err()
+			}
+			return response{n: 1}, nil
+		}
+		rr    = lb.NewRoundRobin[string, response](sd.FixedEndpointer[string, response]{e})
+		retry = lb.RetryWithOptions[string, response](time.Second, rr, lb.RetryMax(2), lb.RetryPerTryTimeout(10*time.Millisecond))
+	)
+	resp, err := retry(context.Background(), "request")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, resp.n; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestRetryTerminalError(t *testing.T) {
+	var (
+		terminal = errors.New("bad request")
+		calls    int32
+		e        = func(context.Context, string) (response, error) {
+			atomic.AddInt32(&calls, 1)
+			return response{}, terminal
+		}
+		rr    = lb.NewRoundRobin[string, response](sd.FixedEndpointer[string, response]{e})
+		retry = lb.RetryWithOptions[string, response](time.Second, rr, lb.RetryMax(5), lb.RetryIf(func(err error) bool { return err != terminal }))
+	)
+	_, err := retry(context.Background(), "request")
+	if want, have := terminal, err.(lb.RetryError).Final; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := int32(1), atomic.LoadInt32(&calls); want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	var (
+		calls int32
+		e     = func(context.Context, string) (response, error) {
+			atomic.AddInt32(&calls, 1)
+			return response{}, errors.New("unavailable")
+		}
+		rr     = lb.NewRoundRobin[string, response](sd.FixedEndpointer[string, response]{e})
+		budget = lb.NewBudget(0.2, 1, time.Minute)
+		retry  = lb.RetryWithOptions[string, response](time.Second, rr, lb.RetryMax(3), lb.RetryBudget(budget))
+	)
+
+	// 10 requests to a failing service allow 1 + 20% of 10 retries in all,
+	// rather than 2 retries of each.
+	for i := 0; i < 10; i++ {
+		if _, err := retry(context.Background(), "request"); err == nil {
+			t.Fatal("want error, have none")
+		}
+	}
+	if want, have := int32(10+3), atomic.LoadInt32(&calls); want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+}
+
+// recordingSpan records the annotations of a span.
+type recordingSpan struct {
+	events []string
+	attrs  map[string]interface{}
+}
+
+func (s *recordingSpan) AddEvent(name string, attrs ...tracing.Attribute) {
+	s.events = append(s.events, name)
+}
+
+func (s *recordingSpan) SetAttributes(attrs ...tracing.Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func TestRetryAnnotatesSpan(t *testing.T) {
+	var (
+		n         int32
+		endpoints = sd.FixedEndpointer[string, response]{
+			func(context.Context, string) (response, error) {
+				if atomic.AddInt32(&n, 1) < 3 {
+					return response{}, errors.New("error")
+				}
+				return response{}, nil
+			},
+		}
+		retry = lb.Retry[string, response](5, time.Second, lb.NewRoundRobin[string, response](endpoints))
+		span  = &recordingSpan{attrs: map[string]interface{}{}}
+	)
+	if _, err := retry(tracing.ContextWithSpan(context.Background(), span), ""); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, len(span.events); want != have {
+		t.Errorf("want %d events, have %v", want, span.events)
+	}
+	if want, have := 3, span.attrs["retry.attempts"]; want != have {
+		t.Errorf("want %v attempts, have %v", want, have)
+	}
+	if want, have := "success", span.attrs["retry.outcome"]; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
@@ -0,0 +1,34 @@
+package lb
+
+import (
+	"sync/atomic"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/sd"
+)
+
+// NewRoundRobin returns a load balancer that returns services in sequence.
+func NewRoundRobin[I, O any](s sd.Endpointer[I, O]) Balancer[I, O] {
+	return &roundRobin[I, O]{
+		s: s,
+		c: 0,
+	}
+}
+
+type roundRobin[I, O any] struct {
+	s sd.Endpointer[I, O]
+	c uint64
+}
+
+func (rr *roundRobin[I, O]) Endpoint() (endpoint.Endpoint[I, O], error) {
+	endpoints, err := rr.s.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+	if len(endpoints) <= 0 {
+		return nil, ErrNoEndpoints
+	}
+	old := atomic.AddUint64(&rr.c, 1) - 1
+	idx := old % uint64(len(endpoints))
+	return endpoints[idx], nil
+}
@@ -0,0 +1,175 @@
+package lb
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/sd"
+)
+
+// DefaultVersion is the version, in the weights of a Splitter, of the
+// instances whose versions have no weights of their own, including those
+// with no version at all.
+const DefaultVersion = ""
+
+// Splitter is a load balancer which splits requests between the versions of
+// a service, by weight, for canary and blue/green rollouts. Within a version,
+// requests are balanced by a Balancer of its own.
+//
+// Weights are relative, and keyed by the version label of instances. For
+// example, {DefaultVersion: 95, "1.5.0": 5} sends 5% of requests to a canary,
+// and {"blue": 0, "green": 100} switches all of them from blue to green.
+// Instances of versions with a weight of 0 get no requests, unless no
+// version with a positive weight has any instances, in which case requests
+// are balanced over all instances, rather than failing.
+//
+// Versions are only known if the Endpointer is an sd.InstanceEndpointer, and
+// the instances have metadata; otherwise, all instances are of the default
+// version.
+type Splitter[I, O any] struct {
+	s           sd.Endpointer[I, O]
+	newBalancer func(sd.Endpointer[I, O]) Balancer[I, O]
+	all         Balancer[I, O]
+
+	mtx       sync.Mutex
+	r         *rand.Rand
+	weights   map[string]int
+	balancers map[string]Balancer[I, O] // by version
+}
+
+// NewSplitter returns a Splitter of the endpoints of s, which sends all
+// requests to the default version until its weights are set. Within each
+// version, requests are balanced by a Balancer from newBalancer, e.g.
+// NewRoundRobin[I, O].
+func NewSplitter[I, O any](s sd.Endpointer[I, O], newBalancer func(sd.Endpointer[I, O]) Balancer[I, O], seed int64) *Splitter[I, O] {
+	return &Splitter[I, O]{
+		s:           s,
+		newBalancer: newBalancer,
+		all:         newBalancer(s),
+		r:           rand.New(rand.NewSource(seed)),
+		weights:     map[string]int{DefaultVersion: 1},
+		balancers:   map[string]Balancer[I, O]{},
+	}
+}
+
+// SetWeights sets the weights of versions, which apply to the next requests.
+// It's safe to call at any time, e.g. from an admin endpoint, as a rollout
+// progresses.
+func (sp *Splitter[I, O]) SetWeights(weights map[string]int) {
+	w := make(map[string]int, len(weights))
+	for version, weight := range weights {
+		if weight < 0 {
+			weight = 0
+		}
+		w[version] = weight
+	}
+	sp.mtx.Lock()
+	defer sp.mtx.Unlock()
+	sp.weights = w
+}
+
+// Weights returns the weights of versions.
+func (sp *Splitter[I, O]) Weights() map[string]int {
+	sp.mtx.Lock()
+	defer sp.mtx.Unlock()
+	w := make(map[string]int, len(sp.weights))
+	for version, weight := range sp.weights {
+		w[version] = weight
+	}
+	return w
+}
+
+// Endpoint implements Balancer.
+func (sp *Splitter[I, O]) Endpoint() (endpoint.Endpoint[I, O], error) {
+	endpoints, err := instanceEndpoints(sp.s)
+	if err != nil {
+		return nil, err
+	}
+	if len(endpoints) <= 0 {
+		return nil, ErrNoEndpoints
+	}
+
+	sp.mtx.Lock()
+	present := map[string]bool{}
+	for _, e := range endpoints {
+		present[route(sp.weights, e.Metadata.Version())] = true
+	}
+	var (
+		versions []string
+		total    int
+	)
+	for version, weight := range sp.weights {
+		if weight > 0 && present[version] {
+			versions = append(versions, version)
+			total += weight
+		}
+	}
+	if len(versions) == 0 {
+		sp.mtx.Unlock()
+		return sp.all.Endpoint()
+	}
+	sort.Strings(versions) // for determinism, given the seed
+	n := sp.r.Intn(total)
+	version := versions[len(versions)-1]
+	for _, v := range versions {
+		if n -= sp.weights[v]; n < 0 {
+			version = v
+			break
+		}
+	}
+	b, ok := sp.balancers[version]
+	if !ok {
+		b = sp.newBalancer(versionEndpointer[I, O]{sp: sp, version: version})
+		sp.balancers[version] = b
+	}
+	sp.mtx.Unlock()
+
+	return b.Endpoint()
+}
+
+// route returns the version whose weight applies to instances of the version.
+func route(weights map[string]int, version string) string {
+	if _, ok := weights[version]; ok {
+		return version
+	}
+	return DefaultVersion
+}
+
+// versionEndpointer yields the endpoints of a Splitter's instances which are
+// routed to its version.
+type versionEndpointer[I, O any] struct {
+	sp      *Splitter[I, O]
+	version string
+}
+
+func (ve versionEndpointer[I, O]) Endpoints() ([]endpoint.Endpoint[I, O], error) {
+	instances, err := ve.InstanceEndpoints()
+	if err != nil {
+		return nil, err
+	}
+	endpoints := make([]endpoint.Endpoint[I, O], len(instances))
+	for i, e := range instances {
+		endpoints[i] = e.Endpoint
+	}
+	return endpoints, nil
+}
+
+func (ve versionEndpointer[I, O]) InstanceEndpoints() ([]sd.InstanceEndpoint[I, O], error) {
+	all, err := instanceEndpoints(ve.sp.s)
+	if err != nil {
+		return nil, err
+	}
+	ve.sp.mtx.Lock()
+	weights := ve.sp.weights
+	ve.sp.mtx.Unlock()
+
+	var endpoints []sd.InstanceEndpoint[I, O]
+	for _, e := range all {
+		if route(weights, e.Metadata.Version()) == ve.version {
+			endpoints = append(endpoints, e)
+		}
+	}
+	return endpoints, nil
+}
@@ -0,0 +1,83 @@
+package lb
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/barrett370/kit/v2/sd"
+)
+
+// versioned returns instances named by their versions and indexes.
+func versioned(versions ...string) sd.FixedInstanceEndpointer[string, string] {
+	var endpoints sd.FixedInstanceEndpointer[string, string]
+	for i, version := range versions {
+		endpoints = append(endpoints, instances(fmt.Sprintf("%s-%d", version, i))...)
+		if version != "" {
+			endpoints[i].Metadata = sd.Metadata{sd.MetadataVersion: version}
+		}
+	}
+	return endpoints
+}
+
+func split(t *testing.T, sp *Splitter[string, string], n int) map[string]int {
+	t.Helper()
+	counts := map[string]int{}
+	for i := 0; i < n; i++ {
+		e, err := sp.Endpoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		name, _ := e(context.Background(), "")
+		counts[name]++
+	}
+	return counts
+}
+
+func TestSplitterCanary(t *testing.T) {
+	sp := NewSplitter[string, string](versioned("1.4", "1.4", "", "1.5"), NewRoundRobin[string, string], 0)
+
+	// Until weights are set, all instances are of the default version.
+	if want, have := 4, len(split(t, sp, 100)); want != have {
+		t.Errorf("want %d instances used, have %d", want, have)
+	}
+
+	sp.SetWeights(map[string]int{DefaultVersion: 90, "1.5": 10})
+	counts := split(t, sp, 10000)
+	if n := counts["1.5-3"]; n < 800 || n > 1200 { // 1000 is exact
+		t.Errorf("want about 10%% of requests to the canary, have %v", counts)
+	}
+	for _, name := range []string{"1.4-0", "1.4-1", "-2"} {
+		if n := counts[name]; n < 2700 || n > 3300 { // 3000 is exact
+			t.Errorf("want about 30%% of requests to %s, have %v", name, counts)
+		}
+	}
+}
+
+func TestSplitterBlueGreen(t *testing.T) {
+	endpoints := versioned("blue", "green")
+	sp := NewSplitter[string, string](endpoints, NewRoundRobin[string, string], 0)
+
+	sp.SetWeights(map[string]int{"blue": 100, "green": 0})
+	if want, have := 100, split(t, sp, 100)["blue-0"]; want != have {
+		t.Errorf("want %d requests to blue, have %d", want, have)
+	}
+	sp.SetWeights(map[string]int{"blue": 0, "green": 100})
+	if want, have := 100, split(t, sp, 100)["green-1"]; want != have {
+		t.Errorf("want %d requests to green, have %d", want, have)
+	}
+
+	// With no instances of a version with weight, requests go to all.
+	sp = NewSplitter[string, string](endpoints[:1], NewRoundRobin[string, string], 0)
+	sp.SetWeights(map[string]int{"blue": 0, "green": 100})
+	if want, have := 10, split(t, sp, 10)["blue-0"]; want != have {
+		t.Errorf("want %d requests to blue, have %d", want, have)
+	}
+}
+
+func TestSplitterNoEndpoints(t *testing.T) {
+	sp := NewSplitter[string, string](sd.FixedEndpointer[string, string]{}, NewRoundRobin[string, string], 0)
+	if _, err := sp.Endpoint(); err != ErrNoEndpoints {
+		t.Errorf("want %v, have %v", ErrNoEndpoints, err)
+	}
+}
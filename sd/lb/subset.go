@@ -0,0 +1,173 @@
+package lb
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/sd"
+)
+
+// SubsetOption sets an optional parameter for subset Endpointers.
+type SubsetOption func(*subsetOptions)
+
+type subsetOptions struct {
+	size    int
+	zone    string
+	zones   ZoneFunc
+	minZone int
+}
+
+// ZoneFunc returns the zone of an instance, e.g. "eu-west-1a", or "" if it's
+// unknown, for zone-aware load balancing.
+type ZoneFunc func(instance string) string
+
+// SubsetSize limits the endpoints to n, to bound the connections of each
+// client, however many instances there are. By default, there's no limit.
+func SubsetSize(n int) SubsetOption {
+	return func(o *subsetOptions) { o.size = n }
+}
+
+// SubsetZone prefers instances in the zone, usually the zone the client runs
+// in, to save the latency and cost of crossing zones. The zones of instances
+// are from their sd.MetadataZone label, or given by SubsetInstanceZones.
+func SubsetZone(zone string) SubsetOption {
+	return func(o *subsetOptions) { o.zone = zone }
+}
+
+// SubsetInstanceZones sets the func which gives the zone of each instance. By
+// default, it's the instance's metadata, with sd.Metadata.Zone.
+func SubsetInstanceZones(f ZoneFunc) SubsetOption {
+	return func(o *subsetOptions) { o.zones = f }
+}
+
+// SubsetMinZoneInstances sets the fewest instances in the zone to use alone.
+// If there are fewer, the endpoints spill over to instances in other zones,
+// so a zone with few instances left isn't overloaded. By default, it's 1.
+func SubsetMinZoneInstances(n int) SubsetOption {
+	return func(o *subsetOptions) { o.minZone = n }
+}
+
+// NewSubsetEndpointer returns an Endpointer which yields a subset of the
+// endpoints of s, chosen deterministically for the client: each client, by
+// its ID, ranks the instances by rendezvous hashing, and takes the first. So
+// the clients' subsets are spread evenly over the instances, and each client's
+// subset changes only as much as the instances do. If a zone is set, the
+// instances in the zone are preferred.
+//
+// Instances, and zones, are only known if s is an sd.InstanceEndpointer, such
+// as the one returned by sd.NewEndpointer; otherwise, endpoints are ranked by
+// their position.
+func NewSubsetEndpointer[I, O any](s sd.Endpointer[I, O], clientID string, options ...SubsetOption) sd.InstanceEndpointer[I, O] {
+	opts := subsetOptions{minZone: 1}
+	for _, option := range options {
+		option(&opts)
+	}
+	return &subsetEndpointer[I, O]{
+		s:        s,
+		clientID: clientID,
+		opts:     opts,
+	}
+}
+
+type subsetEndpointer[I, O any] struct {
+	s        sd.Endpointer[I, O]
+	clientID string
+	opts     subsetOptions
+
+	mtx       sync.Mutex
+	sigs      []string // of the endpoints of s, to detect changes
+	subset    []sd.InstanceEndpoint[I, O]
+	endpoints []endpoint.Endpoint[I, O] // of the subset
+}
+
+func (se *subsetEndpointer[I, O]) Endpoints() ([]endpoint.Endpoint[I, O], error) {
+	_, endpoints, err := se.get()
+	return endpoints, err
+}
+
+func (se *subsetEndpointer[I, O]) InstanceEndpoints() ([]sd.InstanceEndpoint[I, O], error) {
+	subset, _, err := se.get()
+	return subset, err
+}
+
+func (se *subsetEndpointer[I, O]) get() ([]sd.InstanceEndpoint[I, O], []endpoint.Endpoint[I, O], error) {
+	all, err := instanceEndpoints(se.s)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keys := make([]string, len(all))
+	sigs := make([]string, len(all))
+	for i, e := range all {
+		keys[i] = key(e, i)
+		sigs[i] = keys[i] + "\x00" + se.zoneOf(e) + "\x00" + strconv.Itoa(e.Metadata.Weight())
+	}
+
+	se.mtx.Lock()
+	defer se.mtx.Unlock()
+	if !equal(sigs, se.sigs) {
+		se.sigs = sigs
+		se.subset = se.choose(all, keys)
+		se.endpoints = make([]endpoint.Endpoint[I, O], len(se.subset))
+		for i, e := range se.subset {
+			se.endpoints[i] = e.Endpoint
+		}
+	}
+	return se.subset, se.endpoints, nil
+}
+
+// choose returns the subset of the endpoints for the client, in their order.
+func (se *subsetEndpointer[I, O]) choose(all []sd.InstanceEndpoint[I, O], keys []string) []sd.InstanceEndpoint[I, O] {
+	type ranked struct {
+		i     int
+		local bool
+		score uint64
+	}
+	var (
+		ranks = make([]ranked, len(all))
+		local int
+	)
+	for i, e := range all {
+		ranks[i] = ranked{i: i, score: hash(se.clientID + "/" + keys[i])}
+		if se.opts.zone != "" && se.zoneOf(e) == se.opts.zone {
+			ranks[i].local = true
+			local++
+		}
+	}
+	// Instances in the zone rank first, and then by their scores.
+	sort.Slice(ranks, func(a, b int) bool {
+		if ranks[a].local != ranks[b].local {
+			return ranks[a].local
+		}
+		return ranks[a].score > ranks[b].score
+	})
+
+	n := len(ranks)
+	if local > 0 && local >= se.opts.minZone {
+		n = local // no spillover
+	}
+	if se.opts.size > 0 && se.opts.size < n {
+		n = se.opts.size
+	}
+	chosen := make([]int, n)
+	for i := range chosen {
+		chosen[i] = ranks[i].i
+	}
+	sort.Ints(chosen)
+
+	subset := make([]sd.InstanceEndpoint[I, O], n)
+	for i, j := range chosen {
+		subset[i] = all[j]
+	}
+	return subset
+}
+
+// zoneOf returns the zone of the endpoint's instance, if known.
+func (se *subsetEndpointer[I, O]) zoneOf(e sd.InstanceEndpoint[I, O]) string {
+	if se.opts.zones == nil || e.Instance == "" {
+		return e.Metadata.Zone()
+	}
+	return se.opts.zones(e.Instance)
+}
@@ -0,0 +1,143 @@
+package lb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/barrett370/kit/v2/sd"
+)
+
+func names(t *testing.T, s sd.InstanceEndpointer[string, string]) []string {
+	t.Helper()
+	endpoints, err := s.InstanceEndpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(endpoints))
+	for i, e := range endpoints {
+		names[i] = e.Instance
+	}
+	return names
+}
+
+func zoned(zones ...string) sd.FixedInstanceEndpointer[string, string] {
+	endpoints := instances()
+	for i, zone := range zones {
+		endpoints = append(endpoints, instances(fmt.Sprintf("%s-%d", zone, i))...)
+	}
+	return endpoints
+}
+
+// zoneOf is the ZoneFunc of zoned instances.
+func zoneOf(instance string) string {
+	return strings.SplitN(instance, "-", 2)[0]
+}
+
+func TestSubsetEndpointerDeterministic(t *testing.T) {
+	var all []string
+	for i := 0; i < 20; i++ {
+		all = append(all, fmt.Sprintf("10.0.0.%d:80", i))
+	}
+
+	// Each client gets the same subset every time, and the subsets of many
+	// clients spread over the instances.
+	counts := map[string]int{}
+	for c := 0; c < 100; c++ {
+		s := NewSubsetEndpointer[string, string](instances(all...), fmt.Sprintf("client-%d", c), SubsetSize(5))
+		subset := names(t, s)
+		if want, have := 5, len(subset); want != have {
+			t.Fatalf("want %d endpoints, have %d", want, have)
+		}
+		if again := names(t, NewSubsetEndpointer[string, string](instances(all...), fmt.Sprintf("client-%d", c), SubsetSize(5))); !reflect.DeepEqual(subset, again) {
+			t.Fatalf("want %v, have %v", subset, again)
+		}
+		for _, name := range subset {
+			counts[name]++
+		}
+	}
+	for _, name := range all {
+		if n := counts[name]; n < 10 || n > 45 { // 25 is even
+			t.Errorf("%s is in %d subsets, which is uneven", name, n)
+		}
+	}
+
+	// Removing an instance outside a subset doesn't change it.
+	subset := names(t, NewSubsetEndpointer[string, string](instances(all...), "client-0", SubsetSize(5)))
+	var rest []string
+	for _, name := range all {
+		if name != firstNotIn(all, subset) {
+			rest = append(rest, name)
+		}
+	}
+	if have := names(t, NewSubsetEndpointer[string, string](instances(rest...), "client-0", SubsetSize(5))); !reflect.DeepEqual(subset, have) {
+		t.Errorf("want %v, have %v", subset, have)
+	}
+}
+
+func TestSubsetEndpointerZone(t *testing.T) {
+	endpoints := zoned("a", "b", "a", "b", "c")
+
+	s := NewSubsetEndpointer[string, string](endpoints, "client", SubsetInstanceZones(zoneOf), SubsetZone("a"))
+	if want, have := []string{"a-0", "a-2"}, names(t, s); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// Too few instances in the zone spill over to others.
+	s = NewSubsetEndpointer[string, string](endpoints, "client", SubsetInstanceZones(zoneOf), SubsetZone("c"), SubsetMinZoneInstances(2), SubsetSize(3))
+	have := names(t, s)
+	if want := 3; len(have) != want || !contains(have, "c-4") {
+		t.Errorf("want c-4 and 2 others, have %v", have)
+	}
+
+	// No instances in the zone spill over to all.
+	s = NewSubsetEndpointer[string, string](endpoints, "client", SubsetInstanceZones(zoneOf), SubsetZone("d"))
+	if want, have := 5, len(names(t, s)); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestSubsetEndpointerBalancer(t *testing.T) {
+	s := NewSubsetEndpointer[string, string](zoned("a", "b"), "client", SubsetInstanceZones(zoneOf), SubsetZone("b"))
+	balancer := NewRoundRobin[string, string](s)
+	for i := 0; i < 3; i++ {
+		e, err := balancer.Endpoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name, _ := e(context.Background(), ""); name != "b-1" {
+			t.Errorf("want b-1, have %s", name)
+		}
+	}
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func firstNotIn(all, subset []string) string {
+	for _, name := range all {
+		if !contains(subset, name) {
+			return name
+		}
+	}
+	return ""
+}
+
+func TestSubsetEndpointerMetadataZone(t *testing.T) {
+	endpoints := zoned("a", "b", "a")
+	for i := range endpoints {
+		endpoints[i].Metadata = sd.Metadata{sd.MetadataZone: zoneOf(endpoints[i].Instance)}
+	}
+	s := NewSubsetEndpointer[string, string](endpoints, "client", SubsetZone("a"))
+	if want, have := []string{"a-0", "a-2"}, names(t, s); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
@@ -0,0 +1,83 @@
+package lb
+
+import (
+	"sync"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/sd"
+)
+
+// NewWeightedRoundRobin returns a load balancer that returns services in
+// sequence, each in proportion to the weight of its instance, from its
+// metadata, or as given by the Weights option. The sequence is smooth: an instance of weight 3 among
+// instances of weight 1 is returned every other time, rather than 3 times in
+// a row.
+//
+// Instances are only known if the Endpointer is an sd.InstanceEndpointer, such
+// as the one returned by sd.NewEndpointer; otherwise, it's a round robin.
+func NewWeightedRoundRobin[I, O any](s sd.Endpointer[I, O], options ...WeightOption) Balancer[I, O] {
+	var opts weightOptions
+	for _, option := range options {
+		option(&opts)
+	}
+	return &weightedRoundRobin[I, O]{
+		s:       s,
+		weight:  opts.weight,
+		current: map[string]int{},
+	}
+}
+
+type weightedRoundRobin[I, O any] struct {
+	s       sd.Endpointer[I, O]
+	weight  WeightFunc
+	mtx     sync.Mutex
+	current map[string]int // by key
+}
+
+func (wrr *weightedRoundRobin[I, O]) Endpoint() (endpoint.Endpoint[I, O], error) {
+	endpoints, err := instanceEndpoints(wrr.s)
+	if err != nil {
+		return nil, err
+	}
+	if len(endpoints) <= 0 {
+		return nil, ErrNoEndpoints
+	}
+
+	wrr.mtx.Lock()
+	defer wrr.mtx.Unlock()
+	if len(wrr.current) > len(endpoints) {
+		wrr.prune(endpoints)
+	}
+
+	// Each endpoint gains its weight, and the endpoint with the most is
+	// picked, and loses the total.
+	var (
+		total   int
+		best    = -1
+		bestKey string
+	)
+	for i, e := range endpoints {
+		k := key(e, i)
+		w := weight(wrr.weight, e)
+		total += w
+		wrr.current[k] += w
+		if best < 0 || wrr.current[k] > wrr.current[bestKey] {
+			best, bestKey = i, k
+		}
+	}
+	wrr.current[bestKey] -= total
+	return endpoints[best].Endpoint, nil
+}
+
+// prune forgets the state of endpoints which have gone away.
+func (wrr *weightedRoundRobin[I, O]) prune(endpoints []sd.InstanceEndpoint[I, O]) {
+	keys := make(map[string]bool, len(endpoints))
+	for i, e := range endpoints {
+		keys[key(e, i)] = true
+	}
+	for k := range wrr.current {
+		if !keys[k] {
+			delete(wrr.current, k)
+		}
+	}
+}
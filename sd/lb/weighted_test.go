@@ -0,0 +1,75 @@
+package lb
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/sd"
+)
+
+// named returns an endpoint which responds with its name.
+func named(name string) endpoint.Endpoint[struct{}, string] {
+	return func(context.Context, struct{}) (string, error) { return name, nil }
+}
+
+func TestWeightedRoundRobin(t *testing.T) {
+	endpointer := sd.FixedInstanceEndpointer[struct{}, string]{
+		{Instance: "a", Endpoint: named("a")},
+		{Instance: "b", Endpoint: named("b")},
+		{Instance: "c", Endpoint: named("c")},
+	}
+	weights := map[string]int{"a": 3, "c": 1}
+	balancer := NewWeightedRoundRobin[struct{}, string](endpointer, Weights(func(instance string) int { return weights[instance] }))
+
+	var have []string
+	for i := 0; i < 10; i++ {
+		e, err := balancer.Endpoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		name, _ := e(context.Background(), struct{}{})
+		have = append(have, name)
+	}
+	if want := []string{"a", "b", "a", "c", "a", "a", "b", "a", "c", "a"}; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestWeightedRoundRobinUnweighted(t *testing.T) {
+	balancer := NewWeightedRoundRobin[struct{}, string](sd.FixedEndpointer[struct{}, string]{named("a"), named("b")})
+	var have []string
+	for i := 0; i < 4; i++ {
+		e, _ := balancer.Endpoint()
+		name, _ := e(context.Background(), struct{}{})
+		have = append(have, name)
+	}
+	if want := []string{"a", "b", "a", "b"}; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestWeightedRoundRobinNoEndpoints(t *testing.T) {
+	balancer := NewWeightedRoundRobin[struct{}, string](sd.FixedEndpointer[struct{}, string]{})
+	if _, err := balancer.Endpoint(); err != ErrNoEndpoints {
+		t.Errorf("want %v, have %v", ErrNoEndpoints, err)
+	}
+}
+
+func TestWeightedRoundRobinMetadata(t *testing.T) {
+	endpointer := sd.FixedInstanceEndpointer[struct{}, string]{
+		{Instance: "a", Metadata: sd.Metadata{sd.MetadataWeight: "2"}, Endpoint: named("a")},
+		{Instance: "b", Endpoint: named("b")},
+	}
+	balancer := NewWeightedRoundRobin[struct{}, string](endpointer)
+	var have []string
+	for i := 0; i < 6; i++ {
+		e, _ := balancer.Endpoint()
+		name, _ := e(context.Background(), struct{}{})
+		have = append(have, name)
+	}
+	if want := []string{"a", "b", "a", "a", "b", "a"}; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
@@ -0,0 +1,42 @@
+package sd
+
+import "strconv"
+
+// Metadata is the metadata of an instance, as labels, e.g. {"weight": "3"}.
+// Instancers which know more about instances than their addresses may provide
+// it with each Event. Metadata is shared by observers, and must not be
+// modified.
+type Metadata map[string]string
+
+// Well-known metadata labels.
+const (
+	// MetadataWeight is the relative weight of an instance, as a positive
+	// integer, for weighted load balancing.
+	MetadataWeight = "weight"
+
+	// MetadataZone is the zone of an instance, e.g. "eu-west-1a", for
+	// zone-aware load balancing.
+	MetadataZone = "zone"
+
+	// MetadataVersion is the version of an instance, e.g. "1.4.2", for canary
+	// routing.
+	MetadataVersion = "version"
+)
+
+// Weight returns the weight of the instance, or 1 if it has no valid weight.
+func (m Metadata) Weight() int {
+	if w, err := strconv.Atoi(m[MetadataWeight]); err == nil && w > 0 {
+		return w
+	}
+	return 1
+}
+
+// Zone returns the zone of the instance, or "" if it's unknown.
+func (m Metadata) Zone() string {
+	return m[MetadataZone]
+}
+
+// Version returns the version of the instance, or "" if it's unknown.
+func (m Metadata) Version() string {
+	return m[MetadataVersion]
+}
@@ -0,0 +1,52 @@
+package sd
+
+import "testing"
+
+func TestMetadataWeight(t *testing.T) {
+	for _, tc := range []struct {
+		m    Metadata
+		want int
+	}{
+		{nil, 1},
+		{Metadata{}, 1},
+		{Metadata{MetadataWeight: "5"}, 5},
+		{Metadata{MetadataWeight: "0"}, 1},
+		{Metadata{MetadataWeight: "-2"}, 1},
+		{Metadata{MetadataWeight: "heavy"}, 1},
+	} {
+		if have := tc.m.Weight(); tc.want != have {
+			t.Errorf("%v: want %d, have %d", tc.m, tc.want, have)
+		}
+	}
+}
+
+func TestMetadataZone(t *testing.T) {
+	if want, have := "eu-west-1a", (Metadata{MetadataZone: "eu-west-1a"}).Zone(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "", Metadata(nil).Zone(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestMetadataVersion(t *testing.T) {
+	if want, have := "1.4.2", (Metadata{MetadataVersion: "1.4.2"}).Version(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestEventMetadataOf(t *testing.T) {
+	e := Event{
+		Instances: []string{"a:80", "b:80"},
+		Metadata:  map[string]Metadata{"a:80": {MetadataWeight: "2"}},
+	}
+	if want, have := 2, e.MetadataOf("a:80").Weight(); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := 1, e.MetadataOf("b:80").Weight(); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := "", (Event{}).MetadataOf("a:80").Zone(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
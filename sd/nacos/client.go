@@ -0,0 +1,197 @@
+package nacos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultGroup is the group of services registered without one.
+const DefaultGroup = "DEFAULT_GROUP"
+
+// codeNotFound is the code Nacos returns to a beat from an unknown instance.
+const codeNotFound = 20404
+
+// ErrNotFound is returned by SendBeat if Nacos doesn't know the instance,
+// e.g. because it missed too many beats, and it must be registered again.
+var ErrNotFound = errors.New("instance not found")
+
+// Instance is an instance of a service.
+type Instance struct {
+	InstanceID  string            `json:"instanceId,omitempty"`
+	IP          string            `json:"ip"`
+	Port        int               `json:"port"`
+	Weight      float64           `json:"weight"`
+	Enabled     bool              `json:"enabled"`
+	Healthy     bool              `json:"healthy"`
+	Ephemeral   bool              `json:"ephemeral"`
+	ClusterName string            `json:"clusterName,omitempty"`
+	ServiceName string            `json:"serviceName,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// ServiceInfo is the instances of a service.
+type ServiceInfo struct {
+	Name        string      `json:"name"`
+	Clusters    string      `json:"clusters"`
+	Hosts       []*Instance `json:"hosts"`
+	CacheMillis int64       `json:"cacheMillis"`
+	Checksum    string      `json:"checksum"`
+}
+
+// Client is a wrapper around the Nacos naming Open API.
+type Client interface {
+	// RegisterInstance registers an instance of the service in the group.
+	RegisterInstance(ctx context.Context, service, group string, i *Instance) error
+
+	// DeregisterInstance deregisters an instance of the service in the group.
+	DeregisterInstance(ctx context.Context, service, group string, i *Instance) error
+
+	// SendBeat renews an ephemeral instance, and returns the interval at
+	// which Nacos expects beats, if it says. It returns ErrNotFound if the
+	// instance isn't registered.
+	SendBeat(ctx context.Context, service, group string, i *Instance) (time.Duration, error)
+
+	// Instances returns the instances of the service in the group, in any of
+	// the clusters, or all clusters if none are given.
+	Instances(ctx context.Context, service, group string, clusters []string, healthyOnly bool) (*ServiceInfo, error)
+}
+
+type client struct {
+	address   string
+	http      *http.Client
+	namespace string
+}
+
+// NewClient returns a Client for the Nacos server at the address, including
+// the context path, e.g. http://127.0.0.1:8848/nacos, in the namespace, or the
+// public namespace if it's empty. If httpClient is nil, http.DefaultClient is
+// used.
+func NewClient(address string, httpClient *http.Client, namespace string) Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &client{
+		address:   strings.TrimSuffix(address, "/"),
+		http:      httpClient,
+		namespace: namespace,
+	}
+}
+
+func (c *client) query(service, group string) url.Values {
+	if group == "" {
+		group = DefaultGroup
+	}
+	query := url.Values{
+		"serviceName": {service},
+		"groupName":   {group},
+	}
+	if c.namespace != "" {
+		query.Set("namespaceId", c.namespace)
+	}
+	return query
+}
+
+func (c *client) instanceQuery(service, group string, i *Instance) url.Values {
+	query := c.query(service, group)
+	query.Set("ip", i.IP)
+	query.Set("port", strconv.Itoa(i.Port))
+	query.Set("ephemeral", strconv.FormatBool(i.Ephemeral))
+	if i.ClusterName != "" {
+		query.Set("clusterName", i.ClusterName)
+	}
+	return query
+}
+
+func (c *client) RegisterInstance(ctx context.Context, service, group string, i *Instance) error {
+	query := c.instanceQuery(service, group, i)
+	query.Set("weight", strconv.FormatFloat(i.Weight, 'f', -1, 64))
+	query.Set("enabled", strconv.FormatBool(i.Enabled))
+	query.Set("healthy", strconv.FormatBool(i.Healthy))
+	if len(i.Metadata) > 0 {
+		metadata, err := json.Marshal(i.Metadata)
+		if err != nil {
+			return err
+		}
+		query.Set("metadata", string(metadata))
+	}
+	return c.do(ctx, "POST", "/v1/ns/instance", query, nil)
+}
+
+func (c *client) DeregisterInstance(ctx context.Context, service, group string, i *Instance) error {
+	query := c.instanceQuery(service, group, i)
+	return c.do(ctx, "DELETE", "/v1/ns/instance", query, nil)
+}
+
+func (c *client) SendBeat(ctx context.Context, service, group string, i *Instance) (time.Duration, error) {
+	if group == "" {
+		group = DefaultGroup
+	}
+	beat, err := json.Marshal(struct {
+		ServiceName string            `json:"serviceName"`
+		IP          string            `json:"ip"`
+		Port        int               `json:"port"`
+		Weight      float64           `json:"weight"`
+		Cluster     string            `json:"cluster,omitempty"`
+		Metadata    map[string]string `json:"metadata,omitempty"`
+		Scheduled   bool              `json:"scheduled"`
+	}{group + "@@" + service, i.IP, i.Port, i.Weight, i.ClusterName, i.Metadata, false})
+	if err != nil {
+		return 0, err
+	}
+	query := c.query(service, group)
+	query.Set("beat", string(beat))
+	var resp struct {
+		ClientBeatInterval int64 `json:"clientBeatInterval"`
+		Code               int   `json:"code"`
+	}
+	if err := c.do(ctx, "PUT", "/v1/ns/instance/beat", query, &resp); err != nil {
+		return 0, err
+	}
+	if resp.Code == codeNotFound {
+		return 0, ErrNotFound
+	}
+	return time.Duration(resp.ClientBeatInterval) * time.Millisecond, nil
+}
+
+func (c *client) Instances(ctx context.Context, service, group string, clusters []string, healthyOnly bool) (*ServiceInfo, error) {
+	query := c.query(service, group)
+	if len(clusters) > 0 {
+		query.Set("clusters", strings.Join(clusters, ","))
+	}
+	query.Set("healthyOnly", strconv.FormatBool(healthyOnly))
+	var info ServiceInfo
+	if err := c.do(ctx, "GET", "/v1/ns/instance/list", query, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (c *client) do(ctx context.Context, method, path string, query url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.address+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("nacos returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,3 @@
+// Package nacos provides Instancer and Registrar implementations for Nacos,
+// with a small client for the parts of its naming Open API they use.
+package nacos
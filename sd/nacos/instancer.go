@@ -0,0 +1,156 @@
+package nacos
+
+import (
+	"context"
+	"math"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/barrett370/kit/v2/sd"
+	"github.com/barrett370/kit/v2/sd/internal/instance"
+)
+
+// DefaultRefreshInterval is the interval between queries, if Nacos doesn't
+// say how long to cache the instances of a service.
+const DefaultRefreshInterval = 10 * time.Second
+
+// InstancerOption sets an optional parameter for Instancers.
+type InstancerOption func(*Instancer)
+
+// InstancerGroup sets the group of the service. By default, it's
+// DefaultGroup.
+func InstancerGroup(group string) InstancerOption {
+	return func(s *Instancer) { s.group = group }
+}
+
+// InstancerClusters yields only instances in the clusters.
+func InstancerClusters(clusters ...string) InstancerOption {
+	return func(s *Instancer) { s.clusters = clusters }
+}
+
+// InstancerRefreshInterval sets the interval between queries, overriding the
+// cache time Nacos returns with the instances.
+func InstancerRefreshInterval(d time.Duration) InstancerOption {
+	return func(s *Instancer) { s.refresh = d }
+}
+
+// Instancer yields instances of a service in Nacos. It queries the service
+// as often as Nacos says the instances may be cached for, as the Nacos
+// clients do when they don't receive pushes.
+//
+// Only instances which are healthy, enabled, and have a positive weight are
+// yielded.
+type Instancer struct {
+	cache    *instance.Cache
+	client   Client
+	service  string
+	group    string
+	clusters []string
+	refresh  time.Duration
+	logger   log.Logger
+	quit     chan struct{}
+	done     chan struct{}
+}
+
+// NewInstancer returns a Nacos Instancer for the service.
+func NewInstancer(client Client, service string, logger log.Logger, options ...InstancerOption) *Instancer {
+	s := &Instancer{
+		cache:   instance.NewCache(),
+		client:  client,
+		service: service,
+		group:   DefaultGroup,
+		logger:  log.With(logger, "service", service),
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	s.logger = log.With(s.logger, "group", s.group)
+
+	event, next := s.getInstances()
+	if event.Err == nil {
+		s.logger.Log("instances", len(event.Instances))
+	} else {
+		s.logger.Log("err", event.Err)
+	}
+	s.cache.Update(event)
+	go s.loop(next)
+	return s
+}
+
+func (s *Instancer) loop(next time.Duration) {
+	defer close(s.done)
+	timer := time.NewTimer(next)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			event, d := s.getInstances()
+			if event.Err != nil {
+				s.logger.Log("err", event.Err)
+			}
+			s.cache.Update(event)
+			timer.Reset(d)
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// getInstances returns the instances of the service, with their metadata, and
+// the interval until they're queried again. Nacos weights are decimals,
+// usually around 1, so they're scaled by 100 into the weight label.
+func (s *Instancer) getInstances() (sd.Event, time.Duration) {
+	next := s.refresh
+	if next == 0 {
+		next = DefaultRefreshInterval
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), next)
+	defer cancel()
+	info, err := s.client.Instances(ctx, s.service, s.group, s.clusters, true)
+	if err != nil {
+		return sd.Event{Err: err}, next
+	}
+	if s.refresh == 0 && info.CacheMillis > 0 {
+		next = time.Duration(info.CacheMillis) * time.Millisecond
+	}
+
+	var (
+		instances []string
+		metadata  = map[string]sd.Metadata{}
+	)
+	for _, i := range info.Hosts {
+		if !i.Healthy || !i.Enabled || i.Weight <= 0 {
+			continue
+		}
+		instance := net.JoinHostPort(i.IP, strconv.Itoa(i.Port))
+		instances = append(instances, instance)
+		m := make(sd.Metadata, len(i.Metadata)+1)
+		for k, v := range i.Metadata {
+			m[k] = v
+		}
+		m[sd.MetadataWeight] = strconv.Itoa(int(math.Max(1, math.Round(i.Weight*100))))
+		metadata[instance] = m
+	}
+	return sd.Event{Instances: instances, Metadata: metadata}, next
+}
+
+// Stop terminates the Instancer.
+func (s *Instancer) Stop() {
+	close(s.quit)
+	<-s.done
+}
+
+// Register implements Instancer.
+func (s *Instancer) Register(ch chan<- sd.Event) {
+	s.cache.Register(ch)
+}
+
+// Deregister implements Instancer.
+func (s *Instancer) Deregister(ch chan<- sd.Event) {
+	s.cache.Deregister(ch)
+}
@@ -0,0 +1,91 @@
+package nacos
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/barrett370/kit/v2/sd"
+)
+
+var _ sd.Instancer = (*Instancer)(nil) // API check
+
+type testClient struct {
+	mtx     sync.Mutex
+	info    *ServiceInfo
+	queries []string // group and clusters of each query
+}
+
+func (c *testClient) Instances(ctx context.Context, service, group string, clusters []string, healthyOnly bool) (*ServiceInfo, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.queries = append(c.queries, group+" "+strings.Join(clusters, ","))
+	return c.info, nil
+}
+
+func (c *testClient) set(info *ServiceInfo) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.info = info
+}
+
+func (c *testClient) RegisterInstance(ctx context.Context, service, group string, i *Instance) error {
+	return nil
+}
+
+func (c *testClient) DeregisterInstance(ctx context.Context, service, group string, i *Instance) error {
+	return nil
+}
+
+func (c *testClient) SendBeat(ctx context.Context, service, group string, i *Instance) (time.Duration, error) {
+	return 0, nil
+}
+
+func TestInstancer(t *testing.T) {
+	client := &testClient{info: &ServiceInfo{
+		CacheMillis: 5,
+		Hosts: []*Instance{
+			{IP: "10.0.0.1", Port: 80, Weight: 1, Enabled: true, Healthy: true},
+			{IP: "10.0.0.2", Port: 80, Weight: 1, Enabled: false, Healthy: true},
+			{IP: "10.0.0.3", Port: 80, Weight: 0, Enabled: true, Healthy: true},
+			{IP: "10.0.0.4", Port: 80, Weight: 1, Enabled: true, Healthy: false},
+		},
+	}}
+	s := NewInstancer(client, "orders", log.NewNopLogger(), InstancerGroup("shop"), InstancerClusters("a", "b"))
+	defer s.Stop()
+
+	events := make(chan sd.Event, 1)
+	s.Register(events)
+	defer s.Deregister(events)
+	if want, have := []string{"10.0.0.1:80"}, (<-events).Instances; !reflect.DeepEqual(want, have) {
+		t.Fatalf("want %v, have %v", want, have)
+	}
+
+	// The next query is after the cache time Nacos returned.
+	client.set(&ServiceInfo{CacheMillis: 5, Hosts: []*Instance{
+		{IP: "10.0.0.1", Port: 80, Weight: 1, Enabled: true, Healthy: true},
+		{IP: "10.0.0.5", Port: 80, Weight: 0.5, Enabled: true, Healthy: true, Metadata: map[string]string{"version": "1.1"}},
+	}})
+	select {
+	case e := <-events:
+		if want, have := []string{"10.0.0.1:80", "10.0.0.5:80"}, e.Instances; !reflect.DeepEqual(want, have) {
+			t.Errorf("want %v, have %v", want, have)
+		}
+		if want, have := (sd.Metadata{"version": "1.1", "weight": "50"}), e.MetadataOf("10.0.0.5:80"); !reflect.DeepEqual(want, have) {
+			t.Errorf("want %v, have %v", want, have)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for update")
+	}
+
+	client.mtx.Lock()
+	defer client.mtx.Unlock()
+	if want, have := "shop a,b", client.queries[0]; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
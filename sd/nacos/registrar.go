@@ -0,0 +1,130 @@
+package nacos
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+// DefaultBeatInterval is the interval between beats, until Nacos says
+// otherwise.
+const DefaultBeatInterval = 5 * time.Second
+
+// RegistrarOption sets an optional parameter for Registrars.
+type RegistrarOption func(*Registrar)
+
+// RegistrarGroup sets the group of the service. By default, it's
+// DefaultGroup.
+func RegistrarGroup(group string) RegistrarOption {
+	return func(r *Registrar) { r.group = group }
+}
+
+// RegistrarHeartbeat sets the interval between beats, overriding the
+// interval Nacos returns.
+func RegistrarHeartbeat(interval time.Duration) RegistrarOption {
+	return func(r *Registrar) { r.fixed = interval }
+}
+
+// Registrar registers service instance liveness information to Nacos. While
+// an ephemeral instance is registered, it sends beats at the interval Nacos
+// asks for, and registers the instance again if Nacos has expired it.
+// Persistent instances are health checked by Nacos, and get no beats.
+type Registrar struct {
+	client   Client
+	service  string
+	group    string
+	instance *Instance
+	logger   log.Logger
+	fixed    time.Duration // if set, the beat interval
+
+	mtx  sync.Mutex
+	quit chan struct{}
+	done chan struct{}
+}
+
+// NewRegistrar returns a Nacos Registrar acting on the instance of the
+// service.
+func NewRegistrar(client Client, service string, i *Instance, logger log.Logger, options ...RegistrarOption) *Registrar {
+	r := &Registrar{
+		client:   client,
+		service:  service,
+		group:    DefaultGroup,
+		instance: i,
+		logger:   log.With(logger, "service", service, "ip", i.IP, "port", i.Port),
+	}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// Register implements sd.Registrar.
+func (r *Registrar) Register() {
+	if err := r.client.RegisterInstance(context.Background(), r.service, r.group, r.instance); err != nil {
+		r.logger.Log("during", "register", "err", err)
+		return
+	}
+	r.logger.Log("action", "register")
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.instance.Ephemeral && r.quit == nil {
+		r.quit, r.done = make(chan struct{}), make(chan struct{})
+		go r.loop(r.quit, r.done)
+	}
+}
+
+// Deregister implements sd.Registrar.
+func (r *Registrar) Deregister() {
+	r.mtx.Lock()
+	if r.quit != nil {
+		close(r.quit)
+		<-r.done
+		r.quit, r.done = nil, nil
+	}
+	r.mtx.Unlock()
+
+	if err := r.client.DeregisterInstance(context.Background(), r.service, r.group, r.instance); err != nil {
+		r.logger.Log("during", "deregister", "err", err)
+	} else {
+		r.logger.Log("action", "deregister")
+	}
+}
+
+func (r *Registrar) loop(quit, done chan struct{}) {
+	defer close(done)
+	interval := DefaultBeatInterval
+	if r.fixed > 0 {
+		interval = r.fixed
+	}
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			if d := r.beat(interval); d > 0 && r.fixed == 0 {
+				interval = d
+			}
+			timer.Reset(interval)
+		case <-quit:
+			return
+		}
+	}
+}
+
+// beat sends a beat, and returns the interval Nacos asks for, if any.
+func (r *Registrar) beat(timeout time.Duration) time.Duration {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	d, err := r.client.SendBeat(ctx, r.service, r.group, r.instance)
+	if err == ErrNotFound {
+		r.logger.Log("during", "beat", "err", err, "action", "reregister")
+		err = r.client.RegisterInstance(ctx, r.service, r.group, r.instance)
+	}
+	if err != nil {
+		r.logger.Log("during", "beat", "err", err)
+	}
+	return d
+}
@@ -0,0 +1,144 @@
+package nacos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/barrett370/kit/v2/sd"
+)
+
+var _ sd.Registrar = (*Registrar)(nil) // API check
+
+// fakeServer is a Nacos server which forgets instances when expired is set.
+type fakeServer struct {
+	mtx      sync.Mutex
+	requests []string
+	queries  []string
+	expired  bool
+}
+
+func (s *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	s.queries = append(s.queries, r.URL.RawQuery)
+	switch {
+	case r.Method == "POST":
+		s.expired = false
+		fmt.Fprint(w, "ok")
+	case r.URL.Path == "/nacos/v1/ns/instance/beat":
+		var beat map[string]interface{}
+		if err := json.Unmarshal([]byte(r.URL.Query().Get("beat")), &beat); err != nil || beat["serviceName"] != "shop@@orders" {
+			http.Error(w, "bad beat", http.StatusBadRequest)
+			return
+		}
+		code := 10200
+		if s.expired {
+			code = codeNotFound
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"clientBeatInterval": 5, "code": code})
+	default:
+		fmt.Fprint(w, "ok")
+	}
+}
+
+func (s *fakeServer) count(request string) int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	var n int
+	for _, r := range s.requests {
+		if r == request {
+			n++
+		}
+	}
+	return n
+}
+
+func TestRegistrarBeat(t *testing.T) {
+	server := &fakeServer{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	client := NewClient(ts.URL+"/nacos", nil, "dev")
+	i := &Instance{IP: "10.0.0.1", Port: 80, Weight: 1, Enabled: true, Healthy: true, Ephemeral: true, Metadata: map[string]string{"zone": "a"}}
+	r := NewRegistrar(client, "orders", i, log.NewNopLogger(), RegistrarGroup("shop"), RegistrarHeartbeat(5*time.Millisecond))
+	r.Register()
+	waitFor(t, "beats", func() bool { return server.count("PUT /nacos/v1/ns/instance/beat") >= 2 })
+
+	// An expired instance is registered again.
+	server.mtx.Lock()
+	server.expired = true
+	server.mtx.Unlock()
+	waitFor(t, "registration", func() bool { return server.count("POST /nacos/v1/ns/instance") == 2 })
+
+	r.Deregister()
+	beats := server.count("PUT /nacos/v1/ns/instance/beat")
+	time.Sleep(20 * time.Millisecond)
+	if want, have := beats, server.count("PUT /nacos/v1/ns/instance/beat"); want != have {
+		t.Errorf("beats after Deregister: want %d, have %d", want, have)
+	}
+	if want, have := 1, server.count("DELETE /nacos/v1/ns/instance"); want != have {
+		t.Errorf("deregister: want %d, have %d", want, have)
+	}
+
+	server.mtx.Lock()
+	defer server.mtx.Unlock()
+	want := "enabled=true&ephemeral=true&groupName=shop&healthy=true&ip=10.0.0.1&metadata=%7B%22zone%22%3A%22a%22%7D&namespaceId=dev&port=80&serviceName=orders&weight=1"
+	if have := server.queries[0]; want != have {
+		t.Errorf("register query: want %q, have %q", want, have)
+	}
+}
+
+func TestRegistrarPersistent(t *testing.T) {
+	server := &fakeServer{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	r := NewRegistrar(NewClient(ts.URL+"/nacos", nil, ""), "orders", &Instance{IP: "10.0.0.1", Port: 80}, log.NewNopLogger(), RegistrarHeartbeat(time.Millisecond))
+	r.Register()
+	time.Sleep(20 * time.Millisecond)
+	r.Deregister()
+	if want, have := 0, server.count("PUT /nacos/v1/ns/instance/beat"); want != have {
+		t.Errorf("beats: want %d, have %d", want, have)
+	}
+}
+
+func TestClientInstances(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if want, have := "clusters=a%2Cb&groupName=DEFAULT_GROUP&healthyOnly=true&serviceName=orders", r.URL.RawQuery; want != have {
+			t.Errorf("want %q, have %q", want, have)
+		}
+		w.Write([]byte(`{"name":"DEFAULT_GROUP@@orders","cacheMillis":3000,"hosts":[{"ip":"10.0.0.1","port":80,"weight":1.0,"healthy":true,"enabled":true}]}`))
+	}))
+	defer ts.Close()
+
+	info, err := NewClient(ts.URL, nil, "").Instances(context.Background(), "orders", "", []string{"a", "b"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := int64(3000), info.CacheMillis; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := "10.0.0.1", info.Hosts[0].IP; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func waitFor(t *testing.T, what string, f func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !f() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,163 @@
+package sd
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+// DefaultCheckInterval is the interval between readiness checks of a
+// RegistrarGroup.
+const DefaultCheckInterval = 5 * time.Second
+
+// Check reports whether an instance is ready to serve requests, e.g. whether
+// it's connected to its database, by returning nil.
+type Check func(ctx context.Context) error
+
+// RegistrarGroupOption sets an optional parameter for RegistrarGroups.
+type RegistrarGroupOption func(*RegistrarGroup)
+
+// RegistrarGroupChecks sets the readiness checks which must pass before the
+// instance is registered. By default, there are none, and the instance is
+// registered at once.
+func RegistrarGroupChecks(checks ...Check) RegistrarGroupOption {
+	return func(g *RegistrarGroup) { g.checks = checks }
+}
+
+// RegistrarGroupCheckInterval sets the interval between readiness checks,
+// which is also the timeout of each round of checks. By default, it's
+// DefaultCheckInterval.
+func RegistrarGroupCheckInterval(d time.Duration) RegistrarGroupOption {
+	return func(g *RegistrarGroup) { g.interval = d }
+}
+
+// RegistrarGroupRefresh registers the instance again at the interval while
+// it's registered, so it's restored after an outage of a backend which lost
+// it, e.g. Consul or etcd. Registrars must allow repeated registrations,
+// which all the Registrars in package sd do. The Eureka and Nacos Registrars
+// restore their registrations themselves, and don't need it. By default,
+// it's disabled.
+func RegistrarGroupRefresh(d time.Duration) RegistrarGroupOption {
+	return func(g *RegistrarGroup) { g.refresh = d }
+}
+
+// RegistrarGroup manages the registration of an instance with one or more
+// Registrars, e.g. with both Consul and Eureka during a migration, through
+// its lifecycle: it registers the instance once its readiness checks pass,
+// deregisters it if they fail, and registers it again once they pass again.
+// Heartbeats are sent by the Registrars themselves while the instance is
+// registered. At shutdown, it deregisters the instance, so that clients stop
+// sending requests to it before it stops serving them.
+type RegistrarGroup struct {
+	registrars []Registrar
+	logger     log.Logger
+	checks     []Check
+	interval   time.Duration
+	refresh    time.Duration
+
+	mtx        sync.Mutex
+	registered bool
+}
+
+// NewRegistrarGroup returns a RegistrarGroup of the Registrars, which does
+// nothing until it's Run.
+func NewRegistrarGroup(registrars []Registrar, logger log.Logger, options ...RegistrarGroupOption) *RegistrarGroup {
+	g := &RegistrarGroup{
+		registrars: registrars,
+		logger:     logger,
+		interval:   DefaultCheckInterval,
+	}
+	for _, option := range options {
+		option(g)
+	}
+	return g
+}
+
+// Run manages the registration of the instance until the context is done,
+// e.g. when the process receives SIGTERM, as with signal.NotifyContext, and
+// then deregisters it and returns the context's error. It's meant to run for
+// the life of the process, alongside its servers, which should shut down
+// after Run returns.
+func (g *RegistrarGroup) Run(ctx context.Context) error {
+	var last time.Time // registration
+	defer func() {
+		if g.Registered() {
+			g.deregister()
+		}
+	}()
+	for {
+		ready := g.ready(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		switch registered := g.Registered(); {
+		case ready && !registered:
+			g.register()
+			last = time.Now()
+		case ready && g.refresh > 0 && time.Since(last) >= g.refresh:
+			g.register()
+			last = time.Now()
+		case !ready && registered:
+			g.deregister()
+		}
+
+		d := g.interval
+		if len(g.checks) == 0 {
+			if g.refresh <= 0 {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			d = g.refresh
+		}
+		t := time.NewTimer(d)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// Registered returns true if the instance is registered, e.g. for a health
+// endpoint.
+func (g *RegistrarGroup) Registered() bool {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	return g.registered
+}
+
+// ready runs the checks, and returns true if they all pass.
+func (g *RegistrarGroup) ready(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, g.interval)
+	defer cancel()
+	for _, check := range g.checks {
+		if err := check(ctx); err != nil {
+			g.logger.Log("during", "check", "err", err)
+			return false
+		}
+	}
+	return true
+}
+
+func (g *RegistrarGroup) register() {
+	for _, r := range g.registrars {
+		r.Register()
+	}
+	g.mtx.Lock()
+	g.registered = true
+	g.mtx.Unlock()
+}
+
+// deregister deregisters from the Registrars in the reverse order they were
+// registered with.
+func (g *RegistrarGroup) deregister() {
+	g.mtx.Lock()
+	g.registered = false
+	g.mtx.Unlock()
+	for i := len(g.registrars) - 1; i >= 0; i-- {
+		g.registrars[i].Deregister()
+	}
+}
@@ -0,0 +1,124 @@
+package sd
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+type testRegistrar struct {
+	mtx     *sync.Mutex
+	name    string
+	actions *[]string
+}
+
+func (r testRegistrar) Register()   { r.record("register " + r.name) }
+func (r testRegistrar) Deregister() { r.record("deregister " + r.name) }
+
+func (r testRegistrar) record(action string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	*r.actions = append(*r.actions, action)
+}
+
+func testRegistrars(names ...string) ([]Registrar, func() []string) {
+	var (
+		mtx        sync.Mutex
+		actions    []string
+		registrars []Registrar
+	)
+	for _, name := range names {
+		registrars = append(registrars, testRegistrar{&mtx, name, &actions})
+	}
+	return registrars, func() []string {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return append([]string(nil), actions...)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRegistrarGroup(t *testing.T) {
+	var (
+		registrars, actions = testRegistrars("a", "b")
+		ready               int32
+		check               = func(context.Context) error {
+			if atomic.LoadInt32(&ready) == 0 {
+				return errors.New("not ready")
+			}
+			return nil
+		}
+		g           = NewRegistrarGroup(registrars, log.NewNopLogger(), RegistrarGroupChecks(check), RegistrarGroupCheckInterval(time.Millisecond))
+		ctx, cancel = context.WithCancel(context.Background())
+		errc        = make(chan error, 1)
+	)
+	go func() { errc <- g.Run(ctx) }()
+
+	// Nothing is registered until the instance is ready.
+	time.Sleep(10 * time.Millisecond)
+	if g.Registered() || len(actions()) != 0 {
+		t.Fatalf("registered before ready: %v", actions())
+	}
+	atomic.StoreInt32(&ready, 1)
+	waitFor(t, g.Registered)
+
+	// A failing check deregisters it, and passing again registers it again.
+	atomic.StoreInt32(&ready, 0)
+	waitFor(t, func() bool { return !g.Registered() })
+	atomic.StoreInt32(&ready, 1)
+	waitFor(t, g.Registered)
+
+	// Shutdown deregisters it.
+	cancel()
+	if want, have := context.Canceled, <-errc; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	want := []string{
+		"register a", "register b",
+		"deregister b", "deregister a",
+		"register a", "register b",
+		"deregister b", "deregister a",
+	}
+	if have := actions(); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestRegistrarGroupRefresh(t *testing.T) {
+	var (
+		registrars, actions = testRegistrars("a")
+		g                   = NewRegistrarGroup(registrars, log.NewNopLogger(), RegistrarGroupRefresh(time.Millisecond))
+		ctx, cancel         = context.WithCancel(context.Background())
+		errc                = make(chan error, 1)
+	)
+	go func() { errc <- g.Run(ctx) }()
+	waitFor(t, func() bool { return len(actions()) >= 3 })
+	cancel()
+	<-errc
+
+	have := actions()
+	if want := "deregister a"; have[len(have)-1] != want {
+		t.Errorf("want %q last, have %v", want, have)
+	}
+	for _, action := range have[:len(have)-1] {
+		if action != "register a" {
+			t.Errorf("want only registrations before shutdown, have %v", have)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+// Package shadow provides an endpoint middleware which duplicates requests to
+// a shadow endpoint, e.g. a rewrite of a service, and compares its responses
+// with those of the production endpoint, so the rewrite can be verified with
+// production traffic without affecting it.
+package shadow
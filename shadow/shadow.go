@@ -0,0 +1,175 @@
+package shadow
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"time"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/metrics"
+)
+
+// DefaultTimeout is the default timeout of shadow requests.
+const DefaultTimeout = 5 * time.Second
+
+// DefaultMaxConcurrent is the default limit of concurrent shadow requests.
+const DefaultMaxConcurrent = 100
+
+// Comparison is the outcome of a shadowed request, from both endpoints.
+type Comparison[O any] struct {
+	Primary         O
+	PrimaryErr      error
+	PrimaryDuration time.Duration
+	Shadow          O
+	ShadowErr       error
+	ShadowDuration  time.Duration
+
+	// Match is true if the responses are equal, and both endpoints either
+	// failed or succeeded.
+	Match bool
+}
+
+// Option sets an optional parameter for the middleware.
+type Option[O any] func(*options[O])
+
+type options[O any] struct {
+	fraction      float64
+	timeout       time.Duration
+	maxConcurrent int
+	equal         func(primary, shadow O) bool
+	compare       func(ctx context.Context, c Comparison[O])
+	results       metrics.Counter
+	duration      metrics.Histogram
+}
+
+// Fraction sets the fraction of requests which are shadowed, from 0 to 1.
+// By default, it's 1, and all requests are shadowed.
+func Fraction[O any](f float64) Option[O] {
+	return func(o *options[O]) { o.fraction = f }
+}
+
+// Timeout sets the timeout of shadow requests, which don't end with their
+// primary requests. By default, it's DefaultTimeout.
+func Timeout[O any](d time.Duration) Option[O] {
+	return func(o *options[O]) { o.timeout = d }
+}
+
+// MaxConcurrent limits the number of concurrent shadow requests, so a slow
+// shadow endpoint can't pile up goroutines. Requests are not shadowed while
+// it's reached. By default, it's DefaultMaxConcurrent.
+func MaxConcurrent[O any](n int) Option[O] {
+	return func(o *options[O]) { o.maxConcurrent = n }
+}
+
+// Equal sets the func which compares responses, e.g. to ignore timestamps or
+// IDs which differ by design. By default, it's reflect.DeepEqual.
+func Equal[O any](equal func(primary, shadow O) bool) Option[O] {
+	return func(o *options[O]) { o.equal = equal }
+}
+
+// Compare sets a func which is called with the Comparison of each shadowed
+// request, e.g. to log the diffs of mismatches. The context carries the
+// values of the request's context. It's called on the shadow's goroutine.
+func Compare[O any](f func(ctx context.Context, c Comparison[O])) Option[O] {
+	return func(o *options[O]) { o.compare = f }
+}
+
+// Metrics sets the metrics of shadowed requests. Results are counted with
+// the label "result", which is "match", "mismatch", or "dropped" for
+// requests which weren't shadowed due to MaxConcurrent. The durations of
+// shadow requests are observed in seconds. Either may be nil.
+func Metrics[O any](results metrics.Counter, duration metrics.Histogram) Option[O] {
+	return func(o *options[O]) { o.results, o.duration = results, duration }
+}
+
+// NewMiddleware returns an endpoint middleware which sends a fraction of
+// requests to the shadow endpoint as well as the next endpoint. Shadow
+// requests run concurrently, and their responses and errors are discarded,
+// after they're compared with those of the next endpoint. The next endpoint
+// is never delayed by the shadow, and the request must not be modified by
+// either of them.
+func NewMiddleware[I, O any](shadow endpoint.Endpoint[I, O], opts ...Option[O]) endpoint.Middleware[I, O] {
+	o := options[O]{
+		fraction:      1,
+		timeout:       DefaultTimeout,
+		maxConcurrent: DefaultMaxConcurrent,
+		equal:         func(primary, shadow O) bool { return reflect.DeepEqual(primary, shadow) },
+	}
+	for _, option := range opts {
+		option(&o)
+	}
+	sem := make(chan struct{}, o.maxConcurrent)
+
+	type result struct {
+		response O
+		err      error
+		duration time.Duration
+	}
+
+	return func(next endpoint.Endpoint[I, O]) endpoint.Endpoint[I, O] {
+		return func(ctx context.Context, request I) (O, error) {
+			if o.fraction <= 0 || (o.fraction < 1 && rand.Float64() >= o.fraction) {
+				return next(ctx, request)
+			}
+			select {
+			case sem <- struct{}{}:
+			default:
+				if o.results != nil {
+					o.results.With("result", "dropped").Add(1)
+				}
+				return next(ctx, request)
+			}
+
+			primary := make(chan result, 1)
+			go func() {
+				defer func() { <-sem }()
+				sctx, cancel := context.WithTimeout(detached{ctx}, o.timeout)
+				defer cancel()
+				begin := time.Now()
+				response, err := shadow(sctx, request)
+				s := result{response, err, time.Since(begin)}
+				if o.duration != nil {
+					o.duration.Observe(s.duration.Seconds())
+				}
+				p := <-primary
+
+				c := Comparison[O]{
+					Primary:         p.response,
+					PrimaryErr:      p.err,
+					PrimaryDuration: p.duration,
+					Shadow:          s.response,
+					ShadowErr:       s.err,
+					ShadowDuration:  s.duration,
+				}
+				c.Match = (p.err == nil) == (s.err == nil) && o.equal(p.response, s.response)
+				if o.results != nil {
+					label := "match"
+					if !c.Match {
+						label = "mismatch"
+					}
+					o.results.With("result", label).Add(1)
+				}
+				if o.compare != nil {
+					o.compare(sctx, c)
+				}
+			}()
+
+			begin := time.Now()
+			response, err := next(ctx, request)
+			primary <- result{response, err, time.Since(begin)}
+			return response, err
+		}
+	}
+}
+
+// detached is a context with the values of its parent, which isn't canceled
+// with it, so shadow requests outlive the requests they shadow.
+type detached struct {
+	parent context.Context
+}
+
+func (detached) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detached) Done() <-chan struct{}               { return nil }
+func (detached) Err() error                          { return nil }
+func (d detached) Value(key interface{}) interface{} { return d.parent.Value(key) }
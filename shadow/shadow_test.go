@@ -0,0 +1,104 @@
+package shadow
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func constant(s string, err error) func(context.Context, string) (string, error) {
+	return func(context.Context, string) (string, error) { return s, err }
+}
+
+func TestMiddleware(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		shadow    func(context.Context, string) (string, error)
+		wantMatch bool
+	}{
+		{"match", constant("ok", nil), true},
+		{"mismatch", constant("different", nil), false},
+		{"error", constant("ok", errors.New("fail")), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			comparisons := make(chan Comparison[string], 1)
+			e := NewMiddleware(tc.shadow, Compare(func(_ context.Context, c Comparison[string]) {
+				comparisons <- c
+			}))(constant("ok", nil))
+
+			if response, err := e(context.Background(), ""); response != "ok" || err != nil {
+				t.Fatalf("want the primary response, have %q, %v", response, err)
+			}
+			c := <-comparisons
+			if want, have := tc.wantMatch, c.Match; want != have {
+				t.Errorf("want %v, have %v: %+v", want, have, c)
+			}
+			if want, have := "ok", c.Primary; want != have {
+				t.Errorf("want %q, have %q", want, have)
+			}
+		})
+	}
+}
+
+func TestMiddlewareDoesNotWait(t *testing.T) {
+	var (
+		release = make(chan struct{})
+		done    = make(chan struct{})
+		shadow  = func(ctx context.Context, _ string) (string, error) {
+			<-release
+			return "", ctx.Err()
+		}
+		e = NewMiddleware(shadow, Compare(func(context.Context, Comparison[string]) {
+			close(done)
+		}))(constant("ok", nil))
+	)
+
+	// The primary request returns, and its context is canceled, while the
+	// shadow is still running, and the shadow isn't canceled with it.
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := e(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	close(release)
+	<-done
+}
+
+func TestMiddlewareFraction(t *testing.T) {
+	var n int64
+	shadow := func(context.Context, string) (string, error) {
+		atomic.AddInt64(&n, 1)
+		return "", nil
+	}
+	e := NewMiddleware(shadow, Fraction[string](0))(constant("ok", nil))
+	for i := 0; i < 100; i++ {
+		e(context.Background(), "")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if want, have := int64(0), atomic.LoadInt64(&n); want != have {
+		t.Errorf("want %d shadowed, have %d", want, have)
+	}
+}
+
+func TestMiddlewareMaxConcurrent(t *testing.T) {
+	var (
+		release = make(chan struct{})
+		n       int64
+		shadow  = func(context.Context, string) (string, error) {
+			atomic.AddInt64(&n, 1)
+			<-release
+			return "", nil
+		}
+		e = NewMiddleware(shadow, MaxConcurrent[string](1))(constant("ok", nil))
+	)
+	defer close(release)
+	for i := 0; i < 10; i++ {
+		e(context.Background(), "")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if want, have := int64(1), atomic.LoadInt64(&n); want != have {
+		t.Errorf("want %d shadowed, have %d", want, have)
+	}
+}
@@ -0,0 +1,91 @@
+// Package singleflight provides an endpoint middleware which collapses
+// identical concurrent requests into a single call of the next endpoint,
+// whose response is shared by all of them, e.g. to stop the requests of many
+// clients which miss a cache at once from all reaching the database.
+package singleflight
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/metrics"
+)
+
+// KeyFunc returns the key of a request. Concurrent requests with the same
+// key are collapsed, so it must identify all of the request which affects
+// the response, including e.g. the tenant or user in the context. Requests
+// whose key is empty are never collapsed.
+type KeyFunc[I any] func(ctx context.Context, request I) string
+
+// Option sets an optional parameter for the middleware.
+type Option func(*options)
+
+type options struct {
+	collapsed metrics.Counter
+}
+
+// Collapsed sets a counter of the requests which are collapsed into the call
+// of another. By default, they're not counted.
+func Collapsed(counter metrics.Counter) Option {
+	return func(o *options) { o.collapsed = counter }
+}
+
+// NewMiddleware returns an endpoint middleware which collapses concurrent
+// requests with the same key into a single call of the next endpoint. The
+// response, or error, of the call is returned for all of them, so responses
+// mustn't be modified by their callers.
+//
+// The call isn't canceled when the context of the request which made it is
+// done, as other requests may be waiting for it, though it carries the
+// context's values; bound it with a timeout of its own, e.g. of its client.
+// Each request stops waiting for it when its own context is done.
+func NewMiddleware[I, O any](key KeyFunc[I], opts ...Option) endpoint.Middleware[I, O] {
+	var o options
+	for _, option := range opts {
+		option(&o)
+	}
+	return func(next endpoint.Endpoint[I, O]) endpoint.Endpoint[I, O] {
+		var g singleflight.Group
+		return func(ctx context.Context, request I) (O, error) {
+			k := key(ctx, request)
+			if k == "" {
+				return next(ctx, request)
+			}
+			var (
+				zero   O
+				leader bool
+			)
+			c := g.DoChan(k, func() (interface{}, error) {
+				leader = true
+				return next(detached{ctx}, request)
+			})
+			select {
+			case r := <-c:
+				if !leader && o.collapsed != nil {
+					o.collapsed.Add(1)
+				}
+				if r.Err != nil {
+					return zero, r.Err
+				}
+				response, _ := r.Val.(O) // nil, if O is an interface
+				return response, nil
+			case <-ctx.Done():
+				return zero, ctx.Err()
+			}
+		}
+	}
+}
+
+// detached is a context with the values of its parent, which isn't canceled
+// with it.
+type detached struct {
+	parent context.Context
+}
+
+func (detached) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detached) Done() <-chan struct{}               { return nil }
+func (detached) Err() error                          { return nil }
+func (d detached) Value(key interface{}) interface{} { return d.parent.Value(key) }
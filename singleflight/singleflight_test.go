@@ -0,0 +1,93 @@
+package singleflight_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/barrett370/kit/v2/metrics/generic"
+	"github.com/barrett370/kit/v2/singleflight"
+)
+
+func identity(_ context.Context, request string) string { return request }
+
+func TestMiddleware(t *testing.T) {
+	var (
+		calls     int32
+		release   = make(chan struct{})
+		collapsed = generic.NewCounter("collapsed")
+		e         = singleflight.NewMiddleware[string, string](identity, singleflight.Collapsed(collapsed))(
+			func(ctx context.Context, request string) (string, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return request + "!", nil
+			},
+		)
+		wg        sync.WaitGroup
+		responses = make([]string, 10)
+	)
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i], _ = e(context.Background(), "a")
+		}(i)
+	}
+	time.Sleep(10 * time.Millisecond) // for all of them to wait
+	close(release)
+	wg.Wait()
+
+	if want, have := int32(1), atomic.LoadInt32(&calls); want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+	for _, have := range responses {
+		if want := "a!"; want != have {
+			t.Errorf("want %q, have %q", want, have)
+		}
+	}
+	if want, have := 9.0, collapsed.Value(); want != have {
+		t.Errorf("want %v collapsed, have %v", want, have)
+	}
+}
+
+func TestMiddlewareEmptyKey(t *testing.T) {
+	var calls int32
+	e := singleflight.NewMiddleware[string, string](identity)(func(context.Context, string) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "", nil
+	})
+	e(context.Background(), "")
+	e(context.Background(), "")
+	if want, have := int32(2), atomic.LoadInt32(&calls); want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+}
+
+func TestMiddlewareCancel(t *testing.T) {
+	var (
+		errFailed = errors.New("failed")
+		release   = make(chan struct{})
+		e         = singleflight.NewMiddleware[string, string](identity)(func(ctx context.Context, _ string) (string, error) {
+			<-release
+			return "", errFailed
+		})
+		ctx, cancel = context.WithCancel(context.Background())
+		errc        = make(chan error, 1)
+	)
+	go func() { _, err := e(context.Background(), "a"); errc <- err }()
+	time.Sleep(10 * time.Millisecond)
+
+	// A waiting request stops when its context is done, without affecting
+	// the call.
+	cancel()
+	if _, err := e(ctx, "a"); err != context.Canceled {
+		t.Errorf("want %v, have %v", context.Canceled, err)
+	}
+	close(release)
+	if want, have := errFailed, <-errc; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
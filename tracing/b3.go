@@ -0,0 +1,121 @@
+package tracing
+
+import "strings"
+
+// B3 headers, as defined by https://github.com/openzipkin/b3-propagation.
+const (
+	B3TraceIDHeader      = "X-B3-TraceId"
+	B3SpanIDHeader       = "X-B3-SpanId"
+	B3ParentSpanIDHeader = "X-B3-ParentSpanId"
+	B3SampledHeader      = "X-B3-Sampled"
+	B3FlagsHeader        = "X-B3-Flags"
+	B3SingleHeader       = "b3"
+)
+
+// B3 is a Propagator of the B3 format of Zipkin. It extracts span contexts
+// from either the single b3 header or the multiple X-B3 headers, and injects
+// them into the multiple headers, or the single header if SingleHeader is
+// true.
+type B3 struct {
+	SingleHeader bool
+}
+
+// Extract implements Propagator.
+func (p B3) Extract(c Carrier) (SpanContext, bool) {
+	if v := c.Get(B3SingleHeader); v != "" {
+		return extractB3Single(v)
+	}
+	sc := SpanContext{
+		TraceID:      normalizeID(c.Get(B3TraceIDHeader), 32),
+		SpanID:       normalizeID(c.Get(B3SpanIDHeader), 16),
+		ParentSpanID: c.Get(B3ParentSpanIDHeader),
+	}
+	if sc.ParentSpanID != "" {
+		sc.ParentSpanID = normalizeID(sc.ParentSpanID, 16)
+	}
+	switch strings.ToLower(c.Get(B3SampledHeader)) {
+	case "1", "true":
+		sc.Sampling = SamplingAccept
+	case "0", "false":
+		sc.Sampling = SamplingDeny
+	}
+	if c.Get(B3FlagsHeader) == "1" {
+		sc.Sampling = SamplingDebug
+	}
+	if !sc.Valid() || (sc.ParentSpanID != "" && !validID(sc.ParentSpanID, 16)) {
+		return SpanContext{}, false
+	}
+	return sc, true
+}
+
+// extractB3Single extracts a span context from a b3 header of the form
+// {TraceId}-{SpanId}-{SamplingState}-{ParentSpanId}, where the last two are
+// optional. Headers with only a sampling state carry no span context.
+func extractB3Single(v string) (SpanContext, bool) {
+	parts := strings.Split(v, "-")
+	if len(parts) < 2 || len(parts) > 4 {
+		return SpanContext{}, false
+	}
+	sc := SpanContext{
+		TraceID: normalizeID(parts[0], 32),
+		SpanID:  normalizeID(parts[1], 16),
+	}
+	if len(parts) > 2 {
+		switch parts[2] {
+		case "1":
+			sc.Sampling = SamplingAccept
+		case "0":
+			sc.Sampling = SamplingDeny
+		case "d":
+			sc.Sampling = SamplingDebug
+		default:
+			return SpanContext{}, false
+		}
+	}
+	if len(parts) > 3 {
+		sc.ParentSpanID = normalizeID(parts[3], 16)
+		if !validID(sc.ParentSpanID, 16) {
+			return SpanContext{}, false
+		}
+	}
+	if !sc.Valid() {
+		return SpanContext{}, false
+	}
+	return sc, true
+}
+
+// Inject implements Propagator.
+func (p B3) Inject(sc SpanContext, c Carrier) {
+	if !sc.Valid() {
+		return
+	}
+	if p.SingleHeader {
+		v := sc.TraceID + "-" + sc.SpanID
+		switch sc.Sampling {
+		case SamplingAccept:
+			v += "-1"
+		case SamplingDeny:
+			v += "-0"
+		case SamplingDebug:
+			v += "-d"
+		}
+		if sc.Sampling != SamplingDeferred && sc.ParentSpanID != "" {
+			v += "-" + sc.ParentSpanID
+		}
+		c.Set(B3SingleHeader, v)
+		return
+	}
+	c.Set(B3TraceIDHeader, sc.TraceID)
+	c.Set(B3SpanIDHeader, sc.SpanID)
+	if sc.ParentSpanID != "" {
+		c.Set(B3ParentSpanIDHeader, sc.ParentSpanID)
+	}
+	switch sc.Sampling {
+	case SamplingAccept:
+		c.Set(B3SampledHeader, "1")
+	case SamplingDeny:
+		c.Set(B3SampledHeader, "0")
+	case SamplingDebug:
+		c.Set(B3FlagsHeader, "1")
+	}
+}
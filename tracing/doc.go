@@ -0,0 +1,16 @@
+// Package tracing propagates trace contexts across service boundaries, in
+// the headers of requests, so traces are continued by the services called,
+// whichever tracing system emitted them.
+//
+// A SpanContext identifies the span of a request. Servers extract it from
+// incoming headers into the context with a Propagator, for the tracer to
+// continue, and clients inject it from the context into outgoing headers.
+// Propagators are provided for the B3 single and multiple header formats of
+// Zipkin, and the uber-trace-id format of Jaeger; others may be registered,
+// and looked up by name, e.g. from configuration:
+//
+//	p, err := tracing.Lookup("b3", "jaeger") // extracts either, injects both
+//	handler := httptransport.NewServer(e, dec, enc,
+//		httptransport.ServerBefore[Request, Response](tracing.HTTPToContext(p)),
+//	)
+package tracing
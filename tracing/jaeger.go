@@ -0,0 +1,78 @@
+package tracing
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// JaegerHeader is the header of the Jaeger format.
+const JaegerHeader = "uber-trace-id"
+
+// Jaeger flags.
+const (
+	jaegerSampled = 1
+	jaegerDebug   = 2
+)
+
+// Jaeger is a Propagator of the uber-trace-id format of Jaeger, of the form
+// {trace-id}:{span-id}:{parent-span-id}:{flags}. Baggage is not propagated.
+type Jaeger struct{}
+
+// Extract implements Propagator.
+func (Jaeger) Extract(c Carrier) (SpanContext, bool) {
+	v := c.Get(JaegerHeader)
+	if strings.Contains(v, "%") {
+		var err error
+		if v, err = url.QueryUnescape(v); err != nil {
+			return SpanContext{}, false
+		}
+	}
+	parts := strings.Split(v, ":")
+	if len(parts) != 4 {
+		return SpanContext{}, false
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return SpanContext{}, false
+	}
+	sc := SpanContext{
+		TraceID:  normalizeID(parts[0], 32),
+		SpanID:   normalizeID(parts[1], 16),
+		Sampling: SamplingDeny,
+	}
+	if parent := normalizeID(parts[2], 16); validID(parent, 16) {
+		sc.ParentSpanID = parent // 0 means none
+	}
+	switch {
+	case flags&jaegerDebug != 0:
+		sc.Sampling = SamplingDebug
+	case flags&jaegerSampled != 0:
+		sc.Sampling = SamplingAccept
+	}
+	if !sc.Valid() {
+		return SpanContext{}, false
+	}
+	return sc, true
+}
+
+// Inject implements Propagator. Jaeger has no deferred sampling decision, so
+// deferred traces are injected as not sampled.
+func (Jaeger) Inject(sc SpanContext, c Carrier) {
+	if !sc.Valid() {
+		return
+	}
+	parent := sc.ParentSpanID
+	if parent == "" {
+		parent = "0"
+	}
+	var flags int
+	switch sc.Sampling {
+	case SamplingAccept:
+		flags = jaegerSampled
+	case SamplingDebug:
+		flags = jaegerSampled | jaegerDebug
+	}
+	c.Set(JaegerHeader, fmt.Sprintf("%s:%s:%s:%x", sc.TraceID, sc.SpanID, parent, flags))
+}
@@ -0,0 +1,111 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Carrier carries the headers of a request, e.g. HTTP headers or gRPC
+// metadata.
+type Carrier interface {
+	Get(key string) string
+	Set(key, value string)
+}
+
+// HeaderCarrier is a Carrier of HTTP headers.
+type HeaderCarrier http.Header
+
+// Get implements Carrier.
+func (c HeaderCarrier) Get(key string) string { return http.Header(c).Get(key) }
+
+// Set implements Carrier.
+func (c HeaderCarrier) Set(key, value string) { http.Header(c).Set(key, value) }
+
+// MapCarrier is a Carrier of headers with lower-case keys, such as gRPC
+// metadata (metadata.MD) or NATS headers, which convert to it.
+type MapCarrier map[string][]string
+
+// Get implements Carrier.
+func (c MapCarrier) Get(key string) string {
+	if vs := c[strings.ToLower(key)]; len(vs) > 0 {
+		return vs[0]
+	}
+	return ""
+}
+
+// Set implements Carrier.
+func (c MapCarrier) Set(key, value string) { c[strings.ToLower(key)] = []string{value} }
+
+// Propagator extracts span contexts from, and injects them into, the headers
+// of requests, in some format.
+type Propagator interface {
+	Extract(c Carrier) (SpanContext, bool)
+	Inject(sc SpanContext, c Carrier)
+}
+
+// Composite is a Propagator of several formats, for interoperability with
+// services which use any of them. It extracts the span context of the first
+// Propagator which finds one, and injects the span context with all of them.
+type Composite []Propagator
+
+// Extract implements Propagator.
+func (p Composite) Extract(c Carrier) (SpanContext, bool) {
+	for _, propagator := range p {
+		if sc, ok := propagator.Extract(c); ok {
+			return sc, true
+		}
+	}
+	return SpanContext{}, false
+}
+
+// Inject implements Propagator.
+func (p Composite) Inject(sc SpanContext, c Carrier) {
+	for _, propagator := range p {
+		propagator.Inject(sc, c)
+	}
+}
+
+var registry = struct {
+	sync.RWMutex
+	propagators map[string]Propagator
+}{
+	propagators: map[string]Propagator{
+		"b3":      B3{SingleHeader: true},
+		"b3multi": B3{},
+		"jaeger":  Jaeger{},
+	},
+}
+
+// Register registers the Propagator under the name, for Lookup, replacing
+// any Propagator of the name. The names "b3", "b3multi", and "jaeger" are
+// registered by default, as in the OTEL_PROPAGATORS environment variable of
+// OpenTelemetry.
+func Register(name string, p Propagator) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.propagators[name] = p
+}
+
+// Lookup returns a Composite of the Propagators registered under the names,
+// or an error if any of them isn't registered.
+func Lookup(names ...string) (Composite, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+	var p Composite
+	for _, name := range names {
+		propagator, ok := registry.propagators[name]
+		if !ok {
+			known := make([]string, 0, len(registry.propagators))
+			for name := range registry.propagators {
+				known = append(known, name)
+			}
+			sort.Strings(known)
+			return nil, fmt.Errorf("unknown propagator %q (known: %s)", name, strings.Join(known, ", "))
+		}
+		p = append(p, propagator)
+	}
+	return p, nil
+}
@@ -0,0 +1,187 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/barrett370/kit/v2/tracing"
+)
+
+var (
+	traceID = "463ac35c9f6413ad48485a3953bb6124"
+	spanID  = "a2fb4a1d1a96d312"
+	parent  = "0020000000000001"
+)
+
+func TestB3Extract(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		headers http.Header
+		want    tracing.SpanContext
+		ok      bool
+	}{
+		{
+			name: "multi",
+			headers: http.Header{
+				"X-B3-Traceid":      {traceID},
+				"X-B3-Spanid":       {spanID},
+				"X-B3-Parentspanid": {parent},
+				"X-B3-Sampled":      {"1"},
+			},
+			want: tracing.SpanContext{TraceID: traceID, SpanID: spanID, ParentSpanID: parent, Sampling: tracing.SamplingAccept},
+			ok:   true,
+		},
+		{
+			name: "multi debug with 64-bit trace ID",
+			headers: http.Header{
+				"X-B3-Traceid": {"48485A3953BB6124"},
+				"X-B3-Spanid":  {spanID},
+				"X-B3-Flags":   {"1"},
+			},
+			want: tracing.SpanContext{TraceID: "48485a3953bb6124", SpanID: spanID, Sampling: tracing.SamplingDebug},
+			ok:   true,
+		},
+		{
+			name:    "single",
+			headers: http.Header{"B3": {traceID + "-" + spanID + "-d-" + parent}},
+			want:    tracing.SpanContext{TraceID: traceID, SpanID: spanID, ParentSpanID: parent, Sampling: tracing.SamplingDebug},
+			ok:      true,
+		},
+		{
+			name:    "single deferred",
+			headers: http.Header{"B3": {traceID + "-" + spanID}},
+			want:    tracing.SpanContext{TraceID: traceID, SpanID: spanID},
+			ok:      true,
+		},
+		{name: "single sampling only", headers: http.Header{"B3": {"0"}}},
+		{name: "single bad sampling", headers: http.Header{"B3": {traceID + "-" + spanID + "-x"}}},
+		{name: "missing span ID", headers: http.Header{"X-B3-Traceid": {traceID}}},
+		{name: "zero trace ID", headers: http.Header{"X-B3-Traceid": {"0000000000000000"}, "X-B3-Spanid": {spanID}}},
+		{name: "not hex", headers: http.Header{"X-B3-Traceid": {"463ac35c9f6413az"}, "X-B3-Spanid": {spanID}}},
+		{name: "none", headers: http.Header{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			have, ok := tracing.B3{}.Extract(tracing.HeaderCarrier(tc.headers))
+			if ok != tc.ok || have != tc.want {
+				t.Errorf("want %+v, %v, have %+v, %v", tc.want, tc.ok, have, ok)
+			}
+		})
+	}
+}
+
+func TestB3Inject(t *testing.T) {
+	sc := tracing.SpanContext{TraceID: traceID, SpanID: spanID, ParentSpanID: parent, Sampling: tracing.SamplingAccept}
+
+	multi := http.Header{}
+	tracing.B3{}.Inject(sc, tracing.HeaderCarrier(multi))
+	want := http.Header{
+		"X-B3-Traceid":      {traceID},
+		"X-B3-Spanid":       {spanID},
+		"X-B3-Parentspanid": {parent},
+		"X-B3-Sampled":      {"1"},
+	}
+	if !reflect.DeepEqual(want, multi) {
+		t.Errorf("want %v, have %v", want, multi)
+	}
+
+	single := http.Header{}
+	tracing.B3{SingleHeader: true}.Inject(sc, tracing.HeaderCarrier(single))
+	if want, have := traceID+"-"+spanID+"-1-"+parent, single.Get("b3"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	// Round trip.
+	for _, h := range []http.Header{multi, single} {
+		if have, ok := (tracing.B3{}).Extract(tracing.HeaderCarrier(h)); !ok || have != sc {
+			t.Errorf("want %+v, have %+v", sc, have)
+		}
+	}
+}
+
+func TestJaeger(t *testing.T) {
+	for _, tc := range []struct {
+		header string
+		want   tracing.SpanContext
+		ok     bool
+	}{
+		{
+			header: traceID + ":" + spanID + ":0:1",
+			want:   tracing.SpanContext{TraceID: traceID, SpanID: spanID, Sampling: tracing.SamplingAccept},
+			ok:     true,
+		},
+		{
+			header: "abc%3A" + spanID + "%3A" + parent + "%3A3",
+			want:   tracing.SpanContext{TraceID: "0000000000000abc", SpanID: spanID, ParentSpanID: parent, Sampling: tracing.SamplingDebug},
+			ok:     true,
+		},
+		{
+			header: traceID + ":" + spanID + ":0:0",
+			want:   tracing.SpanContext{TraceID: traceID, SpanID: spanID, Sampling: tracing.SamplingDeny},
+			ok:     true,
+		},
+		{header: traceID + ":" + spanID + ":0"},
+		{header: traceID + ":" + spanID + ":0:zz"},
+		{header: "0:" + spanID + ":0:1"},
+	} {
+		have, ok := tracing.Jaeger{}.Extract(tracing.MapCarrier{"uber-trace-id": {tc.header}})
+		if ok != tc.ok || have != tc.want {
+			t.Errorf("%s: want %+v, %v, have %+v, %v", tc.header, tc.want, tc.ok, have, ok)
+		}
+	}
+
+	md := tracing.MapCarrier{}
+	tracing.Jaeger{}.Inject(tracing.SpanContext{TraceID: traceID, SpanID: spanID, Sampling: tracing.SamplingDebug}, md)
+	if want, have := traceID+":"+spanID+":0:3", md.Get("uber-trace-id"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	p, err := tracing.Lookup("b3", "jaeger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := tracing.SpanContext{TraceID: traceID, SpanID: spanID, Sampling: tracing.SamplingAccept}
+
+	// It injects all formats, and extracts any of them.
+	h := http.Header{}
+	p.Inject(sc, tracing.HeaderCarrier(h))
+	if h.Get("b3") == "" || h.Get("uber-trace-id") == "" {
+		t.Errorf("want b3 and uber-trace-id headers, have %v", h)
+	}
+	h.Del("b3")
+	if have, ok := p.Extract(tracing.HeaderCarrier(h)); !ok || have != sc {
+		t.Errorf("want %+v, have %+v", sc, have)
+	}
+
+	if _, err := tracing.Lookup("b3", "unregistered"); err == nil {
+		t.Error("want an error for an unknown propagator, have none")
+	}
+	tracing.Register("xray", tracing.Composite{})
+	if _, err := tracing.Lookup("xray"); err != nil {
+		t.Errorf("want no error, have %v", err)
+	}
+}
+
+func TestHTTPToContextToHTTP(t *testing.T) {
+	sc := tracing.SpanContext{TraceID: traceID, SpanID: spanID, Sampling: tracing.SamplingAccept}
+
+	out := httptest.NewRequest("GET", "/", nil)
+	ctx := tracing.ContextWithSpanContext(context.Background(), sc)
+	tracing.ContextToHTTP(tracing.B3{})(ctx, out)
+
+	ctx = tracing.HTTPToContext(tracing.B3{})(context.Background(), out)
+	if have, ok := tracing.SpanContextFromContext(ctx); !ok || have != sc {
+		t.Errorf("want %+v, have %+v", sc, have)
+	}
+
+	md := map[string][]string{}
+	tracing.ContextToHeaders(ctx, tracing.Jaeger{}, md)
+	ctx = tracing.HeadersToContext(context.Background(), tracing.Jaeger{}, md)
+	if have, ok := tracing.SpanContextFromContext(ctx); !ok || have != sc {
+		t.Errorf("want %+v, have %+v", sc, have)
+	}
+}
@@ -0,0 +1,56 @@
+package tracing
+
+import "context"
+
+// Attribute is a key/value pair annotating a span or an event.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Attr returns an Attribute.
+func Attr(key string, value interface{}) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is the span of a request, as recorded by a tracer, which middlewares
+// annotate to explain its outcome, e.g. that it took three tries. Adapt the
+// spans of a tracer to it, and store them in the context with ContextWithSpan,
+// e.g. in a middleware; with OpenTelemetry, it's
+//
+//	type otelSpan struct{ trace.Span }
+//
+//	func (s otelSpan) AddEvent(name string, attrs ...tracing.Attribute) {
+//		s.Span.AddEvent(name, trace.WithAttributes(otelAttrs(attrs)...))
+//	}
+//
+//	func (s otelSpan) SetAttributes(attrs ...tracing.Attribute) {
+//		s.Span.SetAttributes(otelAttrs(attrs)...)
+//	}
+//
+//	ctx = tracing.ContextWithSpan(ctx, otelSpan{trace.SpanFromContext(ctx)})
+type Span interface {
+	AddEvent(name string, attrs ...Attribute)
+	SetAttributes(attrs ...Attribute)
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns a context carrying the span.
+func ContextWithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the span carried by the context, or a span which
+// records nothing, so middlewares can annotate spans unconditionally.
+func SpanFromContext(ctx context.Context) Span {
+	if span, ok := ctx.Value(spanKey{}).(Span); ok {
+		return span
+	}
+	return nopSpan{}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) AddEvent(string, ...Attribute) {}
+func (nopSpan) SetAttributes(...Attribute)    {}
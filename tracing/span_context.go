@@ -0,0 +1,93 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+)
+
+// Sampling is the sampling decision of a trace.
+type Sampling int
+
+// Sampling decisions. With SamplingDeferred, the decision is left to the
+// receiver of the trace.
+const (
+	SamplingDeferred Sampling = iota
+	SamplingAccept
+	SamplingDeny
+	SamplingDebug
+)
+
+func (s Sampling) String() string {
+	switch s {
+	case SamplingAccept:
+		return "accept"
+	case SamplingDeny:
+		return "deny"
+	case SamplingDebug:
+		return "debug"
+	default:
+		return "deferred"
+	}
+}
+
+// SpanContext identifies a span within a trace, as propagated between
+// services. IDs are lower-case hex: trace IDs are 16 or 32 digits, and span
+// IDs are 16 digits.
+type SpanContext struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string // optional
+	Sampling     Sampling
+}
+
+// Valid returns true if the span context has valid trace and span IDs.
+func (sc SpanContext) Valid() bool {
+	return validID(sc.TraceID, 32) && validID(sc.SpanID, 16)
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns a context carrying the span context.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context carried by the context, if
+// any.
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok
+}
+
+// validID returns true if id is a non-zero hex ID of 16 digits, or of max
+// digits, which may be 16 or 32.
+func validID(id string, max int) bool {
+	if len(id) != 16 && len(id) != max {
+		return false
+	}
+	zero := true
+	for _, c := range id {
+		switch {
+		case c == '0':
+		case '1' <= c && c <= '9', 'a' <= c && c <= 'f':
+			zero = false
+		default:
+			return false
+		}
+	}
+	return !zero
+}
+
+// normalizeID returns the lower-case ID, left-padded with zeros to 16 digits,
+// or 32 if it's longer than 16 and max allows it. Jaeger omits leading zeros.
+func normalizeID(id string, max int) string {
+	id = strings.ToLower(id)
+	n := 16
+	if len(id) > 16 && max > 16 {
+		n = max
+	}
+	if len(id) < n {
+		id = strings.Repeat("0", n-len(id)) + id
+	}
+	return id
+}
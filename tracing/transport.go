@@ -0,0 +1,51 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+)
+
+// HTTPToContext returns a transport/http RequestFunc which extracts the span
+// context of an incoming request from its headers into the context.
+// Particularly useful for servers. It's declared with the underlying type of
+// RequestFunc, since transport/http depends on this package, through sd/lb.
+func HTTPToContext(p Propagator) func(context.Context, *http.Request) context.Context {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		if sc, ok := p.Extract(HeaderCarrier(r.Header)); ok {
+			return ContextWithSpanContext(ctx, sc)
+		}
+		return ctx
+	}
+}
+
+// ContextToHTTP returns a transport/http RequestFunc which injects the span
+// context in the context into the headers of an outgoing request.
+// Particularly useful for clients.
+func ContextToHTTP(p Propagator) func(context.Context, *http.Request) context.Context {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		if sc, ok := SpanContextFromContext(ctx); ok {
+			p.Inject(sc, HeaderCarrier(r.Header))
+		}
+		return ctx
+	}
+}
+
+// HeadersToContext extracts a span context from headers with lower-case
+// keys, such as gRPC metadata (metadata.MD), into the context. Call it from
+// the server before funcs of those transports. Particularly useful for
+// servers.
+func HeadersToContext(ctx context.Context, p Propagator, headers map[string][]string) context.Context {
+	if sc, ok := p.Extract(MapCarrier(headers)); ok {
+		return ContextWithSpanContext(ctx, sc)
+	}
+	return ctx
+}
+
+// ContextToHeaders injects the span context in the context into headers with
+// lower-case keys, such as gRPC metadata (metadata.MD). Call it from the
+// client before funcs of those transports. Particularly useful for clients.
+func ContextToHeaders(ctx context.Context, p Propagator, headers map[string][]string) {
+	if sc, ok := SpanContextFromContext(ctx); ok {
+		p.Inject(sc, MapCarrier(headers))
+	}
+}
@@ -0,0 +1,137 @@
+package transport
+
+import (
+	"context"
+	"errors"
+
+	"github.com/barrett370/kit/v2/metrics"
+)
+
+// Stage is the stage of a request at which a transport error occurred.
+type Stage string
+
+// Stages of requests, as set in the context passed to ErrorHandlers by
+// servers. Other transports may use stages of their own.
+const (
+	StageUnknown  Stage = ""
+	StageDecode   Stage = "decode"
+	StageEndpoint Stage = "endpoint"
+	StageEncode   Stage = "encode"
+)
+
+type stageKey struct{}
+
+// ContextWithStage returns a context with the stage, for ErrorHandlers to
+// find with StageFromContext.
+func ContextWithStage(ctx context.Context, stage Stage) context.Context {
+	return context.WithValue(ctx, stageKey{}, stage)
+}
+
+// StageFromContext returns the stage at which the error being handled
+// occurred, or StageUnknown.
+func StageFromContext(ctx context.Context) Stage {
+	stage, _ := ctx.Value(stageKey{}).(Stage)
+	return stage
+}
+
+// ChainErrorHandler returns an ErrorHandler which passes errors to each of
+// the handlers, in order, e.g. to both log and count them.
+func ChainErrorHandler(handlers ...ErrorHandler) ErrorHandler {
+	return ErrorHandlerFunc(func(ctx context.Context, err error) {
+		for _, h := range handlers {
+			h.Handle(ctx, err)
+		}
+	})
+}
+
+// FilterErrorHandler returns an ErrorHandler which passes errors to next only
+// if keep returns true, e.g. to log only errors at some stages, or to drop
+// errors due to canceled requests.
+func FilterErrorHandler(keep func(ctx context.Context, err error) bool, next ErrorHandler) ErrorHandler {
+	return ErrorHandlerFunc(func(ctx context.Context, err error) {
+		if keep(ctx, err) {
+			next.Handle(ctx, err)
+		}
+	})
+}
+
+// AtStage returns a filter for FilterErrorHandler which keeps errors which
+// occurred at any of the stages.
+func AtStage(stages ...Stage) func(ctx context.Context, err error) bool {
+	return func(ctx context.Context, _ error) bool {
+		stage := StageFromContext(ctx)
+		for _, s := range stages {
+			if s == stage {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// OfClass returns a filter for FilterErrorHandler which keeps errors whose
+// class, as decided by the classifier, is any of the classes.
+func OfClass(classify func(error) string, classes ...string) func(ctx context.Context, err error) bool {
+	return func(_ context.Context, err error) bool {
+		class := classify(err)
+		for _, c := range classes {
+			if c == class {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Error classes of DefaultErrorClassifier.
+const (
+	ClassError    = "error"
+	ClassCanceled = "canceled"
+	ClassTimeout  = "timeout"
+	ClassPanic    = "panic"
+)
+
+// DefaultErrorClassifier classifies errors as ClassCanceled or ClassTimeout,
+// if they're due to their context, ClassPanic, if they're PanicErrors, or
+// otherwise ClassError.
+func DefaultErrorClassifier(err error) string {
+	var pe *PanicError
+	switch {
+	case errors.As(err, &pe):
+		return ClassPanic
+	case errors.Is(err, context.Canceled):
+		return ClassCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ClassTimeout
+	default:
+		return ClassError
+	}
+}
+
+// MetricsErrorHandler is an ErrorHandler which counts errors, with the labels
+// "stage", and "class" as decided by its classifier.
+type MetricsErrorHandler struct {
+	errors   metrics.Counter
+	classify func(error) string
+}
+
+// NewMetricsErrorHandler returns a MetricsErrorHandler which counts errors
+// with the counter. If classify is nil, DefaultErrorClassifier is used.
+func NewMetricsErrorHandler(counter metrics.Counter, classify func(error) string) *MetricsErrorHandler {
+	if classify == nil {
+		classify = DefaultErrorClassifier
+	}
+	return &MetricsErrorHandler{
+		errors:   counter,
+		classify: classify,
+	}
+}
+
+// Handle implements ErrorHandler.
+func (h *MetricsErrorHandler) Handle(ctx context.Context, err error) {
+	stage := StageFromContext(ctx)
+	if stage == StageUnknown {
+		stage = "unknown"
+	}
+	h.errors.With("stage", string(stage), "class", h.classify(err)).Add(1)
+}
@@ -0,0 +1,83 @@
+package transport_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/barrett370/kit/v2/metrics"
+	"github.com/barrett370/kit/v2/metrics/generic"
+	"github.com/barrett370/kit/v2/transport"
+)
+
+func TestChainAndFilterErrorHandler(t *testing.T) {
+	var handled []string
+	record := func(name string) transport.ErrorHandler {
+		return transport.ErrorHandlerFunc(func(ctx context.Context, err error) {
+			handled = append(handled, fmt.Sprintf("%s %s %v", name, transport.StageFromContext(ctx), err))
+		})
+	}
+	h := transport.ChainErrorHandler(
+		record("all"),
+		transport.FilterErrorHandler(transport.AtStage(transport.StageDecode), record("decode")),
+		transport.FilterErrorHandler(transport.OfClass(transport.DefaultErrorClassifier, transport.ClassError), record("errors")),
+	)
+
+	ctx := context.Background()
+	h.Handle(transport.ContextWithStage(ctx, transport.StageDecode), errors.New("bad request"))
+	h.Handle(transport.ContextWithStage(ctx, transport.StageEndpoint), context.Canceled)
+
+	want := []string{
+		"all decode bad request",
+		"decode decode bad request",
+		"errors decode bad request",
+		"all endpoint context canceled",
+	}
+	if !reflect.DeepEqual(want, handled) {
+		t.Errorf("want %q, have %q", want, handled)
+	}
+}
+
+func TestDefaultErrorClassifier(t *testing.T) {
+	for err, want := range map[error]string{
+		errors.New("dang"):                                transport.ClassError,
+		fmt.Errorf("wrapped: %w", context.Canceled):       transport.ClassCanceled,
+		context.DeadlineExceeded:                          transport.ClassTimeout,
+		transport.NewPanicError(context.DeadlineExceeded): transport.ClassPanic,
+	} {
+		if have := transport.DefaultErrorClassifier(err); want != have {
+			t.Errorf("%v: want %q, have %q", err, want, have)
+		}
+	}
+}
+
+func TestMetricsErrorHandler(t *testing.T) {
+	var (
+		labels  []string
+		counter = generic.NewCounter("errors")
+		h       = transport.NewMetricsErrorHandler(labelRecorder{counter, &labels}, nil)
+	)
+	h.Handle(transport.ContextWithStage(context.Background(), transport.StageEncode), errors.New("dang"))
+	h.Handle(context.Background(), context.DeadlineExceeded)
+
+	if want, have := 2.0, counter.Value(); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	want := []string{"stage", "encode", "class", "error", "stage", "unknown", "class", "timeout"}
+	if !reflect.DeepEqual(want, labels) {
+		t.Errorf("want %q, have %q", want, labels)
+	}
+}
+
+// labelRecorder records the label values of a counter.
+type labelRecorder struct {
+	*generic.Counter
+	labels *[]string
+}
+
+func (r labelRecorder) With(labelValues ...string) metrics.Counter {
+	*r.labels = append(*r.labels, labelValues...)
+	return r
+}
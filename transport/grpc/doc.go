@@ -0,0 +1,10 @@
+// Package grpc bridges endpoint middlewares and gRPC unary interceptors, so
+// middlewares written once, e.g. for rate limiting, circuit breaking, or JWT
+// authentication, apply to plain gRPC services and clients which don't use
+// kit endpoints, and interceptors apply to endpoints. It also resolves gRPC
+// targets to the instances of an sd.Instancer, for the load balancing of gRPC.
+//
+// The package doesn't depend on gRPC. Its func types have the signatures of
+// their gRPC counterparts, such as grpc.UnaryHandler, and convert directly to
+// and from them. Package grpcinterop wraps them in the gRPC types.
+package grpc
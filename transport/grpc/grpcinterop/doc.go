@@ -0,0 +1,6 @@
+// Package grpcinterop provides the gRPC types for the bridges of package
+// transport/grpc, which doesn't depend on gRPC. Its interceptors apply
+// endpoint middlewares to plain gRPC servers and clients, and its middleware
+// applies gRPC interceptors to endpoints. Its resolver builder and Dial resolve
+// the instances of an sd.Instancer for the load balancing of gRPC.
+package grpcinterop
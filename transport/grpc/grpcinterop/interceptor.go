@@ -0,0 +1,45 @@
+package grpcinterop
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	kitgrpc "github.com/barrett370/kit/v2/transport/grpc"
+)
+
+// UnaryServerInterceptor returns a gRPC interceptor which applies the
+// middleware to the handlers of calls to a server.
+//
+//	srv := grpc.NewServer(grpc.UnaryInterceptor(grpcinterop.UnaryServerInterceptor(mw)))
+func UnaryServerInterceptor(mw endpoint.Middleware[interface{}, interface{}]) grpc.UnaryServerInterceptor {
+	intercept := kitgrpc.UnaryServerInterceptor(mw)
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return intercept(ctx, req, kitgrpc.UnaryHandler(handler))
+	}
+}
+
+// UnaryClientInterceptor returns a gRPC interceptor which applies the
+// middleware to calls by a client, whose responses are their replies.
+//
+//	conn, err := grpc.Dial(target, grpc.WithUnaryInterceptor(grpcinterop.UnaryClientInterceptor(mw)))
+func UnaryClientInterceptor(mw endpoint.Middleware[interface{}, interface{}]) grpc.UnaryClientInterceptor {
+	intercept := kitgrpc.UnaryClientInterceptor(mw)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return intercept(ctx, req, reply, func(ctx context.Context, req interface{}) error {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		})
+	}
+}
+
+// NewMiddleware returns an endpoint middleware which applies the gRPC
+// interceptor to requests, as if they were calls of the full method, e.g.
+// "/pkg.Service/Method". The interceptor must pass requests of type I to the
+// handler, and return responses of type O, or an error.
+func NewMiddleware[I, O any](interceptor grpc.UnaryServerInterceptor, fullMethod string) endpoint.Middleware[I, O] {
+	info := &grpc.UnaryServerInfo{FullMethod: fullMethod}
+	return kitgrpc.NewMiddleware[I, O](func(ctx context.Context, req interface{}, handler kitgrpc.UnaryHandler) (interface{}, error) {
+		return interceptor(ctx, req, info, grpc.UnaryHandler(handler))
+	})
+}
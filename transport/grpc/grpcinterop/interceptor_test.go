@@ -0,0 +1,83 @@
+package grpcinterop_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/transport/grpc/grpcinterop"
+)
+
+var errDenied = errors.New("denied")
+
+// deny is a middleware which denies requests of "deny".
+func deny(next endpoint.Endpoint[interface{}, interface{}]) endpoint.Endpoint[interface{}, interface{}] {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		if request == "deny" {
+			return nil, errDenied
+		}
+		return next(ctx, request)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	var (
+		interceptor = grpcinterop.UnaryServerInterceptor(deny)
+		info        = &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+		handler     = func(_ context.Context, req interface{}) (interface{}, error) { return req.(string) + "!", nil }
+	)
+	if resp, err := interceptor(context.Background(), "hi", info, handler); resp != "hi!" || err != nil {
+		t.Errorf("want hi!, have %v, %v", resp, err)
+	}
+	if _, err := interceptor(context.Background(), "deny", info, handler); err != errDenied {
+		t.Errorf("want %v, have %v", errDenied, err)
+	}
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	var (
+		interceptor = grpcinterop.UnaryClientInterceptor(deny)
+		method      string
+		invoker     = func(_ context.Context, m string, req, reply interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+			method = m
+			*reply.(*string) = req.(string) + "!"
+			return nil
+		}
+		reply string
+	)
+	if err := interceptor(context.Background(), "/test.Service/Method", "hi", &reply, nil, invoker); err != nil || reply != "hi!" {
+		t.Errorf("want hi!, have %q, %v", reply, err)
+	}
+	if want, have := "/test.Service/Method", method; want != have {
+		t.Errorf("want method %q, have %q", want, have)
+	}
+	if err := interceptor(context.Background(), "/test.Service/Method", "deny", &reply, nil, invoker); err != errDenied {
+		t.Errorf("want %v, have %v", errDenied, err)
+	}
+}
+
+func TestNewMiddleware(t *testing.T) {
+	var method string
+	interceptor := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		method = info.FullMethod
+		if req == "deny" {
+			return nil, errDenied
+		}
+		return handler(ctx, req)
+	}
+	e := grpcinterop.NewMiddleware[string, int](interceptor, "/test.Service/Method")(func(_ context.Context, request string) (int, error) {
+		return len(request), nil
+	})
+	if resp, err := e(context.Background(), "hello"); resp != 5 || err != nil {
+		t.Errorf("want 5, have %v, %v", resp, err)
+	}
+	if want, have := "/test.Service/Method", method; want != have {
+		t.Errorf("want method %q, have %q", want, have)
+	}
+	if _, err := e(context.Background(), "deny"); err != errDenied {
+		t.Errorf("want %v, have %v", errDenied, err)
+	}
+}
@@ -0,0 +1,71 @@
+package grpcinterop
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/resolver"
+
+	"github.com/barrett370/kit/v2/sd"
+	kitgrpc "github.com/barrett370/kit/v2/transport/grpc"
+)
+
+// NewBuilder returns a gRPC resolver builder for the kitgrpc.Scheme, which
+// resolves the target of each service, as returned by kitgrpc.Target, to the
+// instances of its Instancer. Register it with resolver.Register, or dial
+// with grpc.WithResolvers.
+func NewBuilder(instancers map[string]sd.Instancer) resolver.Builder {
+	return builder{instancers: instancers}
+}
+
+type builder struct {
+	instancers map[string]sd.Instancer
+}
+
+func (builder) Scheme() string { return kitgrpc.Scheme }
+
+func (b builder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	instancer, ok := b.instancers[target.Endpoint()]
+	if !ok {
+		return nil, fmt.Errorf("grpcinterop: unknown service %q", target.Endpoint())
+	}
+	return kitResolver{kitgrpc.NewResolver(instancer, clientConn{cc})}, nil
+}
+
+type kitResolver struct {
+	*kitgrpc.Resolver
+}
+
+// ResolveNow does nothing, as the Instancer pushes its instances.
+func (kitResolver) ResolveNow(resolver.ResolveNowOptions) {}
+
+// clientConn adapts a resolver.ClientConn to a kitgrpc.ClientConn.
+type clientConn struct {
+	resolver.ClientConn
+}
+
+func (cc clientConn) UpdateInstances(instances []string) error {
+	addrs := make([]resolver.Address, len(instances))
+	for i, instance := range instances {
+		addrs[i] = resolver.Address{Addr: instance}
+	}
+	return cc.UpdateState(resolver.State{Addresses: addrs})
+}
+
+// DialContext creates a client connection to the service, which balances
+// calls round robin over the instances of the Instancer. The options are
+// applied after the resolver and service config, so they may override the
+// latter; they must include the transport credentials.
+func DialContext(ctx context.Context, service string, instancer sd.Instancer, options ...grpc.DialOption) (*grpc.ClientConn, error) {
+	options = append([]grpc.DialOption{
+		grpc.WithResolvers(NewBuilder(map[string]sd.Instancer{service: instancer})),
+		grpc.WithDefaultServiceConfig(kitgrpc.RoundRobinServiceConfig),
+	}, options...)
+	return grpc.DialContext(ctx, kitgrpc.Target(service), options...)
+}
+
+// Dial is DialContext with a background context.
+func Dial(service string, instancer sd.Instancer, options ...grpc.DialOption) (*grpc.ClientConn, error) {
+	return DialContext(context.Background(), service, instancer, options...)
+}
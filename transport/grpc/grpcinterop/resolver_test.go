@@ -0,0 +1,59 @@
+package grpcinterop_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/barrett370/kit/v2/sd"
+	"github.com/barrett370/kit/v2/transport/grpc/grpcinterop"
+)
+
+func TestDial(t *testing.T) {
+	var instances []string
+	for i := 0; i < 2; i++ {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv := grpc.NewServer()
+		healthpb.RegisterHealthServer(srv, health.NewServer())
+		go srv.Serve(ln)
+		defer srv.Stop()
+		instances = append(instances, ln.Addr().String())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := grpcinterop.DialContext(ctx, "health", sd.NewFixedInstancer(instances), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	client := healthpb.NewHealthClient(conn)
+	for i := 0; i < 4; i++ {
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{}, grpc.WaitForReady(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, have := healthpb.HealthCheckResponse_SERVING, resp.Status; want != have {
+			t.Errorf("want %v, have %v", want, have)
+		}
+	}
+}
+
+func TestDialUnknownService(t *testing.T) {
+	builder := grpcinterop.NewBuilder(map[string]sd.Instancer{"users": sd.NewFixedInstancer(nil)})
+	conn, err := grpc.Dial("kit:///orders", grpc.WithResolvers(builder), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err == nil {
+		conn.Close()
+		t.Error("want an error for an unknown service, have none")
+	}
+}
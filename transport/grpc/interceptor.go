@@ -0,0 +1,72 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/barrett370/kit/v2/endpoint"
+)
+
+// UnaryHandler has the signature of grpc.UnaryHandler, which converts to it.
+type UnaryHandler func(ctx context.Context, req interface{}) (interface{}, error)
+
+// UnaryInvoker makes the call of a client to the server, with the request,
+// and fills in the reply. It's a grpc.UnaryInvoker bound to its method, reply,
+// connection, and call options.
+type UnaryInvoker func(ctx context.Context, req interface{}) error
+
+// UnaryInterceptor is a grpc.UnaryServerInterceptor bound to its method.
+type UnaryInterceptor func(ctx context.Context, req interface{}, handler UnaryHandler) (interface{}, error)
+
+// UnaryServerInterceptor returns a UnaryInterceptor which applies the
+// middleware to the handlers of calls to a gRPC server.
+// grpcinterop.UnaryServerInterceptor wraps it in a
+// grpc.UnaryServerInterceptor.
+func UnaryServerInterceptor(mw endpoint.Middleware[interface{}, interface{}]) UnaryInterceptor {
+	return func(ctx context.Context, req interface{}, handler UnaryHandler) (interface{}, error) {
+		return mw(endpoint.Endpoint[interface{}, interface{}](handler))(ctx, req)
+	}
+}
+
+// UnaryClientInterceptor returns a func which applies the middleware to calls
+// by a gRPC client, whose responses are their replies.
+// grpcinterop.UnaryClientInterceptor wraps it in a
+// grpc.UnaryClientInterceptor.
+func UnaryClientInterceptor(mw endpoint.Middleware[interface{}, interface{}]) func(ctx context.Context, req, reply interface{}, invoke UnaryInvoker) error {
+	return func(ctx context.Context, req, reply interface{}, invoke UnaryInvoker) error {
+		_, err := mw(func(ctx context.Context, req interface{}) (interface{}, error) {
+			if err := invoke(ctx, req); err != nil {
+				return nil, err
+			}
+			return reply, nil
+		})(ctx, req)
+		return err
+	}
+}
+
+// NewMiddleware returns an endpoint middleware which applies the interceptor
+// to requests, e.g. a grpc.UnaryServerInterceptor bound to a method, as
+// grpcinterop.NewMiddleware does. The interceptor must pass requests of type I to the handler, and return
+// responses of type O, or an error.
+func NewMiddleware[I, O any](interceptor UnaryInterceptor) endpoint.Middleware[I, O] {
+	return func(next endpoint.Endpoint[I, O]) endpoint.Endpoint[I, O] {
+		return func(ctx context.Context, request I) (O, error) {
+			var zero O
+			resp, err := interceptor(ctx, request, func(ctx context.Context, req interface{}) (interface{}, error) {
+				r, ok := req.(I)
+				if !ok {
+					return nil, fmt.Errorf("grpc: interceptor passed a request of type %T, want %T", req, request)
+				}
+				return next(ctx, r)
+			})
+			if err != nil {
+				return zero, err
+			}
+			response, ok := resp.(O)
+			if !ok {
+				return zero, fmt.Errorf("grpc: interceptor returned a response of type %T, want %T", resp, zero)
+			}
+			return response, nil
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package grpc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	kitgrpc "github.com/barrett370/kit/v2/transport/grpc"
+)
+
+var errDenied = errors.New("denied")
+
+// deny is a middleware which denies requests of "deny".
+func deny(next endpoint.Endpoint[interface{}, interface{}]) endpoint.Endpoint[interface{}, interface{}] {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		if request == "deny" {
+			return nil, errDenied
+		}
+		return next(ctx, request)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	handler := func(_ context.Context, req interface{}) (interface{}, error) { return req.(string) + "!", nil }
+	if resp, err := kitgrpc.UnaryServerInterceptor(deny)(context.Background(), "hi", handler); resp != "hi!" || err != nil {
+		t.Errorf("want hi!, have %v, %v", resp, err)
+	}
+	if _, err := kitgrpc.UnaryServerInterceptor(deny)(context.Background(), "deny", handler); err != errDenied {
+		t.Errorf("want %v, have %v", errDenied, err)
+	}
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	var (
+		reply  string
+		calls  int
+		invoke = func(_ context.Context, req interface{}) error {
+			calls++
+			reply = req.(string) + "!"
+			return nil
+		}
+	)
+	if err := kitgrpc.UnaryClientInterceptor(deny)(context.Background(), "hi", &reply, invoke); err != nil || reply != "hi!" {
+		t.Errorf("want hi!, have %q, %v", reply, err)
+	}
+	if err := kitgrpc.UnaryClientInterceptor(deny)(context.Background(), "deny", &reply, invoke); err != errDenied {
+		t.Errorf("want %v, have %v", errDenied, err)
+	}
+	if want, have := 1, calls; want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+}
+
+func TestNewMiddleware(t *testing.T) {
+	interceptor := func(ctx context.Context, req interface{}, handler kitgrpc.UnaryHandler) (interface{}, error) {
+		if req == "deny" {
+			return nil, errDenied
+		}
+		return handler(ctx, req)
+	}
+	e := kitgrpc.NewMiddleware[string, int](interceptor)(func(_ context.Context, request string) (int, error) {
+		return len(request), nil
+	})
+	if resp, err := e(context.Background(), "hello"); resp != 5 || err != nil {
+		t.Errorf("want 5, have %v, %v", resp, err)
+	}
+	if _, err := e(context.Background(), "deny"); err != errDenied {
+		t.Errorf("want %v, have %v", errDenied, err)
+	}
+
+	bad := kitgrpc.NewMiddleware[string, int](func(context.Context, interface{}, kitgrpc.UnaryHandler) (interface{}, error) {
+		return "not an int", nil
+	})(nil)
+	if _, err := bad(context.Background(), "hello"); err == nil {
+		t.Error("want an error for a response of the wrong type, have none")
+	}
+}
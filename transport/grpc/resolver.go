@@ -0,0 +1,81 @@
+package grpc
+
+import (
+	"sync"
+
+	"github.com/barrett370/kit/v2/sd"
+)
+
+// Scheme is the scheme of targets resolved by a Resolver, if its builder is
+// registered with it.
+const Scheme = "kit"
+
+// RoundRobinServiceConfig is a service config of gRPC, which balances calls
+// round robin over all the addresses of a target, rather than calling the
+// first one which connects, as gRPC does by default. grpcinterop.Dial
+// applies it.
+const RoundRobinServiceConfig = `{"loadBalancingConfig":[{"round_robin":{}}]}`
+
+// Target returns the gRPC target of the service, in the Scheme.
+func Target(service string) string {
+	return Scheme + ":///" + service
+}
+
+// ClientConn is the part of a gRPC resolver.ClientConn used by a Resolver,
+// with the instances as addresses. Package grpcinterop adapts a
+// resolver.ClientConn to it.
+type ClientConn interface {
+	UpdateInstances(instances []string) error
+	ReportError(error)
+}
+
+// Resolver resolves a gRPC target to the instances of an sd.Instancer, so
+// the load balancing of gRPC, rather than an endpoint balancer of sd/lb,
+// balances calls over them. It's built by the resolver.Builder of package
+// grpcinterop, which picks the Instancer of the service of the target.
+type Resolver struct {
+	instancer sd.Instancer
+	cc        ClientConn
+	ch        chan sd.Event
+	done      chan struct{}
+	once      sync.Once
+}
+
+// NewResolver returns a Resolver which updates the client connection with
+// the instances of the Instancer, until it's closed. Errors of the Instancer
+// are reported to the connection, which keeps its instances.
+func NewResolver(instancer sd.Instancer, cc ClientConn) *Resolver {
+	r := &Resolver{
+		instancer: instancer,
+		cc:        cc,
+		ch:        make(chan sd.Event),
+		done:      make(chan struct{}),
+	}
+	go r.receive()
+	instancer.Register(r.ch)
+	return r
+}
+
+func (r *Resolver) receive() {
+	for {
+		select {
+		case event := <-r.ch:
+			if event.Err != nil {
+				r.cc.ReportError(event.Err)
+				continue
+			}
+			r.cc.UpdateInstances(event.Instances)
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// Close deregisters the Resolver from the Instancer, and stops updating the
+// client connection.
+func (r *Resolver) Close() {
+	r.once.Do(func() {
+		r.instancer.Deregister(r.ch)
+		close(r.done)
+	})
+}
@@ -0,0 +1,67 @@
+package grpc_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/barrett370/kit/v2/sd"
+	kitgrpc "github.com/barrett370/kit/v2/transport/grpc"
+)
+
+func TestResolver(t *testing.T) {
+	var (
+		instancer = &testInstancer{}
+		cc        = &testClientConn{updates: make(chan string, 10)}
+		r         = kitgrpc.NewResolver(instancer, cc)
+	)
+	defer r.Close()
+
+	instancer.ch <- sd.Event{Instances: []string{"a:1", "b:1"}}
+	instancer.ch <- sd.Event{Err: errors.New("unavailable")}
+	instancer.ch <- sd.Event{Instances: []string{"b:1"}}
+
+	for _, want := range []string{"[a:1 b:1]", "error unavailable", "[b:1]"} {
+		select {
+		case have := <-cc.updates:
+			if want != have {
+				t.Errorf("want %q, have %q", want, have)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("want %q, have nothing", want)
+		}
+	}
+
+	r.Close()
+	if instancer.ch != nil {
+		t.Error("want the resolver deregistered")
+	}
+}
+
+func TestTarget(t *testing.T) {
+	if want, have := "kit:///users", kitgrpc.Target("users"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+type testInstancer struct {
+	ch chan<- sd.Event
+}
+
+func (i *testInstancer) Register(ch chan<- sd.Event)   { i.ch = ch }
+func (i *testInstancer) Deregister(ch chan<- sd.Event) { i.ch = nil }
+func (i *testInstancer) Stop()                         {}
+
+type testClientConn struct {
+	updates chan string
+}
+
+func (cc *testClientConn) UpdateInstances(instances []string) error {
+	cc.updates <- fmt.Sprint(instances)
+	return nil
+}
+
+func (cc *testClientConn) ReportError(err error) {
+	cc.updates <- "error " + err.Error()
+}
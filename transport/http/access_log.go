@@ -0,0 +1,96 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+// AccessLogFormat is the format of access logs written by ServerAccessLog.
+type AccessLogFormat int
+
+const (
+	// AccessLogCommon is the Common Log Format, of a line under the "msg"
+	// key, e.g.
+	//
+	//	127.0.0.1 - alice [10/Oct/2000:13:55:36 -0700] "GET /a.gif HTTP/1.0" 200 2326
+	AccessLogCommon AccessLogFormat = iota
+
+	// AccessLogCombined is the Combined Log Format, which is the Common Log
+	// Format followed by the referer and user agent of the request.
+	AccessLogCombined
+
+	// AccessLogJSON logs the fields of the request and response as
+	// key/value pairs, for a structured logger such as log.NewJSONLogger:
+	// remote_addr, method, path, proto, status, size, duration, request_id,
+	// user_agent, and referer. The request ID is set with ServerRequestID.
+	AccessLogJSON
+)
+
+// clfTime is the layout of times in the Common Log Format.
+const clfTime = "02/Jan/2006:15:04:05 -0700"
+
+// ServerAccessLog writes a line to the logger for every request, in the
+// format, once its response has been written. Set it on the servers of the
+// routes to log, e.g. not those of health checks.
+func ServerAccessLog[I, O any](logger log.Logger, format AccessLogFormat) ServerOption[I, O] {
+	return func(s *Server[I, O]) { s.accessLog = &accessLog{logger, format} }
+}
+
+type accessLog struct {
+	logger log.Logger
+	format AccessLogFormat
+}
+
+func (l *accessLog) log(ctx context.Context, r *http.Request, start time.Time, code int, size int64) {
+	if l.format == AccessLogJSON {
+		l.logger.Log(
+			"remote_addr", r.RemoteAddr,
+			"method", r.Method,
+			"path", r.URL.Path,
+			"proto", r.Proto,
+			"status", code,
+			"size", size,
+			"duration", time.Since(start),
+			"request_id", RequestIDKey.Value(ctx),
+			"user_agent", r.UserAgent(),
+			"referer", r.Referer(),
+		)
+		return
+	}
+
+	host := r.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	user, _, _ := r.BasicAuth()
+	bytes := "-"
+	if size > 0 {
+		bytes = strconv.FormatInt(size, 10)
+	}
+	uri := r.RequestURI
+	if uri == "" {
+		uri = r.URL.RequestURI()
+	}
+	line := fmt.Sprintf("%s - %s [%s] %q %d %s",
+		dash(host), dash(user), start.Format(clfTime),
+		r.Method+" "+uri+" "+r.Proto, code, bytes,
+	)
+	if l.format == AccessLogCombined {
+		line += fmt.Sprintf(" %q %q", r.Referer(), r.UserAgent())
+	}
+	l.logger.Log("msg", line)
+}
+
+// dash returns s, or "-" if s is empty, as in the Common Log Format.
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,88 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/go-kit/log"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	httptransport "github.com/barrett370/kit/v2/transport/http"
+)
+
+func TestServerAccessLog(t *testing.T) {
+	for _, tc := range []struct {
+		format httptransport.AccessLogFormat
+		want   string
+	}{
+		{
+			httptransport.AccessLogCommon,
+			`^10\.0\.0\.1 - alice \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /a\?b=c HTTP/1\.1" 418 5$`,
+		},
+		{
+			httptransport.AccessLogCombined,
+			`^10\.0\.0\.1 - alice \[.+\] "GET /a\?b=c HTTP/1\.1" 418 5 "http://example\.com/" "test"$`,
+		},
+	} {
+		var keyvals []interface{}
+		serveAccessLog(tc.format, &keyvals)
+		if len(keyvals) != 2 || keyvals[0] != "msg" {
+			t.Fatalf("want a msg, have %v", keyvals)
+		}
+		if line := keyvals[1].(string); !regexp.MustCompile(tc.want).MatchString(line) {
+			t.Errorf("want %s, have %q", tc.want, line)
+		}
+	}
+}
+
+func TestServerAccessLogJSON(t *testing.T) {
+	var keyvals []interface{}
+	serveAccessLog(httptransport.AccessLogJSON, &keyvals)
+	fields := map[interface{}]interface{}{}
+	for i := 0; i < len(keyvals); i += 2 {
+		fields[keyvals[i]] = keyvals[i+1]
+	}
+	for k, want := range map[string]interface{}{
+		"remote_addr": "10.0.0.1:1234",
+		"method":      "GET",
+		"path":        "/a",
+		"status":      http.StatusTeapot,
+		"size":        int64(5),
+		"request_id":  "abc",
+		"user_agent":  "test",
+	} {
+		if have := fields[k]; want != have {
+			t.Errorf("%s: want %v, have %v", k, want, have)
+		}
+	}
+	if _, ok := fields["duration"]; !ok {
+		t.Error("want a duration, have none")
+	}
+}
+
+func serveAccessLog(format httptransport.AccessLogFormat, keyvals *[]interface{}) {
+	handler := httptransport.NewServer(
+		endpoint.Nop,
+		func(context.Context, *http.Request) (interface{}, error) { return struct{}{}, nil },
+		func(_ context.Context, w http.ResponseWriter, _ interface{}) error {
+			w.WriteHeader(http.StatusTeapot)
+			_, err := w.Write([]byte("hello"))
+			return err
+		},
+		httptransport.ServerRequestID[any, any](),
+		httptransport.ServerAccessLog[any, any](log.LoggerFunc(func(kv ...interface{}) error {
+			*keyvals = kv
+			return nil
+		}), format),
+	)
+	r := httptest.NewRequest("GET", "/a?b=c", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.SetBasicAuth("alice", "secret")
+	r.Header.Set("User-Agent", "test")
+	r.Header.Set("Referer", "http://example.com/")
+	r.Header.Set("X-Request-Id", "abc")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+}
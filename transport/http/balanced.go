@@ -0,0 +1,144 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/sd"
+	"github.com/barrett370/kit/v2/sd/lb"
+)
+
+// NewFactory returns an sd.Factory of clients of the remote method, which
+// send the requests created by req to the instance, by rewriting the scheme
+// and host of their URLs. Instances are host:port, or URLs, e.g.
+// https://host:port, whose scheme is used too. Use it with an sd.Endpointer
+// and a load balancer of your own, or see NewBalancedClient.
+//
+// All the clients share the HTTPClient set with SetClient, by default
+// http.DefaultClient, so connections to each instance are pooled and reused by
+// its transport.
+func NewFactory[I, O any](req CreateRequestFunc[I], dec DecodeResponseFunc[O], options ...ClientOption[I, O]) sd.Factory[I, O] {
+	return func(instance string) (endpoint.Endpoint[I, O], io.Closer, error) {
+		scheme, host := "", instance
+		if strings.Contains(instance, "://") {
+			u, err := url.Parse(instance)
+			if err != nil {
+				return nil, nil, err
+			}
+			scheme, host = u.Scheme, u.Host
+		}
+		rewrite := func(ctx context.Context, request I) (*http.Request, error) {
+			r, err := req(ctx, request)
+			if err != nil {
+				return nil, err
+			}
+			if scheme != "" {
+				r.URL.Scheme = scheme
+			}
+			r.URL.Host, r.Host = host, ""
+			return r, nil
+		}
+		return NewExplicitClient(rewrite, dec, options...).Endpoint(), nil, nil
+	}
+}
+
+// BalancedClient is a client of a remote method, which is provided by the
+// instances of a service discovery system. Requests are load balanced over
+// the instances, and retried on others when they fail.
+type BalancedClient[I, O any] struct {
+	endpointer *sd.DefaultEndpointer[I, O]
+	endpoint   endpoint.Endpoint[I, O]
+}
+
+// NewBalancedClient constructs a BalancedClient for the remote method. The
+// target URL is that of a request to any instance; its host is rewritten for
+// each attempt, as with NewFactory. By default, requests are balanced round
+// robin, and tried up to 3 times within 10 seconds.
+func NewBalancedClient[I, O any](
+	instancer sd.Instancer,
+	method string,
+	tgt *url.URL,
+	enc EncodeRequestFunc[I],
+	dec DecodeResponseFunc[O],
+	options ...BalancedClientOption[I, O],
+) *BalancedClient[I, O] {
+	return NewExplicitBalancedClient(instancer, makeCreateRequestFunc(method, tgt, enc), dec, options...)
+}
+
+// NewExplicitBalancedClient is like NewBalancedClient but uses a
+// CreateRequestFunc instead of a method, target URL, and EncodeRequestFunc.
+func NewExplicitBalancedClient[I, O any](
+	instancer sd.Instancer,
+	req CreateRequestFunc[I],
+	dec DecodeResponseFunc[O],
+	options ...BalancedClientOption[I, O],
+) *BalancedClient[I, O] {
+	opts := balancedClientOptions[I, O]{
+		logger:      log.NewNopLogger(),
+		newBalancer: lb.NewRoundRobin[I, O],
+		timeout:     10 * time.Second,
+		retry:       []lb.RetryOption{lb.RetryMax(3)},
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+	endpointer := sd.NewEndpointer(instancer, NewFactory(req, dec, opts.client...), opts.logger, opts.endpointer...)
+	return &BalancedClient[I, O]{
+		endpointer: endpointer,
+		endpoint:   lb.RetryWithOptions(opts.timeout, opts.newBalancer(endpointer), opts.retry...),
+	}
+}
+
+// Endpoint returns an endpoint which calls the remote method on the
+// instances.
+func (c *BalancedClient[I, O]) Endpoint() endpoint.Endpoint[I, O] {
+	return c.endpoint
+}
+
+// Close deregisters the client from the Instancer.
+func (c *BalancedClient[I, O]) Close() {
+	c.endpointer.Close()
+}
+
+// BalancedClientOption sets an optional parameter for balanced clients.
+type BalancedClientOption[I, O any] func(*balancedClientOptions[I, O])
+
+type balancedClientOptions[I, O any] struct {
+	client      []ClientOption[I, O]
+	endpointer  []sd.EndpointerOption
+	logger      log.Logger
+	newBalancer func(sd.Endpointer[I, O]) lb.Balancer[I, O]
+	timeout     time.Duration
+	retry       []lb.RetryOption
+}
+
+// BalancedClientOptions sets the options of the client of each instance.
+func BalancedClientOptions[I, O any](options ...ClientOption[I, O]) BalancedClientOption[I, O] {
+	return func(o *balancedClientOptions[I, O]) { o.client = append(o.client, options...) }
+}
+
+// BalancedClientEndpointer sets the options of the sd.Endpointer of the
+// instances, and the logger it logs errors of the Instancer with. By default,
+// nothing is logged.
+func BalancedClientEndpointer[I, O any](logger log.Logger, options ...sd.EndpointerOption) BalancedClientOption[I, O] {
+	return func(o *balancedClientOptions[I, O]) { o.logger, o.endpointer = logger, options }
+}
+
+// BalancedClientBalancer sets the constructor of the load balancer, e.g.
+// lb.NewLeastLoaded with a seed. By default, lb.NewRoundRobin is used.
+func BalancedClientBalancer[I, O any](newBalancer func(sd.Endpointer[I, O]) lb.Balancer[I, O]) BalancedClientOption[I, O] {
+	return func(o *balancedClientOptions[I, O]) { o.newBalancer = newBalancer }
+}
+
+// BalancedClientRetry sets the timeout of a request, across all its attempts,
+// and the options of its retries, which replace the default of lb.RetryMax(3).
+func BalancedClientRetry[I, O any](timeout time.Duration, options ...lb.RetryOption) BalancedClientOption[I, O] {
+	return func(o *balancedClientOptions[I, O]) { o.timeout, o.retry = timeout, options }
+}
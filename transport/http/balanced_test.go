@@ -0,0 +1,94 @@
+package http_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/barrett370/kit/v2/sd"
+	httptransport "github.com/barrett370/kit/v2/transport/http"
+)
+
+func TestBalancedClient(t *testing.T) {
+	var (
+		a      = httptest.NewServer(named("a", http.StatusOK))
+		b      = httptest.NewServer(named("b", http.StatusOK))
+		broken = httptest.NewServer(named("broken", http.StatusInternalServerError))
+	)
+	defer a.Close()
+	defer b.Close()
+	defer broken.Close()
+
+	tgt, _ := url.Parse("http://unresolved.invalid/name")
+	client := httptransport.NewBalancedClient(
+		sd.FixedInstancer{a.Listener.Addr().String(), b.URL, broken.Listener.Addr().String()},
+		"GET", tgt,
+		func(context.Context, *http.Request, struct{}) error { return nil },
+		decodeName,
+		httptransport.BalancedClientRetry[struct{}, string](time.Second),
+	)
+	defer client.Close()
+
+	have := map[string]int{}
+	for i := 0; i < 6; i++ {
+		name, err := client.Endpoint()(context.Background(), struct{}{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		have[name]++
+	}
+	if have["a"] == 0 || have["b"] == 0 || have["broken"] != 0 {
+		t.Errorf("want requests to a and b, and retries of broken, have %v", have)
+	}
+}
+
+func TestFactory(t *testing.T) {
+	server := httptest.NewServer(named("a", http.StatusOK))
+	defer server.Close()
+
+	tgt, _ := url.Parse("https://unresolved.invalid/name")
+	factory := httptransport.NewFactory(
+		func(context.Context, struct{}) (*http.Request, error) {
+			return http.NewRequest("GET", tgt.String(), nil)
+		},
+		decodeName,
+	)
+	e, _, err := factory(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, err := e(context.Background(), struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "a", name; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func named(name string, code int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/name" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(code)
+		io.WriteString(w, name)
+	})
+}
+
+func decodeName(_ context.Context, resp *http.Response) (string, error) {
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(resp.Status)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/barrett370/kit/v2/endpoint"
+)
+
+// HandlerMiddleware adapts an endpoint middleware of HTTP requests and
+// responses, e.g. a circuit breaker or rate limiter, to a net/http
+// middleware, for use with existing routers and middleware stacks.
+//
+// The handler's response is buffered into an *http.Response, so the endpoint
+// middleware can inspect or replace it, and retry the handler, before it's
+// written; streaming handlers are unsuited to it. Errors returned by the
+// middleware are written by the ErrorEncoder, or DefaultErrorEncoder if it's
+// nil. Context values set by the middleware are seen by the handler.
+func HandlerMiddleware(mw endpoint.Middleware[*http.Request, *http.Response], errorEncoder ErrorEncoder) func(http.Handler) http.Handler {
+	if errorEncoder == nil {
+		errorEncoder = DefaultErrorEncoder
+	}
+	return func(next http.Handler) http.Handler {
+		e := mw(func(ctx context.Context, r *http.Request) (*http.Response, error) {
+			buf := &responseBuffer{header: http.Header{}}
+			next.ServeHTTP(buf, r.WithContext(ctx))
+			return buf.response(r), nil
+		})
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			resp, err := e(r.Context(), r)
+			if err != nil {
+				errorEncoder(r.Context(), err, w)
+				return
+			}
+			writeResponse(w, resp)
+		})
+	}
+}
+
+// EndpointMiddleware adapts a net/http middleware, e.g. from an existing
+// middleware stack, to an endpoint middleware of HTTP requests and
+// responses. The response of the middleware is buffered into an
+// *http.Response. Errors returned by the next endpoint are returned as they
+// are, unless the middleware writes a response of its own.
+func EndpointMiddleware(mw func(http.Handler) http.Handler) endpoint.Middleware[*http.Request, *http.Response] {
+	return func(next endpoint.Endpoint[*http.Request, *http.Response]) endpoint.Endpoint[*http.Request, *http.Response] {
+		return func(ctx context.Context, r *http.Request) (*http.Response, error) {
+			var (
+				called bool
+				err    error
+				buf    = &responseBuffer{header: http.Header{}}
+			)
+			mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var resp *http.Response
+				called = true
+				if resp, err = next(r.Context(), r); err == nil {
+					writeResponse(w, resp)
+				}
+			})).ServeHTTP(buf, r.WithContext(ctx))
+			if called && err != nil && !buf.wroteHeader {
+				return nil, err
+			}
+			return buf.response(r), nil
+		}
+	}
+}
+
+// writeResponse writes the response, and closes its body.
+func writeResponse(w http.ResponseWriter, resp *http.Response) {
+	for k, values := range resp.Header {
+		for _, v := range values {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	if resp.Body != nil {
+		io.Copy(w, resp.Body)
+		resp.Body.Close()
+	}
+}
+
+// responseBuffer is an http.ResponseWriter which buffers a response.
+type responseBuffer struct {
+	header      http.Header
+	code        int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (b *responseBuffer) Header() http.Header { return b.header }
+
+func (b *responseBuffer) WriteHeader(code int) {
+	if !b.wroteHeader {
+		b.code, b.wroteHeader = code, true
+	}
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	return b.body.Write(p)
+}
+
+func (b *responseBuffer) response(r *http.Request) *http.Response {
+	b.WriteHeader(http.StatusOK)
+	return &http.Response{
+		Status:        strconv.Itoa(b.code) + " " + http.StatusText(b.code),
+		StatusCode:    b.code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        b.header,
+		Body:          io.NopCloser(bytes.NewReader(b.body.Bytes())),
+		ContentLength: int64(b.body.Len()),
+		Request:       r,
+	}
+}
@@ -0,0 +1,115 @@
+package http_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	httptransport "github.com/barrett370/kit/v2/transport/http"
+)
+
+var hello = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Hello", "world")
+	w.WriteHeader(http.StatusAccepted)
+	io.WriteString(w, "hello")
+})
+
+func TestHandlerMiddleware(t *testing.T) {
+	var (
+		errDenied = errors.New("denied")
+		attempts  int
+		mw        = func(next endpoint.Endpoint[*http.Request, *http.Response]) endpoint.Endpoint[*http.Request, *http.Response] {
+			return func(ctx context.Context, r *http.Request) (*http.Response, error) {
+				if r.URL.Path == "/deny" {
+					return nil, errDenied
+				}
+				attempts++
+				resp, _ := next(ctx, r) // a try which is retried
+				resp.Body.Close()
+				attempts++
+				return next(ctx, r)
+			}
+		}
+		h = httptransport.HandlerMiddleware(mw, nil)(hello)
+	)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if want, have := http.StatusAccepted, rec.Code; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := "hello", rec.Body.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "world", rec.Header().Get("X-Hello"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := 2, attempts; want != have {
+		t.Errorf("want %d attempts, have %d", want, have)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/deny", nil))
+	if want, have := http.StatusInternalServerError, rec.Code; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := "denied", rec.Body.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestEndpointMiddleware(t *testing.T) {
+	var (
+		errFailed = errors.New("failed")
+		auth      = func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") == "" {
+					http.Error(w, "unauthorized", http.StatusUnauthorized)
+					return
+				}
+				w.Header().Set("X-Authorized", "true")
+				next.ServeHTTP(w, r)
+			})
+		}
+		next = func(_ context.Context, r *http.Request) (*http.Response, error) {
+			if r.URL.Path == "/fail" {
+				return nil, errFailed
+			}
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+		}
+		e = httptransport.EndpointMiddleware(auth)(next)
+	)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	resp, err := e(context.Background(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := http.StatusUnauthorized, resp.StatusCode; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+
+	r.Header.Set("Authorization", "Bearer token")
+	resp, err = e(context.Background(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if want, have := "ok", string(body); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "true", resp.Header.Get("X-Authorized"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	r = httptest.NewRequest("GET", "/fail", nil)
+	r.Header.Set("Authorization", "Bearer token")
+	if _, err := e(context.Background(), r); err != errFailed {
+		t.Errorf("want %v, have %v", errFailed, err)
+	}
+}
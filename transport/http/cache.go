@@ -0,0 +1,444 @@
+package http
+
+import (
+	"bytes"
+	"container/list"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/barrett370/kit/v2/metrics"
+	"github.com/barrett370/kit/v2/metrics/discard"
+)
+
+// CacheControl describes a Cache-Control response header. The zero value
+// produces no directives.
+type CacheControl struct {
+	MaxAge               time.Duration
+	SharedMaxAge         time.Duration
+	StaleWhileRevalidate time.Duration
+	Public               bool
+	Private              bool
+	NoCache              bool
+	NoStore              bool
+	MustRevalidate       bool
+	Immutable            bool
+}
+
+// String renders the directives as a Cache-Control header value.
+func (cc CacheControl) String() string {
+	var directives []string
+	if cc.Public {
+		directives = append(directives, "public")
+	}
+	if cc.Private {
+		directives = append(directives, "private")
+	}
+	if cc.NoCache {
+		directives = append(directives, "no-cache")
+	}
+	if cc.NoStore {
+		directives = append(directives, "no-store")
+	}
+	if cc.MaxAge > 0 {
+		directives = append(directives, "max-age="+seconds(cc.MaxAge))
+	}
+	if cc.SharedMaxAge > 0 {
+		directives = append(directives, "s-maxage="+seconds(cc.SharedMaxAge))
+	}
+	if cc.StaleWhileRevalidate > 0 {
+		directives = append(directives, "stale-while-revalidate="+seconds(cc.StaleWhileRevalidate))
+	}
+	if cc.MustRevalidate {
+		directives = append(directives, "must-revalidate")
+	}
+	if cc.Immutable {
+		directives = append(directives, "immutable")
+	}
+	return strings.Join(directives, ", ")
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(d/time.Second), 10)
+}
+
+// ServerCacheControl sets the Cache-Control header of every successful
+// response to the given policy. Construct one server per route to apply
+// different policies to different routes. The header is set before the
+// response is encoded, so an EncodeResponseFunc may still override it.
+func ServerCacheControl[I, O any](cc CacheControl) ServerOption[I, O] {
+	return ServerAfter[I, O](SetResponseHeader("Cache-Control", cc.String()))
+}
+
+// ServerResponseCache serves repeated GET requests from the given cache,
+// without decoding the request or invoking the endpoint. Successful responses
+// are stored in the cache according to their Cache-Control header. By default,
+// no cache is used.
+func ServerResponseCache[I, O any](c *ResponseCache) ServerOption[I, O] {
+	return func(s *Server[I, O]) { s.cache = c }
+}
+
+// ResponseCache is an in-process, size-bounded cache of HTTP responses. Entries
+// are keyed by request URL and the values of any request headers named in the
+// response's Vary header. It's safe for concurrent use, and may be shared
+// between multiple servers.
+//
+// Only 200 OK responses to GET requests are stored. Responses with a
+// Cache-Control of no-store, no-cache or private are never stored, and
+// responses to requests with an Authorization or Cookie header are only stored
+// if they're marked public or have an s-maxage, per RFC 9111 section 3.5.
+// Responses which set cookies are never stored, and hop-by-hop headers, and
+// the request ID header of a server with ServerRequestID, are dropped from
+// stored responses, since they belong to the request that filled the cache.
+// The entry lifetime is taken from the response's s-maxage or max-age
+// directives, or the default TTL if neither is present. Requests carrying
+// Cache-Control no-cache or no-store bypass the cache.
+type ResponseCache struct {
+	mtx        sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	maxBody    int
+	now        func() time.Time
+	lru        *list.List
+	entries    map[string]*list.Element
+	vary       map[string]*urlVary
+	hits       metrics.Counter
+	misses     metrics.Counter
+}
+
+// ResponseCacheOption sets an optional parameter for response caches.
+type ResponseCacheOption func(*ResponseCache)
+
+// ResponseCacheMaxEntries bounds the number of responses held in the cache.
+// The least recently used entries are evicted first. By default, at most
+// 1000 responses are held.
+func ResponseCacheMaxEntries(n int) ResponseCacheOption {
+	return func(c *ResponseCache) { c.maxEntries = n }
+}
+
+// ResponseCacheMaxBodySize sets the size in bytes of the largest response body
+// that will be stored. Larger responses are served normally but never
+// cached. By default, the limit is 1MB.
+func ResponseCacheMaxBodySize(n int) ResponseCacheOption {
+	return func(c *ResponseCache) { c.maxBody = n }
+}
+
+// ResponseCacheHits sets a counter which is incremented every time a request is
+// served from the cache.
+func ResponseCacheHits(counter metrics.Counter) ResponseCacheOption {
+	return func(c *ResponseCache) { c.hits = counter }
+}
+
+// ResponseCacheMisses sets a counter which is incremented every time a
+// cacheable request can't be served from the cache.
+func ResponseCacheMisses(counter metrics.Counter) ResponseCacheOption {
+	return func(c *ResponseCache) { c.misses = counter }
+}
+
+// NewResponseCache returns a response cache. The TTL is used for responses
+// that don't specify their own lifetime via Cache-Control; pass zero to only
+// cache responses that do.
+func NewResponseCache(ttl time.Duration, options ...ResponseCacheOption) *ResponseCache {
+	c := &ResponseCache{
+		ttl:        ttl,
+		maxEntries: 1000,
+		maxBody:    1 << 20,
+		now:        time.Now,
+		lru:        list.New(),
+		entries:    map[string]*list.Element{},
+		vary:       map[string]*urlVary{},
+		hits:       discard.NewCounter(),
+		misses:     discard.NewCounter(),
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// urlVary holds the request headers named in the Vary header of the latest
+// response to a URL, and the number of entries stored for the URL, so it can
+// be forgotten with its last entry.
+type urlVary struct {
+	names   []string
+	entries int
+}
+
+type cachedResponse struct {
+	url     string
+	key     string
+	code    int
+	header  http.Header
+	body    []byte
+	stored  time.Time
+	expires time.Time
+}
+
+// serve writes a cached response for r to w, if one exists, leaving the
+// perRequest headers already set on w alone. It returns false if the request
+// must be served normally.
+func (c *ResponseCache) serve(w http.ResponseWriter, r *http.Request, perRequest ...string) bool {
+	if !cacheableRequest(r) {
+		return false
+	}
+
+	c.mtx.Lock()
+	resp, ok := c.lookup(r)
+	c.mtx.Unlock()
+	if !ok {
+		c.misses.Add(1)
+		return false
+	}
+	c.hits.Add(1)
+
+	skip := make(map[string]bool, len(perRequest))
+	for _, name := range perRequest {
+		skip[http.CanonicalHeaderKey(name)] = true
+	}
+	for k, values := range resp.header {
+		if !skip[k] {
+			w.Header()[k] = append([]string(nil), values...)
+		}
+	}
+	w.Header().Set("Age", seconds(c.now().Sub(resp.stored)))
+	w.WriteHeader(resp.code)
+	w.Write(resp.body)
+	return true
+}
+
+func (c *ResponseCache) lookup(r *http.Request) (*cachedResponse, bool) {
+	url := r.URL.String()
+	vary, ok := c.vary[url]
+	if !ok {
+		return nil, false
+	}
+	elem, ok := c.entries[cacheKey(url, vary.names, r.Header)]
+	if !ok {
+		return nil, false
+	}
+	resp := elem.Value.(*cachedResponse)
+	if !c.now().Before(resp.expires) {
+		c.remove(elem)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return resp, true
+}
+
+// record returns a ResponseWriter that captures the response written to w,
+// and a func that stores the captured response once it's complete. The
+// perRequest headers are dropped from the stored response.
+func (c *ResponseCache) record(w http.ResponseWriter, r *http.Request, perRequest ...string) (http.ResponseWriter, func()) {
+	if r.Method != http.MethodGet {
+		return w, func() {}
+	}
+	rw := &recordingWriter{ResponseWriter: w, code: http.StatusOK, max: c.maxBody}
+	return rw, func() { c.store(r, rw, perRequest) }
+}
+
+func (c *ResponseCache) store(r *http.Request, rw *recordingWriter, perRequest []string) {
+	if rw.code != http.StatusOK || rw.overflow || rw.header == nil {
+		return
+	}
+	if _, ok := rw.header["Set-Cookie"]; ok {
+		return // the cookie is the caller's alone
+	}
+	directives := rw.header.Get("Cache-Control")
+	if hasDirective(directives, "no-store") || hasDirective(directives, "no-cache") || hasDirective(directives, "private") {
+		return
+	}
+	if credentialed(r) && !hasDirective(directives, "public") {
+		if _, ok := directiveSeconds(directives, "s-maxage"); !ok {
+			return
+		}
+	}
+	lifetime := c.lifetime(rw.header)
+	if lifetime <= 0 {
+		return
+	}
+
+	var vary []string
+	for _, v := range rw.header.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				vary = append(vary, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	for _, name := range vary {
+		if name == "*" {
+			return
+		}
+	}
+
+	var (
+		url  = r.URL.String()
+		now  = c.now()
+		resp = &cachedResponse{
+			url:     url,
+			key:     cacheKey(url, vary, r.Header),
+			code:    rw.code,
+			header:  storedHeader(rw.header, perRequest),
+			body:    rw.buf.Bytes(),
+			stored:  now,
+			expires: now.Add(lifetime),
+		}
+	)
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if elem, ok := c.entries[resp.key]; ok {
+		c.remove(elem)
+	}
+	v, ok := c.vary[url]
+	if !ok {
+		v = &urlVary{}
+		c.vary[url] = v
+	}
+	v.names = vary
+	v.entries++
+	c.entries[resp.key] = c.lru.PushFront(resp)
+	for c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *ResponseCache) remove(elem *list.Element) {
+	resp := c.lru.Remove(elem).(*cachedResponse)
+	delete(c.entries, resp.key)
+	if v := c.vary[resp.url]; v != nil {
+		if v.entries--; v.entries <= 0 {
+			delete(c.vary, resp.url)
+		}
+	}
+}
+
+// hopByHopHeaders describe a connection, rather than a response, per RFC 9110
+// section 7.6.1, so they're never stored.
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// storedHeader removes the hop-by-hop headers, those named by Connection, and
+// the perRequest headers from the recorded header, which is a copy, and
+// returns it.
+func storedHeader(header http.Header, perRequest []string) http.Header {
+	for _, v := range header.Values("Connection") {
+		for _, name := range strings.Split(v, ",") {
+			header.Del(strings.TrimSpace(name))
+		}
+	}
+	for _, name := range hopByHopHeaders {
+		header.Del(name)
+	}
+	for _, name := range perRequest {
+		header.Del(name)
+	}
+	return header
+}
+
+// credentialed reports whether the request carries credentials, so its
+// response may be private to the caller.
+func credentialed(r *http.Request) bool {
+	return r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != ""
+}
+
+// lifetime returns how long a response with the given headers may be cached.
+func (c *ResponseCache) lifetime(header http.Header) time.Duration {
+	directives := header.Get("Cache-Control")
+	if d, ok := directiveSeconds(directives, "s-maxage"); ok {
+		return d
+	}
+	if d, ok := directiveSeconds(directives, "max-age"); ok {
+		return d
+	}
+	return c.ttl
+}
+
+func cacheableRequest(r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+	directives := r.Header.Get("Cache-Control")
+	return !hasDirective(directives, "no-cache") && !hasDirective(directives, "no-store")
+}
+
+func cacheKey(url string, vary []string, header http.Header) string {
+	var b strings.Builder
+	b.WriteString(url)
+	for _, name := range vary {
+		b.WriteByte('\n')
+		b.WriteString(name)
+		b.WriteByte(':')
+		b.WriteString(strings.Join(header.Values(name), ","))
+	}
+	return b.String()
+}
+
+func hasDirective(directives, name string) bool {
+	for _, d := range strings.Split(directives, ",") {
+		if strings.EqualFold(strings.TrimSpace(d), name) {
+			return true
+		}
+	}
+	return false
+}
+
+func directiveSeconds(directives, name string) (time.Duration, bool) {
+	for _, d := range strings.Split(directives, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(d), "=")
+		if !ok || !strings.EqualFold(k, name) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.Trim(v, `"`))
+		if err != nil {
+			return 0, false
+		}
+		return time.Duration(n) * time.Second, true
+	}
+	return 0, false
+}
+
+// recordingWriter passes writes through to the wrapped ResponseWriter, while
+// capturing the status code, headers, and up to max bytes of the body.
+type recordingWriter struct {
+	http.ResponseWriter
+	code     int
+	header   http.Header
+	buf      bytes.Buffer
+	max      int
+	overflow bool
+}
+
+func (w *recordingWriter) WriteHeader(code int) {
+	if w.header == nil {
+		w.code = code
+		w.header = w.ResponseWriter.Header().Clone()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	if w.header == nil {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.overflow {
+		if w.buf.Len()+len(p) > w.max {
+			w.overflow = true
+			w.buf = bytes.Buffer{}
+		} else {
+			w.buf.Write(p)
+		}
+	}
+	return w.ResponseWriter.Write(p)
+}
@@ -0,0 +1,52 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestResponseCacheForgetsEvictedURLs(t *testing.T) {
+	c := NewResponseCache(time.Minute, ResponseCacheMaxEntries(2))
+	for i := 0; i < 10; i++ {
+		r := httptest.NewRequest("GET", "/thing?page="+strconv.Itoa(i), nil)
+		w, store := c.record(httptest.NewRecorder(), r)
+		w.Header().Set("Vary", "Accept-Language")
+		w.Write([]byte("ok"))
+		store()
+	}
+	if want, have := 2, len(c.vary); want != have {
+		t.Errorf("URLs after eviction: want %d, have %d", want, have)
+	}
+
+	c.now = func() time.Time { return time.Now().Add(time.Hour) }
+	for i := 8; i < 10; i++ {
+		r := httptest.NewRequest("GET", "/thing?page="+strconv.Itoa(i), nil)
+		if c.serve(httptest.NewRecorder(), r) {
+			t.Errorf("page %d: served expired response", i)
+		}
+	}
+	if want, have := 0, len(c.vary); want != have {
+		t.Errorf("URLs after expiry: want %d, have %d", want, have)
+	}
+	if want, have := 0, len(c.entries); want != have {
+		t.Errorf("entries after expiry: want %d, have %d", want, have)
+	}
+}
+
+func TestStoredHeader(t *testing.T) {
+	header := http.Header{
+		"Connection":   {"close, X-Hop"},
+		"X-Hop":        {"1"},
+		"Upgrade":      {"h2c"},
+		"X-Request-Id": {"abc"},
+		"Content-Type": {"application/json"},
+	}
+	want := http.Header{"Content-Type": {"application/json"}}
+	if have := storedHeader(header, []string{"X-Request-Id"}); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
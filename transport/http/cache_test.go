@@ -0,0 +1,204 @@
+package http_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/barrett370/kit/v2/metrics/generic"
+	httptransport "github.com/barrett370/kit/v2/transport/http"
+)
+
+func TestCacheControlString(t *testing.T) {
+	cc := httptransport.CacheControl{Public: true, MaxAge: time.Minute, MustRevalidate: true}
+	if want, have := "public, max-age=60, must-revalidate", cc.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestServerResponseCache(t *testing.T) {
+	var (
+		calls  int
+		hits   = generic.NewCounter("hits")
+		misses = generic.NewCounter("misses")
+		cache  = httptransport.NewResponseCache(0, httptransport.ResponseCacheHits(hits), httptransport.ResponseCacheMisses(misses))
+	)
+	handler := httptransport.NewServer(
+		func(ctx context.Context, request interface{}) (interface{}, error) {
+			calls++
+			return map[string]int{"calls": calls}, nil
+		},
+		func(context.Context, *http.Request) (interface{}, error) { return struct{}{}, nil },
+		func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+			w.Header().Set("Vary", "Accept-Language")
+			return httptransport.EncodeJSONResponse(ctx, w, response)
+		},
+		httptransport.ServerCacheControl[any, any](httptransport.CacheControl{Public: true, MaxAge: time.Minute}),
+		httptransport.ServerResponseCache[any, any](cache),
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	get := func(lang string) string {
+		req, _ := http.NewRequest("GET", server.URL+"/thing", nil)
+		req.Header.Set("Accept-Language", lang)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if want, have := "public, max-age=60", resp.Header.Get("Cache-Control"); want != have {
+			t.Errorf("Cache-Control: want %q, have %q", want, have)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	if want, have := `{"calls":1}`+"\n", get("en"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := `{"calls":1}`+"\n", get("en"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := `{"calls":2}`+"\n", get("de"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := 2, calls; want != have {
+		t.Errorf("endpoint calls: want %d, have %d", want, have)
+	}
+	if want, have := 1.0, hits.Value(); want != have {
+		t.Errorf("hits: want %f, have %f", want, have)
+	}
+	if want, have := 2.0, misses.Value(); want != have {
+		t.Errorf("misses: want %f, have %f", want, have)
+	}
+}
+
+func TestServerResponseCacheNoStore(t *testing.T) {
+	var calls int
+	handler := httptransport.NewServer(
+		func(ctx context.Context, request interface{}) (interface{}, error) {
+			calls++
+			return struct{}{}, nil
+		},
+		func(context.Context, *http.Request) (interface{}, error) { return struct{}{}, nil },
+		httptransport.EncodeJSONResponse,
+		httptransport.ServerCacheControl[any, any](httptransport.CacheControl{NoStore: true}),
+		httptransport.ServerResponseCache[any, any](httptransport.NewResponseCache(time.Minute)),
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	for i := 0; i < 3; i++ {
+		resp, err := http.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if want, have := 3, calls; want != have {
+		t.Errorf("endpoint calls: want %d, have %d", want, have)
+	}
+}
+
+func TestServerResponseCacheCredentials(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		cc    httptransport.CacheControl
+		calls int
+	}{
+		{name: "default", cc: httptransport.CacheControl{MaxAge: time.Minute}, calls: 2},
+		{name: "public", cc: httptransport.CacheControl{Public: true, MaxAge: time.Minute}, calls: 1},
+		{name: "s-maxage", cc: httptransport.CacheControl{SharedMaxAge: time.Minute}, calls: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int
+			handler := httptransport.NewServer(
+				func(ctx context.Context, request interface{}) (interface{}, error) {
+					calls++
+					return struct{}{}, nil
+				},
+				func(context.Context, *http.Request) (interface{}, error) { return struct{}{}, nil },
+				httptransport.EncodeJSONResponse,
+				httptransport.ServerCacheControl[any, any](tc.cc),
+				httptransport.ServerResponseCache[any, any](httptransport.NewResponseCache(time.Minute)),
+			)
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			for _, header := range []string{"Authorization", "Cookie"} {
+				req, _ := http.NewRequest("GET", server.URL, nil)
+				req.Header.Set(header, "secret")
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp.Body.Close()
+			}
+			if want, have := tc.calls, calls; want != have {
+				t.Errorf("endpoint calls: want %d, have %d", want, have)
+			}
+		})
+	}
+}
+
+func TestServerResponseCachePerRequestHeaders(t *testing.T) {
+	var calls int
+	handler := httptransport.NewServer(
+		func(ctx context.Context, request string) (string, error) {
+			calls++
+			return request, nil
+		},
+		func(_ context.Context, r *http.Request) (string, error) { return r.URL.Path, nil },
+		func(ctx context.Context, w http.ResponseWriter, path string) error {
+			if path == "/login" {
+				http.SetCookie(w, &http.Cookie{Name: "session", Value: "caller-1"})
+			}
+			return httptransport.EncodeJSONResponse(ctx, w, path)
+		},
+		httptransport.ServerRequestID[string, string](),
+		httptransport.ServerCacheControl[string, string](httptransport.CacheControl{Public: true, MaxAge: time.Minute}),
+		httptransport.ServerResponseCache[string, string](httptransport.NewResponseCache(time.Minute)),
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	get := func(path, id string) *http.Response {
+		req, _ := http.NewRequest("GET", server.URL+path, nil)
+		req.Header.Set("X-Request-Id", id)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// Both requests hit the endpoint, or the cache, but each gets its own ID.
+	for _, id := range []string{"id-1", "id-2"} {
+		if want, have := id, get("/thing", id).Header.Get("X-Request-Id"); want != have {
+			t.Errorf("X-Request-Id: want %q, have %q", want, have)
+		}
+	}
+	if want, have := 1, calls; want != have {
+		t.Errorf("endpoint calls: want %d, have %d", want, have)
+	}
+
+	// A response which sets a cookie isn't cached, so it isn't replayed.
+	calls = 0
+	for _, id := range []string{"id-3", "id-4"} {
+		resp := get("/login", id)
+		if want, have := 1, len(resp.Cookies()); want != have {
+			t.Errorf("cookies: want %d, have %d", want, have)
+		}
+		if want, have := id, resp.Header.Get("X-Request-Id"); want != have {
+			t.Errorf("X-Request-Id: want %q, have %q", want, have)
+		}
+	}
+	if want, have := 2, calls; want != have {
+		t.Errorf("endpoint calls: want %d, have %d", want, have)
+	}
+}
@@ -98,8 +98,8 @@ func (c Client[I, O]) Endpoint() endpoint.Endpoint[I, O] {
 		if c.finalizer != nil {
 			defer func() {
 				if resp != nil {
-					ctx = context.WithValue(ctx, ContextKeyResponseHeaders, resp.Header)
-					ctx = context.WithValue(ctx, ContextKeyResponseSize, resp.ContentLength)
+					ctx = ResponseHeadersKey.Set(ctx, resp.Header)
+					ctx = ResponseSizeKey.Set(ctx, resp.ContentLength)
 				}
 				for _, f := range c.finalizer {
 					f(ctx, err)
@@ -166,7 +166,8 @@ func (bwc bodyWithCancel) Close() error {
 // ClientFinalizerFunc can be used to perform work at the end of a client HTTP
 // request, after the response is returned. The principal
 // intended use is for error logging. Additional response parameters are
-// provided in the context under keys with the ContextKeyResponse prefix.
+// provided in the context under keys with the Response prefix, e.g.
+// ResponseHeadersKey.
 // Note: err may be nil. There maybe also no additional response parameters
 // depending on when an error occurs.
 type ClientFinalizerFunc func(ctx context.Context, err error)
@@ -0,0 +1,219 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// JSONDecodeError is the error of DecodeJSONRequest for a request body which
+// isn't a valid JSON encoding of the request. It says where the body is
+// wrong, and why, so clients can fix their requests. It's a 400 Bad Request,
+// or a 413 Request Entity Too Large for bodies over the max size, and is
+// encoded as JSON by DefaultErrorEncoder:
+//
+//	{"error":"field \"address.zip\": expected int, have JSON string","field":"address.zip","offset":42}
+type JSONDecodeError struct {
+	// Field is the dotted path of the field which is wrong, if it's known.
+	Field string
+
+	// Offset is the offset in the body, in bytes, at which the error was
+	// found, if it's known.
+	Offset int64
+
+	// Reason is why the body is wrong.
+	Reason string
+
+	// Err is the error of encoding/json, if any.
+	Err error
+
+	code int
+}
+
+// Error implements error.
+func (e *JSONDecodeError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("field %q: %s", e.Field, e.Reason)
+	}
+	return e.Reason
+}
+
+// Unwrap returns the error of encoding/json, if any.
+func (e *JSONDecodeError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode implements StatusCoder.
+func (e *JSONDecodeError) StatusCode() int {
+	if e.code != 0 {
+		return e.code
+	}
+	return http.StatusBadRequest
+}
+
+// MarshalJSON implements json.Marshaler.
+func (e *JSONDecodeError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Error  string `json:"error"`
+		Field  string `json:"field,omitempty"`
+		Offset int64  `json:"offset,omitempty"`
+	}{e.Error(), e.Field, e.Offset})
+}
+
+// JSONDecoderOption sets an optional parameter for DecodeJSONRequest.
+type JSONDecoderOption func(*jsonDecoder)
+
+type jsonDecoder struct {
+	disallowUnknownFields bool
+	useNumber             bool
+	maxDepth              int
+	maxBytes              int64
+}
+
+// JSONDisallowUnknownFields makes fields of the body which aren't fields of
+// the request an error. By default, they're ignored.
+func JSONDisallowUnknownFields() JSONDecoderOption {
+	return func(d *jsonDecoder) { d.disallowUnknownFields = true }
+}
+
+// JSONUseNumber decodes numbers into interface{} values of the request as
+// json.Number, rather than float64, which loses the precision of large
+// integers.
+func JSONUseNumber() JSONDecoderOption {
+	return func(d *jsonDecoder) { d.useNumber = true }
+}
+
+// JSONMaxDepth limits the nesting of objects and arrays in the body. By
+// default, it's unlimited.
+func JSONMaxDepth(n int) JSONDecoderOption {
+	return func(d *jsonDecoder) { d.maxDepth = n }
+}
+
+// JSONMaxBytes limits the size of the body. By default, it's unlimited.
+func JSONMaxBytes(n int64) JSONDecoderOption {
+	return func(d *jsonDecoder) { d.maxBytes = n }
+}
+
+// DecodeJSONRequest returns a DecodeRequestFunc which decodes the JSON body
+// of requests into the request type. The body must be a single JSON value.
+// Errors of bodies which aren't valid are of type *JSONDecodeError.
+func DecodeJSONRequest[I any](options ...JSONDecoderOption) DecodeRequestFunc[I] {
+	d := &jsonDecoder{}
+	for _, option := range options {
+		option(d)
+	}
+	return func(_ context.Context, r *http.Request) (I, error) {
+		var request I
+		body, err := d.read(r.Body)
+		if err != nil {
+			return request, err
+		}
+		if d.maxDepth > 0 {
+			if offset, ok := withinDepth(body, d.maxDepth); !ok {
+				return request, &JSONDecodeError{
+					Offset: offset,
+					Reason: "nesting exceeds max depth of " + strconv.Itoa(d.maxDepth),
+				}
+			}
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(body))
+		if d.disallowUnknownFields {
+			dec.DisallowUnknownFields()
+		}
+		if d.useNumber {
+			dec.UseNumber()
+		}
+		if err := dec.Decode(&request); err != nil {
+			return request, jsonDecodeError(err, dec.InputOffset())
+		}
+		if dec.More() {
+			return request, &JSONDecodeError{Offset: dec.InputOffset(), Reason: "unexpected data after JSON value"}
+		}
+		return request, nil
+	}
+}
+
+func (d *jsonDecoder) read(body io.Reader) ([]byte, error) {
+	if d.maxBytes <= 0 {
+		return io.ReadAll(body)
+	}
+	b, err := io.ReadAll(io.LimitReader(body, d.maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > d.maxBytes {
+		return nil, &JSONDecodeError{
+			Reason: "body exceeds " + strconv.FormatInt(d.maxBytes, 10) + " bytes",
+			code:   http.StatusRequestEntityTooLarge,
+		}
+	}
+	return b, nil
+}
+
+// jsonDecodeError describes an error of encoding/json, at the offset of the
+// decoder if the error doesn't have one.
+func jsonDecodeError(err error, offset int64) error {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &syntaxErr):
+		return &JSONDecodeError{Offset: syntaxErr.Offset, Reason: "invalid JSON: " + syntaxErr.Error(), Err: err}
+	case errors.As(err, &typeErr):
+		return &JSONDecodeError{
+			Field:  typeErr.Field,
+			Offset: typeErr.Offset,
+			Reason: fmt.Sprintf("expected %s, have JSON %s", typeErr.Type, typeErr.Value),
+			Err:    err,
+		}
+	case err == io.EOF:
+		return &JSONDecodeError{Reason: "empty body", Err: err}
+	case err == io.ErrUnexpectedEOF:
+		return &JSONDecodeError{Offset: offset, Reason: "truncated JSON", Err: err}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		return &JSONDecodeError{Field: field, Offset: offset, Reason: "unknown field", Err: err}
+	}
+	return err
+}
+
+// withinDepth returns whether the nesting of objects and arrays in the JSON
+// body is within the max depth, and if not, the offset at which it exceeds
+// it.
+func withinDepth(body []byte, max int) (int64, bool) {
+	var (
+		depth    int
+		inString bool
+		escaped  bool
+	)
+	for i, c := range body {
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			switch c {
+			case '\\':
+				escaped = true
+			case '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '{' || c == '[':
+			if depth++; depth > max {
+				return int64(i), false
+			}
+		case c == '}' || c == ']':
+			depth--
+		}
+	}
+	return 0, true
+}
@@ -0,0 +1,88 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	httptransport "github.com/barrett370/kit/v2/transport/http"
+)
+
+type jsonRequest struct {
+	Name  string      `json:"name"`
+	Count int         `json:"count"`
+	Extra interface{} `json:"extra"`
+}
+
+func TestDecodeJSONRequest(t *testing.T) {
+	dec := httptransport.DecodeJSONRequest[jsonRequest](httptransport.JSONUseNumber())
+	req, err := dec(context.Background(), httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"a","count":2,"extra":12345678901234567890,"ignored":true}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := (jsonRequest{Name: "a", Count: 2, Extra: json.Number("12345678901234567890")}), req; want != have {
+		t.Errorf("want %+v, have %+v", want, have)
+	}
+}
+
+func TestDecodeJSONRequestErrors(t *testing.T) {
+	dec := httptransport.DecodeJSONRequest[jsonRequest](
+		httptransport.JSONDisallowUnknownFields(),
+		httptransport.JSONMaxDepth(2),
+		httptransport.JSONMaxBytes(64),
+	)
+	for _, tc := range []struct {
+		body   string
+		field  string
+		reason string
+		code   int
+	}{
+		{``, "", "empty body", 400},
+		{`{"name":`, "", "truncated JSON", 400},
+		{`{"name" "a"}`, "", "invalid JSON: invalid character '\"' after object key", 400},
+		{`{"count":"2"}`, "count", "expected int, have JSON string", 400},
+		{`{"nmae":"a"}`, "nmae", "unknown field", 400},
+		{`{"extra":[[1]]}`, "", "nesting exceeds max depth of 2", 400},
+		{`{"extra":"[[[[["}`, "", "", 0},
+		{`{"name":"a"} {}`, "", "unexpected data after JSON value", 400},
+		{`{"name":"` + strings.Repeat("a", 64) + `"}`, "", "body exceeds 64 bytes", 413},
+	} {
+		_, err := dec(context.Background(), httptest.NewRequest("POST", "/", strings.NewReader(tc.body)))
+		if tc.reason == "" {
+			if err != nil {
+				t.Errorf("%s: want no error, have %v", tc.body, err)
+			}
+			continue
+		}
+		var decodeErr *httptransport.JSONDecodeError
+		if !errors.As(err, &decodeErr) {
+			t.Errorf("%s: want a JSONDecodeError, have %v", tc.body, err)
+			continue
+		}
+		if want, have := tc.field, decodeErr.Field; want != have {
+			t.Errorf("%s: field: want %q, have %q", tc.body, want, have)
+		}
+		if want, have := tc.reason, decodeErr.Reason; want != have {
+			t.Errorf("%s: reason: want %q, have %q", tc.body, want, have)
+		}
+		if want, have := tc.code, decodeErr.StatusCode(); want != have {
+			t.Errorf("%s: code: want %d, have %d", tc.body, want, have)
+		}
+	}
+}
+
+func TestJSONDecodeErrorEncoding(t *testing.T) {
+	rec := httptest.NewRecorder()
+	err := &httptransport.JSONDecodeError{Field: "count", Offset: 10, Reason: "expected int, have JSON string"}
+	httptransport.DefaultErrorEncoder(context.Background(), err, rec)
+	if want, have := http.StatusBadRequest, rec.Code; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := `{"error":"field \"count\": expected int, have JSON string","field":"count","offset":10}`, rec.Body.String(); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+}
@@ -0,0 +1,185 @@
+package http
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EncodeFormRequest is an EncodeRequestFunc that serializes the request, a
+// struct or a pointer to one, as an application/x-www-form-urlencoded body.
+// Fields are named by their "url" tags, with the options of encoding/json:
+//
+//	type TokenRequest struct {
+//		GrantType string   `url:"grant_type"`
+//		Scope     []string `url:"scope,omitempty"`
+//		Secret    string   `url:"-"`
+//	}
+//
+// Fields without tags are named as they're declared. Strings, bools,
+// numbers, time.Times, which are formatted as RFC 3339, and
+// encoding.TextMarshalers are encoded, as are pointers to them, which are
+// omitted if nil, and slices of them, which are encoded as repeated keys. The
+// fields of embedded structs are encoded as fields of the request. If the
+// request implements Headerer, the provided headers will be applied to the
+// request.
+func EncodeFormRequest[I any](_ context.Context, r *http.Request, request I) error {
+	values, err := encodeValues(request)
+	if err != nil {
+		return err
+	}
+	setHeaders(r, request)
+	body := values.Encode()
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Body = io.NopCloser(strings.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader(body)), nil }
+	return nil
+}
+
+// EncodeQueryRequest is an EncodeRequestFunc that serializes the request as
+// the query parameters of the URL, which replace any parameters of the same
+// names. The request is encoded as by EncodeFormRequest.
+func EncodeQueryRequest[I any](_ context.Context, r *http.Request, request I) error {
+	values, err := encodeValues(request)
+	if err != nil {
+		return err
+	}
+	setHeaders(r, request)
+	q := r.URL.Query()
+	for k, v := range values {
+		q[k] = v
+	}
+	r.URL.RawQuery = q.Encode()
+	return nil
+}
+
+func setHeaders(r *http.Request, request interface{}) {
+	if headerer, ok := request.(Headerer); ok {
+		for k := range headerer.Headers() {
+			r.Header.Set(k, headerer.Headers().Get(k))
+		}
+	}
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func encodeValues(request interface{}) (url.Values, error) {
+	v := reflect.ValueOf(request)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return url.Values{}, nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("can't encode %T as values: not a struct", request)
+	}
+	values := url.Values{}
+	return values, encodeStruct(values, v)
+}
+
+func encodeStruct(values url.Values, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("url")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fv := v.Field(i)
+		if f.Anonymous && name == "" && indirectType(f.Type).Kind() == reflect.Struct && indirectType(f.Type) != timeType {
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			if err := encodeStruct(values, fv); err != nil {
+				return err
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if strings.Contains(","+opts+",", ",omitempty,") && fv.IsZero() {
+			continue
+		}
+		if fv.Kind() == reflect.Slice && !fv.Type().Implements(textMarshalerType) {
+			for j := 0; j < fv.Len(); j++ {
+				s, ok, err := formatValue(fv.Index(j))
+				if err != nil {
+					return fmt.Errorf("field %s: %w", f.Name, err)
+				}
+				if ok {
+					values.Add(name, s)
+				}
+			}
+			continue
+		}
+		s, ok, err := formatValue(fv)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		if ok {
+			values.Add(name, s)
+		}
+	}
+	return nil
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+// formatValue formats a value, and returns false if it's a nil pointer,
+// which is omitted.
+func formatValue(v reflect.Value) (string, bool, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", false, nil
+		}
+		v = v.Elem()
+	}
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).Format(time.RFC3339), true, nil
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textMarshalerType) {
+		v = v.Addr()
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		return string(b), true, err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true, nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true, nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), true, nil
+	}
+	return "", false, fmt.Errorf("unsupported type %s", v.Type())
+}
@@ -0,0 +1,71 @@
+package http_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	httptransport "github.com/barrett370/kit/v2/transport/http"
+)
+
+type Paging struct {
+	Page int `url:"page,omitempty"`
+}
+
+type formRequest struct {
+	Paging
+	GrantType string    `url:"grant_type"`
+	Scope     []string  `url:"scope,omitempty"`
+	Secret    string    `url:"-"`
+	Since     time.Time `url:"since,omitempty"`
+	Limit     *int      `url:"limit"`
+	Ratio     float64   `url:"ratio,omitempty"`
+	Plain     bool
+}
+
+func TestEncodeFormRequest(t *testing.T) {
+	limit := 10
+	req, _ := http.NewRequest("POST", "http://example.com/token", nil)
+	err := httptransport.EncodeFormRequest(context.Background(), req, &formRequest{
+		Paging:    Paging{Page: 2},
+		GrantType: "client_credentials",
+		Scope:     []string{"read", "write"},
+		Secret:    "s3cr3t",
+		Since:     time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Limit:     &limit,
+		Ratio:     0.5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "application/x-www-form-urlencoded", req.Header.Get("Content-Type"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	body, _ := io.ReadAll(req.Body)
+	want := "Plain=false&grant_type=client_credentials&limit=10&page=2&ratio=0.5&scope=read&scope=write&since=2020-01-02T03%3A04%3A05Z"
+	if have := string(body); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	if want, have := int64(len(want)), req.ContentLength; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestEncodeQueryRequest(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://example.com/search?page=1&q=kit", nil)
+	if err := httptransport.EncodeQueryRequest(context.Background(), req, formRequest{Paging: Paging{Page: 3}, GrantType: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "Plain=false&grant_type=x&page=3&q=kit", req.URL.RawQuery; want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+
+	if err := httptransport.EncodeQueryRequest(context.Background(), req, struct{ C chan int }{}); err == nil {
+		t.Error("want an error for an unsupported type, have none")
+	}
+	if err := httptransport.EncodeQueryRequest(context.Background(), req, "not a struct"); err == nil {
+		t.Error("want an error for a request which isn't a struct, have none")
+	}
+}
@@ -0,0 +1,180 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/barrett370/kit/v2/endpoint"
+)
+
+// ETagger may be implemented by response types that know their own entity
+// tag, e.g. a version number or a hash stored alongside the resource. If a
+// response implements ETagger, ETagEncoder uses the provided tag instead of
+// hashing the encoded response body.
+type ETagger interface {
+	ETag() string
+}
+
+// ErrPreconditionFailed is returned by IfMatch when the If-Match request
+// header doesn't match the current entity tag of the resource. It implements
+// StatusCoder, so the DefaultErrorEncoder responds with 412.
+var ErrPreconditionFailed error = preconditionFailedError{}
+
+type preconditionFailedError struct{}
+
+func (preconditionFailedError) Error() string { return "precondition failed" }
+
+// StatusCode implements StatusCoder.
+func (preconditionFailedError) StatusCode() int { return http.StatusPreconditionFailed }
+
+// ETagEncoder wraps an EncodeResponseFunc and sets an ETag header on every
+// successful response. If the response implements ETagger, that tag is used;
+// otherwise the response is encoded into a buffer and the tag is derived from
+// a hash of the body. If weak is true, computed tags are marked as weak
+// validators.
+//
+// For GET and HEAD requests whose If-None-Match header matches the tag, a 304
+// Not Modified is written instead of the response body. The request method
+// and conditional headers are read from the context, so the server must be
+// configured with ServerBefore(PopulateRequestContext).
+func ETagEncoder[O any](next EncodeResponseFunc[O], weak bool) EncodeResponseFunc[O] {
+	return func(ctx context.Context, w http.ResponseWriter, response O) error {
+		if etagger, ok := any(response).(ETagger); ok {
+			tag := quoteETag(etagger.ETag())
+			w.Header().Set("ETag", tag)
+			if notModified(ctx, tag) {
+				w.WriteHeader(http.StatusNotModified)
+				return nil
+			}
+			return next(ctx, w, response)
+		}
+
+		bw := &bufferedResponseWriter{ResponseWriter: w, code: http.StatusOK}
+		if err := next(ctx, bw, response); err != nil {
+			return err
+		}
+		if bw.code < 200 || bw.code > 299 {
+			return bw.flush()
+		}
+
+		tag := computeETag(bw.buf.Bytes(), weak)
+		w.Header().Set("ETag", tag)
+		if notModified(ctx, tag) {
+			w.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+		return bw.flush()
+	}
+}
+
+// IfMatch returns an endpoint middleware that enforces If-Match preconditions
+// for mutating endpoints. The current function yields the entity tag of the
+// resource addressed by the request. If the request carried an If-Match
+// header which doesn't match the current tag, ErrPreconditionFailed is
+// returned and the wrapped endpoint is not invoked. Requests without an
+// If-Match header are passed through unmodified.
+//
+// The If-Match header is read from the context, so the server must be
+// configured with ServerBefore(PopulateRequestContext).
+func IfMatch[I, O any](current func(ctx context.Context, request I) (etag string, err error)) endpoint.Middleware[I, O] {
+	return func(next endpoint.Endpoint[I, O]) endpoint.Endpoint[I, O] {
+		return func(ctx context.Context, request I) (O, error) {
+			header := RequestIfMatchKey.Value(ctx)
+			if header == "" {
+				return next(ctx, request)
+			}
+
+			tag, err := current(ctx, request)
+			if err != nil {
+				var zero O
+				return zero, err
+			}
+			if !matchETag(header, quoteETag(tag), false) {
+				var zero O
+				return zero, ErrPreconditionFailed
+			}
+
+			return next(ctx, request)
+		}
+	}
+}
+
+func notModified(ctx context.Context, tag string) bool {
+	method := RequestMethodKey.Value(ctx)
+	if method != http.MethodGet && method != http.MethodHead {
+		return false
+	}
+	header := RequestIfNoneMatchKey.Value(ctx)
+	return header != "" && matchETag(header, tag, true)
+}
+
+// matchETag reports whether tag is matched by the comma-separated list of
+// entity tags in header, per RFC 7232 section 2.3.2. Weak comparison ignores
+// the W/ prefix; strong comparison never matches a weak tag.
+func matchETag(header, tag string, weakComparison bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weakComparison {
+			if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(tag, "W/") {
+				return true
+			}
+			continue
+		}
+		if strings.HasPrefix(candidate, "W/") || strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		if candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteETag makes sure the given tag is a valid, quoted entity tag.
+func quoteETag(tag string) string {
+	weak := strings.HasPrefix(tag, "W/")
+	tag = strings.TrimPrefix(tag, "W/")
+	if !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) || len(tag) < 2 {
+		tag = `"` + strings.Trim(tag, `"`) + `"`
+	}
+	if weak {
+		tag = "W/" + tag
+	}
+	return tag
+}
+
+func computeETag(body []byte, weak bool) string {
+	sum := sha256.Sum256(body)
+	tag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	if weak {
+		tag = "W/" + tag
+	}
+	return tag
+}
+
+// bufferedResponseWriter captures the status code and body written by an
+// EncodeResponseFunc, so they can be inspected before being sent. Headers are
+// written straight through to the wrapped ResponseWriter, as they aren't sent
+// until the first call to WriteHeader.
+type bufferedResponseWriter struct {
+	http.ResponseWriter
+	code int
+	buf  bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) WriteHeader(code int) { w.code = code }
+
+func (w *bufferedResponseWriter) Write(p []byte) (int, error) { return w.buf.Write(p) }
+
+func (w *bufferedResponseWriter) flush() error {
+	w.ResponseWriter.WriteHeader(w.code)
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	return err
+}
@@ -0,0 +1,139 @@
+package http_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	httptransport "github.com/barrett370/kit/v2/transport/http"
+)
+
+type versionedResponse struct {
+	Version string `json:"version"`
+}
+
+func (r versionedResponse) ETag() string { return r.Version }
+
+func TestETagEncoderComputed(t *testing.T) {
+	handler := httptransport.NewServer(
+		func(context.Context, interface{}) (interface{}, error) { return map[string]string{"a": "b"}, nil },
+		func(context.Context, *http.Request) (interface{}, error) { return struct{}{}, nil },
+		httptransport.ETagEncoder(httptransport.EncodeJSONResponse, false),
+		httptransport.ServerBefore[any, any](httptransport.PopulateRequestContext),
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	tag := resp.Header.Get("ETag")
+	if tag == "" {
+		t.Fatal("no ETag header set")
+	}
+	if want, have := `{"a":"b"}`+"\n", string(body); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	req.Header.Set("If-None-Match", tag)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want, have := http.StatusNotModified, resp.StatusCode; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := tag, resp.Header.Get("ETag"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestETagEncoderETagger(t *testing.T) {
+	handler := httptransport.NewServer(
+		func(context.Context, interface{}) (interface{}, error) { return versionedResponse{"v7"}, nil },
+		func(context.Context, *http.Request) (interface{}, error) { return struct{}{}, nil },
+		httptransport.ETagEncoder(httptransport.EncodeJSONResponse, true),
+		httptransport.ServerBefore[any, any](httptransport.PopulateRequestContext),
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	for _, tc := range []struct {
+		ifNoneMatch string
+		code        int
+	}{
+		{"", http.StatusOK},
+		{`"v6"`, http.StatusOK},
+		{`"v6", W/"v7"`, http.StatusNotModified},
+		{"*", http.StatusNotModified},
+	} {
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		if tc.ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", tc.ifNoneMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if want, have := tc.code, resp.StatusCode; want != have {
+			t.Errorf("If-None-Match %q: want %d, have %d", tc.ifNoneMatch, want, have)
+		}
+		if want, have := `"v7"`, resp.Header.Get("ETag"); want != have {
+			t.Errorf("want %q, have %q", want, have)
+		}
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	var invoked bool
+	e := httptransport.IfMatch[any, any](func(context.Context, interface{}) (string, error) {
+		return "v2", nil
+	})(func(context.Context, interface{}) (interface{}, error) {
+		invoked = true
+		return struct{}{}, nil
+	})
+	handler := httptransport.NewServer(
+		e,
+		func(context.Context, *http.Request) (interface{}, error) { return struct{}{}, nil },
+		httptransport.EncodeJSONResponse,
+		httptransport.ServerBefore[any, any](httptransport.PopulateRequestContext),
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	for _, tc := range []struct {
+		ifMatch string
+		code    int
+		invoked bool
+	}{
+		{"", http.StatusOK, true},
+		{`"v2"`, http.StatusOK, true},
+		{`"v1"`, http.StatusPreconditionFailed, false},
+		{`W/"v2"`, http.StatusPreconditionFailed, false},
+	} {
+		invoked = false
+		req, _ := http.NewRequest("PUT", server.URL, nil)
+		if tc.ifMatch != "" {
+			req.Header.Set("If-Match", tc.ifMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if want, have := tc.code, resp.StatusCode; want != have {
+			t.Errorf("If-Match %q: want %d, have %d", tc.ifMatch, want, have)
+		}
+		if want, have := tc.invoked, invoked; want != have {
+			t.Errorf("If-Match %q: want invoked %v, have %v", tc.ifMatch, want, have)
+		}
+	}
+}
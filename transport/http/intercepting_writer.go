@@ -9,6 +9,7 @@ type interceptingWriter struct {
 	http.ResponseWriter
 	code    int
 	written int64
+	err     error
 }
 
 // WriteHeader may not be explicitly called, so care must be taken to
@@ -20,7 +21,40 @@ func (w *interceptingWriter) WriteHeader(code int) {
 
 func (w *interceptingWriter) Write(p []byte) (int, error) {
 	n, err := w.ResponseWriter.Write(p)
-	w.written += int64(n)
+	w.record(int64(n), err)
+	return n, err
+}
+
+// record counts the bytes written, and keeps the first write error.
+func (w *interceptingWriter) record(n int64, err error) {
+	w.written += n
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+}
+
+// countingReaderFrom counts the bytes written by the io.ReaderFrom of the
+// wrapped ResponseWriter, which bypasses Write.
+type countingReaderFrom struct {
+	w  *interceptingWriter
+	rf io.ReaderFrom
+}
+
+func (c countingReaderFrom) ReadFrom(r io.Reader) (int64, error) {
+	n, err := c.rf.ReadFrom(r)
+	c.w.record(n, err)
+	return n, err
+}
+
+// countingReader counts the bytes read from a request body.
+type countingReader struct {
+	io.ReadCloser
+	read int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.read += int64(n)
 	return n, err
 }
 
@@ -39,6 +73,9 @@ func (w *interceptingWriter) reimplementInterfaces() http.ResponseWriter {
 		fl, i3 = w.ResponseWriter.(http.Flusher)
 		rf, i4 = w.ResponseWriter.(io.ReaderFrom)
 	)
+	if i4 {
+		rf = countingReaderFrom{w, rf}
+	}
 
 	switch {
 	case !i0 && !i1 && !i2 && !i3 && !i4:
@@ -0,0 +1,610 @@
+// Package openapi generates OpenAPI 3 documents from typed HTTP servers.
+//
+// Because Go kit servers are parameterized by their request and response
+// types, the shape of each route's input and output is known at compile time.
+// This package reflects over those types to produce schemas, parameters, and
+// request bodies, so the document can't drift from the code.
+//
+// Register each route as it's added to your router:
+//
+//	spec := openapi.New("addsvc", "1.0.0")
+//	sum, err := openapi.Route(spec, "POST", "/sum", sumServer)
+//	if err != nil {
+//		return err
+//	}
+//	mux.Handle("/sum", sum)
+//	mux.Handle("/openapi.json", spec.Handler())
+//
+// Request struct fields tagged with `path:"name"`, `query:"name"`, or
+// `header:"name"` are described as parameters; all other exported fields
+// form the JSON request body, named according to their `json` tags. A
+// `description:"..."` tag on any field is carried into the document.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	httptransport "github.com/barrett370/kit/v2/transport/http"
+)
+
+// Document is an OpenAPI 3 document. Only the subset of the specification
+// which can be derived from Go types is modeled.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Servers    []Server             `json:"servers,omitempty"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components *Components          `json:"components,omitempty"`
+}
+
+// Info provides metadata about the API.
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Server describes a host serving the API.
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem describes the operations available on a single path.
+type PathItem struct {
+	Get     *Operation `json:"get,omitempty"`
+	Put     *Operation `json:"put,omitempty"`
+	Post    *Operation `json:"post,omitempty"`
+	Delete  *Operation `json:"delete,omitempty"`
+	Options *Operation `json:"options,omitempty"`
+	Head    *Operation `json:"head,omitempty"`
+	Patch   *Operation `json:"patch,omitempty"`
+}
+
+// Operation describes a single API operation on a path.
+type Operation struct {
+	OperationID string               `json:"operationId,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter describes a single operation parameter.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+// RequestBody describes a single request body.
+type RequestBody struct {
+	Description string                `json:"description,omitempty"`
+	Required    bool                  `json:"required,omitempty"`
+	Content     map[string]*MediaType `json:"content"`
+}
+
+// Response describes a single response from an API operation.
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType provides the schema for a given content type.
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Components holds reusable schemas.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// Schema is a JSON schema object, as used by OpenAPI 3.0.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+// Spec collects routes and renders them as an OpenAPI document. It's safe for
+// concurrent use.
+type Spec struct {
+	mtx     sync.Mutex
+	info    Info
+	servers []Server
+	paths   map[string]*PathItem
+	schemas map[string]*Schema
+	names   map[reflect.Type]string // component schema names, by type
+}
+
+// Option sets an optional parameter for specs.
+type Option func(*Spec)
+
+// Description sets the description of the API.
+func Description(description string) Option {
+	return func(s *Spec) { s.info.Description = description }
+}
+
+// Servers adds the URLs of hosts serving the API.
+func Servers(urls ...string) Option {
+	return func(s *Spec) {
+		for _, url := range urls {
+			s.servers = append(s.servers, Server{URL: url})
+		}
+	}
+}
+
+// New returns an empty spec for an API with the given title and version.
+func New(title, version string, options ...Option) *Spec {
+	s := &Spec{
+		info:    Info{Title: title, Version: version},
+		paths:   map[string]*PathItem{},
+		schemas: map[string]*Schema{},
+		names:   map[reflect.Type]string{},
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// OperationOption sets an optional parameter for a single operation.
+type OperationOption func(*Operation)
+
+// OperationID sets the unique identifier of the operation.
+func OperationID(id string) OperationOption {
+	return func(o *Operation) { o.OperationID = id }
+}
+
+// Summary sets a short summary of what the operation does.
+func Summary(summary string) OperationOption {
+	return func(o *Operation) { o.Summary = summary }
+}
+
+// OperationDescription sets a verbose explanation of the operation behavior.
+func OperationDescription(description string) OperationOption {
+	return func(o *Operation) { o.Description = description }
+}
+
+// Tags sets a list of tags for logical grouping of operations.
+func Tags(tags ...string) OperationOption {
+	return func(o *Operation) { o.Tags = append(o.Tags, tags...) }
+}
+
+// Deprecated marks the operation as deprecated.
+func Deprecated() OperationOption {
+	return func(o *Operation) { o.Deprecated = true }
+}
+
+// ErrorResponse documents an additional status code the operation may return.
+// The error is assumed to be rendered as plain text, as by the
+// DefaultErrorEncoder.
+func ErrorResponse(code int, description string) OperationOption {
+	return func(o *Operation) {
+		o.Responses[statusKey(code)] = &Response{
+			Description: description,
+			Content:     map[string]*MediaType{"text/plain": {Schema: &Schema{Type: "string"}}},
+		}
+	}
+}
+
+// Route registers the typed server as the handler for the given method and
+// path, and returns it unmodified, so it can be passed straight to a router.
+// Paths use OpenAPI templating, e.g. "/users/{id}".
+func Route[I, O any](s *Spec, method, path string, server *httptransport.Server[I, O], options ...OperationOption) (*httptransport.Server[I, O], error) {
+	if err := Register[I, O](s, method, path, options...); err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+// Register describes an operation with the given method and path, taking
+// its request and response schemas from the type parameters. It's useful
+// when the route isn't served by an httptransport.Server, or when the
+// server is wrapped by other handlers. It returns an error if OpenAPI
+// doesn't support the method, e.g. CONNECT.
+func Register[I, O any](s *Spec, method, path string, options ...OperationOption) error {
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete, http.MethodOptions, http.MethodHead, http.MethodPatch:
+	default:
+		return fmt.Errorf("openapi: unsupported method %q", method)
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	op := &Operation{
+		Responses: map[string]*Response{},
+	}
+
+	reqType := reflect.TypeOf((*I)(nil)).Elem()
+	op.Parameters, op.RequestBody = s.request(reqType, method)
+
+	resp := &Response{Description: http.StatusText(http.StatusOK)}
+	if schema := s.schemaFor(reflect.TypeOf((*O)(nil)).Elem()); schema != nil {
+		resp.Content = map[string]*MediaType{"application/json": {Schema: schema}}
+	}
+	op.Responses[statusKey(http.StatusOK)] = resp
+
+	for _, option := range options {
+		option(op)
+	}
+
+	item, ok := s.paths[path]
+	if !ok {
+		item = &PathItem{}
+		s.paths[path] = item
+	}
+	switch strings.ToUpper(method) {
+	case http.MethodGet:
+		item.Get = op
+	case http.MethodPut:
+		item.Put = op
+	case http.MethodPost:
+		item.Post = op
+	case http.MethodDelete:
+		item.Delete = op
+	case http.MethodOptions:
+		item.Options = op
+	case http.MethodHead:
+		item.Head = op
+	case http.MethodPatch:
+		item.Patch = op
+	}
+	return nil
+}
+
+// Document returns the OpenAPI document describing all registered routes. It's
+// a copy, so it's unaffected by later registrations, and may be modified.
+func (s *Spec) Document() *Document {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    s.info,
+		Servers: append([]Server(nil), s.servers...),
+		Paths:   make(map[string]*PathItem, len(s.paths)),
+	}
+	for path, item := range s.paths {
+		doc.Paths[path] = item.clone()
+	}
+	if len(s.schemas) > 0 {
+		doc.Components = &Components{Schemas: make(map[string]*Schema, len(s.schemas))}
+		for name, schema := range s.schemas {
+			doc.Components.Schemas[name] = schema.clone()
+		}
+	}
+	return doc
+}
+
+func (item *PathItem) clone() *PathItem {
+	return &PathItem{
+		Get:     item.Get.clone(),
+		Put:     item.Put.clone(),
+		Post:    item.Post.clone(),
+		Delete:  item.Delete.clone(),
+		Options: item.Options.clone(),
+		Head:    item.Head.clone(),
+		Patch:   item.Patch.clone(),
+	}
+}
+
+func (op *Operation) clone() *Operation {
+	if op == nil {
+		return nil
+	}
+	c := *op
+	c.Tags = append([]string(nil), op.Tags...)
+	c.Parameters = nil
+	for _, p := range op.Parameters {
+		p.Schema = p.Schema.clone()
+		c.Parameters = append(c.Parameters, p)
+	}
+	if op.RequestBody != nil {
+		body := *op.RequestBody
+		body.Content = cloneContent(op.RequestBody.Content)
+		c.RequestBody = &body
+	}
+	c.Responses = make(map[string]*Response, len(op.Responses))
+	for code, resp := range op.Responses {
+		r := *resp
+		r.Content = cloneContent(resp.Content)
+		c.Responses[code] = &r
+	}
+	return &c
+}
+
+func cloneContent(content map[string]*MediaType) map[string]*MediaType {
+	if content == nil {
+		return nil
+	}
+	c := make(map[string]*MediaType, len(content))
+	for typ, mt := range content {
+		c[typ] = &MediaType{Schema: mt.Schema.clone()}
+	}
+	return c
+}
+
+func (schema *Schema) clone() *Schema {
+	if schema == nil {
+		return nil
+	}
+	c := *schema
+	c.Items = schema.Items.clone()
+	c.AdditionalProperties = schema.AdditionalProperties.clone()
+	c.Required = append([]string(nil), schema.Required...)
+	if schema.Properties != nil {
+		c.Properties = make(map[string]*Schema, len(schema.Properties))
+		for name, prop := range schema.Properties {
+			c.Properties[name] = prop.clone()
+		}
+	}
+	return &c
+}
+
+// Handler returns an http.Handler that serves the document as JSON, suitable
+// for mounting at e.g. /openapi.json. The document is rendered on each
+// request, so routes registered later are included.
+func (s *Spec) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(s.Document())
+	})
+}
+
+// request splits the request type into parameters and a request body.
+func (s *Spec) request(t reflect.Type, method string) ([]Parameter, *RequestBody) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		if t.Kind() == reflect.Interface || !hasBody(method) {
+			return nil, nil
+		}
+		return nil, jsonBody(s.schemaFor(t))
+	}
+
+	var (
+		params []Parameter
+		body   = &Schema{Type: "object", Properties: map[string]*Schema{}}
+	)
+	for _, f := range fields(t) {
+		var in, name string
+		for _, tag := range []string{"path", "query", "header"} {
+			if v, ok := f.Tag.Lookup(tag); ok {
+				in, name = tag, strings.Split(v, ",")[0]
+				break
+			}
+		}
+		if in != "" {
+			params = append(params, Parameter{
+				Name:        name,
+				In:          in,
+				Description: f.Tag.Get("description"),
+				Required:    in == "path" || f.Type.Kind() != reflect.Ptr,
+				Schema:      s.schemaFor(f.Type),
+			})
+			continue
+		}
+		s.addProperty(body, f)
+	}
+	if len(body.Properties) == 0 || !hasBody(method) {
+		return params, nil
+	}
+	return params, jsonBody(body)
+}
+
+func jsonBody(schema *Schema) *RequestBody {
+	return &RequestBody{
+		Required: true,
+		Content:  map[string]*MediaType{"application/json": {Schema: schema}},
+	}
+}
+
+// schemaFor returns a schema describing values of type t. Named struct types
+// are added to the component schemas and referenced. It returns nil for
+// interface types, whose shape is unknown.
+func (s *Spec) schemaFor(t reflect.Type) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t, nullable = t.Elem(), true
+	}
+
+	var schema *Schema
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		schema = &Schema{Type: "string", Format: "date-time"}
+	case t == reflect.TypeOf(time.Duration(0)):
+		schema = &Schema{Type: "integer", Format: "int64"}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		schema = &Schema{Type: "string", Format: "byte"}
+	default:
+		switch t.Kind() {
+		case reflect.Interface:
+			return nil
+		case reflect.Bool:
+			schema = &Schema{Type: "boolean"}
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+			schema = &Schema{Type: "integer", Format: "int32"}
+		case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+			schema = &Schema{Type: "integer", Format: "int64"}
+		case reflect.Float32:
+			schema = &Schema{Type: "number", Format: "float"}
+		case reflect.Float64:
+			schema = &Schema{Type: "number", Format: "double"}
+		case reflect.String:
+			schema = &Schema{Type: "string"}
+		case reflect.Slice, reflect.Array:
+			items := s.schemaFor(t.Elem())
+			if items == nil {
+				items = &Schema{}
+			}
+			schema = &Schema{Type: "array", Items: items}
+		case reflect.Map:
+			values := s.schemaFor(t.Elem())
+			if values == nil {
+				values = &Schema{}
+			}
+			schema = &Schema{Type: "object", AdditionalProperties: values}
+		case reflect.Struct:
+			schema = s.structSchema(t)
+		default:
+			schema = &Schema{}
+		}
+	}
+
+	if nullable && schema.Ref == "" {
+		schema.Nullable = true
+	}
+	return schema
+}
+
+func (s *Spec) structSchema(t reflect.Type) *Schema {
+	if name, ok := s.names[t]; ok {
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	name := s.uniqueName(t)
+	if name != "" {
+		s.names[t] = name
+		s.schemas[name] = &Schema{} // placeholder, in case of recursive types
+	}
+
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for _, f := range fields(t) {
+		s.addProperty(schema, f)
+	}
+	sort.Strings(schema.Required)
+
+	if name == "" {
+		return schema
+	}
+	s.schemas[name] = schema
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func (s *Spec) addProperty(schema *Schema, f reflect.StructField) {
+	name, omitempty := f.Name, false
+	if tag, ok := f.Tag.Lookup("json"); ok {
+		parts := strings.Split(tag, ",")
+		if parts[0] != "" {
+			name = parts[0]
+		}
+		for _, opt := range parts[1:] {
+			omitempty = omitempty || opt == "omitempty"
+		}
+	}
+
+	prop := s.schemaFor(f.Type)
+	if prop == nil {
+		prop = &Schema{}
+	}
+	// Siblings of $ref are ignored in OpenAPI 3.0, so descriptions of
+	// references are dropped.
+	if description := f.Tag.Get("description"); description != "" && prop.Ref == "" {
+		prop.Description = description
+	}
+	schema.Properties[name] = prop
+	if !omitempty && f.Type.Kind() != reflect.Ptr {
+		schema.Required = append(schema.Required, name)
+	}
+}
+
+// fields returns the exported, JSON-visible fields of a struct type,
+// flattening anonymous struct fields as encoding/json does.
+func fields(t reflect.Type) []reflect.StructField {
+	var fs []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Tag.Get("json") == "-" {
+			continue
+		}
+		if f.Anonymous {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && f.Tag.Get("json") == "" {
+				fs = append(fs, fields(ft)...)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		fs = append(fs, f)
+	}
+	return fs
+}
+
+// schemaName returns the component name for a named type. Instantiated
+// generic types have their type arguments stripped of package paths.
+func schemaName(t reflect.Type) string {
+	name := t.Name()
+	if name == "" {
+		return ""
+	}
+	return strings.NewReplacer("[", "_", "]", "", ",", "_", "*", "", " ", "").Replace(shortenTypeArgs(name))
+}
+
+// uniqueName returns the schema name of the type, with a numeric suffix if
+// another type, e.g. of the same name in another package, already has it.
+func (s *Spec) uniqueName(t reflect.Type) string {
+	base := schemaName(t)
+	if base == "" {
+		return ""
+	}
+	name := base
+	for i := 2; s.schemas[name] != nil; i++ {
+		name = base + strconv.Itoa(i)
+	}
+	return name
+}
+
+func shortenTypeArgs(name string) string {
+	i := strings.IndexByte(name, '[')
+	if i < 0 {
+		return name
+	}
+	args := strings.Split(strings.TrimSuffix(name[i+1:], "]"), ",")
+	for j, arg := range args {
+		if k := strings.LastIndexByte(arg, '.'); k >= 0 {
+			args[j] = arg[k+1:]
+		}
+	}
+	return name[:i] + "[" + strings.Join(args, ",") + "]"
+}
+
+func hasBody(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead, http.MethodDelete, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+func statusKey(code int) string {
+	return strconv.Itoa(code)
+}
@@ -0,0 +1,189 @@
+package openapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	httptransport "github.com/barrett370/kit/v2/transport/http"
+	"github.com/barrett370/kit/v2/transport/http/openapi"
+)
+
+type getUserRequest struct {
+	ID      string `path:"id" description:"user ID"`
+	Verbose *bool  `query:"verbose"`
+}
+
+type updateUserRequest struct {
+	ID   string   `path:"id" json:"-"`
+	Name string   `json:"name"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+type user struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name" description:"display name"`
+	Created time.Time `json:"created"`
+	Manager *user     `json:"manager,omitempty"`
+}
+
+func TestRoute(t *testing.T) {
+	spec := openapi.New("users", "1.0.0", openapi.Servers("https://api.example.com"))
+
+	getServer := httptransport.NewServer(
+		func(context.Context, getUserRequest) (user, error) { return user{}, nil },
+		func(context.Context, *http.Request) (getUserRequest, error) { return getUserRequest{}, nil },
+		func(ctx context.Context, w http.ResponseWriter, response user) error {
+			return httptransport.EncodeJSONResponse(ctx, w, response)
+		},
+	)
+	if have, err := openapi.Route(spec, "GET", "/users/{id}", getServer, openapi.OperationID("getUser")); err != nil || have != getServer {
+		t.Fatalf("Route didn't return the server: %v", err)
+	}
+	if err := openapi.Register[updateUserRequest, user](spec, "PUT", "/users/{id}", openapi.ErrorResponse(http.StatusNotFound, "no such user")); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := spec.Document()
+	item, ok := doc.Paths["/users/{id}"]
+	if !ok {
+		t.Fatal("path not registered")
+	}
+
+	get := item.Get
+	if want, have := "getUser", get.OperationID; want != have {
+		t.Errorf("operationId: want %q, have %q", want, have)
+	}
+	if get.RequestBody != nil {
+		t.Errorf("GET: want no request body, have %+v", get.RequestBody)
+	}
+	wantParams := []openapi.Parameter{
+		{Name: "id", In: "path", Description: "user ID", Required: true, Schema: &openapi.Schema{Type: "string"}},
+		{Name: "verbose", In: "query", Schema: &openapi.Schema{Type: "boolean", Nullable: true}},
+	}
+	if want, have := wantParams, get.Parameters; !reflect.DeepEqual(want, have) {
+		t.Errorf("parameters: want %+v, have %+v", want, have)
+	}
+	if want, have := "#/components/schemas/user", get.Responses["200"].Content["application/json"].Schema.Ref; want != have {
+		t.Errorf("response schema: want %q, have %q", want, have)
+	}
+
+	put := item.Put
+	if put.RequestBody == nil {
+		t.Fatal("PUT: want request body")
+	}
+	body := put.RequestBody.Content["application/json"].Schema
+	if want, have := []string{"name"}, body.Required; !reflect.DeepEqual(want, have) {
+		t.Errorf("required: want %v, have %v", want, have)
+	}
+	if want, have := 2, len(body.Properties); want != have {
+		t.Errorf("properties: want %d, have %d", want, have)
+	}
+	if _, ok := put.Responses["404"]; !ok {
+		t.Error("404 response not documented")
+	}
+
+	schema := doc.Components.Schemas["user"]
+	if want, have := []string{"created", "id", "name"}, schema.Required; !reflect.DeepEqual(want, have) {
+		t.Errorf("required: want %v, have %v", want, have)
+	}
+	if want, have := (&openapi.Schema{Type: "string", Format: "date-time"}), schema.Properties["created"]; !reflect.DeepEqual(want, have) {
+		t.Errorf("created: want %+v, have %+v", want, have)
+	}
+	if want, have := "display name", schema.Properties["name"].Description; want != have {
+		t.Errorf("description: want %q, have %q", want, have)
+	}
+	if want, have := "#/components/schemas/user", schema.Properties["manager"].Ref; want != have {
+		t.Errorf("manager: want %q, have %q", want, have)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	spec := openapi.New("users", "1.0.0")
+	openapi.Register[getUserRequest, user](spec, "GET", "/users/{id}")
+
+	rec := httptest.NewRecorder()
+	spec.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/openapi.json", nil))
+
+	var doc map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "3.0.3", doc["openapi"]; want != have {
+		t.Errorf("openapi: want %v, have %v", want, have)
+	}
+	if _, ok := doc["paths"].(map[string]interface{})["/users/{id}"]; !ok {
+		t.Errorf("path missing from %v", doc["paths"])
+	}
+}
+
+func TestRegisterUnsupportedMethod(t *testing.T) {
+	spec := openapi.New("users", "1.0.0")
+	if err := openapi.Register[getUserRequest, user](spec, "CONNECT", "/users/{id}"); err == nil {
+		t.Error("want an error for CONNECT, have none")
+	}
+	if want, have := 0, len(spec.Document().Paths); want != have {
+		t.Errorf("paths: want %d, have %d", want, have)
+	}
+}
+
+func TestSchemaNameCollision(t *testing.T) {
+	// Another type named user, as if from another package.
+	type user struct {
+		Email string `json:"email"`
+	}
+	type users struct {
+		Local   user     `json:"local"`
+		Package *userRef `json:"package"`
+	}
+
+	spec := openapi.New("users", "1.0.0")
+	openapi.Register[struct{}, users](spec, "GET", "/users")
+	schemas := spec.Document().Components.Schemas
+
+	props := schemas["users"].Properties
+	local, pkg := props["local"].Ref, props["package"].Ref
+	if local == pkg {
+		t.Fatalf("both user types refer to %q", local)
+	}
+	if want, have := "#/components/schemas/user", local; want != have {
+		t.Errorf("local: want %q, have %q", want, have)
+	}
+	if want, have := "#/components/schemas/user2", pkg; want != have {
+		t.Errorf("package: want %q, have %q", want, have)
+	}
+	if _, ok := schemas["user"].Properties["email"]; !ok {
+		t.Errorf("user: want the local type, have %+v", schemas["user"])
+	}
+	if _, ok := schemas["user2"].Properties["created"]; !ok {
+		t.Errorf("user2: want the package type, have %+v", schemas["user2"])
+	}
+}
+
+// userRef refers to the package-level user type, by another name.
+type userRef = user
+
+func TestDocumentIsACopy(t *testing.T) {
+	spec := openapi.New("users", "1.0.0")
+	openapi.Register[getUserRequest, user](spec, "GET", "/users/{id}")
+	doc := spec.Document()
+
+	doc.Paths["/users/{id}"].Get.Summary = "modified"
+	doc.Components.Schemas["user"].Properties["name"].Description = "modified"
+	openapi.Register[updateUserRequest, user](spec, "PUT", "/users/{id}")
+
+	if doc.Paths["/users/{id}"].Put != nil {
+		t.Error("later registration changed the returned document")
+	}
+	fresh := spec.Document()
+	if want, have := "", fresh.Paths["/users/{id}"].Get.Summary; want != have {
+		t.Errorf("summary: want %q, have %q", want, have)
+	}
+	if want, have := "display name", fresh.Components.Schemas["user"].Properties["name"].Description; want != have {
+		t.Errorf("description: want %q, have %q", want, have)
+	}
+}
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/go-kit/log"
+)
+
+// ServerRequestID makes the server read a request ID from each incoming
+// request, generating one if the request didn't carry one. The ID is stored
+// in the context under RequestIDKey, and set as a response header, on
+// errors as well as successful responses.
+//
+// A generated ID is also set as a header on the incoming request, so that
+// it's seen by every ServerBefore func, e.g. PopulateRequestContext.
+// Responses served from a ServerResponseCache carry the ID of the request
+// they're served to, not that of the request which filled the cache.
+func ServerRequestID[I, O any](options ...RequestIDOption) ServerOption[I, O] {
+	r := &requestID{
+		header:   "X-Request-Id",
+		generate: NewRequestID,
+	}
+	for _, option := range options {
+		option(r)
+	}
+	return func(s *Server[I, O]) { s.requestID = r }
+}
+
+// RequestIDOption sets an optional parameter for ServerRequestID.
+type RequestIDOption func(*requestID)
+
+// RequestIDHeader sets the name of the header carrying the request ID. By
+// default, X-Request-Id is used.
+func RequestIDHeader(header string) RequestIDOption {
+	return func(r *requestID) { r.header = header }
+}
+
+// RequestIDGenerator sets the func used to generate a request ID when the
+// request doesn't carry one. By default, NewRequestID is used.
+func RequestIDGenerator(generate func() string) RequestIDOption {
+	return func(r *requestID) { r.generate = generate }
+}
+
+// NewRequestID returns a random (version 4) UUID.
+func NewRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // variant 10
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], b[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], b[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], b[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], b[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], b[10:])
+	return string(buf[:])
+}
+
+// RequestIDFromContext returns the request ID stored in the context by a
+// server configured with ServerRequestID, or the empty string.
+func RequestIDFromContext(ctx context.Context) string {
+	id := RequestIDKey.Value(ctx)
+	return id
+}
+
+// RequestIDValuer returns a log.Valuer which yields the request ID stored in
+// the context. Use it to include the ID in every log line of a request:
+//
+//	logger := log.With(logger, "request_id", httptransport.RequestIDValuer(ctx))
+func RequestIDValuer(ctx context.Context) log.Valuer {
+	return func() interface{} { return RequestIDFromContext(ctx) }
+}
+
+type requestID struct {
+	header   string
+	generate func() string
+}
+
+func (rid *requestID) handle(ctx context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+	id := r.Header.Get(rid.header)
+	if id == "" {
+		id = rid.generate()
+		r.Header.Set(rid.header, id)
+	}
+	w.Header().Set(rid.header, id)
+	return RequestIDKey.Set(ctx, id)
+}
@@ -0,0 +1,105 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	httptransport "github.com/barrett370/kit/v2/transport/http"
+)
+
+func TestServerRequestID(t *testing.T) {
+	var (
+		buf    bytes.Buffer
+		logger = log.NewLogfmtLogger(&buf)
+		seen   string
+	)
+	handler := httptransport.NewServer(
+		func(ctx context.Context, request struct{}) (struct{}, error) {
+			seen = httptransport.RequestIDFromContext(ctx)
+			log.With(logger, "request_id", httptransport.RequestIDValuer(ctx)).Log("msg", "hello")
+			return struct{}{}, errors.New("dang")
+		},
+		func(context.Context, *http.Request) (struct{}, error) { return struct{}{}, nil },
+		func(context.Context, http.ResponseWriter, struct{}) error { return nil },
+		httptransport.ServerRequestID[struct{}, struct{}](httptransport.RequestIDHeader("X-Trace")),
+	)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Trace", "abc123")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if want, have := "abc123", seen; want != have {
+		t.Errorf("context: want %q, have %q", want, have)
+	}
+	if want, have := "abc123", rec.Header().Get("X-Trace"); want != have {
+		t.Errorf("response header: want %q, have %q", want, have)
+	}
+	if want, have := "request_id=abc123 msg=hello\n", buf.String(); want != have {
+		t.Errorf("log: want %q, have %q", want, have)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if seen == "" || seen == "abc123" {
+		t.Errorf("want generated ID, have %q", seen)
+	}
+	if want, have := seen, rec.Header().Get("X-Trace"); want != have {
+		t.Errorf("response header: want %q, have %q", want, have)
+	}
+}
+
+func TestNewRequestID(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	a, b := httptransport.NewRequestID(), httptransport.NewRequestID()
+	if !uuid.MatchString(a) {
+		t.Errorf("%q isn't a version 4 UUID", a)
+	}
+	if a == b {
+		t.Errorf("want distinct IDs, have %q twice", a)
+	}
+}
+
+func TestServerRequestIDWithResponseCache(t *testing.T) {
+	var (
+		cache  = httptransport.NewResponseCache(time.Minute)
+		cached = httptransport.ServerResponseCache[struct{}, struct{}](cache)
+		public = httptransport.ServerCacheControl[struct{}, struct{}](httptransport.CacheControl{Public: true, MaxAge: time.Minute})
+		nop    = func(context.Context, struct{}) (struct{}, error) { return struct{}{}, nil }
+		dec    = func(context.Context, *http.Request) (struct{}, error) { return struct{}{}, nil }
+		enc    = func(ctx context.Context, w http.ResponseWriter, response struct{}) error {
+			return httptransport.EncodeJSONResponse(ctx, w, response)
+		}
+	)
+	// The cache is filled by a server which sets the header itself, e.g. as
+	// it's forwarded from upstream, and served by one with ServerRequestID.
+	filler := httptransport.NewServer(nop, dec, func(ctx context.Context, w http.ResponseWriter, response struct{}) error {
+		w.Header().Set("X-Request-Id", "upstream")
+		return enc(ctx, w, response)
+	}, public, cached)
+	handler := httptransport.NewServer(nop, dec, enc,
+		httptransport.ServerRequestID[struct{}, struct{}](), public, cached)
+
+	filler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/thing", nil))
+	for _, id := range []string{"id-1", "id-2"} {
+		req := httptest.NewRequest("GET", "/thing", nil)
+		req.Header.Set("X-Request-Id", id)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Header().Get("Age") == "" {
+			t.Errorf("%s: want a cache hit", id)
+		}
+		if want, have := []string{id}, rec.Header().Values("X-Request-Id"); !reflect.DeepEqual(want, have) {
+			t.Errorf("X-Request-Id: want %q, have %q", want, have)
+		}
+	}
+}
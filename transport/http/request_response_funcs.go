@@ -3,6 +3,8 @@ package http
 import (
 	"context"
 	"net/http"
+
+	"github.com/barrett370/kit/v2/contextkey"
 )
 
 // RequestFunc may take information from an HTTP request and put it into a
@@ -45,24 +47,26 @@ func SetRequestHeader(key, val string) RequestFunc {
 
 // PopulateRequestContext is a RequestFunc that populates several values into
 // the context from the HTTP request. Those values may be extracted using the
-// corresponding ContextKey type in this package.
+// corresponding key in this package, e.g. RequestPathKey.Get(ctx).
 func PopulateRequestContext(ctx context.Context, r *http.Request) context.Context {
-	for k, v := range map[contextKey]string{
-		ContextKeyRequestMethod:          r.Method,
-		ContextKeyRequestURI:             r.RequestURI,
-		ContextKeyRequestPath:            r.URL.Path,
-		ContextKeyRequestProto:           r.Proto,
-		ContextKeyRequestHost:            r.Host,
-		ContextKeyRequestRemoteAddr:      r.RemoteAddr,
-		ContextKeyRequestXForwardedFor:   r.Header.Get("X-Forwarded-For"),
-		ContextKeyRequestXForwardedProto: r.Header.Get("X-Forwarded-Proto"),
-		ContextKeyRequestAuthorization:   r.Header.Get("Authorization"),
-		ContextKeyRequestReferer:         r.Header.Get("Referer"),
-		ContextKeyRequestUserAgent:       r.Header.Get("User-Agent"),
-		ContextKeyRequestXRequestID:      r.Header.Get("X-Request-Id"),
-		ContextKeyRequestAccept:          r.Header.Get("Accept"),
+	for k, v := range map[*contextkey.Key[string]]string{
+		RequestMethodKey:          r.Method,
+		RequestURIKey:             r.RequestURI,
+		RequestPathKey:            r.URL.Path,
+		RequestProtoKey:           r.Proto,
+		RequestHostKey:            r.Host,
+		RequestRemoteAddrKey:      r.RemoteAddr,
+		RequestXForwardedForKey:   r.Header.Get("X-Forwarded-For"),
+		RequestXForwardedProtoKey: r.Header.Get("X-Forwarded-Proto"),
+		RequestAuthorizationKey:   r.Header.Get("Authorization"),
+		RequestRefererKey:         r.Header.Get("Referer"),
+		RequestUserAgentKey:       r.Header.Get("User-Agent"),
+		RequestXRequestIDKey:      r.Header.Get("X-Request-Id"),
+		RequestAcceptKey:          r.Header.Get("Accept"),
+		RequestIfMatchKey:         r.Header.Get("If-Match"),
+		RequestIfNoneMatchKey:     r.Header.Get("If-None-Match"),
 	} {
-		ctx = context.WithValue(ctx, k, v)
+		ctx = k.Set(ctx, v)
 	}
 	return ctx
 }
@@ -72,62 +76,184 @@ type contextKey int
 const (
 	// ContextKeyRequestMethod is populated in the context by
 	// PopulateRequestContext. Its value is r.Method.
+	//
+	// Deprecated: use RequestMethodKey.
 	ContextKeyRequestMethod contextKey = iota
 
 	// ContextKeyRequestURI is populated in the context by
 	// PopulateRequestContext. Its value is r.RequestURI.
+	//
+	// Deprecated: use RequestURIKey.
 	ContextKeyRequestURI
 
 	// ContextKeyRequestPath is populated in the context by
 	// PopulateRequestContext. Its value is r.URL.Path.
+	//
+	// Deprecated: use RequestPathKey.
 	ContextKeyRequestPath
 
 	// ContextKeyRequestProto is populated in the context by
 	// PopulateRequestContext. Its value is r.Proto.
+	//
+	// Deprecated: use RequestProtoKey.
 	ContextKeyRequestProto
 
 	// ContextKeyRequestHost is populated in the context by
 	// PopulateRequestContext. Its value is r.Host.
+	//
+	// Deprecated: use RequestHostKey.
 	ContextKeyRequestHost
 
 	// ContextKeyRequestRemoteAddr is populated in the context by
 	// PopulateRequestContext. Its value is r.RemoteAddr.
+	//
+	// Deprecated: use RequestRemoteAddrKey.
 	ContextKeyRequestRemoteAddr
 
 	// ContextKeyRequestXForwardedFor is populated in the context by
 	// PopulateRequestContext. Its value is r.Header.Get("X-Forwarded-For").
+	//
+	// Deprecated: use RequestXForwardedForKey.
 	ContextKeyRequestXForwardedFor
 
 	// ContextKeyRequestXForwardedProto is populated in the context by
 	// PopulateRequestContext. Its value is r.Header.Get("X-Forwarded-Proto").
+	//
+	// Deprecated: use RequestXForwardedProtoKey.
 	ContextKeyRequestXForwardedProto
 
 	// ContextKeyRequestAuthorization is populated in the context by
 	// PopulateRequestContext. Its value is r.Header.Get("Authorization").
+	//
+	// Deprecated: use RequestAuthorizationKey.
 	ContextKeyRequestAuthorization
 
 	// ContextKeyRequestReferer is populated in the context by
 	// PopulateRequestContext. Its value is r.Header.Get("Referer").
+	//
+	// Deprecated: use RequestRefererKey.
 	ContextKeyRequestReferer
 
 	// ContextKeyRequestUserAgent is populated in the context by
 	// PopulateRequestContext. Its value is r.Header.Get("User-Agent").
+	//
+	// Deprecated: use RequestUserAgentKey.
 	ContextKeyRequestUserAgent
 
 	// ContextKeyRequestXRequestID is populated in the context by
 	// PopulateRequestContext. Its value is r.Header.Get("X-Request-Id").
+	//
+	// Deprecated: use RequestXRequestIDKey.
 	ContextKeyRequestXRequestID
 
 	// ContextKeyRequestAccept is populated in the context by
 	// PopulateRequestContext. Its value is r.Header.Get("Accept").
+	//
+	// Deprecated: use RequestAcceptKey.
 	ContextKeyRequestAccept
 
 	// ContextKeyResponseHeaders is populated in the context whenever a
 	// ServerFinalizerFunc is specified. Its value is of type http.Header, and
 	// is captured only once the entire response has been written.
+	//
+	// Deprecated: use ResponseHeadersKey.
 	ContextKeyResponseHeaders
 
 	// ContextKeyResponseSize is populated in the context whenever a
 	// ServerFinalizerFunc is specified. Its value is of type int64.
+	//
+	// Deprecated: use ResponseSizeKey.
 	ContextKeyResponseSize
 )
+
+var (
+	// RequestMethodKey is populated in the context by PopulateRequestContext.
+	// Its value is r.Method.
+	RequestMethodKey = contextkey.For[string](ContextKeyRequestMethod)
+
+	// RequestURIKey is populated in the context by PopulateRequestContext.
+	// Its value is r.RequestURI.
+	RequestURIKey = contextkey.For[string](ContextKeyRequestURI)
+
+	// RequestPathKey is populated in the context by PopulateRequestContext.
+	// Its value is r.URL.Path.
+	RequestPathKey = contextkey.For[string](ContextKeyRequestPath)
+
+	// RequestProtoKey is populated in the context by PopulateRequestContext.
+	// Its value is r.Proto.
+	RequestProtoKey = contextkey.For[string](ContextKeyRequestProto)
+
+	// RequestHostKey is populated in the context by PopulateRequestContext.
+	// Its value is r.Host.
+	RequestHostKey = contextkey.For[string](ContextKeyRequestHost)
+
+	// RequestRemoteAddrKey is populated in the context by PopulateRequestContext.
+	// Its value is r.RemoteAddr.
+	RequestRemoteAddrKey = contextkey.For[string](ContextKeyRequestRemoteAddr)
+
+	// RequestXForwardedForKey is populated in the context by PopulateRequestContext.
+	// Its value is r.Header.Get("X-Forwarded-For").
+	RequestXForwardedForKey = contextkey.For[string](ContextKeyRequestXForwardedFor)
+
+	// RequestXForwardedProtoKey is populated in the context by PopulateRequestContext.
+	// Its value is r.Header.Get("X-Forwarded-Proto").
+	RequestXForwardedProtoKey = contextkey.For[string](ContextKeyRequestXForwardedProto)
+
+	// RequestAuthorizationKey is populated in the context by PopulateRequestContext.
+	// Its value is r.Header.Get("Authorization").
+	RequestAuthorizationKey = contextkey.For[string](ContextKeyRequestAuthorization)
+
+	// RequestRefererKey is populated in the context by PopulateRequestContext.
+	// Its value is r.Header.Get("Referer").
+	RequestRefererKey = contextkey.For[string](ContextKeyRequestReferer)
+
+	// RequestUserAgentKey is populated in the context by PopulateRequestContext.
+	// Its value is r.Header.Get("User-Agent").
+	RequestUserAgentKey = contextkey.For[string](ContextKeyRequestUserAgent)
+
+	// RequestXRequestIDKey is populated in the context by PopulateRequestContext.
+	// Its value is r.Header.Get("X-Request-Id").
+	RequestXRequestIDKey = contextkey.For[string](ContextKeyRequestXRequestID)
+
+	// RequestAcceptKey is populated in the context by PopulateRequestContext.
+	// Its value is r.Header.Get("Accept").
+	RequestAcceptKey = contextkey.For[string](ContextKeyRequestAccept)
+
+	// RequestIfMatchKey is populated in the context by PopulateRequestContext.
+	// Its value is r.Header.Get("If-Match").
+	RequestIfMatchKey = contextkey.New[string]("RequestIfMatch")
+
+	// RequestIfNoneMatchKey is populated in the context by
+	// PopulateRequestContext. Its value is r.Header.Get("If-None-Match").
+	RequestIfNoneMatchKey = contextkey.New[string]("RequestIfNoneMatch")
+
+	// RequestIDKey is populated in the context by servers configured with
+	// ServerRequestID. Its value is the request ID, either received from the
+	// client or generated by the server.
+	RequestIDKey = contextkey.New[string]("RequestID")
+
+	// ResponseHeadersKey is populated in the context whenever a
+	// ServerFinalizerFunc is specified. Its value is captured only once the
+	// entire response has been written.
+	ResponseHeadersKey = contextkey.For[http.Header](ContextKeyResponseHeaders)
+
+	// ResponseSizeKey is populated in the context whenever a
+	// ServerFinalizerFunc is specified. Its value counts the bytes of the
+	// response body written.
+	ResponseSizeKey = contextkey.For[int64](ContextKeyResponseSize)
+
+	// ResponseCodeKey is populated in the context whenever a
+	// ServerFinalizerFunc is specified. Its value is the status code of the
+	// response.
+	ResponseCodeKey = contextkey.New[int]("ResponseCode")
+
+	// ResponseWriteErrorKey is populated in the context whenever a
+	// ServerFinalizerFunc is specified, and writing the response failed, e.g.
+	// since the client went away. Its value is the first write error.
+	ResponseWriteErrorKey = contextkey.New[error]("ResponseWriteError")
+
+	// RequestSizeKey is populated in the context whenever a
+	// ServerFinalizerFunc is specified. Its value counts the bytes of the
+	// request body read.
+	RequestSizeKey = contextkey.New[int64]("RequestSize")
+)
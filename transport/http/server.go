@@ -4,22 +4,37 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/metrics"
 	"github.com/barrett370/kit/v2/transport"
 	"github.com/go-kit/log"
 )
 
 // Server wraps an endpoint and implements http.Handler.
 type Server[I, O any] struct {
-	e            endpoint.Endpoint[I, O]
-	dec          DecodeRequestFunc[I]
-	enc          EncodeResponseFunc[O]
-	before       []RequestFunc
-	after        []ServerResponseFunc
-	errorEncoder ErrorEncoder
-	finalizer    []ServerFinalizerFunc
-	errorHandler transport.ErrorHandler
+	e             endpoint.Endpoint[I, O]
+	dec           DecodeRequestFunc[I]
+	enc           EncodeResponseFunc[O]
+	before        []RequestFunc
+	after         []ServerResponseFunc
+	errorEncoder  ErrorEncoder
+	finalizer     []ServerFinalizerFunc
+	errorHandler  transport.ErrorHandler
+	cache         *ResponseCache
+	requestID     *requestID
+	recoverPanics bool
+	panics        metrics.Counter
+	sizes         *sizeMetrics
+	accessLog     *accessLog
+}
+
+type sizeMetrics struct {
+	requestSize  metrics.Histogram
+	responseSize metrics.Histogram
+	writeErrors  metrics.Counter
 }
 
 // NewServer constructs a new server, which implements http.Handler and wraps
@@ -91,15 +106,56 @@ func ServerFinalizer[I, O any](f ...ServerFinalizerFunc) ServerOption[I, O] {
 	return func(s *Server[I, O]) { s.finalizer = append(s.finalizer, f...) }
 }
 
+// ServerRecover makes the server recover from panics while it handles a
+// request, in decoders, endpoints, or encoders, rather than leaving them to
+// net/http, which logs them and drops the connection. A panic is converted to
+// a transport.PanicError, with its stack trace, which is stored in the
+// context with transport.ContextWithPanic, and passed to the ErrorHandler and
+// the ErrorEncoder like other errors. If panics isn't nil, it counts them.
+// Panics with http.ErrAbortHandler are not recovered, since they're meant to
+// abort the response.
+func ServerRecover[I, O any](panics metrics.Counter) ServerOption[I, O] {
+	return func(s *Server[I, O]) { s.recoverPanics, s.panics = true, panics }
+}
+
+// ServerSizeMetrics records the sizes of request and response bodies, in
+// bytes, in histograms labeled with the "code" of the response, and counts
+// responses whose writing failed, e.g. since the client went away. Any of
+// them may be nil.
+func ServerSizeMetrics[I, O any](requestSize, responseSize metrics.Histogram, writeErrors metrics.Counter) ServerOption[I, O] {
+	return func(s *Server[I, O]) { s.sizes = &sizeMetrics{requestSize, responseSize, writeErrors} }
+}
+
 // ServeHTTP implements http.Handler.
 func (s Server[I, O]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	start := time.Now()
+
+	if s.requestID != nil {
+		ctx = s.requestID.handle(ctx, w, r)
+	}
 
-	if len(s.finalizer) > 0 {
-		iw := &interceptingWriter{w, http.StatusOK, 0}
+	if len(s.finalizer) > 0 || s.sizes != nil || s.accessLog != nil {
+		iw := &interceptingWriter{ResponseWriter: w, code: http.StatusOK}
+		body := &countingReader{ReadCloser: r.Body}
+		r.Body = body
 		defer func() {
-			ctx = context.WithValue(ctx, ContextKeyResponseHeaders, iw.Header())
-			ctx = context.WithValue(ctx, ContextKeyResponseSize, iw.written)
+			if s.sizes != nil {
+				s.sizes.observe(iw.code, body.read, iw.written, iw.err)
+			}
+			if s.accessLog != nil {
+				s.accessLog.log(ctx, r, start, iw.code, iw.written)
+			}
+			if len(s.finalizer) == 0 {
+				return
+			}
+			ctx = ResponseHeadersKey.Set(ctx, iw.Header())
+			ctx = ResponseSizeKey.Set(ctx, iw.written)
+			ctx = ResponseCodeKey.Set(ctx, iw.code)
+			ctx = RequestSizeKey.Set(ctx, body.read)
+			if iw.err != nil {
+				ctx = ResponseWriteErrorKey.Set(ctx, iw.err)
+			}
 			for _, f := range s.finalizer {
 				f(ctx, iw.code, r)
 			}
@@ -107,20 +163,52 @@ func (s Server[I, O]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		w = iw.reimplementInterfaces()
 	}
 
+	if s.recoverPanics {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			if s.panics != nil {
+				s.panics.Add(1)
+			}
+			err := transport.NewPanicError(v)
+			ctx = transport.ContextWithPanic(ctx, err)
+			s.errorHandler.Handle(ctx, err)
+			s.errorEncoder(ctx, err, w)
+		}()
+	}
+
+	if s.cache != nil {
+		var perRequest []string
+		if s.requestID != nil {
+			perRequest = append(perRequest, s.requestID.header)
+		}
+		if s.cache.serve(w, r, perRequest...) {
+			return
+		}
+		var store func()
+		w, store = s.cache.record(w, r, perRequest...)
+		defer store()
+	}
+
 	for _, f := range s.before {
 		ctx = f(ctx, r)
 	}
 
 	request, err := s.dec(ctx, r)
 	if err != nil {
-		s.errorHandler.Handle(ctx, err)
+		s.errorHandler.Handle(transport.ContextWithStage(ctx, transport.StageDecode), err)
 		s.errorEncoder(ctx, err, w)
 		return
 	}
 
 	response, err := s.e(ctx, request)
 	if err != nil {
-		s.errorHandler.Handle(ctx, err)
+		s.errorHandler.Handle(transport.ContextWithStage(ctx, transport.StageEndpoint), err)
 		s.errorEncoder(ctx, err, w)
 		return
 	}
@@ -130,12 +218,25 @@ func (s Server[I, O]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.enc(ctx, w, response); err != nil {
-		s.errorHandler.Handle(ctx, err)
+		s.errorHandler.Handle(transport.ContextWithStage(ctx, transport.StageEncode), err)
 		s.errorEncoder(ctx, err, w)
 		return
 	}
 }
 
+func (m *sizeMetrics) observe(code int, requestSize, responseSize int64, err error) {
+	c := strconv.Itoa(code)
+	if m.requestSize != nil {
+		m.requestSize.With("code", c).Observe(float64(requestSize))
+	}
+	if m.responseSize != nil {
+		m.responseSize.With("code", c).Observe(float64(responseSize))
+	}
+	if err != nil && m.writeErrors != nil {
+		m.writeErrors.Add(1)
+	}
+}
+
 // ErrorEncoder is responsible for encoding an error to the ResponseWriter.
 // Users are encouraged to use custom ErrorEncoders to encode HTTP errors to
 // their clients, and will likely want to pass and check for their own error
@@ -146,7 +247,8 @@ type ErrorEncoder func(ctx context.Context, err error, w http.ResponseWriter)
 // request, after the response has been written to the client. The principal
 // intended use is for request logging. In addition to the response code
 // provided in the function signature, additional response parameters are
-// provided in the context under keys with the ContextKeyResponse prefix.
+// provided in the context under keys with the Response prefix, e.g.
+// ResponseHeadersKey, and the size of the request body under RequestSizeKey.
 type ServerFinalizerFunc func(ctx context.Context, code int, r *http.Request)
 
 // NopRequestDecoder is a DecodeRequestFunc that can be used for requests that do not
@@ -3,6 +3,7 @@ package http_test
 import (
 	"context"
 	"errors"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -11,6 +12,8 @@ import (
 	"time"
 
 	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/metrics/generic"
+	"github.com/barrett370/kit/v2/transport"
 	httptransport "github.com/barrett370/kit/v2/transport/http"
 )
 
@@ -440,3 +443,151 @@ func testServer(t *testing.T) (step func(), resp <-chan *http.Response) {
 	}()
 	return func() { stepch <- true }, response
 }
+
+func TestServerRecover(t *testing.T) {
+	var (
+		panics  = generic.NewCounter("panics")
+		handled error
+		stack   []byte
+		handler = httptransport.NewServer(
+			func(context.Context, interface{}) (interface{}, error) { panic("dang") },
+			func(context.Context, *http.Request) (interface{}, error) { return struct{}{}, nil },
+			func(context.Context, http.ResponseWriter, interface{}) error { return nil },
+			httptransport.ServerRecover[interface{}, interface{}](panics),
+			httptransport.ServerErrorHandler[interface{}, interface{}](transport.ErrorHandlerFunc(func(ctx context.Context, err error) {
+				handled = err
+				if pe, ok := transport.PanicFromContext(ctx); ok {
+					stack = pe.Stack
+				}
+			})),
+		)
+	)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if want, have := http.StatusInternalServerError, rec.Code; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := "panic: dang", rec.Body.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if _, ok := handled.(*transport.PanicError); !ok {
+		t.Errorf("want a PanicError handled, have %v", handled)
+	}
+	if len(stack) == 0 {
+		t.Error("want the stack in the context, have none")
+	}
+	if want, have := 1.0, panics.Value(); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// The handler can still abort the response.
+	abort := httptransport.NewServer(
+		func(context.Context, interface{}) (interface{}, error) { panic(http.ErrAbortHandler) },
+		func(context.Context, *http.Request) (interface{}, error) { return struct{}{}, nil },
+		func(context.Context, http.ResponseWriter, interface{}) error { return nil },
+		httptransport.ServerRecover[interface{}, interface{}](nil),
+	)
+	defer func() {
+		if want, have := interface{}(http.ErrAbortHandler), recover(); want != have {
+			t.Errorf("want %v, have %v", want, have)
+		}
+	}()
+	abort.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+func TestServerErrorStage(t *testing.T) {
+	var stages []transport.Stage
+	handler := func(e endpoint.Endpoint[interface{}, interface{}], dec httptransport.DecodeRequestFunc[interface{}], enc httptransport.EncodeResponseFunc[interface{}]) http.Handler {
+		return httptransport.NewServer(e, dec, enc, httptransport.ServerErrorHandler[interface{}, interface{}](
+			transport.ErrorHandlerFunc(func(ctx context.Context, err error) {
+				stages = append(stages, transport.StageFromContext(ctx))
+			}),
+		))
+	}
+	var (
+		ok     = func(context.Context, interface{}) (interface{}, error) { return struct{}{}, nil }
+		fail   = func(context.Context, interface{}) (interface{}, error) { return nil, errors.New("dang") }
+		dec    = func(context.Context, *http.Request) (interface{}, error) { return struct{}{}, nil }
+		badDec = func(context.Context, *http.Request) (interface{}, error) { return nil, errors.New("dang") }
+		enc    = func(context.Context, http.ResponseWriter, interface{}) error { return nil }
+		badEnc = func(context.Context, http.ResponseWriter, interface{}) error { return errors.New("dang") }
+	)
+	for _, h := range []http.Handler{
+		handler(ok, badDec, enc),
+		handler(fail, dec, enc),
+		handler(ok, dec, badEnc),
+	} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+	want := []transport.Stage{transport.StageDecode, transport.StageEndpoint, transport.StageEncode}
+	if len(stages) != len(want) {
+		t.Fatalf("want %v, have %v", want, stages)
+	}
+	for i := range want {
+		if want[i] != stages[i] {
+			t.Errorf("want %v, have %v", want, stages)
+		}
+	}
+}
+
+func TestServerSizes(t *testing.T) {
+	type sizes struct {
+		request, response int64
+		code              int
+		err               error
+	}
+	var (
+		requestSize  = generic.NewHistogram("request_size", 10)
+		responseSize = generic.NewHistogram("response_size", 10)
+		writeErrors  = generic.NewCounter("write_errors")
+		done         = make(chan sizes, 1)
+	)
+	handler := httptransport.NewServer(
+		endpoint.Nop,
+		func(_ context.Context, r *http.Request) (interface{}, error) {
+			_, err := ioutil.ReadAll(r.Body)
+			return struct{}{}, err
+		},
+		func(_ context.Context, w http.ResponseWriter, _ interface{}) error {
+			w.WriteHeader(http.StatusAccepted)
+			// The io.ReaderFrom of the ResponseWriter bypasses Write.
+			_, err := w.(io.ReaderFrom).ReadFrom(strings.NewReader("accepted"))
+			return err
+		},
+		httptransport.ServerSizeMetrics[any, any](requestSize, responseSize, writeErrors),
+		httptransport.ServerFinalizer[any, any](func(ctx context.Context, code int, _ *http.Request) {
+			done <- sizes{
+				request:  httptransport.RequestSizeKey.Value(ctx),
+				response: httptransport.ResponseSizeKey.Value(ctx),
+				code:     httptransport.ResponseCodeKey.Value(ctx),
+				err:      httptransport.ResponseWriteErrorKey.Value(ctx),
+			}
+		}),
+	)
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	resp, err := http.Post(server.URL, "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	select {
+	case have := <-done:
+		if want := (sizes{request: 5, response: 8, code: http.StatusAccepted}); want != have {
+			t.Errorf("want %+v, have %+v", want, have)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for finalizer")
+	}
+	if want, have := 5.0, requestSize.Quantile(0.5); want != have {
+		t.Errorf("request size: want %v, have %v", want, have)
+	}
+	if want, have := 8.0, responseSize.Quantile(0.5); want != have {
+		t.Errorf("response size: want %v, have %v", want, have)
+	}
+	if want, have := 0.0, writeErrors.Value(); want != have {
+		t.Errorf("write errors: want %v, have %v", want, have)
+	}
+}
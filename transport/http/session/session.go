@@ -0,0 +1,327 @@
+// Package session provides cookie-based sessions for browser-facing services
+// built on transport/http, as a pair of ServerBefore and ServerAfter funcs.
+//
+// Session cookies are encrypted and authenticated with AES-GCM, so clients
+// can neither read nor forge them. With a Store, the cookie carries only the
+// session ID, and the values are kept on the server; without one, the values
+// are carried in the cookie itself, which must then stay within the ~4KB
+// browsers allow.
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	stdhttp "net/http"
+	"sync"
+	"time"
+
+	"github.com/barrett370/kit/v2/contextkey"
+	"github.com/barrett370/kit/v2/transport"
+	"github.com/barrett370/kit/v2/transport/http"
+)
+
+// DefaultMaxAge is the default lifetime of a session since it was last
+// refreshed.
+const DefaultMaxAge = 24 * time.Hour
+
+// ContextKey holds the key used to store the *Session in the context.
+var ContextKey = contextkey.New[*Session]("Session")
+
+// Session is the session of a request. It's safe for concurrent use.
+type Session struct {
+	mtx         sync.Mutex
+	id          string
+	previousID  string // set by Regenerate, until the old session is deleted
+	values      map[string]string
+	expiry      time.Time
+	isNew       bool
+	modified    bool
+	invalidated bool
+}
+
+// FromContext returns the session stored in the context by the manager's
+// ServerBefore func, if any.
+func FromContext(ctx context.Context) (*Session, bool) {
+	s, ok := ContextKey.Get(ctx)
+	return s, ok
+}
+
+// ID returns the ID of the session.
+func (s *Session) ID() string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.id
+}
+
+// IsNew reports whether the session was created for this request, rather than
+// loaded from the request's cookie.
+func (s *Session) IsNew() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.isNew
+}
+
+// Get returns the value for the key, or "" if there is none.
+func (s *Session) Get(key string) string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.values[key]
+}
+
+// Set sets the value for the key.
+func (s *Session) Set(key, value string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.values[key] = value
+	s.modified = true
+}
+
+// Delete deletes the value for the key.
+func (s *Session) Delete(key string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	delete(s.values, key)
+	s.modified = true
+}
+
+// Regenerate gives the session a new ID, keeping its values, and deletes the
+// session with the old ID. Call it when the privileges of the session change,
+// e.g. on login, to prevent session fixation.
+func (s *Session) Regenerate() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.previousID == "" && !s.isNew {
+		s.previousID = s.id
+	}
+	s.id = newID()
+	s.modified = true
+}
+
+// Invalidate deletes the session and its cookie, e.g. on logout.
+func (s *Session) Invalidate() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.values = map[string]string{}
+	s.invalidated = true
+}
+
+// Option sets an optional parameter for managers.
+type Option func(*Manager)
+
+// WithStore keeps session values in the store, rather than in the cookie.
+func WithStore(store Store) Option {
+	return func(m *Manager) { m.store = store }
+}
+
+// WithMaxAge sets the lifetime of a session since it was last refreshed. By
+// default, it's DefaultMaxAge.
+func WithMaxAge(d time.Duration) Option {
+	return func(m *Manager) { m.maxAge = d }
+}
+
+// WithRefreshAfter sets how long after being issued or refreshed a session's
+// expiry is extended, and its cookie reissued, when it's used, so that
+// active sessions don't expire. By default, it's half the max age.
+func WithRefreshAfter(d time.Duration) Option {
+	return func(m *Manager) { m.refreshAfter = d }
+}
+
+// WithCookie sets the attributes of the session cookie other than its name,
+// value and expiry, e.g. Domain or SameSite. By default, the cookie has
+// Path "/", and is HttpOnly, Secure, and SameSite=Lax.
+func WithCookie(c stdhttp.Cookie) Option {
+	return func(m *Manager) { m.cookie = c }
+}
+
+// WithErrorHandler sets the handler for errors which occur while loading or
+// saving sessions, which can't be returned by ServerBefore and ServerAfter
+// funcs. A session which fails to load is replaced by a new one. By default,
+// errors are ignored.
+func WithErrorHandler(h transport.ErrorHandler) Option {
+	return func(m *Manager) { m.errorHandler = h }
+}
+
+// Manager issues, refreshes and invalidates session cookies.
+type Manager struct {
+	name         string
+	codec        *codec
+	store        Store
+	maxAge       time.Duration
+	refreshAfter time.Duration
+	cookie       stdhttp.Cookie
+	errorHandler transport.ErrorHandler
+}
+
+// NewManager returns a manager for sessions in the cookie with the name.
+// Cookies are encrypted with the first key, and decrypted with any of them,
+// so that keys can be rotated by adding a new key at the front, and removing
+// the old one once its sessions have expired. Keys must be 16, 24 or 32 bytes
+// long, for AES-128, AES-192 or AES-256.
+func NewManager(name string, keys [][]byte, options ...Option) (*Manager, error) {
+	c, err := newCodec(keys)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manager{
+		name:   name,
+		codec:  c,
+		maxAge: DefaultMaxAge,
+		cookie: stdhttp.Cookie{
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   true,
+			SameSite: stdhttp.SameSiteLaxMode,
+		},
+		errorHandler: transport.ErrorHandlerFunc(func(context.Context, error) {}),
+	}
+	for _, option := range options {
+		option(m)
+	}
+	if m.refreshAfter <= 0 {
+		m.refreshAfter = m.maxAge / 2
+	}
+	return m, nil
+}
+
+// payload is the content of the cookie.
+type payload struct {
+	ID     string            `json:"id"`
+	Expiry int64             `json:"exp"`
+	Values map[string]string `json:"v,omitempty"` // only without a store
+}
+
+// ServerBefore returns a RequestFunc which loads the session from the
+// request's cookie, or creates a new one, and stores it in the context under
+// ContextKey.
+func (m *Manager) ServerBefore() http.RequestFunc {
+	return func(ctx context.Context, r *stdhttp.Request) context.Context {
+		s, err := m.load(ctx, r)
+		if err != nil {
+			m.errorHandler.Handle(ctx, err)
+		}
+		if s == nil {
+			s = &Session{id: newID(), values: map[string]string{}, isNew: true}
+		}
+		return ContextKey.Set(ctx, s)
+	}
+}
+
+func (m *Manager) load(ctx context.Context, r *stdhttp.Request) (*Session, error) {
+	cookie, err := r.Cookie(m.name)
+	if err != nil {
+		return nil, nil // no session yet
+	}
+	b, err := m.codec.open(m.name, cookie.Value)
+	if err != nil {
+		return nil, nil // tampered with, or from a retired key
+	}
+	var p payload
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, err
+	}
+	expiry := time.Unix(p.Expiry, 0)
+	if !time.Now().Before(expiry) {
+		return nil, nil
+	}
+	values := p.Values
+	if m.store != nil {
+		values, err = m.store.Load(ctx, p.ID)
+		if err == ErrNotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if values == nil {
+		values = map[string]string{}
+	}
+	return &Session{id: p.ID, values: values, expiry: expiry}, nil
+}
+
+// ServerAfter returns a ServerResponseFunc which saves the session in the
+// context, and sets its cookie, if it was created, modified, or is due to be
+// refreshed. An invalidated session is deleted, along with its cookie. Since
+// ServerAfter funcs run only when the endpoint succeeds, changes made by
+// failed requests aren't saved.
+func (m *Manager) ServerAfter() http.ServerResponseFunc {
+	return func(ctx context.Context, w stdhttp.ResponseWriter) context.Context {
+		s, ok := FromContext(ctx)
+		if !ok {
+			return ctx
+		}
+		if err := m.save(ctx, w, s); err != nil {
+			m.errorHandler.Handle(ctx, err)
+		}
+		return ctx
+	}
+}
+
+func (m *Manager) save(ctx context.Context, w stdhttp.ResponseWriter, s *Session) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.previousID != "" && m.store != nil {
+		if err := m.store.Delete(ctx, s.previousID); err != nil {
+			return err
+		}
+	}
+	s.previousID = ""
+
+	if s.invalidated {
+		if m.store != nil && !s.isNew {
+			if err := m.store.Delete(ctx, s.id); err != nil {
+				return err
+			}
+		}
+		if !s.isNew {
+			c := m.cookie
+			c.Name, c.Value, c.MaxAge = m.name, "", -1
+			stdhttp.SetCookie(w, &c)
+		}
+		return nil
+	}
+
+	now := time.Now()
+	refresh := !s.isNew && now.After(s.expiry.Add(-m.maxAge+m.refreshAfter))
+	if s.isNew && len(s.values) == 0 {
+		return nil // don't issue cookies for empty sessions
+	}
+	if !s.isNew && !s.modified && !refresh {
+		return nil
+	}
+
+	s.expiry = now.Add(m.maxAge)
+	p := payload{ID: s.id, Expiry: s.expiry.Unix()}
+	if m.store != nil {
+		if err := m.store.Save(ctx, s.id, s.values, s.expiry); err != nil {
+			return err
+		}
+	} else {
+		p.Values = s.values
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	value, err := m.codec.seal(m.name, b)
+	if err != nil {
+		return err
+	}
+	c := m.cookie
+	c.Name, c.Value, c.Expires = m.name, value, s.expiry
+	c.MaxAge = int(m.maxAge / time.Second)
+	stdhttp.SetCookie(w, &c)
+	s.isNew, s.modified = false, false
+	return nil
+}
+
+func newID() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err) // crypto/rand doesn't fail on supported platforms
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,212 @@
+package session_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	httptransport "github.com/barrett370/kit/v2/transport/http"
+	"github.com/barrett370/kit/v2/transport/http/session"
+)
+
+var key = []byte("0123456789abcdef0123456789abcdef")
+
+// newServer returns a server whose endpoint performs the action named by the
+// path on the session, and responds with the session's user.
+func newServer(t *testing.T, m *session.Manager) *httptest.Server {
+	t.Helper()
+	e := func(ctx context.Context, action string) (string, error) {
+		s, _ := session.FromContext(ctx)
+		switch action {
+		case "/login":
+			s.Regenerate()
+			s.Set("user", "alice")
+		case "/logout":
+			s.Invalidate()
+		}
+		return s.Get("user"), nil
+	}
+	dec := func(_ context.Context, r *http.Request) (string, error) { return r.URL.Path, nil }
+	enc := func(_ context.Context, w http.ResponseWriter, user string) error {
+		_, err := w.Write([]byte(user))
+		return err
+	}
+	return httptest.NewServer(httptransport.NewServer(e, dec, enc,
+		httptransport.ServerBefore[string, string](m.ServerBefore()),
+		httptransport.ServerAfter[string, string](m.ServerAfter()),
+	))
+}
+
+type client struct {
+	t *testing.T
+	*http.Client
+	base string
+}
+
+func newClient(t *testing.T, server *httptest.Server) *client {
+	jar, _ := cookiejar.New(nil)
+	return &client{t, &http.Client{Jar: jar}, server.URL}
+}
+
+func (c *client) get(path string) (string, *http.Response) {
+	c.t.Helper()
+	resp, err := c.Get(c.base + path)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(resp.Body)
+	return string(b), resp
+}
+
+func (c *client) cookies() []*http.Cookie {
+	u, _ := url.Parse(c.base)
+	return c.Jar.Cookies(u)
+}
+
+func TestLifecycle(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		options []session.Option
+	}{
+		{"cookie", nil},
+		{"store", []session.Option{session.WithStore(session.NewMemoryStore())}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// The test server is plain HTTP, so the cookie mustn't be Secure.
+			options := append(tc.options, session.WithCookie(http.Cookie{Path: "/", HttpOnly: true}))
+			m, err := session.NewManager("sid", [][]byte{key}, options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := newServer(t, m)
+			defer server.Close()
+			c := newClient(t, server)
+
+			if _, resp := c.get("/"); len(resp.Cookies()) != 0 {
+				t.Errorf("empty session issued a cookie")
+			}
+			if want, have := "alice", func() string { s, _ := c.get("/login"); return s }(); want != have {
+				t.Errorf("login: want %q, have %q", want, have)
+			}
+			if want, have := "alice", func() string { s, _ := c.get("/"); return s }(); want != have {
+				t.Errorf("after login: want %q, have %q", want, have)
+			}
+			if _, resp := c.get("/"); len(resp.Cookies()) != 0 {
+				t.Errorf("unmodified session reissued its cookie")
+			}
+			c.get("/logout")
+			if want, have := 0, len(c.cookies()); want != have {
+				t.Errorf("cookies after logout: want %d, have %d", want, have)
+			}
+			if want, have := "", func() string { s, _ := c.get("/"); return s }(); want != have {
+				t.Errorf("after logout: want %q, have %q", want, have)
+			}
+		})
+	}
+}
+
+func TestTamperedCookie(t *testing.T) {
+	m, _ := session.NewManager("sid", [][]byte{key})
+	rec := httptest.NewRecorder()
+	ctx := m.ServerBefore()(context.Background(), httptest.NewRequest("GET", "/", nil))
+	s, _ := session.FromContext(ctx)
+	s.Set("user", "alice")
+	m.ServerAfter()(ctx, rec)
+	cookie := rec.Result().Cookies()[0]
+
+	for _, tc := range []struct {
+		name  string
+		value string
+		keys  [][]byte
+		want  string
+	}{
+		{"valid", cookie.Value, [][]byte{key}, "alice"},
+		{"rotated key", cookie.Value, [][]byte{[]byte("fedcba9876543210fedcba9876543210"), key}, "alice"},
+		{"retired key", cookie.Value, [][]byte{[]byte("fedcba9876543210fedcba9876543210")}, ""},
+		{"tampered", cookie.Value[:len(cookie.Value)-2] + "AA", [][]byte{key}, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m, _ := session.NewManager("sid", tc.keys)
+			r := httptest.NewRequest("GET", "/", nil)
+			r.AddCookie(&http.Cookie{Name: "sid", Value: tc.value})
+			s, _ := session.FromContext(m.ServerBefore()(context.Background(), r))
+			if want, have := tc.want, s.Get("user"); want != have {
+				t.Errorf("want %q, have %q", want, have)
+			}
+		})
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	store := session.NewMemoryStore()
+	m, _ := session.NewManager("sid", [][]byte{key}, session.WithStore(store), session.WithMaxAge(time.Hour), session.WithRefreshAfter(-1))
+
+	// With refresh after at zero or less, it defaults to half the max age,
+	// so a fresh session isn't refreshed.
+	rec := httptest.NewRecorder()
+	ctx := m.ServerBefore()(context.Background(), httptest.NewRequest("GET", "/", nil))
+	s, _ := session.FromContext(ctx)
+	s.Set("user", "alice")
+	m.ServerAfter()(ctx, rec)
+	cookie := rec.Result().Cookies()[0]
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	m.ServerAfter()(m.ServerBefore()(context.Background(), r), rec)
+	if want, have := 0, len(rec.Result().Cookies()); want != have {
+		t.Errorf("fresh session: want %d cookies, have %d", want, have)
+	}
+
+	// A manager which refreshes immediately reissues the cookie.
+	m, _ = session.NewManager("sid", [][]byte{key}, session.WithStore(store), session.WithMaxAge(time.Hour), session.WithRefreshAfter(time.Nanosecond))
+	time.Sleep(time.Millisecond)
+	rec = httptest.NewRecorder()
+	ctx = m.ServerBefore()(context.Background(), r)
+	m.ServerAfter()(ctx, rec)
+	if want, have := 1, len(rec.Result().Cookies()); want != have {
+		t.Fatalf("due session: want %d cookies, have %d", want, have)
+	}
+	if s, _ := session.FromContext(ctx); s.Get("user") != "alice" {
+		t.Errorf("refreshed session lost its values")
+	}
+}
+
+func TestRegenerate(t *testing.T) {
+	store := session.NewMemoryStore()
+	m, _ := session.NewManager("sid", [][]byte{key}, session.WithStore(store))
+
+	rec := httptest.NewRecorder()
+	ctx := m.ServerBefore()(context.Background(), httptest.NewRequest("GET", "/", nil))
+	s, _ := session.FromContext(ctx)
+	s.Set("cart", "42")
+	m.ServerAfter()(ctx, rec)
+	oldID := s.ID()
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(rec.Result().Cookies()[0])
+	ctx = m.ServerBefore()(context.Background(), r)
+	s, _ = session.FromContext(ctx)
+	s.Regenerate()
+	m.ServerAfter()(ctx, httptest.NewRecorder())
+
+	if s.ID() == oldID {
+		t.Fatal("ID wasn't regenerated")
+	}
+	if _, err := store.Load(context.Background(), oldID); err != session.ErrNotFound {
+		t.Errorf("old session: want %v, have %v", session.ErrNotFound, err)
+	}
+	values, err := store.Load(context.Background(), s.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "42", values["cart"]; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
@@ -0,0 +1,83 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by a Store which has no session with the ID, e.g.
+// because it expired.
+var ErrNotFound = errors.New("session not found")
+
+// Store keeps session values on the server, so that the cookie carries only
+// the session ID. Stores shared by several servers, e.g. backed by Redis or a
+// database, let any instance serve any session.
+type Store interface {
+	// Load returns the values of the session with the ID, or ErrNotFound.
+	Load(ctx context.Context, id string) (map[string]string, error)
+
+	// Save stores the values of the session with the ID until the expiry.
+	Save(ctx context.Context, id string, values map[string]string, expiry time.Time) error
+
+	// Delete removes the session with the ID, if it exists.
+	Delete(ctx context.Context, id string) error
+}
+
+// MemoryStore is a Store for a single server, which keeps sessions in memory.
+type MemoryStore struct {
+	mtx      sync.Mutex
+	sessions map[string]memorySession
+}
+
+type memorySession struct {
+	values map[string]string
+	expiry time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: map[string]memorySession{}}
+}
+
+// Load implements Store.
+func (s *MemoryStore) Load(_ context.Context, id string) (map[string]string, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok || !time.Now().Before(sess.expiry) {
+		return nil, ErrNotFound
+	}
+	return copyValues(sess.values), nil
+}
+
+// Save implements Store. Expired sessions are removed as a side effect.
+func (s *MemoryStore) Save(_ context.Context, id string, values map[string]string, expiry time.Time) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	now := time.Now()
+	for k, sess := range s.sessions {
+		if !now.Before(sess.expiry) {
+			delete(s.sessions, k)
+		}
+	}
+	s.sessions[id] = memorySession{values: copyValues(values), expiry: expiry}
+	return nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+func copyValues(values map[string]string) map[string]string {
+	c := make(map[string]string, len(values))
+	for k, v := range values {
+		c[k] = v
+	}
+	return c
+}
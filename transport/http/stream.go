@@ -0,0 +1,145 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Stream is an iterator over the values of a response body, which are
+// decoded one at a time, so large responses, e.g. exports, aren't buffered
+// in full. Use it with a client with BufferedStream(true), so the body is
+// left open for it, and Close it once done:
+//
+//	stream, err := client.Endpoint()(ctx, request)
+//	if err != nil {
+//		return err
+//	}
+//	defer stream.Close()
+//	for stream.Next() {
+//		process(stream.Value())
+//	}
+//	return stream.Err()
+type Stream[T any] struct {
+	ctx    context.Context
+	body   io.ReadCloser
+	decode func() (T, error)
+	value  T
+	err    error
+}
+
+// Next decodes the next value, and returns true if there is one. It returns
+// false at the end of the body, or after an error, or once the context of
+// the request is done.
+func (s *Stream[T]) Next() bool {
+	if s.err != nil {
+		return false
+	}
+	if err := s.ctx.Err(); err != nil {
+		s.err = err
+		return false
+	}
+	s.value, s.err = s.decode()
+	return s.err == nil
+}
+
+// Value returns the value decoded by the last call of Next.
+func (s *Stream[T]) Value() T {
+	return s.value
+}
+
+// Err returns the error which ended the stream, if it didn't end at the end
+// of the body.
+func (s *Stream[T]) Err() error {
+	if s.err == io.EOF {
+		return nil
+	}
+	return s.err
+}
+
+// Close closes the body, which ends the request.
+func (s *Stream[T]) Close() error {
+	return s.body.Close()
+}
+
+// DecodeNDJSONStream is a DecodeResponseFunc which returns a Stream of the
+// values of a body of newline-delimited JSON. Blank lines are skipped.
+func DecodeNDJSONStream[T any](ctx context.Context, resp *http.Response) (*Stream[T], error) {
+	if err := streamStatus(resp); err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(resp.Body)
+	return &Stream[T]{
+		ctx:  ctx,
+		body: resp.Body,
+		decode: func() (T, error) {
+			var v T
+			err := dec.Decode(&v)
+			return v, err
+		},
+	}, nil
+}
+
+// DecodeLengthPrefixedStream returns a DecodeResponseFunc which returns a
+// Stream of the messages of a body, each of which is prefixed with its length
+// as a varint, as written by protodelim.MarshalTo of protobuf, and decoded by
+// unmarshal, e.g.
+//
+//	func(b []byte) (*pb.Event, error) {
+//		e := &pb.Event{}
+//		return e, proto.Unmarshal(b, e)
+//	}
+//
+// Messages longer than maxSize bytes end the stream with an error.
+func DecodeLengthPrefixedStream[T any](unmarshal func([]byte) (T, error), maxSize int) DecodeResponseFunc[*Stream[T]] {
+	return func(ctx context.Context, resp *http.Response) (*Stream[T], error) {
+		if err := streamStatus(resp); err != nil {
+			return nil, err
+		}
+		var (
+			r   = bufio.NewReader(resp.Body)
+			buf []byte
+		)
+		return &Stream[T]{
+			ctx:  ctx,
+			body: resp.Body,
+			decode: func() (T, error) {
+				var zero T
+				n, err := binary.ReadUvarint(r)
+				if err != nil {
+					return zero, err // io.EOF at the end of the body
+				}
+				if n > uint64(maxSize) {
+					return zero, fmt.Errorf("message of %d bytes exceeds max size of %d", n, maxSize)
+				}
+				if cap(buf) < int(n) {
+					buf = make([]byte, n)
+				}
+				buf = buf[:n]
+				if _, err := io.ReadFull(r, buf); err != nil {
+					if err == io.EOF {
+						err = io.ErrUnexpectedEOF
+					}
+					return zero, err
+				}
+				return unmarshal(buf)
+			},
+		}, nil
+	}
+}
+
+// streamStatus returns an error for responses which aren't successful, whose
+// bodies aren't streams, and closes them.
+func streamStatus(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return errors.New(resp.Status + ": " + string(msg))
+}
@@ -0,0 +1,122 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	httptransport "github.com/barrett370/kit/v2/transport/http"
+)
+
+type item struct {
+	N int `json:"n"`
+}
+
+func TestDecodeNDJSONStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 3; i++ {
+			io.WriteString(w, `{"n":`+strconv.Itoa(i)+"}\n\n")
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	client := httptransport.NewClient("GET", u,
+		func(context.Context, *http.Request, struct{}) error { return nil },
+		httptransport.DecodeNDJSONStream[item],
+		httptransport.BufferedStream[struct{}, *httptransport.Stream[item]](true),
+	)
+	stream, err := client.Endpoint()(context.Background(), struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	var have []int
+	for stream.Next() {
+		have = append(have, stream.Value().N)
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "[0 1 2]", fmt.Sprint(have); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+}
+
+func TestDecodeNDJSONStreamCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	resp := &http.Response{StatusCode: 200, Body: io.NopCloser(&infinite{})}
+	stream, err := httptransport.DecodeNDJSONStream[item](ctx, resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3 && stream.Next(); i++ {
+	}
+	cancel()
+	if stream.Next() {
+		t.Error("want no value after cancel")
+	}
+	if want, have := context.Canceled, stream.Err(); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+// infinite is an endless body of newline-delimited JSON.
+type infinite struct{ pending []byte }
+
+func (r *infinite) Read(p []byte) (int, error) {
+	if len(r.pending) == 0 {
+		r.pending = []byte(`{"n":1}` + "\n")
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func TestDecodeLengthPrefixedStream(t *testing.T) {
+	var body []byte
+	for _, msg := range []string{"a", "bc", ""} {
+		prefix := make([]byte, binary.MaxVarintLen64)
+		body = append(body, prefix[:binary.PutUvarint(prefix, uint64(len(msg)))]...)
+		body = append(body, msg...)
+	}
+	dec := httptransport.DecodeLengthPrefixedStream(func(b []byte) (string, error) { return string(b), nil }, 10)
+	stream, err := dec(context.Background(), &http.Response{StatusCode: 200, Body: io.NopCloser(bytes.NewReader(body))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have []string
+	for stream.Next() {
+		have = append(have, stream.Value())
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := `["a" "bc" ""]`, fmt.Sprintf("%q", have); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+
+	// Truncated and oversized messages are errors.
+	for _, body := range [][]byte{{3, 'a'}, {11}} {
+		stream, _ := dec(context.Background(), &http.Response{StatusCode: 200, Body: io.NopCloser(bytes.NewReader(body))})
+		if stream.Next() || stream.Err() == nil {
+			t.Errorf("%v: want an error, have none", body)
+		}
+	}
+}
+
+func TestDecodeStreamStatus(t *testing.T) {
+	resp := &http.Response{StatusCode: 500, Status: "500 Internal Server Error", Body: io.NopCloser(bytes.NewReader([]byte("dang")))}
+	if _, err := httptransport.DecodeNDJSONStream[item](context.Background(), resp); err == nil || err.Error() != "500 Internal Server Error: dang" {
+		t.Errorf("want an error with the status, have %v", err)
+	}
+}
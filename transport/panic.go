@@ -0,0 +1,68 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/barrett370/kit/v2/endpoint"
+	"github.com/barrett370/kit/v2/metrics"
+)
+
+// PanicError is the error a recovered panic is converted to.
+type PanicError struct {
+	Value interface{} // the value passed to panic
+	Stack []byte      // the stack trace of the panicking goroutine
+}
+
+// NewPanicError returns a PanicError of the value, with the stack trace of
+// the calling goroutine. Call it from the deferred func which recovers.
+func NewPanicError(v interface{}) *PanicError {
+	return &PanicError{Value: v, Stack: debug.Stack()}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the value, if it's an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+type panicKey struct{}
+
+// ContextWithPanic returns a context with the PanicError, for error handlers
+// and encoders to find with PanicFromContext, e.g. to log the stack trace.
+func ContextWithPanic(ctx context.Context, e *PanicError) context.Context {
+	return context.WithValue(ctx, panicKey{}, e)
+}
+
+// PanicFromContext returns the PanicError stored in the context by a server
+// which recovered from a panic, if any.
+func PanicFromContext(ctx context.Context) (*PanicError, bool) {
+	e, ok := ctx.Value(panicKey{}).(*PanicError)
+	return e, ok
+}
+
+// NewRecoveryMiddleware returns an endpoint middleware which recovers from
+// panics in the next endpoint, and returns them as PanicErrors, so they're
+// handled like other errors, by the transport's ErrorHandler and error
+// encoder. If panics isn't nil, it counts them.
+func NewRecoveryMiddleware[I, O any](panics metrics.Counter) endpoint.Middleware[I, O] {
+	return func(next endpoint.Endpoint[I, O]) endpoint.Endpoint[I, O] {
+		return func(ctx context.Context, request I) (response O, err error) {
+			defer func() {
+				if v := recover(); v != nil {
+					if panics != nil {
+						panics.Add(1)
+					}
+					var zero O
+					response, err = zero, NewPanicError(v)
+				}
+			}()
+			return next(ctx, request)
+		}
+	}
+}
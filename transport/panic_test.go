@@ -0,0 +1,44 @@
+package transport_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/barrett370/kit/v2/metrics/generic"
+	"github.com/barrett370/kit/v2/transport"
+)
+
+func TestRecoveryMiddleware(t *testing.T) {
+	var (
+		errBoom = errors.New("boom")
+		panics  = generic.NewCounter("panics")
+		e       = transport.NewRecoveryMiddleware[string, string](panics)(func(context.Context, string) (string, error) {
+			panic(errBoom)
+		})
+	)
+	_, err := e(context.Background(), "")
+
+	var pe *transport.PanicError
+	if !errors.As(err, &pe) {
+		t.Fatalf("want a PanicError, have %v", err)
+	}
+	if !errors.Is(err, errBoom) {
+		t.Errorf("want %v wrapped, have %v", errBoom, err)
+	}
+	if want, have := "panic: boom", err.Error(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if !strings.Contains(string(pe.Stack), "panic_test.go") {
+		t.Errorf("want the stack of the panic, have %s", pe.Stack)
+	}
+	if want, have := 1.0, panics.Value(); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	ctx := transport.ContextWithPanic(context.Background(), pe)
+	if have, ok := transport.PanicFromContext(ctx); !ok || have != pe {
+		t.Errorf("want %v, have %v", pe, have)
+	}
+}
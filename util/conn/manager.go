@@ -1,6 +1,7 @@
 package conn
 
 import (
+	"crypto/tls"
 	"errors"
 	"math/rand"
 	"net"
@@ -13,6 +14,23 @@ import (
 // safe for use by multiple concurrent goroutines.
 type Dialer func(network, address string) (net.Conn, error)
 
+// NetDialer returns a Dialer which dials with d, so that timeouts, keepalives
+// and the local address may be configured.
+func NetDialer(d *net.Dialer) Dialer {
+	return d.Dial
+}
+
+// TLSDialer returns a Dialer which dials with d, and establishes a TLS
+// connection configured by config. If d is nil, a zero net.Dialer is used.
+func TLSDialer(d *net.Dialer, config *tls.Config) Dialer {
+	if d == nil {
+		d = &net.Dialer{}
+	}
+	return func(network, address string) (net.Conn, error) {
+		return tls.DialWithDialer(d, network, address, config)
+	}
+}
+
 // AfterFunc imitates time.After.
 type AfterFunc func(time.Duration) <-chan time.Time
 
@@ -22,7 +40,7 @@ type AfterFunc func(time.Duration) <-chan time.Time
 // address. Clients should Take the connection when they want to use it, and Put
 // back whatever error they receive from its use. When a non-nil error is Put,
 // the connection is invalidated, and a new connection is established.
-// Connection failures are retried after an exponential backoff.
+// Connection failures are retried after an exponential backoff, with jitter.
 type Manager struct {
 	dialer  Dialer
 	network string
@@ -30,15 +48,45 @@ type Manager struct {
 	after   AfterFunc
 	logger  log.Logger
 
+	minBackoff    time.Duration
+	maxBackoff    time.Duration
+	probe         func(net.Conn) error
+	probeInterval time.Duration
+
 	takec chan net.Conn
 	putc  chan error
 }
 
+// Option sets an optional parameter for Managers.
+type Option func(*Manager)
+
+// WithDialer sets the Dialer used to establish connections, e.g. one returned
+// by NetDialer or TLSDialer. It overrides the Dialer passed to NewManager.
+func WithDialer(d Dialer) Option {
+	return func(m *Manager) { m.dialer = d }
+}
+
+// WithBackoff sets the bounds of the exponential backoff between attempts to
+// reconnect. By default, the first retry is after one second, and retries are
+// at most one minute apart.
+func WithBackoff(min, max time.Duration) Option {
+	return func(m *Manager) { m.minBackoff, m.maxBackoff = min, max }
+}
+
+// WithProbe checks the liveness of the connection every interval with probe,
+// e.g. ReadProbe. If the probe returns an error, the connection is closed and
+// re-established, as if the error had been Put. It detects connections which
+// the peer has closed, which would otherwise accept writes until the kernel
+// gives up on them. By default, connections aren't probed.
+func WithProbe(interval time.Duration, probe func(net.Conn) error) Option {
+	return func(m *Manager) { m.probe, m.probeInterval = probe, interval }
+}
+
 // NewManager returns a connection manager using the passed Dialer, network, and
 // address. The AfterFunc is used to control exponential backoff and retries.
 // The logger is used to log errors; pass a log.NopLogger if you don't care to
 // receive them. For normal use, prefer NewDefaultManager.
-func NewManager(d Dialer, network, address string, after AfterFunc, logger log.Logger) *Manager {
+func NewManager(d Dialer, network, address string, after AfterFunc, logger log.Logger, options ...Option) *Manager {
 	m := &Manager{
 		dialer:  d,
 		network: network,
@@ -46,17 +94,23 @@ func NewManager(d Dialer, network, address string, after AfterFunc, logger log.L
 		after:   after,
 		logger:  logger,
 
+		minBackoff: time.Second,
+		maxBackoff: time.Minute,
+
 		takec: make(chan net.Conn),
 		putc:  make(chan error),
 	}
+	for _, option := range options {
+		option(m)
+	}
 	go m.loop()
 	return m
 }
 
 // NewDefaultManager is a helper constructor, suitable for most normal use in
 // real (non-test) code. It uses the real net.Dial and time.After functions.
-func NewDefaultManager(network, address string, logger log.Logger) *Manager {
-	return NewManager(net.Dial, network, address, time.After, logger)
+func NewDefaultManager(network, address string, logger log.Logger, options ...Option) *Manager {
+	return NewManager(net.Dial, network, address, time.After, logger, options...)
 }
 
 // Take yields the current connection. It may be nil.
@@ -87,8 +141,12 @@ func (m *Manager) loop() {
 		conn       = dial(m.dialer, m.network, m.address, m.logger) // may block slightly
 		connc      = make(chan net.Conn, 1)
 		reconnectc <-chan time.Time // initially nil
-		backoff    = time.Second
+		backoff    = m.minBackoff
+		probec     <-chan time.Time // nil unless probing
 	)
+	if m.probe != nil {
+		probec = m.after(m.probeInterval)
+	}
 
 	// If the initial dial fails, we need to trigger a reconnect via the loop
 	// body, below. If we did this in a goroutine, we would race on the conn
@@ -104,12 +162,12 @@ func (m *Manager) loop() {
 		case conn = <-connc:
 			if conn == nil {
 				// didn't work
-				backoff = Exponential(backoff) // wait longer
-				reconnectc = m.after(backoff)  // try again
+				backoff = exponential(backoff, m.maxBackoff) // wait longer
+				reconnectc = m.after(backoff)                // try again
 			} else {
 				// worked!
-				backoff = time.Second // reset wait time
-				reconnectc = nil      // no retry necessary
+				backoff = m.minBackoff // reset wait time
+				reconnectc = nil       // no retry necessary
 			}
 
 		case m.takec <- conn:
@@ -121,6 +179,18 @@ func (m *Manager) loop() {
 				conn = nil                            // connection is bad
 				reconnectc = m.after(time.Nanosecond) // trigger immediately
 			}
+
+		case <-probec:
+			probec = m.after(m.probeInterval) // one-shot, so rearm
+			if conn == nil {
+				continue // reconnect already pending
+			}
+			if err := m.probe(conn); err != nil {
+				m.logger.Log("during", "probe", "err", err)
+				conn.Close()
+				conn = nil                            // connection is dead
+				reconnectc = m.after(time.Nanosecond) // trigger immediately
+			}
 		}
 	}
 }
@@ -138,16 +208,42 @@ func dial(d Dialer, network, address string, logger log.Logger) net.Conn {
 // used to provide backoff for operations that may fail and should avoid thundering herds.
 // See https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/ for rationale
 func Exponential(d time.Duration) time.Duration {
+	return exponential(d, time.Minute)
+}
+
+func exponential(d, max time.Duration) time.Duration {
 	d *= 2
 	jitter := rand.Float64() + 0.5
 	d = time.Duration(int64(float64(d.Nanoseconds()) * jitter))
-	if d > time.Minute {
-		d = time.Minute
+	if d > max {
+		d = max
 	}
 	return d
+}
 
+// ReadProbe is a liveness probe for connections to peers which never send
+// data, such as Graphite and StatsD servers. It reads from the connection with
+// an immediate deadline: a timeout means the connection is alive, whereas EOF
+// or any other error means the peer has closed or reset it. It mustn't be
+// used with peers which send data, which it would consume.
+func ReadProbe(conn net.Conn) error {
+	if err := conn.SetReadDeadline(time.Now().Add(time.Millisecond)); err != nil {
+		return err
+	}
+	defer conn.SetReadDeadline(time.Time{})
+	var b [1]byte
+	_, err := conn.Read(b[:])
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return nil
+	}
+	if err == nil {
+		return errUnexpectedData
+	}
+	return err
 }
 
+var errUnexpectedData = errors.New("unexpected data from peer")
+
 // ErrConnectionUnavailable is returned by the Manager's Write method when the
 // manager cannot yield a good connection.
 var ErrConnectionUnavailable = errors.New("connection unavailable")
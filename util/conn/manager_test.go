@@ -124,6 +124,57 @@ func TestIssue292(t *testing.T) {
 	}
 }
 
+func TestManagerProbe(t *testing.T) {
+	var (
+		dials  uint64
+		peers  = make(chan net.Conn, 2)
+		dialer = func(string, string) (net.Conn, error) {
+			atomic.AddUint64(&dials, 1)
+			local, remote := net.Pipe()
+			peers <- remote
+			return local, nil
+		}
+		mgr = NewManager(dialer, "netw", "addr", time.After, log.NewNopLogger(), WithProbe(10*time.Millisecond, ReadProbe))
+	)
+
+	if conn := mgr.Take(); conn == nil {
+		t.Fatal("nil conn")
+	}
+
+	// Let a few probes pass, then have the peer close the connection.
+	time.Sleep(50 * time.Millisecond)
+	if want, have := uint64(1), atomic.LoadUint64(&dials); want != have {
+		t.Fatalf("dials: want %d, have %d", want, have)
+	}
+	(<-peers).Close()
+
+	if !within(time.Second, func() bool {
+		return atomic.LoadUint64(&dials) == 2 && mgr.Take() != nil
+	}) {
+		t.Fatal("closed connection wasn't detected and re-dialed")
+	}
+}
+
+func TestWithDialer(t *testing.T) {
+	var (
+		dialconn = &mockConn{}
+		dialer   = func(string, string) (net.Conn, error) { return dialconn, nil }
+		failing  = func(string, string) (net.Conn, error) { return nil, errors.New("fail") }
+		mgr      = NewManager(failing, "netw", "addr", time.After, log.NewNopLogger(), WithDialer(dialer))
+	)
+	if want, have := net.Conn(dialconn), mgr.Take(); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestExponentialMax(t *testing.T) {
+	for d := time.Second; d < time.Hour; d *= 2 {
+		if have := exponential(d, 5*time.Second); have > 5*time.Second {
+			t.Fatalf("exponential(%s): want at most 5s, have %s", d, have)
+		}
+	}
+}
+
 type mockConn struct {
 	rd, wr uint64
 }
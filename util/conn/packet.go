@@ -0,0 +1,85 @@
+package conn
+
+import (
+	"bytes"
+	"io"
+)
+
+// Conventional maximum packet sizes for line-based metrics protocols, such as
+// StatsD, sent over datagram sockets.
+const (
+	// MaxUDPPacketSize fits a UDP packet in the MTU of most networks, after
+	// the IP and UDP headers, so it isn't fragmented.
+	MaxUDPPacketSize = 1432
+
+	// MaxUDSPacketSize is the default size of datagrams accepted by the
+	// DogStatsD agent over a Unix domain socket ("unixgram").
+	MaxUDSPacketSize = 8192
+)
+
+// PacketWriter buffers newline-terminated lines, and writes them to the
+// underlying writer in packets of whole lines, each at most a given size. It's
+// meant for datagram connections, e.g. to a StatsD server, where each Write
+// yields one packet: too small, and the network is flooded with packets; too
+// large, and packets are fragmented, truncated or dropped.
+//
+// A line longer than the size is written in a packet of its own, so the
+// underlying writer reports the error, rather than the line being truncated.
+// Flush must be called after the last Write.
+type PacketWriter struct {
+	w    io.Writer
+	size int
+	buf  []byte
+}
+
+// NewPacketWriter returns a PacketWriter which writes to w in packets of at
+// most size bytes.
+func NewPacketWriter(w io.Writer, size int) *PacketWriter {
+	return &PacketWriter{w: w, size: size}
+}
+
+// Write buffers the lines in p, first writing the buffered lines as a packet
+// if they'd otherwise exceed the packet size. A trailing partial line is
+// treated as a whole line.
+func (pw *PacketWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		line := p
+		if i := bytes.IndexByte(p, '\n'); i >= 0 {
+			line = p[:i+1]
+		}
+		if len(pw.buf) > 0 && len(pw.buf)+len(line) > pw.size {
+			if err := pw.Flush(); err != nil {
+				return n - len(p), err
+			}
+		}
+		pw.buf = append(pw.buf, line...)
+		p = p[len(line):]
+	}
+	return n, nil
+}
+
+// Flush writes the buffered lines, if any, as a packet.
+func (pw *PacketWriter) Flush() error {
+	if len(pw.buf) == 0 {
+		return nil
+	}
+	_, err := pw.w.Write(pw.buf)
+	pw.buf = pw.buf[:0]
+	return err
+}
+
+// MaxPacketSize returns the conventional maximum packet size for line-based
+// metrics protocols sent over the network, i.e. MaxUDPPacketSize for UDP, and
+// MaxUDSPacketSize for Unix datagram sockets. For stream networks, such as
+// TCP, it returns 0.
+func MaxPacketSize(network string) int {
+	switch network {
+	case "udp", "udp4", "udp6":
+		return MaxUDPPacketSize
+	case "unixgram":
+		return MaxUDSPacketSize
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,83 @@
+package conn
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+type packetRecorder struct{ packets []string }
+
+func (r *packetRecorder) Write(p []byte) (int, error) {
+	r.packets = append(r.packets, string(p))
+	return len(p), nil
+}
+
+func TestPacketWriter(t *testing.T) {
+	var r packetRecorder
+	pw := NewPacketWriter(&r, 10)
+	for _, line := range []string{"aaaa\n", "bbbb\n", "cc\n", "dddddddddddd\n", "ee\nff\n"} {
+		if n, err := pw.Write([]byte(line)); err != nil || n != len(line) {
+			t.Fatalf("Write(%q): n=%d err=%v", line, n, err)
+		}
+	}
+	if err := pw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"aaaa\nbbbb\n", "cc\n", "dddddddddddd\n", "ee\nff\n"}
+	if have := r.packets; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestManagerUnixgram(t *testing.T) {
+	dir, err := os.MkdirTemp("", "conn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	addr := &net.UnixAddr{Name: filepath.Join(dir, "dsd.socket"), Net: "unixgram"}
+	server, err := net.ListenUnixgram("unixgram", addr)
+	if err != nil {
+		t.Skipf("unixgram unsupported: %v", err)
+	}
+	defer server.Close()
+
+	pw := NewPacketWriter(NewDefaultManager("unixgram", addr.Name, log.NewNopLogger()), MaxUDSPacketSize)
+	pw.Write([]byte("a:1|c\n"))
+	pw.Write([]byte("b:2|c\n"))
+	if err := pw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, MaxUDSPacketSize)
+	server.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := server.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "a:1|c\nb:2|c\n", string(buf[:n]); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestMaxPacketSize(t *testing.T) {
+	for network, want := range map[string]int{
+		"udp":      MaxUDPPacketSize,
+		"udp6":     MaxUDPPacketSize,
+		"unixgram": MaxUDSPacketSize,
+		"tcp":      0,
+		"unix":     0,
+	} {
+		if have := MaxPacketSize(network); want != have {
+			t.Errorf("%s: want %d, have %d", network, want, have)
+		}
+	}
+}